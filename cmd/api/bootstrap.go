@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/verigate/verigate-server/internal/pkg/utils/jwt"
+)
+
+// bootstrapKeysCommand is the subcommand generating the initial signing keys
+const bootstrapKeysCommand = "bootstrap-keys"
+
+// bootstrapKeys runs the bootstrap-keys subcommand with its arguments: it generates the initial
+// RSA and EC signing keys, persists them with the configured key provider, and prints the
+// resulting JWKS to stdout for verification. Existing keys are only replaced with -force.
+// Returns the exit code of the process.
+func bootstrapKeys(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet(bootstrapKeysCommand, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	force := flags.Bool("force", false, "replace existing signing keys")
+	rsaBits := flags.Int("rsa-bits", jwt.MinRSAKeyBits, fmt.Sprintf("size of the RSA key in bits, at least %d", jwt.MinRSAKeyBits))
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	set, err := jwt.BootstrapKeys(context.Background(), *rsaBits, *force)
+	if errors.Is(err, jwt.ErrKeysExist) {
+		fmt.Fprintf(stderr, "%v; pass -force to replace them\n", err)
+		return 1
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to bootstrap signing keys: %v\n", err)
+		return 1
+	}
+
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(set); err != nil {
+		fmt.Fprintf(stderr, "Failed to print JWKS: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -3,30 +3,55 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 	"time"
 
+	"github.com/verigate/verigate-server/internal/app/admin"
+	"github.com/verigate/verigate-server/internal/app/audit"
 	"github.com/verigate/verigate-server/internal/app/auth"
+	"github.com/verigate/verigate-server/internal/app/cleanup"
 	"github.com/verigate/verigate-server/internal/app/client"
+	"github.com/verigate/verigate-server/internal/app/health"
+	"github.com/verigate/verigate-server/internal/app/logout"
 	"github.com/verigate/verigate-server/internal/app/oauth"
 	"github.com/verigate/verigate-server/internal/app/scope"
 	"github.com/verigate/verigate-server/internal/app/token"
 	"github.com/verigate/verigate-server/internal/app/user"
+	"github.com/verigate/verigate-server/internal/app/webhook"
 	"github.com/verigate/verigate-server/internal/pkg/config"
+	"github.com/verigate/verigate-server/internal/pkg/db/file"
+	"github.com/verigate/verigate-server/internal/pkg/db/memory"
 	"github.com/verigate/verigate-server/internal/pkg/db/postgres"
 	"github.com/verigate/verigate-server/internal/pkg/db/redis"
+	"github.com/verigate/verigate-server/internal/pkg/db/routing"
+	"github.com/verigate/verigate-server/internal/pkg/metrics"
 	"github.com/verigate/verigate-server/internal/pkg/middleware"
+	"github.com/verigate/verigate-server/internal/pkg/tenant"
 	"github.com/verigate/verigate-server/internal/pkg/utils/jwt"
 
 	"github.com/gin-gonic/gin"
+	goredis "github.com/go-redis/redis/v8"
 	"go.uber.org/zap"
 )
 
 // main is the entry point for the Verigate Server API.
-// It initializes all components and starts the HTTP server.
+// It initializes all components and starts the HTTP server, unless run as
+// "bootstrap-keys" to generate the initial signing keys.
 func main() {
 	// Configuration and logging
 	config.Load()
+	if len(os.Args) > 1 && os.Args[1] == bootstrapKeysCommand {
+		os.Exit(bootstrapKeys(os.Args[2:], os.Stdout, os.Stderr))
+	}
 	logger, err := setupLogger()
 	if err != nil {
 		log.Fatalf("Failed to initialize logger: %v", err)
@@ -35,10 +60,39 @@ func main() {
 
 	sugar := logger.Sugar()
 
+	shutdownTimeout, err := time.ParseDuration(config.AppConfig.ShutdownTimeout)
+	if err != nil {
+		sugar.Fatalf("Invalid shutdown timeout: %v", err)
+	}
+	readinessTimeout, err := time.ParseDuration(config.AppConfig.ReadinessTimeout)
+	if err != nil {
+		sugar.Fatalf("Invalid readiness timeout: %v", err)
+	}
+
+	// Cancelled on SIGTERM or SIGINT; background work derives its context from it
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	// Tenants served besides the default one
+	tenantRegistry, err := setupTenants()
+	if err != nil {
+		sugar.Fatalf("Failed to configure tenants: %v", err)
+	}
+
 	// Initialize JWT keys
 	if err := jwt.InitKeys(); err != nil {
 		sugar.Fatalf("Failed to initialize JWT keys: %v", err)
 	}
+	if err := setupTenantKeys(ctx, tenantRegistry); err != nil {
+		sugar.Fatalf("Failed to initialize tenant JWT keys: %v", err)
+	}
+
+	// Scheduled signing key rotation
+	rotationCtx, stopRotation := context.WithCancel(ctx)
+	defer stopRotation()
+	if err := startKeyRotation(rotationCtx, sugar); err != nil {
+		sugar.Fatalf("Failed to start JWT key rotation: %v", err)
+	}
 
 	// Database connections
 	redisClient, err := redis.NewConnection()
@@ -53,37 +107,175 @@ func main() {
 	}
 	defer postgresDB.Close()
 
+	// Every tenant's data lives in its own schema
+	tenantDB, err := postgres.NewTenantDB(postgresDB, tenantRegistry.Tenants())
+	if err != nil {
+		sugar.Fatalf("Failed to set up tenant databases: %v", err)
+	}
+	defer tenantDB.Close()
+
+	// Reads tolerating replication lag are served by the read replica, when one is configured
+	db, closeReplica, err := setupReplicaDB(ctx, sugar, tenantDB, tenantRegistry.Tenants())
+	if err != nil {
+		sugar.Fatalf("Failed to set up the PostgreSQL read replica: %v", err)
+	}
+	defer closeReplica()
+
 	// Repositories
-	userRepo := postgres.NewUserRepository(postgresDB)
-	clientRepo := postgres.NewClientRepository(postgresDB)
-	oauthRepo := postgres.NewOAuthRepository(postgresDB)
-	tokenRepo := postgres.NewTokenRepository(postgresDB)
-	scopeRepo := postgres.NewScopeRepository(postgresDB)
+	userRepo := postgres.NewUserRepository(db)
+	clientRepo := setupClientRepository(db)
+	oauthRepo := postgres.NewOAuthRepository(db)
+	tokenRepo := postgres.NewTokenRepository(db)
+	scopeRepo := postgres.NewScopeRepository(db)
 	cacheRepo := redis.NewCacheRepository(redisClient)
-	authRepo := redis.NewAuthRepository(redisClient) // Added
+	authRepo := setupTokenStore(ctx, redisClient, db)
+	devicePollRepo := redis.NewDevicePollRepository(redisClient)
+	jtiStore := redis.NewJTIStore(redisClient)
+	pushedRepo := redis.NewPushedRequestRepository(redisClient)
+	logoutRepo := postgres.NewLogoutRepository(db)
+	lockoutRepo := redis.NewLockoutRepository(redisClient)
+	captchaRepo := redis.NewCaptchaRepository(redisClient)
+	twoFactorRepo := postgres.NewTwoFactorRepository(db)
+	webAuthnRepo := postgres.NewWebAuthnRepository(db)
+	webAuthnSessionRepo := redis.NewWebAuthnSessionRepository(redisClient)
+	sessionRepo := redis.NewSessionRepository(redisClient)
+	webhookRepo := postgres.NewWebhookRepository(db)
+	webhookQueueRepo := redis.NewWebhookQueueRepository(redisClient)
+	cleanupRepo := postgres.NewCleanupRepository(db)
+	cleanupLockRepo := redis.NewCleanupLockRepository(redisClient)
+	auditRepo, err := setupAuditRepository(db)
+	if err != nil {
+		sugar.Fatalf("Failed to open audit store: %v", err)
+	}
 
 	// Services
-	authService := auth.NewService(authRepo)                    // Added
-	userService := user.NewService(userRepo, authService)       // Modified
-	clientService := client.NewService(clientRepo, authService) // Modified
+	auditService := audit.NewService(auditRepo)
+	webhookService := webhook.NewService(webhookRepo, webhookQueueRepo, tenantRegistry.Tenants())
+	auditService.AddListener(webhookService)
+	authService := auth.NewService(authRepo, sessionRepo)                     // Added
+	clientService := client.NewService(clientRepo, authService, auditService) // Modified
+	logoutService := logout.NewService(logoutRepo, clientService)
+	userService := user.NewService(userRepo, lockoutRepo, captchaRepo, twoFactorRepo, webAuthnRepo, webAuthnSessionRepo, authService, logoutService, auditService) // Modified
 	scopeService := scope.NewService(scopeRepo)
-	tokenService := token.NewService(tokenRepo, cacheRepo, authService)                                              // Modified
-	oauthService := oauth.NewService(oauthRepo, userService, clientService, tokenService, scopeService, authService) // Modified
+	if err := scopeService.CheckHierarchy(ctx); err != nil {
+		sugar.Fatalf("Invalid scope hierarchy: %v", err)
+	}
+	tokenService := token.NewService(tokenRepo, cacheRepo, authService, auditService) // Modified
+	cleanupService := cleanup.NewService(cleanupRepo, cleanupLockRepo, tenantRegistry.Tenants())
+	oauthService := oauth.NewService(oauthRepo, userService, clientService, tokenService, scopeService, authService, devicePollRepo, jtiStore, pushedRepo, logoutService, auditService) // Modified
+
+	// Back-channel logout delivery workers
+	logoutCtx, stopLogout := context.WithCancel(ctx)
+	defer stopLogout()
+	logoutService.Start(logoutCtx)
+
+	// Webhook delivery workers
+	webhookCtx, stopWebhooks := context.WithCancel(ctx)
+	defer stopWebhooks()
+	webhookService.Start(webhookCtx)
+
+	// Expired data purge
+	cleanupCtx, stopCleanup := context.WithCancel(ctx)
+	defer stopCleanup()
+	cleanupService.Start(cleanupCtx, func(tenantID string, resp *cleanup.PurgeResponse, err error) {
+		if tenantID == "" {
+			tenantID = "default"
+		}
+		if err != nil {
+			sugar.Errorf("Failed to purge expired data of tenant %s: %v", tenantID, err)
+			return
+		}
+		sugar.Infof("Purged %d expired rows of tenant %s", resp.Total, tenantID)
+	})
+
+	// Audit event writer
+	auditCtx, stopAudit := context.WithCancel(ctx)
+	defer stopAudit()
+	auditService.Start(auditCtx)
+
+	// Rate limiting
+	rateLimiters, err := setupRateLimiters(ctx, logger, clientService)
+	if err != nil {
+		sugar.Fatalf("Failed to configure rate limiter: %v", err)
+	}
+	adminService := admin.NewService(rateLimiters.Limiter(oauthRateLimiter), authService, logoutService, userService, tokenService, auditService, webhookService, cleanupService, clientService)
+	healthService := health.NewService(redisClient, postgresDB, readinessTimeout)
 
 	// Handlers
 	userHandler := user.NewHandler(userService)
 	clientHandler := client.NewHandler(clientService)
 	tokenHandler := token.NewHandler(tokenService)
 	oauthHandler := oauth.NewHandler(oauthService)
+	adminHandler := admin.NewHandler(adminService)
+	healthHandler := health.NewHandler(healthService)
 
 	// Router setup
-	router := setupRouter(logger, userHandler, clientHandler, tokenHandler, oauthHandler)
+	drainer := middleware.NewDrainer()
+	router, err := setupRouter(logger, drainer, rateLimiters, userHandler, clientHandler, tokenHandler, oauthHandler, adminHandler, healthHandler)
+	if err != nil {
+		sugar.Fatalf("Failed to set up router: %v", err)
+	}
 
-	// Start server
+	// Start server, resolving the tenant of every request before routing it and tracking its
+	// database writes, so that its reads after a write are served by the primary
+	server := &http.Server{
+		Addr:    ":" + config.AppConfig.AppPort,
+		Handler: tenantRegistry.Handler(routing.Handler(router)),
+		// Clients may present a certificate for mutual TLS; which ones are trusted is up to client authentication
+		TLSConfig: &tls.Config{ClientAuth: tls.RequestClientCert},
+	}
 	sugar.Infof("Starting server on port %s", config.AppConfig.AppPort)
-	if err := router.Run(":" + config.AppConfig.AppPort); err != nil {
+	if err := runServer(ctx, sugar, server, drainer, shutdownTimeout); err != nil {
 		sugar.Fatalf("Failed to start server: %v", err)
 	}
+
+	// Stop background work before the deferred calls close the Redis and PostgreSQL connections
+	stopRotation()
+	stopLogout()
+	stopWebhooks()
+	stopCleanup()
+	stopAudit()
+	auditService.Wait()
+	sugar.Info("Server stopped")
+}
+
+// runServer serves HTTP requests until ctx is cancelled, then shuts the server down gracefully.
+// It serves HTTPS when a TLS certificate and key are configured, and plain HTTP otherwise.
+// During the shutdown the listener is closed, requests arriving on open connections are
+// rejected with 503, and in-flight requests get up to timeout to complete.
+// Returns an error if the server fails to start; a shutdown that times out is only logged,
+// since the remaining connections are closed either way.
+func runServer(ctx context.Context, sugar *zap.SugaredLogger, server *http.Server, drainer *middleware.Drainer, timeout time.Duration) error {
+	serverErr := make(chan error, 1)
+	go func() {
+		if config.AppConfig.TLSCertFile != "" {
+			serverErr <- server.ListenAndServeTLS(config.AppConfig.TLSCertFile, config.AppConfig.TLSKeyFile)
+			return
+		}
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+	}
+
+	sugar.Infof("Shutting down, waiting up to %s for in-flight requests", timeout)
+	drainer.Start()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		sugar.Errorf("Graceful shutdown did not complete: %v", err)
+		server.Close()
+	}
+
+	return nil
 }
 
 // setupLogger initializes and configures the application logger.
@@ -101,36 +293,341 @@ func setupLogger() (*zap.Logger, error) {
 	return zapConfig.Build()
 }
 
+// startKeyRotation schedules signing key rotation when an interval is configured.
+// Retired keys stay published for the longest token lifetime so that tokens
+// issued before a rotation remain verifiable until they expire. Keys held by an external
+// key provider are rotated in the provider, so no interval may be configured for them.
+func startKeyRotation(ctx context.Context, sugar *zap.SugaredLogger) error {
+	interval, err := time.ParseDuration(config.AppConfig.JWTKeyRotationInterval)
+	if err != nil {
+		return err
+	}
+	if interval <= 0 {
+		return nil
+	}
+	if config.AppConfig.JWTKeyProvider != jwt.KeyProviderLocal {
+		return fmt.Errorf("scheduled rotation requires the local key provider, rotate the %s keys in the provider instead", config.AppConfig.JWTKeyProvider)
+	}
+
+	accessExpiry, err := time.ParseDuration(config.AppConfig.JWTAccessExpiry)
+	if err != nil {
+		return err
+	}
+	refreshExpiry, err := time.ParseDuration(config.AppConfig.JWTRefreshExpiry)
+	if err != nil {
+		return err
+	}
+
+	grace := accessExpiry
+	if refreshExpiry > grace {
+		grace = refreshExpiry
+	}
+
+	jwt.StartKeyRotation(ctx, interval, grace, func(tenantID, kid string, err error) {
+		if tenantID == "" {
+			tenantID = "default"
+		}
+		if err != nil {
+			sugar.Errorf("Failed to rotate JWT signing key of tenant %s: %v", tenantID, err)
+			return
+		}
+		sugar.Infof("Rotated JWT signing key of tenant %s, new kid %s", tenantID, kid)
+	})
+
+	return nil
+}
+
+// setupReplicaDB routes the reads repositories mark as tolerating replication lag to the read
+// replica configured in the application configuration, checking its lag until ctx is cancelled.
+// Without a replica, primary is returned and every query goes to it. The returned function
+// closes the replica's connections.
+func setupReplicaDB(ctx context.Context, sugar *zap.SugaredLogger, primary postgres.DB, tenants []*tenant.Tenant) (postgres.DB, func(), error) {
+	if config.AppConfig.PostgresReplicaHost == "" {
+		return primary, func() {}, nil
+	}
+
+	maxLag, err := time.ParseDuration(config.AppConfig.PostgresReplicaMaxLag)
+	if err != nil || maxLag <= 0 {
+		return nil, nil, fmt.Errorf("invalid replica max lag: %s", config.AppConfig.PostgresReplicaMaxLag)
+	}
+	interval, err := time.ParseDuration(config.AppConfig.PostgresReplicaLagInterval)
+	if err != nil || interval <= 0 {
+		return nil, nil, fmt.Errorf("invalid replica lag check interval: %s", config.AppConfig.PostgresReplicaLagInterval)
+	}
+
+	replicaConn, err := postgres.NewReplicaConnection()
+	if err != nil {
+		return nil, nil, err
+	}
+	replica, err := postgres.NewTenantReplicaDB(replicaConn, tenants)
+	if err != nil {
+		replicaConn.Close()
+		return nil, nil, err
+	}
+
+	db := postgres.NewReplicaDB(primary, replica, maxLag)
+	db.Start(ctx, interval, func(lagging bool, lag time.Duration, err error) {
+		switch {
+		case err != nil:
+			sugar.Warnf("PostgreSQL read replica unavailable, reading from the primary: %v", err)
+		case lagging:
+			sugar.Warnf("PostgreSQL read replica is %s behind, reading from the primary", lag)
+		default:
+			sugar.Infof("PostgreSQL read replica is within %s, serving reads from it", maxLag)
+		}
+	})
+
+	return db, func() {
+		replica.Close()
+		replicaConn.Close()
+	}, nil
+}
+
+// setupTokenStore creates the web refresh token store selected in the application configuration:
+// Redis, the web_refresh_tokens table in PostgreSQL, or process memory swept until ctx is cancelled.
+func setupTokenStore(ctx context.Context, redisClient *goredis.Client, db postgres.DB) auth.TokenStore {
+	switch config.AppConfig.TokenStore {
+	case "postgres":
+		return postgres.NewAuthRepository(db)
+	case "memory":
+		return memory.NewAuthRepository(ctx)
+	}
+	return redis.NewAuthRepository(redisClient)
+}
+
+// setupClientRepository creates the OAuth client store selected in the application configuration:
+// the clients table in PostgreSQL, or process memory.
+func setupClientRepository(db postgres.DB) client.Repository {
+	if config.AppConfig.ClientStore == "memory" {
+		return memory.NewClientRepository()
+	}
+	return postgres.NewClientRepository(db)
+}
+
+// setupAuditRepository creates the audit event store selected in the application configuration:
+// an append-only file, or the audit_events table in PostgreSQL.
+func setupAuditRepository(db postgres.DB) (audit.Repository, error) {
+	if config.AppConfig.AuditStore == "file" {
+		return file.NewAuditRepository(config.AppConfig.AuditFilePath)
+	}
+	return postgres.NewAuditRepository(db), nil
+}
+
+// setupTenants creates the registry of the configured tenants. Tenants are refused with the
+// stores that are not partitioned by tenant: the in-memory token and client stores and the audit file.
+func setupTenants() (*tenant.Registry, error) {
+	registry, err := tenant.NewRegistry(config.AppConfig.Tenants, config.AppConfig.TenantHosts)
+	if err != nil {
+		return nil, err
+	}
+	if len(registry.Tenants()) == 0 {
+		return registry, nil
+	}
+
+	if config.AppConfig.TokenStore == "memory" || config.AppConfig.ClientStore == "memory" {
+		return nil, fmt.Errorf("tenants require the PostgreSQL or Redis token and client stores")
+	}
+	if config.AppConfig.AuditStore == "file" {
+		return nil, fmt.Errorf("tenants require the PostgreSQL audit store")
+	}
+	return registry, nil
+}
+
+// setupTenantKeys loads the signing key of every tenant from <id>.pem in the tenant keys directory.
+// With the Vault key provider, tenants sign with transit keys of their own instead, which are
+// read to check that every tenant has them.
+func setupTenantKeys(ctx context.Context, registry *tenant.Registry) error {
+	if config.AppConfig.JWTKeyProvider == jwt.KeyProviderVault {
+		for _, t := range registry.Tenants() {
+			if _, err := jwt.PublicJWKS(tenant.WithTenant(ctx, t)); err != nil {
+				return fmt.Errorf("tenant %s: %w", t.ID, err)
+			}
+		}
+		return nil
+	}
+
+	for _, t := range registry.Tenants() {
+		privateKey, err := os.ReadFile(filepath.Join(config.AppConfig.TenantKeysDir, t.ID+".pem"))
+		if err != nil {
+			return err
+		}
+		if err := jwt.InitTenantKeys(t.ID, privateKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Names of the rate limiters attached to route groups
+const (
+	oauthRateLimiter     = "oauth"     // Every OAuth endpoint
+	tokenRateLimiter     = "token"     // The token endpoint, in addition to the OAuth limit
+	loginRateLimiter     = "login"     // The user login endpoints
+	discoveryRateLimiter = "discovery" // The metadata and JWKS documents
+)
+
+// Names of the concurrency limiters, as reported in the metrics
+const (
+	apiConcurrencyLimiter   = "api"   // Every request but the health probes and metrics
+	tokenConcurrencyLimiter = "token" // The token endpoint, within the API limit
+)
+
+// setupRateLimiters creates the rate limiters of the route groups, counting in Redis or, when
+// configured, in process memory swept until ctx is cancelled. The OAuth and token limiters apply
+// their limits and per-client tiers; the others apply the route limits that are configured.
+// A client's tier is the one assigned to it, falling back to RATE_LIMIT_CLIENT_TIERS; public
+// clients have none, as anyone can present their client ID.
+// The counting algorithm, IP lists, and fail-closed behavior are shared by all of them, and each
+// counts for the subject RATE_LIMIT_KEYS configures for it.
+func setupRateLimiters(ctx context.Context, logger *zap.Logger, clientService *client.Service) (*middleware.RateLimiterRegistry, error) {
+	registry := middleware.NewRateLimiterRegistry()
+
+	register := func(name string, limit int, window time.Duration, tierLookup middleware.TierLookup) error {
+		var rateLimiter *middleware.RateLimiter
+		if config.AppConfig.RateLimitStore == "memory" {
+			rateLimiter = middleware.NewTieredMemoryRateLimiter(ctx, middleware.RateLimitKeyPrefix(name), limit, window, tierLookup)
+		} else {
+			rateLimiter = middleware.NewTieredRedisRateLimiter(redis.GetClient(), middleware.RateLimitKeyPrefix(name), limit, window, tierLookup)
+		}
+		rateLimiter.FailClosed = config.AppConfig.RateLimitFailClosed
+		rateLimiter.Algorithm = middleware.ParseRateLimitAlgorithm(config.AppConfig.RateLimitAlgorithm)
+		rateLimiter.RefillRate = config.AppConfig.RateLimitRefillRate
+		rateLimiter.BurstCapacity = config.AppConfig.RateLimitBurstCapacity
+		rateLimiter.Logger = logger
+		if key, ok := config.AppConfig.RateLimitKeys[name]; ok {
+			keyFunc, err := middleware.ParseRateLimitKey(key)
+			if err != nil {
+				return fmt.Errorf("%s rate limit: %w", name, err)
+			}
+			rateLimiter.KeyFunc = keyFunc
+		}
+
+		if err := rateLimiter.SetIPLists(config.AppConfig.RateLimitAllowlist, config.AppConfig.RateLimitDenylist); err != nil {
+			return err
+		}
+		return registry.Register(name, rateLimiter)
+	}
+
+	configuredTiers := make(map[string]middleware.ClientTier, len(config.AppConfig.RateLimitClientTiers))
+	for clientID, value := range config.AppConfig.RateLimitClientTiers {
+		tier, err := middleware.ParseClientTier(value)
+		if err != nil {
+			return nil, fmt.Errorf("rate limit tier of client %s: %w", clientID, err)
+		}
+		configuredTiers[clientID] = tier
+	}
+	tierLookup := func(ctx context.Context, clientID string) (middleware.ClientTier, bool) {
+		c, err := clientService.GetByClientID(ctx, clientID)
+		if err != nil || c == nil || !c.IsActive || c.TokenEndpointAuthMethod == client.AuthMethodNone {
+			return middleware.ClientTier{}, false
+		}
+		if c.RateLimitTier != "" {
+			tier, err := middleware.ParseClientTier(c.RateLimitTier)
+			return tier, err == nil
+		}
+		tier, ok := configuredTiers[clientID]
+		return tier, ok
+	}
+	if err := register(oauthRateLimiter, config.AppConfig.RateLimitRequestsPerMinute, time.Minute, tierLookup); err != nil {
+		return nil, err
+	}
+
+	for _, name := range []string{tokenRateLimiter, loginRateLimiter, discoveryRateLimiter} {
+		value, ok := config.AppConfig.RateLimitRoutes[name]
+		if !ok {
+			continue
+		}
+		limit, window, err := middleware.ParseRateLimit(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s rate limit: %w", name, err)
+		}
+		var lookup middleware.TierLookup
+		if name == tokenRateLimiter {
+			lookup = tierLookup
+		}
+		if err := register(name, limit, window, lookup); err != nil {
+			return nil, err
+		}
+	}
+
+	return registry, nil
+}
+
+// securityHeadersConfig returns the security headers of every response, as configured.
+func securityHeadersConfig() (middleware.SecurityHeadersConfig, error) {
+	hstsMaxAge, err := time.ParseDuration(config.AppConfig.SecurityHSTSMaxAge)
+	if err != nil {
+		return middleware.SecurityHeadersConfig{}, fmt.Errorf("invalid HSTS max age: %w", err)
+	}
+
+	return middleware.SecurityHeadersConfig{
+		ContentSecurityPolicy: config.AppConfig.SecurityCSP,
+		FrameOptions:          config.AppConfig.SecurityFrameOptions,
+		ReferrerPolicy:        config.AppConfig.SecurityReferrerPolicy,
+		ContentTypeOptions:    config.AppConfig.SecurityContentTypeOptions,
+		HSTSMaxAge:            hstsMaxAge,
+		HSTSIncludeSubdomains: config.AppConfig.SecurityHSTSSubdomains,
+	}, nil
+}
+
 // setupRouter configures the HTTP router with all routes and middleware.
 // It registers all handlers, sets up middleware for logging, error handling, rate limiting,
 // CORS, and recovery from panics.
-// Returns the configured gin engine ready to serve HTTP requests.
+// Client addresses are only taken from X-Forwarded-For when the request comes from one of the
+// configured trusted proxies, and gin is given the same proxies and header, so c.ClientIP()
+// agrees with middleware.ClientIP, which the rate limiter, IP filter, and logs are keyed on.
+// Once the drainer starts, new requests are rejected with 503 while in-flight ones complete.
+// Returns the configured gin engine ready to serve HTTP requests, or an error if the
+// trusted proxy list is invalid.
 func setupRouter(
 	logger *zap.Logger,
+	drainer *middleware.Drainer,
+	rateLimiters *middleware.RateLimiterRegistry,
 	userHandler *user.Handler,
 	clientHandler *client.Handler,
 	tokenHandler *token.Handler,
 	oauthHandler *oauth.Handler,
-) *gin.Engine {
+	adminHandler *admin.Handler,
+	healthHandler *health.Handler,
+) (*gin.Engine, error) {
 	if config.AppConfig.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
 	router := gin.New()
 
+	// Client addresses are resolved by ClientIPMiddleware; gin trusts the same proxies for the
+	// same header, so neither honors forwarding headers from anyone else
+	router.RemoteIPHeaders = []string{middleware.HeaderForwardedFor}
+	if err := router.SetTrustedProxies(config.AppConfig.TrustedProxies); err != nil {
+		return nil, err
+	}
+	clientIP, err := middleware.ClientIPMiddleware(config.AppConfig.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	clientCert, err := middleware.ClientCertificateMiddleware(config.AppConfig.TrustedProxies, config.AppConfig.MTLSClientCertHeader)
+	if err != nil {
+		return nil, err
+	}
+
 	// Middleware
-	router.Use(middleware.RequestLogger(logger))
+	router.Use(clientIP)
+	router.Use(clientCert)
+	router.Use(middleware.MetricsMiddleware())
+	router.Use(middleware.RequestLoggingMiddleware(logger))
 	router.Use(middleware.Recovery(logger))
-	router.Use(middleware.CORS())
+	corsConfig := middleware.DefaultCORSConfig(config.AppConfig.CORSAllowedOrigins)
+	corsConfig.AllowCredentials = config.AppConfig.CORSAllowCredentials
+	corsConfig.OriginValidator = oauthHandler.CORSOriginValidator()
+	router.Use(middleware.CORSMiddleware(corsConfig))
+	securityHeaders, err := securityHeadersConfig()
+	if err != nil {
+		return nil, err
+	}
+	router.Use(middleware.SecurityHeadersMiddleware(securityHeaders))
 	router.Use(middleware.ErrorHandler())
-
-	// Rate limiting setup
-	rateLimiter := middleware.NewRedisRateLimiter(
-		redis.GetClient(),
-		"rate_limit:",
-		config.AppConfig.RateLimitRequestsPerMinute,
-		60, // 1 minute window
-	)
+	router.Use(middleware.DrainMiddleware(drainer))
 
 	// IP control setup
 	ipControl := middleware.NewIPControl(
@@ -141,22 +638,66 @@ func setupRouter(
 	// Apply middleware
 	router.Use(middleware.IPControlMiddleware(ipControl))
 
+	// Concurrency limits, attached to the route groups so the health probes and metrics bypass them
+	retryAfter, err := time.ParseDuration(config.AppConfig.ConcurrencyRetryAfter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CONCURRENCY_RETRY_AFTER: %w", err)
+	}
+	apiLimit := middleware.ConcurrencyLimitMiddleware(
+		middleware.NewConcurrencyLimiter(apiConcurrencyLimiter, config.AppConfig.ConcurrencyLimit, retryAfter))
+	tokenLimit := middleware.ConcurrencyLimitMiddleware(
+		middleware.NewConcurrencyLimiter(tokenConcurrencyLimiter, config.AppConfig.TokenConcurrencyLimit, retryAfter))
+
+	// Discovery endpoints served from the server root
+	discoveryGroup := router.Group("", apiLimit)
+	rateLimiters.Attach(discoveryRateLimiter, discoveryGroup)
+	oauthHandler.RegisterWellKnownRoutes(discoveryGroup)
+
 	// API routes
-	api := router.Group("/api/v1")
+	api := router.Group("/api/v1", apiLimit)
 	{
-		// OAuth endpoints (with rate limiting)
-		oauthGroup := api.Group("/oauth")
-		oauthGroup.Use(middleware.RateLimitMiddleware(rateLimiter))
+		// OAuth endpoints (with rate limiting), their request limits applied before anything reads the form
+		oauthGroup := api.Group("/oauth", middleware.RequestLimitsMiddleware(middleware.RequestLimits{
+			MaxFormBodyBytes: config.AppConfig.MaxFormBodyBytes,
+			MaxJSONBodyBytes: config.AppConfig.MaxJSONBodyBytes,
+			MaxParamValues:   config.AppConfig.MaxParamValues,
+			MaxParamLength:   config.AppConfig.MaxParamLength,
+		}))
+		rateLimiters.Attach(oauthRateLimiter, oauthGroup)
+		rateLimiters.DeferToClientAuthentication(oauthHandler.ClientAuthenticatedPaths(oauthGroup.BasePath())...)
 		{
 			oauthHandler.RegisterRoutes(oauthGroup)
+
+			// Token endpoint, with a limit of its own on top of the OAuth limit
+			tokenGroup := oauthGroup.Group("")
+			rateLimiters.Attach(tokenRateLimiter, tokenGroup)
+			tokenGroup.Use(tokenLimit)
+			oauthHandler.RegisterTokenRoutes(tokenGroup)
+
+			// Dynamic client registration
+			clientHandler.RegisterRegistrationRoutes(oauthGroup.Group("/register"))
 		}
 
 		// User endpoints
 		userGroup := api.Group("/users")
 		{
 			userHandler.RegisterRoutes(userGroup)
+
+			// Login endpoints, limited apart from the rest of the API
+			loginGroup := userGroup.Group("")
+			rateLimiters.Attach(loginRateLimiter, loginGroup)
+			userHandler.RegisterLoginRoutes(loginGroup)
+		}
+
+		// Account security endpoints
+		accountGroup := api.Group("/account")
+		{
+			userHandler.RegisterAccountRoutes(accountGroup)
 		}
 
+		// Authorized applications, on a group of their own so web authentication runs once per request
+		oauthHandler.RegisterAccountRoutes(api.Group("/account"))
+
 		// Client endpoints
 		clientGroup := api.Group("/clients")
 		{
@@ -168,8 +709,27 @@ func setupRouter(
 		{
 			tokenHandler.RegisterRoutes(tokenGroup)
 		}
+
+		// Admin endpoints
+		adminGroup := api.Group("/admin")
+		{
+			adminHandler.RegisterRoutes(adminGroup)
+		}
+
+		// Token debugging, only when explicitly enabled
+		if config.AppConfig.DebugTokenEndpoint {
+			debugGroup := api.Group("/debug")
+			rateLimiters.Attach(oauthRateLimiter, debugGroup)
+			oauthHandler.RegisterDebugRoutes(debugGroup)
+		}
 	}
 
+	// Prometheus metrics
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+
+	// Liveness and readiness probes, outside the rate and concurrency limits and authentication
+	healthHandler.RegisterRoutes(router)
+
 	// Health check endpoint
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -178,5 +738,5 @@ func setupRouter(
 		})
 	})
 
-	return router
+	return router, nil
 }
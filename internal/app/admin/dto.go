@@ -0,0 +1,59 @@
+// Package admin provides operator-facing functionality for inspecting
+// and managing the running authorization server.
+package admin
+
+import (
+	"time"
+
+	"github.com/verigate/verigate-server/internal/app/client"
+)
+
+// RateLimitQuery represents the query parameters for inspecting rate limit state.
+type RateLimitQuery struct {
+	Kind    string `form:"kind" binding:"required"`    // Subject kind ("user" or "ip")
+	Subject string `form:"subject" binding:"required"` // User ID or IP address
+}
+
+// RateLimitStatusResponse describes the live rate limit state for a subject.
+type RateLimitStatusResponse struct {
+	Kind    string    `json:"kind"`     // Subject kind ("user" or "ip")
+	Subject string    `json:"subject"`  // User ID or IP address
+	Count   int       `json:"count"`    // Requests counted in the current window
+	ResetAt time.Time `json:"reset_at"` // When the window fully resets
+}
+
+// LockoutQuery represents the query parameters for clearing an account lockout.
+type LockoutQuery struct {
+	Email string `form:"email" binding:"required"` // Login email whose lockout to clear
+}
+
+// ScopeClientAdmin is the scope an administration client's access token must carry to use
+// the client administration endpoints
+const ScopeClientAdmin = "admin:clients"
+
+// ClientQuery represents the query parameters for listing clients.
+type ClientQuery struct {
+	Page         int    `form:"page"`         // Page number, 1-indexed (default 1)
+	Limit        int    `form:"limit"`        // Clients per page (default 10, at most 100)
+	OwnerID      uint   `form:"owner_id"`     // Only clients owned by this user
+	Query        string `form:"q"`            // Case-insensitive substring of the client name or client ID
+	Active       *bool  `form:"active"`       // Only active or only inactive clients
+	Confidential *bool  `form:"confidential"` // Only confidential or only public clients
+}
+
+// CreateClientRequest represents a client created by an administrator,
+// optionally on behalf of a user who then manages it.
+type CreateClientRequest struct {
+	OwnerID uint `json:"owner_id"` // User owning the client, zero for none
+	client.CreateClientRequest
+}
+
+// RateLimitTierRequest represents the rate limit tier assigned to a client.
+type RateLimitTierRequest struct {
+	RateLimitTier string `json:"rate_limit_tier"` // exempt or requests/window, empty for the configured tier
+}
+
+// RotateSecretRequest represents the options of a client secret rotation.
+type RotateSecretRequest struct {
+	GracePeriod int `json:"grace_period"` // Seconds the replaced secret keeps working, zero to revoke it at once
+}
@@ -0,0 +1,453 @@
+// Package admin provides operator-facing functionality for inspecting
+// and managing the running authorization server.
+package admin
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/app/client"
+	"github.com/verigate/verigate-server/internal/app/logout"
+	"github.com/verigate/verigate-server/internal/app/webhook"
+	"github.com/verigate/verigate-server/internal/pkg/config"
+	"github.com/verigate/verigate-server/internal/pkg/middleware"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler manages HTTP requests for administrative endpoints.
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new admin handler with the given service.
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the admin routes on the provided router group.
+// All routes require web authentication, a CSRF token for cookie sessions, and an administrator account.
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	// Client administration authenticates operator tooling by its client access token instead,
+	// so its group is set up before the user authentication below applies
+	clients := r.Group("/clients", middleware.AdminScope(h.service.tokenService.ValidateAccessToken, ScopeClientAdmin, config.AppConfig.AdminClientIDs))
+	clients.GET("", h.ListClients)                                // List clients of every owner
+	clients.POST("", h.CreateClient)                              // Create a client
+	clients.GET("/:id", h.GetClient)                              // Get a client
+	clients.PUT("/:id", h.UpdateClient)                           // Update a client
+	clients.DELETE("/:id", h.DeleteClient)                        // Delete a client
+	clients.POST("/:id/rotate-secret", h.RotateClientSecret)      // Rotate a client secret
+	clients.PUT("/:id/rate-limit-tier", h.SetClientRateLimitTier) // Assign a client's rate limit tier
+
+	r.Use(middleware.CSRF())
+	r.Use(middleware.WebAuth(h.service.authService))
+	r.Use(middleware.AdminOnly(config.AppConfig.AdminUserIDs))
+
+	// Revoking tokens in bulk demands a recent, and where configured a stronger, login
+	stepUp := middleware.RequireUserAuthentication(adminStepUp())
+
+	r.GET("/ratelimit", h.InspectRateLimit)                            // Inspect rate limit state
+	r.GET("/logout/deliveries", h.ListLogoutDeliveries)                // List back-channel logout deliveries
+	r.DELETE("/lockouts", h.ClearLockout)                              // Clear an account lockout
+	r.GET("/audit", h.ListAuditEvents)                                 // Query the audit trail
+	r.POST("/users/:id/revoke-tokens", stepUp, h.RevokeUserTokens)     // Revoke all tokens of a user
+	r.POST("/clients/:id/revoke-tokens", stepUp, h.RevokeClientTokens) // Revoke all tokens of a client
+	r.POST("/webhooks", h.CreateWebhook)                               // Subscribe to webhook notifications
+	r.GET("/webhooks", h.ListWebhooks)                                 // List webhook subscriptions
+	r.DELETE("/webhooks/:id", h.DeleteWebhook)                         // Remove a webhook subscription
+	r.GET("/webhooks/dead-letters", h.ListWebhookDeadLetters)          // List failed webhook deliveries
+	r.POST("/cleanup", h.PurgeExpired)                                 // Purge expired tokens, codes, and sessions
+}
+
+// adminStepUp returns the user authentication demanded of administrators revoking tokens in bulk.
+// It panics on an invalid maximum age, as the other settings are checked at startup.
+func adminStepUp() middleware.UserAuthentication {
+	maxAge, err := time.ParseDuration(config.AppConfig.AdminStepUpMaxAge)
+	if err != nil || maxAge < 0 {
+		panic("invalid admin step-up max age: " + config.AppConfig.AdminStepUpMaxAge)
+	}
+	return middleware.UserAuthentication{
+		ACRValues: config.AppConfig.AdminStepUpACRValues,
+		MaxAge:    maxAge,
+	}
+}
+
+// InspectRateLimit handles the GET request to inspect the rate limit state of a subject.
+//
+// Route: GET /api/v1/admin/ratelimit
+// Query parameters:
+//   - kind: Subject kind, either "user" or "ip"
+//   - subject: The user ID or IP address to inspect
+func (h *Handler) InspectRateLimit(c *gin.Context) {
+	var query RateLimitQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.Error(errors.BadRequest(errors.ErrMsgRateLimitSubjectRequired))
+		return
+	}
+
+	status, err := h.service.InspectRateLimit(c.Request.Context(), query.Kind, query.Subject)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// ListLogoutDeliveries handles the GET request to list back-channel logout deliveries, newest first.
+//
+// Route: GET /api/v1/admin/logout/deliveries
+// Query parameters:
+//   - status: Optional delivery status, one of "pending", "delivered", or "failed"
+//   - user_id: Optional user whose deliveries to list
+//   - limit: Optional maximum number of deliveries (default 50, at most 500)
+func (h *Handler) ListLogoutDeliveries(c *gin.Context) {
+	var query logout.DeliveryQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.Error(errors.BadRequest(errors.ErrMsgInvalidLogoutDeliveryQuery))
+		return
+	}
+
+	deliveries, err := h.service.ListLogoutDeliveries(c.Request.Context(), query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// ClearLockout handles the DELETE request to clear the lockout of a login after repeated failed attempts.
+//
+// Route: DELETE /api/v1/admin/lockouts
+// Query parameters:
+//   - email: The login email whose lockout to clear
+func (h *Handler) ClearLockout(c *gin.Context) {
+	var query LockoutQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.Error(errors.BadRequest(errors.ErrMsgLockoutEmailRequired))
+		return
+	}
+
+	adminID := c.GetUint(middleware.ContextKeyUserID)
+	if err := h.service.ClearLockout(c.Request.Context(), adminID, query.Email); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListAuditEvents handles the GET request to query the audit trail, newest first. The events are
+// returned in the list envelope, {"items", "next_cursor", "total_estimate"}; other parameters
+// than the ones below are rejected with 400 Bad Request.
+//
+// Route: GET /api/v1/admin/audit
+// Query parameters:
+//   - actor: Optional actor, such as "user:42" or "client:my-client"
+//   - type: Optional event type, such as "login.failed"
+//   - since: Optional RFC 3339 time; only events at or after it are listed
+//   - until: Optional RFC 3339 time; only events before it are listed
+//   - sort: Optional "created_at" for oldest first, or "-created_at" (the default)
+//   - cursor: Optional next_cursor of the previous page
+//   - limit: Optional maximum number of events per page (default 50, at most 500)
+func (h *Handler) ListAuditEvents(c *gin.Context) {
+	events, err := h.service.ListAuditEvents(c.Request.Context(), c.Request.URL.Query())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, events)
+}
+
+// RevokeUserTokens handles the POST request to revoke every active access and refresh token of a user.
+// Returns 200 OK with the number of revoked tokens, or 401 Unauthorized with a step-up challenge
+// when the administrator's login is older or weaker than configured.
+//
+// Route: POST /api/v1/admin/users/:id/revoke-tokens
+// Path parameters:
+//   - id: The ID of the user whose tokens to revoke
+func (h *Handler) RevokeUserTokens(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || userID == 0 {
+		c.Error(errors.BadRequest(errors.ErrMsgInvalidAdminUserID))
+		return
+	}
+
+	adminID := c.GetUint(middleware.ContextKeyUserID)
+	resp, err := h.service.RevokeUserTokens(c.Request.Context(), adminID, uint(userID))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// RevokeClientTokens handles the POST request to revoke every active access and refresh token of a client.
+// Returns 200 OK with the number of revoked tokens, or 401 Unauthorized with a step-up challenge
+// when the administrator's login is older or weaker than configured.
+//
+// Route: POST /api/v1/admin/clients/:id/revoke-tokens
+// Path parameters:
+//   - id: The client_id of the client whose tokens to revoke
+func (h *Handler) RevokeClientTokens(c *gin.Context) {
+	adminID := c.GetUint(middleware.ContextKeyUserID)
+	resp, err := h.service.RevokeClientTokens(c.Request.Context(), adminID, c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// CreateWebhook handles the POST request to subscribe a URL to webhook notifications.
+// Returns 201 Created with the subscription, including the signing secret, which is not shown again.
+//
+// Route: POST /api/v1/admin/webhooks
+// Request body:
+//   - url: HTTP or HTTPS URL the events are POSTed to
+//   - event_types: Audit event types to deliver, such as "token.revoked"
+func (h *Handler) CreateWebhook(c *gin.Context) {
+	var req webhook.CreateSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.BadRequest(errors.ErrMsgInvalidWebhookRequest))
+		return
+	}
+
+	adminID := c.GetUint(middleware.ContextKeyUserID)
+	resp, err := h.service.CreateWebhook(c.Request.Context(), adminID, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+// ListWebhooks handles the GET request to list webhook subscriptions, oldest first.
+//
+// Route: GET /api/v1/admin/webhooks
+func (h *Handler) ListWebhooks(c *gin.Context) {
+	subscriptions, err := h.service.ListWebhooks(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, subscriptions)
+}
+
+// DeleteWebhook handles the DELETE request to remove a webhook subscription.
+//
+// Route: DELETE /api/v1/admin/webhooks/:id
+// Path parameters:
+//   - id: The ID of the subscription to remove
+func (h *Handler) DeleteWebhook(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		c.Error(errors.BadRequest(errors.ErrMsgInvalidWebhookSubscriptionID))
+		return
+	}
+
+	adminID := c.GetUint(middleware.ContextKeyUserID)
+	if err := h.service.DeleteWebhook(c.Request.Context(), adminID, uint(id)); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListWebhookDeadLetters handles the GET request to list webhook deliveries that exhausted
+// their attempts, most recent first.
+//
+// Route: GET /api/v1/admin/webhooks/dead-letters
+// Query parameters:
+//   - limit: Optional maximum number of dead letters (default 50, at most 500)
+func (h *Handler) ListWebhookDeadLetters(c *gin.Context) {
+	var query webhook.DeadLetterQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.Error(errors.BadRequest(errors.ErrMsgInvalidDeadLetterQuery))
+		return
+	}
+
+	deadLetters, err := h.service.ListWebhookDeadLetters(c.Request.Context(), query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, deadLetters)
+}
+
+// PurgeExpired handles the POST request to purge expired tokens, codes, and sessions now.
+// Returns 409 Conflict if a purge is already in progress.
+//
+// Route: POST /api/v1/admin/cleanup
+func (h *Handler) PurgeExpired(c *gin.Context) {
+	adminID := c.GetUint(middleware.ContextKeyUserID)
+	resp, err := h.service.PurgeExpired(c.Request.Context(), adminID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// ListClients handles the GET request to list the clients of every owner, newest first.
+//
+// Route: GET /api/v1/admin/clients
+// Query parameters:
+//   - page: Optional page number (default 1)
+//   - limit: Optional number of clients per page (default 10, at most 100)
+//   - owner_id: Optional user whose clients to list
+//   - q: Optional case-insensitive substring of the client name or client ID
+//   - active: Optional "true" or "false" to list only active or inactive clients
+//   - confidential: Optional "true" or "false" to list only confidential or public clients
+func (h *Handler) ListClients(c *gin.Context) {
+	var query ClientQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.Error(errors.BadRequest(errors.ErrMsgInvalidClientQuery))
+		return
+	}
+
+	clients, err := h.service.ListClients(c.Request.Context(), query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, clients)
+}
+
+// CreateClient handles the POST request to create a client.
+// Returns 201 Created with the client, including its secret, which is not shown again.
+//
+// Route: POST /api/v1/admin/clients
+// Request body: the client metadata accepted by POST /clients, plus
+//   - owner_id: Optional user owning the client
+func (h *Handler) CreateClient(c *gin.Context) {
+	var req CreateClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.BadRequest(errors.ErrMsgInvalidClientAdminBody))
+		return
+	}
+
+	resp, err := h.service.CreateClient(c.Request.Context(), c.GetString(middleware.ContextKeyAdminClientID), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+// GetClient handles the GET request to read a client, without its secret.
+//
+// Route: GET /api/v1/admin/clients/:id
+// Path parameters:
+//   - id: The client_id of the client
+func (h *Handler) GetClient(c *gin.Context) {
+	resp, err := h.service.GetClient(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// UpdateClient handles the PUT request to update a client. Only the fields set in the body change.
+// Returns 204 No Content.
+//
+// Route: PUT /api/v1/admin/clients/:id
+// Path parameters:
+//   - id: The client_id of the client
+//
+// Request body: the client metadata accepted by PUT /clients/:id
+func (h *Handler) UpdateClient(c *gin.Context) {
+	var req client.UpdateClientRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.BadRequest(errors.ErrMsgInvalidClientAdminBody))
+		return
+	}
+
+	if err := h.service.UpdateClient(c.Request.Context(), c.GetString(middleware.ContextKeyAdminClientID), c.Param("id"), req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// DeleteClient handles the DELETE request to remove a client.
+// Returns 204 No Content.
+//
+// Route: DELETE /api/v1/admin/clients/:id
+// Path parameters:
+//   - id: The client_id of the client
+func (h *Handler) DeleteClient(c *gin.Context) {
+	if err := h.service.DeleteClient(c.Request.Context(), c.GetString(middleware.ContextKeyAdminClientID), c.Param("id")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// SetClientRateLimitTier handles the PUT request to assign the rate limit tier of a client,
+// applied to its requests once it has authenticated. Returns 204 No Content.
+//
+// Route: PUT /api/v1/admin/clients/:id/rate-limit-tier
+// Path parameters:
+//   - id: The client_id of the client
+//
+// Request body:
+//   - rate_limit_tier: "exempt", the top tier, a limit such as "6000/1m", or empty for the configured tier
+func (h *Handler) SetClientRateLimitTier(c *gin.Context) {
+	var req RateLimitTierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.BadRequest(errors.ErrMsgInvalidClientAdminBody))
+		return
+	}
+
+	if err := h.service.SetClientRateLimitTier(c.Request.Context(), c.GetString(middleware.ContextKeyAdminClientID), c.Param("id"), req); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RotateClientSecret handles the POST request to replace a client's secret.
+// Returns 200 OK with the new secret, which is not shown again. Tokens already issued stay valid.
+//
+// Route: POST /api/v1/admin/clients/:id/rotate-secret
+// Path parameters:
+//   - id: The client_id of the client
+//
+// Request body (optional):
+//   - grace_period: Seconds the old secret keeps working alongside the new one (default 0)
+func (h *Handler) RotateClientSecret(c *gin.Context) {
+	var req RotateSecretRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(errors.BadRequest(errors.ErrMsgInvalidClientAdminBody))
+			return
+		}
+	}
+
+	resp, err := h.service.RotateClientSecret(c.Request.Context(), c.GetString(middleware.ContextKeyAdminClientID), c.Param("id"), req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
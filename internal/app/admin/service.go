@@ -0,0 +1,307 @@
+// Package admin provides operator-facing functionality for inspecting
+// and managing the running authorization server.
+package admin
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/app/audit"
+	"github.com/verigate/verigate-server/internal/app/auth"
+	"github.com/verigate/verigate-server/internal/app/cleanup"
+	"github.com/verigate/verigate-server/internal/app/client"
+	"github.com/verigate/verigate-server/internal/app/logout"
+	"github.com/verigate/verigate-server/internal/app/token"
+	"github.com/verigate/verigate-server/internal/app/user"
+	"github.com/verigate/verigate-server/internal/app/webhook"
+	"github.com/verigate/verigate-server/internal/pkg/pagination"
+)
+
+// RateLimitInspector defines the read-only view of rate limit state
+// needed by the admin service.
+type RateLimitInspector interface {
+	// Inspect returns the request count and reset time for a subject without consuming quota
+	Inspect(ctx context.Context, subjectKind, subject string) (int, time.Time, error)
+}
+
+// Service handles administrative operations for server operators.
+type Service struct {
+	rateLimiter    RateLimitInspector
+	authService    *auth.Service
+	logoutService  *logout.Service
+	userService    *user.Service
+	tokenService   *token.Service
+	auditService   *audit.Service
+	webhookService *webhook.Service
+	cleanupService *cleanup.Service
+	clientService  *client.Service
+}
+
+// NewService creates a new admin service instance.
+// It requires a rate limit inspector, an auth service for authenticating operators,
+// a logout service for reporting back-channel logout deliveries, a user service
+// for managing account lockouts, a token service for bulk token revocation,
+// an audit service for recording and querying the audit trail,
+// a webhook service for managing webhook subscriptions,
+// a cleanup service for purging expired data on demand,
+// and a client service for managing clients of every owner.
+func NewService(rateLimiter RateLimitInspector, authService *auth.Service, logoutService *logout.Service, userService *user.Service, tokenService *token.Service, auditService *audit.Service, webhookService *webhook.Service, cleanupService *cleanup.Service, clientService *client.Service) *Service {
+	return &Service{
+		rateLimiter:    rateLimiter,
+		authService:    authService,
+		logoutService:  logoutService,
+		userService:    userService,
+		tokenService:   tokenService,
+		auditService:   auditService,
+		webhookService: webhookService,
+		cleanupService: cleanupService,
+		clientService:  clientService,
+	}
+}
+
+// ClearLockout lifts the lockout of a login after repeated failed attempts
+// and forgets its failure history, so the next lockout starts from the base duration.
+// The action is recorded in the audit trail under the administrator's user ID.
+func (s *Service) ClearLockout(ctx context.Context, adminID uint, email string) error {
+	err := s.userService.ClearLockout(ctx, email)
+
+	event := audit.Event{
+		Type:    audit.EventAdminAction,
+		Actor:   audit.UserActor(adminID),
+		Details: map[string]string{"action": "clear_lockout", "login": user.NormalizeLogin(email)},
+	}
+	if err != nil {
+		event.Outcome = audit.OutcomeFailure
+	}
+	s.auditService.Record(ctx, event)
+
+	return err
+}
+
+// RevokeUserTokens revokes every active access and refresh token of a user,
+// for example when the user leaves the organization.
+// The action is recorded in the audit trail under the administrator's user ID.
+func (s *Service) RevokeUserTokens(ctx context.Context, adminID, userID uint) (*token.BulkRevocationResponse, error) {
+	resp, err := s.tokenService.RevokeAllUserTokens(ctx, userID)
+	s.recordBulkRevocation(ctx, adminID, "", map[string]string{
+		"action":  "revoke_user_tokens",
+		"user_id": strconv.FormatUint(uint64(userID), 10),
+	}, resp, err)
+	return resp, err
+}
+
+// RevokeClientTokens revokes every active access and refresh token of a client,
+// for example when the client's credentials are compromised.
+// The action is recorded in the audit trail under the administrator's user ID.
+func (s *Service) RevokeClientTokens(ctx context.Context, adminID uint, clientID string) (*token.BulkRevocationResponse, error) {
+	resp, err := s.tokenService.RevokeAllClientTokens(ctx, clientID)
+	s.recordBulkRevocation(ctx, adminID, clientID, map[string]string{"action": "revoke_client_tokens"}, resp, err)
+	return resp, err
+}
+
+// recordBulkRevocation records a bulk token revocation in the audit trail,
+// with the number of revoked tokens when it succeeded.
+func (s *Service) recordBulkRevocation(ctx context.Context, adminID uint, clientID string, details map[string]string, resp *token.BulkRevocationResponse, err error) {
+	event := audit.Event{
+		Type:     audit.EventAdminAction,
+		Actor:    audit.UserActor(adminID),
+		ClientID: clientID,
+		Details:  details,
+	}
+	if err != nil {
+		event.Outcome = audit.OutcomeFailure
+	} else {
+		details["access_tokens_revoked"] = strconv.Itoa(resp.AccessTokensRevoked)
+		details["refresh_tokens_revoked"] = strconv.FormatInt(resp.RefreshTokensRevoked, 10)
+	}
+	s.auditService.Record(ctx, event)
+}
+
+// ListAuditEvents returns the page of the audit trail the query parameters request.
+func (s *Service) ListAuditEvents(ctx context.Context, query url.Values) (*pagination.Page[audit.Event], error) {
+	return s.auditService.ListEvents(ctx, query)
+}
+
+// ListLogoutDeliveries returns recent back-channel logout deliveries,
+// so operators can see which clients failed to acknowledge a logout.
+func (s *Service) ListLogoutDeliveries(ctx context.Context, query logout.DeliveryQuery) (*logout.DeliveryListResponse, error) {
+	return s.logoutService.ListDeliveries(ctx, query)
+}
+
+// CreateWebhook subscribes a URL to webhook notifications of the given event types.
+// The response carries the signing secret, which is not shown again.
+// The action is recorded in the audit trail under the administrator's user ID.
+func (s *Service) CreateWebhook(ctx context.Context, adminID uint, req webhook.CreateSubscriptionRequest) (*webhook.SubscriptionResponse, error) {
+	resp, err := s.webhookService.CreateSubscription(ctx, req)
+
+	event := audit.Event{
+		Type:    audit.EventAdminAction,
+		Actor:   audit.UserActor(adminID),
+		Details: map[string]string{"action": "create_webhook", "url": req.URL},
+	}
+	if err != nil {
+		event.Outcome = audit.OutcomeFailure
+	} else {
+		event.Details["webhook_id"] = strconv.FormatUint(uint64(resp.ID), 10)
+	}
+	s.auditService.Record(ctx, event)
+
+	return resp, err
+}
+
+// ListWebhooks returns every webhook subscription, without their secrets.
+func (s *Service) ListWebhooks(ctx context.Context) (*webhook.SubscriptionListResponse, error) {
+	return s.webhookService.ListSubscriptions(ctx)
+}
+
+// DeleteWebhook removes a webhook subscription. Deliveries still queued for it are dropped.
+// The action is recorded in the audit trail under the administrator's user ID.
+func (s *Service) DeleteWebhook(ctx context.Context, adminID, id uint) error {
+	err := s.webhookService.DeleteSubscription(ctx, id)
+
+	event := audit.Event{
+		Type:    audit.EventAdminAction,
+		Actor:   audit.UserActor(adminID),
+		Details: map[string]string{"action": "delete_webhook", "webhook_id": strconv.FormatUint(uint64(id), 10)},
+	}
+	if err != nil {
+		event.Outcome = audit.OutcomeFailure
+	}
+	s.auditService.Record(ctx, event)
+
+	return err
+}
+
+// ListWebhookDeadLetters returns the webhook deliveries that exhausted their attempts, most recent first.
+func (s *Service) ListWebhookDeadLetters(ctx context.Context, query webhook.DeadLetterQuery) (*webhook.DeadLetterListResponse, error) {
+	return s.webhookService.ListDeadLetters(ctx, query)
+}
+
+// PurgeExpired deletes the expired tokens, codes, and sessions of the tenant right away instead
+// of waiting for the next scheduled purge. Returns Conflict if a purge is already in progress.
+// The action is recorded in the audit trail under the administrator's user ID.
+func (s *Service) PurgeExpired(ctx context.Context, adminID uint) (*cleanup.PurgeResponse, error) {
+	resp, err := s.cleanupService.Purge(ctx)
+
+	event := audit.Event{
+		Type:    audit.EventAdminAction,
+		Actor:   audit.UserActor(adminID),
+		Details: map[string]string{"action": "purge_expired"},
+	}
+	if resp != nil {
+		event.Details["purged"] = strconv.FormatInt(resp.Total, 10)
+	}
+	if err != nil {
+		event.Outcome = audit.OutcomeFailure
+	}
+	s.auditService.Record(ctx, event)
+
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// InspectRateLimit returns the current rate limit state for the given subject.
+// The lookup is read-only and does not count against the subject's quota.
+func (s *Service) InspectRateLimit(ctx context.Context, kind, subject string) (*RateLimitStatusResponse, error) {
+	count, resetAt, err := s.rateLimiter.Inspect(ctx, kind, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RateLimitStatusResponse{
+		Kind:    kind,
+		Subject: subject,
+		Count:   count,
+		ResetAt: resetAt,
+	}, nil
+}
+
+// ListClients returns a page of the clients of every owner matching the query, newest first.
+func (s *Service) ListClients(ctx context.Context, query ClientQuery) (*client.ClientListResponse, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Limit < 1 || query.Limit > 100 {
+		query.Limit = 10
+	}
+
+	filter := client.ClientFilter{
+		OwnerID:        query.OwnerID,
+		Query:          query.Query,
+		IsActive:       query.Active,
+		IsConfidential: query.Confidential,
+	}
+	return s.clientService.AdminList(ctx, filter, query.Page, query.Limit)
+}
+
+// GetClient returns a client whoever owns it, without its secret.
+func (s *Service) GetClient(ctx context.Context, clientID string) (*client.ClientResponse, error) {
+	return s.clientService.AdminGet(ctx, clientID)
+}
+
+// CreateClient registers a client owned by the given user, or by no one.
+// The response carries the client secret, which is not shown again.
+// The action is recorded in the audit trail under the administration client's ID.
+func (s *Service) CreateClient(ctx context.Context, adminClientID string, req CreateClientRequest) (*client.ClientResponse, error) {
+	resp, err := s.clientService.Create(ctx, req.OwnerID, req.CreateClientRequest)
+
+	createdID := ""
+	if resp != nil {
+		createdID = resp.ClientID
+	}
+	s.recordClientAdmin(ctx, adminClientID, createdID, "create_client", err)
+
+	return resp, err
+}
+
+// UpdateClient modifies a client whoever owns it. Only the fields set in the request change.
+// The action is recorded in the audit trail under the administration client's ID.
+func (s *Service) UpdateClient(ctx context.Context, adminClientID, clientID string, req client.UpdateClientRequest) error {
+	err := s.clientService.AdminUpdate(ctx, clientID, req)
+	s.recordClientAdmin(ctx, adminClientID, clientID, "update_client", err)
+	return err
+}
+
+// DeleteClient removes a client whoever owns it.
+// The action is recorded in the audit trail under the administration client's ID.
+func (s *Service) DeleteClient(ctx context.Context, adminClientID, clientID string) error {
+	err := s.clientService.AdminDelete(ctx, clientID)
+	s.recordClientAdmin(ctx, adminClientID, clientID, "delete_client", err)
+	return err
+}
+
+// RotateClientSecret replaces a client's secret, keeping the old one valid for the grace period.
+// Tokens already issued to the client are left untouched. The response carries the new secret,
+// which is not shown again.
+// The action is recorded in the audit trail under the administration client's ID.
+func (s *Service) RotateClientSecret(ctx context.Context, adminClientID, clientID string, req RotateSecretRequest) (*client.SecretRotationResponse, error) {
+	resp, err := s.clientService.RotateSecret(ctx, clientID, time.Duration(req.GracePeriod)*time.Second)
+	s.recordClientAdmin(ctx, adminClientID, clientID, "rotate_client_secret", err)
+	return resp, err
+}
+
+// SetClientRateLimitTier assigns the rate limit tier of a client, which may exempt it from rate
+// limiting altogether. The action is recorded in the audit trail under the administration client's ID.
+func (s *Service) SetClientRateLimitTier(ctx context.Context, adminClientID, clientID string, req RateLimitTierRequest) error {
+	err := s.clientService.SetRateLimitTier(ctx, clientID, req.RateLimitTier)
+	s.recordClientAdmin(ctx, adminClientID, clientID, "set_client_rate_limit_tier", err)
+	return err
+}
+
+// recordClientAdmin records a client administration action in the audit trail.
+func (s *Service) recordClientAdmin(ctx context.Context, adminClientID, clientID, action string, err error) {
+	event := audit.Event{
+		Type:     audit.EventAdminAction,
+		Actor:    audit.ClientActor(adminClientID),
+		ClientID: clientID,
+		Details:  map[string]string{"action": action},
+	}
+	if err != nil {
+		event.Outcome = audit.OutcomeFailure
+	}
+	s.auditService.Record(ctx, event)
+}
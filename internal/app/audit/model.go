@@ -0,0 +1,115 @@
+// Package audit records security-relevant events, such as token issuance, failed logins,
+// and administrative actions, in an append-only audit trail.
+package audit
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/pkg/pagination"
+	"github.com/verigate/verigate-server/internal/pkg/utils/hash"
+)
+
+// Event types
+const (
+	EventTokenIssued      = "token.issued"      // The token endpoint issued tokens, or refused to
+	EventTokenRevoked     = "token.revoked"     // A token was revoked
+	EventLoginSucceeded   = "login.succeeded"   // A user completed every login factor
+	EventLoginFailed      = "login.failed"      // A login was rejected
+	EventClientRegistered = "client.registered" // A client was registered
+	EventConsentGranted   = "consent.granted"   // A user granted scopes to a client
+	EventConsentRevoked   = "consent.revoked"   // A user's grant to a client was revoked
+	EventAdminAction      = "admin.action"      // An administrator changed server state
+	EventSessionEnded     = "session.ended"     // A user's web session was ended before it expired
+	EventAccountLocked    = "account.locked"    // A login was locked after repeated failures
+)
+
+// EventTypes lists every event type, in documented order
+var EventTypes = []string{
+	EventTokenIssued,
+	EventTokenRevoked,
+	EventLoginSucceeded,
+	EventLoginFailed,
+	EventClientRegistered,
+	EventConsentGranted,
+	EventConsentRevoked,
+	EventAdminAction,
+	EventSessionEnded,
+	EventAccountLocked,
+}
+
+// IsValidEventType reports whether eventType is a known event type.
+func IsValidEventType(eventType string) bool {
+	for _, t := range EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Event outcomes
+const (
+	OutcomeSuccess = "success" // The action was carried out
+	OutcomeFailure = "failure" // The action was refused or failed
+)
+
+// Event is a recorded security-relevant event. Events are never updated once recorded.
+// Details must not hold secrets such as tokens; record them with HashValue instead.
+type Event struct {
+	ID        uint              `json:"id"`                   // Primary key, or line number for the file store
+	Type      string            `json:"type"`                 // What happened, one of the Event constants
+	Actor     string            `json:"actor,omitempty"`      // Who acted, see UserActor and ClientActor
+	ClientID  string            `json:"client_id,omitempty"`  // Client involved in the event
+	IPAddress string            `json:"ip_address,omitempty"` // Address of the client that made the request
+	Outcome   string            `json:"outcome"`              // success or failure
+	Details   map[string]string `json:"details,omitempty"`    // Event-specific context
+	CreatedAt time.Time         `json:"created_at"`           // When the event happened
+}
+
+// EventListSpec declares how the audit trail may be filtered and sorted when listed. Only the
+// indexed columns are offered, the actor and event type each leading an index with created_at.
+var EventListSpec = pagination.Spec{
+	Filters: []pagination.Filter{
+		{Param: "actor", Column: "actor", Op: pagination.OpEqual, Kind: pagination.KindString},
+		{Param: "type", Column: "event_type", Op: pagination.OpEqual, Kind: pagination.KindString},
+		{Param: "since", Column: "created_at", Op: pagination.OpAtLeast, Kind: pagination.KindTime},
+		{Param: "until", Column: "created_at", Op: pagination.OpBefore, Kind: pagination.KindTime},
+	},
+	Sorts:        []pagination.Sort{{Name: "created_at", Column: "created_at", Kind: pagination.KindTime}},
+	DefaultSort:  "-created_at",
+	DefaultLimit: 50,
+	MaxLimit:     500,
+}
+
+// EventValue returns the value an event holds in a column of EventListSpec, for stores that
+// page through events in memory and to build the cursors of pages.
+func EventValue(e Event, column string) interface{} {
+	switch column {
+	case "id":
+		return int64(e.ID)
+	case "actor":
+		return e.Actor
+	case "event_type":
+		return e.Type
+	case "created_at":
+		return e.CreatedAt
+	}
+	return nil
+}
+
+// UserActor identifies a user as the actor of an event.
+func UserActor(userID uint) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// ClientActor identifies a client acting on its own behalf as the actor of an event.
+func ClientActor(clientID string) string {
+	return "client:" + clientID
+}
+
+// HashValue returns the SHA-256 hash of a sensitive value, so an event can refer to a token
+// or secret without recording it. The same value always yields the same hash.
+func HashValue(value string) string {
+	return hash.HashToken(value)
+}
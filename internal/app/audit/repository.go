@@ -0,0 +1,17 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/verigate/verigate-server/internal/pkg/pagination"
+)
+
+// Repository defines the interface for audit event storage.
+// Stores are append-only: events can be added and queried but never changed or removed.
+type Repository interface {
+	// Save appends an event and sets its ID
+	Save(ctx context.Context, event *Event) error
+
+	// List retrieves the requested page of events, validated against EventListSpec
+	List(ctx context.Context, page *pagination.Request) (*pagination.Page[Event], error)
+}
@@ -0,0 +1,134 @@
+package audit
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/pkg/config"
+	"github.com/verigate/verigate-server/internal/pkg/metrics"
+	"github.com/verigate/verigate-server/internal/pkg/middleware"
+	"github.com/verigate/verigate-server/internal/pkg/pagination"
+	"github.com/verigate/verigate-server/internal/pkg/tenant"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+)
+
+// Service records audit events without blocking the request path: events are queued
+// and written to the store by a background worker.
+type Service struct {
+	repo      Repository
+	events    chan queuedEvent
+	done      chan struct{}
+	listeners []Listener
+}
+
+// Listener is notified of recorded events, such as to forward them to external systems.
+type Listener interface {
+	// Notify is called by the worker for every event it writes, with the context of the
+	// event's tenant. It runs before the next event is written, so it must not block for long.
+	Notify(ctx context.Context, event Event)
+}
+
+// queuedEvent is an event waiting to be written, with the context of the tenant it was recorded for.
+type queuedEvent struct {
+	ctx   context.Context
+	event *Event
+}
+
+// NewService creates a new audit service instance writing to the given store.
+// Events are only written once Start has launched the worker.
+func NewService(repo Repository) *Service {
+	return &Service{
+		repo:   repo,
+		events: make(chan queuedEvent, max(config.AppConfig.AuditQueueSize, 1)),
+		done:   make(chan struct{}),
+	}
+}
+
+// Start launches the worker writing queued events. When ctx is cancelled the worker
+// writes the events still queued and stops; Wait blocks until it has.
+func (s *Service) Start(ctx context.Context) {
+	go s.work(ctx)
+}
+
+// AddListener registers a listener notified of every event written from now on.
+// Listeners must be added before Start.
+func (s *Service) AddListener(listener Listener) {
+	s.listeners = append(s.listeners, listener)
+}
+
+// Wait blocks until the worker started by Start has written the queued events and stopped.
+func (s *Service) Wait() {
+	<-s.done
+}
+
+// Record queues an event for the audit trail and returns immediately.
+// The event is timestamped now, attributed to the client IP of the request in ctx unless
+// it names one, and counted as a success unless it names an outcome.
+func (s *Service) Record(ctx context.Context, event Event) {
+	event.CreatedAt = time.Now()
+	if event.IPAddress == "" {
+		event.IPAddress = middleware.ClientIPFromContext(ctx)
+	}
+	if event.Outcome == "" {
+		event.Outcome = OutcomeSuccess
+	}
+
+	queued := queuedEvent{ctx: tenant.Detach(ctx), event: &event}
+	select {
+	case s.events <- queued:
+	default:
+		// Queue is full, wait for a free slot in the background
+		go func() { s.events <- queued }()
+	}
+}
+
+// ListEvents returns a page of the audit trail, filtered, sorted, and paged as the query
+// parameters request within EventListSpec; by default the newest events come first.
+func (s *Service) ListEvents(ctx context.Context, query url.Values) (*pagination.Page[Event], error) {
+	page, err := pagination.Parse(query, EventListSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	since, hasSince := page.Value("since")
+	until, hasUntil := page.Value("until")
+	if hasSince && hasUntil && !until.(time.Time).After(since.(time.Time)) {
+		return nil, errors.BadRequest(errors.ErrMsgInvalidAuditTimeRange)
+	}
+
+	return s.repo.List(ctx, page)
+}
+
+// work writes queued events until ctx is cancelled, then writes what is still queued.
+func (s *Service) work(ctx context.Context) {
+	defer close(s.done)
+
+	for {
+		select {
+		case queued := <-s.events:
+			s.write(queued)
+		case <-ctx.Done():
+			for {
+				select {
+				case queued := <-s.events:
+					s.write(queued)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// write saves an event to the store of its tenant and notifies the listeners. The request
+// that caused the event has moved on, so a failed write can only be counted; the listeners
+// are notified either way.
+func (s *Service) write(queued queuedEvent) {
+	if err := s.repo.Save(queued.ctx, queued.event); err != nil {
+		metrics.AuditWriteFailures.Inc()
+	}
+	for _, listener := range s.listeners {
+		listener.Notify(queued.ctx, *queued.event)
+	}
+}
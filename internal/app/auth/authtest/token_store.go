@@ -0,0 +1,281 @@
+// Package authtest provides a conformance suite for implementations of auth.TokenStore, run by
+// the tests of every backend so that each honours the same contract.
+package authtest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/app/auth"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+	"github.com/verigate/verigate-server/internal/pkg/utils/hash"
+)
+
+// Users the suite issues tokens to; stores with referential integrity must know them
+const (
+	UserID      uint = 1
+	OtherUserID uint = 2
+)
+
+// expiringLifetime is the lifetime of the tokens the suite waits out
+const expiringLifetime = 300 * time.Millisecond
+
+// Store is a token store under test.
+type Store struct {
+	auth.TokenStore
+
+	// Elapse, when set, advances the clock of a store that expires tokens by a clock of its own,
+	// such as an in-process Redis server. The suite calls it after waiting d in real time.
+	Elapse func(d time.Duration)
+}
+
+// RunTokenStoreTests runs the conformance suite, calling newStore for an empty store in every test.
+func RunTokenStoreTests(t *testing.T, newStore func(t *testing.T) Store) {
+	t.Run("SaveAndFind", func(t *testing.T) { testSaveAndFind(t, newStore(t)) })
+	t.Run("Expiry", func(t *testing.T) { testExpiry(t, newStore(t)) })
+	t.Run("IdempotentRevocation", func(t *testing.T) { testIdempotentRevocation(t, newStore(t)) })
+	t.Run("ConcurrentRotation", func(t *testing.T) { testConcurrentRotation(t, newStore(t)) })
+	t.Run("RevokeAllUserRefreshTokens", func(t *testing.T) { testRevokeAllUserRefreshTokens(t, newStore(t)) })
+	t.Run("DeleteSessionRefreshTokens", func(t *testing.T) { testDeleteSessionRefreshTokens(t, newStore(t)) })
+}
+
+// newToken returns an unrevoked token of the user living for lifetime from now. Its stored value
+// is a placeholder, which no plain text token matches.
+func newToken(id string, userID uint, lifetime time.Duration) *auth.RefreshToken {
+	now := time.Now()
+	return &auth.RefreshToken{
+		ID:        id,
+		UserID:    userID,
+		Token:     "unhashed-" + id,
+		ExpiresAt: now.Add(lifetime),
+		CreatedAt: now,
+		AuthTime:  now,
+	}
+}
+
+// save stores the token, failing the test on error.
+func save(t *testing.T, store Store, token *auth.RefreshToken) {
+	t.Helper()
+	if err := store.SaveRefreshToken(context.Background(), token); err != nil {
+		t.Fatalf("SaveRefreshToken(%s) failed: %v", token.ID, err)
+	}
+}
+
+// find looks up the token by ID, failing the test on error.
+func find(t *testing.T, store Store, tokenID string) *auth.RefreshToken {
+	t.Helper()
+	token, err := store.FindRefreshToken(context.Background(), tokenID)
+	if err != nil {
+		t.Fatalf("FindRefreshToken(%s) failed: %v", tokenID, err)
+	}
+	return token
+}
+
+// isRevoked reports whether the store considers the token revoked, failing the test on error.
+func isRevoked(t *testing.T, store Store, tokenID string) bool {
+	t.Helper()
+	revoked, err := store.IsRefreshTokenRevoked(context.Background(), tokenID)
+	if err != nil {
+		t.Fatalf("IsRefreshTokenRevoked(%s) failed: %v", tokenID, err)
+	}
+	return revoked
+}
+
+func testSaveAndFind(t *testing.T, store Store) {
+	ctx := context.Background()
+
+	token := newToken("token-1", UserID, time.Hour)
+	token.SessionID = "session-1"
+	var err error
+	if token.Token, err = hash.HashPassword("secret-1"); err != nil {
+		t.Fatalf("HashPassword failed: %v", err)
+	}
+	save(t, store, token)
+
+	found := find(t, store, token.ID)
+	if found == nil || found.UserID != token.UserID || found.Token != token.Token || found.SessionID != token.SessionID || found.IsRevoked {
+		t.Fatalf("FindRefreshToken = %+v, want %+v", found, token)
+	}
+
+	byValue, err := store.FindRefreshTokenByToken(ctx, "secret-1")
+	if err != nil || byValue == nil || byValue.ID != token.ID {
+		t.Errorf("FindRefreshTokenByToken = %+v, %v, want token %s", byValue, err, token.ID)
+	}
+	if byValue, err := store.FindRefreshTokenByToken(ctx, "other-secret"); err != nil || byValue != nil {
+		t.Errorf("FindRefreshTokenByToken of an unknown value = %+v, %v, want nil", byValue, err)
+	}
+
+	if missing := find(t, store, "missing"); missing != nil {
+		t.Errorf("FindRefreshToken of a missing token = %+v, want nil", missing)
+	}
+	if !isRevoked(t, store, "missing") {
+		t.Error("a missing token is not reported revoked")
+	}
+}
+
+func testExpiry(t *testing.T, store Store) {
+	ctx := context.Background()
+
+	// Hashed first, so the token's lifetime starts when it is saved
+	hashed, err := hash.HashPassword("expiring-secret")
+	if err != nil {
+		t.Fatalf("HashPassword failed: %v", err)
+	}
+	expiring := newToken("expiring", UserID, expiringLifetime)
+	expiring.Token = hashed
+	save(t, store, expiring)
+	save(t, store, newToken("live", UserID, time.Hour))
+
+	wait := expiringLifetime + 200*time.Millisecond
+	time.Sleep(wait)
+	if store.Elapse != nil {
+		store.Elapse(wait)
+	}
+
+	if found, err := store.FindRefreshTokenByToken(ctx, "expiring-secret"); err != nil || found != nil {
+		t.Errorf("FindRefreshTokenByToken of an expired token = %+v, %v, want nil", found, err)
+	}
+	rotated, err := store.RotateRefreshToken(ctx, expiring.ID, newToken("successor", UserID, time.Hour))
+	if err != nil || rotated {
+		t.Errorf("RotateRefreshToken of an expired token = %v, %v, want false", rotated, err)
+	}
+	if successor := find(t, store, "successor"); successor != nil {
+		t.Error("rotating an expired token saved its successor")
+	}
+
+	if err := store.DeleteExpiredTokens(ctx); err != nil {
+		t.Fatalf("DeleteExpiredTokens failed: %v", err)
+	}
+	if found := find(t, store, expiring.ID); found != nil {
+		t.Errorf("expired token still found after DeleteExpiredTokens: %+v", found)
+	}
+	if !isRevoked(t, store, expiring.ID) {
+		t.Error("a deleted expired token is not reported revoked")
+	}
+	if live := find(t, store, "live"); live == nil {
+		t.Error("DeleteExpiredTokens deleted an unexpired token")
+	}
+}
+
+func testIdempotentRevocation(t *testing.T, store Store) {
+	ctx := context.Background()
+
+	token := newToken("token-1", UserID, time.Hour)
+	save(t, store, token)
+
+	for i := 1; i <= 2; i++ {
+		if err := store.RevokeRefreshToken(ctx, token.ID); err != nil {
+			t.Fatalf("revocation %d failed: %v", i, err)
+		}
+		if !isRevoked(t, store, token.ID) {
+			t.Errorf("token not revoked after revocation %d", i)
+		}
+	}
+	if found := find(t, store, token.ID); found == nil || !found.IsRevoked {
+		t.Errorf("FindRefreshToken = %+v, want the revoked token", found)
+	}
+
+	err := store.RevokeRefreshToken(ctx, "missing")
+	if customErr, ok := errors.As(err); !ok || customErr.Code() != errors.ErrMsgRefreshTokenNotFound {
+		t.Errorf("revoking a missing token: got error %v, want %q", err, errors.ErrMsgRefreshTokenNotFound)
+	}
+
+	rotated, err := store.RotateRefreshToken(ctx, token.ID, newToken("successor", UserID, time.Hour))
+	if err != nil || rotated {
+		t.Errorf("RotateRefreshToken of a revoked token = %v, %v, want false", rotated, err)
+	}
+}
+
+func testConcurrentRotation(t *testing.T, store Store) {
+	const rotations = 10
+	ctx := context.Background()
+
+	old := newToken("old", UserID, time.Hour)
+	save(t, store, old)
+
+	// Release every rotation at once, so they race for the same token
+	start := make(chan struct{})
+	results := make([]bool, rotations)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			rotated, err := store.RotateRefreshToken(ctx, old.ID, newToken(fmt.Sprintf("successor-%d", i), UserID, time.Hour))
+			if err != nil {
+				t.Errorf("rotation %d failed: %v", i, err)
+			}
+			results[i] = rotated
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	succeeded := 0
+	for i, rotated := range results {
+		successor := find(t, store, fmt.Sprintf("successor-%d", i))
+		if rotated {
+			succeeded++
+			if successor == nil || successor.IsRevoked {
+				t.Errorf("successful rotation %d left successor %+v, want it saved unrevoked", i, successor)
+			}
+		} else if successor != nil {
+			t.Errorf("failed rotation %d saved its successor", i)
+		}
+	}
+	if succeeded != 1 {
+		t.Errorf("%d of %d concurrent rotations succeeded, want exactly 1", succeeded, rotations)
+	}
+	if !isRevoked(t, store, old.ID) {
+		t.Error("rotated token is not revoked")
+	}
+}
+
+func testRevokeAllUserRefreshTokens(t *testing.T, store Store) {
+	save(t, store, newToken("user-1", UserID, time.Hour))
+	save(t, store, newToken("user-2", UserID, time.Hour))
+	save(t, store, newToken("other-user", OtherUserID, time.Hour))
+
+	if err := store.RevokeAllUserRefreshTokens(context.Background(), UserID); err != nil {
+		t.Fatalf("RevokeAllUserRefreshTokens failed: %v", err)
+	}
+
+	for _, tokenID := range []string{"user-1", "user-2"} {
+		if !isRevoked(t, store, tokenID) {
+			t.Errorf("token %s of the user is not revoked", tokenID)
+		}
+	}
+	if isRevoked(t, store, "other-user") {
+		t.Error("token of another user was revoked")
+	}
+}
+
+func testDeleteSessionRefreshTokens(t *testing.T, store Store) {
+	inSession := newToken("in-session", UserID, time.Hour)
+	inSession.SessionID = "session-1"
+	otherSession := newToken("other-session", UserID, time.Hour)
+	otherSession.SessionID = "session-2"
+	otherUser := newToken("other-user", OtherUserID, time.Hour)
+	otherUser.SessionID = "session-1"
+	for _, token := range []*auth.RefreshToken{inSession, otherSession, otherUser} {
+		save(t, store, token)
+	}
+
+	if err := store.DeleteSessionRefreshTokens(context.Background(), UserID, "session-1"); err != nil {
+		t.Fatalf("DeleteSessionRefreshTokens failed: %v", err)
+	}
+
+	if found := find(t, store, inSession.ID); found != nil {
+		t.Errorf("token of the session still found: %+v", found)
+	}
+	if found := find(t, store, otherSession.ID); found == nil || found.IsRevoked {
+		t.Errorf("token of another session = %+v, want it kept", found)
+	}
+	if found := find(t, store, otherUser.ID); found == nil || found.IsRevoked {
+		t.Errorf("token of another user = %+v, want it kept", found)
+	}
+}
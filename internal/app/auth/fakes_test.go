@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Fakes implement the methods the tests reach; the embedded interface of each leaves any other
+// method nil, so a test reaching one fails loudly.
+
+// fakeSessionRepository is an in-memory SessionRepository recording the time to live each
+// session was last given.
+type fakeSessionRepository struct {
+	SessionRepository
+	mu       sync.Mutex
+	sessions map[string]*Session
+	ttls     map[string]time.Duration
+
+	expireBeforeTouch bool // Drops a session between its lookup and its renewal
+}
+
+// newFakeSessionRepository returns a repository holding copies of the sessions.
+func newFakeSessionRepository(sessions ...*Session) *fakeSessionRepository {
+	r := &fakeSessionRepository{sessions: make(map[string]*Session), ttls: make(map[string]time.Duration)}
+	for _, session := range sessions {
+		stored := *session
+		r.sessions[session.ID] = &stored
+	}
+	return r
+}
+
+// FindSession returns a copy of the session, or nil if it does not exist.
+func (r *fakeSessionRepository) FindSession(ctx context.Context, id string) (*Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	found := *stored
+	return &found, nil
+}
+
+// TouchSession records the time to live, reporting false if the session does not exist.
+func (r *fakeSessionRepository) TouchSession(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.expireBeforeTouch {
+		delete(r.sessions, id)
+	}
+	if _, ok := r.sessions[id]; !ok {
+		return false, nil
+	}
+	r.ttls[id] = ttl
+	return true, nil
+}
+
+// DeleteSession removes the session.
+func (r *fakeSessionRepository) DeleteSession(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.sessions, id)
+	return nil
+}
+
+// state reports whether the session still exists and the time to live it was last given.
+func (r *fakeSessionRepository) state(id string) (exists bool, ttl time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, exists = r.sessions[id]
+	return exists, r.ttls[id]
+}
@@ -16,6 +16,8 @@ type RefreshToken struct {
 	Token     string    `json:"token"`                // Hashed token value, stored in Redis but not returned to clients
 	ExpiresAt time.Time `json:"expires_at"`           // Expiration timestamp
 	CreatedAt time.Time `json:"created_at"`           // Creation timestamp
+	AuthTime  time.Time `json:"auth_time"`            // When the user authenticated, kept across rotations
+	SessionID string    `json:"session_id,omitempty"` // Session the token belongs to, kept across rotations
 	IsRevoked bool      `json:"is_revoked"`           // Whether the token has been revoked
 	UserAgent string    `json:"user_agent,omitempty"` // Client user agent for audit
 	IPAddress string    `json:"ip_address,omitempty"` // Client IP address for audit
@@ -29,3 +31,56 @@ type TokenPair struct {
 	AccessTokenExpiresAt  time.Time `json:"access_token_expires_at"`  // When the access token expires
 	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"` // When the refresh token expires
 }
+
+// Authentication context class references recorded on a web session, naming how the user logged in
+const (
+	ACRPassword = "pwd" // Password only
+	ACRMFA      = "mfa" // Password and a second factor
+	ACRPasskey  = "hwk" // Passkey (hardware-bound key)
+)
+
+// Authentication method references reported in the amr claim of ID tokens (RFC 8176 Section 2)
+const (
+	AMRPassword    = "pwd" // Password
+	AMROTP         = "otp" // One-time password, including recovery codes
+	AMRMFA         = "mfa" // More than one factor
+	AMRHardwareKey = "hwk" // Proof of possession of a hardware-secured key
+)
+
+// AuthenticationMethods returns the amr values of a session authenticated with acr,
+// or nil if acr is unknown.
+func AuthenticationMethods(acr string) []string {
+	switch acr {
+	case ACRPassword:
+		return []string{AMRPassword}
+	case ACRMFA:
+		return []string{AMRPassword, AMROTP, AMRMFA}
+	case ACRPasskey:
+		return []string{AMRHardwareKey}
+	}
+	return nil
+}
+
+// Session is a server-side web session, referenced by the session cookie.
+// The cookie carries only the random ID; everything else stays in the store.
+type Session struct {
+	ID                string    `json:"id"`                   // Secret random identifier, the session cookie value
+	SID               string    `json:"sid"`                  // Public session ID, shared with the session's tokens and sent to clients as sid
+	UserID            uint      `json:"user_id"`              // User the session belongs to
+	AuthTime          time.Time `json:"auth_time"`            // When the user authenticated
+	ACR               string    `json:"acr"`                  // How the user authenticated
+	CreatedAt         time.Time `json:"created_at"`           // Creation timestamp
+	AbsoluteExpiresAt time.Time `json:"absolute_expires_at"`  // When the session ends regardless of activity
+	UserAgent         string    `json:"user_agent,omitempty"` // User agent of the browser that logged in
+	IPAddress         string    `json:"ip_address,omitempty"` // IP address the login came from
+
+	// Claims mapped from the attributes of an upstream identity source, for users it
+	// authenticated; cached for the lifetime of the session
+	Claims map[string]interface{} `json:"claims,omitempty"`
+}
+
+// Session limit policies applied when a login would exceed the maximum sessions per user
+const (
+	SessionLimitReject      = "reject"       // Reject the new login
+	SessionLimitEvictOldest = "evict_oldest" // End the user's oldest sessions to make room
+)
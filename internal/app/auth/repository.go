@@ -5,11 +5,13 @@ package auth
 
 import (
 	"context"
+	"time"
 )
 
-// Repository defines the interface for authentication-related data storage and retrieval.
-// It handles persistence operations for refresh tokens and authorization sessions.
-type Repository interface {
+// TokenStore defines the storage of web refresh tokens. Implementations exist for Redis, where
+// tokens expire with their lifetime, and PostgreSQL, which keeps them for auditing; the store is
+// chosen at startup from the application configuration.
+type TokenStore interface {
 	// SaveRefreshToken stores a new refresh token.
 	// The token is already hashed before being passed to this function.
 	SaveRefreshToken(ctx context.Context, token *RefreshToken) error
@@ -25,9 +27,15 @@ type Repository interface {
 	FindRefreshTokenByToken(ctx context.Context, plainTextToken string) (*RefreshToken, error)
 
 	// RevokeRefreshToken marks a specific token as revoked.
-	// It should return an error if the token doesn't exist.
+	// It should return an error if the token doesn't exist. Revoking a revoked token succeeds.
 	RevokeRefreshToken(ctx context.Context, tokenID string) error
 
+	// RotateRefreshToken replaces a refresh token with its successor as one atomic step: the old
+	// token is revoked and the new one saved only if the old one was still unrevoked and unexpired.
+	// It returns false, saving nothing, if the old token was already revoked, expired, or missing,
+	// so that of concurrent rotations of one token exactly one succeeds and reuse is detected.
+	RotateRefreshToken(ctx context.Context, oldTokenID string, newToken *RefreshToken) (bool, error)
+
 	// RevokeAllUserRefreshTokens revokes all refresh tokens for a user.
 	// This is typically used during logout or password change operations.
 	RevokeAllUserRefreshTokens(ctx context.Context, userID uint) error
@@ -39,4 +47,37 @@ type Repository interface {
 	// IsRefreshTokenRevoked checks if a refresh token has been revoked.
 	// Returns true if the token is revoked or doesn't exist.
 	IsRefreshTokenRevoked(ctx context.Context, tokenID string) (bool, error)
+
+	// DeleteSessionRefreshTokens removes the refresh tokens issued within a user's session.
+	// Deleted tokens are unknown rather than revoked, so presenting one is not treated as reuse.
+	DeleteSessionRefreshTokens(ctx context.Context, userID uint, sessionID string) error
+}
+
+// SessionRepository defines the interface for web session storage.
+// Stored sessions expire on their own once their time to live runs out.
+type SessionRepository interface {
+	// SaveSession stores a new session, expiring after ttl.
+	SaveSession(ctx context.Context, session *Session, ttl time.Duration) error
+
+	// SaveSessionWithinLimit stores a new session like SaveSession, unless the user already
+	// holds limit live sessions. Counting and saving are one atomic step, so concurrent logins
+	// cannot together exceed the limit. It reports false if the session was not saved.
+	SaveSessionWithinLimit(ctx context.Context, session *Session, ttl time.Duration, limit int) (bool, error)
+
+	// FindSession looks up a session by its secret ID.
+	// Returns nil if the session doesn't exist or has expired.
+	FindSession(ctx context.Context, id string) (*Session, error)
+
+	// TouchSession resets the time to live of a session to ttl.
+	// It reports false if the session no longer exists.
+	TouchSession(ctx context.Context, id string, ttl time.Duration) (bool, error)
+
+	// DeleteSession removes a session. Deleting a missing session is not an error.
+	DeleteSession(ctx context.Context, id string) error
+
+	// DeleteUserSessions removes every session of a user.
+	DeleteUserSessions(ctx context.Context, userID uint) error
+
+	// ListUserSessions returns the live sessions of a user, oldest first.
+	ListUserSessions(ctx context.Context, userID uint) ([]*Session, error)
 }
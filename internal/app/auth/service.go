@@ -5,8 +5,6 @@ package auth
 
 import (
 	"context"
-	"crypto/rand"
-	"encoding/base64"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,23 +12,32 @@ import (
 	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
 	"github.com/verigate/verigate-server/internal/pkg/utils/hash"
 	jwtutil "github.com/verigate/verigate-server/internal/pkg/utils/jwt"
+	"github.com/verigate/verigate-server/internal/pkg/utils/opaque"
 )
 
+// mfaChallengeExpiry is how long a user has to enter the second factor after the password
+const mfaChallengeExpiry = 5 * time.Minute
+
 // Service handles authentication-related business logic.
 // It manages the creation, validation, and revocation of tokens,
 // as well as other authentication-related operations.
 type Service struct {
-	repo              Repository
-	accessExpiry      time.Duration
-	refreshExpiry     time.Duration
-	accessTokenIssuer string
+	repo                   TokenStore
+	sessionRepo            SessionRepository
+	accessExpiry           time.Duration
+	refreshExpiry          time.Duration
+	sessionIdleTimeout     time.Duration
+	sessionAbsoluteTimeout time.Duration
+	maxSessionsPerUser     int    // Zero allows any number of sessions
+	sessionLimitPolicy     string // What happens when a login exceeds maxSessionsPerUser
+	accessTokenIssuer      string
 }
 
 // NewService creates a new authentication service instance.
-// It initializes the service with token expiration settings
+// It initializes the service with token expiration and session timeout settings
 // loaded from the application configuration.
 // Note: The RSA keys are managed centrally by the JWT utility package.
-func NewService(repo Repository) *Service {
+func NewService(repo TokenStore, sessionRepo SessionRepository) *Service {
 	// JWT keys are now initialized in main.go via jwt.InitKeys()
 
 	// Parse expiry durations
@@ -44,11 +51,26 @@ func NewService(repo Repository) *Service {
 		panic("invalid refresh token expiry: " + err.Error())
 	}
 
+	sessionIdleTimeout, err := time.ParseDuration(config.AppConfig.SessionIdleTimeout)
+	if err != nil || sessionIdleTimeout <= 0 {
+		panic("invalid session idle timeout: " + config.AppConfig.SessionIdleTimeout)
+	}
+
+	sessionAbsoluteTimeout, err := time.ParseDuration(config.AppConfig.SessionAbsoluteTimeout)
+	if err != nil || sessionAbsoluteTimeout < sessionIdleTimeout {
+		panic("invalid session absolute timeout: must be a duration no shorter than the idle timeout")
+	}
+
 	return &Service{
-		repo:              repo,
-		accessExpiry:      accessExpiry,
-		refreshExpiry:     refreshExpiry,
-		accessTokenIssuer: "verigate-web", // Distinct from OAuth tokens
+		repo:                   repo,
+		sessionRepo:            sessionRepo,
+		accessExpiry:           accessExpiry,
+		refreshExpiry:          refreshExpiry,
+		sessionIdleTimeout:     sessionIdleTimeout,
+		sessionAbsoluteTimeout: sessionAbsoluteTimeout,
+		maxSessionsPerUser:     config.AppConfig.MaxSessionsPerUser,
+		sessionLimitPolicy:     config.AppConfig.SessionLimitPolicy,
+		accessTokenIssuer:      "verigate-web", // Distinct from OAuth tokens
 	}
 }
 
@@ -56,30 +78,52 @@ func NewService(repo Repository) *Service {
 // The access token is a JWT with user identity claims, and the refresh token
 // is a secure random string that can be exchanged for a new token pair.
 // User agent and IP address are stored for audit purposes.
+// Each call starts a new session with its own session ID.
 func (s *Service) CreateTokenPair(ctx context.Context, userID uint, userAgent, ipAddress string) (*TokenPair, error) {
+	return s.createTokenPair(ctx, userID, userAgent, ipAddress, time.Now(), uuid.New().String())
+}
+
+// createTokenPair generates a token pair for a session in which the user
+// authenticated at authTime. The authentication time and session ID are recorded
+// in the access token and the stored refresh token so they survive token rotation.
+func (s *Service) createTokenPair(ctx context.Context, userID uint, userAgent, ipAddress string, authTime time.Time, sessionID string) (*TokenPair, error) {
+	tokenPair, refreshTokenModel, err := s.newTokenPair(ctx, userID, userAgent, ipAddress, authTime, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SaveRefreshToken(ctx, refreshTokenModel); err != nil {
+		return nil, err
+	}
+
+	return tokenPair, nil
+}
+
+// newTokenPair generates a token pair and the refresh token record to store for it,
+// without storing anything.
+func (s *Service) newTokenPair(ctx context.Context, userID uint, userAgent, ipAddress string, authTime time.Time, sessionID string) (*TokenPair, *RefreshToken, error) {
 	// Generate access token
 	tokenID := uuid.New().String()
 	now := time.Now()
 
 	// Use the GenerateCustomToken function from JWT utility package
-	accessToken, err := jwtutil.GenerateCustomToken(userID, s.accessTokenIssuer, jwtutil.TokenTypeAccess, tokenID, s.accessExpiry)
+	accessToken, err := jwtutil.GenerateCustomToken(ctx, userID, s.accessTokenIssuer, jwtutil.TokenTypeAccess, tokenID, s.accessExpiry, authTime, sessionID)
 	if err != nil {
-		return nil, errors.Internal(errors.ErrMsgFailedToGenerateAccessToken)
+		return nil, nil, errors.Internal(errors.ErrMsgFailedToGenerateAccessToken)
 	}
 
 	// Generate refresh token
 	refreshTokenID := uuid.New().String()
-	refreshTokenBytes := make([]byte, 32)
-	if _, err := rand.Read(refreshTokenBytes); err != nil {
-		return nil, errors.Internal(errors.ErrMsgFailedToGenerateRefreshToken)
+	refreshToken, err := opaque.Generate(opaque.PrefixRefreshToken)
+	if err != nil {
+		return nil, nil, errors.Internal(errors.ErrMsgFailedToGenerateRefreshToken)
 	}
-	refreshToken := base64.URLEncoding.EncodeToString(refreshTokenBytes)
 	refreshExpiry := now.Add(s.refreshExpiry)
 
 	// Hash the refresh token
 	hashedRefreshToken, err := hash.HashPassword(refreshToken)
 	if err != nil {
-		return nil, errors.Internal(errors.ErrMsgFailedToHashRefreshToken)
+		return nil, nil, errors.Internal(errors.ErrMsgFailedToHashRefreshToken)
 	}
 
 	// Store the refresh token
@@ -89,26 +133,29 @@ func (s *Service) CreateTokenPair(ctx context.Context, userID uint, userAgent, i
 		Token:     hashedRefreshToken,
 		ExpiresAt: refreshExpiry,
 		CreatedAt: now,
+		AuthTime:  authTime,
+		SessionID: sessionID,
 		IsRevoked: false,
 		UserAgent: userAgent,
 		IPAddress: ipAddress,
 	}
 
-	if err := s.repo.SaveRefreshToken(ctx, refreshTokenModel); err != nil {
-		return nil, err
-	}
-
 	return &TokenPair{
 		AccessToken:           accessToken,
 		RefreshToken:          refreshToken,
 		AccessTokenExpiresAt:  now.Add(s.accessExpiry),
 		RefreshTokenExpiresAt: refreshExpiry,
-	}, nil
+	}, refreshTokenModel, nil
 }
 
 // RefreshTokens uses a refresh token to issue a new token pair (Refresh Token Rotation pattern).
 // It validates the provided refresh token, revokes it, and generates a new token pair.
 func (s *Service) RefreshTokens(ctx context.Context, refreshToken, userAgent, ipAddress string) (*TokenPair, error) {
+	// Reject malformed tokens before looking them up
+	if !opaque.Acceptable(refreshToken, opaque.PrefixRefreshToken) {
+		return nil, errors.Unauthorized(errors.ErrMsgInvalidToken)
+	}
+
 	// Find the refresh token
 	token, err := s.repo.FindRefreshTokenByToken(ctx, refreshToken)
 	if err != nil {
@@ -130,20 +177,65 @@ func (s *Service) RefreshTokens(ctx context.Context, refreshToken, userAgent, ip
 		return nil, errors.Unauthorized(errors.ErrMsgTokenExpired)
 	}
 
-	// Revoke current refresh token (RTR pattern)
-	if err := s.repo.RevokeRefreshToken(ctx, token.ID); err != nil {
+	// Create new token pair for the same session; tokens stored before
+	// auth times were recorded fall back to their creation time
+	authTime := token.AuthTime
+	if authTime.IsZero() {
+		authTime = token.CreatedAt
+	}
+	sessionID := token.SessionID
+	if sessionID == "" {
+		sessionID = uuid.New().String()
+	}
+	tokenPair, refreshTokenModel, err := s.newTokenPair(ctx, token.UserID, userAgent, ipAddress, authTime, sessionID)
+	if err != nil {
 		return nil, err
 	}
 
-	// Create new token pair
-	return s.CreateTokenPair(ctx, token.UserID, userAgent, ipAddress)
+	// Swap the presented token for the new one (RTR pattern). Losing the swap means the
+	// token was used concurrently or revoked meanwhile, which is handled as reuse.
+	rotated, err := s.repo.RotateRefreshToken(ctx, token.ID, refreshTokenModel)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		s.repo.RevokeAllUserRefreshTokens(ctx, token.UserID)
+		return nil, errors.Unauthorized(errors.ErrMsgTokenRevoked)
+	}
+
+	return tokenPair, nil
 }
 
 // ValidateAccessToken validates an access token and returns the user ID.
 // It checks the token's signature, expiration, issuer, and type.
-func (s *Service) ValidateAccessToken(tokenString string) (uint, error) {
+func (s *Service) ValidateAccessToken(ctx context.Context, tokenString string) (uint, error) {
 	// Use the common JWT utility for consistent token validation
-	return jwtutil.ValidateAccessTokenWithClaims(tokenString, s.accessTokenIssuer)
+	return jwtutil.ValidateAccessTokenWithClaims(ctx, tokenString, s.accessTokenIssuer)
+}
+
+// ValidateSession validates a web session access token and returns the session it
+// belongs to: the user ID, the time the user authenticated, and the session ID.
+func (s *Service) ValidateSession(ctx context.Context, tokenString string) (*jwtutil.SessionClaims, error) {
+	return jwtutil.ValidateSessionToken(ctx, tokenString, s.accessTokenIssuer)
+}
+
+// CreateMFAChallenge issues a short-lived challenge proving that the user passed the
+// password step of a login; it is exchanged for a token pair once the second factor is verified.
+func (s *Service) CreateMFAChallenge(ctx context.Context, userID uint) (string, error) {
+	token, err := jwtutil.GenerateCustomToken(ctx, userID, s.accessTokenIssuer, jwtutil.TokenTypeMFA, uuid.New().String(), mfaChallengeExpiry, time.Now(), "")
+	if err != nil {
+		return "", errors.Internal(errors.ErrMsgFailedToGenerateMFAChallenge)
+	}
+	return token, nil
+}
+
+// ValidateMFAChallenge validates a challenge issued by CreateMFAChallenge and returns the user ID.
+func (s *Service) ValidateMFAChallenge(ctx context.Context, tokenString string) (uint, error) {
+	session, err := jwtutil.ValidateTypedToken(ctx, tokenString, s.accessTokenIssuer, jwtutil.TokenTypeMFA)
+	if err != nil {
+		return 0, errors.Unauthorized(errors.ErrMsgInvalidMFAChallenge)
+	}
+	return session.UserID, nil
 }
 
 // RevokeRefreshToken revokes a specific refresh token.
@@ -0,0 +1,197 @@
+// Package auth provides authentication and authorization services
+// for the application, including token generation, validation,
+// and management of authentication sessions.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+)
+
+// sessionIDBytes is the number of random bytes in a session ID
+const sessionIDBytes = 32
+
+// CreateSession starts a server-side web session for a user who just authenticated
+// with the given method. The session ID is drawn from a cryptographically secure source
+// and is the only value handed to the browser. The session ends after the idle timeout
+// without activity, or after the absolute timeout however active it is.
+// When the user already holds the maximum number of sessions, the login is rejected or the
+// oldest sessions are ended, depending on the session limit policy; the ended sessions are
+// returned alongside the new one.
+func (s *Service) CreateSession(ctx context.Context, userID uint, acr, userAgent, ipAddress string) (*Session, []*Session, error) {
+	return s.createSession(ctx, userID, acr, nil, userAgent, ipAddress)
+}
+
+// CreateFederatedSession starts a web session like CreateSession for a user authenticated by an
+// upstream identity source, caching the claims mapped from its attributes with the session.
+func (s *Service) CreateFederatedSession(ctx context.Context, userID uint, acr string, claims map[string]interface{}, userAgent, ipAddress string) (*Session, []*Session, error) {
+	return s.createSession(ctx, userID, acr, claims, userAgent, ipAddress)
+}
+
+// createSession starts a web session carrying the given claims, enforcing the session limit.
+func (s *Service) createSession(ctx context.Context, userID uint, acr string, claims map[string]interface{}, userAgent, ipAddress string) (*Session, []*Session, error) {
+	idBytes := make([]byte, sessionIDBytes)
+	if _, err := rand.Read(idBytes); err != nil {
+		return nil, nil, errors.Internal(errors.ErrMsgFailedToGenerateSessionID)
+	}
+
+	now := time.Now()
+	session := &Session{
+		ID:                base64.RawURLEncoding.EncodeToString(idBytes),
+		SID:               uuid.New().String(),
+		UserID:            userID,
+		AuthTime:          now,
+		ACR:               acr,
+		CreatedAt:         now,
+		AbsoluteExpiresAt: now.Add(s.sessionAbsoluteTimeout),
+		UserAgent:         userAgent,
+		IPAddress:         ipAddress,
+		Claims:            claims,
+	}
+
+	// The reject policy counts and saves in one step, so concurrent logins cannot slip past the limit
+	if s.maxSessionsPerUser > 0 && s.sessionLimitPolicy == SessionLimitReject {
+		saved, err := s.sessionRepo.SaveSessionWithinLimit(ctx, session, s.sessionIdleTimeout, s.maxSessionsPerUser)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !saved {
+			return nil, nil, errors.Forbidden(errors.ErrMsgTooManySessions)
+		}
+		return session, nil, nil
+	}
+
+	if err := s.sessionRepo.SaveSession(ctx, session, s.sessionIdleTimeout); err != nil {
+		return nil, nil, err
+	}
+
+	evicted, err := s.evictExcessSessions(ctx, session)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return session, evicted, nil
+}
+
+// evictExcessSessions ends the user's oldest sessions other than the one just created
+// until the user holds no more than the maximum number of sessions. Checking after the
+// new session is saved keeps concurrent logins from together exceeding the limit.
+func (s *Service) evictExcessSessions(ctx context.Context, current *Session) ([]*Session, error) {
+	if s.maxSessionsPerUser <= 0 {
+		return nil, nil
+	}
+
+	sessions, err := s.sessionRepo.ListUserSessions(ctx, current.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	excess := len(sessions) - s.maxSessionsPerUser
+	var evicted []*Session
+	for _, session := range sessions {
+		if excess <= 0 {
+			break
+		}
+		if session.ID == current.ID {
+			continue
+		}
+		if err := s.endSession(ctx, session); err != nil {
+			return nil, err
+		}
+		evicted = append(evicted, session)
+		excess--
+	}
+
+	return evicted, nil
+}
+
+// CreateSessionTokenPair issues a token pair within a web session, so that the tokens
+// carry the session's authentication time and session ID.
+func (s *Service) CreateSessionTokenPair(ctx context.Context, session *Session, userAgent, ipAddress string) (*TokenPair, error) {
+	return s.createTokenPair(ctx, session.UserID, userAgent, ipAddress, session.AuthTime, session.SID)
+}
+
+// ValidateSessionID returns the live web session with the given ID and slides its idle
+// expiry forward. The new time to live never reaches past the absolute expiry, so
+// activity cannot keep a session alive beyond it.
+func (s *Service) ValidateSessionID(ctx context.Context, id string) (*Session, error) {
+	session, err := s.sessionRepo.FindSession(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, errors.Unauthorized(errors.ErrMsgInvalidSession)
+	}
+
+	remaining := time.Until(session.AbsoluteExpiresAt)
+	if remaining <= 0 {
+		if err := s.sessionRepo.DeleteSession(ctx, id); err != nil {
+			// Not critical, continue
+		}
+		return nil, errors.Unauthorized(errors.ErrMsgInvalidSession)
+	}
+
+	ttl := s.sessionIdleTimeout
+	if remaining < ttl {
+		ttl = remaining
+	}
+	ok, err := s.sessionRepo.TouchSession(ctx, id, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		// Expired between the lookup and the renewal
+		return nil, errors.Unauthorized(errors.ErrMsgInvalidSession)
+	}
+
+	return session, nil
+}
+
+// EndSession ends a single web session, as when the user logs out in one browser.
+func (s *Service) EndSession(ctx context.Context, id string) error {
+	return s.sessionRepo.DeleteSession(ctx, id)
+}
+
+// EndAllUserSessions ends every web session of a user.
+func (s *Service) EndAllUserSessions(ctx context.Context, userID uint) error {
+	return s.sessionRepo.DeleteUserSessions(ctx, userID)
+}
+
+// ListUserSessions returns the live web sessions of a user, oldest first.
+func (s *Service) ListUserSessions(ctx context.Context, userID uint) ([]*Session, error) {
+	return s.sessionRepo.ListUserSessions(ctx, userID)
+}
+
+// EndUserSession ends one of the user's web sessions, identified by its public session ID,
+// together with the refresh tokens issued in it. It returns the ended session.
+func (s *Service) EndUserSession(ctx context.Context, userID uint, sid string) (*Session, error) {
+	sessions, err := s.sessionRepo.ListUserSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, session := range sessions {
+		if session.SID == sid {
+			if err := s.endSession(ctx, session); err != nil {
+				return nil, err
+			}
+			return session, nil
+		}
+	}
+
+	return nil, errors.NotFound(errors.ErrMsgSessionNotFound)
+}
+
+// endSession deletes a session and the refresh tokens issued in it, so the browser
+// holding it can neither use the session nor renew its tokens.
+func (s *Service) endSession(ctx context.Context, session *Session) error {
+	if err := s.sessionRepo.DeleteSession(ctx, session.ID); err != nil {
+		return err
+	}
+	return s.repo.DeleteSessionRefreshTokens(ctx, session.UserID, session.SID)
+}
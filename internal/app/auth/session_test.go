@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+)
+
+func TestValidateSessionID(t *testing.T) {
+	const (
+		idleTimeout     = 30 * time.Minute
+		absoluteTimeout = 8 * time.Hour
+	)
+
+	tests := []struct {
+		name              string
+		remaining         time.Duration // Time from now to the absolute expiry
+		expireBeforeTouch bool
+		wantValid         bool
+		wantTTLAbove      time.Duration // Bounds of the renewed time to live, for a valid session
+		wantTTLAtMost     time.Duration
+	}{
+		{"fresh session slides a full idle timeout", absoluteTimeout, false, true, idleTimeout - time.Second, idleTimeout},
+		{"idle timeout just fits", idleTimeout + time.Minute, false, true, idleTimeout - time.Second, idleTimeout},
+		{"renewal capped at the absolute expiry", 10 * time.Minute, false, true, 10*time.Minute - time.Second, 10 * time.Minute},
+		{"past the absolute expiry", -time.Second, false, false, 0, 0},
+		{"at the absolute expiry", 0, false, false, 0, 0},
+		{"expired before renewal", absoluteTimeout, true, false, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			session := &Session{
+				ID:                "session-1",
+				SID:               "sid-1",
+				UserID:            1,
+				CreatedAt:         now.Add(tt.remaining - absoluteTimeout),
+				AbsoluteExpiresAt: now.Add(tt.remaining),
+			}
+			repo := newFakeSessionRepository(session)
+			repo.expireBeforeTouch = tt.expireBeforeTouch
+			service := &Service{sessionRepo: repo, sessionIdleTimeout: idleTimeout, sessionAbsoluteTimeout: absoluteTimeout}
+
+			got, err := service.ValidateSessionID(context.Background(), session.ID)
+			exists, ttl := repo.state(session.ID)
+
+			if !tt.wantValid {
+				if customErr, ok := errors.As(err); !ok || customErr.Code() != errors.ErrMsgInvalidSession {
+					t.Fatalf("got %+v, %v, want %q", got, err, errors.ErrMsgInvalidSession)
+				}
+				if exists {
+					t.Error("invalid session was kept")
+				}
+				if ttl != 0 {
+					t.Errorf("invalid session was renewed for %v", ttl)
+				}
+				return
+			}
+
+			if err != nil || got == nil || got.ID != session.ID {
+				t.Fatalf("got %+v, %v, want the session", got, err)
+			}
+			if ttl <= tt.wantTTLAbove || ttl > tt.wantTTLAtMost {
+				t.Errorf("renewed for %v, want more than %v and at most %v", ttl, tt.wantTTLAbove, tt.wantTTLAtMost)
+			}
+		})
+	}
+}
+
+func TestValidateSessionIDUnknownSession(t *testing.T) {
+	service := &Service{sessionRepo: newFakeSessionRepository(), sessionIdleTimeout: time.Minute, sessionAbsoluteTimeout: time.Hour}
+
+	_, err := service.ValidateSessionID(context.Background(), "missing")
+	if customErr, ok := errors.As(err); !ok || customErr.Code() != errors.ErrMsgInvalidSession {
+		t.Errorf("got error %v, want %q", err, errors.ErrMsgInvalidSession)
+	}
+}
+
+// TestValidateSessionIDSlidingUntilAbsoluteCutoff keeps a session active with a request every
+// idle period and checks that renewals stop at the absolute expiry.
+func TestValidateSessionIDSlidingUntilAbsoluteCutoff(t *testing.T) {
+	const (
+		idleTimeout     = 40 * time.Millisecond
+		absoluteTimeout = 100 * time.Millisecond
+	)
+
+	now := time.Now()
+	session := &Session{ID: "session-1", SID: "sid-1", UserID: 1, CreatedAt: now, AbsoluteExpiresAt: now.Add(absoluteTimeout)}
+	repo := newFakeSessionRepository(session)
+	service := &Service{sessionRepo: repo, sessionIdleTimeout: idleTimeout, sessionAbsoluteTimeout: absoluteTimeout}
+	ctx := context.Background()
+
+	for time.Until(session.AbsoluteExpiresAt) > 10*time.Millisecond {
+		if _, err := service.ValidateSessionID(ctx, session.ID); err != nil {
+			t.Fatalf("active session rejected %v before its absolute expiry: %v", time.Until(session.AbsoluteExpiresAt), err)
+		}
+		if _, ttl := repo.state(session.ID); ttl > time.Until(session.AbsoluteExpiresAt)+time.Millisecond {
+			t.Fatalf("renewed for %v, past the absolute expiry", ttl)
+		}
+		time.Sleep(idleTimeout / 4)
+	}
+
+	time.Sleep(time.Until(session.AbsoluteExpiresAt) + time.Millisecond)
+	if _, err := service.ValidateSessionID(ctx, session.ID); err == nil {
+		t.Error("session still valid after its absolute expiry")
+	}
+	if exists, _ := repo.state(session.ID); exists {
+		t.Error("session past its absolute expiry was not deleted")
+	}
+}
@@ -0,0 +1,7 @@
+package cleanup
+
+// PurgeResponse reports the expired rows a purge deleted.
+type PurgeResponse struct {
+	Purged map[string]int64 `json:"purged"` // Rows deleted by table
+	Total  int64            `json:"total"`  // Rows deleted altogether
+}
@@ -0,0 +1,23 @@
+// Package cleanup purges expired authorization codes, device codes, tokens, and sessions
+// from PostgreSQL, which, unlike Redis, keeps rows until they are deleted.
+package cleanup
+
+// Tables purged of expired rows, in the order they are purged
+const (
+	TableAuthorizationCodes = "authorization_codes" // Authorization codes, with the replay links of the tokens issued for them
+	TableDeviceCodes        = "device_codes"        // Device authorization codes
+	TableAccessTokens       = "access_tokens"       // OAuth access tokens
+	TableRefreshTokens      = "refresh_tokens"      // OAuth refresh tokens
+	TableWebRefreshTokens   = "web_refresh_tokens"  // Web sessions kept in the PostgreSQL token store
+	TableRPSessions         = "rp_sessions"         // Sessions clients are notified of on logout
+)
+
+// Tables lists every purged table, in the order they are purged
+var Tables = []string{
+	TableAuthorizationCodes,
+	TableDeviceCodes,
+	TableAccessTokens,
+	TableRefreshTokens,
+	TableWebRefreshTokens,
+	TableRPSessions,
+}
@@ -0,0 +1,22 @@
+package cleanup
+
+import (
+	"context"
+	"time"
+)
+
+// Repository defines the interface for deleting expired rows.
+type Repository interface {
+	// DeleteExpired deletes up to limit rows of table that expired before cutoff and returns how
+	// many it deleted. Rows locked by a concurrent purge are skipped rather than waited for.
+	DeleteExpired(ctx context.Context, table string, cutoff time.Time, limit int) (int64, error)
+}
+
+// LockRepository holds the lock that keeps replicas from purging the same tenant at once.
+type LockRepository interface {
+	// Acquire takes the lock for owner until ttl passes, reporting false if another owner holds it
+	Acquire(ctx context.Context, owner string, ttl time.Duration) (bool, error)
+
+	// Release gives up the lock if owner still holds it
+	Release(ctx context.Context, owner string) error
+}
@@ -0,0 +1,142 @@
+package cleanup
+
+import (
+	"context"
+	stderrors "errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/verigate/verigate-server/internal/pkg/config"
+	"github.com/verigate/verigate-server/internal/pkg/metrics"
+	"github.com/verigate/verigate-server/internal/pkg/tenant"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+)
+
+// lockTTL bounds how long a purge may hold the lock of a tenant. A purge stops once it runs
+// this long, leaving the remaining rows to the next run, so a replica that dies mid-purge
+// blocks the others no longer than this.
+const lockTTL = 10 * time.Minute
+
+// Service purges expired rows in batches. Each purge holds a lock per tenant, so replicas
+// sharing the database take turns instead of deleting the same rows concurrently.
+type Service struct {
+	repo      Repository
+	lock      LockRepository
+	tenants   []*tenant.Tenant
+	interval  time.Duration
+	retention time.Duration
+	batchSize int
+}
+
+// NewService creates a new cleanup service instance purging the default tenant and the given
+// tenants. It panics when the configured interval or retention is invalid, like the other
+// services' constructors. Scheduled purges only run once Start has been called.
+func NewService(repo Repository, lock LockRepository, tenants []*tenant.Tenant) *Service {
+	interval, err := time.ParseDuration(config.AppConfig.CleanupInterval)
+	if err != nil || interval < 0 {
+		panic("invalid cleanup interval: " + config.AppConfig.CleanupInterval)
+	}
+	retention, err := time.ParseDuration(config.AppConfig.CleanupRetention)
+	if err != nil || retention < 0 {
+		panic("invalid cleanup retention: " + config.AppConfig.CleanupRetention)
+	}
+
+	return &Service{
+		repo:      repo,
+		lock:      lock,
+		tenants:   tenants,
+		interval:  interval,
+		retention: retention,
+		batchSize: max(config.AppConfig.CleanupBatchSize, 1),
+	}
+}
+
+// Start purges every tenant once per interval until ctx is cancelled, calling report with the
+// outcome of each purge. Tenants another replica is purging at the time are skipped without a
+// report. Nothing is scheduled when the interval is zero.
+func (s *Service) Start(ctx context.Context, report func(tenantID string, resp *PurgeResponse, err error)) {
+	if s.interval == 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.purgeScheduled(ctx, "", report)
+				for _, t := range s.tenants {
+					s.purgeScheduled(tenant.WithTenant(ctx, t), t.ID, report)
+				}
+			}
+		}
+	}()
+}
+
+// purgeScheduled runs a scheduled purge of the tenant ctx is served for and reports its outcome.
+func (s *Service) purgeScheduled(ctx context.Context, tenantID string, report func(string, *PurgeResponse, error)) {
+	resp, err := s.Purge(ctx)
+	if stderrors.Is(err, errors.Conflict(errors.ErrMsgPurgeInProgress)) {
+		return
+	}
+	report(tenantID, resp, err)
+}
+
+// Purge deletes the rows of the tenant ctx is served for that expired longer than the retention
+// ago, one batch at a time so no statement holds its row locks for long. Returns Conflict if
+// another purge of the tenant is in progress. A purge stopped by its time limit or by ctx
+// reports the rows deleted so far along with the error.
+func (s *Service) Purge(ctx context.Context) (*PurgeResponse, error) {
+	owner := uuid.NewString()
+	acquired, err := s.lock.Acquire(ctx, owner, lockTTL)
+	if err != nil {
+		return nil, errors.ServiceUnavailable(errors.ErrMsgFailedToAcquirePurgeLock).Wrap(err)
+	}
+	if !acquired {
+		return nil, errors.Conflict(errors.ErrMsgPurgeInProgress)
+	}
+	// Release even when ctx was cancelled, so the next run need not wait for the lock to expire
+	defer s.lock.Release(tenant.Detach(ctx), owner)
+
+	ctx, cancel := context.WithTimeout(ctx, lockTTL)
+	defer cancel()
+
+	cutoff := time.Now().Add(-s.retention)
+	resp := &PurgeResponse{Purged: make(map[string]int64, len(Tables))}
+	for _, table := range Tables {
+		purged, err := s.purgeTable(ctx, table, cutoff)
+		resp.Purged[table] = purged
+		resp.Total += purged
+		if err != nil {
+			return resp, err
+		}
+	}
+	return resp, nil
+}
+
+// purgeTable deletes the rows of table that expired before cutoff in batches until a batch
+// comes back short, and returns how many it deleted.
+func (s *Service) purgeTable(ctx context.Context, table string, cutoff time.Time) (int64, error) {
+	counter := metrics.PurgedRows.WithLabelValues(table)
+
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, errors.ServiceUnavailable(errors.ErrMsgFailedToPurgeExpired).Wrap(err)
+		}
+
+		deleted, err := s.repo.DeleteExpired(ctx, table, cutoff, s.batchSize)
+		total += deleted
+		counter.Add(float64(deleted))
+		if err != nil {
+			return total, err
+		}
+		if deleted < int64(s.batchSize) {
+			return total, nil
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package client
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/verigate/verigate-server/internal/pkg/config"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+)
+
+// Tokens a claim template adds its claim to
+const (
+	ClaimTemplateTokenAccess = "access_token" // Access tokens issued to the client
+	ClaimTemplateTokenID     = "id_token"     // ID tokens issued to the client
+)
+
+// Variables claim templates may reference. User attributes are only set when a scope granted
+// with the token releases the claim of the same name, so templates cannot disclose more than
+// the client could read from the userinfo endpoint.
+const (
+	ClaimVariableUserSub               = "user.sub"                // Subject the client knows the user by, pairwise for pairwise clients
+	ClaimVariableUserPreferredUsername = "user.preferred_username" // Released with the profile scope
+	ClaimVariableUserName              = "user.name"               // Released with the profile scope
+	ClaimVariableUserGivenName         = "user.given_name"         // Released with the profile scope
+	ClaimVariableUserFamilyName        = "user.family_name"        // Released with the profile scope
+	ClaimVariableUserPicture           = "user.picture"            // Released with the profile scope
+	ClaimVariableUserEmail             = "user.email"              // Released with the email scope
+	ClaimVariableUserEmailVerified     = "user.email_verified"     // Released with the email scope
+	ClaimVariableUserPhoneNumber       = "user.phone_number"       // Released with the phone scope
+	ClaimVariableClientID              = "client.id"               // Client the token is issued to
+	ClaimVariableGrantType             = "request.grant_type"      // Grant type of the token request
+	ClaimVariableScope                 = "request.scope"           // Scope granted with the token
+	ClaimVariableTenantID              = "tenant.id"               // Tenant the token is issued for, empty for the default tenant
+)
+
+// claimVariables are the variables claim templates may reference
+var claimVariables = map[string]bool{
+	ClaimVariableUserSub:               true,
+	ClaimVariableUserPreferredUsername: true,
+	ClaimVariableUserName:              true,
+	ClaimVariableUserGivenName:         true,
+	ClaimVariableUserFamilyName:        true,
+	ClaimVariableUserPicture:           true,
+	ClaimVariableUserEmail:             true,
+	ClaimVariableUserEmailVerified:     true,
+	ClaimVariableUserPhoneNumber:       true,
+	ClaimVariableClientID:              true,
+	ClaimVariableGrantType:             true,
+	ClaimVariableScope:                 true,
+	ClaimVariableTenantID:              true,
+}
+
+// reservedClaimNames are the claims this server sets itself or that carry security decisions,
+// which no claim template may name even before it is namespaced
+var reservedClaimNames = map[string]bool{
+	"iss": true, "sub": true, "aud": true, "exp": true, "iat": true, "nbf": true, "jti": true,
+	"scope": true, "client_id": true, "azp": true, "cnf": true, "sid": true, "nonce": true,
+	"acr": true, "amr": true, "auth_time": true, "at_hash": true, "c_hash": true, "events": true,
+	"type": true, "user_id": true, "act": true, "may_act": true,
+}
+
+// maxClaimTemplates bounds how many claim templates a client may register
+const maxClaimTemplates = 20
+
+// claimNamePattern is the form of claim template names and of the custom claim prefix
+var claimNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{0,63}$`)
+
+// ClaimTemplate produces one custom claim in the tokens issued to a client.
+// Exactly one of Source, Value, and Template is set: Source copies a variable, Value is a fixed
+// value, and Template expands ${variable} references into a string. Templates are expanded and
+// never evaluated, so they cannot run code. A template whose variables are all unset produces
+// no claim. The claim is issued under the server's custom claim prefix, so it cannot collide
+// with standard claims.
+type ClaimTemplate struct {
+	Claim    string      `json:"claim"`              // Name of the claim, without the custom claim prefix
+	Source   string      `json:"source,omitempty"`   // Variable copied into the claim
+	Value    interface{} `json:"value,omitempty"`    // Fixed value of the claim
+	Template string      `json:"template,omitempty"` // String referencing variables as ${name}
+	Tokens   []string    `json:"tokens,omitempty"`   // access_token and/or id_token, both when empty
+}
+
+// CustomClaimName returns the name a claim template's claim is issued under.
+func CustomClaimName(claim string) string {
+	return config.AppConfig.CustomClaimPrefix + claim
+}
+
+// AppliesTo reports whether the template adds its claim to the given kind of token.
+func (t ClaimTemplate) AppliesTo(token string) bool {
+	if len(t.Tokens) == 0 {
+		return true
+	}
+	for _, allowed := range t.Tokens {
+		if allowed == token {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate returns the value the template produces from the variables, and whether it produced one.
+// Unset variables expand to nothing.
+func (t ClaimTemplate) Evaluate(variables map[string]interface{}) (interface{}, bool) {
+	switch {
+	case t.Source != "":
+		value, ok := variables[t.Source]
+		if !ok || value == "" {
+			return nil, false
+		}
+		return value, true
+	case t.Value != nil:
+		return t.Value, true
+	case t.Template != "":
+		value := strings.TrimSpace(os.Expand(t.Template, func(name string) string {
+			return variableString(variables[name])
+		}))
+		return value, value != ""
+	}
+	return nil, false
+}
+
+// CustomClaims evaluates the client's claim templates for the given kind of token and returns
+// the claims produced, keyed by their namespaced names.
+func (c *Client) CustomClaims(token string, variables map[string]interface{}) map[string]interface{} {
+	claims := map[string]interface{}{}
+	for _, t := range c.ClaimTemplates {
+		if !t.AppliesTo(token) {
+			continue
+		}
+		if value, ok := t.Evaluate(variables); ok {
+			claims[CustomClaimName(t.Claim)] = value
+		}
+	}
+	return claims
+}
+
+// validateClaimTemplates checks the claim templates a client registers: each names a claim
+// that is not reserved and not produced by an earlier template, is of exactly one kind,
+// lists only known tokens, and references only defined variables.
+func validateClaimTemplates(templates []ClaimTemplate) error {
+	if len(templates) > maxClaimTemplates {
+		return invalidClaimTemplate("at most " + strconv.Itoa(maxClaimTemplates) + " claim templates may be registered")
+	}
+
+	seen := make(map[string]bool, len(templates))
+	for _, t := range templates {
+		if !claimNamePattern.MatchString(t.Claim) {
+			return invalidClaimTemplate("claim names must start with a letter and contain only letters, digits, and underscores")
+		}
+		if reservedClaimNames[t.Claim] {
+			return invalidClaimTemplate("claim " + t.Claim + " is reserved")
+		}
+		if seen[t.Claim] {
+			return invalidClaimTemplate("claim " + t.Claim + " is produced by more than one template")
+		}
+		seen[t.Claim] = true
+
+		kinds := 0
+		if t.Source != "" {
+			kinds++
+		}
+		if t.Value != nil {
+			kinds++
+		}
+		if t.Template != "" {
+			kinds++
+		}
+		if kinds != 1 {
+			return invalidClaimTemplate("claim " + t.Claim + " must set exactly one of source, value, and template")
+		}
+
+		for _, token := range t.Tokens {
+			if token != ClaimTemplateTokenAccess && token != ClaimTemplateTokenID {
+				return invalidClaimTemplate("tokens must be access_token or id_token")
+			}
+		}
+
+		if t.Source != "" && !claimVariables[t.Source] {
+			return invalidClaimTemplate("claim " + t.Claim + " references undefined variable " + t.Source)
+		}
+		if t.Template != "" {
+			var undefined string
+			os.Expand(t.Template, func(name string) string {
+				if undefined == "" && !claimVariables[name] {
+					undefined = name
+				}
+				return ""
+			})
+			if undefined != "" {
+				return invalidClaimTemplate("claim " + t.Claim + " references undefined variable " + undefined)
+			}
+		}
+	}
+	return nil
+}
+
+// IsValidCustomClaimPrefix reports whether a prefix can namespace custom claims. It must be
+// non-empty, so that no custom claim can take the name of a standard one.
+func IsValidCustomClaimPrefix(prefix string) bool {
+	return claimNamePattern.MatchString(prefix)
+}
+
+// nonNilClaimTemplates returns templates, or an empty list when nil, so that clients
+// without claim templates store an empty list.
+func nonNilClaimTemplates(templates []ClaimTemplate) []ClaimTemplate {
+	if templates == nil {
+		return []ClaimTemplate{}
+	}
+	return templates
+}
+
+// variableString formats a variable for a template. Unset variables contribute nothing.
+func variableString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	}
+	return ""
+}
+
+// invalidClaimTemplate returns the error for an invalid claim template, described by detail.
+func invalidClaimTemplate(detail string) error {
+	return errors.BadRequest(errors.ErrMsgInvalidClaimTemplate).WithDetails(detail)
+}
@@ -2,70 +2,149 @@
 // including registration, configuration, and permission management.
 package client
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // CreateClientRequest represents the data required to create a new OAuth client.
 // It contains all the client metadata required for OAuth 2.0 client registration.
 type CreateClientRequest struct {
-	ClientName      string   `json:"client_name" binding:"required"`
-	Description     string   `json:"description"`
-	ClientURI       string   `json:"client_uri"`
-	LogoURI         string   `json:"logo_uri"`
-	RedirectURIs    []string `json:"redirect_uris" binding:"required,min=1"`
-	GrantTypes      []string `json:"grant_types" binding:"required,min=1"`
-	ResponseTypes   []string `json:"response_types"`
-	Scope           string   `json:"scope" binding:"required"`
-	TOSUri          string   `json:"tos_uri"`
-	PolicyURI       string   `json:"policy_uri"`
-	JwksURI         string   `json:"jwks_uri"`
-	Jwks            string   `json:"jwks"`
-	Contacts        []string `json:"contacts"`
-	SoftwareID      string   `json:"software_id"`
-	SoftwareVersion string   `json:"software_version"`
-	IsConfidential  bool     `json:"is_confidential"`
+	ClientName                  string          `json:"client_name" binding:"required"`
+	Description                 string          `json:"description"`
+	ClientURI                   string          `json:"client_uri"`
+	LogoURI                     string          `json:"logo_uri"`
+	RedirectURIs                []string        `json:"redirect_uris" binding:"required,min=1"`
+	GrantTypes                  []string        `json:"grant_types" binding:"required,min=1"`
+	ResponseTypes               []string        `json:"response_types"`
+	Scope                       string          `json:"scope" binding:"required"`
+	TOSUri                      string          `json:"tos_uri"`
+	PolicyURI                   string          `json:"policy_uri"`
+	JwksURI                     string          `json:"jwks_uri"`
+	Jwks                        string          `json:"jwks"`
+	Contacts                    []string        `json:"contacts"`
+	SoftwareID                  string          `json:"software_id"`
+	SoftwareVersion             string          `json:"software_version"`
+	IsConfidential              bool            `json:"is_confidential"`
+	RequirePKCE                 bool            `json:"require_pkce"`
+	TokenEndpointAuthMethod     string          `json:"token_endpoint_auth_method"`                 // Defaults by client type when empty
+	AccessTokenFormat           string          `json:"access_token_format"`                        // legacy or jwt, server default when empty
+	AllowedResources            []string        `json:"allowed_resources"`                          // Absolute URIs of the resource servers the client may request
+	IDTokenSignedResponseAlg    string          `json:"id_token_signed_response_alg"`               // RS256 (the default), ES256, or HS256 keyed with the client secret to sign ID tokens
+	IDTokenEncryptedResponseAlg string          `json:"id_token_encrypted_response_alg"`            // RSA-OAEP or RSA-OAEP-256 to encrypt ID tokens
+	IDTokenEncryptedResponseEnc string          `json:"id_token_encrypted_response_enc"`            // Content encryption, A128CBC-HS256 when empty
+	UserInfoSigningAlg          string          `json:"userinfo_signed_response_alg"`               // RS256 or ES256 to answer UserInfo requests with a signed JWT, plain JSON when empty
+	UserInfoEncryptionAlg       string          `json:"userinfo_encrypted_response_alg"`            // RSA-OAEP or RSA-OAEP-256 to encrypt signed UserInfo responses
+	UserInfoEncryptionEnc       string          `json:"userinfo_encrypted_response_enc"`            // Content encryption, A128CBC-HS256 when empty
+	BackchannelLogoutURI        string          `json:"backchannel_logout_uri"`                     // Absolute URI receiving logout tokens (OpenID Connect Back-Channel Logout)
+	FrontchannelLogoutURI       string          `json:"frontchannel_logout_uri"`                    // Absolute URI loaded in an iframe on logout (OpenID Connect Front-Channel Logout)
+	PostLogoutRedirectURIs      []string        `json:"post_logout_redirect_uris"`                  // Absolute URIs the user may be sent to after logging out
+	RequestURIs                 []string        `json:"request_uris"`                               // https URIs of request objects the server may fetch (RFC 9101)
+	RequirePushedAuthRequests   bool            `json:"require_pushed_authorization_requests"`      // Reject authorization requests not pushed first (RFC 9126)
+	BindTokenToIP               bool            `json:"bind_token_to_ip"`                           // Reject refreshes from outside the subnet the refresh token was issued to
+	TLSClientAuthSubjectDN      string          `json:"tls_client_auth_subject_dn"`                 // Certificate subject DN for tls_client_auth, in RFC 4514 form
+	CertificateBoundTokens      bool            `json:"tls_client_certificate_bound_access_tokens"` // Bind access tokens to the client certificate (RFC 8705)
+	ManagedState                bool            `json:"managed_state"`                              // Have the server issue and check a signed state, for first-party clients
+	SubjectType                 string          `json:"subject_type"`                               // public or pairwise, public when empty
+	SectorIdentifierURI         string          `json:"sector_identifier_uri"`                      // https URI listing redirect URIs of clients sharing pairwise subjects
+	AccessTokenLifetime         int             `json:"access_token_lifetime"`                      // Seconds, grant type default when zero
+	RefreshTokenLifetime        int             `json:"refresh_token_lifetime"`                     // Seconds, grant type default when zero
+	IDTokenLifetime             int             `json:"id_token_lifetime"`                          // Seconds, grant type default when zero
+	ClaimTemplates              []ClaimTemplate `json:"claim_templates"`                            // Custom claims added to the client's tokens
 }
 
 // UpdateClientRequest represents the data used to update an existing OAuth client.
 // All fields are optional - only non-empty fields will be updated.
 type UpdateClientRequest struct {
-	ClientName      string   `json:"client_name"`
-	Description     string   `json:"description"`
-	ClientURI       string   `json:"client_uri"`
-	LogoURI         string   `json:"logo_uri"`
-	RedirectURIs    []string `json:"redirect_uris"`
-	GrantTypes      []string `json:"grant_types"`
-	ResponseTypes   []string `json:"response_types"`
-	Scope           string   `json:"scope"`
-	TOSUri          string   `json:"tos_uri"`
-	PolicyURI       string   `json:"policy_uri"`
-	JwksURI         string   `json:"jwks_uri"`
-	Jwks            string   `json:"jwks"`
-	Contacts        []string `json:"contacts"`
-	SoftwareID      string   `json:"software_id"`
-	SoftwareVersion string   `json:"software_version"`
+	ClientName                  string           `json:"client_name"`
+	Description                 string           `json:"description"`
+	ClientURI                   string           `json:"client_uri"`
+	LogoURI                     string           `json:"logo_uri"`
+	RedirectURIs                []string         `json:"redirect_uris"`
+	GrantTypes                  []string         `json:"grant_types"`
+	ResponseTypes               []string         `json:"response_types"`
+	Scope                       string           `json:"scope"`
+	TOSUri                      string           `json:"tos_uri"`
+	PolicyURI                   string           `json:"policy_uri"`
+	JwksURI                     string           `json:"jwks_uri"`
+	Jwks                        string           `json:"jwks"`
+	Contacts                    []string         `json:"contacts"`
+	SoftwareID                  string           `json:"software_id"`
+	SoftwareVersion             string           `json:"software_version"`
+	RequirePKCE                 *bool            `json:"require_pkce"`
+	TokenEndpointAuthMethod     string           `json:"token_endpoint_auth_method"`
+	AccessTokenFormat           string           `json:"access_token_format"`
+	AllowedResources            []string         `json:"allowed_resources"`
+	IDTokenSignedResponseAlg    string           `json:"id_token_signed_response_alg"`
+	IDTokenEncryptedResponseAlg string           `json:"id_token_encrypted_response_alg"`
+	IDTokenEncryptedResponseEnc string           `json:"id_token_encrypted_response_enc"`
+	UserInfoSigningAlg          string           `json:"userinfo_signed_response_alg"`
+	UserInfoEncryptionAlg       string           `json:"userinfo_encrypted_response_alg"`
+	UserInfoEncryptionEnc       string           `json:"userinfo_encrypted_response_enc"`
+	BackchannelLogoutURI        string           `json:"backchannel_logout_uri"`
+	FrontchannelLogoutURI       string           `json:"frontchannel_logout_uri"`
+	PostLogoutRedirectURIs      []string         `json:"post_logout_redirect_uris"`
+	RequestURIs                 []string         `json:"request_uris"`
+	RequirePushedAuthRequests   *bool            `json:"require_pushed_authorization_requests"`
+	BindTokenToIP               *bool            `json:"bind_token_to_ip"`
+	TLSClientAuthSubjectDN      string           `json:"tls_client_auth_subject_dn"`
+	CertificateBoundTokens      *bool            `json:"tls_client_certificate_bound_access_tokens"`
+	ManagedState                *bool            `json:"managed_state"`
+	SubjectType                 string           `json:"subject_type"`
+	SectorIdentifierURI         string           `json:"sector_identifier_uri"`
+	AccessTokenLifetime         *int             `json:"access_token_lifetime"`
+	RefreshTokenLifetime        *int             `json:"refresh_token_lifetime"`
+	IDTokenLifetime             *int             `json:"id_token_lifetime"`
+	ClaimTemplates              *[]ClaimTemplate `json:"claim_templates"` // Replaces the claim templates when sent, an empty list removing them
 }
 
 // ClientResponse represents an OAuth client response returned to API consumers.
 // It contains all client metadata but only includes the client secret when
 // initially created (it cannot be retrieved later).
 type ClientResponse struct {
-	ID             uint      `json:"id"`
-	ClientID       string    `json:"client_id"`
-	ClientSecret   string    `json:"client_secret,omitempty"`
-	ClientName     string    `json:"client_name"`
-	Description    string    `json:"description,omitempty"`
-	ClientURI      string    `json:"client_uri,omitempty"`
-	LogoURI        string    `json:"logo_uri,omitempty"`
-	RedirectURIs   []string  `json:"redirect_uris"`
-	GrantTypes     []string  `json:"grant_types"`
-	ResponseTypes  []string  `json:"response_types,omitempty"`
-	Scope          string    `json:"scope"`
-	TOSUri         string    `json:"tos_uri,omitempty"`
-	PolicyURI      string    `json:"policy_uri,omitempty"`
-	IsConfidential bool      `json:"is_confidential"`
-	IsActive       bool      `json:"is_active"`
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
+	ID                          uint            `json:"id"`
+	ClientID                    string          `json:"client_id"`
+	ClientSecret                string          `json:"client_secret,omitempty"`
+	ClientName                  string          `json:"client_name"`
+	Description                 string          `json:"description,omitempty"`
+	ClientURI                   string          `json:"client_uri,omitempty"`
+	LogoURI                     string          `json:"logo_uri,omitempty"`
+	RedirectURIs                []string        `json:"redirect_uris"`
+	GrantTypes                  []string        `json:"grant_types"`
+	ResponseTypes               []string        `json:"response_types,omitempty"`
+	Scope                       string          `json:"scope"`
+	TOSUri                      string          `json:"tos_uri,omitempty"`
+	PolicyURI                   string          `json:"policy_uri,omitempty"`
+	IsConfidential              bool            `json:"is_confidential"`
+	RequirePKCE                 bool            `json:"require_pkce"`
+	TokenEndpointAuthMethod     string          `json:"token_endpoint_auth_method"`
+	AccessTokenFormat           string          `json:"access_token_format,omitempty"`
+	AllowedResources            []string        `json:"allowed_resources,omitempty"`
+	IDTokenSignedResponseAlg    string          `json:"id_token_signed_response_alg,omitempty"`
+	IDTokenEncryptedResponseAlg string          `json:"id_token_encrypted_response_alg,omitempty"`
+	IDTokenEncryptedResponseEnc string          `json:"id_token_encrypted_response_enc,omitempty"`
+	UserInfoSigningAlg          string          `json:"userinfo_signed_response_alg,omitempty"`
+	UserInfoEncryptionAlg       string          `json:"userinfo_encrypted_response_alg,omitempty"`
+	UserInfoEncryptionEnc       string          `json:"userinfo_encrypted_response_enc,omitempty"`
+	BackchannelLogoutURI        string          `json:"backchannel_logout_uri,omitempty"`
+	FrontchannelLogoutURI       string          `json:"frontchannel_logout_uri,omitempty"`
+	PostLogoutRedirectURIs      []string        `json:"post_logout_redirect_uris,omitempty"`
+	RequestURIs                 []string        `json:"request_uris,omitempty"`
+	RequirePushedAuthRequests   bool            `json:"require_pushed_authorization_requests,omitempty"`
+	BindTokenToIP               bool            `json:"bind_token_to_ip,omitempty"`
+	TLSClientAuthSubjectDN      string          `json:"tls_client_auth_subject_dn,omitempty"`
+	CertificateBoundTokens      bool            `json:"tls_client_certificate_bound_access_tokens,omitempty"`
+	ManagedState                bool            `json:"managed_state,omitempty"`
+	SubjectType                 string          `json:"subject_type"`
+	SectorIdentifierURI         string          `json:"sector_identifier_uri,omitempty"`
+	AccessTokenLifetime         int             `json:"access_token_lifetime,omitempty"`
+	RefreshTokenLifetime        int             `json:"refresh_token_lifetime,omitempty"`
+	IDTokenLifetime             int             `json:"id_token_lifetime,omitempty"`
+	ClaimTemplates              []ClaimTemplate `json:"claim_templates,omitempty"`
+	RateLimitTier               string          `json:"rate_limit_tier,omitempty"`
+	IsActive                    bool            `json:"is_active"`
+	CreatedAt                   time.Time       `json:"created_at"`
+	UpdatedAt                   time.Time       `json:"updated_at"`
 }
 
 // ClientListResponse represents a paginated list of OAuth clients.
@@ -76,3 +155,98 @@ type ClientListResponse struct {
 	Page    int              `json:"page"`     // The current page number (1-indexed)
 	PerPage int              `json:"per_page"` // The number of items per page
 }
+
+// SecretRotationResponse carries a client secret generated by a rotation.
+// The secret is only returned this once.
+type SecretRotationResponse struct {
+	ClientID                string     `json:"client_id"`
+	ClientSecret            string     `json:"client_secret"`
+	PreviousSecretExpiresAt *time.Time `json:"previous_secret_expires_at,omitempty"` // When the replaced secret stops working, absent if it already has
+}
+
+// RegistrationRequest represents client metadata submitted for dynamic client registration
+// (RFC 7591 Section 2) or for updating a registered client (RFC 7592 Section 2.2).
+// Omitted grant_types, response_types, and token_endpoint_auth_method take the RFC defaults.
+type RegistrationRequest struct {
+	ClientID                    string          `json:"client_id,omitempty"` // Required on update, must match the registered client
+	RedirectURIs                []string        `json:"redirect_uris"`
+	GrantTypes                  []string        `json:"grant_types"`
+	ResponseTypes               []string        `json:"response_types"`
+	ClientName                  string          `json:"client_name"`
+	ClientURI                   string          `json:"client_uri"`
+	LogoURI                     string          `json:"logo_uri"`
+	Scope                       string          `json:"scope"`
+	Contacts                    []string        `json:"contacts"`
+	TOSUri                      string          `json:"tos_uri"`
+	PolicyURI                   string          `json:"policy_uri"`
+	JwksURI                     string          `json:"jwks_uri"`
+	Jwks                        json.RawMessage `json:"jwks,omitempty"`
+	SoftwareID                  string          `json:"software_id"`
+	SoftwareVersion             string          `json:"software_version"`
+	TokenEndpointAuthMethod     string          `json:"token_endpoint_auth_method"`
+	IDTokenSignedResponseAlg    string          `json:"id_token_signed_response_alg,omitempty"`
+	IDTokenEncryptedResponseAlg string          `json:"id_token_encrypted_response_alg,omitempty"`
+	IDTokenEncryptedResponseEnc string          `json:"id_token_encrypted_response_enc,omitempty"`
+	UserInfoSigningAlg          string          `json:"userinfo_signed_response_alg,omitempty"`
+	UserInfoEncryptionAlg       string          `json:"userinfo_encrypted_response_alg,omitempty"`
+	UserInfoEncryptionEnc       string          `json:"userinfo_encrypted_response_enc,omitempty"`
+	BackchannelLogoutURI        string          `json:"backchannel_logout_uri,omitempty"`
+	FrontchannelLogoutURI       string          `json:"frontchannel_logout_uri,omitempty"`
+	PostLogoutRedirectURIs      []string        `json:"post_logout_redirect_uris,omitempty"`
+	RequestURIs                 []string        `json:"request_uris,omitempty"`
+	RequirePushedAuthRequests   bool            `json:"require_pushed_authorization_requests,omitempty"`
+	BindTokenToIP               bool            `json:"bind_token_to_ip,omitempty"`
+	TLSClientAuthSubjectDN      string          `json:"tls_client_auth_subject_dn,omitempty"`
+	CertificateBoundTokens      bool            `json:"tls_client_certificate_bound_access_tokens,omitempty"`
+	SubjectType                 string          `json:"subject_type,omitempty"`
+	SectorIdentifierURI         string          `json:"sector_identifier_uri,omitempty"`
+}
+
+// RegistrationResponse represents the client information response (RFC 7591 Section 3.2.1).
+// The client secret and registration access token are only returned at registration time.
+type RegistrationResponse struct {
+	ClientID                    string          `json:"client_id"`
+	ClientSecret                string          `json:"client_secret,omitempty"`
+	ClientIDIssuedAt            int64           `json:"client_id_issued_at"`
+	ClientSecretExpiresAt       *int64          `json:"client_secret_expires_at,omitempty"` // Zero when a secret is issued, as secrets do not expire
+	RegistrationAccessToken     string          `json:"registration_access_token,omitempty"`
+	RegistrationClientURI       string          `json:"registration_client_uri"`
+	RedirectURIs                []string        `json:"redirect_uris"`
+	GrantTypes                  []string        `json:"grant_types"`
+	ResponseTypes               []string        `json:"response_types"`
+	ClientName                  string          `json:"client_name,omitempty"`
+	ClientURI                   string          `json:"client_uri,omitempty"`
+	LogoURI                     string          `json:"logo_uri,omitempty"`
+	Scope                       string          `json:"scope"`
+	Contacts                    []string        `json:"contacts,omitempty"`
+	TOSUri                      string          `json:"tos_uri,omitempty"`
+	PolicyURI                   string          `json:"policy_uri,omitempty"`
+	JwksURI                     string          `json:"jwks_uri,omitempty"`
+	Jwks                        json.RawMessage `json:"jwks,omitempty"`
+	SoftwareID                  string          `json:"software_id,omitempty"`
+	SoftwareVersion             string          `json:"software_version,omitempty"`
+	TokenEndpointAuthMethod     string          `json:"token_endpoint_auth_method"`
+	IDTokenSignedResponseAlg    string          `json:"id_token_signed_response_alg,omitempty"`
+	IDTokenEncryptedResponseAlg string          `json:"id_token_encrypted_response_alg,omitempty"`
+	IDTokenEncryptedResponseEnc string          `json:"id_token_encrypted_response_enc,omitempty"`
+	UserInfoSigningAlg          string          `json:"userinfo_signed_response_alg,omitempty"`
+	UserInfoEncryptionAlg       string          `json:"userinfo_encrypted_response_alg,omitempty"`
+	UserInfoEncryptionEnc       string          `json:"userinfo_encrypted_response_enc,omitempty"`
+	BackchannelLogoutURI        string          `json:"backchannel_logout_uri,omitempty"`
+	FrontchannelLogoutURI       string          `json:"frontchannel_logout_uri,omitempty"`
+	PostLogoutRedirectURIs      []string        `json:"post_logout_redirect_uris,omitempty"`
+	RequestURIs                 []string        `json:"request_uris,omitempty"`
+	RequirePushedAuthRequests   bool            `json:"require_pushed_authorization_requests,omitempty"`
+	BindTokenToIP               bool            `json:"bind_token_to_ip,omitempty"`
+	TLSClientAuthSubjectDN      string          `json:"tls_client_auth_subject_dn,omitempty"`
+	CertificateBoundTokens      bool            `json:"tls_client_certificate_bound_access_tokens,omitempty"`
+	SubjectType                 string          `json:"subject_type"`
+	SectorIdentifierURI         string          `json:"sector_identifier_uri,omitempty"`
+}
+
+// RegistrationErrorResponse represents a client registration error (RFC 7591 Section 3.2.2).
+type RegistrationErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+	RequestID        string `json:"request_id,omitempty"` // Correlation ID for tracing the request in server logs
+}
@@ -25,7 +25,7 @@ func NewHandler(service *Service) *Handler {
 }
 
 // RegisterRoutes sets up the client-related routes on the provided router group.
-// All routes are protected with web authentication middleware.
+// All routes are protected with web authentication middleware and, for cookie sessions, a CSRF token.
 // Routes include:
 // - POST /clients - Create a new OAuth client
 // - GET /clients - List all clients for the authenticated user
@@ -34,6 +34,7 @@ func NewHandler(service *Service) *Handler {
 // - DELETE /clients/:id - Delete a specific client
 func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
 	// All client endpoints require web authentication
+	r.Use(middleware.CSRF())
 	r.Use(middleware.WebAuth(h.service.authService))
 
 	r.POST("", h.Create)
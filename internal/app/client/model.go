@@ -3,7 +3,40 @@
 package client
 
 import (
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/verigate/verigate-server/internal/pkg/config"
+	"github.com/verigate/verigate-server/internal/pkg/utils/hash"
+	jwtutil "github.com/verigate/verigate-server/internal/pkg/utils/jwt"
+)
+
+// Token endpoint client authentication methods (OpenID Connect Core Section 9)
+const (
+	AuthMethodClientSecretBasic = "client_secret_basic" // Secret sent with HTTP Basic authentication
+	AuthMethodClientSecretPost  = "client_secret_post"  // Secret sent in the request body
+	AuthMethodPrivateKeyJWT     = "private_key_jwt"     // Signed JWT assertion verified with the client's public key (RFC 7523)
+	AuthMethodNone              = "none"                // Public client that does not authenticate
+
+	// Mutual TLS client authentication (RFC 8705 Section 2)
+	AuthMethodTLSClientAuth           = "tls_client_auth"             // CA-issued certificate with the registered subject DN
+	AuthMethodSelfSignedTLSClientAuth = "self_signed_tls_client_auth" // Self-signed certificate registered in the client's JWKS
+)
+
+// Access token formats
+const (
+	AccessTokenFormatLegacy = "legacy" // Signed token addressed to the client, validated through this server
+	AccessTokenFormatJWT    = "jwt"    // JWT profile for access tokens (RFC 9068) that resource servers validate locally
+)
+
+// Subject identifier types (OpenID Connect Core Section 8)
+const (
+	SubjectTypePublic   = "public"   // Every client receives the same sub for a user
+	SubjectTypePairwise = "pairwise" // Each sector receives a different sub for a user
 )
 
 // Client represents an OAuth client application registered with the system.
@@ -11,27 +44,310 @@ import (
 // Client represents an OAuth client application registered with the system.
 // It stores all metadata required for OAuth 2.0 operations and client authentication.
 type Client struct {
-	ID              uint      `json:"id"`                         // Internal unique identifier
-	ClientID        string    `json:"client_id"`                  // Public unique identifier for the client
-	ClientSecret    string    `json:"client_secret,omitempty"`    // Hashed client secret for confidential clients
-	ClientName      string    `json:"client_name"`                // Human-readable name of the client
-	Description     string    `json:"description,omitempty"`      // Optional description of the client
-	ClientURI       string    `json:"client_uri,omitempty"`       // URI of the client's homepage
-	LogoURI         string    `json:"logo_uri,omitempty"`         // URI of the client's logo
-	RedirectURIs    []string  `json:"redirect_uris"`              // Authorized redirect URIs for authorization code flow
-	GrantTypes      []string  `json:"grant_types"`                // Allowed OAuth grant types for this client
-	ResponseTypes   []string  `json:"response_types,omitempty"`   // Allowed OAuth response types
-	Scope           string    `json:"scope"`                      // Default scope string for the client
-	TOSUri          string    `json:"tos_uri,omitempty"`          // URI to the client's terms of service
-	PolicyURI       string    `json:"policy_uri,omitempty"`       // URI to the client's privacy policy
-	JwksURI         string    `json:"jwks_uri,omitempty"`         // URI to the client's JSON Web Key Set
-	Jwks            string    `json:"jwks,omitempty"`             // JSON Web Key Set as a string
-	Contacts        []string  `json:"contacts,omitempty"`         // Contact information for the client
-	SoftwareID      string    `json:"software_id,omitempty"`      // Software identifier
-	SoftwareVersion string    `json:"software_version,omitempty"` // Software version
-	IsConfidential  bool      `json:"is_confidential"`            // Whether the client is confidential (can keep a secret)
-	IsActive        bool      `json:"is_active"`                  // Whether the client is active and allowed to be used
-	CreatedAt       time.Time `json:"created_at"`                 // When the client was created
-	UpdatedAt       time.Time `json:"updated_at"`                 // When the client was last updated
-	OwnerID         uint      `json:"owner_id"`                   // User ID of the client owner
+	ID                          uint            `json:"id"`                                         // Internal unique identifier
+	ClientID                    string          `json:"client_id"`                                  // Public unique identifier for the client
+	ClientSecret                string          `json:"client_secret,omitempty"`                    // Hashed client secret for confidential clients
+	ClientName                  string          `json:"client_name"`                                // Human-readable name of the client
+	Description                 string          `json:"description,omitempty"`                      // Optional description of the client
+	ClientURI                   string          `json:"client_uri,omitempty"`                       // URI of the client's homepage
+	LogoURI                     string          `json:"logo_uri,omitempty"`                         // URI of the client's logo
+	RedirectURIs                []string        `json:"redirect_uris"`                              // Authorized redirect URIs for authorization code flow
+	GrantTypes                  []string        `json:"grant_types"`                                // Allowed OAuth grant types for this client
+	ResponseTypes               []string        `json:"response_types,omitempty"`                   // Allowed OAuth response types
+	Scope                       string          `json:"scope"`                                      // Default scope string for the client
+	TOSUri                      string          `json:"tos_uri,omitempty"`                          // URI to the client's terms of service
+	PolicyURI                   string          `json:"policy_uri,omitempty"`                       // URI to the client's privacy policy
+	JwksURI                     string          `json:"jwks_uri,omitempty"`                         // URI to the client's JSON Web Key Set
+	Jwks                        string          `json:"jwks,omitempty"`                             // JSON Web Key Set as a string
+	Contacts                    []string        `json:"contacts,omitempty"`                         // Contact information for the client
+	SoftwareID                  string          `json:"software_id,omitempty"`                      // Software identifier
+	SoftwareVersion             string          `json:"software_version,omitempty"`                 // Software version
+	IsConfidential              bool            `json:"is_confidential"`                            // Whether the client is confidential (can keep a secret)
+	RequirePKCE                 bool            `json:"require_pkce"`                               // Whether PKCE is mandatory even for confidential clients
+	TokenEndpointAuthMethod     string          `json:"token_endpoint_auth_method"`                 // How the client authenticates at the token endpoint
+	AccessTokenFormat           string          `json:"access_token_format"`                        // Format of issued access tokens, empty for the server default
+	AllowedResources            []string        `json:"allowed_resources"`                          // Resource servers the client may request tokens for (RFC 8707)
+	IDTokenSignedResponseAlg    string          `json:"id_token_signed_response_alg"`               // JWS algorithm ID tokens are signed with, empty for RS256
+	IDTokenSigningSecret        string          `json:"-"`                                          // Client secret sealed with the client secret encryption key, kept only for HS256 ID tokens
+	IDTokenEncryptedResponseAlg string          `json:"id_token_encrypted_response_alg"`            // JWE key management algorithm for ID tokens, empty for signed-only
+	IDTokenEncryptedResponseEnc string          `json:"id_token_encrypted_response_enc"`            // JWE content encryption algorithm for ID tokens
+	UserInfoSigningAlg          string          `json:"userinfo_signed_response_alg"`               // JWS algorithm UserInfo responses are signed with, empty for plain JSON
+	UserInfoEncryptionAlg       string          `json:"userinfo_encrypted_response_alg"`            // JWE key management algorithm for UserInfo responses, empty for signed-only
+	UserInfoEncryptionEnc       string          `json:"userinfo_encrypted_response_enc"`            // JWE content encryption algorithm for UserInfo responses
+	BackchannelLogoutURI        string          `json:"backchannel_logout_uri"`                     // Endpoint notified when a user's session ends, empty if not registered
+	FrontchannelLogoutURI       string          `json:"frontchannel_logout_uri"`                    // Page loaded in an iframe when a user's session ends, empty if not registered
+	PostLogoutRedirectURIs      []string        `json:"post_logout_redirect_uris"`                  // Where the user may be sent after logging out (RP-Initiated Logout)
+	RequestURIs                 []string        `json:"request_uris"`                               // URIs the server may fetch request objects from (RFC 9101), the only ones it will fetch
+	RequirePushedAuthRequests   bool            `json:"require_pushed_authorization_requests"`      // Whether authorization requests must be pushed first (RFC 9126)
+	BindTokenToIP               bool            `json:"bind_token_to_ip"`                           // Whether refresh tokens only work from the subnet they were issued to
+	TLSClientAuthSubjectDN      string          `json:"tls_client_auth_subject_dn"`                 // Subject DN of the certificate a tls_client_auth client authenticates with
+	CertificateBoundTokens      bool            `json:"tls_client_certificate_bound_access_tokens"` // Whether access tokens are bound to the client certificate (RFC 8705 Section 3)
+	ManagedState                bool            `json:"managed_state"`                              // Whether the server issues a signed state bound to the session, checked at the code exchange
+	SubjectType                 string          `json:"subject_type"`                               // public or pairwise sub values for this client
+	SectorIdentifierURI         string          `json:"sector_identifier_uri"`                      // Document listing redirect URIs of clients sharing a pairwise sector, empty if not registered
+	AccessTokenLifetime         int             `json:"access_token_lifetime"`                      // Access token lifetime in seconds, zero for the grant type default
+	RefreshTokenLifetime        int             `json:"refresh_token_lifetime"`                     // Refresh token lifetime in seconds, zero for the grant type default
+	IDTokenLifetime             int             `json:"id_token_lifetime"`                          // ID token lifetime in seconds, zero for the grant type default
+	ClaimTemplates              []ClaimTemplate `json:"claim_templates"`                            // Custom claims added to issued tokens
+	RateLimitTier               string          `json:"rate_limit_tier"`                            // Rate limit tier applied once the client authenticates, empty for the configured one
+	IsActive                    bool            `json:"is_active"`                                  // Whether the client is active and allowed to be used
+	CreatedAt                   time.Time       `json:"created_at"`                                 // When the client was created
+	UpdatedAt                   time.Time       `json:"updated_at"`                                 // When the client was last updated
+	OwnerID                     uint            `json:"owner_id"`                                   // User ID of the client owner, zero for dynamically registered clients
+	RegistrationAccessToken     string          `json:"-"`                                          // Hash of the token managing a dynamically registered client
+
+	// Secret replaced by the last rotation, still accepted during the grace period so the
+	// client's deployments can switch over; empty when there is none
+	PreviousClientSecret    string     `json:"-"` // Hash of the previous client secret
+	PreviousSecretExpiresAt *time.Time `json:"-"` // When the previous client secret stops being accepted
+}
+
+// ClientFilter narrows a client search. Zero fields match every client.
+type ClientFilter struct {
+	OwnerID        uint   // Only clients owned by this user
+	Query          string // Case-insensitive substring of the client name or client ID
+	IsActive       *bool  // Only active or only inactive clients
+	IsConfidential *bool  // Only confidential or only public clients
+}
+
+// AcceptsPreviousSecret reports whether the secret replaced by the last rotation is still
+// within its grace period at now.
+func (c *Client) AcceptsPreviousSecret(now time.Time) bool {
+	return c.PreviousClientSecret != "" && c.PreviousSecretExpiresAt != nil && now.Before(*c.PreviousSecretExpiresAt)
+}
+
+// DefaultTokenEndpointAuthMethod returns the authentication method assigned when a
+// client does not register one: Basic authentication for confidential clients and
+// none for public clients.
+func DefaultTokenEndpointAuthMethod(isConfidential bool) string {
+	if isConfidential {
+		return AuthMethodClientSecretBasic
+	}
+	return AuthMethodNone
+}
+
+// IsValidTokenEndpointAuthMethod reports whether method is supported and suits the client type.
+// Confidential clients must use a secret, a private key, or a certificate, and public clients must use none.
+func IsValidTokenEndpointAuthMethod(method string, isConfidential bool) bool {
+	switch method {
+	case AuthMethodClientSecretBasic, AuthMethodClientSecretPost, AuthMethodPrivateKeyJWT,
+		AuthMethodTLSClientAuth, AuthMethodSelfSignedTLSClientAuth:
+		return isConfidential
+	case AuthMethodNone:
+		return !isConfidential
+	}
+	return false
+}
+
+// IsValidAccessTokenFormat reports whether format is a supported access token format.
+// The empty format selects the server default.
+func IsValidAccessTokenFormat(format string) bool {
+	return format == "" || format == AccessTokenFormatLegacy || format == AccessTokenFormatJWT
+}
+
+// IssuesJWTAccessTokens reports whether access tokens issued to the client follow RFC 9068,
+// either because the client selected that format or because it is the server default.
+func (c *Client) IssuesJWTAccessTokens() bool {
+	format := c.AccessTokenFormat
+	if format == "" {
+		format = config.AppConfig.AccessTokenFormat
+	}
+	return format == AccessTokenFormatJWT
+}
+
+// IDTokenSigningAlg returns the JWS algorithm ID tokens issued to the client are signed with,
+// RS256 unless the client registered another (OpenID Connect Dynamic Client Registration Section 2).
+func (c *Client) IDTokenSigningAlg() string {
+	if c.IDTokenSignedResponseAlg == "" {
+		return jwtutil.SigningAlgRS256
+	}
+	return c.IDTokenSignedResponseAlg
+}
+
+// SupportedIDTokenSigningAlgorithms returns the ID token signing algorithms clients can register,
+// for discovery metadata. HS256 is only offered when client secrets can be kept to sign with.
+func SupportedIDTokenSigningAlgorithms() []string {
+	if config.AppConfig.ClientSecretEncryptionKey == "" {
+		return []string{jwtutil.SigningAlgRS256, jwtutil.SigningAlgES256}
+	}
+	return jwtutil.SupportedSigningAlgorithms()
+}
+
+// SupportedUserInfoSigningAlgorithms returns the UserInfo signing algorithms clients can register,
+// for discovery metadata. Responses are signed with the server's published keys, never with a
+// client secret.
+func SupportedUserInfoSigningAlgorithms() []string {
+	return []string{jwtutil.SigningAlgRS256, jwtutil.SigningAlgES256}
+}
+
+// IDTokenEncryption returns the JWE algorithms ID tokens issued to the client are encrypted with.
+// The content encryption defaults to A128CBC-HS256 as required by OpenID Connect Dynamic
+// Client Registration Section 2. Returns empty strings if ID tokens are only signed.
+func (c *Client) IDTokenEncryption() (string, string) {
+	if c.IDTokenEncryptedResponseAlg == "" {
+		return "", ""
+	}
+	enc := c.IDTokenEncryptedResponseEnc
+	if enc == "" {
+		enc = jwtutil.JWEEncA128CBCHS256
+	}
+	return c.IDTokenEncryptedResponseAlg, enc
+}
+
+// UserInfoEncryption returns the JWE algorithms signed UserInfo responses to the client are
+// encrypted with, the content encryption defaulting to A128CBC-HS256 like that of ID tokens.
+// Returns empty strings if UserInfo responses are not encrypted.
+func (c *Client) UserInfoEncryption() (string, string) {
+	if c.UserInfoEncryptionAlg == "" {
+		return "", ""
+	}
+	enc := c.UserInfoEncryptionEnc
+	if enc == "" {
+		enc = jwtutil.JWEEncA128CBCHS256
+	}
+	return c.UserInfoEncryptionAlg, enc
+}
+
+// IsValidSubjectType reports whether subjectType is a supported subject identifier type.
+// The empty type selects public.
+func IsValidSubjectType(subjectType string) bool {
+	return subjectType == "" || subjectType == SubjectTypePublic || subjectType == SubjectTypePairwise
+}
+
+// SectorIdentifier returns the host pairwise subjects are computed for (OpenID Connect Core Section 8.1):
+// the host of the sector identifier URI when registered, otherwise the host of the first redirect URI.
+// Registration ensures that all redirect URIs share that host when no sector identifier URI is registered.
+func (c *Client) SectorIdentifier() string {
+	uri := c.SectorIdentifierURI
+	if uri == "" && len(c.RedirectURIs) > 0 {
+		uri = c.RedirectURIs[0]
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// Subject returns the sub value identifying the user to the client: the user ID for
+// public clients, or the user's pairwise subject in the client's sector.
+// Clients sharing a sector identifier receive the same pairwise subject.
+func (c *Client) Subject(userID uint) string {
+	if c.SubjectType != SubjectTypePairwise {
+		return strconv.FormatUint(uint64(userID), 10)
+	}
+	return hash.PairwiseSubject(c.SectorIdentifier(), userID, config.AppConfig.PairwiseSubjectSalt)
+}
+
+// AllowsRedirectURI reports whether uri is one of the client's registered redirect URIs.
+// URIs are compared as exact strings (OAuth 2.0 Security Best Current Practice Section 4.1.3),
+// without normalization, wildcards, or prefix matching. The one exception, when enabled in the
+// configuration, lets public clients use any port with a registered loopback IP redirect URI
+// (RFC 8252 Section 7.3).
+func (c *Client) AllowsRedirectURI(uri string) bool {
+	for _, registered := range c.RedirectURIs {
+		if registered == uri {
+			return true
+		}
+	}
+
+	if !config.AppConfig.RedirectURILoopbackAnyPort || c.IsConfidential {
+		return false
+	}
+	for _, registered := range c.RedirectURIs {
+		if matchesLoopbackRedirectURI(registered, uri) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesLoopbackRedirectURI reports whether uri is the registered http loopback IP redirect URI
+// with only the port changed. Every other part must be identical to the character, and
+// "localhost" does not qualify since it may resolve to a non-loopback address.
+func matchesLoopbackRedirectURI(registered, uri string) bool {
+	r, err := url.Parse(registered)
+	if err != nil || r.Scheme != "http" || r.User != nil {
+		return false
+	}
+	if ip := net.ParseIP(r.Hostname()); ip == nil || !ip.IsLoopback() {
+		return false
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil || u.Port() == "" {
+		return false
+	}
+
+	expected := *r
+	expected.Host = net.JoinHostPort(r.Hostname(), u.Port())
+	return expected.String() == uri
+}
+
+// AllowsPostLogoutRedirectURI reports whether uri exactly matches one of the client's
+// registered post-logout redirect URIs.
+func (c *Client) AllowsPostLogoutRedirectURI(uri string) bool {
+	for _, registered := range c.PostLogoutRedirectURIs {
+		if registered == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsResource reports whether the client is registered to request tokens for the resource.
+func (c *Client) AllowsResource(resource string) bool {
+	for _, allowed := range c.AllowedResources {
+		if allowed == resource {
+			return true
+		}
+	}
+	return false
+}
+
+// Response types the authorization endpoint can answer with (OpenID Connect Core Section 3),
+// written with their values in canonical order
+const (
+	ResponseTypeCode             = "code"                // Authorization code flow
+	ResponseTypeCodeIDToken      = "code id_token"       // Hybrid flow returning an ID token with the code
+	ResponseTypeCodeToken        = "code token"          // Hybrid flow returning an access token with the code
+	ResponseTypeCodeIDTokenToken = "code id_token token" // Hybrid flow returning both with the code
+)
+
+// NormalizeResponseType returns the response type with its space-separated values in canonical
+// order, as the order they are sent in carries no meaning (RFC 6749 Section 3.1.1).
+func NormalizeResponseType(responseType string) string {
+	values := strings.Fields(responseType)
+	sort.Strings(values)
+	return strings.Join(values, " ")
+}
+
+// AllowsResponseType reports whether the client is registered to use the response type.
+// Clients that registered no response types may use code, the RFC 7591 default.
+func (c *Client) AllowsResponseType(responseType string) bool {
+	responseType = NormalizeResponseType(responseType)
+	if len(c.ResponseTypes) == 0 {
+		return responseType == ResponseTypeCode
+	}
+	for _, registered := range c.ResponseTypes {
+		if NormalizeResponseType(registered) == responseType {
+			return true
+		}
+	}
+	return false
+}
+
+// IsClientSecretMethod reports whether method authenticates the client with a shared secret.
+func IsClientSecretMethod(method string) bool {
+	return method == AuthMethodClientSecretBasic || method == AuthMethodClientSecretPost
+}
+
+// IsTLSClientAuthMethod reports whether method authenticates the client with mutual TLS (RFC 8705 Section 2).
+func IsTLSClientAuthMethod(method string) bool {
+	return method == AuthMethodTLSClientAuth || method == AuthMethodSelfSignedTLSClientAuth
+}
+
+// UsesClientSecret reports whether the client authenticates with a shared secret.
+func (c *Client) UsesClientSecret() bool {
+	return IsClientSecretMethod(c.TokenEndpointAuthMethod)
 }
@@ -0,0 +1,67 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/verigate/verigate-server/internal/pkg/config"
+)
+
+func TestAllowsRedirectURI(t *testing.T) {
+	previous := config.AppConfig.RedirectURILoopbackAnyPort
+	t.Cleanup(func() { config.AppConfig.RedirectURILoopbackAnyPort = previous })
+
+	registered := []string{
+		"https://app.example.com/callback",
+		"https://app.example.com/cb?tenant=acme",
+		"http://127.0.0.1:8080/callback",
+		"http://[::1]/callback",
+		"http://localhost:8080/callback",
+	}
+
+	tests := []struct {
+		name         string
+		uri          string
+		confidential bool
+		anyPort      bool
+		want         bool
+	}{
+		{"exact match", "https://app.example.com/callback", false, false, true},
+		{"exact match with query", "https://app.example.com/cb?tenant=acme", false, false, true},
+		{"trailing slash added", "https://app.example.com/callback/", false, false, false},
+		{"trailing slash removed from host", "https://app.example.com", false, false, false},
+		{"host case differs", "https://APP.example.com/callback", false, false, false},
+		{"scheme case differs", "HTTPS://app.example.com/callback", false, false, false},
+		{"path case differs", "https://app.example.com/Callback", false, false, false},
+		{"query appended", "https://app.example.com/callback?next=https://evil.example", false, false, false},
+		{"query parameter injected", "https://app.example.com/cb?tenant=acme&redirect=https://evil.example", false, false, false},
+		{"query value changed", "https://app.example.com/cb?tenant=evil", false, false, false},
+		{"fragment appended", "https://app.example.com/callback#frag", false, false, false},
+		{"path prefix", "https://app.example.com/callback/../evil", false, false, false},
+		{"subdomain", "https://evil.app.example.com/callback", false, false, false},
+		{"userinfo", "https://app.example.com@evil.example/callback", false, false, false},
+		{"port added", "https://app.example.com:8443/callback", false, false, false},
+		{"scheme downgraded", "http://app.example.com/callback", false, false, false},
+
+		{"loopback registered port", "http://127.0.0.1:8080/callback", false, false, true},
+		{"loopback other port when disabled", "http://127.0.0.1:53177/callback", false, false, false},
+		{"loopback other port", "http://127.0.0.1:53177/callback", false, true, true},
+		{"loopback IPv6 any port", "http://[::1]:53177/callback", false, true, true},
+		{"loopback other port for confidential client", "http://127.0.0.1:53177/callback", true, true, false},
+		{"loopback other path", "http://127.0.0.1:53177/evil", false, true, false},
+		{"loopback with query", "http://127.0.0.1:53177/callback?x=1", false, true, false},
+		{"loopback without port", "http://127.0.0.1/callback", false, true, false},
+		{"loopback over https", "https://127.0.0.1:53177/callback", false, true, false},
+		{"localhost other port", "http://localhost:53177/callback", false, true, false},
+		{"non-loopback other port", "https://app.example.com:53177/callback", false, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.AppConfig.RedirectURILoopbackAnyPort = tt.anyPort
+			c := &Client{RedirectURIs: registered, IsConfidential: tt.confidential}
+			if got := c.AllowsRedirectURI(tt.uri); got != tt.want {
+				t.Errorf("AllowsRedirectURI(%q) = %v, want %v", tt.uri, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,439 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/pkg/db/routing"
+	"github.com/verigate/verigate-server/internal/pkg/tenant"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+	"github.com/verigate/verigate-server/internal/pkg/utils/hash"
+)
+
+// Dynamic client registration settings
+const (
+	RegistrationPath = "/api/v1/oauth/register" // Path of the client registration endpoint
+
+	defaultRegistrationScope  = "openid" // Scope assigned when a registration requests none
+	registrationTokenByteSize = 32       // Random bytes in a registration access token
+)
+
+// supportedGrantTypes lists the grant types that dynamically registered clients may use
+var supportedGrantTypes = map[string]bool{
+	"authorization_code": true,
+	"refresh_token":      true,
+	"client_credentials": true,
+	"urn:ietf:params:oauth:grant-type:device_code":    true,
+	"urn:ietf:params:oauth:grant-type:token-exchange": true,
+}
+
+// supportedResponseTypes lists the response types that dynamically registered clients may use
+var supportedResponseTypes = map[string]bool{
+	ResponseTypeCode:             true,
+	ResponseTypeCodeIDToken:      true,
+	ResponseTypeCodeToken:        true,
+	ResponseTypeCodeIDTokenToken: true,
+}
+
+// Register creates a client from dynamically submitted metadata (RFC 7591 Section 3).
+// The client is confidential unless it registers token_endpoint_auth_method "none".
+// Returns the client information including a client secret for confidential clients using
+// one and a registration access token for later management of the registration; both are
+// only available in this response.
+func (s *Service) Register(ctx context.Context, req RegistrationRequest) (*RegistrationResponse, error) {
+	createReq, err := registrationToCreateRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	registrationToken, err := generateRegistrationToken()
+	if err != nil {
+		return nil, errors.Internal(errors.ErrMsgFailedToGenerateRegistrationToken).Wrap(err)
+	}
+
+	client, clientSecret, err := s.create(ctx, 0, createReq, hash.HashToken(registrationToken))
+	if err != nil {
+		return nil, err
+	}
+
+	response := toRegistrationResponse(ctx, client)
+	response.ClientSecret = clientSecret
+	response.RegistrationAccessToken = registrationToken
+	if clientSecret != "" {
+		neverExpires := int64(0)
+		response.ClientSecretExpiresAt = &neverExpires
+	}
+	return response, nil
+}
+
+// GetRegistration returns the current registration of a dynamically registered client
+// (RFC 7592 Section 2.1) after verifying its registration access token.
+func (s *Service) GetRegistration(ctx context.Context, clientID, registrationToken string) (*RegistrationResponse, error) {
+	client, err := s.findRegisteredClient(ctx, clientID, registrationToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return toRegistrationResponse(ctx, client), nil
+}
+
+// UpdateRegistration replaces the metadata of a dynamically registered client
+// (RFC 7592 Section 2.2) after verifying its registration access token.
+// Omitted fields are reset to their defaults rather than left unchanged, and the
+// client cannot switch between confidential and public.
+func (s *Service) UpdateRegistration(ctx context.Context, clientID, registrationToken string, req RegistrationRequest) (*RegistrationResponse, error) {
+	client, err := s.findRegisteredClient(ctx, clientID, registrationToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.ClientID != clientID {
+		return nil, errors.BadRequest(errors.ErrMsgRegistrationClientIDMismatch)
+	}
+
+	updated, err := registrationToCreateRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if updated.IsConfidential != client.IsConfidential {
+		return nil, errors.BadRequest(errors.ErrMsgClientTypeChangeNotAllowed)
+	}
+	if err := validateClientKeys(updated.TokenEndpointAuthMethod, updated.Jwks, updated.JwksURI); err != nil {
+		return nil, err
+	}
+	if err := validateTLSClientAuth(updated.TokenEndpointAuthMethod, updated.TLSClientAuthSubjectDN); err != nil {
+		return nil, err
+	}
+	if err := validateIDTokenEncryption(updated.IDTokenEncryptedResponseAlg, updated.IDTokenEncryptedResponseEnc, updated.Jwks, updated.JwksURI); err != nil {
+		return nil, err
+	}
+	if err := validateUserInfoResponse(updated.UserInfoSigningAlg, updated.UserInfoEncryptionAlg, updated.UserInfoEncryptionEnc, updated.Jwks, updated.JwksURI); err != nil {
+		return nil, err
+	}
+	if err := validateBackchannelLogoutURI(updated.BackchannelLogoutURI); err != nil {
+		return nil, err
+	}
+	if err := validateFrontchannelLogoutURI(updated.FrontchannelLogoutURI); err != nil {
+		return nil, err
+	}
+	if err := validatePostLogoutRedirectURIs(updated.PostLogoutRedirectURIs); err != nil {
+		return nil, err
+	}
+	if err := validateRequestURIs(updated.RequestURIs); err != nil {
+		return nil, err
+	}
+	if err := validateSubjectType(ctx, updated.SubjectType, updated.SectorIdentifierURI, updated.RedirectURIs); err != nil {
+		return nil, err
+	}
+
+	// A client registered without a secret cannot switch to secret-based authentication
+	client.TokenEndpointAuthMethod = updated.TokenEndpointAuthMethod
+	if client.UsesClientSecret() && client.ClientSecret == "" {
+		return nil, errors.BadRequest(errors.ErrMsgInvalidTokenEndpointAuthMethod)
+	}
+	client.IDTokenSignedResponseAlg = updated.IDTokenSignedResponseAlg
+	if err := s.validateIDTokenSigningChange(client); err != nil {
+		return nil, err
+	}
+
+	client.ClientName = updated.ClientName
+	client.ClientURI = updated.ClientURI
+	client.LogoURI = updated.LogoURI
+	client.RedirectURIs = updated.RedirectURIs
+	client.GrantTypes = updated.GrantTypes
+	client.ResponseTypes = updated.ResponseTypes
+	client.Scope = updated.Scope
+	client.TOSUri = updated.TOSUri
+	client.PolicyURI = updated.PolicyURI
+	client.JwksURI = updated.JwksURI
+	client.Jwks = updated.Jwks
+	client.Contacts = updated.Contacts
+	client.SoftwareID = updated.SoftwareID
+	client.SoftwareVersion = updated.SoftwareVersion
+	client.IDTokenEncryptedResponseAlg = updated.IDTokenEncryptedResponseAlg
+	client.IDTokenEncryptedResponseEnc = updated.IDTokenEncryptedResponseEnc
+	client.UserInfoSigningAlg = updated.UserInfoSigningAlg
+	client.UserInfoEncryptionAlg = updated.UserInfoEncryptionAlg
+	client.UserInfoEncryptionEnc = updated.UserInfoEncryptionEnc
+	client.BackchannelLogoutURI = updated.BackchannelLogoutURI
+	client.FrontchannelLogoutURI = updated.FrontchannelLogoutURI
+	client.PostLogoutRedirectURIs = nonNilStrings(updated.PostLogoutRedirectURIs)
+	client.RequestURIs = nonNilStrings(updated.RequestURIs)
+	client.RequirePushedAuthRequests = updated.RequirePushedAuthRequests
+	client.BindTokenToIP = updated.BindTokenToIP
+	client.TLSClientAuthSubjectDN = updated.TLSClientAuthSubjectDN
+	client.CertificateBoundTokens = updated.CertificateBoundTokens
+	client.SubjectType = updated.SubjectType
+	client.SectorIdentifierURI = updated.SectorIdentifierURI
+	client.UpdatedAt = time.Now()
+
+	if err := s.repo.Update(ctx, client); err != nil {
+		return nil, err
+	}
+
+	return toRegistrationResponse(ctx, client), nil
+}
+
+// DeleteRegistration removes a dynamically registered client (RFC 7592 Section 2.3)
+// after verifying its registration access token.
+func (s *Service) DeleteRegistration(ctx context.Context, clientID, registrationToken string) error {
+	client, err := s.findRegisteredClient(ctx, clientID, registrationToken)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.Delete(ctx, client.ID)
+}
+
+// findRegisteredClient loads a dynamically registered client and verifies the registration
+// access token presented for it. Unknown clients, clients registered by other means, and
+// token mismatches are all reported as an invalid token so that client IDs cannot be probed.
+func (s *Service) findRegisteredClient(ctx context.Context, clientID, registrationToken string) (*Client, error) {
+	if registrationToken == "" {
+		return nil, errors.Unauthorized(errors.ErrMsgInvalidRegistrationAccessToken)
+	}
+
+	// Clients are read, updated, and deleted right after registering, so they are read from the primary
+	client, err := s.repo.FindByClientID(routing.WithPrimary(ctx), clientID)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil || client.RegistrationAccessToken == "" {
+		return nil, errors.Unauthorized(errors.ErrMsgInvalidRegistrationAccessToken)
+	}
+
+	presented := hash.HashToken(registrationToken)
+	if subtle.ConstantTimeCompare([]byte(presented), []byte(client.RegistrationAccessToken)) != 1 {
+		return nil, errors.Unauthorized(errors.ErrMsgInvalidRegistrationAccessToken)
+	}
+
+	return client, nil
+}
+
+// registrationToCreateRequest validates registration metadata, applies the RFC 7591
+// defaults, and converts it into a client creation request.
+func registrationToCreateRequest(req RegistrationRequest) (CreateClientRequest, error) {
+	grantTypes := req.GrantTypes
+	if len(grantTypes) == 0 {
+		grantTypes = []string{"authorization_code"}
+	}
+	responseTypes := req.ResponseTypes
+	if len(responseTypes) == 0 {
+		responseTypes = []string{"code"}
+	}
+	authMethod := req.TokenEndpointAuthMethod
+	if authMethod == "" {
+		authMethod = AuthMethodClientSecretBasic
+	}
+	isConfidential := authMethod != AuthMethodNone
+
+	if !IsValidTokenEndpointAuthMethod(authMethod, isConfidential) {
+		return CreateClientRequest{}, errors.BadRequest(errors.ErrMsgInvalidTokenEndpointAuthMethod)
+	}
+
+	for _, grantType := range grantTypes {
+		if !supportedGrantTypes[grantType] {
+			return CreateClientRequest{}, errors.BadRequest(errors.ErrMsgUnsupportedClientGrantType)
+		}
+		if (grantType == "client_credentials" || grantType == "urn:ietf:params:oauth:grant-type:token-exchange") && !isConfidential {
+			return CreateClientRequest{}, errors.BadRequest(errors.ErrMsgGrantTypeNotAllowedForPublicClient)
+		}
+	}
+	for _, responseType := range responseTypes {
+		if !supportedResponseTypes[NormalizeResponseType(responseType)] {
+			return CreateClientRequest{}, errors.BadRequest(errors.ErrMsgUnsupportedClientResponseType)
+		}
+	}
+
+	if containsString(grantTypes, "authorization_code") && len(req.RedirectURIs) == 0 {
+		return CreateClientRequest{}, errors.BadRequest(errors.ErrMsgRedirectURIsRequired)
+	}
+	for _, redirectURI := range req.RedirectURIs {
+		if err := validateRedirectURI(redirectURI, isConfidential); err != nil {
+			return CreateClientRequest{}, err
+		}
+	}
+
+	scope := strings.Join(strings.Fields(req.Scope), " ")
+	if scope == "" {
+		scope = defaultRegistrationScope
+	}
+	subjectType := req.SubjectType
+	if subjectType == "" {
+		subjectType = SubjectTypePublic
+	}
+
+	var jwks string
+	if len(req.Jwks) > 0 && string(req.Jwks) != "null" {
+		jwks = string(req.Jwks)
+	}
+
+	return CreateClientRequest{
+		ClientName:                  req.ClientName,
+		ClientURI:                   req.ClientURI,
+		LogoURI:                     req.LogoURI,
+		RedirectURIs:                nonNilStrings(req.RedirectURIs),
+		GrantTypes:                  grantTypes,
+		ResponseTypes:               responseTypes,
+		Scope:                       scope,
+		TOSUri:                      req.TOSUri,
+		PolicyURI:                   req.PolicyURI,
+		JwksURI:                     req.JwksURI,
+		Jwks:                        jwks,
+		Contacts:                    req.Contacts,
+		SoftwareID:                  req.SoftwareID,
+		SoftwareVersion:             req.SoftwareVersion,
+		IDTokenSignedResponseAlg:    req.IDTokenSignedResponseAlg,
+		IDTokenEncryptedResponseAlg: req.IDTokenEncryptedResponseAlg,
+		IDTokenEncryptedResponseEnc: req.IDTokenEncryptedResponseEnc,
+		UserInfoSigningAlg:          req.UserInfoSigningAlg,
+		UserInfoEncryptionAlg:       req.UserInfoEncryptionAlg,
+		UserInfoEncryptionEnc:       req.UserInfoEncryptionEnc,
+		BackchannelLogoutURI:        req.BackchannelLogoutURI,
+		FrontchannelLogoutURI:       req.FrontchannelLogoutURI,
+		PostLogoutRedirectURIs:      req.PostLogoutRedirectURIs,
+		RequestURIs:                 req.RequestURIs,
+		RequirePushedAuthRequests:   req.RequirePushedAuthRequests,
+		BindTokenToIP:               req.BindTokenToIP,
+		TLSClientAuthSubjectDN:      req.TLSClientAuthSubjectDN,
+		CertificateBoundTokens:      req.CertificateBoundTokens,
+		SubjectType:                 subjectType,
+		SectorIdentifierURI:         req.SectorIdentifierURI,
+		IsConfidential:              isConfidential,
+		TokenEndpointAuthMethod:     authMethod,
+	}, nil
+}
+
+// validateRedirectURI checks that a registered redirect URI is absolute and has no fragment
+// (RFC 6749 Section 3.1.2). Confidential clients, which run on web servers, must use https
+// on a non-loopback host; public native clients may also use loopback http redirects or
+// private-use URI schemes (RFC 8252 Section 7).
+func validateRedirectURI(redirectURI string, isConfidential bool) error {
+	if err := validateRedirectURIWildcard(redirectURI); err != nil {
+		return err
+	}
+
+	u, err := url.Parse(redirectURI)
+	if err != nil || !u.IsAbs() || u.Fragment != "" {
+		return errors.BadRequest(errors.ErrMsgInvalidRedirectUri).WithDetails(errors.ErrMsgRedirectURINotAbsolute)
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	if (scheme == "http" || scheme == "https") && u.Host == "" {
+		return errors.BadRequest(errors.ErrMsgInvalidRedirectUri).WithDetails(errors.ErrMsgRedirectURINotAbsolute)
+	}
+
+	if isConfidential && (scheme != "https" || isLoopbackHost(u.Hostname())) {
+		return errors.BadRequest(errors.ErrMsgInvalidRedirectUri).WithDetails(errors.ErrMsgRedirectURINotAllowed)
+	}
+	if !isConfidential && scheme == "http" && !isLoopbackHost(u.Hostname()) {
+		return errors.BadRequest(errors.ErrMsgInvalidRedirectUri).WithDetails(errors.ErrMsgRedirectURINotAllowed)
+	}
+
+	return nil
+}
+
+// validateRedirectURIWildcard rejects redirect URIs that look like wildcard patterns.
+// Redirect URIs are only ever matched exactly, so a pattern would never match what it seems to.
+func validateRedirectURIWildcard(redirectURI string) error {
+	if strings.Contains(redirectURI, "*") {
+		return errors.BadRequest(errors.ErrMsgInvalidRedirectUri).WithDetails(errors.ErrMsgRedirectURIWildcard)
+	}
+	return nil
+}
+
+// isLoopbackHost reports whether host is localhost or a loopback IP address.
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// toRegistrationResponse converts a client into its RFC 7591 client information response,
+// without the client secret or registration access token.
+func toRegistrationResponse(ctx context.Context, client *Client) *RegistrationResponse {
+	var jwks json.RawMessage
+	if client.Jwks != "" {
+		jwks = json.RawMessage(client.Jwks)
+	}
+
+	return &RegistrationResponse{
+		ClientID:                    client.ClientID,
+		ClientIDIssuedAt:            client.CreatedAt.Unix(),
+		RegistrationClientURI:       RegistrationClientURI(ctx, client.ClientID),
+		RedirectURIs:                client.RedirectURIs,
+		GrantTypes:                  client.GrantTypes,
+		ResponseTypes:               client.ResponseTypes,
+		ClientName:                  client.ClientName,
+		ClientURI:                   client.ClientURI,
+		LogoURI:                     client.LogoURI,
+		Scope:                       client.Scope,
+		Contacts:                    client.Contacts,
+		TOSUri:                      client.TOSUri,
+		PolicyURI:                   client.PolicyURI,
+		JwksURI:                     client.JwksURI,
+		Jwks:                        jwks,
+		SoftwareID:                  client.SoftwareID,
+		SoftwareVersion:             client.SoftwareVersion,
+		TokenEndpointAuthMethod:     client.TokenEndpointAuthMethod,
+		IDTokenSignedResponseAlg:    client.IDTokenSignedResponseAlg,
+		IDTokenEncryptedResponseAlg: client.IDTokenEncryptedResponseAlg,
+		IDTokenEncryptedResponseEnc: client.IDTokenEncryptedResponseEnc,
+		UserInfoSigningAlg:          client.UserInfoSigningAlg,
+		UserInfoEncryptionAlg:       client.UserInfoEncryptionAlg,
+		UserInfoEncryptionEnc:       client.UserInfoEncryptionEnc,
+		BackchannelLogoutURI:        client.BackchannelLogoutURI,
+		FrontchannelLogoutURI:       client.FrontchannelLogoutURI,
+		PostLogoutRedirectURIs:      client.PostLogoutRedirectURIs,
+		RequestURIs:                 client.RequestURIs,
+		RequirePushedAuthRequests:   client.RequirePushedAuthRequests,
+		BindTokenToIP:               client.BindTokenToIP,
+		TLSClientAuthSubjectDN:      client.TLSClientAuthSubjectDN,
+		CertificateBoundTokens:      client.CertificateBoundTokens,
+		SubjectType:                 client.SubjectType,
+		SectorIdentifierURI:         client.SectorIdentifierURI,
+	}
+}
+
+// RegistrationClientURI returns the client configuration endpoint URL for a client registered
+// with the tenant ctx is served for.
+func RegistrationClientURI(ctx context.Context, clientID string) string {
+	return tenant.BaseURL(ctx) + RegistrationPath + "/" + url.PathEscape(clientID)
+}
+
+// generateRegistrationToken creates a cryptographically secure registration access token.
+func generateRegistrationToken() (string, error) {
+	b := make([]byte, registrationTokenByteSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// containsString reports whether values contains value.
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// nonNilStrings returns values, or an empty slice if values is nil,
+// for columns that must not be NULL.
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
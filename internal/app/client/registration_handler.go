@@ -0,0 +1,133 @@
+package client
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/verigate/verigate-server/internal/pkg/middleware"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRegistrationRoutes sets up the dynamic client registration routes on the provided router group.
+// Registration is open; the configuration endpoints are protected by the registration
+// access token issued at registration rather than by user authentication.
+// Routes include:
+// - POST /register - Register a new client (RFC 7591)
+// - GET /register/:client_id - Read a client registration (RFC 7592)
+// - PUT /register/:client_id - Replace a client registration (RFC 7592)
+// - DELETE /register/:client_id - Delete a client registration (RFC 7592)
+func (h *Handler) RegisterRegistrationRoutes(r *gin.RouterGroup) {
+	r.POST("", h.RegisterClient)
+	r.GET("/:client_id", h.GetRegistration)
+	r.PUT("/:client_id", h.UpdateRegistration)
+	r.DELETE("/:client_id", h.DeleteRegistration)
+}
+
+// RegisterClient handles dynamic client registration requests.
+// Returns 201 Created with the client information, including credentials that are only shown once.
+func (h *Handler) RegisterClient(c *gin.Context) {
+	var req RegistrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeRegistrationError(c, errors.BadRequest(errors.ErrMsgInvalidRequestFormat))
+		return
+	}
+
+	resp, err := h.service.Register(c.Request.Context(), req)
+	if err != nil {
+		writeRegistrationError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, resp)
+}
+
+// GetRegistration handles requests to read a client registration.
+// Returns 200 OK with the client information, or 401 Unauthorized if the
+// registration access token is missing or invalid.
+func (h *Handler) GetRegistration(c *gin.Context) {
+	resp, err := h.service.GetRegistration(c.Request.Context(), c.Param("client_id"), registrationToken(c))
+	if err != nil {
+		writeRegistrationError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, resp)
+}
+
+// UpdateRegistration handles requests to replace a client registration.
+// The body must contain the complete client metadata, including the client_id.
+// Returns 200 OK with the updated client information.
+func (h *Handler) UpdateRegistration(c *gin.Context) {
+	var req RegistrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeRegistrationError(c, errors.BadRequest(errors.ErrMsgInvalidRequestFormat))
+		return
+	}
+
+	resp, err := h.service.UpdateRegistration(c.Request.Context(), c.Param("client_id"), registrationToken(c), req)
+	if err != nil {
+		writeRegistrationError(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, resp)
+}
+
+// DeleteRegistration handles requests to delete a client registration.
+// Returns 204 No Content on success.
+func (h *Handler) DeleteRegistration(c *gin.Context) {
+	if err := h.service.DeleteRegistration(c.Request.Context(), c.Param("client_id"), registrationToken(c)); err != nil {
+		writeRegistrationError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// registrationToken extracts the registration access token from the Authorization header.
+// Returns an empty string if no bearer token is present.
+func registrationToken(c *gin.Context) string {
+	parts := strings.SplitN(c.GetHeader(middleware.AuthHeaderName), " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], middleware.AuthHeaderPrefix) {
+		return ""
+	}
+	return strings.TrimSpace(parts[1])
+}
+
+// writeRegistrationError writes an RFC 7591 Section 3.2.2 error response.
+// Invalid tokens are reported as 401 invalid_token with a WWW-Authenticate header,
+// redirect URI problems as invalid_redirect_uri, and other validation failures
+// as invalid_client_metadata.
+func writeRegistrationError(c *gin.Context, err error) {
+	customErr, ok := errors.As(err)
+	if !ok || customErr.Status >= http.StatusInternalServerError {
+		c.JSON(http.StatusInternalServerError, RegistrationErrorResponse{
+			Error:            "server_error",
+			ErrorDescription: "Internal server error",
+			RequestID:        middleware.GetRequestID(c),
+		})
+		return
+	}
+
+	resp := RegistrationErrorResponse{
+		Error:            errors.ErrMsgInvalidClientMetadata,
+		ErrorDescription: customErr.Message,
+		RequestID:        middleware.GetRequestID(c),
+	}
+
+	switch {
+	case customErr.Status == http.StatusUnauthorized:
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		resp.Error = "invalid_token"
+	case customErr.Message == errors.ErrMsgInvalidRedirectUri:
+		resp.Error = errors.ErrMsgInvalidRedirectUri
+		resp.ErrorDescription, _ = customErr.Details.(string)
+	}
+
+	c.JSON(customErr.Status, resp)
+}
@@ -4,6 +4,7 @@ package client
 
 import (
 	"context"
+	"time"
 )
 
 // Repository defines the interface for client-related data storage and retrieval.
@@ -29,6 +30,16 @@ type Repository interface {
 	// Returns the clients, total count, and any error that occurred.
 	FindByOwnerID(ctx context.Context, ownerID uint, page, limit int) ([]Client, int64, error)
 
+	// Search retrieves a paginated list of the OAuth clients matching the filter, newest first.
+	// Returns the clients, total count of matches, and any error that occurred.
+	Search(ctx context.Context, filter ClientFilter, page, limit int) ([]Client, int64, error)
+
+	// UpdateSecret replaces the secret hash of an OAuth client, keeping the previous hash
+	// accepted until previousExpiresAt, or not at all when it is nil. The sealed ID token
+	// signing secret is replaced along with it.
+	// Returns an error if the client doesn't exist or the update fails.
+	UpdateSecret(ctx context.Context, id uint, secretHash, signingSecret, previousHash string, previousExpiresAt *time.Time) error
+
 	// Delete removes an OAuth client from the data store.
 	// Returns an error if the client doesn't exist or the deletion fails.
 	Delete(ctx context.Context, id uint) error
@@ -37,4 +48,8 @@ type Repository interface {
 	// This can be used to enable or disable a client without deleting it.
 	// Returns an error if the client doesn't exist or the update fails.
 	UpdateStatus(ctx context.Context, id uint, isActive bool) error
+
+	// HasRedirectURIOrigin reports whether any active client has a redirect URI on the given origin.
+	// The origin has the form scheme://host[:port] as sent in the Origin header.
+	HasRedirectURIOrigin(ctx context.Context, origin string) (bool, error)
 }
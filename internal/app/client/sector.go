@@ -0,0 +1,104 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/pkg/config"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+)
+
+// Sector identifier document retrieval limits
+const (
+	sectorFetchTimeout = 5 * time.Second // Maximum time to fetch a sector identifier document
+	maxSectorDocSize   = 64 << 10        // Maximum accepted size of a sector identifier document in bytes
+)
+
+// sectorHTTPClient fetches sector identifier documents
+var sectorHTTPClient = &http.Client{Timeout: sectorFetchTimeout}
+
+// validateSubjectType checks the subject type registration (OpenID Connect Dynamic Client
+// Registration Section 2 and Core Section 8.1). Pairwise subjects require the server salt.
+// A registered sector identifier URI must use https and list every redirect URI of the client;
+// without one, a pairwise client's redirect URIs must share a host, since that host is its sector.
+func validateSubjectType(ctx context.Context, subjectType, sectorIdentifierURI string, redirectURIs []string) error {
+	if !IsValidSubjectType(subjectType) {
+		return errors.BadRequest(errors.ErrMsgInvalidSubjectType)
+	}
+	if subjectType == SubjectTypePairwise && config.AppConfig.PairwiseSubjectSalt == "" {
+		return errors.BadRequest(errors.ErrMsgPairwiseSubjectNotConfigured)
+	}
+
+	if sectorIdentifierURI != "" {
+		u, err := url.Parse(sectorIdentifierURI)
+		if err != nil || u.Scheme != "https" || u.Host == "" || u.Fragment != "" {
+			return errors.BadRequest(errors.ErrMsgInvalidSectorIdentifierURI)
+		}
+
+		listed, err := fetchSectorRedirectURIs(ctx, sectorIdentifierURI)
+		if err != nil {
+			return errors.BadRequest(errors.ErrMsgInvalidSectorIdentifierURI).WithDetails(err.Error())
+		}
+		for _, redirectURI := range redirectURIs {
+			if !containsString(listed, redirectURI) {
+				return errors.BadRequest(errors.ErrMsgSectorRedirectURIsMismatch)
+			}
+		}
+		return nil
+	}
+
+	if subjectType == SubjectTypePairwise {
+		var host string
+		for i, redirectURI := range redirectURIs {
+			u, err := url.Parse(redirectURI)
+			if err != nil {
+				continue
+			}
+			if i > 0 && u.Host != host {
+				return errors.BadRequest(errors.ErrMsgSectorIdentifierURIRequired)
+			}
+			host = u.Host
+		}
+	}
+
+	return nil
+}
+
+// fetchSectorRedirectURIs retrieves the JSON array of redirect URIs published at a sector identifier URI.
+// The request is bounded by a timeout and the document by a maximum size.
+func fetchSectorRedirectURIs(ctx context.Context, uri string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := sectorHTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sector identifier document: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch sector identifier document: unexpected status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSectorDocSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sector identifier document: %w", err)
+	}
+	if len(data) > maxSectorDocSize {
+		return nil, fmt.Errorf("sector identifier document too large")
+	}
+
+	var uris []string
+	if err := json.Unmarshal(data, &uris); err != nil {
+		return nil, fmt.Errorf("sector identifier document is not a JSON array of strings")
+	}
+	return uris, nil
+}
@@ -6,26 +6,66 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/base64"
+	"net/url"
+	"strings"
 	"time"
 
+	"github.com/verigate/verigate-server/internal/app/audit"
 	"github.com/verigate/verigate-server/internal/app/auth"
+	"github.com/verigate/verigate-server/internal/pkg/config"
+	"github.com/verigate/verigate-server/internal/pkg/db/routing"
+	"github.com/verigate/verigate-server/internal/pkg/middleware"
+	"github.com/verigate/verigate-server/internal/pkg/utils/encryption"
 	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
 	"github.com/verigate/verigate-server/internal/pkg/utils/hash"
+	jwtutil "github.com/verigate/verigate-server/internal/pkg/utils/jwt"
 )
 
 // Service provides business logic for managing OAuth clients.
 // It handles client creation, retrieval, updating, deletion, and authentication.
 type Service struct {
-	repo        Repository
-	authService *auth.Service
+	repo         Repository
+	authService  *auth.Service
+	auditService *audit.Service
+	jwksCache    *jwtutil.JWKSCache // Encryption keys fetched from client JWKS URIs
+	secretKey    []byte             // Key sealing the client secrets kept to sign HS256 ID tokens, nil when HS256 is disabled
+	maxGrace     time.Duration      // Longest grace period a rotated secret stays valid for
 }
 
 // NewService creates a new client service instance.
-// It requires a client repository for data access and an auth service for authentication operations.
-func NewService(repo Repository, authService *auth.Service) *Service {
+// It requires a client repository for data access, an auth service for authentication operations,
+// and an audit service recording client registrations.
+func NewService(repo Repository, authService *auth.Service, auditService *audit.Service) *Service {
+	jwksCacheTTL, err := time.ParseDuration(config.AppConfig.ClientJWKSCacheTTL)
+	if err != nil {
+		panic("invalid client JWKS cache TTL: " + err.Error())
+	}
+
+	maxGrace, err := time.ParseDuration(config.AppConfig.ClientSecretMaxGracePeriod)
+	if err != nil || maxGrace < 0 {
+		panic("invalid client secret max grace period: " + config.AppConfig.ClientSecretMaxGracePeriod)
+	}
+
+	if !IsValidCustomClaimPrefix(config.AppConfig.CustomClaimPrefix) {
+		panic("invalid custom claim prefix: " + config.AppConfig.CustomClaimPrefix)
+	}
+
+	var secretKey []byte
+	if config.AppConfig.ClientSecretEncryptionKey != "" {
+		key, err := encryption.ParseKey(config.AppConfig.ClientSecretEncryptionKey)
+		if err != nil {
+			panic("invalid client secret encryption key: " + err.Error())
+		}
+		secretKey = key
+	}
+
 	return &Service{
-		repo:        repo,
-		authService: authService,
+		repo:         repo,
+		authService:  authService,
+		auditService: auditService,
+		jwksCache:    jwtutil.NewJWKSCache(jwksCacheTTL),
+		secretKey:    secretKey,
+		maxGrace:     maxGrace,
 	}
 }
 
@@ -34,72 +74,179 @@ func NewService(repo Repository, authService *auth.Service) *Service {
 // then saves the client to the repository and returns the created client details.
 // The client secret is only returned once at creation time.
 func (s *Service) Create(ctx context.Context, ownerID uint, req CreateClientRequest) (*ClientResponse, error) {
+	client, clientSecret, err := s.create(ctx, ownerID, req, "")
+	if err != nil {
+		return nil, err
+	}
+
+	// Return response with unhashed secret (only time it's available)
+	response := s.toResponse(client)
+	response.ClientSecret = clientSecret
+	return response, nil
+}
+
+// create builds and saves a new client, generating its client ID and, for confidential
+// clients using a shared secret, its client secret.
+// The registration token hash is set only for dynamically registered clients.
+// Returns the saved client and the unhashed secret, which is empty for clients without one.
+func (s *Service) create(ctx context.Context, ownerID uint, req CreateClientRequest, registrationTokenHash string) (*Client, string, error) {
 	// Generate client ID and secret
 	clientID, err := s.generateClientID()
 	if err != nil {
-		return nil, errors.Internal("Failed to generate client ID: " + err.Error())
+		return nil, "", errors.Internal("Failed to generate client ID: " + err.Error())
+	}
+
+	authMethod := req.TokenEndpointAuthMethod
+	if authMethod == "" {
+		authMethod = DefaultTokenEndpointAuthMethod(req.IsConfidential)
+	}
+	if !IsValidTokenEndpointAuthMethod(authMethod, req.IsConfidential) {
+		return nil, "", errors.BadRequest(errors.ErrMsgInvalidTokenEndpointAuthMethod)
+	}
+	if !IsValidAccessTokenFormat(req.AccessTokenFormat) {
+		return nil, "", errors.BadRequest(errors.ErrMsgInvalidAccessTokenFormat)
+	}
+	if err := validateResourceURIs(req.AllowedResources); err != nil {
+		return nil, "", err
+	}
+	if err := validateClientKeys(authMethod, req.Jwks, req.JwksURI); err != nil {
+		return nil, "", err
+	}
+	if err := validateTLSClientAuth(authMethod, req.TLSClientAuthSubjectDN); err != nil {
+		return nil, "", err
+	}
+	if err := s.validateIDTokenSigning(req.IDTokenSignedResponseAlg, authMethod, req.IsConfidential); err != nil {
+		return nil, "", err
+	}
+	if err := validateIDTokenEncryption(req.IDTokenEncryptedResponseAlg, req.IDTokenEncryptedResponseEnc, req.Jwks, req.JwksURI); err != nil {
+		return nil, "", err
+	}
+	if err := validateUserInfoResponse(req.UserInfoSigningAlg, req.UserInfoEncryptionAlg, req.UserInfoEncryptionEnc, req.Jwks, req.JwksURI); err != nil {
+		return nil, "", err
+	}
+	if err := validateBackchannelLogoutURI(req.BackchannelLogoutURI); err != nil {
+		return nil, "", err
+	}
+	if err := validateFrontchannelLogoutURI(req.FrontchannelLogoutURI); err != nil {
+		return nil, "", err
+	}
+	if err := validatePostLogoutRedirectURIs(req.PostLogoutRedirectURIs); err != nil {
+		return nil, "", err
+	}
+	if err := validateRequestURIs(req.RequestURIs); err != nil {
+		return nil, "", err
+	}
+	for _, redirectURI := range req.RedirectURIs {
+		if err := validateRedirectURIWildcard(redirectURI); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := validateSubjectType(ctx, req.SubjectType, req.SectorIdentifierURI, req.RedirectURIs); err != nil {
+		return nil, "", err
+	}
+	subjectType := req.SubjectType
+	if subjectType == "" {
+		subjectType = SubjectTypePublic
+	}
+	if err := validateTokenLifetimes(req.AccessTokenLifetime, req.RefreshTokenLifetime, req.IDTokenLifetime); err != nil {
+		return nil, "", err
+	}
+	if err := validateClaimTemplates(req.ClaimTemplates); err != nil {
+		return nil, "", err
 	}
 
+	// Clients authenticating with a private key or a certificate have no shared secret
 	var clientSecret string
 	var hashedSecret string
-	if req.IsConfidential {
+	if req.IsConfidential && IsClientSecretMethod(authMethod) {
 		clientSecret, hashedSecret, err = s.generateClientSecret()
 		if err != nil {
-			return nil, errors.Internal("Failed to generate client secret: " + err.Error())
+			return nil, "", errors.Internal("Failed to generate client secret: " + err.Error())
+		}
+	}
+
+	// HS256 ID tokens are keyed with the secret, so it is kept sealed as well as hashed
+	var signingSecret string
+	if req.IDTokenSignedResponseAlg == jwtutil.SigningAlgHS256 {
+		signingSecret, err = s.sealSigningSecret(clientSecret)
+		if err != nil {
+			return nil, "", err
 		}
 	}
 
 	// Create client model
 	client := &Client{
-		ClientID:        clientID,
-		ClientSecret:    hashedSecret,
-		ClientName:      req.ClientName,
-		Description:     req.Description,
-		ClientURI:       req.ClientURI,
-		LogoURI:         req.LogoURI,
-		RedirectURIs:    req.RedirectURIs,
-		GrantTypes:      req.GrantTypes,
-		ResponseTypes:   req.ResponseTypes,
-		Scope:           req.Scope,
-		TOSUri:          req.TOSUri,
-		PolicyURI:       req.PolicyURI,
-		JwksURI:         req.JwksURI,
-		Jwks:            req.Jwks,
-		Contacts:        req.Contacts,
-		SoftwareID:      req.SoftwareID,
-		SoftwareVersion: req.SoftwareVersion,
-		IsConfidential:  req.IsConfidential,
-		IsActive:        true,
-		CreatedAt:       time.Now(),
-		UpdatedAt:       time.Now(),
-		OwnerID:         ownerID,
+		ClientID:                    clientID,
+		ClientSecret:                hashedSecret,
+		ClientName:                  req.ClientName,
+		Description:                 req.Description,
+		ClientURI:                   req.ClientURI,
+		LogoURI:                     req.LogoURI,
+		RedirectURIs:                req.RedirectURIs,
+		GrantTypes:                  req.GrantTypes,
+		ResponseTypes:               req.ResponseTypes,
+		Scope:                       req.Scope,
+		TOSUri:                      req.TOSUri,
+		PolicyURI:                   req.PolicyURI,
+		JwksURI:                     req.JwksURI,
+		Jwks:                        req.Jwks,
+		Contacts:                    req.Contacts,
+		SoftwareID:                  req.SoftwareID,
+		SoftwareVersion:             req.SoftwareVersion,
+		IsConfidential:              req.IsConfidential,
+		RequirePKCE:                 req.RequirePKCE,
+		TokenEndpointAuthMethod:     authMethod,
+		AccessTokenFormat:           req.AccessTokenFormat,
+		AllowedResources:            nonNilStrings(req.AllowedResources),
+		IDTokenSignedResponseAlg:    req.IDTokenSignedResponseAlg,
+		IDTokenSigningSecret:        signingSecret,
+		IDTokenEncryptedResponseAlg: req.IDTokenEncryptedResponseAlg,
+		IDTokenEncryptedResponseEnc: req.IDTokenEncryptedResponseEnc,
+		UserInfoSigningAlg:          req.UserInfoSigningAlg,
+		UserInfoEncryptionAlg:       req.UserInfoEncryptionAlg,
+		UserInfoEncryptionEnc:       req.UserInfoEncryptionEnc,
+		BackchannelLogoutURI:        req.BackchannelLogoutURI,
+		FrontchannelLogoutURI:       req.FrontchannelLogoutURI,
+		PostLogoutRedirectURIs:      nonNilStrings(req.PostLogoutRedirectURIs),
+		RequestURIs:                 nonNilStrings(req.RequestURIs),
+		RequirePushedAuthRequests:   req.RequirePushedAuthRequests,
+		BindTokenToIP:               req.BindTokenToIP,
+		TLSClientAuthSubjectDN:      req.TLSClientAuthSubjectDN,
+		CertificateBoundTokens:      req.CertificateBoundTokens,
+		ManagedState:                req.ManagedState,
+		SubjectType:                 subjectType,
+		SectorIdentifierURI:         req.SectorIdentifierURI,
+		AccessTokenLifetime:         req.AccessTokenLifetime,
+		RefreshTokenLifetime:        req.RefreshTokenLifetime,
+		IDTokenLifetime:             req.IDTokenLifetime,
+		ClaimTemplates:              nonNilClaimTemplates(req.ClaimTemplates),
+		IsActive:                    true,
+		CreatedAt:                   time.Now(),
+		UpdatedAt:                   time.Now(),
+		OwnerID:                     ownerID,
+		RegistrationAccessToken:     registrationTokenHash,
 	}
 
 	// Save to repository
 	if err := s.repo.Save(ctx, client); err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	// Return response with unhashed secret (only time it's available)
-	return &ClientResponse{
-		ID:             client.ID,
-		ClientID:       client.ClientID,
-		ClientSecret:   clientSecret, // Return unhashed secret
-		ClientName:     client.ClientName,
-		Description:    client.Description,
-		ClientURI:      client.ClientURI,
-		LogoURI:        client.LogoURI,
-		RedirectURIs:   client.RedirectURIs,
-		GrantTypes:     client.GrantTypes,
-		ResponseTypes:  client.ResponseTypes,
-		Scope:          client.Scope,
-		TOSUri:         client.TOSUri,
-		PolicyURI:      client.PolicyURI,
-		IsConfidential: client.IsConfidential,
-		IsActive:       client.IsActive,
-		CreatedAt:      client.CreatedAt,
-		UpdatedAt:      client.UpdatedAt,
-	}, nil
+	// Dynamically registered clients have no owner to attribute the registration to
+	event := audit.Event{
+		Type:     audit.EventClientRegistered,
+		ClientID: client.ClientID,
+		Details:  map[string]string{"client_name": client.ClientName},
+	}
+	if ownerID != 0 {
+		event.Actor = audit.UserActor(ownerID)
+	}
+	if registrationTokenHash != "" {
+		event.Details["registration"] = "dynamic"
+	}
+	s.auditService.Record(ctx, event)
+
+	return client, clientSecret, nil
 }
 
 // GetByID retrieves a client by its internal ID.
@@ -134,7 +281,8 @@ func (s *Service) GetByClientID(ctx context.Context, clientID string) (*Client,
 // Returns an error if the client doesn't exist, the user doesn't own it,
 // or if the update operation fails.
 func (s *Service) Update(ctx context.Context, id uint, ownerID uint, req UpdateClientRequest) error {
-	client, err := s.repo.FindByID(ctx, id)
+	// Clients about to be changed are read from the primary, so no change made within the replication lag is lost
+	client, err := s.repo.FindByID(routing.WithPrimary(ctx), id)
 	if err != nil {
 		return err
 	}
@@ -147,6 +295,12 @@ func (s *Service) Update(ctx context.Context, id uint, ownerID uint, req UpdateC
 		return errors.Forbidden(errors.ErrMsgNotAuthorizedForClient)
 	}
 
+	return s.applyUpdate(ctx, client, req)
+}
+
+// applyUpdate modifies a client with the non-empty/non-zero fields of the request,
+// validates the result, and saves it.
+func (s *Service) applyUpdate(ctx context.Context, client *Client, req UpdateClientRequest) error {
 	// Update fields if provided
 	if req.ClientName != "" {
 		client.ClientName = req.ClientName
@@ -161,6 +315,11 @@ func (s *Service) Update(ctx context.Context, id uint, ownerID uint, req UpdateC
 		client.LogoURI = req.LogoURI
 	}
 	if len(req.RedirectURIs) > 0 {
+		for _, redirectURI := range req.RedirectURIs {
+			if err := validateRedirectURIWildcard(redirectURI); err != nil {
+				return err
+			}
+		}
 		client.RedirectURIs = req.RedirectURIs
 	}
 	if len(req.GrantTypes) > 0 {
@@ -179,6 +338,133 @@ func (s *Service) Update(ctx context.Context, id uint, ownerID uint, req UpdateC
 	client.Contacts = req.Contacts
 	client.SoftwareID = req.SoftwareID
 	client.SoftwareVersion = req.SoftwareVersion
+	if req.RequirePKCE != nil {
+		client.RequirePKCE = *req.RequirePKCE
+	}
+	if req.RequirePushedAuthRequests != nil {
+		client.RequirePushedAuthRequests = *req.RequirePushedAuthRequests
+	}
+	if req.BindTokenToIP != nil {
+		client.BindTokenToIP = *req.BindTokenToIP
+	}
+	if req.TLSClientAuthSubjectDN != "" {
+		client.TLSClientAuthSubjectDN = req.TLSClientAuthSubjectDN
+	}
+	if req.CertificateBoundTokens != nil {
+		client.CertificateBoundTokens = *req.CertificateBoundTokens
+	}
+	if req.ManagedState != nil {
+		client.ManagedState = *req.ManagedState
+	}
+	if req.TokenEndpointAuthMethod != "" {
+		if !IsValidTokenEndpointAuthMethod(req.TokenEndpointAuthMethod, client.IsConfidential) {
+			return errors.BadRequest(errors.ErrMsgInvalidTokenEndpointAuthMethod)
+		}
+		client.TokenEndpointAuthMethod = req.TokenEndpointAuthMethod
+	}
+	if req.AccessTokenFormat != "" {
+		if !IsValidAccessTokenFormat(req.AccessTokenFormat) {
+			return errors.BadRequest(errors.ErrMsgInvalidAccessTokenFormat)
+		}
+		client.AccessTokenFormat = req.AccessTokenFormat
+	}
+	if req.AllowedResources != nil {
+		if err := validateResourceURIs(req.AllowedResources); err != nil {
+			return err
+		}
+		client.AllowedResources = req.AllowedResources
+	}
+	if req.IDTokenSignedResponseAlg != "" {
+		client.IDTokenSignedResponseAlg = req.IDTokenSignedResponseAlg
+	}
+	if req.IDTokenEncryptedResponseAlg != "" {
+		client.IDTokenEncryptedResponseAlg = req.IDTokenEncryptedResponseAlg
+	}
+	if req.IDTokenEncryptedResponseEnc != "" {
+		client.IDTokenEncryptedResponseEnc = req.IDTokenEncryptedResponseEnc
+	}
+	if req.UserInfoSigningAlg != "" {
+		client.UserInfoSigningAlg = req.UserInfoSigningAlg
+	}
+	if req.UserInfoEncryptionAlg != "" {
+		client.UserInfoEncryptionAlg = req.UserInfoEncryptionAlg
+	}
+	if req.UserInfoEncryptionEnc != "" {
+		client.UserInfoEncryptionEnc = req.UserInfoEncryptionEnc
+	}
+	if req.BackchannelLogoutURI != "" {
+		if err := validateBackchannelLogoutURI(req.BackchannelLogoutURI); err != nil {
+			return err
+		}
+		client.BackchannelLogoutURI = req.BackchannelLogoutURI
+	}
+	if req.FrontchannelLogoutURI != "" {
+		if err := validateFrontchannelLogoutURI(req.FrontchannelLogoutURI); err != nil {
+			return err
+		}
+		client.FrontchannelLogoutURI = req.FrontchannelLogoutURI
+	}
+	if req.PostLogoutRedirectURIs != nil {
+		if err := validatePostLogoutRedirectURIs(req.PostLogoutRedirectURIs); err != nil {
+			return err
+		}
+		client.PostLogoutRedirectURIs = req.PostLogoutRedirectURIs
+	}
+	if req.RequestURIs != nil {
+		if err := validateRequestURIs(req.RequestURIs); err != nil {
+			return err
+		}
+		client.RequestURIs = req.RequestURIs
+	}
+	if req.SubjectType != "" {
+		client.SubjectType = req.SubjectType
+	}
+	if req.SectorIdentifierURI != "" {
+		client.SectorIdentifierURI = req.SectorIdentifierURI
+	}
+	// The sector is re-checked whenever something it depends on changes
+	if req.SubjectType != "" || req.SectorIdentifierURI != "" || len(req.RedirectURIs) > 0 {
+		if err := validateSubjectType(ctx, client.SubjectType, client.SectorIdentifierURI, client.RedirectURIs); err != nil {
+			return err
+		}
+	}
+	if req.AccessTokenLifetime != nil {
+		client.AccessTokenLifetime = *req.AccessTokenLifetime
+	}
+	if req.RefreshTokenLifetime != nil {
+		client.RefreshTokenLifetime = *req.RefreshTokenLifetime
+	}
+	if req.IDTokenLifetime != nil {
+		client.IDTokenLifetime = *req.IDTokenLifetime
+	}
+	if err := validateTokenLifetimes(client.AccessTokenLifetime, client.RefreshTokenLifetime, client.IDTokenLifetime); err != nil {
+		return err
+	}
+	if req.ClaimTemplates != nil {
+		if err := validateClaimTemplates(*req.ClaimTemplates); err != nil {
+			return err
+		}
+		client.ClaimTemplates = nonNilClaimTemplates(*req.ClaimTemplates)
+	}
+	if err := validateTLSClientAuth(client.TokenEndpointAuthMethod, client.TLSClientAuthSubjectDN); err != nil {
+		return err
+	}
+	if err := validateClientKeys(client.TokenEndpointAuthMethod, client.Jwks, client.JwksURI); err != nil {
+		return err
+	}
+	if err := s.validateIDTokenSigningChange(client); err != nil {
+		return err
+	}
+	if err := validateIDTokenEncryption(client.IDTokenEncryptedResponseAlg, client.IDTokenEncryptedResponseEnc, client.Jwks, client.JwksURI); err != nil {
+		return err
+	}
+	if err := validateUserInfoResponse(client.UserInfoSigningAlg, client.UserInfoEncryptionAlg, client.UserInfoEncryptionEnc, client.Jwks, client.JwksURI); err != nil {
+		return err
+	}
+	// A client registered without a secret cannot switch to secret-based authentication
+	if client.UsesClientSecret() && client.ClientSecret == "" {
+		return errors.BadRequest(errors.ErrMsgInvalidTokenEndpointAuthMethod)
+	}
 	client.UpdatedAt = time.Now()
 
 	return s.repo.Update(ctx, client)
@@ -189,7 +475,7 @@ func (s *Service) Update(ctx context.Context, id uint, ownerID uint, req UpdateC
 // Returns an error if the client doesn't exist, the user doesn't own it,
 // or if the delete operation fails.
 func (s *Service) Delete(ctx context.Context, id uint, ownerID uint) error {
-	client, err := s.repo.FindByID(ctx, id)
+	client, err := s.repo.FindByID(routing.WithPrimary(ctx), id)
 	if err != nil {
 		return err
 	}
@@ -228,11 +514,13 @@ func (s *Service) List(ctx context.Context, ownerID uint, page, limit int) (*Cli
 	}, nil
 }
 
-// ValidateClient verifies client credentials for authentication purposes.
+// AuthenticateClient verifies client credentials presented at the token endpoint.
+// The method used to present them must match the client's registered token_endpoint_auth_method,
+// so a client registered for Basic authentication cannot send its secret in the request body
+// and a public client registered with none cannot present a secret at all.
 // For confidential clients, it checks that the provided secret matches the stored hash.
-// For public clients, it just verifies the client exists and is active.
-// Returns the client if validation succeeds or an error if credentials are invalid or the client is inactive.
-func (s *Service) ValidateClient(ctx context.Context, clientID, clientSecret string) (*Client, error) {
+// Returns the client if authentication succeeds or an error if credentials are invalid or the client is inactive.
+func (s *Service) AuthenticateClient(ctx context.Context, clientID, clientSecret, method string) (*Client, error) {
 	client, err := s.repo.FindByClientID(ctx, clientID)
 	if err != nil {
 		return nil, err
@@ -245,9 +533,25 @@ func (s *Service) ValidateClient(ctx context.Context, clientID, clientSecret str
 		return nil, errors.Unauthorized(errors.ErrMsgClientNotActive)
 	}
 
-	// For confidential clients, verify secret
-	if client.IsConfidential {
-		if err := hash.CompareHashAndPassword(client.ClientSecret, clientSecret); err != nil {
+	if method != client.TokenEndpointAuthMethod {
+		return nil, errors.Unauthorized(errors.ErrMsgClientAuthMethodMismatch)
+	}
+
+	if method == AuthMethodNone {
+		if client.IsConfidential || clientSecret != "" {
+			return nil, errors.Unauthorized(errors.ErrMsgInvalidClientCredentials)
+		}
+		return client, nil
+	}
+
+	// Assertion- and certificate-based methods are verified by the OAuth service
+	if !client.UsesClientSecret() {
+		return nil, errors.Unauthorized(errors.ErrMsgInvalidClientCredentials)
+	}
+
+	// Secret-based methods, verify secret; the secret replaced by a rotation works during its grace period
+	if err := hash.CompareHashAndPassword(client.ClientSecret, clientSecret); err != nil {
+		if !client.AcceptsPreviousSecret(time.Now()) || hash.CompareHashAndPassword(client.PreviousClientSecret, clientSecret) != nil {
 			return nil, errors.Unauthorized(errors.ErrMsgInvalidClientCredentials)
 		}
 	}
@@ -255,8 +559,435 @@ func (s *Service) ValidateClient(ctx context.Context, clientID, clientSecret str
 	return client, nil
 }
 
+// AdminList retrieves the OAuth clients of every owner matching the filter, with pagination.
+// The page parameter is 1-indexed (first page is 1, not 0).
+func (s *Service) AdminList(ctx context.Context, filter ClientFilter, page, limit int) (*ClientListResponse, error) {
+	clients, total, err := s.repo.Search(ctx, filter, page, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := []ClientResponse{}
+	for _, client := range clients {
+		responses = append(responses, *s.toResponse(&client))
+	}
+
+	return &ClientListResponse{
+		Clients: responses,
+		Total:   total,
+		Page:    page,
+		PerPage: limit,
+	}, nil
+}
+
+// AdminGet retrieves a client by its client ID whoever owns it.
+// The client secret is never returned in the response.
+func (s *Service) AdminGet(ctx context.Context, clientID string) (*ClientResponse, error) {
+	client, err := s.findByClientID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	return s.toResponse(client), nil
+}
+
+// AdminUpdate modifies a client like Update, without checking who owns it.
+func (s *Service) AdminUpdate(ctx context.Context, clientID string, req UpdateClientRequest) error {
+	client, err := s.findByClientID(routing.WithPrimary(ctx), clientID)
+	if err != nil {
+		return err
+	}
+	return s.applyUpdate(ctx, client, req)
+}
+
+// AdminDelete removes a client whoever owns it.
+func (s *Service) AdminDelete(ctx context.Context, clientID string) error {
+	client, err := s.findByClientID(routing.WithPrimary(ctx), clientID)
+	if err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, client.ID)
+}
+
+// SetRateLimitTier assigns the rate limit tier applied to a client once it has authenticated:
+// "exempt", the top tier, which is not rate limited at all, or a limit written as requests/window.
+// An empty tier returns the client to the tier configured for it, if any. Tiers are only set by
+// administrators, as they lift the limits the client's owner is otherwise held to, and only for
+// clients that authenticate, since anyone can present the client ID of a public client.
+func (s *Service) SetRateLimitTier(ctx context.Context, clientID, tier string) error {
+	tier = strings.TrimSpace(tier)
+	if tier != "" {
+		if _, err := middleware.ParseClientTier(tier); err != nil {
+			return errors.BadRequest(errors.ErrMsgInvalidRateLimitTier)
+		}
+	}
+
+	client, err := s.findByClientID(routing.WithPrimary(ctx), clientID)
+	if err != nil {
+		return err
+	}
+	if tier != "" && client.TokenEndpointAuthMethod == AuthMethodNone {
+		return errors.BadRequest(errors.ErrMsgRateLimitTierNeedsAuth)
+	}
+
+	client.RateLimitTier = tier
+	client.UpdatedAt = time.Now()
+	return s.repo.Update(ctx, client)
+}
+
+// RotateSecret replaces the secret of a client authenticating with one. The new secret is
+// returned once and never again. During the grace period the old secret keeps working too,
+// so the client's deployments can switch over one at a time; without one it stops at once.
+// Tokens already issued to the client stay valid, as they are not tied to the secret.
+// A client signing ID tokens with HS256 has them keyed with the new secret from now on.
+func (s *Service) RotateSecret(ctx context.Context, clientID string, grace time.Duration) (*SecretRotationResponse, error) {
+	if grace < 0 || grace > s.maxGrace {
+		return nil, errors.BadRequest(errors.ErrMsgInvalidSecretGracePeriod)
+	}
+
+	client, err := s.findByClientID(routing.WithPrimary(ctx), clientID)
+	if err != nil {
+		return nil, err
+	}
+	if !client.UsesClientSecret() || client.ClientSecret == "" {
+		return nil, errors.BadRequest(errors.ErrMsgClientHasNoSecret)
+	}
+
+	secret, hashedSecret, err := s.generateClientSecret()
+	if err != nil {
+		return nil, errors.Internal(errors.ErrMsgFailedToGenerateSecret).Wrap(err)
+	}
+
+	signingSecret := client.IDTokenSigningSecret
+	if signingSecret != "" {
+		if signingSecret, err = s.sealSigningSecret(secret); err != nil {
+			return nil, err
+		}
+	}
+
+	var previousHash string
+	var previousExpiresAt *time.Time
+	if grace > 0 {
+		expiresAt := time.Now().Add(grace)
+		previousHash, previousExpiresAt = client.ClientSecret, &expiresAt
+	}
+
+	if err := s.repo.UpdateSecret(ctx, client.ID, hashedSecret, signingSecret, previousHash, previousExpiresAt); err != nil {
+		return nil, err
+	}
+
+	return &SecretRotationResponse{
+		ClientID:                client.ClientID,
+		ClientSecret:            secret,
+		PreviousSecretExpiresAt: previousExpiresAt,
+	}, nil
+}
+
+// findByClientID retrieves a client by its client ID, or NotFound if there is none.
+func (s *Service) findByClientID(ctx context.Context, clientID string) (*Client, error) {
+	client, err := s.repo.FindByClientID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, errors.NotFound(errors.ErrMsgClientNotFound)
+	}
+	return client, nil
+}
+
+// VerificationKeys returns the public keys registered for verifying the client's signed assertions.
+// An inline JWKS takes precedence over a JWKS URI, which is fetched on every call so that
+// key rotation on the client side takes effect immediately.
+func (s *Service) VerificationKeys(ctx context.Context, client *Client) (jwtutil.JWKSet, error) {
+	var (
+		set jwtutil.JWKSet
+		err error
+	)
+	if client.Jwks != "" {
+		set, err = jwtutil.ParseJWKSet([]byte(client.Jwks))
+	} else if client.JwksURI != "" {
+		set, err = jwtutil.FetchJWKS(ctx, client.JwksURI)
+	} else {
+		return jwtutil.JWKSet{}, errors.BadRequest(errors.ErrMsgClientKeysRequired)
+	}
+
+	if err != nil {
+		return jwtutil.JWKSet{}, errors.Internal(errors.ErrMsgFailedToLoadClientKeys).Wrap(err)
+	}
+	return set, nil
+}
+
+// EncryptionKey returns the client's public key to encrypt ID tokens and UserInfo responses to
+// with alg. An inline JWKS takes precedence over a JWKS URI, which is cached for the configured
+// TTL since encryption keys are needed on every token response.
+func (s *Service) EncryptionKey(ctx context.Context, client *Client, alg string) (jwtutil.JWK, error) {
+	var (
+		set jwtutil.JWKSet
+		err error
+	)
+	if client.Jwks != "" {
+		set, err = jwtutil.ParseJWKSet([]byte(client.Jwks))
+	} else if client.JwksURI != "" {
+		set, err = s.jwksCache.Fetch(ctx, client.JwksURI)
+	} else {
+		return jwtutil.JWK{}, errors.BadRequest(errors.ErrMsgClientKeysRequired)
+	}
+	if err != nil {
+		return jwtutil.JWK{}, errors.Internal(errors.ErrMsgFailedToLoadClientKeys).Wrap(err)
+	}
+
+	key, err := jwtutil.EncryptionKey(set, alg)
+	if err != nil {
+		return jwtutil.JWK{}, errors.Internal(errors.ErrMsgFailedToLoadClientKeys).Wrap(err)
+	}
+	return key, nil
+}
+
+// IsRegisteredOrigin reports whether the origin belongs to a redirect URI of an active client.
+// Browser-based clients calling the OAuth endpoints cross-origin are allowed only from
+// origins they have registered.
+func (s *Service) IsRegisteredOrigin(ctx context.Context, origin string) (bool, error) {
+	return s.repo.HasRedirectURIOrigin(ctx, origin)
+}
+
 // Helper methods
 
+// validateClientKeys checks the key registration required by the authentication method.
+// Clients using private_key_jwt must register a JWKS or JWKS URI, and an inline JWKS must parse.
+// Clients using self_signed_tls_client_auth register their certificates in the x5c of their keys.
+func validateClientKeys(authMethod, jwks, jwksURI string) error {
+	if jwks != "" {
+		if _, err := jwtutil.ParseJWKSet([]byte(jwks)); err != nil {
+			return errors.BadRequest(errors.ErrMsgInvalidClientJwks)
+		}
+	}
+	if authMethod == AuthMethodPrivateKeyJWT && jwks == "" && jwksURI == "" {
+		return errors.BadRequest(errors.ErrMsgClientKeysRequired)
+	}
+	if authMethod == AuthMethodSelfSignedTLSClientAuth && jwks == "" && jwksURI == "" {
+		return errors.BadRequest(errors.ErrMsgClientCertificatesRequired)
+	}
+	return nil
+}
+
+// validateTLSClientAuth checks that a tls_client_auth client registers the subject DN of its
+// certificate (RFC 8705 Section 2.1.2), the only certificate metadata this server matches.
+func validateTLSClientAuth(authMethod, subjectDN string) error {
+	if authMethod == AuthMethodTLSClientAuth && strings.TrimSpace(subjectDN) == "" {
+		return errors.BadRequest(errors.ErrMsgTLSClientAuthSubjectDNRequired)
+	}
+	return nil
+}
+
+// IDTokenSigner returns the signer of ID tokens issued to the client, following the algorithm
+// it registered. HS256 is keyed with the client secret, unsealed from storage.
+func (s *Service) IDTokenSigner(client *Client) (jwtutil.Signer, error) {
+	alg := client.IDTokenSigningAlg()
+	if alg != jwtutil.SigningAlgHS256 {
+		signer, err := jwtutil.NewSigner(alg, nil)
+		if err != nil {
+			return jwtutil.Signer{}, errors.Internal(errors.ErrMsgFailedToLoadIDTokenSigningKey).Wrap(err)
+		}
+		return signer, nil
+	}
+
+	if s.secretKey == nil || client.IDTokenSigningSecret == "" {
+		return jwtutil.Signer{}, errors.Internal(errors.ErrMsgFailedToLoadIDTokenSigningKey)
+	}
+	secret, err := encryption.Decrypt(s.secretKey, client.IDTokenSigningSecret)
+	if err != nil {
+		return jwtutil.Signer{}, errors.Internal(errors.ErrMsgFailedToLoadIDTokenSigningKey).Wrap(err)
+	}
+	signer, err := jwtutil.NewSigner(alg, []byte(secret))
+	if err != nil {
+		return jwtutil.Signer{}, errors.Internal(errors.ErrMsgFailedToLoadIDTokenSigningKey).Wrap(err)
+	}
+	return signer, nil
+}
+
+// UserInfoSigner returns the signer of the UserInfo responses to the client, using the
+// server's key for the algorithm the client registered.
+func (s *Service) UserInfoSigner(client *Client) (jwtutil.Signer, error) {
+	signer, err := jwtutil.NewSigner(client.UserInfoSigningAlg, nil)
+	if err != nil {
+		return jwtutil.Signer{}, errors.Internal(errors.ErrMsgFailedToLoadUserInfoSigningKey).Wrap(err)
+	}
+	return signer, nil
+}
+
+// validateIDTokenSigning checks the ID token signing algorithm a new client registers (OpenID
+// Connect Dynamic Client Registration Section 2). HS256 is keyed with the client secret, so it
+// requires a confidential client authenticating with one, on a server able to keep it sealed.
+func (s *Service) validateIDTokenSigning(alg, authMethod string, isConfidential bool) error {
+	if alg == "" {
+		return nil
+	}
+	if !jwtutil.IsSupportedSigningAlgorithm(alg) {
+		return errors.BadRequest(errors.ErrMsgUnsupportedIDTokenSigningAlg)
+	}
+	if alg != jwtutil.SigningAlgHS256 {
+		return nil
+	}
+	if s.secretKey == nil {
+		return errors.BadRequest(errors.ErrMsgHS256IDTokensNotEnabled)
+	}
+	if !isConfidential || !IsClientSecretMethod(authMethod) {
+		return errors.BadRequest(errors.ErrMsgHS256RequiresClientSecret)
+	}
+	return nil
+}
+
+// validateIDTokenSigningChange checks the ID token signing algorithm of an updated client.
+// Only the hash of the secret of a client registered for another algorithm is stored,
+// so such a client cannot switch to HS256.
+func (s *Service) validateIDTokenSigningChange(client *Client) error {
+	if err := s.validateIDTokenSigning(client.IDTokenSignedResponseAlg, client.TokenEndpointAuthMethod, client.IsConfidential); err != nil {
+		return err
+	}
+	if client.IDTokenSignedResponseAlg == jwtutil.SigningAlgHS256 && client.IDTokenSigningSecret == "" {
+		return errors.BadRequest(errors.ErrMsgHS256RequiresNewClient)
+	}
+	return nil
+}
+
+// sealSigningSecret encrypts a client secret to be kept for signing HS256 ID tokens.
+// Secrets too short to be a sound HMAC key are rejected.
+func (s *Service) sealSigningSecret(secret string) (string, error) {
+	if _, err := jwtutil.NewSigner(jwtutil.SigningAlgHS256, []byte(secret)); err != nil {
+		return "", errors.BadRequest(errors.ErrMsgClientSecretTooWeak)
+	}
+
+	sealed, err := encryption.Encrypt(s.secretKey, secret)
+	if err != nil {
+		return "", errors.Internal(errors.ErrMsgFailedToLoadIDTokenSigningKey).Wrap(err)
+	}
+	return sealed, nil
+}
+
+// validateIDTokenEncryption checks the ID token encryption registration (OpenID Connect
+// Dynamic Client Registration Section 2). The algorithms must be supported, a content
+// encryption algorithm requires a key management algorithm, and the client must register
+// the keys to encrypt to.
+func validateIDTokenEncryption(alg, enc, jwks, jwksURI string) error {
+	if alg == "" {
+		if enc != "" {
+			return errors.BadRequest(errors.ErrMsgInvalidIDTokenEncryption)
+		}
+		return nil
+	}
+	if !jwtutil.IsSupportedJWEAlgorithm(alg) || (enc != "" && !jwtutil.IsSupportedJWEEncryption(enc)) {
+		return errors.BadRequest(errors.ErrMsgInvalidIDTokenEncryption)
+	}
+	if jwks == "" && jwksURI == "" {
+		return errors.BadRequest(errors.ErrMsgClientKeysRequired)
+	}
+	return nil
+}
+
+// validateUserInfoResponse checks the registration of signed and encrypted UserInfo responses
+// (OpenID Connect Dynamic Client Registration Section 2). They are signed with the server's
+// keys, so only the algorithms of SupportedUserInfoSigningAlgorithms are accepted, and are
+// encrypted only once signed, so encryption requires a signing algorithm. The encryption
+// algorithms are checked as those of ID tokens are.
+func validateUserInfoResponse(signingAlg, alg, enc, jwks, jwksURI string) error {
+	if signingAlg != "" && !containsString(SupportedUserInfoSigningAlgorithms(), signingAlg) {
+		return errors.BadRequest(errors.ErrMsgUnsupportedUserInfoSigningAlg)
+	}
+	if alg == "" {
+		if enc != "" {
+			return errors.BadRequest(errors.ErrMsgInvalidUserInfoEncryption)
+		}
+		return nil
+	}
+	if signingAlg == "" {
+		return errors.BadRequest(errors.ErrMsgUserInfoEncryptionNeedsSigning)
+	}
+	if !jwtutil.IsSupportedJWEAlgorithm(alg) || (enc != "" && !jwtutil.IsSupportedJWEEncryption(enc)) {
+		return errors.BadRequest(errors.ErrMsgInvalidUserInfoEncryption)
+	}
+	if jwks == "" && jwksURI == "" {
+		return errors.BadRequest(errors.ErrMsgClientKeysRequired)
+	}
+	return nil
+}
+
+// validateBackchannelLogoutURI checks that a back-channel logout URI, if registered, is an
+// absolute http or https URI without a fragment (Back-Channel Logout Section 2.2).
+func validateBackchannelLogoutURI(uri string) error {
+	if uri != "" && !isValidLogoutURI(uri) {
+		return errors.BadRequest(errors.ErrMsgInvalidBackchannelLogoutURI)
+	}
+	return nil
+}
+
+// validateFrontchannelLogoutURI checks that a front-channel logout URI, if registered, is an
+// absolute http or https URI without a fragment (Front-Channel Logout Section 2).
+func validateFrontchannelLogoutURI(uri string) error {
+	if uri != "" && !isValidLogoutURI(uri) {
+		return errors.BadRequest(errors.ErrMsgInvalidFrontchannelLogoutURI)
+	}
+	return nil
+}
+
+// validatePostLogoutRedirectURIs checks that every post-logout redirect URI is an
+// absolute URI without a fragment (RP-Initiated Logout Section 3.1).
+func validatePostLogoutRedirectURIs(uris []string) error {
+	for _, uri := range uris {
+		u, err := url.Parse(uri)
+		if err != nil || !u.IsAbs() || u.Fragment != "" {
+			return errors.BadRequest(errors.ErrMsgInvalidPostLogoutRedirectURI)
+		}
+	}
+	return nil
+}
+
+// validateRequestURIs checks that every request URI is an https URI (RFC 9101 Section 10.2),
+// so request objects are only fetched over authenticated connections.
+func validateRequestURIs(uris []string) error {
+	for _, uri := range uris {
+		u, err := url.Parse(uri)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return errors.BadRequest(errors.ErrMsgInvalidRequestURIs)
+		}
+	}
+	return nil
+}
+
+// validateTokenLifetimes checks the token lifetimes a client overrides, in seconds with zero
+// for the grant type default. None may be negative, and a refresh token must outlive the
+// access token it is issued with when both are overridden.
+func validateTokenLifetimes(accessLifetime, refreshLifetime, idTokenLifetime int) error {
+	if accessLifetime < 0 || refreshLifetime < 0 || idTokenLifetime < 0 {
+		return errors.BadRequest(errors.ErrMsgInvalidTokenLifetime)
+	}
+	if accessLifetime > 0 && refreshLifetime > 0 && refreshLifetime <= accessLifetime {
+		return errors.BadRequest(errors.ErrMsgRefreshLifetimeTooShort)
+	}
+	return nil
+}
+
+// isValidLogoutURI reports whether uri is an absolute http or https URI without a fragment.
+func isValidLogoutURI(uri string) bool {
+	u, err := url.Parse(uri)
+	return err == nil && u.Fragment == "" && u.Host != "" && (u.Scheme == "https" || u.Scheme == "http")
+}
+
+// IsValidResourceURI reports whether resource is usable as a resource indicator:
+// an absolute URI without a fragment (RFC 8707 Section 2).
+func IsValidResourceURI(resource string) bool {
+	u, err := url.Parse(resource)
+	return err == nil && u.IsAbs() && u.Fragment == ""
+}
+
+// validateResourceURIs checks every resource URI a client registers as allowed.
+func validateResourceURIs(resources []string) error {
+	for _, resource := range resources {
+		if !IsValidResourceURI(resource) {
+			return errors.BadRequest(errors.ErrMsgInvalidResourceURI)
+		}
+	}
+	return nil
+}
+
 // generateClientID creates a cryptographically secure random client ID.
 // The ID is generated as a URL-safe base64 encoded string of 16 random bytes,
 // resulting in a 22-character string.
@@ -292,21 +1023,47 @@ func (s *Service) generateClientSecret() (string, string, error) {
 
 func (s *Service) toResponse(client *Client) *ClientResponse {
 	return &ClientResponse{
-		ID:             client.ID,
-		ClientID:       client.ClientID,
-		ClientName:     client.ClientName,
-		Description:    client.Description,
-		ClientURI:      client.ClientURI,
-		LogoURI:        client.LogoURI,
-		RedirectURIs:   client.RedirectURIs,
-		GrantTypes:     client.GrantTypes,
-		ResponseTypes:  client.ResponseTypes,
-		Scope:          client.Scope,
-		TOSUri:         client.TOSUri,
-		PolicyURI:      client.PolicyURI,
-		IsConfidential: client.IsConfidential,
-		IsActive:       client.IsActive,
-		CreatedAt:      client.CreatedAt,
-		UpdatedAt:      client.UpdatedAt,
+		ID:                          client.ID,
+		ClientID:                    client.ClientID,
+		ClientName:                  client.ClientName,
+		Description:                 client.Description,
+		ClientURI:                   client.ClientURI,
+		LogoURI:                     client.LogoURI,
+		RedirectURIs:                client.RedirectURIs,
+		GrantTypes:                  client.GrantTypes,
+		ResponseTypes:               client.ResponseTypes,
+		Scope:                       client.Scope,
+		TOSUri:                      client.TOSUri,
+		PolicyURI:                   client.PolicyURI,
+		IsConfidential:              client.IsConfidential,
+		RequirePKCE:                 client.RequirePKCE,
+		TokenEndpointAuthMethod:     client.TokenEndpointAuthMethod,
+		AccessTokenFormat:           client.AccessTokenFormat,
+		AllowedResources:            client.AllowedResources,
+		IDTokenSignedResponseAlg:    client.IDTokenSignedResponseAlg,
+		IDTokenEncryptedResponseAlg: client.IDTokenEncryptedResponseAlg,
+		IDTokenEncryptedResponseEnc: client.IDTokenEncryptedResponseEnc,
+		UserInfoSigningAlg:          client.UserInfoSigningAlg,
+		UserInfoEncryptionAlg:       client.UserInfoEncryptionAlg,
+		UserInfoEncryptionEnc:       client.UserInfoEncryptionEnc,
+		BackchannelLogoutURI:        client.BackchannelLogoutURI,
+		FrontchannelLogoutURI:       client.FrontchannelLogoutURI,
+		PostLogoutRedirectURIs:      client.PostLogoutRedirectURIs,
+		RequestURIs:                 client.RequestURIs,
+		RequirePushedAuthRequests:   client.RequirePushedAuthRequests,
+		BindTokenToIP:               client.BindTokenToIP,
+		TLSClientAuthSubjectDN:      client.TLSClientAuthSubjectDN,
+		CertificateBoundTokens:      client.CertificateBoundTokens,
+		ManagedState:                client.ManagedState,
+		SubjectType:                 client.SubjectType,
+		SectorIdentifierURI:         client.SectorIdentifierURI,
+		AccessTokenLifetime:         client.AccessTokenLifetime,
+		RefreshTokenLifetime:        client.RefreshTokenLifetime,
+		IDTokenLifetime:             client.IDTokenLifetime,
+		ClaimTemplates:              client.ClaimTemplates,
+		RateLimitTier:               client.RateLimitTier,
+		IsActive:                    client.IsActive,
+		CreatedAt:                   client.CreatedAt,
+		UpdatedAt:                   client.UpdatedAt,
 	}
 }
@@ -0,0 +1,158 @@
+package federation
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+)
+
+// reservedClaims are the claims this server sets itself when issuing tokens, which no
+// upstream identity may supply
+var reservedClaims = map[string]bool{
+	"iss": true, "aud": true, "exp": true, "iat": true, "nbf": true, "jti": true,
+	"azp": true, "sid": true, "nonce": true, "acr": true, "amr": true, "auth_time": true,
+}
+
+// Mapper turns the attributes of an upstream identity into claims by applying a rule set.
+// It is safe for concurrent use.
+type Mapper struct {
+	rules []Rule
+}
+
+// NewMapper creates a mapper applying the rules in order, later rules overriding the claims
+// of earlier ones. The rule set is rejected unless some rule produces the sub claim, every
+// rule names a claim and is of exactly one kind, and no rule produces a reserved claim.
+func NewMapper(rules []Rule) (*Mapper, error) {
+	producesSubject := false
+	for i, rule := range rules {
+		if rule.Claim == "" {
+			return nil, fmt.Errorf("claim mapping rule %d names no claim", i)
+		}
+		if reservedClaims[rule.Claim] {
+			return nil, fmt.Errorf("claim mapping rule %d produces reserved claim %q", i, rule.Claim)
+		}
+		if rule.Kind() == "" {
+			return nil, fmt.Errorf("claim mapping rule %d for %q must set exactly one of source, value, and template", i, rule.Claim)
+		}
+		if rule.Claim == ClaimSubject {
+			producesSubject = true
+		}
+	}
+	if !producesSubject {
+		return nil, fmt.Errorf("claim mapping produces no %s claim", ClaimSubject)
+	}
+
+	return &Mapper{rules: append([]Rule(nil), rules...)}, nil
+}
+
+// LoadRules reads a rule set from a JSON file holding an array of rules.
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read claim mapping rules: %w", err)
+	}
+
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse claim mapping rules: %w", err)
+	}
+	return rules, nil
+}
+
+// Map applies the rules to the attributes of an upstream identity and returns the claims
+// produced. Returns Unauthorized when the attributes yield no sub claim, since the identity
+// could not be told apart from others.
+func (m *Mapper) Map(attributes map[string]interface{}) (map[string]interface{}, error) {
+	claims := make(map[string]interface{}, len(m.rules))
+	for _, rule := range m.rules {
+		value, ok := applyRule(rule, attributes)
+		if !ok {
+			continue
+		}
+		claims[rule.Claim] = value
+	}
+
+	// The subject is compared as a string, so a multi-valued or structured attribute cannot be one
+	sub, ok := claims[ClaimSubject].(string)
+	if !ok || strings.TrimSpace(sub) == "" {
+		return nil, errors.Unauthorized(errors.ErrMsgFederatedSubjectMissing)
+	}
+	return claims, nil
+}
+
+// applyRule returns the claim value a rule produces from the attributes, and whether it
+// produced one.
+func applyRule(rule Rule, attributes map[string]interface{}) (interface{}, bool) {
+	switch rule.Kind() {
+	case RuleKindRename:
+		value, ok := attributes[rule.Source]
+		if !ok || isEmpty(value) {
+			return nil, false
+		}
+		return singleValue(value), true
+	case RuleKindStatic:
+		return rule.Value, true
+	case RuleKindTemplate:
+		value := strings.TrimSpace(os.Expand(rule.Template, func(name string) string {
+			return attributeString(attributes[name])
+		}))
+		return value, value != ""
+	}
+	return nil, false
+}
+
+// singleValue unwraps a multi-valued attribute holding a single value, as directories return
+// most attributes as lists.
+func singleValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case []string:
+		if len(v) == 1 {
+			return v[0]
+		}
+	case []interface{}:
+		if len(v) == 1 {
+			return v[0]
+		}
+	}
+	return value
+}
+
+// attributeString formats an attribute for a template. Multi-valued attributes contribute
+// their first value; missing attributes contribute nothing.
+func attributeString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []string:
+		if len(v) == 0 {
+			return ""
+		}
+		return v[0]
+	case []interface{}:
+		if len(v) == 0 {
+			return ""
+		}
+		return attributeString(v[0])
+	}
+	return fmt.Sprint(value)
+}
+
+// isEmpty reports whether an attribute holds no value.
+func isEmpty(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []string:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+// Package federation maps the attributes of users authenticated by an upstream identity source,
+// such as an LDAP directory or an OpenID Connect provider, to the claims this server issues.
+package federation
+
+// Kinds of claim mapping rules
+const (
+	RuleKindRename   = "rename"   // Copies a source attribute under the claim name
+	RuleKindStatic   = "static"   // Sets the claim to a fixed value
+	RuleKindTemplate = "template" // Expands ${attribute} references in a template
+)
+
+// ClaimSubject is the claim every mapping must produce
+const ClaimSubject = "sub"
+
+// Rule produces one claim from the attributes of an upstream identity.
+// Exactly one of Source, Value, and Template is set, selecting the kind of the rule.
+// A rule whose source attributes are missing or empty produces no claim.
+type Rule struct {
+	Claim    string      `json:"claim"`              // Name of the claim produced
+	Source   string      `json:"source,omitempty"`   // Attribute copied into the claim
+	Value    interface{} `json:"value,omitempty"`    // Fixed value of the claim
+	Template string      `json:"template,omitempty"` // Template referencing attributes as ${name}
+}
+
+// Kind returns the kind of the rule, or an empty string when it sets none or several of
+// Source, Value, and Template.
+func (r Rule) Kind() string {
+	kind, kinds := "", 0
+	if r.Source != "" {
+		kind, kinds = RuleKindRename, kinds+1
+	}
+	if r.Value != nil {
+		kind, kinds = RuleKindStatic, kinds+1
+	}
+	if r.Template != "" {
+		kind, kinds = RuleKindTemplate, kinds+1
+	}
+	if kinds != 1 {
+		return ""
+	}
+	return kind
+}
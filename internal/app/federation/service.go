@@ -0,0 +1,35 @@
+package federation
+
+import (
+	"context"
+
+	"github.com/verigate/verigate-server/internal/app/auth"
+)
+
+// Service establishes web sessions for users authenticated by an upstream identity source.
+type Service struct {
+	mapper      *Mapper
+	authService *auth.Service
+}
+
+// NewService creates a new federation service instance mapping upstream attributes with the
+// given mapper, so the rule set can be swapped without a live upstream.
+func NewService(mapper *Mapper, authService *auth.Service) *Service {
+	return &Service{
+		mapper:      mapper,
+		authService: authService,
+	}
+}
+
+// EstablishSession maps the attributes an upstream source returned for the local user it
+// authenticated and starts a web session caching the resulting claims, so the mapping runs
+// once per login rather than on every token issued in the session. The sessions ended to
+// respect the session limit are returned alongside the new one.
+func (s *Service) EstablishSession(ctx context.Context, userID uint, acr string, attributes map[string]interface{}, userAgent, ipAddress string) (*auth.Session, []*auth.Session, error) {
+	claims, err := s.mapper.Map(attributes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return s.authService.CreateFederatedSession(ctx, userID, acr, claims, userAgent, ipAddress)
+}
@@ -0,0 +1,25 @@
+package health
+
+// Status values reported by the probes
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// Dependency names reported by the readiness probe
+const (
+	DependencyRedis    = "redis"
+	DependencyPostgres = "postgres"
+)
+
+// LivenessResponse is returned by the liveness probe.
+type LivenessResponse struct {
+	Status string `json:"status"` // Always ok while the process serves requests
+}
+
+// ReadinessResponse is returned by the readiness probe.
+type ReadinessResponse struct {
+	Status  string            `json:"status"`            // ok when every dependency is reachable, unavailable otherwise
+	Checks  map[string]string `json:"checks"`            // Status of each dependency by name
+	Failing []string          `json:"failing,omitempty"` // Names of the unreachable dependencies
+}
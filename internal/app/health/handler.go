@@ -0,0 +1,55 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler manages HTTP requests for the health probes.
+type Handler struct {
+	service *Service
+}
+
+// NewHandler creates a new health handler with the given service.
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes registers the probe routes on the provided router.
+// The probes must be registered outside the rate-limited and authenticated groups,
+// so that frequent polling neither creates rate limit entries nor needs credentials.
+func (h *Handler) RegisterRoutes(r gin.IRoutes) {
+	r.GET("/healthz", h.Liveness) // Liveness probe
+	r.GET("/readyz", h.Readiness) // Readiness probe
+}
+
+// Liveness handles the liveness probe.
+// It reports success whenever the process is able to serve requests.
+//
+// Route: GET /healthz
+func (h *Handler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, LivenessResponse{Status: StatusOK})
+}
+
+// Readiness handles the readiness probe.
+// It responds with 503 Service Unavailable and names the failing dependencies
+// when Redis or PostgreSQL cannot be reached within the readiness timeout.
+//
+// Route: GET /readyz
+func (h *Handler) Readiness(c *gin.Context) {
+	checks, failing := h.service.CheckReadiness(c.Request.Context())
+
+	resp := ReadinessResponse{
+		Status:  StatusOK,
+		Checks:  checks,
+		Failing: failing,
+	}
+	status := http.StatusOK
+	if len(failing) > 0 {
+		resp.Status = StatusUnavailable
+		status = http.StatusServiceUnavailable
+	}
+
+	c.JSON(status, resp)
+}
@@ -0,0 +1,73 @@
+// Package health provides the liveness and readiness probes used by orchestrators
+// to decide whether the server should be restarted or receive traffic.
+package health
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Service checks the connectivity of the server's dependencies.
+type Service struct {
+	redisClient *redis.Client
+	db          *sql.DB
+	timeout     time.Duration
+}
+
+// NewService creates a new health service.
+// Each readiness check is bounded by timeout, so a probe never waits longer than that
+// for a Redis reply or a free database connection.
+func NewService(redisClient *redis.Client, db *sql.DB, timeout time.Duration) *Service {
+	return &Service{
+		redisClient: redisClient,
+		db:          db,
+		timeout:     timeout,
+	}
+}
+
+// CheckReadiness pings Redis and PostgreSQL concurrently.
+// Returns the status of each dependency and the sorted names of those that failed.
+func (s *Service) CheckReadiness(ctx context.Context) (map[string]string, []string) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	checks := map[string]func(context.Context) error{
+		DependencyRedis: func(ctx context.Context) error {
+			return s.redisClient.Ping(ctx).Err()
+		},
+		DependencyPostgres: s.db.PingContext,
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	statuses := make(map[string]string, len(checks))
+	var failing []string
+
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check func(context.Context) error) {
+			defer wg.Done()
+
+			status := StatusOK
+			if err := check(ctx); err != nil {
+				status = StatusUnavailable
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			statuses[name] = status
+			if status != StatusOK {
+				failing = append(failing, name)
+			}
+		}(name, check)
+	}
+	wg.Wait()
+
+	sort.Strings(failing)
+	return statuses, failing
+}
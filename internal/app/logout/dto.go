@@ -0,0 +1,13 @@
+package logout
+
+// DeliveryQuery represents the filters for listing logout deliveries.
+type DeliveryQuery struct {
+	Status string `form:"status"`  // Only deliveries with this status, all when empty
+	UserID uint   `form:"user_id"` // Only deliveries for this user, all when zero
+	Limit  int    `form:"limit"`   // Maximum number of deliveries, a default when zero
+}
+
+// DeliveryListResponse lists logout deliveries, newest first.
+type DeliveryListResponse struct {
+	Deliveries []Delivery `json:"deliveries"`
+}
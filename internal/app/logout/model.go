@@ -0,0 +1,40 @@
+// Package logout implements OpenID Connect Back-Channel Logout: clients that received
+// ID tokens in a user's session are notified with a logout token when the session ends.
+package logout
+
+import "time"
+
+// Delivery statuses
+const (
+	DeliveryStatusPending   = "pending"   // Not yet delivered; attempts remain or delivery is in progress
+	DeliveryStatusDelivered = "delivered" // The client acknowledged the logout token
+	DeliveryStatusFailed    = "failed"    // Every attempt failed
+)
+
+// RPSession records that a client (relying party) received an ID token in a user's session.
+// The session ID is the sid claim of that ID token.
+type RPSession struct {
+	ClientID  string    `json:"client_id"`  // Client that received the ID token
+	UserID    uint      `json:"user_id"`    // User the session belongs to
+	SessionID string    `json:"session_id"` // Web session identifier
+	CreatedAt time.Time `json:"created_at"` // When the session was first recorded for the client
+	ExpiresAt time.Time `json:"expires_at"` // When the web session expires at the latest
+}
+
+// Delivery tracks sending a logout token for one session to one client.
+type Delivery struct {
+	ID        uint      `json:"id"`                   // Primary key
+	UserID    uint      `json:"user_id"`              // User who logged out
+	ClientID  string    `json:"client_id"`            // Client being notified
+	SessionID string    `json:"session_id"`           // Session reported in the logout token's sid
+	Status    string    `json:"status"`               // pending, delivered, or failed
+	Attempts  int       `json:"attempts"`             // Delivery attempts made so far
+	LastError string    `json:"last_error,omitempty"` // Error of the most recent failed attempt
+	CreatedAt time.Time `json:"created_at"`           // When the logout was requested
+	UpdatedAt time.Time `json:"updated_at"`           // When the status last changed
+}
+
+// IsValidDeliveryStatus reports whether status is a known delivery status.
+func IsValidDeliveryStatus(status string) bool {
+	return status == DeliveryStatusPending || status == DeliveryStatusDelivered || status == DeliveryStatusFailed
+}
@@ -0,0 +1,39 @@
+package logout
+
+import "context"
+
+// Repository defines the interface for back-channel logout data storage and retrieval.
+// It handles the sessions clients take part in and the delivery state of logout tokens.
+type Repository interface {
+	// Session methods
+
+	// SaveSession records a client session, extending its expiry if already recorded
+	SaveSession(ctx context.Context, session *RPSession) error
+
+	// FindSessionsByUser retrieves the unexpired client sessions of a user
+	FindSessionsByUser(ctx context.Context, userID uint) ([]RPSession, error)
+
+	// FindSessionsBySession retrieves the unexpired client sessions of a user within one web session
+	FindSessionsBySession(ctx context.Context, userID uint, sessionID string) ([]RPSession, error)
+
+	// FindSessionUser retrieves the user of an unexpired client session, or zero if there is none
+	FindSessionUser(ctx context.Context, clientID, sessionID string) (uint, error)
+
+	// DeleteSessionsByUser removes all client sessions of a user
+	DeleteSessionsByUser(ctx context.Context, userID uint) error
+
+	// DeleteSessionsBySession removes the client sessions of a user within one web session
+	DeleteSessionsBySession(ctx context.Context, userID uint, sessionID string) error
+
+	// Delivery methods
+
+	// SaveDelivery persists a new delivery and sets its ID
+	SaveDelivery(ctx context.Context, delivery *Delivery) error
+
+	// UpdateDelivery stores the status, attempts, and last error of a delivery
+	UpdateDelivery(ctx context.Context, delivery *Delivery) error
+
+	// ListDeliveries retrieves the most recent deliveries, newest first.
+	// An empty status or a zero user ID matches every delivery.
+	ListDeliveries(ctx context.Context, status string, userID uint, limit int) ([]Delivery, error)
+}
@@ -0,0 +1,370 @@
+package logout
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/verigate/verigate-server/internal/app/client"
+	"github.com/verigate/verigate-server/internal/pkg/config"
+	"github.com/verigate/verigate-server/internal/pkg/tenant"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+	jwtutil "github.com/verigate/verigate-server/internal/pkg/utils/jwt"
+)
+
+const (
+	// EventBackchannelLogout is the event member of logout tokens (Back-Channel Logout Section 2.4)
+	EventBackchannelLogout = "http://schemas.openid.net/event/backchannel-logout"
+	// MediaTypeLogoutToken is the "typ" header of logout tokens (Back-Channel Logout Section 2.4)
+	MediaTypeLogoutToken = "logout+jwt"
+
+	logoutTokenExpiry   = 2 * time.Minute  // Lifetime of a logout token
+	retryBaseDelay      = time.Second      // Delay before the first retry, doubled on each further retry
+	retryMaxDelay       = 30 * time.Second // Upper bound for the delay between retries
+	deliveryQueueSize   = 256              // Deliveries buffered before enqueueing blocks
+	defaultListLimit    = 50               // Deliveries listed when no limit is requested
+	maxListLimit        = 500              // Upper bound for deliveries listed at once
+	maxErrorBodyPreview = 1024             // Bytes of a client's error response kept in last_error
+)
+
+// Service notifies clients over the back channel when a user's session ends.
+// Deliveries are fanned out to a bounded pool of workers and retried with exponential backoff.
+type Service struct {
+	repo          Repository
+	clientService *client.Service
+	httpClient    *http.Client
+	sessionExpiry time.Duration // How long a recorded client session can stay active
+	maxAttempts   int
+	workers       int
+	jobs          chan deliveryJob
+}
+
+// deliveryJob is a queued delivery with the context of the tenant it is sent for.
+type deliveryJob struct {
+	ctx      context.Context
+	delivery *Delivery
+}
+
+// NewService creates a new back-channel logout service instance.
+// Deliveries are only sent once Start has launched the workers.
+func NewService(repo Repository, clientService *client.Service) *Service {
+	timeout, err := time.ParseDuration(config.AppConfig.BackchannelLogoutTimeout)
+	if err != nil {
+		panic("invalid back-channel logout timeout: " + err.Error())
+	}
+
+	sessionExpiry, err := time.ParseDuration(config.AppConfig.JWTRefreshExpiry)
+	if err != nil {
+		panic("invalid refresh token expiry: " + err.Error())
+	}
+
+	return &Service{
+		repo:          repo,
+		clientService: clientService,
+		httpClient:    &http.Client{Timeout: timeout},
+		sessionExpiry: sessionExpiry,
+		maxAttempts:   max(config.AppConfig.BackchannelLogoutAttempts, 1),
+		workers:       max(config.AppConfig.BackchannelLogoutWorkers, 1),
+		jobs:          make(chan deliveryJob, deliveryQueueSize),
+	}
+}
+
+// Start launches the delivery workers. They stop when ctx is cancelled;
+// deliveries still pending at that point stay pending in the repository.
+func (s *Service) Start(ctx context.Context) {
+	for i := 0; i < s.workers; i++ {
+		go s.work(ctx)
+	}
+}
+
+// RecordSession remembers that a client received an ID token in the user's session,
+// so the client is notified when that session ends.
+// Clients registered for neither back-channel nor front-channel logout are not recorded.
+func (s *Service) RecordSession(ctx context.Context, clientID string, userID uint, sessionID string) error {
+	c, err := s.clientService.GetByClientID(ctx, clientID)
+	if err != nil {
+		return err
+	}
+	if c == nil || (c.BackchannelLogoutURI == "" && c.FrontchannelLogoutURI == "") {
+		return nil
+	}
+
+	now := time.Now()
+	return s.repo.SaveSession(ctx, &RPSession{
+		ClientID:  clientID,
+		UserID:    userID,
+		SessionID: sessionID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(s.sessionExpiry),
+	})
+}
+
+// SessionUser returns the user of a recorded client session, or zero if the session is unknown.
+func (s *Service) SessionUser(ctx context.Context, clientID, sessionID string) (uint, error) {
+	if sessionID == "" {
+		return 0, nil
+	}
+	return s.repo.FindSessionUser(ctx, clientID, sessionID)
+}
+
+// NotifyLogout queues a logout token for every client with a back-channel logout URI
+// that had an active session for the user. All of the user's sessions are forgotten once
+// the deliveries are recorded; delivery itself happens asynchronously.
+func (s *Service) NotifyLogout(ctx context.Context, userID uint) error {
+	sessions, err := s.repo.FindSessionsByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteSessionsByUser(ctx, userID); err != nil {
+		return err
+	}
+
+	return s.queueLogoutTokens(ctx, userID, sessions)
+}
+
+// NotifySessionLogout queues a logout token like NotifyLogout, but only for the clients that
+// took part in one web session of the user, identified by its public session ID. Only the
+// client sessions within it are forgotten; the user's other sessions stay logged in.
+func (s *Service) NotifySessionLogout(ctx context.Context, userID uint, sessionID string) error {
+	sessions, err := s.repo.FindSessionsBySession(ctx, userID, sessionID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteSessionsBySession(ctx, userID, sessionID); err != nil {
+		return err
+	}
+
+	return s.queueLogoutTokens(ctx, userID, sessions)
+}
+
+// queueLogoutTokens records and queues a logout delivery for each of the given client sessions
+// whose client has a back-channel logout URI.
+func (s *Service) queueLogoutTokens(ctx context.Context, userID uint, sessions []RPSession) error {
+	for _, session := range sessions {
+		c, err := s.clientService.GetByClientID(ctx, session.ClientID)
+		if err != nil {
+			return err
+		}
+		if c == nil || c.BackchannelLogoutURI == "" {
+			continue
+		}
+
+		now := time.Now()
+		delivery := &Delivery{
+			UserID:    userID,
+			ClientID:  session.ClientID,
+			SessionID: session.SessionID,
+			Status:    DeliveryStatusPending,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if err := s.repo.SaveDelivery(ctx, delivery); err != nil {
+			return err
+		}
+		s.enqueue(ctx, delivery)
+	}
+
+	return nil
+}
+
+// FrontchannelLogoutURIs returns the front-channel logout URI of every client that had an
+// active session for the user, with the iss and sid query parameters identifying the session
+// (Front-Channel Logout Section 2). The pages are to be loaded in iframes before the session ends.
+func (s *Service) FrontchannelLogoutURIs(ctx context.Context, userID uint) ([]string, error) {
+	sessions, err := s.repo.FindSessionsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.frontchannelURIs(ctx, sessions)
+}
+
+// FrontchannelSessionLogoutURIs returns the front-channel logout URIs like FrontchannelLogoutURIs,
+// but only of the clients that took part in one web session of the user.
+func (s *Service) FrontchannelSessionLogoutURIs(ctx context.Context, userID uint, sessionID string) ([]string, error) {
+	sessions, err := s.repo.FindSessionsBySession(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return s.frontchannelURIs(ctx, sessions)
+}
+
+// frontchannelURIs builds the front-channel logout URI of each of the given client sessions
+// whose client has one.
+func (s *Service) frontchannelURIs(ctx context.Context, sessions []RPSession) ([]string, error) {
+	uris := []string{}
+	for _, session := range sessions {
+		c, err := s.clientService.GetByClientID(ctx, session.ClientID)
+		if err != nil {
+			return nil, err
+		}
+		if c == nil || c.FrontchannelLogoutURI == "" {
+			continue
+		}
+
+		u, err := url.Parse(c.FrontchannelLogoutURI)
+		if err != nil {
+			continue
+		}
+		q := u.Query()
+		q.Set("iss", jwtutil.Issuer(ctx))
+		q.Set("sid", session.SessionID)
+		u.RawQuery = q.Encode()
+		uris = append(uris, u.String())
+	}
+
+	return uris, nil
+}
+
+// ListDeliveries returns the most recent logout deliveries matching the query, newest first.
+func (s *Service) ListDeliveries(ctx context.Context, query DeliveryQuery) (*DeliveryListResponse, error) {
+	if query.Status != "" && !IsValidDeliveryStatus(query.Status) {
+		return nil, errors.BadRequest(errors.ErrMsgInvalidLogoutDeliveryStatus)
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	if limit > maxListLimit {
+		limit = maxListLimit
+	}
+
+	deliveries, err := s.repo.ListDeliveries(ctx, query.Status, query.UserID, limit)
+	if err != nil {
+		return nil, err
+	}
+	if deliveries == nil {
+		deliveries = []Delivery{}
+	}
+
+	return &DeliveryListResponse{Deliveries: deliveries}, nil
+}
+
+// enqueue hands a delivery to the workers without blocking the caller.
+// The delivery is sent for the tenant ctx is served for.
+func (s *Service) enqueue(ctx context.Context, delivery *Delivery) {
+	job := deliveryJob{ctx: tenant.Detach(ctx), delivery: delivery}
+	select {
+	case s.jobs <- job:
+	default:
+		// Queue is full, wait for a free slot in the background
+		go func() { s.jobs <- job }()
+	}
+}
+
+// work delivers queued logout tokens until ctx is cancelled.
+func (s *Service) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-s.jobs:
+			jobCtx := ctx
+			if t := tenant.FromContext(job.ctx); t != nil {
+				jobCtx = tenant.WithTenant(ctx, t)
+			}
+			s.deliver(jobCtx, job.delivery)
+		}
+	}
+}
+
+// deliver sends the logout token for a delivery, retrying with exponential backoff,
+// and records the outcome of every attempt.
+func (s *Service) deliver(ctx context.Context, delivery *Delivery) {
+	// Outcomes are recorded even when ctx is cancelled during an attempt
+	recordCtx := tenant.Detach(ctx)
+	delay := retryBaseDelay
+	for delivery.Attempts < s.maxAttempts {
+		err := s.send(ctx, delivery)
+		delivery.Attempts++
+		delivery.UpdatedAt = time.Now()
+
+		if err == nil {
+			delivery.Status = DeliveryStatusDelivered
+			delivery.LastError = ""
+			s.repo.UpdateDelivery(recordCtx, delivery)
+			return
+		}
+
+		delivery.LastError = err.Error()
+		if delivery.Attempts >= s.maxAttempts {
+			break
+		}
+		// Not final yet, record the attempt and retry
+		s.repo.UpdateDelivery(recordCtx, delivery)
+
+		select {
+		case <-ctx.Done():
+			// Shutting down, the delivery stays pending
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, retryMaxDelay)
+	}
+
+	delivery.Status = DeliveryStatusFailed
+	s.repo.UpdateDelivery(recordCtx, delivery)
+}
+
+// send POSTs a freshly signed logout token to the client's back-channel logout URI.
+// Any 2xx response counts as delivered (Back-Channel Logout Section 2.8).
+func (s *Service) send(ctx context.Context, delivery *Delivery) error {
+	c, err := s.clientService.GetByClientID(ctx, delivery.ClientID)
+	if err != nil {
+		return err
+	}
+	if c == nil || c.BackchannelLogoutURI == "" {
+		return fmt.Errorf("client no longer has a back-channel logout URI")
+	}
+
+	token, err := s.createLogoutToken(ctx, delivery, c)
+	if err != nil {
+		return err
+	}
+
+	form := url.Values{"logout_token": {token}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BackchannelLogoutURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyPreview))
+		return fmt.Errorf("client responded with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
+
+// createLogoutToken signs a logout token (Back-Channel Logout Section 2.4) naming the user and session.
+// The user is named by the same sub the client received in its ID tokens.
+func (s *Service) createLogoutToken(ctx context.Context, delivery *Delivery, c *client.Client) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		jwtutil.ClaimKeyISS:       jwtutil.Issuer(ctx),
+		jwtutil.ClaimKeyAud:       delivery.ClientID,
+		jwtutil.ClaimKeyIAT:       now.Unix(),
+		jwtutil.ClaimKeyEXP:       now.Add(logoutTokenExpiry).Unix(),
+		jwtutil.ClaimKeyJTI:       uuid.NewString(),
+		jwtutil.ClaimKeySub:       c.Subject(delivery.UserID),
+		jwtutil.ClaimKeySessionID: delivery.SessionID,
+		jwtutil.ClaimKeyEvents:    map[string]interface{}{EventBackchannelLogout: map[string]interface{}{}},
+	}
+
+	return jwtutil.SignTokenWithType(ctx, claims, MediaTypeLogoutToken)
+}
@@ -0,0 +1,120 @@
+package oauth
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/app/client"
+	"github.com/verigate/verigate-server/internal/app/token"
+	"github.com/verigate/verigate-server/internal/pkg/middleware"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+)
+
+// accessTokenOptions determines the format, scope and audience of an access token issued to the client.
+// The token carries the granted scope with the scopes it implies expanded. It is addressed to the
+// requested resource indicators (RFC 8707), or to the resources granted earlier in the authorization
+// flow when none are requested. Without any resources, the token is addressed to the audiences
+// registered for its scopes, or to the client when none of them has one.
+// When granted is not empty, it is kept with the new grant and requested must be a subset of it.
+// Clients receiving pairwise subjects get tokens whose sub is the user's pairwise subject.
+// The token lifetimes are resolved for the grant type and the client's overrides, and clients
+// registered with bind_token_to_ip get refresh tokens bound to the subnet of the request.
+// Clients registered for certificate-bound access tokens must present their certificate, which
+// their access tokens are then bound to (RFC 8705 Section 3).
+// Requests carrying a verified DPoP proof get access tokens bound to its key, and public
+// clients get their refresh tokens bound to it too (RFC 9449 Section 5).
+// The token carries the custom claims the client's claim templates produce for the user, who
+// is zero for tokens the client receives for itself.
+func (s *Service) accessTokenOptions(ctx context.Context, grantType, clientID string, userID uint, scope string, granted, requested []string) (token.AccessTokenOptions, error) {
+	c, err := s.clientService.GetByClientID(ctx, clientID)
+	if err != nil {
+		return token.AccessTokenOptions{}, err
+	}
+	if c == nil {
+		return token.AccessTokenOptions{}, errors.Unauthorized(errors.ErrMsgInvalidClient)
+	}
+
+	if err := validateResources(c, requested); err != nil {
+		return token.AccessTokenOptions{}, err
+	}
+	if len(granted) > 0 {
+		for _, resource := range requested {
+			if !containsScope(granted, resource) {
+				return token.AccessTokenOptions{}, errors.BadRequest(errors.ErrMsgInvalidTarget)
+			}
+		}
+	}
+
+	opts := token.AccessTokenOptions{
+		JWTProfile: c.IssuesJWTAccessTokens(),
+		Audience:   requested,
+		Resources:  granted,
+		Lifetimes:  s.tokenService.Lifetimes(grantType, clientLifetimes(c)),
+		ClientIP:   middleware.ClientIPFromContext(ctx),
+		BindToIP:   c.BindTokenToIP,
+	}
+	if c.SubjectType == client.SubjectTypePairwise {
+		opts.PairwiseSector = c.SectorIdentifier()
+	}
+	if c.CertificateBoundTokens {
+		opts.CertThumbprint = middleware.ClientCertificateThumbprint(ctx)
+		if opts.CertThumbprint == "" {
+			return token.AccessTokenOptions{}, errors.BadRequest(errors.ErrMsgInvalidRequest).WithDetails(errors.ErrMsgClientCertificateRequired)
+		}
+	}
+	if thumbprint := token.DPoPKeyFromContext(ctx); thumbprint != "" {
+		opts.DPoPThumbprint = thumbprint
+		opts.BindRefreshToDPoPKey = !c.IsConfidential
+	}
+	if len(opts.Audience) == 0 {
+		opts.Audience = granted
+	}
+	if len(opts.Resources) == 0 {
+		opts.Resources = requested
+	}
+
+	opts.Scope, err = s.scopeService.ExpandScope(ctx, scope)
+	if err != nil {
+		return token.AccessTokenOptions{}, err
+	}
+	opts.Claims, err = s.customClaims(ctx, c, client.ClaimTemplateTokenAccess, grantType, userID, opts.Scope)
+	if err != nil {
+		return token.AccessTokenOptions{}, err
+	}
+	if len(opts.Audience) > 0 {
+		return opts, nil
+	}
+
+	scopes, err := s.scopeService.GetScopesByNames(ctx, strings.Fields(opts.Scope))
+	if err != nil {
+		return token.AccessTokenOptions{}, err
+	}
+	for _, sc := range scopes {
+		if sc.Audience != "" && !containsScope(opts.Audience, sc.Audience) {
+			opts.Audience = append(opts.Audience, sc.Audience)
+		}
+	}
+
+	return opts, nil
+}
+
+// clientLifetimes returns the token lifetimes a client overrides, unset where it uses the defaults.
+func clientLifetimes(c *client.Client) token.Lifetimes {
+	return token.Lifetimes{
+		AccessToken:  time.Duration(c.AccessTokenLifetime) * time.Second,
+		RefreshToken: time.Duration(c.RefreshTokenLifetime) * time.Second,
+		IDToken:      time.Duration(c.IDTokenLifetime) * time.Second,
+	}
+}
+
+// validateResources checks that every requested resource indicator is a valid resource URI
+// registered as allowed for the client. Duplicates are not rejected.
+func validateResources(c *client.Client, resources []string) error {
+	for _, resource := range resources {
+		if !client.IsValidResourceURI(resource) || !c.AllowsResource(resource) {
+			return errors.BadRequest(errors.ErrMsgInvalidTarget)
+		}
+	}
+	return nil
+}
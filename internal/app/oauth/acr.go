@@ -0,0 +1,79 @@
+package oauth
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/verigate/verigate-server/internal/pkg/config"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+)
+
+// acrSatisfied reports whether a session whose user logged in as sessionACR meets the
+// requested acr value, according to the configured ACR requirements.
+func acrSatisfied(value, sessionACR string) bool {
+	return sessionACR != "" && containsScope(config.AppConfig.ACRRequirements[value], sessionACR)
+}
+
+// supportedACRValues returns the acr values relying parties may request, in a stable order.
+func supportedACRValues() []string {
+	values := make([]string, 0, len(config.AppConfig.ACRRequirements))
+	for value := range config.AppConfig.ACRRequirements {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+	return values
+}
+
+// resolveACR determines the acr claim of the ID token issued for an authorization request
+// (OpenID Connect Core Section 3.1.2.1). Without acr_values it is how the user logged in.
+// Otherwise it is the first requested value, in order of preference, that the session meets.
+// When the session meets none, login_required asks for a step-up login if the user can log
+// in in a way that meets one, and unmet_authentication_requirements reports that they cannot.
+// A session of unknown ACR, such as one authenticated with a web access token, meets no value.
+func (s *Service) resolveACR(ctx context.Context, userID uint, acrValues []string, sessionACR string) (string, error) {
+	if len(acrValues) == 0 {
+		return sessionACR, nil
+	}
+
+	for _, value := range acrValues {
+		if acrSatisfied(value, sessionACR) {
+			return value, nil
+		}
+	}
+
+	contexts, err := s.userService.AuthenticationContexts(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	for _, value := range acrValues {
+		for _, acr := range contexts {
+			if acrSatisfied(value, acr) {
+				return "", errors.Unauthorized(errors.ErrMsgLoginRequired).WithDetails("a stronger authentication is required")
+			}
+		}
+	}
+
+	return "", errors.BadRequest(errors.ErrMsgUnmetAuthRequirements).WithDetails("the requested acr_values cannot be met")
+}
+
+// resolveRequestedACR determines the acr claim of the ID token from the acr_values parameter or,
+// taking precedence, the acr claim requested in the claims parameter. An essential acr claim is
+// resolved like acr_values. A voluntary one is the first value the session meets, and how the
+// user logged in when it meets none, without asking for a step-up login.
+func (s *Service) resolveRequestedACR(ctx context.Context, userID uint, acrValues string, claimsRequest *ClaimsRequest, sessionACR string) (string, error) {
+	values, essential := claimsRequest.requestedACR()
+	if len(values) == 0 {
+		return s.resolveACR(ctx, userID, strings.Fields(acrValues), sessionACR)
+	}
+	if essential {
+		return s.resolveACR(ctx, userID, values, sessionACR)
+	}
+
+	for _, value := range values {
+		if acrSatisfied(value, sessionACR) {
+			return value, nil
+		}
+	}
+	return sessionACR, nil
+}
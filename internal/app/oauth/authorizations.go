@@ -0,0 +1,87 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/app/audit"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+)
+
+// ListAuthorizations returns the applications the user has consented to, oldest grant first.
+// Consents to clients that have since been deleted are left out.
+func (s *Service) ListAuthorizations(ctx context.Context, userID uint) ([]*AuthorizationResponse, error) {
+	consents, err := s.oauthRepo.FindUserConsentsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*AuthorizationResponse, 0, len(consents))
+	for _, consent := range consents {
+		c, err := s.clientService.GetByClientID(ctx, consent.ClientID)
+		if err != nil {
+			return nil, err
+		}
+		if c == nil {
+			continue
+		}
+
+		responses = append(responses, &AuthorizationResponse{
+			ClientID:   c.ClientID,
+			ClientName: c.ClientName,
+			ClientURI:  c.ClientURI,
+			LogoURI:    c.LogoURI,
+			Scope:      consent.Scope,
+			GrantedAt:  consent.CreatedAt,
+			UpdatedAt:  consent.UpdatedAt,
+			LastUsedAt: consent.LastUsedAt,
+		})
+	}
+	return responses, nil
+}
+
+// RevokeAuthorization withdraws the user's consent to a client and revokes every access and
+// refresh token the client holds for the user, so the client must ask for consent again.
+// The tokens are revoked before the consent is deleted, so a failed revocation can be retried.
+// Returns NotFound if the user has not authorized the client.
+func (s *Service) RevokeAuthorization(ctx context.Context, userID uint, clientID string) error {
+	consent, err := s.oauthRepo.FindUserConsent(ctx, userID, clientID)
+	if err != nil {
+		return err
+	}
+	if consent == nil {
+		return errors.NotFound(fmt.Sprintf(errors.ErrMsgUserConsentNotFoundForUserAndClient, userID, clientID))
+	}
+
+	revoked, err := s.tokenService.RevokeUserClientTokens(ctx, userID, clientID)
+	if err != nil {
+		return err
+	}
+	if err := s.oauthRepo.DeleteUserConsent(ctx, userID, clientID); err != nil {
+		return err
+	}
+
+	s.auditService.Record(ctx, audit.Event{
+		Type:     audit.EventConsentRevoked,
+		Actor:    audit.UserActor(userID),
+		ClientID: clientID,
+		Details: map[string]string{
+			"access_tokens_revoked":  strconv.Itoa(revoked.AccessTokensRevoked),
+			"refresh_tokens_revoked": strconv.FormatInt(revoked.RefreshTokensRevoked, 10),
+		},
+	})
+	return nil
+}
+
+// recordAuthorizationUse records that tokens were issued to a client under the user's consent,
+// so the user can tell which authorizations have gone unused. Client tokens have no user.
+func (s *Service) recordAuthorizationUse(ctx context.Context, userID uint, clientID string) {
+	if userID == 0 {
+		return
+	}
+	if err := s.oauthRepo.TouchUserConsent(ctx, userID, clientID, time.Now()); err != nil {
+		// Not critical, the tokens were issued
+	}
+}
@@ -0,0 +1,105 @@
+package oauth
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/verigate/verigate-server/internal/app/user"
+)
+
+// OpenID Connect scope constants
+const (
+	ScopeOpenID  = "openid"  // Required scope for OpenID Connect requests
+	ScopeProfile = "profile" // Grants access to the user's default profile claims
+	ScopeEmail   = "email"   // Grants access to the email and email_verified claims
+	ScopePhone   = "phone"   // Grants access to the phone_number claim
+
+	// ScopeOfflineAccess requests a refresh token, for access while the user is not logged in
+	ScopeOfflineAccess = "offline_access"
+)
+
+// ClaimProvider returns the claims released for a scope about the given user.
+// Claims with empty values should be omitted from the returned map.
+type ClaimProvider func(u *user.UserResponse) map[string]interface{}
+
+// ClaimRegistry maps scopes to the providers of the claims they release.
+// It is safe for concurrent use.
+type ClaimRegistry struct {
+	mu        sync.RWMutex
+	providers map[string][]ClaimProvider
+}
+
+// NewClaimRegistry creates a claim registry preloaded with the standard
+// OpenID Connect profile, email, and phone scope claims.
+func NewClaimRegistry() *ClaimRegistry {
+	r := &ClaimRegistry{providers: make(map[string][]ClaimProvider)}
+	r.Register(ScopeProfile, profileClaims)
+	r.Register(ScopeEmail, emailClaims)
+	r.Register(ScopePhone, phoneClaims)
+	return r
+}
+
+// Register adds a claim provider for the scope.
+// Multiple providers may be registered for the same scope; their claims are merged.
+func (r *ClaimRegistry) Register(scope string, provider ClaimProvider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[scope] = append(r.providers[scope], provider)
+}
+
+// Claims collects the claims released by the given scopes for the user.
+// Scopes without a registered provider contribute no claims.
+func (r *ClaimRegistry) Claims(scopes []string, u *user.UserResponse) map[string]interface{} {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	claims := make(map[string]interface{})
+	for _, scope := range scopes {
+		for _, provider := range r.providers[scope] {
+			for name, value := range provider(u) {
+				claims[name] = value
+			}
+		}
+	}
+	return claims
+}
+
+// profileClaims releases the standard profile claims available for a user.
+func profileClaims(u *user.UserResponse) map[string]interface{} {
+	claims := map[string]interface{}{
+		"preferred_username": u.Username,
+	}
+
+	if u.FullName != nil && strings.TrimSpace(*u.FullName) != "" {
+		claims["name"] = *u.FullName
+
+		// Split the full name on the last space into given and family names
+		parts := strings.Fields(*u.FullName)
+		claims["given_name"] = strings.Join(parts[:max(1, len(parts)-1)], " ")
+		if len(parts) > 1 {
+			claims["family_name"] = parts[len(parts)-1]
+		}
+	}
+
+	if u.ProfilePictureURL != nil && *u.ProfilePictureURL != "" {
+		claims["picture"] = *u.ProfilePictureURL
+	}
+
+	return claims
+}
+
+// emailClaims releases the email and email_verified claims for a user.
+func emailClaims(u *user.UserResponse) map[string]interface{} {
+	return map[string]interface{}{
+		"email":          u.Email,
+		"email_verified": u.IsVerified,
+	}
+}
+
+// phoneClaims releases the phone_number claim for a user if one is set.
+func phoneClaims(u *user.UserResponse) map[string]interface{} {
+	if u.PhoneNumber == nil || *u.PhoneNumber == "" {
+		return nil
+	}
+	return map[string]interface{}{"phone_number": *u.PhoneNumber}
+}
@@ -0,0 +1,88 @@
+package oauth
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+	jwtutil "github.com/verigate/verigate-server/internal/pkg/utils/jwt"
+)
+
+// ClaimsRequest is the claims request parameter (OpenID Connect Core Section 5.5): the individual
+// claims a relying party asks to receive in the ID token and from the UserInfo endpoint. A claim
+// is only released if a granted scope releases it, so the parameter can move scope claims into
+// the ID token but never widens what the user consented to. The UserInfo endpoint releases every
+// claim of the granted scopes, so the claims it is asked for are already covered.
+type ClaimsRequest struct {
+	UserInfo map[string]*ClaimRequest `json:"userinfo,omitempty"` // Claims requested from the UserInfo endpoint
+	IDToken  map[string]*ClaimRequest `json:"id_token,omitempty"` // Claims requested in the ID token
+}
+
+// ClaimRequest qualifies a requested claim (OpenID Connect Core Section 5.5.1).
+// A null entry requests the claim in the default manner, as a voluntary claim.
+type ClaimRequest struct {
+	Essential bool          `json:"essential,omitempty"` // Whether the claim is needed for the client to work
+	Value     interface{}   `json:"value,omitempty"`     // The value the claim is requested to have
+	Values    []interface{} `json:"values,omitempty"`    // The values the claim is requested to have, most preferred first
+}
+
+// parseClaimsRequest parses the JSON of the claims parameter. An empty parameter yields nil.
+// Anything other than an object whose userinfo and id_token members map claim names to null
+// or to an object of essential, value, and values is an invalid_request; other members are ignored.
+func parseClaimsRequest(raw string) (*ClaimsRequest, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+
+	var req ClaimsRequest
+	if !strings.HasPrefix(raw, "{") || json.Unmarshal([]byte(raw), &req) != nil {
+		return nil, errors.BadRequest(errors.ErrMsgInvalidRequest).WithDetails(errors.ErrMsgInvalidClaimsParameter)
+	}
+	return &req, nil
+}
+
+// idTokenClaims returns the names of the claims requested in the ID token, in a stable order.
+func (r *ClaimsRequest) idTokenClaims() []string {
+	if r == nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(r.IDToken))
+	for name := range r.IDToken {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// requestedACR returns the acr values requested for the ID token, most preferred first, and
+// whether the acr claim is essential. It returns no values if acr is not requested with any.
+func (r *ClaimsRequest) requestedACR() ([]string, bool) {
+	if r == nil || r.IDToken[jwtutil.ClaimKeyACR] == nil {
+		return nil, false
+	}
+
+	claim := r.IDToken[jwtutil.ClaimKeyACR]
+	var values []string
+	if value, ok := claim.Value.(string); ok {
+		values = append(values, value)
+	}
+	for _, v := range claim.Values {
+		if value, ok := v.(string); ok {
+			values = append(values, value)
+		}
+	}
+	return values, claim.Essential
+}
+
+// requestedSubject returns the sub value requested for the ID token, if any. Only the user
+// known to the client by that subject may then be authorized (OpenID Connect Core Section 3.1.2.2).
+func (r *ClaimsRequest) requestedSubject() (string, bool) {
+	if r == nil || r.IDToken[jwtutil.ClaimKeySub] == nil || r.IDToken[jwtutil.ClaimKeySub].Value == nil {
+		return "", false
+	}
+	return fmt.Sprint(r.IDToken[jwtutil.ClaimKeySub].Value), true
+}
@@ -0,0 +1,101 @@
+package oauth
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/verigate/verigate-server/internal/app/client"
+	"github.com/verigate/verigate-server/internal/app/replay"
+	"github.com/verigate/verigate-server/internal/pkg/tenant"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+	jwtutil "github.com/verigate/verigate-server/internal/pkg/utils/jwt"
+)
+
+// Client assertion settings (RFC 7523)
+const (
+	TokenEndpointPath            = "/api/v1/oauth/token"                                    // Path of the token endpoint, the required assertion audience
+	ClientAssertionTypeJWTBearer = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer" // client_assertion_type for JWT assertions
+
+	maxClientAssertionLifetime = time.Hour // Latest accepted assertion expiry, bounding replay records
+)
+
+// TokenEndpointURL returns the absolute URL of the token endpoint of the tenant ctx is served for.
+func TokenEndpointURL(ctx context.Context) string {
+	return tenant.BaseURL(ctx) + TokenEndpointPath
+}
+
+// authenticateClientAssertion verifies a private_key_jwt client assertion (RFC 7523 Section 3).
+// The assertion must be signed by a key registered for the client, name the client as both
+// iss and sub, be addressed to the token endpoint, carry an expiry and a jti, and not have
+// been used before. An iat, when present, must be no older than the longest accepted assertion
+// lifetime. Used jti values are remembered until the assertion expires.
+func (s *Service) authenticateClientAssertion(ctx context.Context, creds ClientCredentials) (*client.Client, error) {
+	if creds.ClientAssertionType != ClientAssertionTypeJWTBearer || creds.ClientAssertion == "" {
+		return nil, errors.Unauthorized(errors.ErrMsgInvalidClientAssertion)
+	}
+
+	// The client is identified by the assertion subject before its signature can be checked
+	var unverified jwt.RegisteredClaims
+	if _, _, err := new(jwt.Parser).ParseUnverified(creds.ClientAssertion, &unverified); err != nil {
+		return nil, errors.Unauthorized(errors.ErrMsgInvalidClientAssertion)
+	}
+	clientID := unverified.Subject
+	if clientID == "" || (creds.ClientID != "" && creds.ClientID != clientID) {
+		return nil, errors.Unauthorized(errors.ErrMsgInvalidClientAssertion)
+	}
+
+	c, err := s.clientService.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, errors.Unauthorized(errors.ErrMsgInvalidClientCredentials)
+	}
+	if !c.IsActive {
+		return nil, errors.Unauthorized(errors.ErrMsgClientNotActive)
+	}
+	if c.TokenEndpointAuthMethod != client.AuthMethodPrivateKeyJWT {
+		return nil, errors.Unauthorized(errors.ErrMsgClientAuthMethodMismatch)
+	}
+
+	keys, err := s.clientService.VerificationKeys(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims jwt.RegisteredClaims
+	if _, err := jwtutil.ParseWithJWKS(creds.ClientAssertion, keys, &claims); err != nil {
+		return nil, errors.Unauthorized(errors.ErrMsgInvalidClientAssertion)
+	}
+
+	if claims.Issuer != clientID || claims.Subject != clientID {
+		return nil, errors.Unauthorized(errors.ErrMsgInvalidClientAssertion)
+	}
+	if !claims.VerifyAudience(TokenEndpointURL(ctx), true) {
+		return nil, errors.Unauthorized(errors.ErrMsgInvalidClientAssertion)
+	}
+	if claims.ExpiresAt == nil || claims.ID == "" {
+		return nil, errors.Unauthorized(errors.ErrMsgInvalidClientAssertion)
+	}
+
+	// Bound the assertion's lifetime, tolerating the same clock skew as its exp and nbf checks
+	skew := jwtutil.ClockSkew()
+	if time.Until(claims.ExpiresAt.Time) > maxClientAssertionLifetime+skew {
+		return nil, errors.Unauthorized(errors.ErrMsgInvalidClientAssertion)
+	}
+	if claims.IssuedAt != nil && time.Since(claims.IssuedAt.Time) > maxClientAssertionLifetime+skew {
+		return nil, errors.Unauthorized(errors.ErrMsgInvalidClientAssertion)
+	}
+
+	// Remember the jti for as long as the assertion is accepted; jti values are unique per client
+	replayed, err := s.jtiStore.Record(ctx, replay.NamespaceClientAssertion, clientID+":"+claims.ID, replay.TTL(claims.ExpiresAt.Time))
+	if err != nil {
+		return nil, errors.Internal(errors.ErrMsgFailedToRecordAssertion).Wrap(err)
+	}
+	if replayed {
+		return nil, errors.Unauthorized(errors.ErrMsgClientAssertionReplayed)
+	}
+
+	return c, nil
+}
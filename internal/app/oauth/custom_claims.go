@@ -0,0 +1,38 @@
+package oauth
+
+import (
+	"context"
+	"strings"
+
+	"github.com/verigate/verigate-server/internal/app/client"
+	"github.com/verigate/verigate-server/internal/pkg/tenant"
+)
+
+// customClaims evaluates the client's claim templates for a token of the given kind issued to the
+// user, or to the client itself when userID is zero. User attributes are offered to the templates
+// only when the granted scope releases them, and the user's identifier only as the subject the
+// client knows the user by. Returns nil when the client registered no templates.
+func (s *Service) customClaims(ctx context.Context, c *client.Client, token, grantType string, userID uint, scope string) (map[string]interface{}, error) {
+	if len(c.ClaimTemplates) == 0 {
+		return nil, nil
+	}
+
+	variables := map[string]interface{}{
+		client.ClaimVariableClientID:  c.ClientID,
+		client.ClaimVariableGrantType: grantType,
+		client.ClaimVariableScope:     scope,
+		client.ClaimVariableTenantID:  tenant.ID(ctx),
+	}
+	if userID != 0 {
+		user, err := s.userService.GetByID(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range s.claimRegistry.Claims(strings.Fields(scope), user) {
+			variables["user."+name] = value
+		}
+		variables[client.ClaimVariableUserSub] = c.Subject(userID)
+	}
+
+	return c.CustomClaims(token, variables), nil
+}
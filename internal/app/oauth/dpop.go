@@ -0,0 +1,167 @@
+package oauth
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/app/replay"
+	"github.com/verigate/verigate-server/internal/app/token"
+	"github.com/verigate/verigate-server/internal/pkg/config"
+	"github.com/verigate/verigate-server/internal/pkg/utils/encryption"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+	jwtutil "github.com/verigate/verigate-server/internal/pkg/utils/jwt"
+)
+
+// DPoP headers (RFC 9449)
+const (
+	HeaderDPoP      = "DPoP"       // Request header carrying a DPoP proof
+	HeaderDPoPNonce = "DPoP-Nonce" // Response header carrying the nonce the next proof must contain
+)
+
+// dpopSettings holds how DPoP proofs are checked.
+type dpopSettings struct {
+	maxAge        time.Duration // How old the iat of a proof may be
+	nonceKey      []byte        // Key encrypting issued nonces, nil when proofs need no nonce
+	nonceLifetime time.Duration // How long an issued nonce is accepted
+}
+
+// loadDPoPSettings reads the DPoP settings from the application configuration.
+// It panics on an invalid duration or nonce key, like the other settings of the service.
+func loadDPoPSettings() dpopSettings {
+	maxAge, err := time.ParseDuration(config.AppConfig.DPoPProofMaxAge)
+	if err != nil || maxAge <= 0 {
+		panic("invalid DPoP proof max age: " + config.AppConfig.DPoPProofMaxAge)
+	}
+	settings := dpopSettings{maxAge: maxAge}
+
+	if config.AppConfig.DPoPNonceKey != "" {
+		key, err := encryption.ParseKey(config.AppConfig.DPoPNonceKey)
+		if err != nil {
+			panic("invalid DPoP nonce key: " + err.Error())
+		}
+		lifetime, err := time.ParseDuration(config.AppConfig.DPoPNonceLifetime)
+		if err != nil || lifetime <= 0 {
+			panic("invalid DPoP nonce lifetime: " + config.AppConfig.DPoPNonceLifetime)
+		}
+		settings.nonceKey = key
+		settings.nonceLifetime = lifetime
+	}
+
+	return settings
+}
+
+// VerifyDPoPProof checks the DPoP proof sent with a request (RFC 9449 Section 4.3) and
+// returns the JWK thumbprint of the key it was signed with. The proof must be for the
+// request's method and URI, compared without query and fragment, have been issued within
+// the accepted age, and not have been used before. When nonces are enabled it must carry
+// a nonce the server issued that has not expired. When the proof is sent with an access
+// token, it must carry the token's hash in ath.
+// Failures are invalid_dpop_proof errors, or use_dpop_nonce when the nonce is missing or stale.
+func (s *Service) VerifyDPoPProof(ctx context.Context, proof, method, uri, accessToken string) (string, error) {
+	claims, thumbprint, err := jwtutil.ParseDPoPProof(proof)
+	if err != nil {
+		return "", invalidDPoPProof("proof signature or format is invalid")
+	}
+
+	if claims.HTM != method {
+		return "", invalidDPoPProof("htm does not match the request method")
+	}
+	if !sameRequestURI(claims.HTU, uri) {
+		return "", invalidDPoPProof("htu does not match the request URI")
+	}
+
+	now := time.Now()
+	skew := jwtutil.ClockSkew()
+	issuedAt := claims.IssuedAt.Time
+	if issuedAt.After(now.Add(skew)) || issuedAt.Before(now.Add(-s.dpop.maxAge-skew)) {
+		return "", invalidDPoPProof("iat is outside the accepted window")
+	}
+
+	if accessToken != "" && claims.ATH != jwtutil.AccessTokenHash(accessToken) {
+		return "", invalidDPoPProof("ath does not match the access token")
+	}
+
+	if s.dpop.nonceKey != nil && !s.isValidDPoPNonce(claims.Nonce) {
+		return "", errors.BadRequest(errors.ErrMsgUseDPoPNonce).WithDetails("proof must carry the nonce provided in the DPoP-Nonce header")
+	}
+
+	// Proofs are per key, and a proof is accepted until its iat leaves the window
+	replayed, err := s.jtiStore.Record(ctx, replay.NamespaceDPoPProof, thumbprint+":"+claims.ID, replay.TTL(issuedAt.Add(s.dpop.maxAge)))
+	if err != nil {
+		return "", err
+	}
+	if replayed {
+		return "", invalidDPoPProof("proof has already been used")
+	}
+
+	return thumbprint, nil
+}
+
+// DPoPNonce issues a nonce for clients to include in their next DPoP proofs (RFC 9449 Section 8),
+// or returns an empty string when nonces are not enabled. A nonce is the encrypted time it was
+// issued at, so any instance of the server can check it without shared state.
+func (s *Service) DPoPNonce() (string, error) {
+	if s.dpop.nonceKey == nil {
+		return "", nil
+	}
+	nonce, err := encryption.Encrypt(s.dpop.nonceKey, strconv.FormatInt(time.Now().Unix(), 10))
+	if err != nil {
+		return "", errors.Internal(errors.ErrMsgFailedToIssueDPoPNonce).Wrap(err)
+	}
+	return nonce, nil
+}
+
+// isValidDPoPNonce reports whether the nonce was issued by DPoPNonce and has not expired.
+func (s *Service) isValidDPoPNonce(nonce string) bool {
+	if nonce == "" {
+		return false
+	}
+	plaintext, err := encryption.Decrypt(s.dpop.nonceKey, nonce)
+	if err != nil {
+		return false
+	}
+	issued, err := strconv.ParseInt(plaintext, 10, 64)
+	if err != nil {
+		return false
+	}
+	age := time.Since(time.Unix(issued, 0))
+	return age >= -jwtutil.ClockSkew() && age <= s.dpop.nonceLifetime
+}
+
+// withDPoPProof verifies the DPoP proof of a request, when one was sent, and returns ctx
+// carrying the thumbprint of its key, which the tokens issued for the request are bound to.
+// Without a proof ctx is returned unchanged.
+func (s *Service) withDPoPProof(ctx context.Context, proof, method, uri, accessToken string) (context.Context, error) {
+	if proof == "" {
+		return ctx, nil
+	}
+	thumbprint, err := s.VerifyDPoPProof(ctx, proof, method, uri, accessToken)
+	if err != nil {
+		return ctx, err
+	}
+	return token.WithDPoPKey(ctx, thumbprint), nil
+}
+
+// sameRequestURI reports whether the htu of a proof names the request URI. Query and fragment
+// are ignored, and scheme and host are compared case-insensitively (RFC 9449 Section 4.3).
+func sameRequestURI(htu, uri string) bool {
+	got, err := url.Parse(htu)
+	if err != nil {
+		return false
+	}
+	want, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(got.Scheme, want.Scheme) &&
+		strings.EqualFold(got.Host, want.Host) &&
+		got.EscapedPath() == want.EscapedPath()
+}
+
+// invalidDPoPProof returns an invalid_dpop_proof error with the given description.
+func invalidDPoPProof(description string) error {
+	return errors.BadRequest(errors.ErrMsgInvalidDPoPProof).WithDetails(description)
+}
@@ -2,65 +2,323 @@
 // including authorization code, implicit, password, and client credentials.
 package oauth
 
+import (
+	"crypto/x509"
+	"time"
+
+	jwtutil "github.com/verigate/verigate-server/internal/pkg/utils/jwt"
+)
+
 // AuthorizeRequest represents an OAuth 2.0 authorization request.
 // This request initiates the authorization flow as defined in RFC 6749.
 type AuthorizeRequest struct {
-	ResponseType        string `form:"response_type" binding:"required"` // Response type (code, token)
-	ClientID            string `form:"client_id" binding:"required"`     // OAuth client identifier
-	RedirectURI         string `form:"redirect_uri" binding:"required"`  // URI to redirect after authorization
-	Scope               string `form:"scope"`                            // Requested permission scopes
-	State               string `form:"state"`                            // Client state value for CSRF protection
-	CodeChallenge       string `form:"code_challenge"`                   // PKCE code challenge
-	CodeChallengeMethod string `form:"code_challenge_method"`            // PKCE challenge method (plain or S256)
+	ResponseType        string   `form:"response_type" binding:"required"` // Response type: code, or a hybrid type such as "code id_token"
+	ClientID            string   `form:"client_id" binding:"required"`     // OAuth client identifier
+	RedirectURI         string   `form:"redirect_uri"`                     // URI to redirect after authorization, required unless sent in a request object
+	ResponseMode        string   `form:"response_mode"`                    // How the response is delivered: query, fragment, or form_post (default depends on the response type)
+	Scope               string   `form:"scope"`                            // Requested permission scopes
+	State               string   `form:"state"`                            // Client state value for CSRF protection
+	Nonce               string   `form:"nonce"`                            // Value copied into the ID token to bind it to the client session (OpenID Connect Core Section 3.1.2.1)
+	CodeChallenge       string   `form:"code_challenge"`                   // PKCE code challenge
+	CodeChallengeMethod string   `form:"code_challenge_method"`            // PKCE challenge method (plain or S256)
+	Prompt              string   `form:"prompt"`                           // Space-separated prompt values (OpenID Connect Core Section 3.1.2.1)
+	MaxAge              string   `form:"max_age"`                          // Maximum seconds since the user last authenticated
+	Resource            []string `form:"resource"`                         // Resource servers the access token is for (RFC 8707)
+	Request             string   `form:"request"`                          // Request object carrying the parameters as a signed JWT (RFC 9101)
+	RequestURI          string   `form:"request_uri"`                      // URI to fetch the request object from (RFC 9101)
+	UILocales           string   `form:"ui_locales"`                       // Space-separated preferred languages for the user interface, most preferred first
+	ACRValues           string   `form:"acr_values"`                       // Space-separated authentication context classes requested, most preferred first
+	Claims              string   `form:"claims"`                           // JSON of the individual claims requested (OpenID Connect Core Section 5.5)
+	LoginHint           string   `form:"login_hint"`                       // Identifier of the user the client expects to log in, such as a username or email address
+}
+
+// AuthorizationResult is what the authorization endpoint sends back to the client for an approved
+// request: the code and the state, plus for hybrid response types the access token or ID token
+// issued with the code (OpenID Connect Core Section 3.3.2.5).
+type AuthorizationResult struct {
+	Code        string // Authorization code to exchange at the token endpoint
+	State       string // State to return to the client
+	AccessToken string // Access token for response types with token
+	TokenType   string // Type of the access token
+	ExpiresIn   int    // Access token lifetime in seconds
+	Scope       string // Scope of the access token
+	IDToken     string // ID token for response types with id_token
+}
+
+// PushedAuthorizationResponse is returned by the pushed authorization request endpoint (RFC 9126 Section 2.2).
+type PushedAuthorizationResponse struct {
+	RequestURI string `json:"request_uri"` // Reference to the pushed request for the authorization endpoint
+	ExpiresIn  int    `json:"expires_in"`  // Seconds until the request_uri expires
 }
 
+// Prompt values (OpenID Connect Core Section 3.1.2.1)
+const (
+	PromptNone    = "none"    // Never show UI; fail with login_required or consent_required instead
+	PromptLogin   = "login"   // Force re-authentication even with an active session
+	PromptConsent = "consent" // Force the consent screen even when the requested scopes were granted before
+)
+
+// loginAfterParam is added to an authorization request resumed after a login the server asked
+// for, holding the Unix time it asked. Only a session authenticated since then may resume it,
+// so a session found too old or too weak is never reused for the request it failed.
+const loginAfterParam = "login_after"
+
+// Grant type constants
+const (
+	GrantTypeAuthorizationCode = "authorization_code"                              // Authorization code exchange (RFC 6749 Section 4.1)
+	GrantTypeRefreshToken      = "refresh_token"                                   // Refresh token exchange (RFC 6749 Section 6)
+	GrantTypeClientCredentials = "client_credentials"                              // Client acting on its own behalf (RFC 6749 Section 4.4)
+	GrantTypeDeviceCode        = "urn:ietf:params:oauth:grant-type:device_code"    // Device authorization grant (RFC 8628)
+	GrantTypeTokenExchange     = "urn:ietf:params:oauth:grant-type:token-exchange" // Token exchange (RFC 8693)
+)
+
 // TokenRequest represents an OAuth 2.0 token request.
 // This can be used for authorization code exchange, refresh token usage,
 // client credentials, or password grant types.
 type TokenRequest struct {
-	GrantType    string `form:"grant_type" binding:"required"` // Grant type (e.g., authorization_code, refresh_token)
-	Code         string `form:"code"`                          // Authorization code (for authorization_code grant)
-	RedirectURI  string `form:"redirect_uri"`                  // Must match the original redirect URI
-	ClientID     string `form:"client_id"`                     // OAuth client identifier
-	ClientSecret string `form:"client_secret"`                 // Client secret for confidential clients
-	RefreshToken string `form:"refresh_token"`                 // Refresh token (for refresh_token grant)
-	Scope        string `form:"scope"`                         // Requested permission scopes
-	CodeVerifier string `form:"code_verifier"`                 // PKCE code verifier
+	GrantType             string   `form:"grant_type" binding:"required"` // Grant type (e.g., authorization_code, refresh_token)
+	Code                  string   `form:"code"`                          // Authorization code (for authorization_code grant)
+	RedirectURI           string   `form:"redirect_uri"`                  // Must match the original redirect URI
+	ClientID              string   `form:"client_id"`                     // OAuth client identifier
+	ClientSecret          string   `form:"client_secret"`                 // Client secret for confidential clients
+	RefreshToken          string   `form:"refresh_token"`                 // Refresh token (for refresh_token grant)
+	Scope                 string   `form:"scope"`                         // Requested permission scopes
+	CodeVerifier          string   `form:"code_verifier"`                 // PKCE code verifier
+	DeviceCode            string   `form:"device_code"`                   // Device code (for device_code grant)
+	ClientAssertionType   string   `form:"client_assertion_type"`         // Client assertion format (for private_key_jwt)
+	ClientAssertion       string   `form:"client_assertion"`              // Signed client authentication JWT (for private_key_jwt)
+	Resource              []string `form:"resource"`                      // Resource servers the access token is for (RFC 8707)
+	State                 string   `form:"state"`                         // State token the server issued with the code, required from managed_state clients
+	GrantManagementAction string   `form:"grant_management_action"`       // How the authorization is applied to a grant: create, update, or replace
+	GrantID               string   `form:"grant_id"`                      // Grant to update or replace
+	SubjectToken          string   `form:"subject_token"`                 // Token representing the user (for token-exchange grant)
+	SubjectTokenType      string   `form:"subject_token_type"`            // Type of the subject token
+	ActorToken            string   `form:"actor_token"`                   // Token representing the party acting for the user, for delegation
+	ActorTokenType        string   `form:"actor_token_type"`              // Type of the actor token, required with it
+	RequestedTokenType    string   `form:"requested_token_type"`          // Type of token to issue, only access tokens are supported
+	Audience              []string `form:"audience"`                      // Logical names of the services the token is for (RFC 8693)
+}
+
+// ClientCredentials holds the client authentication data presented at a token endpoint.
+// Method records how the credentials were presented so it can be checked against
+// the client's registered token_endpoint_auth_method.
+type ClientCredentials struct {
+	ClientID            string            // Claimed client identifier, may be empty when only an assertion is sent
+	ClientSecret        string            // Client secret for client_secret_basic and client_secret_post
+	ClientAssertionType string            // Assertion format for private_key_jwt
+	ClientAssertion     string            // Signed assertion for private_key_jwt
+	Method              string            // Client authentication method the credentials were presented with
+	ClientCertificate   *x509.Certificate // Certificate presented for mutual TLS when no other credentials were sent
 }
 
 // TokenResponse represents an OAuth 2.0 token response.
 // This is returned when a token is successfully issued, as defined in RFC 6749 Section 5.1.
 type TokenResponse struct {
-	AccessToken  string `json:"access_token"`            // The issued access token
-	TokenType    string `json:"token_type"`              // Token type (typically "Bearer")
-	ExpiresIn    int    `json:"expires_in"`              // Token lifetime in seconds
-	RefreshToken string `json:"refresh_token,omitempty"` // Optional refresh token
-	Scope        string `json:"scope,omitempty"`         // Scope of the access token
+	AccessToken     string `json:"access_token"`                // The issued access token
+	IssuedTokenType string `json:"issued_token_type,omitempty"` // Type of the issued token, for token exchange (RFC 8693 Section 2.2.1)
+	TokenType       string `json:"token_type"`                  // Token type (typically "Bearer")
+	ExpiresIn       int    `json:"expires_in"`                  // Token lifetime in seconds
+	RefreshToken    string `json:"refresh_token,omitempty"`     // Optional refresh token
+	Scope           string `json:"scope,omitempty"`             // Scope of the access token
+	IDToken         string `json:"id_token,omitempty"`          // OpenID Connect ID token for openid requests
+	GrantID         string `json:"grant_id,omitempty"`          // Grant the tokens were issued under, for grant management requests
 }
 
+// Token type hints accepted by the revocation endpoint (RFC 7009 Section 2.1)
+const (
+	TokenTypeHintAccessToken  = "access_token"
+	TokenTypeHintRefreshToken = "refresh_token"
+)
+
+// RevokeRequest represents an OAuth 2.0 token revocation request (RFC 7009).
 type RevokeRequest struct {
-	Token         string `form:"token" binding:"required"`
-	TokenTypeHint string `form:"token_type_hint"`
+	Token         string `form:"token" binding:"required"` // The token to revoke
+	TokenTypeHint string `form:"token_type_hint"`          // Optional hint: access_token or refresh_token
+}
+
+// IntrospectRequest represents an OAuth 2.0 token introspection request (RFC 7662).
+type IntrospectRequest struct {
+	Token         string `form:"token" binding:"required"` // The token to introspect
+	TokenTypeHint string `form:"token_type_hint"`          // Optional hint: access_token or refresh_token
+}
+
+// IntrospectionResponse represents an OAuth 2.0 token introspection response (RFC 7662 Section 2.2).
+// Inactive tokens are reported with only the active field set.
+type IntrospectionResponse struct {
+	Active    bool                  `json:"active"`               // Whether the token is currently active
+	Scope     string                `json:"scope,omitempty"`      // Space-separated list of scopes
+	ClientID  string                `json:"client_id,omitempty"`  // Client the token was issued to
+	Username  string                `json:"username,omitempty"`   // Username of the resource owner
+	TokenType string                `json:"token_type,omitempty"` // Type of the token
+	Exp       int64                 `json:"exp,omitempty"`        // Expiration time as a Unix timestamp
+	Iat       int64                 `json:"iat,omitempty"`        // Issue time as a Unix timestamp
+	Sub       string                `json:"sub,omitempty"`        // Subject (user ID) of the token
+	Aud       interface{}           `json:"aud,omitempty"`        // Audience, a string or an array as in the token's aud claim
+	Cnf       *jwtutil.Confirmation `json:"cnf,omitempty"`        // Certificate or DPoP key the token is bound to (RFC 8705 Section 3.2, RFC 9449 Section 6.2)
+	AuthTime  int64                 `json:"auth_time,omitempty"`  // When the user authenticated as a Unix timestamp (RFC 9470 Section 6.2)
+	ACR       string                `json:"acr,omitempty"`        // Authentication context class the user authentication met (RFC 9470 Section 6.2)
 }
 
-type UserInfoResponse struct {
-	Sub               string `json:"sub"`
-	Name              string `json:"name,omitempty"`
-	Email             string `json:"email,omitempty"`
-	EmailVerified     bool   `json:"email_verified,omitempty"`
-	PreferredUsername string `json:"preferred_username,omitempty"`
+// UserInfoResponse holds the claims returned by the OpenID Connect UserInfo endpoint.
+// It always contains "sub" plus the claims released by the token's scopes.
+type UserInfoResponse map[string]interface{}
+
+// DeviceAuthorizationRequest represents a device authorization request (RFC 8628 Section 3.1).
+type DeviceAuthorizationRequest struct {
+	ClientID string `form:"client_id"` // OAuth client identifier
+	Scope    string `form:"scope"`     // Requested permission scopes
+}
+
+// DeviceAuthorizationResponse is returned to the device after a successful
+// device authorization request (RFC 8628 Section 3.2).
+type DeviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`               // Code the device uses to poll the token endpoint
+	UserCode                string `json:"user_code"`                 // Code the user enters on the verification page
+	VerificationURI         string `json:"verification_uri"`          // Page where the user enters the user code
+	VerificationURIComplete string `json:"verification_uri_complete"` // Verification page with the user code prefilled
+	ExpiresIn               int    `json:"expires_in"`                // Lifetime of the codes in seconds
+	Interval                int    `json:"interval"`                  // Minimum seconds between polls
+}
+
+// DeviceVerificationRequest represents the user's decision on the device verification page.
+type DeviceVerificationRequest struct {
+	UserCode string `json:"user_code" binding:"required"` // User code shown on the device
+	Approve  bool   `json:"approve"`                      // Whether the user approves the request
+}
+
+// DeviceVerificationPageData contains the details shown on the device verification page.
+// Only UserCode is set when no code has been entered yet.
+type DeviceVerificationPageData struct {
+	UserCode   string   `json:"user_code"`             // Normalized user code being verified
+	ClientName string   `json:"client_name,omitempty"` // Name of the requesting client
+	ClientID   string   `json:"client_id,omitempty"`   // Identifier of the requesting client
+	ScopeList  []string `json:"scope_list,omitempty"`  // Requested scopes
 }
 
 type ErrorResponse struct {
 	Error            string `json:"error"`
 	ErrorDescription string `json:"error_description,omitempty"`
 	ErrorURI         string `json:"error_uri,omitempty"`
+	RequestID        string `json:"request_id,omitempty"` // Correlation ID for tracing the request in server logs
 }
 
+// ConsentPageData contains the information shown on the consent screen.
+// Only scopes the user has not granted to the client before are listed for approval,
+// unless the consent screen was forced with prompt=consent.
 type ConsentPageData struct {
-	ClientName     string   `json:"client_name"`
-	ClientID       string   `json:"client_id"`
-	RequestedScope string   `json:"requested_scope"`
-	ScopeList      []string `json:"scope_list"`
-	State          string   `json:"state"`
+	ClientName      string         `json:"client_name"`
+	ClientID        string         `json:"client_id"`
+	RequestedScope  string         `json:"requested_scope"`
+	ScopeList       []string       `json:"scope_list"`       // Names of the scopes awaiting approval
+	Scopes          []ConsentScope `json:"scopes"`           // Scopes awaiting approval with their descriptions
+	EffectiveScopes []ConsentScope `json:"effective_scopes"` // Scopes awaiting approval and every scope they imply
+	GrantedScopes   []string       `json:"granted_scopes"`   // Requested scopes the user has already granted
+	OfflineAccess   bool           `json:"offline_access"`   // Whether offline_access awaits approval, granting access while the user is logged out
+	State           string         `json:"state"`
+}
+
+// ConsentScope describes a scope awaiting the user's approval on the consent screen.
+type ConsentScope struct {
+	Name        string `json:"name"`        // Scope identifier
+	Description string `json:"description"` // Human-readable description of the permission
+	Required    bool   `json:"required"`    // Whether the scope cannot be declined individually
+}
+
+// ConsentRequest represents the user's decision on the consent screen about the request
+// held under the request_uri query parameter. ApprovedScopes lists the scopes awaiting
+// approval that the user accepted; when omitted, all requested scopes are approved.
+type ConsentRequest struct {
+	ClientID       string   `json:"client_id" binding:"required"`
+	Consent        bool     `json:"consent"`
+	ApprovedScopes []string `json:"approved_scopes"`
+}
+
+// EndSessionRequest represents an RP-initiated logout request (OpenID Connect RP-Initiated Logout Section 2).
+type EndSessionRequest struct {
+	IDTokenHint           string `form:"id_token_hint"`            // ID token previously issued to the client, possibly expired
+	PostLogoutRedirectURI string `form:"post_logout_redirect_uri"` // Where to send the user afterwards, must be registered for the client
+	State                 string `form:"state"`                    // Opaque value echoed back on the post-logout redirect
+}
+
+// EndSessionPageData contains what the logout page needs once the session has ended.
+type EndSessionPageData struct {
+	FrontchannelLogoutURIs []string // Client pages loaded in iframes to clear their sessions
+	RedirectURI            string   // Post-logout redirect with the state, empty to stay on the page
+	Nonce                  string   // CSP nonce of the page's inline script, set when it is rendered
+}
+
+// ServerMetadata represents the authorization server metadata (RFC 8414 Section 2),
+// extended with the OpenID Provider metadata (OpenID Connect Discovery Section 3).
+// Endpoints and values of disabled features are omitted.
+type ServerMetadata struct {
+	Issuer                                     string   `json:"issuer"`
+	AuthorizationEndpoint                      string   `json:"authorization_endpoint,omitempty"`
+	TokenEndpoint                              string   `json:"token_endpoint"`
+	UserInfoEndpoint                           string   `json:"userinfo_endpoint"`
+	JWKSURI                                    string   `json:"jwks_uri"`
+	RegistrationEndpoint                       string   `json:"registration_endpoint"`
+	RevocationEndpoint                         string   `json:"revocation_endpoint"`
+	IntrospectionEndpoint                      string   `json:"introspection_endpoint"`
+	DeviceAuthorizationEndpoint                string   `json:"device_authorization_endpoint,omitempty"`
+	EndSessionEndpoint                         string   `json:"end_session_endpoint"`
+	ScopesSupported                            []string `json:"scopes_supported"`
+	ResponseTypesSupported                     []string `json:"response_types_supported"`
+	ResponseModesSupported                     []string `json:"response_modes_supported,omitempty"`
+	GrantTypesSupported                        []string `json:"grant_types_supported"`
+	PromptValuesSupported                      []string `json:"prompt_values_supported,omitempty"`
+	CodeChallengeMethodsSupported              []string `json:"code_challenge_methods_supported,omitempty"`
+	TokenEndpointAuthMethodsSupported          []string `json:"token_endpoint_auth_methods_supported"`
+	TokenEndpointAuthSigningAlgValuesSupported []string `json:"token_endpoint_auth_signing_alg_values_supported"`
+	RevocationEndpointAuthMethodsSupported     []string `json:"revocation_endpoint_auth_methods_supported"`
+	IntrospectionEndpointAuthMethodsSupported  []string `json:"introspection_endpoint_auth_methods_supported"`
+	SubjectTypesSupported                      []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported           []string `json:"id_token_signing_alg_values_supported"`
+	IDTokenEncryptionAlgValuesSupported        []string `json:"id_token_encryption_alg_values_supported"`
+	IDTokenEncryptionEncValuesSupported        []string `json:"id_token_encryption_enc_values_supported"`
+	UserInfoSigningAlgValuesSupported          []string `json:"userinfo_signing_alg_values_supported"`
+	UserInfoEncryptionAlgValuesSupported       []string `json:"userinfo_encryption_alg_values_supported"`
+	UserInfoEncryptionEncValuesSupported       []string `json:"userinfo_encryption_enc_values_supported"`
+	BackchannelLogoutSupported                 bool     `json:"backchannel_logout_supported"`
+	BackchannelLogoutSessionSupported          bool     `json:"backchannel_logout_session_supported"`
+	FrontchannelLogoutSupported                bool     `json:"frontchannel_logout_supported"`
+	FrontchannelLogoutSessionSupported         bool     `json:"frontchannel_logout_session_supported"`
+	PushedAuthorizationRequestEndpoint         string   `json:"pushed_authorization_request_endpoint,omitempty"`
+	RequirePushedAuthorizationRequests         bool     `json:"require_pushed_authorization_requests"`
+	RequestParameterSupported                  bool     `json:"request_parameter_supported"`
+	RequestURIParameterSupported               bool     `json:"request_uri_parameter_supported"`
+	RequireRequestURIRegistration              bool     `json:"require_request_uri_registration"`
+	RequestObjectSigningAlgValuesSupported     []string `json:"request_object_signing_alg_values_supported,omitempty"`
+	ACRValuesSupported                         []string `json:"acr_values_supported,omitempty"`
+	ClaimsParameterSupported                   bool     `json:"claims_parameter_supported"`
+	TLSClientCertificateBoundAccessTokens      bool     `json:"tls_client_certificate_bound_access_tokens"`
+	DPoPSigningAlgValuesSupported              []string `json:"dpop_signing_alg_values_supported,omitempty"`
+	GrantManagementEndpoint                    string   `json:"grant_management_endpoint,omitempty"`
+	GrantManagementActionsSupported            []string `json:"grant_management_actions_supported,omitempty"`
+	GrantManagementActionRequired              bool     `json:"grant_management_action_required"`
+}
+
+// AuthorizationResponse describes an application the user has authorized.
+type AuthorizationResponse struct {
+	ClientID   string     `json:"client_id"`              // OAuth client identifier
+	ClientName string     `json:"client_name"`            // Human-readable name of the client
+	ClientURI  string     `json:"client_uri,omitempty"`   // URI of the client's homepage
+	LogoURI    string     `json:"logo_uri,omitempty"`     // URI of the client's logo
+	Scope      string     `json:"scope"`                  // Space-separated list of granted scopes
+	GrantedAt  time.Time  `json:"granted_at"`             // When the user first authorized the client
+	UpdatedAt  time.Time  `json:"updated_at"`             // When the granted scopes last changed
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"` // When tokens were last issued to the client, omitted if never
+}
+
+// GrantResponse describes a grant to the client managing it (FAPI Grant Management Section 6).
+type GrantResponse struct {
+	Scopes    []GrantScope `json:"scopes"`           // Granted scopes and the resources they were granted for
+	Claims    []string     `json:"claims,omitempty"` // Claims granted through the claims parameter
+	CreatedAt time.Time    `json:"created_at"`       // When the grant was created
+	UpdatedAt time.Time    `json:"updated_at"`       // When the grant was last updated or replaced
+}
+
+// GrantScope is an entry of the scopes of a grant.
+type GrantScope struct {
+	Scope     string   `json:"scope"`               // Space-separated list of granted scopes
+	Resources []string `json:"resources,omitempty"` // Resource indicators the scopes were granted for (RFC 8707)
 }
@@ -0,0 +1,259 @@
+package oauth
+
+import (
+	"context"
+	"html/template"
+	"net/url"
+	"strconv"
+
+	"github.com/verigate/verigate-server/internal/app/client"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+	jwtutil "github.com/verigate/verigate-server/internal/pkg/utils/jwt"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// endSessionPage is the page rendered once the session has ended. It loads each client's
+// front-channel logout URI in a hidden iframe and, after they have loaded, follows the
+// post-logout redirect when there is one. Its script runs with the CSP nonce of the response,
+// and the iframes are hidden without inline styles, which the policy does not allow.
+var endSessionPage = template.Must(template.New("end_session").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Logged out</title>
+</head>
+<body>
+<p>You have been logged out.</p>
+{{range .FrontchannelLogoutURIs}}<iframe src="{{.}}" hidden aria-hidden="true"></iframe>
+{{end}}{{if .RedirectURI}}<p><a href="{{.RedirectURI}}">Continue</a></p>
+<script nonce="{{.Nonce}}">
+window.addEventListener("load", function () { window.location.replace({{.RedirectURI}}); });
+</script>
+{{end}}</body>
+</html>
+`))
+
+// What an RP-initiated logout ends
+const (
+	EndSessionScopeUser    = "user"    // Every session of the user
+	EndSessionScopeSession = "session" // Only the session the logout names
+)
+
+// idTokenHintClaims holds the claims read from an id_token_hint.
+type idTokenHintClaims struct {
+	SessionID       string `json:"sid,omitempty"`
+	AuthorizedParty string `json:"azp,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// clientID returns the client the ID token was issued to: the authorized party when the token
+// names one, else its only audience (OpenID Connect Core Section 2).
+func (h *idTokenHintClaims) clientID() string {
+	if h.AuthorizedParty != "" {
+		return h.AuthorizedParty
+	}
+	return h.Audience[0]
+}
+
+// EndSession ends the user's session for an RP-initiated logout.
+// The user is the one of the current web session or, without one, the one the id_token_hint was issued for.
+// A post_logout_redirect_uri must be registered for the client the id_token_hint was issued to.
+// Clients with front-channel logout are returned to be loaded in iframes; the user's refresh tokens
+// are revoked, which also notifies clients with back-channel logout.
+// Scoped to the session, the logout ends only the session named by the hint's sid or else the
+// current web session, sessionSID, and involves only the clients that took part in it.
+func (s *Service) EndSession(ctx context.Context, req EndSessionRequest, sessionUserID uint, sessionSID string) (*EndSessionPageData, error) {
+	userID := sessionUserID
+	sid := sessionSID
+	var clientID string
+
+	if req.IDTokenHint != "" {
+		hint, err := s.parseIDTokenHint(ctx, req.IDTokenHint)
+		if err != nil {
+			return nil, errors.BadRequest(errors.ErrMsgInvalidIDTokenHint)
+		}
+		hintUserID, err := s.resolveHintUser(ctx, hint, sessionUserID)
+		if err != nil {
+			return nil, err
+		}
+		userID = hintUserID
+		clientID = hint.clientID()
+		if hint.SessionID != "" {
+			sid = hint.SessionID
+		}
+	}
+
+	data := &EndSessionPageData{FrontchannelLogoutURIs: []string{}}
+
+	if req.PostLogoutRedirectURI != "" {
+		if clientID == "" {
+			return nil, errors.BadRequest(errors.ErrMsgPostLogoutRedirectURINotAllowed)
+		}
+		c, err := s.clientService.GetByClientID(ctx, clientID)
+		if err != nil {
+			return nil, err
+		}
+		if c == nil || !c.AllowsPostLogoutRedirectURI(req.PostLogoutRedirectURI) {
+			return nil, errors.BadRequest(errors.ErrMsgPostLogoutRedirectURINotAllowed)
+		}
+		data.RedirectURI = buildPostLogoutRedirect(req.PostLogoutRedirectURI, req.State)
+	}
+
+	// Without a session or hint there is no one to log out
+	if userID == 0 {
+		return data, nil
+	}
+
+	if s.logoutScope == EndSessionScopeSession && sid != "" {
+		// Collect the front-channel pages before the logout forgets the session
+		uris, err := s.logoutService.FrontchannelSessionLogoutURIs(ctx, userID, sid)
+		if err != nil {
+			return nil, err
+		}
+		data.FrontchannelLogoutURIs = uris
+
+		if err := s.userService.LogoutSession(ctx, userID, sid); err != nil {
+			return nil, err
+		}
+		return data, nil
+	}
+
+	// Collect the front-channel pages before the logout forgets the sessions
+	uris, err := s.logoutService.FrontchannelLogoutURIs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	data.FrontchannelLogoutURIs = uris
+
+	if err := s.userService.Logout(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// parseIDTokenHint verifies an ID token issued by this server and returns its claims.
+// The time-based claims are not checked because an expired ID token still identifies the
+// session it was issued in (RP-Initiated Logout Section 2); only the signature and issuer are.
+// Other tokens this server signs, such as access and logout tokens, are told apart by their
+// typ header and rejected. A token with several audiences must name the client it was
+// issued to as its authorized party, which must be one of the audiences.
+// The signature is checked with the algorithm the client registered for its ID tokens.
+func (s *Service) parseIDTokenHint(ctx context.Context, hint string) (*idTokenHintClaims, error) {
+	// The client, and with it the signing key, is read from the claims before they are verified
+	var unverified idTokenHintClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(hint, &unverified); err != nil {
+		return nil, errors.BadRequest(errors.ErrMsgInvalidIDTokenHint)
+	}
+	if len(unverified.Audience) == 0 {
+		return nil, errors.BadRequest(errors.ErrMsgInvalidIDTokenHint)
+	}
+	c, err := s.clientService.GetByClientID(ctx, unverified.clientID())
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, errors.BadRequest(errors.ErrMsgInvalidIDTokenHint)
+	}
+	signer, err := s.clientService.IDTokenSigner(c)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims idTokenHintClaims
+	token, err := signer.ParseIgnoringTime(ctx, hint, &claims)
+	if err != nil {
+		return nil, err
+	}
+	if typ, _ := token.Header[jwtutil.HeaderType].(string); typ != "" && typ != "JWT" {
+		return nil, errors.BadRequest(errors.ErrMsgInvalidIDTokenHint)
+	}
+	if claims.Issuer != jwtutil.Issuer(ctx) || len(claims.Audience) == 0 || claims.Subject == "" {
+		return nil, errors.BadRequest(errors.ErrMsgInvalidIDTokenHint)
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty == "" {
+		return nil, errors.BadRequest(errors.ErrMsgInvalidIDTokenHint)
+	}
+	if claims.AuthorizedParty != "" && !claims.VerifyAudience(claims.AuthorizedParty, true) {
+		return nil, errors.BadRequest(errors.ErrMsgInvalidIDTokenHint)
+	}
+
+	return &claims, nil
+}
+
+// resolveHintUser returns the local user an id_token_hint was issued for.
+// With a web session, the hint's subject must be the session user's subject at the client.
+// Otherwise a public subject is the user ID itself, while a pairwise subject is resolved
+// through the client session named by the hint's sid; zero is returned when it is unknown.
+func (s *Service) resolveHintUser(ctx context.Context, hint *idTokenHintClaims, sessionUserID uint) (uint, error) {
+	c, err := s.clientService.GetByClientID(ctx, hint.clientID())
+	if err != nil {
+		return 0, err
+	}
+	if c == nil {
+		return 0, errors.BadRequest(errors.ErrMsgInvalidIDTokenHint)
+	}
+
+	if sessionUserID != 0 {
+		if c.Subject(sessionUserID) != hint.Subject {
+			return 0, errors.BadRequest(errors.ErrMsgIDTokenHintUserMismatch)
+		}
+		return sessionUserID, nil
+	}
+
+	if c.SubjectType != client.SubjectTypePairwise {
+		userID, err := strconv.ParseUint(hint.Subject, 10, 64)
+		if err != nil || userID == 0 {
+			return 0, errors.BadRequest(errors.ErrMsgInvalidIDTokenHint)
+		}
+		return uint(userID), nil
+	}
+
+	userID, err := s.logoutService.SessionUser(ctx, c.ClientID, hint.SessionID)
+	if err != nil {
+		return 0, err
+	}
+	if userID != 0 && c.Subject(userID) != hint.Subject {
+		return 0, errors.BadRequest(errors.ErrMsgIDTokenHintUserMismatch)
+	}
+	return userID, nil
+}
+
+// buildPostLogoutRedirect appends the state, when present, to a post-logout redirect URI.
+func buildPostLogoutRedirect(redirectURI, state string) string {
+	if state == "" {
+		return redirectURI
+	}
+
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+	q := u.Query()
+	q.Set("state", state)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// cspSources returns the Content-Security-Policy sources allowing the URIs: their origins, or
+// their schemes for URIs without a host, such as the private-use schemes of native apps.
+func cspSources(uris ...string) []string {
+	seen := make(map[string]bool, len(uris))
+	sources := make([]string, 0, len(uris))
+	for _, uri := range uris {
+		u, err := url.Parse(uri)
+		if err != nil || u.Scheme == "" {
+			continue
+		}
+		source := u.Scheme + ":"
+		if u.Host != "" {
+			source = u.Scheme + "://" + u.Host
+		}
+		if !seen[source] {
+			seen[source] = true
+			sources = append(sources, source)
+		}
+	}
+	return sources
+}
@@ -0,0 +1,156 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	jwtutil "github.com/verigate/verigate-server/internal/pkg/utils/jwt"
+	"github.com/verigate/verigate-server/internal/pkg/utils/jwt/jwttest"
+)
+
+// hintClaims returns the claims of an ID token this server issued to the client for user 1.
+func hintClaims(ctx context.Context, clientID string) *idTokenHintClaims {
+	now := time.Now()
+	return &idTokenHintClaims{
+		SessionID: "sid-1",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    jwtutil.Issuer(ctx),
+			Subject:   "1",
+			Audience:  jwt.ClaimStrings{clientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		},
+	}
+}
+
+// signHint signs the claims with the server's RS256 key and the given typ header.
+func signHint(t *testing.T, ctx context.Context, claims *idTokenHintClaims, typ string) string {
+	t.Helper()
+	hint, err := jwtutil.DefaultSigner().Sign(ctx, claims, typ)
+	if err != nil {
+		t.Fatalf("failed to sign hint: %v", err)
+	}
+	return hint
+}
+
+func TestParseIDTokenHint(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	s.addClient(t, "client-a", "https://app.example.com/callback")
+
+	// foreignKey impersonates the server's key ID with a key the server never held
+	foreignKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		hint   func(t *testing.T) string
+		wantOK bool
+	}{
+		{"valid", func(t *testing.T) string {
+			return signHint(t, ctx, hintClaims(ctx, "client-a"), "JWT")
+		}, true},
+		{"expired", func(t *testing.T) string {
+			claims := hintClaims(ctx, "client-a")
+			claims.IssuedAt = jwt.NewNumericDate(time.Now().Add(-2 * time.Hour))
+			claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+			return signHint(t, ctx, claims, "JWT")
+		}, true},
+		{"several audiences with authorized party", func(t *testing.T) string {
+			claims := hintClaims(ctx, "client-a")
+			claims.Audience = jwt.ClaimStrings{"client-a", "https://api.example.com"}
+			claims.AuthorizedParty = "client-a"
+			return signHint(t, ctx, claims, "JWT")
+		}, true},
+
+		{"tampered payload", func(t *testing.T) string {
+			parts := strings.Split(signHint(t, ctx, hintClaims(ctx, "client-a"), "JWT"), ".")
+			claims := hintClaims(ctx, "client-a")
+			claims.Subject = "2"
+			payload, _ := json.Marshal(claims)
+			parts[1] = base64.RawURLEncoding.EncodeToString(payload)
+			return strings.Join(parts, ".")
+		}, false},
+		{"tampered signature", func(t *testing.T) string {
+			hint := signHint(t, ctx, hintClaims(ctx, "client-a"), "JWT")
+			last := hint[len(hint)-2]
+			replacement := byte('A')
+			if last == replacement {
+				replacement = 'B'
+			}
+			return hint[:len(hint)-2] + string(replacement) + hint[len(hint)-1:]
+		}, false},
+		{"foreign issuer", func(t *testing.T) string {
+			claims := hintClaims(ctx, "client-a")
+			claims.Issuer = "https://idp.example.com"
+			return signHint(t, ctx, claims, "JWT")
+		}, false},
+		{"foreign key", func(t *testing.T) string {
+			token := jwt.NewWithClaims(jwt.SigningMethodRS256, hintClaims(ctx, "client-a"))
+			token.Header[jwtutil.HeaderType] = "JWT"
+			token.Header["kid"] = jwttest.RSAKeyID
+			hint, err := token.SignedString(foreignKey)
+			if err != nil {
+				t.Fatalf("failed to sign hint: %v", err)
+			}
+			return hint
+		}, false},
+		{"unsigned", func(t *testing.T) string {
+			hint, err := jwt.NewWithClaims(jwt.SigningMethodNone, hintClaims(ctx, "client-a")).SignedString(jwt.UnsafeAllowNoneSignatureType)
+			if err != nil {
+				t.Fatalf("failed to build hint: %v", err)
+			}
+			return hint
+		}, false},
+		{"access token", func(t *testing.T) string {
+			return signHint(t, ctx, hintClaims(ctx, "client-a"), "at+jwt")
+		}, false},
+		{"unknown client", func(t *testing.T) string {
+			return signHint(t, ctx, hintClaims(ctx, "client-unknown"), "JWT")
+		}, false},
+		{"several audiences without authorized party", func(t *testing.T) string {
+			claims := hintClaims(ctx, "client-a")
+			claims.Audience = jwt.ClaimStrings{"client-a", "https://api.example.com"}
+			return signHint(t, ctx, claims, "JWT")
+		}, false},
+		{"authorized party outside audiences", func(t *testing.T) string {
+			claims := hintClaims(ctx, "client-a")
+			claims.Audience = jwt.ClaimStrings{"https://api.example.com", "https://other.example.com"}
+			claims.AuthorizedParty = "client-a"
+			return signHint(t, ctx, claims, "JWT")
+		}, false},
+		{"without subject", func(t *testing.T) string {
+			claims := hintClaims(ctx, "client-a")
+			claims.Subject = ""
+			return signHint(t, ctx, claims, "JWT")
+		}, false},
+		{"malformed", func(t *testing.T) string { return "not-a-jwt" }, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := s.parseIDTokenHint(ctx, tt.hint(t))
+			if !tt.wantOK {
+				if err == nil {
+					t.Errorf("got claims %+v, want the hint rejected", claims)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got error %v, want the hint accepted", err)
+			}
+			if claims.Subject != "1" || claims.clientID() != "client-a" || claims.SessionID != "sid-1" {
+				t.Errorf("got subject %q, client %q and sid %q, want 1, client-a and sid-1", claims.Subject, claims.clientID(), claims.SessionID)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package oauth
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/verigate/verigate-server/internal/app/client"
+	"github.com/verigate/verigate-server/internal/pkg/config"
+	"github.com/verigate/verigate-server/internal/pkg/middleware"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+	jwtutil "github.com/verigate/verigate-server/internal/pkg/utils/jwt"
+	"github.com/verigate/verigate-server/internal/pkg/utils/locale"
+)
+
+// Endpoints report errors over one of two transports. The authorization endpoint redirects
+// back to the client once the redirect URI is known to be registered (RFC 6749 Section 4.1.2.1)
+// and renders an error page to the user before that, so an unverified URI is never followed.
+// Endpoints the client calls directly, such as the token endpoint, answer with a JSON body
+// (RFC 6749 Section 5.2) whose HTTP status follows from the error code. Descriptions sent to
+// clients stay untranslated, since RFC 6749 limits error_description to ASCII; only the error
+// page shown to the user is localized.
+
+// errorStatuses maps error codes to the HTTP status of a JSON error response.
+// Codes not listed are sent with 400 Bad Request.
+var errorStatuses = map[string]int{
+	errors.ErrMsgInvalidClient:          http.StatusUnauthorized,
+	errors.ErrMsgServerError:            http.StatusInternalServerError,
+	errors.ErrMsgTemporarilyUnavailable: http.StatusServiceUnavailable,
+}
+
+// authorizationErrorCodes are the error codes the authorization endpoint reports as they are;
+// other client errors are reported as invalid_request described by their message
+// (RFC 6749 Section 4.1.2.1, OpenID Connect Core Section 3.1.2.6, RFC 8707, RFC 9101).
+var authorizationErrorCodes = []string{
+	errors.ErrMsgInvalidRequest,
+	errors.ErrMsgUnauthorizedClient,
+	errors.ErrMsgAccessDenied,
+	errors.ErrMsgUnsupportedResponseType,
+	errors.ErrMsgInvalidScope,
+	errors.ErrMsgInvalidTarget,
+	errors.ErrMsgLoginRequired,
+	errors.ErrMsgConsentRequired,
+	errors.ErrMsgUnmetAuthRequirements,
+	errors.ErrMsgInvalidRequestObject,
+	errors.ErrMsgInvalidRequestURI,
+}
+
+// pushedRequestErrorCodes are the error codes the pushed authorization request endpoint
+// reports as they are (RFC 9126 Section 2.3)
+var pushedRequestErrorCodes = []string{
+	errors.ErrMsgInvalidRequest,
+	errors.ErrMsgInvalidClient,
+	errors.ErrMsgUnauthorizedClient,
+	errors.ErrMsgInvalidScope,
+	errors.ErrMsgInvalidTarget,
+	errors.ErrMsgUnsupportedResponseType,
+	errors.ErrMsgInvalidRedirectUri,
+	errors.ErrMsgInvalidRequestObject,
+	errors.ErrMsgInvalidRequestURI,
+}
+
+// tokenErrorCodes are the error codes the token and device authorization endpoints
+// report as they are (RFC 6749 Section 5.2, RFC 8628 Section 3.5, RFC 8707, RFC 9449 Section 5)
+var tokenErrorCodes = []string{
+	errors.ErrMsgInvalidRequest,
+	errors.ErrMsgInvalidClient,
+	errors.ErrMsgUnauthorizedClient,
+	errors.ErrMsgUnsupportedGrantType,
+	errors.ErrMsgInvalidScope,
+	errors.ErrMsgInvalidTarget,
+	errors.ErrMsgAccessDenied,
+	errors.ErrMsgAuthorizationPending,
+	errors.ErrMsgSlowDown,
+	errors.ErrMsgExpiredToken,
+	errors.ErrMsgInvalidGrantID,
+	errors.ErrMsgInvalidDPoPProof,
+	errors.ErrMsgUseDPoPNonce,
+}
+
+// authorizationErrorPage is rendered when an authorization request fails before its redirect URI
+// has been validated. It shows the error to the user and offers no way back to the client.
+var authorizationErrorPage = template.Must(template.New("authorization_error").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Authorization error</title>
+</head>
+<body>
+<h1>The authorization request could not be processed</h1>
+<p>Error: {{.Error}}</p>
+{{if .ErrorDescription}}<p>{{.ErrorDescription}}</p>
+{{end}}{{if .RequestID}}<p>Request ID: {{.RequestID}}</p>
+{{end}}</body>
+</html>
+`))
+
+// errorStatus returns the HTTP status of a JSON error response with the given code.
+func errorStatus(code string) int {
+	if status, ok := errorStatuses[code]; ok {
+		return status
+	}
+	return http.StatusBadRequest
+}
+
+// writeError writes an OAuth error response tagged with the request's correlation ID.
+func writeError(c *gin.Context, status int, resp ErrorResponse) {
+	resp.RequestID = middleware.GetRequestID(c)
+	resp.ErrorDescription = sanitizeErrorDescription(resp.ErrorDescription)
+	c.JSON(status, resp)
+}
+
+// writeOAuthError writes a JSON error response with the HTTP status that belongs to the error code.
+func writeOAuthError(c *gin.Context, resp ErrorResponse) {
+	writeError(c, errorStatus(resp.Error), resp)
+}
+
+// serviceError maps a service error to an error response. Server failures become server_error
+// or temporarily_unavailable without revealing their cause. Client errors whose message is one of
+// codes keep it, described by their details; the others are reported with fallback as the code
+// and their message as the description.
+func serviceError(err error, codes []string, fallback string) ErrorResponse {
+	customErr, ok := errors.As(err)
+	if !ok || customErr.Status >= http.StatusInternalServerError {
+		if ok && customErr.Status == http.StatusServiceUnavailable {
+			return ErrorResponse{Error: errors.ErrMsgTemporarilyUnavailable, ErrorDescription: "service temporarily unavailable"}
+		}
+		return ErrorResponse{Error: errors.ErrMsgServerError, ErrorDescription: "internal server error"}
+	}
+
+	if containsScope(codes, customErr.Message) {
+		description, _ := customErr.Details.(string)
+		return ErrorResponse{Error: customErr.Message, ErrorDescription: description}
+	}
+	return ErrorResponse{Error: fallback, ErrorDescription: customErr.Message}
+}
+
+// authorizationError maps an error of the authorization endpoint to an error response.
+func authorizationError(err error) ErrorResponse {
+	return serviceError(err, authorizationErrorCodes, errors.ErrMsgInvalidRequest)
+}
+
+// pushedRequestError maps a validation error of a pushed authorization request to an error response.
+func pushedRequestError(err error) ErrorResponse {
+	return serviceError(err, pushedRequestErrorCodes, errors.ErrMsgInvalidRequest)
+}
+
+// tokenError maps an error of the token or device authorization endpoint to an error response.
+// A grant that cannot be used for any other reason is invalid_grant.
+func tokenError(err error) ErrorResponse {
+	return serviceError(err, tokenErrorCodes, errors.ErrMsgInvalidGrant)
+}
+
+// invalidClient writes the standard invalid_client error response for failed client authentication.
+// When the client attempted HTTP Basic authentication, the response carries a
+// WWW-Authenticate challenge as required by RFC 6749 Section 5.2.
+func (h *Handler) invalidClient(c *gin.Context, method string) {
+	if method == client.AuthMethodClientSecretBasic {
+		c.Header("WWW-Authenticate", `Basic realm="`+jwtutil.Issuer(c.Request.Context())+`"`)
+	}
+	writeOAuthError(c, ErrorResponse{
+		Error:            errors.ErrMsgInvalidClient,
+		ErrorDescription: "Client authentication failed",
+	})
+}
+
+// renderAuthorizationError shows an authorization error to the user instead of redirecting,
+// for failures before the redirect URI has been validated. The description is translated into
+// the language the user prefers; the error code is not.
+func renderAuthorizationError(c *gin.Context, resp ErrorResponse) {
+	resp.RequestID = middleware.GetRequestID(c)
+	preferred := locale.Preferred(c.Query("ui_locales"), c.GetHeader("Accept-Language"))
+	resp.ErrorDescription = errors.Localize(sanitizeErrorDescription(resp.ErrorDescription), preferred, config.AppConfig.DefaultLocale)
+
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Header("Cache-Control", "no-store")
+	c.Status(errorStatus(resp.Error))
+	if err := authorizationErrorPage.Execute(c.Writer, resp); err != nil {
+		c.Error(errors.Internal(errors.ErrMsgFailedToRenderPage).Wrap(err))
+	}
+}
+
+// redirectError sends an authorization error back to the client's validated redirect URI
+// in the response mode of the request.
+func (h *Handler) redirectError(c *gin.Context, redirectURI, mode, state string, resp ErrorResponse) {
+	authorizationResponse{
+		RedirectURI: redirectURI,
+		Mode:        mode,
+		Params:      errorParams(state, resp.Error, resp.ErrorDescription),
+	}.send(c)
+}
+
+// appendQuery adds the encoded params to the query of uri, keeping its existing query parameters.
+func appendQuery(uri string, params url.Values) string {
+	separator := "?"
+	if strings.Contains(uri, "?") {
+		separator = "&"
+	}
+	return uri + separator + params.Encode()
+}
+
+// sanitizeErrorDescription reduces an error description to the characters RFC 6749 Section 5.2
+// allows in error_description: printable ASCII other than the double quote and backslash.
+// Any other character, including a line break, becomes a space, and runs of spaces collapse into one.
+func sanitizeErrorDescription(description string) string {
+	var b strings.Builder
+	pendingSpace := false
+	for _, r := range description {
+		if r <= ' ' || r > '~' || r == '"' || r == '\\' {
+			pendingSpace = b.Len() > 0
+			continue
+		}
+		if pendingSpace {
+			b.WriteByte(' ')
+			pendingSpace = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,139 @@
+package oauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+)
+
+// Transports of an error response
+const (
+	transportRedirect = "redirect" // Redirected to the client's validated redirect URI
+	transportPage     = "page"     // Rendered to the user before the redirect URI is validated
+	transportJSON     = "json"     // Answered to the client calling the endpoint directly
+)
+
+// errorRecorder runs write in a gin context and returns the recorded response.
+func errorRecorder(write func(c *gin.Context)) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	write(c)
+	return recorder
+}
+
+func TestErrorResponses(t *testing.T) {
+	const (
+		redirectURI = "https://app.example.com/callback"
+		state       = "xyz"
+	)
+
+	tests := []struct {
+		name       string
+		transport  string
+		mapError   func(error) ErrorResponse
+		err        error
+		wantCode   string
+		wantStatus int
+	}{
+		// Authorization endpoint, after the redirect URI is validated
+		{"authorization invalid_request", transportRedirect, authorizationError, errors.BadRequest(errors.ErrMsgInvalidRequest), "invalid_request", http.StatusFound},
+		{"authorization unauthorized_client", transportRedirect, authorizationError, errors.BadRequest(errors.ErrMsgUnauthorizedClient), "unauthorized_client", http.StatusFound},
+		{"authorization access_denied", transportRedirect, authorizationError, errors.Forbidden(errors.ErrMsgAccessDenied), "access_denied", http.StatusFound},
+		{"authorization unsupported_response_type", transportRedirect, authorizationError, errors.BadRequest(errors.ErrMsgUnsupportedResponseType), "unsupported_response_type", http.StatusFound},
+		{"authorization invalid_scope", transportRedirect, authorizationError, errors.BadRequest(errors.ErrMsgInvalidScope), "invalid_scope", http.StatusFound},
+		{"authorization invalid_target", transportRedirect, authorizationError, errors.BadRequest(errors.ErrMsgInvalidTarget), "invalid_target", http.StatusFound},
+		{"authorization login_required", transportRedirect, authorizationError, errors.BadRequest(errors.ErrMsgLoginRequired), "login_required", http.StatusFound},
+		{"authorization consent_required", transportRedirect, authorizationError, errors.BadRequest(errors.ErrMsgConsentRequired), "consent_required", http.StatusFound},
+		{"authorization invalid_request_uri", transportRedirect, authorizationError, errors.BadRequest(errors.ErrMsgInvalidRequestURI), "invalid_request_uri", http.StatusFound},
+		{"authorization other client error", transportRedirect, authorizationError, errors.BadRequest(errors.ErrMsgInvalidGrant), "invalid_request", http.StatusFound},
+		{"authorization server failure", transportRedirect, authorizationError, errors.Internal("database unreachable"), "server_error", http.StatusFound},
+		{"authorization unavailable", transportRedirect, authorizationError, errors.ServiceUnavailable("database unreachable"), "temporarily_unavailable", http.StatusFound},
+
+		// Authorization endpoint, before the redirect URI is validated
+		{"authorization page invalid_request", transportPage, authorizationError, errors.BadRequest(errors.ErrMsgInvalidRedirectUri), "invalid_request", http.StatusBadRequest},
+		{"authorization page server failure", transportPage, authorizationError, errors.Internal("database unreachable"), "server_error", http.StatusInternalServerError},
+		{"authorization page unavailable", transportPage, authorizationError, errors.ServiceUnavailable("database unreachable"), "temporarily_unavailable", http.StatusServiceUnavailable},
+
+		// Token endpoint
+		{"token invalid_request", transportJSON, tokenError, errors.BadRequest(errors.ErrMsgInvalidRequest), "invalid_request", http.StatusBadRequest},
+		{"token invalid_client", transportJSON, tokenError, errors.Unauthorized(errors.ErrMsgInvalidClient), "invalid_client", http.StatusUnauthorized},
+		{"token invalid_grant", transportJSON, tokenError, errors.BadRequest(errors.ErrMsgInvalidGrant), "invalid_grant", http.StatusBadRequest},
+		{"token unauthorized_client", transportJSON, tokenError, errors.BadRequest(errors.ErrMsgUnauthorizedClient), "unauthorized_client", http.StatusBadRequest},
+		{"token unsupported_grant_type", transportJSON, tokenError, errors.BadRequest(errors.ErrMsgUnsupportedGrantType), "unsupported_grant_type", http.StatusBadRequest},
+		{"token invalid_scope", transportJSON, tokenError, errors.BadRequest(errors.ErrMsgInvalidScope), "invalid_scope", http.StatusBadRequest},
+		{"token invalid_target", transportJSON, tokenError, errors.BadRequest(errors.ErrMsgInvalidTarget), "invalid_target", http.StatusBadRequest},
+		{"token authorization_pending", transportJSON, tokenError, errors.BadRequest(errors.ErrMsgAuthorizationPending), "authorization_pending", http.StatusBadRequest},
+		{"token slow_down", transportJSON, tokenError, errors.BadRequest(errors.ErrMsgSlowDown), "slow_down", http.StatusBadRequest},
+		{"token expired_token", transportJSON, tokenError, errors.BadRequest(errors.ErrMsgExpiredToken), "expired_token", http.StatusBadRequest},
+		{"token access_denied", transportJSON, tokenError, errors.Forbidden(errors.ErrMsgAccessDenied), "access_denied", http.StatusBadRequest},
+		{"token invalid_dpop_proof", transportJSON, tokenError, errors.BadRequest(errors.ErrMsgInvalidDPoPProof), "invalid_dpop_proof", http.StatusBadRequest},
+		{"token other client error", transportJSON, tokenError, errors.Unauthorized(errors.ErrMsgRefreshTokenNotIssuedToClient), "invalid_grant", http.StatusBadRequest},
+		{"token server failure", transportJSON, tokenError, errors.Internal("database unreachable"), "server_error", http.StatusInternalServerError},
+		{"token unavailable", transportJSON, tokenError, errors.ServiceUnavailable("database unreachable"), "temporarily_unavailable", http.StatusServiceUnavailable},
+
+		// Pushed authorization request endpoint
+		{"pushed request invalid_client", transportJSON, pushedRequestError, errors.Unauthorized(errors.ErrMsgInvalidClient), "invalid_client", http.StatusUnauthorized},
+		{"pushed request invalid_redirect_uri", transportJSON, pushedRequestError, errors.BadRequest(errors.ErrMsgInvalidRedirectUri), "invalid_redirect_uri", http.StatusBadRequest},
+		{"pushed request other client error", transportJSON, pushedRequestError, errors.BadRequest(errors.ErrMsgInvalidGrant), "invalid_request", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := tt.mapError(tt.err)
+
+			var recorder *httptest.ResponseRecorder
+			var gotCode string
+			switch tt.transport {
+			case transportRedirect:
+				recorder = errorRecorder(func(c *gin.Context) {
+					(&Handler{}).redirectError(c, redirectURI, ResponseModeQuery, state, resp)
+				})
+				location, err := url.Parse(recorder.Header().Get("Location"))
+				if err != nil || !strings.HasPrefix(location.String(), redirectURI+"?") {
+					t.Fatalf("got Location %q, want the redirect URI", recorder.Header().Get("Location"))
+				}
+				if got := location.Query().Get("state"); got != state {
+					t.Errorf("got state %q, want %q", got, state)
+				}
+				gotCode = location.Query().Get("error")
+			case transportPage:
+				recorder = errorRecorder(func(c *gin.Context) { renderAuthorizationError(c, resp) })
+				if got := recorder.Header().Get("Location"); got != "" {
+					t.Errorf("got Location %q, want no redirect", got)
+				}
+				if body := recorder.Body.String(); strings.Contains(body, "Error: "+tt.wantCode) {
+					gotCode = tt.wantCode
+				}
+			case transportJSON:
+				recorder = errorRecorder(func(c *gin.Context) { writeOAuthError(c, resp) })
+				var body ErrorResponse
+				if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+					t.Fatalf("failed to decode body %q: %v", recorder.Body.String(), err)
+				}
+				gotCode = body.Error
+			}
+
+			if gotCode != tt.wantCode {
+				t.Errorf("got error %q, want %q", gotCode, tt.wantCode)
+			}
+			if recorder.Code != tt.wantStatus {
+				t.Errorf("got status %d, want %d", recorder.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestServiceErrorHidesServerFailures(t *testing.T) {
+	resp := tokenError(errors.Internal("connection to 10.0.0.5:5432 refused"))
+	if strings.Contains(resp.ErrorDescription, "10.0.0.5") {
+		t.Errorf("got description %q, want the cause hidden", resp.ErrorDescription)
+	}
+}
@@ -0,0 +1,385 @@
+package oauth
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/app/audit"
+	"github.com/verigate/verigate-server/internal/app/client"
+	"github.com/verigate/verigate-server/internal/app/scope"
+	"github.com/verigate/verigate-server/internal/app/token"
+	"github.com/verigate/verigate-server/internal/pkg/db/memory"
+)
+
+// Fakes implement the methods the tests reach; the embedded interface of each leaves any other
+// method nil, so a test reaching one fails loudly.
+
+// fakeRepository is an in-memory oauth.Repository of authorization codes and user consents.
+// Tests interleave concurrent exchanges through the optional hooks, run outside the lock.
+type fakeRepository struct {
+	Repository
+	mu       sync.Mutex
+	codes    map[string]*AuthorizationCode
+	consents []*UserConsent
+
+	afterFind     func()            // Runs after each FindAuthorizationCode
+	afterMarkUsed func(marked bool) // Runs after each MarkCodeAsUsed with its result
+}
+
+// SaveAuthorizationCode stores a copy of the code.
+func (r *fakeRepository) SaveAuthorizationCode(ctx context.Context, code *AuthorizationCode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := *code
+	r.codes[code.Code] = &stored
+	return nil
+}
+
+// FindAuthorizationCode returns a copy of the code, or nil if it does not exist.
+func (r *fakeRepository) FindAuthorizationCode(ctx context.Context, code string) (*AuthorizationCode, error) {
+	if r.afterFind != nil {
+		defer r.afterFind()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.codes[code]
+	if !ok {
+		return nil, nil
+	}
+	found := *stored
+	return &found, nil
+}
+
+// MarkCodeAsUsed marks an unused code as used, reporting whether it was unused.
+func (r *fakeRepository) MarkCodeAsUsed(ctx context.Context, code string) (bool, error) {
+	marked := r.markCodeAsUsed(code)
+	if r.afterMarkUsed != nil {
+		r.afterMarkUsed(marked)
+	}
+	return marked, nil
+}
+
+func (r *fakeRepository) markCodeAsUsed(code string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.codes[code]
+	if !ok || stored.IsUsed {
+		return false
+	}
+	stored.IsUsed = true
+	return true
+}
+
+// MarkCodeReplayed flags the code as presented again.
+func (r *fakeRepository) MarkCodeReplayed(ctx context.Context, code string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if stored, ok := r.codes[code]; ok {
+		stored.ReplayDetected = true
+	}
+	return nil
+}
+
+// TouchUserConsent accepts every use of a consent.
+func (r *fakeRepository) TouchUserConsent(ctx context.Context, userID uint, clientID string, at time.Time) error {
+	return nil
+}
+
+// FindUserConsent returns a copy of the user's consent to the client, or nil if there is none.
+func (r *fakeRepository) FindUserConsent(ctx context.Context, userID uint, clientID string) (*UserConsent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, consent := range r.consents {
+		if consent.UserID == userID && consent.ClientID == clientID {
+			found := *consent
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+// SaveUserConsent stores a copy of the consent.
+func (r *fakeRepository) SaveUserConsent(ctx context.Context, consent *UserConsent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := *consent
+	r.consents = append(r.consents, &stored)
+	return nil
+}
+
+// UpdateUserConsent replaces the stored consent of the same user and client.
+func (r *fakeRepository) UpdateUserConsent(ctx context.Context, consent *UserConsent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, stored := range r.consents {
+		if stored.UserID == consent.UserID && stored.ClientID == consent.ClientID {
+			updated := *consent
+			r.consents[i] = &updated
+		}
+	}
+	return nil
+}
+
+// fakePushedRequestRepository is an in-memory PushedRequestRepository, ignoring expiry.
+type fakePushedRequestRepository struct {
+	mu       sync.Mutex
+	requests map[string]AuthorizeRequest
+}
+
+// SavePushedRequest stores a copy of the request.
+func (r *fakePushedRequestRepository) SavePushedRequest(ctx context.Context, handle string, req *AuthorizeRequest, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests[handle] = *req
+	return nil
+}
+
+// FindPushedRequest returns a copy of the request, or nil if it does not exist.
+func (r *fakePushedRequestRepository) FindPushedRequest(ctx context.Context, handle string) (*AuthorizeRequest, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	req, ok := r.requests[handle]
+	if !ok {
+		return nil, nil
+	}
+	return &req, nil
+}
+
+// TakePushedRequest returns and removes the request, or returns nil if it does not exist.
+func (r *fakePushedRequestRepository) TakePushedRequest(ctx context.Context, handle string) (*AuthorizeRequest, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	req, ok := r.requests[handle]
+	if !ok {
+		return nil, nil
+	}
+	delete(r.requests, handle)
+	return &req, nil
+}
+
+// fakeTokenRepository is an in-memory token.Repository recording the tokens issued for each code.
+type fakeTokenRepository struct {
+	token.Repository
+	mu            sync.Mutex
+	accessTokens  map[string]*token.AccessToken
+	refreshTokens map[string]*token.RefreshToken
+	codeTokens    map[string][]string // Access token IDs by the authorization code they were issued for
+}
+
+// SaveAccessToken stores a copy of the access token.
+func (r *fakeTokenRepository) SaveAccessToken(ctx context.Context, t *token.AccessToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := *t
+	r.accessTokens[t.TokenID] = &stored
+	return nil
+}
+
+// FindAccessToken returns a copy of the access token, or nil if it does not exist.
+func (r *fakeTokenRepository) FindAccessToken(ctx context.Context, tokenID string) (*token.AccessToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.accessTokens[tokenID]
+	if !ok {
+		return nil, nil
+	}
+	found := *stored
+	return &found, nil
+}
+
+// FindAccessTokenByHash returns a copy of the access token with the hash, or nil if there is none.
+func (r *fakeTokenRepository) FindAccessTokenByHash(ctx context.Context, tokenHash string) (*token.AccessToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, stored := range r.accessTokens {
+		if stored.TokenHash == tokenHash {
+			found := *stored
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+// SaveRefreshToken stores a copy of the refresh token.
+func (r *fakeTokenRepository) SaveRefreshToken(ctx context.Context, t *token.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := *t
+	r.refreshTokens[t.TokenID] = &stored
+	return nil
+}
+
+// SaveAuthorizationCodeToken links an access token to the code it was issued for.
+func (r *fakeTokenRepository) SaveAuthorizationCodeToken(ctx context.Context, authCode, tokenID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.codeTokens[authCode] = append(r.codeTokens[authCode], tokenID)
+	return nil
+}
+
+// RevokeTokensByAuthCode revokes the access tokens issued for the code and their refresh tokens.
+func (r *fakeTokenRepository) RevokeTokensByAuthCode(ctx context.Context, authCode string) (*token.RevokedTokens, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	revoked := &token.RevokedTokens{}
+	for _, tokenID := range r.codeTokens[authCode] {
+		if t, ok := r.accessTokens[tokenID]; ok && !t.IsRevoked {
+			t.IsRevoked = true
+			revoked.AccessTokens = append(revoked.AccessTokens, token.AccessToken{TokenID: t.TokenID, ExpiresAt: t.ExpiresAt})
+		}
+		for _, rt := range r.refreshTokens {
+			if rt.AccessTokenID == tokenID && !rt.IsRevoked {
+				rt.IsRevoked = true
+				revoked.RefreshTokens++
+			}
+		}
+	}
+	return revoked, nil
+}
+
+// activeTokens counts the access and refresh tokens that are not revoked.
+func (r *fakeTokenRepository) activeTokens() (access, refresh int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, t := range r.accessTokens {
+		if !t.IsRevoked {
+			access++
+		}
+	}
+	for _, t := range r.refreshTokens {
+		if !t.IsRevoked {
+			refresh++
+		}
+	}
+	return access, refresh
+}
+
+// fakeCache is an in-memory token.CacheRepository.
+type fakeCache struct {
+	mu     sync.Mutex
+	values map[string]interface{}
+}
+
+// Set stores the value, ignoring the expiration.
+func (c *fakeCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] = value
+	return nil
+}
+
+// Get returns the value as a string, or an empty string if it is not cached.
+func (c *fakeCache) Get(ctx context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.values[key]; !ok {
+		return "", nil
+	}
+	return "1", nil
+}
+
+// Delete removes the value.
+func (c *fakeCache) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.values, key)
+	return nil
+}
+
+// fakeScopeRepository is a scope.Repository of a fixed list of scopes.
+type fakeScopeRepository struct {
+	scope.Repository
+	scopes []scope.Scope
+}
+
+// FindAll returns every scope.
+func (r *fakeScopeRepository) FindAll(ctx context.Context) ([]scope.Scope, error) {
+	return r.scopes, nil
+}
+
+// FindByNames returns the named scopes that exist.
+func (r *fakeScopeRepository) FindByNames(ctx context.Context, names []string) ([]scope.Scope, error) {
+	var found []scope.Scope
+	for _, sc := range r.scopes {
+		if containsScope(names, sc.Name) {
+			found = append(found, sc)
+		}
+	}
+	return found, nil
+}
+
+// testService is a Service over fakes, along with the fakes the tests inspect.
+type testService struct {
+	*Service
+	codes   *fakeRepository
+	tokens  *fakeTokenRepository
+	clients client.Repository
+}
+
+// newTestService returns a Service backed by in-memory stores, knowing the openid,
+// offline_access, and profile scopes.
+func newTestService(t *testing.T) *testService {
+	t.Helper()
+
+	codes := &fakeRepository{codes: make(map[string]*AuthorizationCode)}
+	tokens := &fakeTokenRepository{
+		accessTokens:  make(map[string]*token.AccessToken),
+		refreshTokens: make(map[string]*token.RefreshToken),
+		codeTokens:    make(map[string][]string),
+	}
+	clients := memory.NewClientRepository()
+	scopes := &fakeScopeRepository{scopes: []scope.Scope{{Name: ScopeOpenID}, {Name: ScopeOfflineAccess}, {Name: "profile"}}}
+
+	auditService := audit.NewService(nil)
+	tokenService := token.NewService(tokens, &fakeCache{values: make(map[string]interface{})}, nil, auditService)
+	clientService := client.NewService(clients, nil, auditService)
+
+	pushed := &fakePushedRequestRepository{requests: make(map[string]AuthorizeRequest)}
+
+	service := NewService(codes, nil, clientService, tokenService, scope.NewService(scopes), nil, nil, nil, pushed, nil, auditService)
+	return &testService{Service: service, codes: codes, tokens: tokens, clients: clients}
+}
+
+// addClient registers a confidential client allowed the authorization code, refresh token,
+// and client credentials grants, redirecting to the given URIs.
+func (s *testService) addClient(t *testing.T, clientID string, redirectURIs ...string) *client.Client {
+	t.Helper()
+
+	c := &client.Client{
+		ClientID:       clientID,
+		ClientName:     clientID,
+		RedirectURIs:   redirectURIs,
+		GrantTypes:     []string{GrantTypeAuthorizationCode, GrantTypeRefreshToken, GrantTypeClientCredentials},
+		Scope:          "openid offline_access profile",
+		IsConfidential: true,
+		IsActive:       true,
+	}
+	if err := s.clients.Save(context.Background(), c); err != nil {
+		t.Fatalf("failed to save client: %v", err)
+	}
+	return c
+}
+
+// addCode stores an unused authorization code issued to the client for user 1.
+func (s *testService) addCode(t *testing.T, code, clientID, redirectURI, scope string) *AuthorizationCode {
+	t.Helper()
+
+	now := time.Now()
+	authCode := &AuthorizationCode{
+		Code:        code,
+		ClientID:    clientID,
+		UserID:      1,
+		RedirectURI: redirectURI,
+		Scope:       scope,
+		ExpiresAt:   now.Add(time.Minute),
+		CreatedAt:   now,
+		AuthTime:    now,
+	}
+	if err := s.codes.SaveAuthorizationCode(context.Background(), authCode); err != nil {
+		t.Fatalf("failed to save authorization code: %v", err)
+	}
+	return authCode
+}
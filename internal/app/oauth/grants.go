@@ -0,0 +1,179 @@
+package oauth
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/app/audit"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+	"github.com/verigate/verigate-server/internal/pkg/utils/opaque"
+)
+
+// prepareGrant validates the grant management parameters of an authorization code exchange
+// (FAPI Grant Management Section 5) and returns the grant the tokens are to be issued under,
+// with the code's authorization applied but not yet stored. Returns nil when the request does
+// not manage a grant. It runs before the code is consumed, so a rejected request leaves the code
+// usable. A grant_id the client may not use, because it is unknown, was revoked, or belongs to
+// another client or user, is reported as invalid_grant_id.
+func (s *Service) prepareGrant(ctx context.Context, req TokenRequest, code *AuthorizationCode) (*Grant, error) {
+	if req.GrantManagementAction == "" {
+		if req.GrantID != "" {
+			return nil, errors.BadRequest(errors.ErrMsgInvalidRequest).WithDetails(errors.ErrMsgGrantManagementActionRequired)
+		}
+		return nil, nil
+	}
+
+	now := time.Now()
+	switch req.GrantManagementAction {
+	case GrantManagementActionCreate:
+		if req.GrantID != "" {
+			return nil, errors.BadRequest(errors.ErrMsgInvalidRequest).WithDetails(errors.ErrMsgGrantIDNotAllowed)
+		}
+		grantID, err := opaque.Random()
+		if err != nil {
+			return nil, errors.Internal(errors.ErrMsgFailedToSaveGrant)
+		}
+		return &Grant{
+			GrantID:   grantID,
+			ClientID:  code.ClientID,
+			UserID:    code.UserID,
+			Scope:     code.Scope,
+			Claims:    code.IDTokenClaims,
+			Resources: code.Resources,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}, nil
+	case GrantManagementActionUpdate, GrantManagementActionReplace:
+		if req.GrantID == "" {
+			return nil, errors.BadRequest(errors.ErrMsgInvalidRequest).WithDetails(errors.ErrMsgGrantIDRequired)
+		}
+	default:
+		return nil, errors.BadRequest(errors.ErrMsgInvalidRequest).WithDetails(errors.ErrMsgInvalidGrantManagementAction)
+	}
+
+	grant, err := s.oauthRepo.FindGrant(ctx, req.GrantID)
+	if err != nil {
+		return nil, err
+	}
+	if grant == nil || grant.ClientID != code.ClientID || grant.UserID != code.UserID {
+		return nil, errors.BadRequest(errors.ErrMsgInvalidGrantID)
+	}
+
+	if req.GrantManagementAction == GrantManagementActionUpdate {
+		grant.Scope = mergeScopes(grant.Scope, code.Scope)
+		grant.Claims = mergeValues(grant.Claims, code.IDTokenClaims)
+		grant.Resources = mergeValues(grant.Resources, code.Resources)
+	} else {
+		grant.Scope = code.Scope
+		grant.Claims = code.IDTokenClaims
+		grant.Resources = code.Resources
+	}
+	grant.UpdatedAt = now
+	return grant, nil
+}
+
+// saveGrant stores a grant prepareGrant returned once the code has been consumed. Replacing a
+// grant revokes the tokens issued under it before, since they may carry scopes the new
+// authorization no longer includes; updated grants keep their tokens.
+func (s *Service) saveGrant(ctx context.Context, grant *Grant, action string) error {
+	switch action {
+	case GrantManagementActionCreate:
+		return s.oauthRepo.SaveGrant(ctx, grant)
+	case GrantManagementActionReplace:
+		if _, err := s.tokenService.RevokeGrantTokens(ctx, grant.GrantID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.oauthRepo.UpdateGrant(ctx, grant); err != nil {
+		if customErr, ok := errors.As(err); ok && customErr.Status == http.StatusNotFound {
+			return errors.BadRequest(errors.ErrMsgInvalidGrantID)
+		}
+		return err
+	}
+	return nil
+}
+
+// GetGrant returns the grant with the given ID to the client it was given to
+// (FAPI Grant Management Section 6.1). Returns NotFound if the grant does not exist,
+// was revoked, or belongs to another client, so clients cannot probe for other grants.
+func (s *Service) GetGrant(ctx context.Context, clientID, grantID string) (*GrantResponse, error) {
+	grant, err := s.findClientGrant(ctx, clientID, grantID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GrantResponse{
+		Scopes:    []GrantScope{{Scope: grant.Scope, Resources: grant.Resources}},
+		Claims:    grant.Claims,
+		CreatedAt: grant.CreatedAt,
+		UpdatedAt: grant.UpdatedAt,
+	}, nil
+}
+
+// RevokeGrant revokes a grant on behalf of the client it was given to (FAPI Grant Management
+// Section 6.2), together with every access and refresh token issued under it. The tokens are
+// revoked before the grant is deleted, so a failed revocation can be retried.
+// Returns NotFound like GetGrant.
+func (s *Service) RevokeGrant(ctx context.Context, clientID, grantID string) error {
+	grant, err := s.findClientGrant(ctx, clientID, grantID)
+	if err != nil {
+		return err
+	}
+
+	revoked, err := s.tokenService.RevokeGrantTokens(ctx, grant.GrantID)
+	if err != nil {
+		return err
+	}
+	if err := s.oauthRepo.DeleteGrant(ctx, grant.GrantID); err != nil {
+		return err
+	}
+
+	s.auditService.Record(ctx, audit.Event{
+		Type:     audit.EventConsentRevoked,
+		Actor:    audit.ClientActor(clientID),
+		ClientID: clientID,
+		Details: map[string]string{
+			"grant_id":               grant.GrantID,
+			"user_id":                strconv.FormatUint(uint64(grant.UserID), 10),
+			"access_tokens_revoked":  strconv.Itoa(len(revoked.AccessTokens)),
+			"refresh_tokens_revoked": strconv.FormatInt(revoked.RefreshTokens, 10),
+		},
+	})
+	return nil
+}
+
+// findClientGrant retrieves a grant given to a client, returning NotFound if there is none.
+func (s *Service) findClientGrant(ctx context.Context, clientID, grantID string) (*Grant, error) {
+	grant, err := s.oauthRepo.FindGrant(ctx, grantID)
+	if err != nil {
+		return nil, err
+	}
+	if grant == nil || grant.ClientID != clientID {
+		return nil, errors.NotFound(errors.ErrMsgGrantNotFound)
+	}
+	return grant, nil
+}
+
+// isGrantActive reports whether a grant tokens were issued under still exists.
+// Tokens issued outside grant management have no grant and are always active.
+func (s *Service) isGrantActive(ctx context.Context, grantID string) bool {
+	if grantID == "" {
+		return true
+	}
+	grant, err := s.oauthRepo.FindGrant(ctx, grantID)
+	return err == nil && grant != nil
+}
+
+// mergeValues returns the union of two lists, keeping the order of first appearance.
+func mergeValues(existing, added []string) []string {
+	merged := append([]string{}, existing...)
+	for _, value := range added {
+		if !containsScope(merged, value) {
+			merged = append(merged, value)
+		}
+	}
+	return merged
+}
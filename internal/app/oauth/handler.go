@@ -3,12 +3,22 @@
 package oauth
 
 import (
+	"context"
 	"encoding/base64"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/verigate/verigate-server/internal/app/client"
+	"github.com/verigate/verigate-server/internal/app/token"
+	"github.com/verigate/verigate-server/internal/pkg/metrics"
 	"github.com/verigate/verigate-server/internal/pkg/middleware"
+	"github.com/verigate/verigate-server/internal/pkg/tenant"
 	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+	jwtutil "github.com/verigate/verigate-server/internal/pkg/utils/jwt"
+	"github.com/verigate/verigate-server/internal/pkg/utils/locale"
 
 	"github.com/gin-gonic/gin"
 )
@@ -28,127 +38,348 @@ func NewHandler(service *Service) *Handler {
 // RegisterRoutes sets up the OAuth-related routes on the provided router group.
 // Routes are organized into three categories:
 // - Public endpoints: Token issuance and revocation
-// - OAuth protected endpoints: Require OAuth token authorization
-// - Web app protected endpoints: Require web authentication for consent screens
+// - Authorization and end session endpoints: Use the web session when present
+// - Web app protected endpoints: Require web authentication and a CSRF token for consent screens
 func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
 	// Public endpoints
-	r.POST("/token", h.Token)
 	r.POST("/revoke", h.Revoke)
+	r.POST("/introspect", h.Introspect)
+	r.POST("/device_authorization", h.DeviceAuthorization)
+	r.POST("/par", h.PushAuthorizationRequest)
+	r.GET("/grants/:grant_id", h.GetGrant)
+	r.DELETE("/grants/:grant_id", h.RevokeGrant)
+
+	// UserInfo validates its bearer token itself to report RFC 6750 errors
+	r.GET("/userinfo", h.UserInfo)
+	r.POST("/userinfo", h.UserInfo)
 
-	// OAuth protected endpoints
-	oauthProtected := r.Group("")
-	oauthProtected.Use(middleware.Auth())
+	// The authorization endpoint decides itself when the user must log in
+	webOptional := r.Group("")
+	webOptional.Use(middleware.OptionalWebAuth(h.service.authService))
 	{
-		oauthProtected.GET("/authorize", h.Authorize)
-		oauthProtected.GET("/userinfo", h.UserInfo)
+		webOptional.GET("/authorize", h.Authorize)
+		webOptional.GET("/logout", h.EndSession)
 	}
 
 	// Web app protected endpoints (consent screen)
 	webProtected := r.Group("")
+	webProtected.Use(middleware.CSRF())
 	webProtected.Use(middleware.WebAuth(h.service.authService))
 	{
 		webProtected.GET("/consent", h.ShowConsent)
 		webProtected.POST("/consent", h.HandleConsent)
+		webProtected.GET("/device", h.ShowDeviceVerification)
+		webProtected.POST("/device", h.HandleDeviceVerification)
+	}
+}
+
+// RegisterAccountRoutes sets up the routes through which users manage the applications they
+// have authorized on the provided router group. All routes require web authentication.
+func (h *Handler) RegisterAccountRoutes(r *gin.RouterGroup) {
+	r.Use(middleware.CSRF())
+	r.Use(middleware.WebAuth(h.service.authService))
+
+	r.GET("/authorizations", h.ListAuthorizations)
+	r.DELETE("/authorizations/:client_id", h.RevokeAuthorization)
+}
+
+// RegisterDebugRoutes sets up the token debugging endpoint, which is only registered when
+// enabled in the configuration. It requires a web session and a CSRF token.
+func (h *Handler) RegisterDebugRoutes(r *gin.RouterGroup) {
+	r.Use(middleware.CSRF())
+	r.Use(middleware.WebAuth(h.service.authService))
+
+	r.POST("/token", h.DebugToken)
+}
+
+// RegisterTokenRoutes sets up the token endpoint on the provided router group,
+// kept apart from RegisterRoutes so that it can be rate limited on its own.
+func (h *Handler) RegisterTokenRoutes(r gin.IRoutes) {
+	r.POST("/token", h.Token)
+}
+
+// ClientAuthenticatedPaths returns the full paths, under the basePath the routes of RegisterRoutes
+// and RegisterTokenRoutes are registered at, of the endpoints at which clients authenticate.
+// Their handlers report the outcome of client authentication to the rate limiters.
+func (h *Handler) ClientAuthenticatedPaths(basePath string) []string {
+	paths := []string{"/token", "/revoke", "/introspect", "/device_authorization", "/par", "/grants/:grant_id"}
+	for i, p := range paths {
+		paths[i] = strings.TrimRight(basePath, "/") + p
+	}
+	return paths
+}
+
+// RegisterWellKnownRoutes sets up the discovery routes that must be served
+// from the root of the server rather than under the API prefix.
+func (h *Handler) RegisterWellKnownRoutes(r gin.IRoutes) {
+	r.GET(JWKSPath, h.JWKS)
+	r.GET("/.well-known/oauth-authorization-server", h.Metadata)
+	r.GET("/.well-known/openid-configuration", h.Metadata)
+}
+
+// CORSOriginValidator returns a CORS origin check for the endpoints that browser-based
+// clients call directly. Requests to the token and userinfo endpoints are accepted from
+// any origin registered as a client redirect URI; other paths are not affected.
+func (h *Handler) CORSOriginValidator() middleware.OriginValidator {
+	return func(c *gin.Context, origin string) bool {
+		path := c.Request.URL.Path
+		if !strings.HasSuffix(path, "/oauth/token") && !strings.HasSuffix(path, "/oauth/userinfo") {
+			return false
+		}
+		return h.service.IsAllowedOrigin(c.Request.Context(), origin)
+	}
+}
+
+// JWKS publishes the public keys used to verify issued tokens.
+// The response may be cached until the next scheduled key rotation.
+func (h *Handler) JWKS(c *gin.Context) {
+	set, err := jwtutil.PublicJWKS(c.Request.Context())
+	if err != nil {
+		c.Error(errors.Internal(errors.ErrMsgFailedToLoadSigningKeys))
+		return
 	}
+
+	maxAge := int(jwtutil.JWKSCacheMaxAge(c.Request.Context()).Seconds())
+	c.Header("Cache-Control", "public, max-age="+strconv.Itoa(maxAge))
+	c.JSON(http.StatusOK, set)
+}
+
+// Metadata serves the authorization server metadata document (RFC 8414 Section 3),
+// which is also the OpenID Provider configuration (OpenID Connect Discovery Section 4).
+// The document may be cached for an hour, as it only changes with the configuration or scopes.
+func (h *Handler) Metadata(c *gin.Context) {
+	metadata, err := h.service.Metadata(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age="+strconv.Itoa(int(metadataCacheMaxAge.Seconds())))
+	c.JSON(http.StatusOK, metadata)
 }
 
 // Authorize handles the OAuth authorization request.
 // This is the entry point for the OAuth authorization code flow.
-// It validates the request, checks if user consent is needed,
-// and either issues an authorization code or redirects to the consent page.
+// Parameters may be passed in a signed request object, by value or by reference (RFC 9101).
+// It validates the request, sends the user to log in when there is no session,
+// a fresh login is requested with prompt=login, the session is older than max_age, or it does
+// not meet the requested acr_values but a step-up login would (RFC 9470 Section 4), checks if
+// user consent is needed, and either issues an authorization code or redirects to the consent page.
+// A request resumed after such a login is only served for a session authenticated since.
+// With prompt=none no UI is shown; login_required or consent_required is returned to the client instead.
+// A login_hint pre-fills the username on the login page, and a session of a user other than
+// the hinted one is treated like no session.
 func (h *Handler) Authorize(c *gin.Context) {
+	// The redirect URI has not been validated, so parameter errors are shown instead of redirected
+	query := c.Request.URL.Query()
+	if err := authorizeParams.validate(query, query); err != nil {
+		renderAuthorizationError(c, authorizationError(err))
+		return
+	}
+
 	var req AuthorizeRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
-		h.redirectError(c, req.RedirectURI, req.State, "invalid_request", "Invalid request parameters")
+		// The redirect URI has not been validated, so the error is shown instead of redirected
+		renderAuthorizationError(c, ErrorResponse{
+			Error:            errors.ErrMsgInvalidRequest,
+			ErrorDescription: "Invalid request parameters",
+		})
 		return
 	}
 
-	userID := c.GetUint("user_id")
-	code, err := h.service.Authorize(c.Request.Context(), req, userID)
+	// Parameters sent in a request object replace the query parameters; until it is
+	// verified the redirect URI is unknown, so errors are shown instead of redirected
+	req, err := h.service.ResolveRequestObject(c.Request.Context(), req)
+	if err != nil {
+		renderAuthorizationError(c, authorizationError(err))
+		return
+	}
+	if req.RedirectURI == "" {
+		renderAuthorizationError(c, authorizationError(missingParameter("redirect_uri")))
+		return
+	}
+
+	// Errors may only be sent to a redirect URI registered for the client
+	if _, err := h.service.ValidateRedirectURI(c.Request.Context(), req.ClientID, req.RedirectURI); err != nil {
+		renderAuthorizationError(c, serviceError(err, nil, errors.ErrMsgInvalidRequest))
+		return
+	}
+
+	// Errors about the response mode itself are delivered in the default mode of the response type
+	mode, err := resolveResponseMode(req.ResponseType, req.ResponseMode)
+	if err != nil {
+		h.redirectError(c, req.RedirectURI, mode, req.State, authorizationError(err))
+		return
+	}
+
+	promptNone := hasPrompt(req.Prompt, PromptNone)
+	if promptNone && len(strings.Fields(req.Prompt)) > 1 {
+		h.redirectError(c, req.RedirectURI, mode, req.State, ErrorResponse{Error: errors.ErrMsgInvalidRequest, ErrorDescription: errors.ErrMsgInvalidPrompt})
+		return
+	}
+
+	maxAge := -1
+	if req.MaxAge != "" {
+		parsed, err := strconv.Atoi(req.MaxAge)
+		if err != nil || parsed < 0 {
+			h.redirectError(c, req.RedirectURI, mode, req.State, ErrorResponse{Error: errors.ErrMsgInvalidRequest, ErrorDescription: errors.ErrMsgInvalidMaxAge})
+			return
+		}
+		maxAge = parsed
+	}
+
+	if _, err := parseClaimsRequest(req.Claims); err != nil {
+		h.redirectError(c, req.RedirectURI, mode, req.State, ErrorResponse{Error: errors.ErrMsgInvalidRequest, ErrorDescription: errors.ErrMsgInvalidClaimsParameter})
+		return
+	}
+
+	userID := c.GetUint(middleware.ContextKeyUserID)
+	authTime := c.GetTime(middleware.ContextKeyAuthTime)
+
+	loginHint := sanitizeLoginHint(req.LoginHint)
+
+	loginRequired := userID == 0 || hasPrompt(req.Prompt, PromptLogin) ||
+		(maxAge >= 0 && time.Since(authTime) > time.Duration(maxAge)*time.Second)
+	if loginAfter, err := strconv.ParseInt(query.Get(loginAfterParam), 10, 64); err == nil && authTime.Before(time.Unix(loginAfter, 0)) {
+		loginRequired = true
+	}
+	if !loginRequired && loginHint != "" {
+		matches, err := h.service.SessionMatchesLoginHint(c.Request.Context(), req.ClientID, userID, loginHint)
+		if err != nil {
+			h.redirectError(c, req.RedirectURI, mode, req.State, authorizationError(err))
+			return
+		}
+		loginRequired = !matches
+	}
+	if loginRequired {
+		if promptNone {
+			h.redirectError(c, req.RedirectURI, mode, req.State, ErrorResponse{Error: errors.ErrMsgLoginRequired})
+			return
+		}
+		c.Redirect(http.StatusFound, h.buildLoginURL(c, loginHint))
+		return
+	}
+
+	result, err := h.service.Authorize(c.Request.Context(), req, userID, authTime,
+		c.GetString(middleware.ContextKeySessionID), c.GetString(middleware.ContextKeyACR))
 
 	if err != nil {
+		// A step-up login meets the requested acr_values the session does not
+		if customErr, ok := errors.As(err); ok && customErr.Message == errors.ErrMsgLoginRequired && !promptNone {
+			c.Redirect(http.StatusFound, h.buildLoginURL(c, loginHint))
+			return
+		}
+
 		// Check if consent is required
-		if customErr, ok := err.(errors.CustomError); ok && customErr.Status == 302 {
-			// Redirect to consent page
-			c.Redirect(http.StatusFound, h.buildConsentURL(req))
+		if customErr, ok := errors.As(err); ok && customErr.Status == 302 {
+			if promptNone {
+				h.redirectError(c, req.RedirectURI, mode, req.State, ErrorResponse{Error: errors.ErrMsgConsentRequired})
+				return
+			}
+
+			// Redirect to consent page, which asks for offline access only when it is not ignored.
+			// The request is held until the user decides, and a pushed request is used up now
+			req.Scope = h.service.OfflineAccessScope(c.Request.Context(), userID, req.ClientID, req.Scope, req.Prompt)
+			requestURI, err := h.service.HoldForConsent(c.Request.Context(), req)
+			if err != nil {
+				h.redirectError(c, req.RedirectURI, mode, req.State, authorizationError(err))
+				return
+			}
+			c.Redirect(http.StatusFound, h.buildConsentURL(c.Request.Context(), req, requestURI))
 			return
 		}
 
-		// Handle other errors
-		h.redirectError(c, req.RedirectURI, req.State, "server_error", err.Error())
+		h.redirectError(c, req.RedirectURI, mode, req.State, authorizationError(err))
+		return
+	}
+
+	// Deliver the code, and the tokens of a hybrid response type, in the response mode
+	authorizationResponse{
+		RedirectURI: req.RedirectURI,
+		Mode:        mode,
+		Params:      result.params(),
+	}.send(c)
+}
+
+// EndSession handles an RP-initiated logout request (OpenID Connect RP-Initiated Logout).
+// The session identified by the web session or the id_token_hint is ended and a page is
+// rendered that loads every participating client's front-channel logout URI in an iframe
+// before following the post_logout_redirect_uri with the state. Without front-channel
+// clients the user is redirected directly.
+func (h *Handler) EndSession(c *gin.Context) {
+	var req EndSessionRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.Error(errors.BadRequest(errors.ErrMsgInvalidRequestFormat))
+		return
+	}
+
+	userID := c.GetUint(middleware.ContextKeyUserID)
+	sid := c.GetString(middleware.ContextKeySessionID)
+
+	data, err := h.service.EndSession(c.Request.Context(), req, userID, sid)
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	// Build redirect URL with code
-	redirectURL := h.buildRedirectURL(req.RedirectURI, code, req.State)
-	c.Redirect(http.StatusFound, redirectURL)
+	c.Header("Cache-Control", "no-store")
+	if len(data.FrontchannelLogoutURIs) == 0 && data.RedirectURI != "" {
+		c.Redirect(http.StatusFound, data.RedirectURI)
+		return
+	}
+
+	// The page frames only the front-channel logout pages of the clients
+	middleware.SetCSPDirective(c, "frame-src", cspSources(data.FrontchannelLogoutURIs...)...)
+	data.Nonce = middleware.CSPNonce(c)
+
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := endSessionPage.Execute(c.Writer, data); err != nil {
+		c.Error(errors.Internal(errors.ErrMsgFailedToRenderPage).Wrap(err))
+	}
 }
 
 // Token handles the OAuth token issuance endpoint.
 // This endpoint supports various grant types including authorization_code,
 // refresh_token, client_credentials, and password grants.
-// It validates the client credentials and issues access and refresh tokens.
+// It validates the client credentials and issues access and refresh tokens,
+// bound to the key of the DPoP proof in the DPoP header when one is sent (RFC 9449 Section 5).
 func (h *Handler) Token(c *gin.Context) {
+	if !h.validateFormParams(c, tokenParams, tokenError) {
+		return
+	}
+
 	var req TokenRequest
 	if err := c.ShouldBind(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error:            "invalid_request",
+		writeOAuthError(c, ErrorResponse{
+			Error:            errors.ErrMsgInvalidRequest,
 			ErrorDescription: "invalid request format",
 		})
 		return
 	}
 
-	// Get client credentials
-	clientID, clientSecret, err := h.getClientCredentials(c, req)
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{
-			Error:            "invalid_client",
-			ErrorDescription: "Client authentication failed",
-		})
+	// Authenticate client
+	clientID, ok := h.authenticateClient(c, req)
+	if !ok {
 		return
 	}
 
-	// Validate client if confidential
-	if clientSecret != "" {
-		client, err := h.service.ValidateClient(c.Request.Context(), clientID, clientSecret)
-		if err != nil || client == nil {
-			c.JSON(http.StatusUnauthorized, ErrorResponse{
-				Error:            "invalid_client",
-				ErrorDescription: "Client authentication failed",
-			})
-			return
-		}
-	} else {
-		// Verify this is a public client
-		isPublic, err := h.service.IsPublicClient(c.Request.Context(), clientID)
-		if err != nil || !isPublic {
-			c.JSON(http.StatusUnauthorized, ErrorResponse{
-				Error:            "invalid_client",
-				ErrorDescription: "Client authentication failed",
-			})
-			return
-		}
-	}
-
 	// Set client ID in request
 	req.ClientID = clientID
 
-	token, err := h.service.Token(c.Request.Context(), req)
+	ctx, err := h.dpopContext(c, TokenEndpointURL(c.Request.Context()), "")
 	if err != nil {
-		if customErr, ok := err.(errors.CustomError); ok {
-			c.JSON(customErr.Status, ErrorResponse{
-				Error:            "invalid_grant",
-				ErrorDescription: customErr.Message,
-			})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error:            "server_error",
-			ErrorDescription: "Internal server error",
-		})
+		writeOAuthError(c, tokenError(err))
 		return
 	}
 
+	token, err := h.service.Token(ctx, req)
+	if err != nil {
+		writeOAuthError(c, tokenError(err))
+		return
+	}
+
+	metrics.TokensIssued.WithLabelValues(req.GrantType).Inc()
 	c.JSON(http.StatusOK, token)
 }
 
@@ -159,20 +390,16 @@ func (h *Handler) Token(c *gin.Context) {
 func (h *Handler) Revoke(c *gin.Context) {
 	var req RevokeRequest
 	if err := c.ShouldBind(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error:            "invalid_request",
+		writeOAuthError(c, ErrorResponse{
+			Error:            errors.ErrMsgInvalidRequest,
 			ErrorDescription: "invalid request format",
 		})
 		return
 	}
 
-	// Get client credentials
-	clientID, _, err := h.getClientCredentials(c, TokenRequest{})
-	if err != nil {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{
-			Error:            "invalid_client",
-			ErrorDescription: "Client authentication failed",
-		})
+	// Authenticate client the same way as the token endpoint
+	clientID, ok := h.authenticateClient(c, TokenRequest{})
+	if !ok {
 		return
 	}
 
@@ -180,25 +407,213 @@ func (h *Handler) Revoke(c *gin.Context) {
 		// RFC 7009: Always return success
 	}
 
+	// RFC 7009: Respond with 200 and an empty body regardless of token validity
 	c.Status(http.StatusOK)
 }
 
+// PushAuthorizationRequest implements the pushed authorization request endpoint (RFC 9126).
+// An authenticated client posts the authorization request parameters and receives a
+// request_uri to send to the authorization endpoint in their place, keeping them out of
+// the browser. Invalid parameters are reported here with 400 and an OAuth error code.
+func (h *Handler) PushAuthorizationRequest(c *gin.Context) {
+	if !h.validateFormParams(c, pushedRequestParams, pushedRequestError) {
+		return
+	}
+
+	var req AuthorizeRequest
+	if err := c.ShouldBind(&req); err != nil {
+		writeOAuthError(c, ErrorResponse{
+			Error:            errors.ErrMsgInvalidRequest,
+			ErrorDescription: "invalid request format",
+		})
+		return
+	}
+
+	clientID, ok := h.authenticateClient(c, TokenRequest{})
+	if !ok {
+		return
+	}
+
+	resp, err := h.service.PushAuthorizationRequest(c.Request.Context(), clientID, req)
+	if err != nil {
+		writeOAuthError(c, pushedRequestError(err))
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, resp)
+}
+
+// Introspect handles token introspection as specified in RFC 7662.
+// It allows an authenticated client acting as a protected resource to
+// determine whether a token is active and to obtain its metadata.
+// Inactive tokens are reported as {"active": false} without further detail.
+func (h *Handler) Introspect(c *gin.Context) {
+	var req IntrospectRequest
+	if err := c.ShouldBind(&req); err != nil {
+		writeOAuthError(c, ErrorResponse{
+			Error:            errors.ErrMsgInvalidRequest,
+			ErrorDescription: "invalid request format",
+		})
+		return
+	}
+
+	// Introspection requires client authentication
+	if _, ok := h.authenticateClient(c, TokenRequest{}); !ok {
+		return
+	}
+
+	resp, err := h.service.Introspect(c.Request.Context(), req)
+	if err != nil {
+		writeOAuthError(c, ErrorResponse{
+			Error:            errors.ErrMsgServerError,
+			ErrorDescription: "Internal server error",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
 // UserInfo implements the OpenID Connect UserInfo endpoint.
 // It returns claims about the authenticated user based on the scope
 // of the access token used to access this endpoint.
-// The endpoint is OAuth 2.0 protected and requires a valid access token.
+// The access token is read from the Authorization header or, for POST
+// requests, the access_token form parameter (RFC 6750). Tokens bound to a
+// DPoP key are sent with the DPoP scheme and a proof of the key (RFC 9449 Section 7).
+// Failures are reported with a WWW-Authenticate header: 401 for a missing
+// or invalid token or proof and 403 when the token lacks the openid scope.
 func (h *Handler) UserInfo(c *gin.Context) {
-	userID := c.GetUint("user_id")
+	accessToken, scheme := h.getAccessToken(c)
+	if accessToken == "" {
+		c.Header("WWW-Authenticate", `Bearer realm="`+jwtutil.Issuer(c.Request.Context())+`"`)
+		writeError(c, http.StatusUnauthorized, ErrorResponse{
+			Error:            "invalid_request",
+			ErrorDescription: "Missing access token",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if scheme == HeaderDPoP {
+		var err error
+		ctx, err = h.dpopContext(c, tenant.BaseURL(ctx)+UserInfoEndpointPath, accessToken)
+		if err == nil && token.DPoPKeyFromContext(ctx) == "" {
+			err = invalidDPoPProof("a DPoP proof is required with the DPoP scheme")
+		}
+		if err != nil {
+			h.dpopChallenge(c, err)
+			return
+		}
+	}
 
-	userInfo, err := h.service.GetUserInfo(c.Request.Context(), userID)
+	userInfo, signed, err := h.service.GetUserInfo(ctx, accessToken)
 	if err != nil {
-		c.Error(err)
+		customErr, ok := errors.As(err)
+		switch {
+		case ok && customErr.Status == http.StatusForbidden:
+			c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+ScopeOpenID+`"`)
+			writeError(c, http.StatusForbidden, ErrorResponse{
+				Error:            "insufficient_scope",
+				ErrorDescription: "The access token was not granted the openid scope",
+			})
+		case ok && (customErr.Status == http.StatusUnauthorized || customErr.Status == http.StatusNotFound):
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			writeError(c, http.StatusUnauthorized, ErrorResponse{
+				Error:            "invalid_token",
+				ErrorDescription: "The access token is invalid",
+			})
+		default:
+			writeError(c, http.StatusInternalServerError, ErrorResponse{
+				Error:            "server_error",
+				ErrorDescription: "Internal server error",
+			})
+		}
 		return
 	}
 
+	writeUserInfo(c, userInfo, signed)
+}
+
+// writeUserInfo answers a UserInfo request with the claims, or with the signed JWT of the
+// claims for clients that registered signed responses (OpenID Connect Core Section 5.3.2).
+func writeUserInfo(c *gin.Context, userInfo UserInfoResponse, signed string) {
+	if signed != "" {
+		c.Data(http.StatusOK, contentTypeJWT, []byte(signed))
+		return
+	}
 	c.JSON(http.StatusOK, userInfo)
 }
 
+// DeviceAuthorization handles the device authorization endpoint (RFC 8628 Section 3.1).
+// Input-constrained devices call it to obtain a device code for polling and a
+// user code that the user enters on the verification page from another device.
+func (h *Handler) DeviceAuthorization(c *gin.Context) {
+	var req DeviceAuthorizationRequest
+	if err := c.ShouldBind(&req); err != nil {
+		writeOAuthError(c, ErrorResponse{
+			Error:            errors.ErrMsgInvalidRequest,
+			ErrorDescription: "invalid request format",
+		})
+		return
+	}
+
+	// Authenticate client the same way as the token endpoint
+	clientID, ok := h.authenticateClient(c, TokenRequest{ClientID: req.ClientID})
+	if !ok {
+		return
+	}
+	req.ClientID = clientID
+
+	resp, err := h.service.RequestDeviceAuthorization(c.Request.Context(), req)
+	if err != nil {
+		writeOAuthError(c, tokenError(err))
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, resp)
+}
+
+// ShowDeviceVerification displays the device verification page.
+// Without a user_code query parameter the page only asks for the code;
+// with one it shows the requesting client and scopes for approval.
+// In a real application, this would render an HTML template.
+func (h *Handler) ShowDeviceVerification(c *gin.Context) {
+	userCode := c.Query("user_code")
+	if userCode == "" {
+		c.JSON(http.StatusOK, DeviceVerificationPageData{})
+		return
+	}
+
+	data, err := h.service.GetDeviceVerificationPageData(c.Request.Context(), userCode)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+// HandleDeviceVerification processes the user's approval or denial of a device
+// authorization request identified by its user code.
+func (h *Handler) HandleDeviceVerification(c *gin.Context) {
+	var req DeviceVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.BadRequest(errors.ErrMsgInvalidRequestFormat))
+		return
+	}
+
+	userID := c.GetUint(middleware.ContextKeyUserID)
+
+	if err := h.service.VerifyDeviceCode(c.Request.Context(), req.UserCode, userID, req.Approve); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"approved": req.Approve})
+}
+
 // ShowConsent displays the OAuth consent page to the user.
 // This page shows the application name, requested scopes, and allows the user
 // to approve or deny the authorization request.
@@ -210,12 +625,17 @@ func (h *Handler) UserInfo(c *gin.Context) {
 func (h *Handler) ShowConsent(c *gin.Context) {
 	clientID := c.Query("client_id")
 	scope := c.Query("scope")
+	forceConsent := hasPrompt(c.Query("prompt"), PromptConsent)
+	userID := c.GetUint(middleware.ContextKeyUserID)
+
+	locales := locale.Preferred(c.Query("ui_locales"), c.GetHeader("Accept-Language"))
 
-	data, err := h.service.GetConsentPageData(c.Request.Context(), clientID, scope)
+	data, err := h.service.GetConsentPageData(c.Request.Context(), userID, clientID, scope, forceConsent, locales)
 	if err != nil {
 		c.Error(err)
 		return
 	}
+	data.State = c.Query("state")
 
 	// In a real application, this would render a consent page template
 	c.JSON(http.StatusOK, data)
@@ -224,13 +644,9 @@ func (h *Handler) ShowConsent(c *gin.Context) {
 // HandleConsent processes the user's consent decision for an OAuth authorization request.
 // It receives the user's approval or rejection of the requested permissions
 // and either proceeds with the authorization flow or returns an access_denied error.
+// The user may decline individual optional scopes; the authorization then proceeds
+// with the scopes that were granted.
 func (h *Handler) HandleConsent(c *gin.Context) {
-	type ConsentRequest struct {
-		ClientID string `json:"client_id" binding:"required"`
-		Scope    string `json:"scope" binding:"required"`
-		Consent  bool   `json:"consent" binding:"required"`
-	}
-
 	var req ConsentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.Error(errors.BadRequest(errors.ErrMsgInvalidRequestFormat))
@@ -239,150 +655,404 @@ func (h *Handler) HandleConsent(c *gin.Context) {
 
 	userID := c.GetUint("user_id")
 
+	// The decision applies to the request held when consent was asked for, never to
+	// parameters sent back by the browser, and the held request is used up either way
+	authReq, err := h.service.TakeConsentRequest(c.Request.Context(), req.ClientID, c.Query("request_uri"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// Neither the code nor a denial may be sent to a redirect URI not registered for the client
+	if _, err := h.service.ValidateRedirectURI(c.Request.Context(), authReq.ClientID, authReq.RedirectURI); err != nil {
+		c.Error(err)
+		return
+	}
+
+	mode, err := resolveResponseMode(authReq.ResponseType, authReq.ResponseMode)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	result := authorizationResponse{RedirectURI: authReq.RedirectURI, Mode: mode}
+
 	if !req.Consent {
 		// User denied consent
-		c.JSON(http.StatusOK, gin.H{
-			"redirect": h.buildErrorRedirect(c.Query("redirect_uri"), c.Query("state"), errors.ErrMsgAccessDenied, errors.ErrMsgUserDeniedAccess),
-		})
+		result.Params = errorParams(authReq.State, errors.ErrMsgAccessDenied, errors.ErrMsgUserDeniedAccess)
+		c.JSON(http.StatusOK, result.consentResult())
 		return
 	}
 
-	// Save consent
-	if err := h.service.SaveConsent(c.Request.Context(), userID, req.ClientID, req.Scope); err != nil {
+	// Save consent for the approved scopes
+	grantedScope, err := h.service.GrantConsent(c.Request.Context(), userID, authReq.ClientID, authReq.Scope, req.ApprovedScopes)
+	if err != nil {
 		c.Error(err)
 		return
 	}
 
-	// Create authorization request to retry
-	authReq := AuthorizeRequest{
-		ResponseType:        "code",
-		ClientID:            req.ClientID,
-		RedirectURI:         c.Query("redirect_uri"),
-		Scope:               req.Scope,
-		State:               c.Query("state"),
-		CodeChallenge:       c.Query("code_challenge"),
-		CodeChallengeMethod: c.Query("code_challenge_method"),
-	}
+	// Retry the authorization request with the granted scopes; prompt=consent has been met
+	authReq.Scope = grantedScope
+	authReq.ResponseMode = mode
+	authReq.Prompt = ""
 
-	code, err := h.service.Authorize(c.Request.Context(), authReq, userID)
+	authorized, err := h.service.Authorize(c.Request.Context(), authReq, userID, c.GetTime(middleware.ContextKeyAuthTime),
+		c.GetString(middleware.ContextKeySessionID), c.GetString(middleware.ContextKeyACR))
 	if err != nil {
 		c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"redirect": h.buildRedirectURL(authReq.RedirectURI, code, authReq.State),
-	})
+	result.Params = authorized.params()
+	c.JSON(http.StatusOK, result.consentResult())
 }
 
 // Helper methods
 
+// authenticateClient extracts and verifies the client credentials for a request.
+// The way the credentials are presented (Basic auth, request body, signed assertion, or client_id only)
+// must match the client's registered token_endpoint_auth_method.
+// On failure it writes an invalid_client error response and returns false. Once the client has
+// authenticated, its rate limit tier is applied, which may reject the request with 429.
+func (h *Handler) authenticateClient(c *gin.Context, req TokenRequest) (string, bool) {
+	creds, err := h.getClientCredentials(c, req)
+	if err != nil {
+		metrics.AuthenticationFailures.WithLabelValues(metrics.AuthFailureMalformedCredentials).Inc()
+		middleware.ClientAuthenticationFailed(c)
+		h.invalidClient(c, creds.Method)
+		return "", false
+	}
+
+	authenticated, err := h.service.AuthenticateClient(c.Request.Context(), creds)
+	if err != nil {
+		metrics.AuthenticationFailures.WithLabelValues(metrics.AuthFailureInvalidClient).Inc()
+		middleware.ClientAuthenticationFailed(c)
+		h.invalidClient(c, creds.Method)
+		return "", false
+	}
+
+	// Tiers and exemptions of the client apply only now that it has authenticated
+	if !middleware.ApplyClientRateLimits(c, authenticated.ClientID) {
+		return "", false
+	}
+
+	return authenticated.ClientID, true
+}
+
+// validateFormParams checks the parameters of a request the client posts directly, from both
+// the request URI and the body. On failure it writes the error response mapped by toResponse
+// and returns false.
+func (h *Handler) validateFormParams(c *gin.Context, params requestParams, toResponse func(error) ErrorResponse) bool {
+	if err := c.Request.ParseForm(); err != nil {
+		writeOAuthError(c, ErrorResponse{
+			Error:            errors.ErrMsgInvalidRequest,
+			ErrorDescription: "invalid request format",
+		})
+		return false
+	}
+
+	if err := params.validate(c.Request.Form, c.Request.URL.Query()); err != nil {
+		writeOAuthError(c, toResponse(err))
+		return false
+	}
+	return true
+}
+
+// getAccessToken extracts the access token from the Authorization header, sent with the
+// Bearer or the DPoP scheme. For POST requests it falls back to the access_token form
+// parameter, which is a bearer token. Returns the token and its scheme, or an empty
+// string if no token is present.
+func (h *Handler) getAccessToken(c *gin.Context) (string, string) {
+	authHeader := c.GetHeader(middleware.AuthHeaderName)
+	if authHeader != "" {
+		parts := strings.SplitN(authHeader, " ", 2)
+		if len(parts) != 2 {
+			return "", ""
+		}
+		switch {
+		case strings.EqualFold(parts[0], middleware.AuthHeaderPrefix):
+			return strings.TrimSpace(parts[1]), middleware.AuthHeaderPrefix
+		case strings.EqualFold(parts[0], HeaderDPoP):
+			return strings.TrimSpace(parts[1]), HeaderDPoP
+		}
+		return "", ""
+	}
+
+	if c.Request.Method == http.MethodPost {
+		return c.PostForm("access_token"), middleware.AuthHeaderPrefix
+	}
+
+	return "", ""
+}
+
+// dpopContext verifies the DPoP proof sent with a request to uri, if any, and returns the
+// request's context carrying the thumbprint of its key. When nonces are enabled, a fresh one
+// is sent in the DPoP-Nonce header of every response, so that a client whose proof lacked
+// one can retry with it (RFC 9449 Section 8).
+func (h *Handler) dpopContext(c *gin.Context, uri, accessToken string) (context.Context, error) {
+	ctx := c.Request.Context()
+	nonce, err := h.service.DPoPNonce()
+	if err != nil {
+		return ctx, err
+	}
+	if nonce != "" {
+		c.Header(HeaderDPoPNonce, nonce)
+	}
+
+	proofs := c.Request.Header.Values(HeaderDPoP)
+	switch len(proofs) {
+	case 0:
+		return ctx, nil
+	case 1:
+		return h.service.withDPoPProof(ctx, proofs[0], c.Request.Method, uri, accessToken)
+	default:
+		return ctx, invalidDPoPProof("only one DPoP proof may be sent")
+	}
+}
+
+// dpopChallenge writes the 401 response of a protected resource rejecting the DPoP proof of
+// a request, with a DPoP WWW-Authenticate challenge naming the error (RFC 9449 Section 7.1).
+func (h *Handler) dpopChallenge(c *gin.Context, err error) {
+	resp := tokenError(err)
+	if resp.Error != errors.ErrMsgInvalidDPoPProof && resp.Error != errors.ErrMsgUseDPoPNonce {
+		writeOAuthError(c, resp)
+		return
+	}
+
+	c.Header("WWW-Authenticate", `DPoP error="`+resp.Error+`", algs="`+strings.Join(jwtutil.DPoPProofAlgorithms(), " ")+`"`)
+	writeError(c, http.StatusUnauthorized, resp)
+}
+
 // getClientCredentials extracts client credentials from the request.
 // It first tries to get credentials from the Authorization header using HTTP Basic auth,
-// and falls back to form parameters if not found in the header.
-// Returns the client ID, client secret (may be empty for public clients), and any error that occurred.
-func (h *Handler) getClientCredentials(c *gin.Context, req TokenRequest) (string, string, error) {
+// then a client assertion, and falls back to form parameters if neither is present.
+// Returns the credentials, including the client authentication method they were presented
+// with, and any error that occurred. The method is set even on error so that failures can
+// be reported appropriately.
+func (h *Handler) getClientCredentials(c *gin.Context, req TokenRequest) (ClientCredentials, error) {
 	// Try Authorization header first
 	authHeader := c.GetHeader("Authorization")
 	if authHeader != "" && strings.HasPrefix(authHeader, "Basic ") {
+		creds := ClientCredentials{Method: client.AuthMethodClientSecretBasic}
+
 		credentials, err := base64.StdEncoding.DecodeString(authHeader[6:])
 		if err != nil {
-			return "", "", errors.BadRequest(errors.ErrMsgInvalidBasicAuthFormat)
+			return creds, errors.BadRequest(errors.ErrMsgInvalidBasicAuthFormat)
 		}
 
 		parts := strings.SplitN(string(credentials), ":", 2)
-		if len(parts) != 2 {
-			return "", "", errors.BadRequest(errors.ErrMsgInvalidBasicAuthFormat)
+		if len(parts) != 2 || parts[0] == "" {
+			return creds, errors.BadRequest(errors.ErrMsgInvalidBasicAuthFormat)
 		}
 
-		return parts[0], parts[1], nil
+		// RFC 6749 Section 2.3.1: credentials are form-urlencoded before Basic encoding
+		clientID, errID := url.QueryUnescape(parts[0])
+		clientSecret, errSecret := url.QueryUnescape(parts[1])
+		if errID != nil || errSecret != nil {
+			return creds, errors.BadRequest(errors.ErrMsgInvalidBasicAuthFormat)
+		}
+
+		creds.ClientID = clientID
+		creds.ClientSecret = clientSecret
+		return creds, nil
 	}
 
 	// Fall back to form parameters
-	clientID := req.ClientID
-	if clientID == "" {
-		clientID = c.PostForm("client_id")
+	creds := ClientCredentials{
+		ClientID:            req.ClientID,
+		ClientSecret:        req.ClientSecret,
+		ClientAssertionType: req.ClientAssertionType,
+		ClientAssertion:     req.ClientAssertion,
+	}
+	if creds.ClientID == "" {
+		creds.ClientID = c.PostForm("client_id")
+	}
+	if creds.ClientSecret == "" {
+		creds.ClientSecret = c.PostForm("client_secret")
 	}
+	if creds.ClientAssertionType == "" {
+		creds.ClientAssertionType = c.PostForm("client_assertion_type")
+	}
+	if creds.ClientAssertion == "" {
+		creds.ClientAssertion = c.PostForm("client_assertion")
+	}
+
+	switch {
+	case creds.ClientAssertionType != "" || creds.ClientAssertion != "":
+		creds.Method = client.AuthMethodPrivateKeyJWT
+
+		// An assertion replaces the secret; presenting both is ambiguous
+		if creds.ClientSecret != "" {
+			return creds, errors.BadRequest(errors.ErrMsgInvalidRequest)
+		}
 
-	clientSecret := req.ClientSecret
-	if clientSecret == "" {
-		clientSecret = c.PostForm("client_secret")
+		// RFC 7523 Section 3: client_id is optional, the assertion identifies the client
+		return creds, nil
+	case creds.ClientSecret != "":
+		creds.Method = client.AuthMethodClientSecretPost
+	default:
+		// Without a secret or an assertion, the client may authenticate with its TLS certificate
+		creds.Method = client.AuthMethodNone
+		creds.ClientCertificate = middleware.ClientCertificateFromContext(c.Request.Context())
 	}
 
-	if clientID == "" {
-		return "", "", errors.BadRequest(errors.ErrMsgMissingClientId)
+	if creds.ClientID == "" {
+		return creds, errors.BadRequest(errors.ErrMsgMissingClientId)
 	}
 
-	return clientID, clientSecret, nil
+	return creds, nil
 }
 
-// buildRedirectURL constructs the OAuth callback URL with authorization code and state parameters.
-// It handles adding the appropriate query string separator (? or &) depending on whether
-// the redirect URI already contains query parameters.
-func (h *Handler) buildRedirectURL(redirectURI, code, state string) string {
+// buildLoginURL constructs the URL of the login page with a return_to parameter that
+// resumes the authorization request once the user has logged in.
+// The login prompt and max_age are dropped from the resumed request so that the fresh
+// session satisfies it instead of sending the user back to the login page; in their place
+// login_after requires that session to have authenticated after now, so the session that was
+// turned down cannot resume the request unless the user logs in again.
+// A sanitized login hint is passed as login_hint for the login page to pre-fill the username.
+// It is dropped from the resumed request as well, so a user choosing to log in as someone
+// else is not sent back to the login page.
+func (h *Handler) buildLoginURL(c *gin.Context, loginHint string) string {
+	query := c.Request.URL.Query()
+	query.Del("max_age")
+	query.Del("login_hint")
+	query.Set(loginAfterParam, strconv.FormatInt(time.Now().Unix(), 10))
+
+	var prompts []string
+	for _, p := range strings.Fields(query.Get("prompt")) {
+		if p != PromptLogin {
+			prompts = append(prompts, p)
+		}
+	}
+	if len(prompts) > 0 {
+		query.Set("prompt", strings.Join(prompts, " "))
+	} else {
+		query.Del("prompt")
+	}
+
+	ctx := c.Request.Context()
+	returnTo := tenant.BaseURL(ctx) + c.Request.URL.Path + "?" + query.Encode()
+
+	loginURL := tenant.LoginURL(ctx)
 	separator := "?"
-	if strings.Contains(redirectURI, "?") {
+	if strings.Contains(loginURL, "?") {
 		separator = "&"
 	}
+	loginURL += separator + "return_to=" + url.QueryEscape(returnTo)
+
+	// The login page offers the ways of logging in that meet the requested authentication context
+	if acrValues := query.Get("acr_values"); acrValues != "" {
+		loginURL += "&acr_values=" + url.QueryEscape(acrValues)
+	}
+	if loginHint != "" {
+		loginURL += "&login_hint=" + url.QueryEscape(loginHint)
+	}
+	return loginURL
+}
+
+// buildConsentURL constructs the URL for the consent page. It carries the request_uri of the
+// held request, which the consent decision is applied to, and only the parameters the page
+// displays.
+func (h *Handler) buildConsentURL(ctx context.Context, req AuthorizeRequest, requestURI string) string {
+	params := []string{
+		"client_id=" + url.QueryEscape(req.ClientID),
+		"request_uri=" + url.QueryEscape(requestURI),
+		"scope=" + url.QueryEscape(req.Scope),
+		"state=" + url.QueryEscape(req.State),
+	}
+
+	if req.Prompt != "" {
+		params = append(params, "prompt="+url.QueryEscape(req.Prompt))
+	}
 
-	result := redirectURI + separator + "code=" + code
-	if state != "" {
-		result += "&state=" + state
+	if req.UILocales != "" {
+		params = append(params, "ui_locales="+url.QueryEscape(req.UILocales))
 	}
 
-	return result
+	return tenant.Path(ctx, "/oauth/consent") + "?" + strings.Join(params, "&")
 }
 
-// buildErrorRedirect constructs an OAuth error redirect URL according to the OAuth 2.0 specification.
-// It includes the error code, error description (with spaces replaced by '+'), and preserves the state parameter.
-func (h *Handler) buildErrorRedirect(redirectURI, state, errorCode, errorDesc string) string {
-	separator := "?"
-	if strings.Contains(redirectURI, "?") {
-		separator = "&"
+// ListAuthorizations returns the applications the authenticated user has authorized,
+// with the scopes granted to each and when it was last used.
+func (h *Handler) ListAuthorizations(c *gin.Context) {
+	userID := c.GetUint(middleware.ContextKeyUserID)
+
+	response, err := h.service.ListAuthorizations(c.Request.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// RevokeAuthorization withdraws the authenticated user's authorization of an application,
+// revoking every token the application holds for the user.
+func (h *Handler) RevokeAuthorization(c *gin.Context) {
+	userID := c.GetUint(middleware.ContextKeyUserID)
+
+	if err := h.service.RevokeAuthorization(c.Request.Context(), userID, c.Param("client_id")); err != nil {
+		c.Error(err)
+		return
 	}
 
-	result := redirectURI + separator + "error=" + errorCode
-	if errorDesc != "" {
-		result += "&error_description=" + strings.ReplaceAll(errorDesc, " ", "+")
+	c.Status(http.StatusNoContent)
+}
+
+// DebugToken decodes and validates a token for the logged-in user, returning its header and
+// claims and whether it verifies, has expired, or was revoked. Administrators may inspect any
+// token, other users only their own and those of the clients they own.
+//
+// Route: POST /debug/token
+// Request body:
+//   - token: The token to inspect
+func (h *Handler) DebugToken(c *gin.Context) {
+	var req TokenDebugRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.BadRequest(errors.ErrMsgInvalidTokenDebugBody))
+		return
 	}
-	if state != "" {
-		result += "&state=" + state
+
+	resp, err := h.service.DebugToken(c.Request.Context(), c.GetUint(middleware.ContextKeyUserID), req.Token)
+	if err != nil {
+		c.Error(err)
+		return
 	}
 
-	return result
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, resp)
 }
 
-// redirectError handles OAuth error responses according to the OAuth 2.0 specification.
-// If a valid redirect URI is provided, it redirects the client with error parameters in the query string.
-// If no redirect URI is available, it returns a JSON error response directly.
-func (h *Handler) redirectError(c *gin.Context, redirectURI, state, errorCode, errorDesc string) {
-	if redirectURI == "" {
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error:            errorCode,
-			ErrorDescription: errorDesc,
-		})
+// GetGrant implements the grant query of the grant management endpoint (FAPI Grant Management
+// Section 6.1). The authenticated client receives the scopes and claims of one of its grants.
+func (h *Handler) GetGrant(c *gin.Context) {
+	clientID, ok := h.authenticateClient(c, TokenRequest{})
+	if !ok {
+		return
+	}
+
+	response, err := h.service.GetGrant(c.Request.Context(), clientID, c.Param("grant_id"))
+	if err != nil {
+		c.Error(err)
 		return
 	}
 
-	c.Redirect(http.StatusFound, h.buildErrorRedirect(redirectURI, state, errorCode, errorDesc))
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, response)
 }
 
-// buildConsentURL constructs the URL for the consent page, preserving all the
-// parameters from the original authorization request to use after consent.
-// This ensures the OAuth flow can continue with the same parameters once
-// the user has provided their consent decision.
-func (h *Handler) buildConsentURL(req AuthorizeRequest) string {
-	params := []string{
-		"client_id=" + req.ClientID,
-		"redirect_uri=" + req.RedirectURI,
-		"scope=" + req.Scope,
-		"state=" + req.State,
+// RevokeGrant implements the grant revocation of the grant management endpoint (FAPI Grant
+// Management Section 6.2). The authenticated client revokes one of its grants and every token
+// issued under it.
+func (h *Handler) RevokeGrant(c *gin.Context) {
+	clientID, ok := h.authenticateClient(c, TokenRequest{})
+	if !ok {
+		return
 	}
 
-	if req.CodeChallenge != "" {
-		params = append(params, "code_challenge="+req.CodeChallenge)
-		params = append(params, "code_challenge_method="+req.CodeChallengeMethod)
+	if err := h.service.RevokeGrant(c.Request.Context(), clientID, c.Param("grant_id")); err != nil {
+		c.Error(err)
+		return
 	}
 
-	return "/oauth/consent?" + strings.Join(params, "&")
+	c.Status(http.StatusNoContent)
 }
@@ -0,0 +1,96 @@
+package oauth
+
+import (
+	"context"
+	"strings"
+
+	"github.com/verigate/verigate-server/internal/app/audit"
+	"github.com/verigate/verigate-server/internal/app/client"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+)
+
+// Values a response type combines (OpenID Connect Core Section 3.3)
+const (
+	responseTypeIDToken = "id_token" // An ID token issued from the authorization endpoint
+	responseTypeToken   = "token"    // An access token issued from the authorization endpoint
+)
+
+// supportedResponseTypes are the response types the authorization endpoint answers with,
+// the code flow and the hybrid flows ending in the code flow
+var supportedResponseTypes = []string{
+	client.ResponseTypeCode,
+	client.ResponseTypeCodeIDToken,
+	client.ResponseTypeCodeToken,
+	client.ResponseTypeCodeIDTokenToken,
+}
+
+// isSupportedResponseType reports whether the authorization endpoint answers with the response
+// type, whatever order its values are sent in.
+func isSupportedResponseType(responseType string) bool {
+	return containsScope(supportedResponseTypes, client.NormalizeResponseType(responseType))
+}
+
+// hasResponseTypeValue reports whether a response type combines the value.
+func hasResponseTypeValue(responseType, value string) bool {
+	return containsScope(strings.Fields(responseType), value)
+}
+
+// issueFrontChannelTokens issues the tokens a hybrid response type returns from the authorization
+// endpoint with the code: an access token for token, without a refresh token (RFC 6749
+// Section 4.2.2), and an ID token for id_token carrying the hashes of the code and the access
+// token. The access token is linked to the code, so it is revoked if the code is replayed.
+// The code response type issues nothing here.
+func (s *Service) issueFrontChannelTokens(ctx context.Context, responseType string, authCode *AuthorizationCode, result *AuthorizationResult) error {
+	withToken := hasResponseTypeValue(responseType, responseTypeToken)
+	withIDToken := hasResponseTypeValue(responseType, responseTypeIDToken)
+	if !withToken && !withIDToken {
+		return nil
+	}
+
+	opts, err := s.accessTokenOptions(ctx, GrantTypeAuthorizationCode, authCode.ClientID, authCode.UserID, authCode.Scope, authCode.Resources, nil)
+	if err != nil {
+		return err
+	}
+	opts.AuthTime = authCode.AuthTime
+	opts.ACR = authCode.ACR
+
+	if withToken {
+		opts.WithoutRefreshToken = true
+		tokenResp, err := s.tokenService.CreateTokens(ctx, authCode.UserID, authCode.ClientID, authCode.Scope, authCode.Code, opts)
+		if err != nil {
+			return err
+		}
+		result.AccessToken = tokenResp.AccessToken
+		result.TokenType = tokenResp.TokenType
+		result.ExpiresIn = tokenResp.ExpiresIn
+		result.Scope = tokenResp.Scope
+
+		s.auditService.Record(ctx, audit.Event{
+			Type:     audit.EventTokenIssued,
+			Actor:    audit.UserActor(authCode.UserID),
+			ClientID: authCode.ClientID,
+			Details: map[string]string{
+				"response_type":     client.NormalizeResponseType(responseType),
+				"scope":             tokenResp.Scope,
+				"access_token_hash": audit.HashValue(tokenResp.AccessToken),
+			},
+		})
+	}
+
+	if withIDToken {
+		idToken, err := s.createIDToken(ctx, authCode, opts.Lifetimes.IDToken, authCode.Code, result.AccessToken)
+		if err != nil {
+			return errors.Internal(errors.ErrMsgFailedToGenerateIDToken).Wrap(err)
+		}
+		result.IDToken = idToken
+
+		// Remember the session so the client is told when it ends
+		if authCode.SessionID != "" {
+			if err := s.logoutService.RecordSession(ctx, authCode.ClientID, authCode.UserID, authCode.SessionID); err != nil {
+				// Not critical, continue
+			}
+		}
+	}
+
+	return nil
+}
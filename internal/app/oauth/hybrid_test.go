@@ -0,0 +1,93 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/verigate/verigate-server/internal/app/client"
+	"github.com/verigate/verigate-server/internal/pkg/config"
+	"github.com/verigate/verigate-server/internal/pkg/utils/encryption"
+	jwtutil "github.com/verigate/verigate-server/internal/pkg/utils/jwt"
+)
+
+// sha256HalfHash computes an at_hash or c_hash for the algorithms hashing with SHA-256,
+// independently of the signer that computes them for the ID token.
+func sha256HalfHash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+}
+
+func TestFrontChannelIDTokenHashes(t *testing.T) {
+	// HS256 ID tokens are signed with the client secret, kept sealed with this key
+	secretKey := make([]byte, encryption.KeySize)
+	if _, err := rand.Read(secretKey); err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	previous := config.AppConfig.ClientSecretEncryptionKey
+	config.AppConfig.ClientSecretEncryptionKey = base64.StdEncoding.EncodeToString(secretKey)
+	t.Cleanup(func() { config.AppConfig.ClientSecretEncryptionKey = previous })
+
+	sealedSecret, err := encryption.Encrypt(secretKey, "client-secret-of-at-least-32-bytes")
+	if err != nil {
+		t.Fatalf("failed to seal client secret: %v", err)
+	}
+
+	const redirectURI = "https://app.example.com/callback"
+
+	for _, alg := range []string{jwtutil.SigningAlgRS256, jwtutil.SigningAlgES256, jwtutil.SigningAlgHS256} {
+		t.Run(alg, func(t *testing.T) {
+			s := newTestService(t)
+			ctx := context.Background()
+
+			c := &client.Client{
+				ClientID:                 "client-a",
+				ClientName:               "client-a",
+				RedirectURIs:             []string{redirectURI},
+				GrantTypes:               []string{GrantTypeAuthorizationCode},
+				Scope:                    "openid profile",
+				IsConfidential:           true,
+				IsActive:                 true,
+				IDTokenSignedResponseAlg: alg,
+			}
+			if alg == jwtutil.SigningAlgHS256 {
+				c.IDTokenSigningSecret = sealedSecret
+			}
+			if err := s.clients.Save(ctx, c); err != nil {
+				t.Fatalf("failed to save client: %v", err)
+			}
+			authCode := s.addCode(t, "code-1", "client-a", redirectURI, "openid profile")
+
+			result := &AuthorizationResult{Code: authCode.Code}
+			if err := s.issueFrontChannelTokens(ctx, client.ResponseTypeCodeIDTokenToken, authCode, result); err != nil {
+				t.Fatalf("issueFrontChannelTokens failed: %v", err)
+			}
+			if result.AccessToken == "" || result.IDToken == "" {
+				t.Fatalf("got access token %q and ID token %q, want both", result.AccessToken, result.IDToken)
+			}
+
+			signer, err := s.clientService.IDTokenSigner(c)
+			if err != nil {
+				t.Fatalf("IDTokenSigner failed: %v", err)
+			}
+			claims := jwt.MapClaims{}
+			token, err := signer.ParseIgnoringTime(ctx, result.IDToken, claims)
+			if err != nil {
+				t.Fatalf("failed to verify ID token: %v", err)
+			}
+			if token.Method.Alg() != alg {
+				t.Errorf("ID token signed with %s, want %s", token.Method.Alg(), alg)
+			}
+
+			if got, want := claims[jwtutil.ClaimKeyCHash], sha256HalfHash(result.Code); got != want {
+				t.Errorf("got c_hash %v, want %q for the co-issued code", got, want)
+			}
+			if got, want := claims[jwtutil.ClaimKeyAtHash], sha256HalfHash(result.AccessToken); got != want {
+				t.Errorf("got at_hash %v, want %q for the co-issued access token", got, want)
+			}
+		})
+	}
+}
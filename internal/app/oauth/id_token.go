@@ -0,0 +1,139 @@
+package oauth
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/app/client"
+	jwtutil "github.com/verigate/verigate-server/internal/pkg/utils/jwt"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// createIDToken issues an OpenID Connect ID token (Core Section 2) for the authorization code.
+// The auth_time claim reports when the user authenticated in the session that approved the request,
+// so relying parties can enforce their own max_age, and the sid claim identifies that session
+// so a later back-channel logout can name it. The acr and amr claims report the authentication
+// context the session met and how the user logged in, when known. The nonce of the authorization
+// request is returned unchanged for the client to check. The sub claim is the subject the client knows the user by.
+// Clients that registered ID token encryption receive the signed token as a nested JWT
+// encrypted to their public key (Core Section 10.2); others receive it only signed.
+// Claims requested in the ID token with the claims parameter are added if a granted scope releases them.
+// An ID token issued from the authorization endpoint alongside the code or an access token carries
+// their hashes in c_hash and at_hash (Core Section 3.3.2.11); either is left out when empty.
+// The custom claims of the client's claim templates never replace the claims set here.
+func (s *Service) createIDToken(ctx context.Context, authCode *AuthorizationCode, expiry time.Duration, code, accessToken string) (string, error) {
+	now := time.Now()
+
+	authTime := authCode.AuthTime
+	if authTime.IsZero() {
+		authTime = authCode.CreatedAt
+	}
+
+	c, err := s.clientService.GetByClientID(ctx, authCode.ClientID)
+	if err != nil {
+		return "", err
+	}
+
+	subject := strconv.FormatUint(uint64(authCode.UserID), 10)
+	if c != nil {
+		subject = c.Subject(authCode.UserID)
+	}
+
+	claims := jwt.MapClaims{
+		jwtutil.ClaimKeyISS:      jwtutil.Issuer(ctx),
+		jwtutil.ClaimKeySub:      subject,
+		jwtutil.ClaimKeyAud:      authCode.ClientID,
+		jwtutil.ClaimKeyIAT:      now.Unix(),
+		jwtutil.ClaimKeyEXP:      now.Add(expiry).Unix(),
+		jwtutil.ClaimKeyAuthTime: authTime.Unix(),
+	}
+	if authCode.SessionID != "" {
+		claims[jwtutil.ClaimKeySessionID] = authCode.SessionID
+	}
+	if authCode.Nonce != "" {
+		claims[jwtutil.ClaimKeyNonce] = authCode.Nonce
+	}
+	if authCode.ACR != "" {
+		claims[jwtutil.ClaimKeyACR] = authCode.ACR
+	}
+	if len(authCode.AMR) > 0 {
+		claims[jwtutil.ClaimKeyAMR] = authCode.AMR
+	}
+	if err := s.addRequestedIDTokenClaims(ctx, claims, authCode); err != nil {
+		return "", err
+	}
+	if c != nil {
+		custom, err := s.customClaims(ctx, c, client.ClaimTemplateTokenID, GrantTypeAuthorizationCode, authCode.UserID, authCode.Scope)
+		if err != nil {
+			return "", err
+		}
+		for name, value := range custom {
+			if _, set := claims[name]; !set {
+				claims[name] = value
+			}
+		}
+	}
+
+	signer := jwtutil.DefaultSigner()
+	if c != nil {
+		if signer, err = s.clientService.IDTokenSigner(c); err != nil {
+			return "", err
+		}
+	}
+
+	// The hashes use the hash function of the algorithm the ID token is signed with
+	if code != "" {
+		claims[jwtutil.ClaimKeyCHash] = signer.HalfHash(code)
+	}
+	if accessToken != "" {
+		claims[jwtutil.ClaimKeyAtHash] = signer.HalfHash(accessToken)
+	}
+
+	signed, err := signer.Sign(ctx, claims, "JWT")
+	if err != nil {
+		return "", err
+	}
+	if c == nil {
+		return signed, nil
+	}
+
+	alg, enc := c.IDTokenEncryption()
+	if alg == "" {
+		return signed, nil
+	}
+
+	key, err := s.clientService.EncryptionKey(ctx, c, alg)
+	if err != nil {
+		return "", err
+	}
+
+	return jwtutil.EncryptJWT(signed, key, alg, enc)
+}
+
+// addRequestedIDTokenClaims adds the user claims that the claims parameter of the authorization
+// request asked for in the ID token (OpenID Connect Core Section 5.5). Claims no granted scope
+// releases are left out, as are claims the ID token already has, which the request cannot override.
+func (s *Service) addRequestedIDTokenClaims(ctx context.Context, claims jwt.MapClaims, authCode *AuthorizationCode) error {
+	if len(authCode.IDTokenClaims) == 0 {
+		return nil
+	}
+
+	user, err := s.userService.GetByID(ctx, authCode.UserID)
+	if err != nil {
+		return err
+	}
+
+	released := s.claimRegistry.Claims(strings.Fields(authCode.Scope), user)
+	for _, name := range authCode.IDTokenClaims {
+		if _, set := claims[name]; set {
+			continue
+		}
+		if value, ok := released[name]; ok {
+			claims[name] = value
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package oauth
+
+import (
+	"context"
+	"strings"
+	"unicode"
+)
+
+// maxLoginHintLength bounds the login hints passed on, the longest email address allowed
+const maxLoginHintLength = 254
+
+// sanitizeLoginHint returns the login_hint of an authorization request in the form it is passed
+// to the login page to pre-fill the username, or empty when it is to be ignored. Hints are
+// usernames, email addresses, or subject identifiers, so only letters, digits, and the
+// punctuation of those are accepted; a hint with anything else, such as markup, is dropped
+// as a whole rather than altered into the name of another user.
+func sanitizeLoginHint(hint string) string {
+	hint = strings.TrimSpace(hint)
+	if len(hint) > maxLoginHintLength {
+		return ""
+	}
+	for _, r := range hint {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune(".-_@+", r) {
+			return ""
+		}
+	}
+	return hint
+}
+
+// SessionMatchesLoginHint reports whether the user of the current session is the one the
+// client's login_hint names: by username, by email address, or by the subject the client
+// knows the user by. A session of another user does not satisfy the request, so the user is
+// sent to log in as the hinted user instead. The hint never authenticates anyone on its own.
+func (s *Service) SessionMatchesLoginHint(ctx context.Context, clientID string, userID uint, hint string) (bool, error) {
+	u, err := s.userService.GetByID(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if strings.EqualFold(u.Username, hint) || strings.EqualFold(u.Email, hint) {
+		return true, nil
+	}
+
+	c, err := s.clientService.GetByClientID(ctx, clientID)
+	if err != nil {
+		return false, err
+	}
+	return c != nil && c.Subject(userID) == hint, nil
+}
@@ -0,0 +1,110 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+	jwtutil "github.com/verigate/verigate-server/internal/pkg/utils/jwt"
+)
+
+// MediaTypeManagedState is the "typ" header of the state tokens issued to managed_state clients
+const MediaTypeManagedState = "state+jwt"
+
+// managedStateExpiry is how long a state token is valid, the lifetime of the code it is issued with
+const managedStateExpiry = 10 * time.Minute
+
+// managedStateClaims are the claims of the state the server issues to a managed_state client.
+// The token is signed with the server's signing key, so the client can verify it against the
+// JWKS on its callback, and travels back with the code exchange, where the server checks it
+// was issued with that code, in the same session, for the same redirect and nonce.
+type managedStateClaims struct {
+	CodeHash    string `json:"c_hash"`            // Left half of the SHA-256 of the authorization code, base64url-encoded
+	SessionID   string `json:"sid"`               // Web session the code was issued in
+	RedirectURI string `json:"redirect_uri"`      // Redirect URI of the authorization request
+	Nonce       string `json:"nonce,omitempty"`   // Nonce of the authorization request
+	Context     string `json:"context,omitempty"` // State the client sent, returned to it inside the token
+	jwt.RegisteredClaims
+}
+
+// authorizationState returns the state to send back with an authorization code. Clients
+// without managed_state get their own state back unchanged. Managed_state clients receive
+// a signed state token instead, with the state they sent carried inside it as context.
+func (s *Service) authorizationState(ctx context.Context, code *AuthorizationCode, clientState string) (string, error) {
+	c, err := s.clientService.GetByClientID(ctx, code.ClientID)
+	if err != nil {
+		return "", err
+	}
+	if c == nil || !c.ManagedState {
+		return clientState, nil
+	}
+
+	now := time.Now()
+	claims := managedStateClaims{
+		CodeHash:    codeHash(code.Code),
+		SessionID:   code.SessionID,
+		RedirectURI: code.RedirectURI,
+		Nonce:       code.Nonce,
+		Context:     clientState,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    jwtutil.Issuer(ctx),
+			Audience:  jwt.ClaimStrings{code.ClientID},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(managedStateExpiry)),
+			ID:        uuid.NewString(),
+		},
+	}
+
+	state, err := jwtutil.SignTokenWithType(ctx, claims, MediaTypeManagedState)
+	if err != nil {
+		return "", errors.Internal(errors.ErrMsgFailedToIssueManagedState)
+	}
+	return state, nil
+}
+
+// verifyManagedState checks the state a managed_state client presents with an authorization
+// code: it must be an unexpired state token issued to the client together with that code,
+// in the session the code was issued in, so a state from one user's flow cannot be replayed
+// in another's. The state of other clients is not checked.
+func (s *Service) verifyManagedState(ctx context.Context, code *AuthorizationCode, state string) error {
+	c, err := s.clientService.GetByClientID(ctx, code.ClientID)
+	if err != nil {
+		return err
+	}
+	if c == nil || !c.ManagedState {
+		return nil
+	}
+
+	invalid := errors.BadRequest(errors.ErrMsgInvalidGrant).WithDetails(errors.ErrMsgInvalidManagedState)
+	if state == "" {
+		return invalid
+	}
+
+	var claims managedStateClaims
+	token, err := jwtutil.ParseToken(ctx, state, &claims)
+	if err != nil {
+		return invalid
+	}
+	if typ, _ := token.Header[jwtutil.HeaderType].(string); typ != MediaTypeManagedState {
+		return invalid
+	}
+	if claims.Issuer != jwtutil.Issuer(ctx) || !claims.VerifyAudience(code.ClientID, true) {
+		return invalid
+	}
+	if claims.CodeHash != codeHash(code.Code) || claims.SessionID != code.SessionID ||
+		claims.RedirectURI != code.RedirectURI || claims.Nonce != code.Nonce {
+		return invalid
+	}
+	return nil
+}
+
+// codeHash returns the base64url encoding of the left half of the SHA-256 hash of an
+// authorization code, computed like the c_hash of OpenID Connect Core Section 3.3.2.11.
+func codeHash(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+}
@@ -0,0 +1,135 @@
+package oauth
+
+import (
+	"context"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/app/client"
+	"github.com/verigate/verigate-server/internal/pkg/config"
+	"github.com/verigate/verigate-server/internal/pkg/tenant"
+	jwtutil "github.com/verigate/verigate-server/internal/pkg/utils/jwt"
+	"github.com/verigate/verigate-server/internal/pkg/utils/pkce"
+)
+
+// Server metadata settings (RFC 8414)
+const (
+	AuthorizationEndpointPath       = "/api/v1/oauth/authorize"            // Path of the authorization endpoint
+	UserInfoEndpointPath            = "/api/v1/oauth/userinfo"             // Path of the UserInfo endpoint
+	RevocationEndpointPath          = "/api/v1/oauth/revoke"               // Path of the revocation endpoint
+	IntrospectionEndpointPath       = "/api/v1/oauth/introspect"           // Path of the introspection endpoint
+	DeviceAuthorizationEndpointPath = "/api/v1/oauth/device_authorization" // Path of the device authorization endpoint
+	EndSessionEndpointPath          = "/api/v1/oauth/logout"               // Path of the end session endpoint
+	GrantManagementEndpointPath     = "/api/v1/oauth/grants"               // Path of the grant management endpoint
+	JWKSPath                        = "/.well-known/jwks.json"             // Path of the JSON Web Key Set
+
+	signingAlg          = "RS256"   // Algorithm of the tokens this server signs other than client-specific ID tokens
+	metadataCacheMaxAge = time.Hour // How long clients may cache the metadata document
+)
+
+// supportedGrantTypes lists the grant types the token endpoint implements, in advertised order
+var supportedGrantTypes = []string{
+	GrantTypeAuthorizationCode,
+	GrantTypeRefreshToken,
+	GrantTypeClientCredentials,
+	GrantTypeDeviceCode,
+	GrantTypeTokenExchange,
+}
+
+// isGrantTypeEnabled reports whether the grant type is enabled in the application configuration.
+func isGrantTypeEnabled(grantType string) bool {
+	return containsScope(config.AppConfig.EnabledGrantTypes, grantType)
+}
+
+// Metadata builds the authorization server metadata document (RFC 8414 Section 2), which also
+// serves as the OpenID Provider metadata (OpenID Connect Discovery Section 3). It reflects the
+// current configuration: disabled grant types and the endpoints only they use are left out,
+// the scopes are the registered ones, and pairwise subjects are only advertised when they can
+// be issued. The issuer is the iss value of every token the server issues.
+func (s *Service) Metadata(ctx context.Context) (*ServerMetadata, error) {
+	scopes, err := s.scopeService.GetAllScopes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := tenant.BaseURL(ctx)
+	clientAuthMethods := []string{
+		client.AuthMethodClientSecretBasic,
+		client.AuthMethodClientSecretPost,
+		client.AuthMethodPrivateKeyJWT,
+		client.AuthMethodTLSClientAuth,
+		client.AuthMethodSelfSignedTLSClientAuth,
+		client.AuthMethodNone,
+	}
+
+	metadata := &ServerMetadata{
+		Issuer:                            jwtutil.Issuer(ctx),
+		TokenEndpoint:                     TokenEndpointURL(ctx),
+		UserInfoEndpoint:                  baseURL + UserInfoEndpointPath,
+		JWKSURI:                           baseURL + JWKSPath,
+		RegistrationEndpoint:              baseURL + client.RegistrationPath,
+		RevocationEndpoint:                baseURL + RevocationEndpointPath,
+		IntrospectionEndpoint:             baseURL + IntrospectionEndpointPath,
+		EndSessionEndpoint:                baseURL + EndSessionEndpointPath,
+		ScopesSupported:                   []string{},
+		ResponseTypesSupported:            []string{},
+		GrantTypesSupported:               []string{},
+		TokenEndpointAuthMethodsSupported: clientAuthMethods,
+		TokenEndpointAuthSigningAlgValuesSupported: []string{signingAlg},
+		RevocationEndpointAuthMethodsSupported:     clientAuthMethods,
+		IntrospectionEndpointAuthMethodsSupported:  clientAuthMethods,
+		SubjectTypesSupported:                      []string{client.SubjectTypePublic},
+		IDTokenSigningAlgValuesSupported:           client.SupportedIDTokenSigningAlgorithms(),
+		IDTokenEncryptionAlgValuesSupported:        []string{jwtutil.JWEAlgRSAOAEP, jwtutil.JWEAlgRSAOAEP256},
+		IDTokenEncryptionEncValuesSupported:        []string{jwtutil.JWEEncA128CBCHS256},
+		UserInfoSigningAlgValuesSupported:          client.SupportedUserInfoSigningAlgorithms(),
+		UserInfoEncryptionAlgValuesSupported:       jwtutil.SupportedJWEAlgorithms(),
+		UserInfoEncryptionEncValuesSupported:       jwtutil.SupportedJWEEncryptions(),
+		BackchannelLogoutSupported:                 true,
+		BackchannelLogoutSessionSupported:          true,
+		FrontchannelLogoutSupported:                true,
+		FrontchannelLogoutSessionSupported:         true,
+		ACRValuesSupported:                         supportedACRValues(),
+		ClaimsParameterSupported:                   true,
+		TLSClientCertificateBoundAccessTokens:      true,
+		DPoPSigningAlgValuesSupported:              jwtutil.DPoPProofAlgorithms(),
+	}
+
+	for _, sc := range scopes {
+		metadata.ScopesSupported = append(metadata.ScopesSupported, sc.Name)
+	}
+
+	for _, grantType := range supportedGrantTypes {
+		if isGrantTypeEnabled(grantType) {
+			metadata.GrantTypesSupported = append(metadata.GrantTypesSupported, grantType)
+		}
+	}
+
+	// The authorization endpoint only issues codes
+	if isGrantTypeEnabled(GrantTypeAuthorizationCode) {
+		metadata.AuthorizationEndpoint = baseURL + AuthorizationEndpointPath
+		metadata.PushedAuthorizationRequestEndpoint = baseURL + PushedAuthorizationRequestEndpointPath
+		metadata.ResponseTypesSupported = supportedResponseTypes
+		metadata.ResponseModesSupported = []string{ResponseModeQuery, ResponseModeFragment, ResponseModeFormPost}
+		metadata.PromptValuesSupported = []string{PromptNone, PromptLogin, PromptConsent}
+		metadata.CodeChallengeMethodsSupported = []string{pkce.MethodS256, pkce.MethodPlain}
+		metadata.RequestParameterSupported = true
+		metadata.RequestURIParameterSupported = true
+		metadata.RequireRequestURIRegistration = true
+		metadata.RequestObjectSigningAlgValuesSupported = []string{signingAlg}
+		metadata.GrantManagementEndpoint = baseURL + GrantManagementEndpointPath
+		metadata.GrantManagementActionsSupported = []string{
+			GrantManagementActionCreate,
+			GrantManagementActionUpdate,
+			GrantManagementActionReplace,
+		}
+	}
+	if isGrantTypeEnabled(GrantTypeDeviceCode) {
+		metadata.DeviceAuthorizationEndpoint = baseURL + DeviceAuthorizationEndpointPath
+	}
+
+	if config.AppConfig.PairwiseSubjectSalt != "" {
+		metadata.SubjectTypesSupported = append(metadata.SubjectTypesSupported, client.SubjectTypePairwise)
+	}
+
+	return metadata, nil
+}
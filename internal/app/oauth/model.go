@@ -21,15 +21,70 @@ type AuthorizationCode struct {
 	ExpiresAt           time.Time `json:"expires_at"`                      // Expiration timestamp
 	CreatedAt           time.Time `json:"created_at"`                      // Creation timestamp
 	IsUsed              bool      `json:"is_used"`                         // Whether the code has been used
+	AuthTime            time.Time `json:"auth_time"`                       // When the user authenticated in the session that issued the code
+	Resources           []string  `json:"resources,omitempty"`             // Resource indicators granted with the code (RFC 8707)
+	SessionID           string    `json:"session_id,omitempty"`            // Web session that issued the code, reported as the ID token sid
+	Nonce               string    `json:"nonce,omitempty"`                 // Nonce of the authorization request, copied into the ID token
+	ACR                 string    `json:"acr,omitempty"`                   // Authentication context class the session met, reported as the ID token acr
+	AMR                 []string  `json:"amr,omitempty"`                   // How the user authenticated, reported as the ID token amr
+	IDTokenClaims       []string  `json:"id_token_claims,omitempty"`       // Claims the claims parameter requested in the ID token
+	ReplayDetected      bool      `json:"replay_detected"`                 // Whether the code was presented again after being used
 }
 
 // UserConsent represents a user's explicit permission for an OAuth client
 // to access specific resources on their behalf with defined scopes.
 type UserConsent struct {
+	ID         uint       `json:"id"`                     // Primary key
+	UserID     uint       `json:"user_id"`                // User who granted consent
+	ClientID   string     `json:"client_id"`              // OAuth client receiving consent
+	Scope      string     `json:"scope"`                  // Space-separated list of approved scopes
+	CreatedAt  time.Time  `json:"created_at"`             // When consent was first granted
+	UpdatedAt  time.Time  `json:"updated_at"`             // When consent was last updated
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"` // When tokens were last issued under the consent, nil if never
+}
+
+// Grant management actions a client can request at the token endpoint (FAPI Grant Management)
+const (
+	GrantManagementActionCreate  = "create"  // Issue the tokens under a new grant
+	GrantManagementActionUpdate  = "update"  // Merge the authorization into an existing grant
+	GrantManagementActionReplace = "replace" // Replace an existing grant with the authorization
+)
+
+// Grant represents the authorization a user has given a client, managed by the client through
+// FAPI Grant Management. It outlives the tokens issued under it: refresh tokens reference it by
+// GrantID, and revoking the grant revokes them all.
+type Grant struct {
 	ID        uint      `json:"id"`         // Primary key
-	UserID    uint      `json:"user_id"`    // User who granted consent
-	ClientID  string    `json:"client_id"`  // OAuth client receiving consent
-	Scope     string    `json:"scope"`      // Space-separated list of approved scopes
-	CreatedAt time.Time `json:"created_at"` // When consent was first granted
-	UpdatedAt time.Time `json:"updated_at"` // When consent was last updated
+	GrantID   string    `json:"grant_id"`   // Opaque identifier the client references the grant by
+	ClientID  string    `json:"client_id"`  // Client the grant was given to
+	UserID    uint      `json:"user_id"`    // User who gave the grant
+	Scope     string    `json:"scope"`      // Space-separated list of granted scopes
+	Claims    []string  `json:"claims"`     // Claims granted through the claims parameter
+	Resources []string  `json:"resources"`  // Resource indicators granted (RFC 8707)
+	CreatedAt time.Time `json:"created_at"` // When the grant was created
+	UpdatedAt time.Time `json:"updated_at"` // When the grant was last updated or replaced
+}
+
+// Device code status values
+const (
+	DeviceCodeStatusPending  = "pending"  // Waiting for the user to approve or deny
+	DeviceCodeStatusApproved = "approved" // Approved by the user, not yet exchanged
+	DeviceCodeStatusDenied   = "denied"   // Denied by the user
+	DeviceCodeStatusConsumed = "consumed" // Exchanged for tokens
+)
+
+// DeviceCode represents a pending device authorization request (RFC 8628).
+// The device polls the token endpoint with the device code while the user
+// enters the user code on a separate device to approve the request.
+type DeviceCode struct {
+	ID           uint      `json:"id"`            // Primary key
+	DeviceCode   string    `json:"device_code"`   // Code the device uses to poll the token endpoint
+	UserCode     string    `json:"user_code"`     // Short code the user enters on the verification page
+	ClientID     string    `json:"client_id"`     // Client that requested authorization
+	Scope        string    `json:"scope"`         // Space-separated list of requested scopes
+	UserID       uint      `json:"user_id"`       // User who approved or denied the request, zero while pending
+	Status       string    `json:"status"`        // Current status of the request
+	PollInterval int       `json:"poll_interval"` // Minimum seconds between token endpoint polls
+	ExpiresAt    time.Time `json:"expires_at"`    // Expiration timestamp
+	CreatedAt    time.Time `json:"created_at"`    // Creation timestamp
 }
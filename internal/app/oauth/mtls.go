@@ -0,0 +1,108 @@
+package oauth
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/app/client"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+	jwtutil "github.com/verigate/verigate-server/internal/pkg/utils/jwt"
+)
+
+// loadClientCAs reads the CAs trusted to issue certificates to tls_client_auth clients from a
+// PEM bundle. Without a path it returns nil, so certificates are verified against the system roots.
+func loadClientCAs(path string) (*x509.CertPool, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	bundle, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(bundle) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+// authenticateClientCertificate authenticates a client with the certificate it presented for
+// mutual TLS (RFC 8705 Section 2). A tls_client_auth client must present a certificate that chains
+// to a trusted CA, is valid for client authentication, and has the registered subject DN.
+// A self_signed_tls_client_auth client must present one of the certificates registered in its JWKS.
+// Clients registered for another method are authenticated as having sent no credentials, so a
+// public client whose TLS stack happens to present a certificate is not turned away.
+func (s *Service) authenticateClientCertificate(ctx context.Context, creds ClientCredentials) (*client.Client, error) {
+	c, err := s.clientService.GetByClientID(ctx, creds.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, errors.Unauthorized(errors.ErrMsgInvalidClientCredentials)
+	}
+	if !client.IsTLSClientAuthMethod(c.TokenEndpointAuthMethod) {
+		return s.clientService.AuthenticateClient(ctx, creds.ClientID, "", client.AuthMethodNone)
+	}
+	if !c.IsActive {
+		return nil, errors.Unauthorized(errors.ErrMsgClientNotActive)
+	}
+
+	cert := creds.ClientCertificate
+	if c.TokenEndpointAuthMethod == client.AuthMethodTLSClientAuth {
+		if !s.isTrustedClientCertificate(cert, c.TLSClientAuthSubjectDN) {
+			return nil, errors.Unauthorized(errors.ErrMsgInvalidClientCredentials)
+		}
+		return c, nil
+	}
+
+	keys, err := s.clientService.VerificationKeys(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	if !isRegisteredCertificate(keys, cert) {
+		return nil, errors.Unauthorized(errors.ErrMsgInvalidClientCredentials)
+	}
+	return c, nil
+}
+
+// isTrustedClientCertificate reports whether a CA-issued certificate identifies the client
+// registered with subjectDN (RFC 8705 Section 2.1). The DN is compared case-insensitively in
+// its RFC 4514 string form, and the certificate must chain to a trusted CA for client authentication.
+func (s *Service) isTrustedClientCertificate(cert *x509.Certificate, subjectDN string) bool {
+	if !strings.EqualFold(cert.Subject.String(), strings.TrimSpace(subjectDN)) {
+		return false
+	}
+
+	_, err := cert.Verify(x509.VerifyOptions{
+		Roots:     s.clientCAs,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	return err == nil
+}
+
+// isRegisteredCertificate reports whether a self-signed certificate is the first certificate of
+// the x5c chain of one of the client's keys (RFC 8705 Section 2.2) and is within its validity period.
+func isRegisteredCertificate(keys jwtutil.JWKSet, cert *x509.Certificate) bool {
+	now := time.Now()
+	if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return false
+	}
+
+	for _, key := range keys.Keys {
+		if len(key.X5c) == 0 {
+			continue
+		}
+		der, err := base64.StdEncoding.DecodeString(key.X5c[0])
+		if err == nil && bytes.Equal(der, cert.Raw) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+)
+
+// Pushed authorization request settings (RFC 9126)
+const (
+	PushedAuthorizationRequestEndpointPath = "/api/v1/oauth/par"                  // Path of the pushed authorization request endpoint
+	PushedRequestURIPrefix                 = "urn:ietf:params:oauth:request_uri:" // Prefix of the request_uri values issued for pushed requests
+
+	pushedRequestHandleBytes = 32               // Random bytes in a pushed request handle
+	consentRequestTTL        = 10 * time.Minute // How long a request waits for the user's consent
+)
+
+// PushAuthorizationRequest stores the authorization request pushed by an authenticated
+// client and returns the request_uri the client then sends to the authorization endpoint
+// (RFC 9126 Section 2). The parameters, including those of a request object, are validated
+// now so that errors reach the client directly rather than the user's browser. A pushed
+// request may not itself refer to a request_uri, and its client_id must be the
+// authenticated client. The stored request expires after the configured TTL.
+func (s *Service) PushAuthorizationRequest(ctx context.Context, clientID string, req AuthorizeRequest) (*PushedAuthorizationResponse, error) {
+	if req.ClientID != clientID {
+		return nil, errors.BadRequest(errors.ErrMsgInvalidRequest).WithDetails("client_id does not match the authenticated client")
+	}
+	if req.RequestURI != "" {
+		return nil, errors.BadRequest(errors.ErrMsgInvalidRequest).WithDetails("request_uri cannot be pushed")
+	}
+
+	c, err := s.clientService.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil || !c.IsActive {
+		return nil, errors.BadRequest(errors.ErrMsgInvalidClient)
+	}
+
+	req, err = s.applyRequestObject(ctx, c, req)
+	if err != nil {
+		return nil, err
+	}
+	req.Request = ""
+
+	if req.RedirectURI == "" {
+		return nil, missingParameter("redirect_uri")
+	}
+	if hasPrompt(req.Prompt, PromptNone) && len(strings.Fields(req.Prompt)) > 1 {
+		return nil, errors.BadRequest(errors.ErrMsgInvalidRequest).WithDetails(errors.ErrMsgInvalidPrompt)
+	}
+	if req.MaxAge != "" {
+		if maxAge, err := strconv.Atoi(req.MaxAge); err != nil || maxAge < 0 {
+			return nil, errors.BadRequest(errors.ErrMsgInvalidRequest).WithDetails(errors.ErrMsgInvalidMaxAge)
+		}
+	}
+	if _, err := parseClaimsRequest(req.Claims); err != nil {
+		return nil, err
+	}
+	if _, _, err := s.validateAuthorizeRequest(ctx, req); err != nil {
+		return nil, err
+	}
+
+	requestURI, err := s.savePushedRequest(ctx, req, s.pushedTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PushedAuthorizationResponse{
+		RequestURI: requestURI,
+		ExpiresIn:  int(s.pushedTTL.Seconds()),
+	}, nil
+}
+
+// HoldForConsent stores a resolved authorization request while the user is asked for consent
+// and returns the request_uri the consent page sends back. The consent decision is then applied
+// to the request as the client sent it, never to parameters carried through the browser, so
+// neither the scope, the redirect URI, nor the claims of a pushed or signed request can be
+// changed on the way. A pushed request is used up now, whatever the user decides.
+func (s *Service) HoldForConsent(ctx context.Context, req AuthorizeRequest) (string, error) {
+	if err := s.consumePushedRequest(ctx, req.RequestURI); err != nil {
+		return "", err
+	}
+	req.Request = ""
+	req.RequestURI = ""
+
+	return s.savePushedRequest(ctx, req, consentRequestTTL)
+}
+
+// TakeConsentRequest returns the request held by HoldForConsent for the client, using it up
+// so that a consent decision can only be applied once.
+func (s *Service) TakeConsentRequest(ctx context.Context, clientID, requestURI string) (AuthorizeRequest, error) {
+	if requestURI == "" {
+		return AuthorizeRequest{}, missingParameter("request_uri")
+	}
+	if !strings.HasPrefix(requestURI, PushedRequestURIPrefix) {
+		return AuthorizeRequest{}, errors.BadRequest(errors.ErrMsgInvalidRequestURI)
+	}
+
+	held, err := s.pushedRepo.TakePushedRequest(ctx, strings.TrimPrefix(requestURI, PushedRequestURIPrefix))
+	if err != nil {
+		return AuthorizeRequest{}, errors.Internal(errors.ErrMsgFailedToFindPushedRequest).Wrap(err)
+	}
+	if held == nil || held.ClientID != clientID {
+		return AuthorizeRequest{}, errors.BadRequest(errors.ErrMsgInvalidRequestURI).WithDetails("request_uri is expired, already used, or issued to another client")
+	}
+	return *held, nil
+}
+
+// savePushedRequest stores the request under a new random handle for ttl and returns its request_uri.
+func (s *Service) savePushedRequest(ctx context.Context, req AuthorizeRequest, ttl time.Duration) (string, error) {
+	handleBytes := make([]byte, pushedRequestHandleBytes)
+	if _, err := rand.Read(handleBytes); err != nil {
+		return "", errors.Internal(errors.ErrMsgFailedToSavePushedRequest)
+	}
+	handle := base64.RawURLEncoding.EncodeToString(handleBytes)
+
+	if err := s.pushedRepo.SavePushedRequest(ctx, handle, &req, ttl); err != nil {
+		return "", errors.Internal(errors.ErrMsgFailedToSavePushedRequest).Wrap(err)
+	}
+	return PushedRequestURIPrefix + handle, nil
+}
+
+// findPushedRequest returns the pushed request a request_uri stands for, without using it up.
+// The request must have been pushed by the client named in the query and not yet be used.
+func (s *Service) findPushedRequest(ctx context.Context, req AuthorizeRequest) (AuthorizeRequest, error) {
+	if req.Request != "" {
+		return req, errors.BadRequest(errors.ErrMsgInvalidRequest).WithDetails("request and request_uri cannot both be used")
+	}
+
+	pushed, err := s.pushedRepo.FindPushedRequest(ctx, strings.TrimPrefix(req.RequestURI, PushedRequestURIPrefix))
+	if err != nil {
+		return req, errors.Internal(errors.ErrMsgFailedToFindPushedRequest).Wrap(err)
+	}
+	if pushed == nil || pushed.ClientID != req.ClientID {
+		return req, errors.BadRequest(errors.ErrMsgInvalidRequestURI).WithDetails("request_uri is expired, already used, or issued to another client")
+	}
+
+	// Kept so that issuing the code uses the pushed request up
+	pushed.RequestURI = req.RequestURI
+	return *pushed, nil
+}
+
+// consumePushedRequest uses up the pushed request a request_uri stands for, so it cannot
+// be replayed. Request URIs of request objects need nothing and are ignored.
+func (s *Service) consumePushedRequest(ctx context.Context, requestURI string) error {
+	if !strings.HasPrefix(requestURI, PushedRequestURIPrefix) {
+		return nil
+	}
+
+	pushed, err := s.pushedRepo.TakePushedRequest(ctx, strings.TrimPrefix(requestURI, PushedRequestURIPrefix))
+	if err != nil {
+		return errors.Internal(errors.ErrMsgFailedToFindPushedRequest).Wrap(err)
+	}
+	if pushed == nil {
+		return errors.BadRequest(errors.ErrMsgInvalidRequestURI)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package oauth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/app/client"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+)
+
+func TestPushedRequestUsedUpByConsent(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+
+	const redirectURI = "https://app.example.com/callback"
+	c := s.addClient(t, "client-a", redirectURI, "https://attacker.example.com/callback")
+	c.RequirePushedAuthRequests = true
+	if err := s.clients.Update(ctx, c); err != nil {
+		t.Fatalf("failed to update client: %v", err)
+	}
+
+	pushed, err := s.PushAuthorizationRequest(ctx, c.ClientID, AuthorizeRequest{
+		ResponseType: client.ResponseTypeCode,
+		ClientID:     c.ClientID,
+		RedirectURI:  redirectURI,
+		Scope:        "openid",
+		State:        "state-1",
+	})
+	if err != nil {
+		t.Fatalf("PushAuthorizationRequest failed: %v", err)
+	}
+	query := AuthorizeRequest{ResponseType: client.ResponseTypeCode, ClientID: c.ClientID, RequestURI: pushed.RequestURI}
+
+	req, err := s.ResolveRequestObject(ctx, query)
+	if err != nil {
+		t.Fatalf("ResolveRequestObject failed: %v", err)
+	}
+	if _, err := s.Authorize(ctx, req, 1, time.Now(), "", ""); errorCode(err) != errors.ErrMsgConsentRequired {
+		t.Fatalf("got error %v, want %s", err, errors.ErrMsgConsentRequired)
+	}
+	held, err := s.HoldForConsent(ctx, req)
+	if err != nil {
+		t.Fatalf("HoldForConsent failed: %v", err)
+	}
+
+	// The pushed request_uri was used up on the way to the consent page
+	if _, err := s.ResolveRequestObject(ctx, query); errorCode(err) != errors.ErrMsgInvalidRequestURI {
+		t.Errorf("got error %v for the request_uri reused while consent is pending, want %s", err, errors.ErrMsgInvalidRequestURI)
+	}
+
+	consented, err := s.TakeConsentRequest(ctx, c.ClientID, held)
+	if err != nil {
+		t.Fatalf("TakeConsentRequest failed: %v", err)
+	}
+	if consented.RedirectURI != redirectURI || consented.Scope != "openid" || consented.State != "state-1" {
+		t.Errorf("got consented request %+v, want the pushed one", consented)
+	}
+	consented.Scope, err = s.GrantConsent(ctx, 1, c.ClientID, consented.Scope, nil)
+	if err != nil {
+		t.Fatalf("GrantConsent failed: %v", err)
+	}
+	result, err := s.Authorize(ctx, consented, 1, time.Now(), "", "")
+	if err != nil {
+		t.Fatalf("Authorize after consent failed: %v", err)
+	}
+	if result.Code == "" {
+		t.Fatal("got no authorization code after consent")
+	}
+
+	// Neither the pushed request nor the consent decision can be used a second time
+	if _, err := s.ResolveRequestObject(ctx, query); errorCode(err) != errors.ErrMsgInvalidRequestURI {
+		t.Errorf("got error %v for the request_uri reused after consent, want %s", err, errors.ErrMsgInvalidRequestURI)
+	}
+	if _, err := s.TakeConsentRequest(ctx, c.ClientID, held); errorCode(err) != errors.ErrMsgInvalidRequestURI {
+		t.Errorf("got error %v for the consent submitted again, want %s", err, errors.ErrMsgInvalidRequestURI)
+	}
+
+	// A request rebuilt from query parameters is refused to a client that must push its requests
+	tampered := AuthorizeRequest{ResponseType: client.ResponseTypeCode, ClientID: c.ClientID, RedirectURI: "https://attacker.example.com/callback", Scope: "openid"}
+	if _, err := s.ResolveRequestObject(ctx, tampered); errorCode(err) != errors.ErrMsgInvalidRequest {
+		t.Errorf("got error %v for an unpushed request, want %s", err, errors.ErrMsgInvalidRequest)
+	}
+}
+
+func TestTakeConsentRequestOfAnotherClient(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+
+	const redirectURI = "https://app.example.com/callback"
+	s.addClient(t, "client-a", redirectURI)
+
+	held, err := s.HoldForConsent(ctx, AuthorizeRequest{ResponseType: client.ResponseTypeCode, ClientID: "client-a", RedirectURI: redirectURI, Scope: "openid"})
+	if err != nil {
+		t.Fatalf("HoldForConsent failed: %v", err)
+	}
+	if _, err := s.TakeConsentRequest(ctx, "client-b", held); errorCode(err) != errors.ErrMsgInvalidRequestURI {
+		t.Errorf("got error %v, want %s", err, errors.ErrMsgInvalidRequestURI)
+	}
+}
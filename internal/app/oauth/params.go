@@ -0,0 +1,95 @@
+package oauth
+
+import (
+	"net/url"
+	"sort"
+
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+)
+
+// maxEchoedValueLength bounds the part of a parameter value repeated in an error description
+const maxEchoedValueLength = 64
+
+// requestParams describes the parameters an endpoint accepts, for the checks RFC 6749 Section 3.1
+// applies to every request: a parameter sent without a value is treated as omitted, and a parameter
+// may not be sent more than once. Parameters the endpoint does not recognize are ignored, so that
+// clients can send extensions the server does not implement.
+type requestParams struct {
+	required   []string // Parameters that must be sent with a value
+	repeatable []string // Parameters that may be sent more than once, such as resource (RFC 8707)
+	bodyOnly   []string // Parameters rejected when sent in the request URI, such as client credentials (RFC 6749 Section 2.3.1)
+}
+
+// authorizeParams are the parameters checked at the authorization endpoint
+var authorizeParams = requestParams{
+	required:   []string{"response_type", "client_id"},
+	repeatable: []string{"resource"},
+}
+
+// pushedRequestParams are the parameters checked at the pushed authorization request endpoint,
+// where the client authenticates like at the token endpoint
+var pushedRequestParams = requestParams{
+	required:   authorizeParams.required,
+	repeatable: authorizeParams.repeatable,
+	bodyOnly:   []string{"client_secret", "client_assertion"},
+}
+
+// tokenParams are the parameters checked at the token endpoint
+var tokenParams = requestParams{
+	required:   []string{"grant_type"},
+	repeatable: []string{"resource", "audience"},
+	bodyOnly:   []string{"client_secret", "client_assertion", "subject_token", "actor_token"},
+}
+
+// validate checks the parameters of a request: the values of form, which holds the parameters of
+// both the request URI and the body, and of query, the request URI's alone. It returns an
+// invalid_request error naming the first offending parameter. Descriptions name parameters but
+// never repeat their values, so that rejected credentials do not show up in logs or error pages.
+func (p requestParams) validate(form, query url.Values) error {
+	names := make([]string, 0, len(form))
+	for name := range form {
+		names = append(names, name)
+	}
+	// Report offenders in a stable order
+	sort.Strings(names)
+
+	for _, name := range names {
+		if len(form[name]) > 1 && !containsScope(p.repeatable, name) {
+			return invalidParameter("duplicate parameter: " + name)
+		}
+	}
+
+	for _, name := range p.bodyOnly {
+		if _, ok := query[name]; ok {
+			return invalidParameter(name + " must not be sent in the request URI")
+		}
+	}
+
+	for _, name := range p.required {
+		if form.Get(name) == "" {
+			return missingParameter(name)
+		}
+	}
+
+	return nil
+}
+
+// invalidParameter returns an invalid_request error with the given description.
+func invalidParameter(description string) error {
+	return errors.BadRequest(errors.ErrMsgInvalidRequest).WithDetails(description)
+}
+
+// missingParameter returns the invalid_request error for a required parameter sent without a value.
+func missingParameter(name string) error {
+	return invalidParameter("missing required parameter: " + name)
+}
+
+// unsupportedValue describes a value of an enumerated parameter the server does not support,
+// such as response_type or grant_type. Only parameters whose values are public identifiers
+// may be described this way; the value is truncated so a client cannot fill the description.
+func unsupportedValue(name, value string) string {
+	if len(value) > maxEchoedValueLength {
+		value = value[:maxEchoedValueLength] + "..."
+	}
+	return "unsupported " + name + ": " + value
+}
@@ -4,6 +4,7 @@ package oauth
 
 import (
 	"context"
+	"time"
 )
 
 // Repository defines the interface for OAuth data storage and retrieval operations.
@@ -17,8 +18,12 @@ type Repository interface {
 	// FindAuthorizationCode retrieves an authorization code by its value
 	FindAuthorizationCode(ctx context.Context, code string) (*AuthorizationCode, error)
 
-	// MarkCodeAsUsed updates an authorization code to indicate it has been exchanged for tokens
-	MarkCodeAsUsed(ctx context.Context, code string) error
+	// MarkCodeAsUsed atomically marks an unused authorization code as exchanged for tokens.
+	// Returns false if the code was already used.
+	MarkCodeAsUsed(ctx context.Context, code string) (bool, error)
+
+	// MarkCodeReplayed records that a used authorization code was presented again
+	MarkCodeReplayed(ctx context.Context, code string) error
 
 	// DeleteExpiredCodes removes expired authorization codes from storage
 	DeleteExpiredCodes(ctx context.Context) error
@@ -36,4 +41,63 @@ type Repository interface {
 
 	// DeleteUserConsent removes a user's consent for a specific client
 	DeleteUserConsent(ctx context.Context, userID uint, clientID string) error
+
+	// FindUserConsentsByUserID retrieves every consent a user has granted, oldest first
+	FindUserConsentsByUserID(ctx context.Context, userID uint) ([]*UserConsent, error)
+
+	// TouchUserConsent records that tokens were issued to a client under the user's consent
+	TouchUserConsent(ctx context.Context, userID uint, clientID string, at time.Time) error
+
+	// Grant methods
+
+	// SaveGrant persists a new grant
+	SaveGrant(ctx context.Context, grant *Grant) error
+
+	// FindGrant retrieves a grant by its grant ID, or nil if it does not exist
+	FindGrant(ctx context.Context, grantID string) (*Grant, error)
+
+	// UpdateGrant stores the scope, claims, and resources of an existing grant
+	UpdateGrant(ctx context.Context, grant *Grant) error
+
+	// DeleteGrant removes a grant. Returns NotFound if it does not exist.
+	DeleteGrant(ctx context.Context, grantID string) error
+
+	// Device code methods
+
+	// SaveDeviceCode persists a new device authorization request
+	SaveDeviceCode(ctx context.Context, code *DeviceCode) error
+
+	// FindDeviceCode retrieves a device authorization request by its device code
+	FindDeviceCode(ctx context.Context, deviceCode string) (*DeviceCode, error)
+
+	// FindDeviceCodeByUserCode retrieves a device authorization request by its user code
+	FindDeviceCodeByUserCode(ctx context.Context, userCode string) (*DeviceCode, error)
+
+	// UpdateDeviceCodeStatus moves a device code from fromStatus to toStatus and records the user.
+	// Returns false if the code was not in fromStatus.
+	UpdateDeviceCodeStatus(ctx context.Context, deviceCode, fromStatus, toStatus string, userID uint) (bool, error)
+
+	// UpdateDeviceCodeInterval changes the minimum polling interval of a device code
+	UpdateDeviceCodeInterval(ctx context.Context, deviceCode string, interval int) error
+}
+
+// DevicePollRepository tracks when devices last polled the token endpoint.
+type DevicePollRepository interface {
+	// RecordPoll registers a poll for the device code and reports whether it
+	// arrived sooner than interval after the previous poll.
+	RecordPoll(ctx context.Context, deviceCode string, interval time.Duration) (bool, error)
+}
+
+// PushedRequestRepository stores pushed authorization requests until they are used or expire.
+type PushedRequestRepository interface {
+	// SavePushedRequest stores the request under handle, expiring after ttl.
+	SavePushedRequest(ctx context.Context, handle string, req *AuthorizeRequest, ttl time.Duration) error
+
+	// FindPushedRequest returns the request stored under handle without consuming it.
+	// Returns nil if it does not exist, has expired, or was already used.
+	FindPushedRequest(ctx context.Context, handle string) (*AuthorizeRequest, error)
+
+	// TakePushedRequest returns and removes the request stored under handle in one step,
+	// so it can only be used once. Returns nil if it does not exist or was already used.
+	TakePushedRequest(ctx context.Context, handle string) (*AuthorizeRequest, error)
 }
@@ -0,0 +1,229 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/verigate/verigate-server/internal/app/client"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+	jwtutil "github.com/verigate/verigate-server/internal/pkg/utils/jwt"
+)
+
+// Request object retrieval limits (RFC 9101 Section 5.2)
+const (
+	requestObjectFetchTimeout = 5 * time.Second // Maximum time to fetch a request object from a request_uri
+	maxRequestObjectSize      = 64 << 10        // Maximum accepted size of a request object in bytes
+)
+
+// requestObjectHTTPClient fetches request objects by reference
+var requestObjectHTTPClient = &http.Client{Timeout: requestObjectFetchTimeout}
+
+// requestObjectClaims holds the authorization request parameters carried in a request object.
+type requestObjectClaims struct {
+	jwt.RegisteredClaims
+	ResponseType        string           `json:"response_type"`
+	ClientID            string           `json:"client_id"`
+	RedirectURI         string           `json:"redirect_uri"`
+	ResponseMode        string           `json:"response_mode"`
+	Scope               string           `json:"scope"`
+	State               string           `json:"state"`
+	Nonce               string           `json:"nonce"`
+	CodeChallenge       string           `json:"code_challenge"`
+	CodeChallengeMethod string           `json:"code_challenge_method"`
+	Prompt              string           `json:"prompt"`
+	MaxAge              *json.Number     `json:"max_age"`
+	Resource            jwt.ClaimStrings `json:"resource"`
+	UILocales           string           `json:"ui_locales"`
+	ACRValues           string           `json:"acr_values"`
+	Claims              json.RawMessage  `json:"claims"`
+	LoginHint           string           `json:"login_hint"`
+}
+
+// ResolveRequestObject returns the authorization request that the authorization endpoint
+// acts on. A request_uri issued by the pushed authorization request endpoint (RFC 9126)
+// stands for the pushed parameters, and the query parameters besides client_id are ignored;
+// the pushed request stays available until a code is issued for it. Clients requiring
+// pushed requests may not send any other kind. Otherwise a request object passed by value
+// in request or by reference in request_uri (RFC 9101) is applied, and requests without
+// one are returned unchanged.
+func (s *Service) ResolveRequestObject(ctx context.Context, req AuthorizeRequest) (AuthorizeRequest, error) {
+	if strings.HasPrefix(req.RequestURI, PushedRequestURIPrefix) {
+		return s.findPushedRequest(ctx, req)
+	}
+
+	c, err := s.clientService.GetByClientID(ctx, req.ClientID)
+	if err != nil {
+		return req, err
+	}
+	if c == nil || !c.IsActive {
+		return req, errors.BadRequest(errors.ErrMsgInvalidClient)
+	}
+	if c.RequirePushedAuthRequests {
+		return req, errors.BadRequest(errors.ErrMsgInvalidRequest).WithDetails("the client must use a pushed authorization request")
+	}
+
+	return s.applyRequestObject(ctx, c, req)
+}
+
+// applyRequestObject returns the authorization request defined by the request object
+// passed by value in request or by reference in request_uri (RFC 9101). Requests without
+// one are returned unchanged.
+//
+// The request object must be signed by a key registered for the client, name the client
+// as iss, be addressed to this server, and carry an expiry. Only its parameters are used:
+// response_type and client_id must also be sent as query parameters and match, and any
+// other query parameter must repeat the request object's value, so that no unsigned
+// parameter is silently dropped or takes effect. A request_uri must be one the client
+// registered, which also keeps the server from being used to fetch arbitrary URLs.
+func (s *Service) applyRequestObject(ctx context.Context, c *client.Client, req AuthorizeRequest) (AuthorizeRequest, error) {
+	if req.Request == "" && req.RequestURI == "" {
+		return req, nil
+	}
+	if req.Request != "" && req.RequestURI != "" {
+		return req, errors.BadRequest(errors.ErrMsgInvalidRequest).WithDetails("request and request_uri cannot both be used")
+	}
+
+	var err error
+	requestObject := req.Request
+	if req.RequestURI != "" {
+		if !containsScope(c.RequestURIs, req.RequestURI) {
+			return req, errors.BadRequest(errors.ErrMsgInvalidRequestURI).WithDetails("request_uri is not registered for the client")
+		}
+		requestObject, err = fetchRequestObject(ctx, req.RequestURI)
+		if err != nil {
+			return req, errors.BadRequest(errors.ErrMsgInvalidRequestURI).WithDetails(err.Error())
+		}
+	}
+
+	claims, err := s.verifyRequestObject(ctx, c, requestObject)
+	if err != nil {
+		return req, errors.BadRequest(errors.ErrMsgInvalidRequestObject).WithDetails(err.Error())
+	}
+
+	resolved := AuthorizeRequest{
+		ResponseType:        claims.ResponseType,
+		ClientID:            req.ClientID,
+		RedirectURI:         claims.RedirectURI,
+		ResponseMode:        claims.ResponseMode,
+		Scope:               claims.Scope,
+		State:               claims.State,
+		Nonce:               claims.Nonce,
+		CodeChallenge:       claims.CodeChallenge,
+		CodeChallengeMethod: claims.CodeChallengeMethod,
+		Prompt:              claims.Prompt,
+		Resource:            claims.Resource,
+		UILocales:           claims.UILocales,
+		ACRValues:           claims.ACRValues,
+		LoginHint:           claims.LoginHint,
+	}
+	if len(claims.Claims) > 0 {
+		resolved.Claims = string(claims.Claims)
+	}
+	if claims.MaxAge != nil {
+		resolved.MaxAge = claims.MaxAge.String()
+	}
+	if resolved.ResponseType == "" {
+		resolved.ResponseType = req.ResponseType
+	}
+
+	if err := checkRequestObjectConflicts(req, resolved); err != nil {
+		return req, err
+	}
+
+	return resolved, nil
+}
+
+// verifyRequestObject verifies the signature and the iss, aud, exp, and client_id claims
+// of a request object and returns its claims.
+func (s *Service) verifyRequestObject(ctx context.Context, c *client.Client, requestObject string) (*requestObjectClaims, error) {
+	keys, err := s.clientService.VerificationKeys(ctx, c)
+	if err != nil {
+		return nil, fmt.Errorf("the client has no keys to verify the request object with")
+	}
+
+	var claims requestObjectClaims
+	if _, err := jwtutil.ParseWithJWKS(requestObject, keys, &claims); err != nil {
+		return nil, fmt.Errorf("request object signature or claims are invalid")
+	}
+
+	if claims.Issuer != c.ClientID {
+		return nil, fmt.Errorf("request object iss must be the client_id")
+	}
+	if !claims.VerifyAudience(jwtutil.Issuer(ctx), true) {
+		return nil, fmt.Errorf("request object aud must be the issuer")
+	}
+	if claims.ExpiresAt == nil {
+		return nil, fmt.Errorf("request object must have an expiry")
+	}
+	if claims.ClientID != "" && claims.ClientID != c.ClientID {
+		return nil, fmt.Errorf("request object client_id does not match the client_id parameter")
+	}
+
+	return &claims, nil
+}
+
+// checkRequestObjectConflicts rejects query parameters that differ from the parameters
+// of the request object they were sent with.
+func checkRequestObjectConflicts(query, resolved AuthorizeRequest) error {
+	params := []struct {
+		name, query, resolved string
+	}{
+		{"response_type", query.ResponseType, resolved.ResponseType},
+		{"redirect_uri", query.RedirectURI, resolved.RedirectURI},
+		{"response_mode", query.ResponseMode, resolved.ResponseMode},
+		{"scope", query.Scope, resolved.Scope},
+		{"state", query.State, resolved.State},
+		{"nonce", query.Nonce, resolved.Nonce},
+		{"code_challenge", query.CodeChallenge, resolved.CodeChallenge},
+		{"code_challenge_method", query.CodeChallengeMethod, resolved.CodeChallengeMethod},
+		{"prompt", query.Prompt, resolved.Prompt},
+		{"max_age", query.MaxAge, resolved.MaxAge},
+		{"resource", strings.Join(query.Resource, " "), strings.Join(resolved.Resource, " ")},
+		{"ui_locales", query.UILocales, resolved.UILocales},
+		{"acr_values", query.ACRValues, resolved.ACRValues},
+		{"claims", query.Claims, resolved.Claims},
+		{"login_hint", query.LoginHint, resolved.LoginHint},
+	}
+	for _, p := range params {
+		if p.query != "" && p.query != p.resolved {
+			return errors.BadRequest(errors.ErrMsgInvalidRequest).WithDetails(p.name + " conflicts with the request object")
+		}
+	}
+	return nil
+}
+
+// fetchRequestObject retrieves the request object published at a request_uri.
+// The request is bounded by a timeout and the document by a maximum size.
+func fetchRequestObject(ctx context.Context, uri string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/oauth-authz-req+jwt")
+
+	resp, err := requestObjectHTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch request object: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch request object: unexpected status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRequestObjectSize+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read request object: %w", err)
+	}
+	if len(data) > maxRequestObjectSize {
+		return "", fmt.Errorf("request object too large")
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,169 @@
+package oauth
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/verigate/verigate-server/internal/pkg/middleware"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+)
+
+// Response modes, how the parameters of an authorization response are delivered to the
+// redirect URI (OAuth 2.0 Multiple Response Type Encoding Practices, OAuth 2.0 Form Post Response Mode)
+const (
+	ResponseModeQuery    = "query"     // Added to the query of the redirect URI
+	ResponseModeFragment = "fragment"  // Added to the fragment of the redirect URI, never sent to the client's server by the browser
+	ResponseModeFormPost = "form_post" // Posted to the redirect URI by an auto-submitting form, keeping them out of URLs
+)
+
+// formPostPage is rendered for response_mode=form_post. It posts the response parameters to the
+// redirect URI as soon as it loads, with a button for browsers running without scripts. The
+// form is submitted by a script carrying the CSP nonce, as the policy allows no inline handlers.
+var formPostPage = template.Must(template.New("form_post").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Submit this form</title>
+</head>
+<body>
+<form method="post" action="{{.RedirectURI}}">
+{{range $name, $values := .Params}}{{range $values}}<input type="hidden" name="{{$name}}" value="{{.}}">
+{{end}}{{end}}<noscript><button type="submit">Continue</button></noscript>
+</form>
+<script nonce="{{.Nonce}}">document.forms[0].submit();</script>
+</body>
+</html>
+`))
+
+// authorizationResponse is an authorization response or error on its way to a validated redirect URI.
+type authorizationResponse struct {
+	RedirectURI string     // Redirect URI registered for the client
+	Mode        string     // Response mode delivering the parameters
+	Params      url.Values // Response parameters, such as code and state or error and state
+}
+
+// formPostPageData is what the form_post page is rendered with.
+type formPostPageData struct {
+	authorizationResponse
+	Nonce string // CSP nonce of the page's inline script
+}
+
+// defaultResponseMode returns the response mode used when a request names none: query for
+// the code response type, and fragment for response types returning tokens from the
+// authorization endpoint, which must not appear in a query.
+func defaultResponseMode(responseType string) string {
+	if returnsTokens(responseType) {
+		return ResponseModeFragment
+	}
+	return ResponseModeQuery
+}
+
+// returnsTokens reports whether a response type returns an access or ID token from the authorization endpoint.
+func returnsTokens(responseType string) bool {
+	return hasResponseTypeValue(responseType, responseTypeToken) || hasResponseTypeValue(responseType, responseTypeIDToken)
+}
+
+// resolveResponseMode returns the response mode of an authorization request, defaulted from its
+// response type when empty. The query mode is rejected for response types returning tokens.
+// On error the default mode is returned too, as the way to deliver the error response.
+func resolveResponseMode(responseType, responseMode string) (string, error) {
+	fallback := defaultResponseMode(responseType)
+
+	switch responseMode {
+	case "":
+		return fallback, nil
+	case ResponseModeQuery:
+		if returnsTokens(responseType) {
+			return fallback, invalidParameter("response_mode query cannot be used with response_type " + responseType)
+		}
+		return responseMode, nil
+	case ResponseModeFragment, ResponseModeFormPost:
+		return responseMode, nil
+	default:
+		return fallback, invalidParameter(unsupportedValue("response_mode", responseMode))
+	}
+}
+
+// params returns the parameters of a successful authorization response: the code and state,
+// and the tokens a hybrid response type issued along with them (OpenID Connect Core Section 3.3.2.5).
+func (r *AuthorizationResult) params() url.Values {
+	params := url.Values{}
+	params.Set("code", r.Code)
+	if r.State != "" {
+		params.Set("state", r.State)
+	}
+	if r.AccessToken != "" {
+		params.Set("access_token", r.AccessToken)
+		params.Set("token_type", r.TokenType)
+		params.Set("expires_in", strconv.Itoa(r.ExpiresIn))
+		params.Set("scope", r.Scope)
+	}
+	if r.IDToken != "" {
+		params.Set("id_token", r.IDToken)
+	}
+	return params
+}
+
+// errorParams returns the parameters of an authorization error response (RFC 6749 Section 4.1.2.1).
+// The description is limited to the characters RFC 6749 allows.
+func errorParams(state, errorCode, errorDesc string) url.Values {
+	params := url.Values{}
+	params.Set("error", errorCode)
+	if description := sanitizeErrorDescription(errorDesc); description != "" {
+		params.Set("error_description", description)
+	}
+	if state != "" {
+		params.Set("state", state)
+	}
+	return params
+}
+
+// URL returns the redirect URI carrying the parameters in its query or fragment.
+// It is not used for the form_post mode.
+func (r authorizationResponse) URL() string {
+	if r.Mode == ResponseModeFragment {
+		return strings.SplitN(r.RedirectURI, "#", 2)[0] + "#" + r.Params.Encode()
+	}
+	return appendQuery(r.RedirectURI, r.Params)
+}
+
+// send delivers the response to the browser: a redirect for the query and fragment modes,
+// and an auto-submitting form for the form_post mode.
+func (r authorizationResponse) send(c *gin.Context) {
+	if r.Mode != ResponseModeFormPost {
+		c.Redirect(http.StatusFound, r.URL())
+		return
+	}
+
+	// The form posts to the client, which the server's own policy would not allow
+	middleware.SetCSPDirective(c, "form-action", cspSources(r.RedirectURI)...)
+
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+	if err := formPostPage.Execute(c.Writer, formPostPageData{authorizationResponse: r, Nonce: middleware.CSPNonce(c)}); err != nil {
+		c.Error(errors.Internal(errors.ErrMsgFailedToRenderPage).Wrap(err))
+	}
+}
+
+// consentResult returns the body answering a consent decision, which the consent page uses to
+// continue: a redirect URL, or for the form_post mode the form to post and its parameters.
+func (r authorizationResponse) consentResult() gin.H {
+	if r.Mode != ResponseModeFormPost {
+		return gin.H{"redirect": r.URL()}
+	}
+
+	params := make(map[string]string, len(r.Params))
+	for name := range r.Params {
+		params[name] = r.Params.Get(name)
+	}
+	return gin.H{
+		"response_mode": ResponseModeFormPost,
+		"action":        r.RedirectURI,
+		"params":        params,
+	}
+}
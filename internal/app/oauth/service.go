@@ -3,27 +3,59 @@ package oauth
 import (
 	"context"
 	"crypto/rand"
-	"encoding/base64"
+	"crypto/x509"
+	"math/big"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/verigate/verigate-server/internal/app/audit"
 	"github.com/verigate/verigate-server/internal/app/auth"
 	"github.com/verigate/verigate-server/internal/app/client"
+	"github.com/verigate/verigate-server/internal/app/logout"
+	"github.com/verigate/verigate-server/internal/app/replay"
 	"github.com/verigate/verigate-server/internal/app/scope"
 	"github.com/verigate/verigate-server/internal/app/token"
 	"github.com/verigate/verigate-server/internal/app/user"
+	"github.com/verigate/verigate-server/internal/pkg/config"
+	"github.com/verigate/verigate-server/internal/pkg/db/routing"
+	"github.com/verigate/verigate-server/internal/pkg/tenant"
 	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+	jwtutil "github.com/verigate/verigate-server/internal/pkg/utils/jwt"
+	"github.com/verigate/verigate-server/internal/pkg/utils/opaque"
 	"github.com/verigate/verigate-server/internal/pkg/utils/pkce"
 )
 
+// Device authorization flow settings
+const (
+	DeviceVerificationPath = "/api/v1/oauth/device" // Path of the device verification page
+
+	deviceCodeExpiry       = 10 * time.Minute       // Lifetime of device and user codes
+	devicePollInterval     = 5                      // Default minimum seconds between polls
+	devicePollSlowDownStep = 5                      // Seconds added to the interval on each slow_down
+	userCodeLength         = 8                      // Number of characters in a user code
+	userCodeAlphabet       = "BCDFGHJKLMNPQRSTVWXZ" // Consonants only, to avoid ambiguous or offensive codes
+)
+
 type Service struct {
-	oauthRepo     Repository
-	userService   *user.Service
-	clientService *client.Service
-	tokenService  *token.Service
-	scopeService  *scope.Service
-	authService   *auth.Service
+	oauthRepo      Repository
+	userService    *user.Service
+	clientService  *client.Service
+	tokenService   *token.Service
+	scopeService   *scope.Service
+	authService    *auth.Service
+	pollRepo       DevicePollRepository
+	jtiStore       replay.JTIStore
+	pushedRepo     PushedRequestRepository
+	logoutService  *logout.Service
+	auditService   *audit.Service
+	claimRegistry  *ClaimRegistry
+	exchangePolicy TokenExchangePolicy
+	pushedTTL      time.Duration
+	logoutScope    string         // What an RP-initiated logout ends, EndSessionScopeUser or EndSessionScopeSession
+	clientCAs      *x509.CertPool // CAs issuing tls_client_auth certificates, nil for the system roots
+	dpop           dpopSettings   // How DPoP proofs are checked
 }
 
 func NewService(
@@ -33,70 +65,119 @@ func NewService(
 	tokenService *token.Service,
 	scopeService *scope.Service,
 	authService *auth.Service,
+	pollRepo DevicePollRepository,
+	jtiStore replay.JTIStore,
+	pushedRepo PushedRequestRepository,
+	logoutService *logout.Service,
+	auditService *audit.Service,
 ) *Service {
+	pushedTTL, err := time.ParseDuration(config.AppConfig.PushedRequestTTL)
+	if err != nil || pushedTTL <= 0 {
+		panic("invalid pushed request TTL: " + config.AppConfig.PushedRequestTTL)
+	}
+	clientCAs, err := loadClientCAs(config.AppConfig.MTLSClientCAFile)
+	if err != nil {
+		panic("invalid mutual TLS client CA file: " + err.Error())
+	}
+
 	return &Service{
-		oauthRepo:     oauthRepo,
-		userService:   userService,
-		clientService: clientService,
-		tokenService:  tokenService,
-		scopeService:  scopeService,
-		authService:   authService,
+		oauthRepo:      oauthRepo,
+		userService:    userService,
+		clientService:  clientService,
+		tokenService:   tokenService,
+		scopeService:   scopeService,
+		authService:    authService,
+		pollRepo:       pollRepo,
+		jtiStore:       jtiStore,
+		pushedRepo:     pushedRepo,
+		logoutService:  logoutService,
+		auditService:   auditService,
+		claimRegistry:  NewClaimRegistry(),
+		exchangePolicy: DownscopingPolicy,
+		pushedTTL:      pushedTTL,
+		logoutScope:    config.AppConfig.EndSessionScope,
+		clientCAs:      clientCAs,
+		dpop:           loadDPoPSettings(),
 	}
 }
 
-func (s *Service) Authorize(ctx context.Context, req AuthorizeRequest, userID uint) (string, error) {
-	// Validate response type
-	if req.ResponseType != "code" {
-		return "", errors.BadRequest(errors.ErrMsgUnsupportedResponseType)
-	}
+// RegisterClaims adds a provider of UserInfo claims for a custom scope.
+// Providers registered for the same scope are merged into the response.
+func (s *Service) RegisterClaims(scope string, provider ClaimProvider) {
+	s.claimRegistry.Register(scope, provider)
+}
 
-	// Validate client
-	client, err := s.clientService.GetByClientID(ctx, req.ClientID)
+// ValidateRedirectURI checks that the client exists, is active, and has registered the redirect URI.
+// Errors must not be reported to an unverified redirect URI, so the authorization endpoint
+// calls this before redirecting with any error.
+func (s *Service) ValidateRedirectURI(ctx context.Context, clientID, redirectURI string) (*client.Client, error) {
+	c, err := s.clientService.GetByClientID(ctx, clientID)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	if client == nil || !client.IsActive {
-		return "", errors.BadRequest(errors.ErrMsgInvalidClient)
+	if c == nil || !c.IsActive {
+		return nil, errors.BadRequest(errors.ErrMsgInvalidClient)
 	}
 
-	// Validate redirect URI
-	validRedirect := false
-	for _, uri := range client.RedirectURIs {
-		if uri == req.RedirectURI {
-			validRedirect = true
-			break
-		}
+	if !c.AllowsRedirectURI(redirectURI) {
+		return nil, errors.BadRequest(errors.ErrMsgInvalidRedirectUri)
 	}
-	if !validRedirect {
-		return "", errors.BadRequest(errors.ErrMsgInvalidRedirectUri)
+	return c, nil
+}
+
+// Authorize issues an authorization code for an authenticated user.
+// authTime is when the user last authenticated, sessionID identifies the web session, and
+// sessionACR records how the user logged in; all are carried into the ID token.
+// It returns a login_required error if the session does not meet the requested acr_values
+// but a step-up login would, or if the claims parameter requests the ID token of another
+// subject, and a 302 consent_required error if the user must first approve the requested scopes.
+// Along with the code it returns the state to send back, which is a signed state token for
+// managed_state clients; see authorizationState. Hybrid response types also return the access
+// token or ID token issued with the code.
+func (s *Service) Authorize(ctx context.Context, req AuthorizeRequest, userID uint, authTime time.Time, sessionID, sessionACR string) (*AuthorizationResult, error) {
+	requestedScope, codeChallengeMethod, err := s.validateAuthorizeRequest(ctx, req)
+	if err != nil {
+		return nil, err
 	}
+	requestedScope = s.OfflineAccessScope(ctx, userID, req.ClientID, requestedScope, req.Prompt)
 
-	// Validate PKCE
-	if req.CodeChallengeMethod != "" && req.CodeChallengeMethod != "plain" && req.CodeChallengeMethod != "S256" {
-		return "", errors.BadRequest(errors.ErrMsgInvalidCodeChallengeMethod)
+	claimsRequest, err := parseClaimsRequest(req.Claims)
+	if err != nil {
+		return nil, err
 	}
 
-	// Validate and normalize scope
-	requestedScope := req.Scope
-	if requestedScope == "" {
-		requestedScope = "profile" // Default scope
+	// The user must have authenticated strongly enough before being asked for consent
+	acr, err := s.resolveRequestedACR(ctx, userID, req.ACRValues, claimsRequest, sessionACR)
+	if err != nil {
+		return nil, err
 	}
 
-	validScope, err := s.scopeService.ValidateScope(ctx, requestedScope, client.Scope)
-	if err != nil || !validScope {
-		return "", errors.BadRequest(errors.ErrMsgInvalidScope)
+	// A requested sub can only be met by logging in as the user it identifies
+	if requestedSubject, ok := claimsRequest.requestedSubject(); ok {
+		subject, err := s.subjectFor(ctx, req.ClientID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if requestedSubject != subject {
+			return nil, errors.Unauthorized(errors.ErrMsgLoginRequired).WithDetails("the requested sub is not the logged-in user")
+		}
 	}
 
-	// Check if consent is needed
-	if s.needsConsent(ctx, userID, req.ClientID, requestedScope) {
+	// Check if consent is needed for scopes not granted before
+	if len(s.pendingConsentScopes(ctx, userID, req.ClientID, requestedScope, hasPrompt(req.Prompt, PromptConsent))) > 0 {
 		// Return indicator that consent is needed (to be handled by the handler)
-		return "", errors.New(302, "consent_required")
+		return nil, errors.New(302, errors.ErrMsgConsentRequired)
+	}
+
+	// A pushed request is used up by the code issued for it
+	if err := s.consumePushedRequest(ctx, req.RequestURI); err != nil {
+		return nil, err
 	}
 
 	// Generate authorization code
 	code, err := s.generateAuthorizationCode()
 	if err != nil {
-		return "", errors.Internal(errors.ErrMsgFailedToGenerateAuthCode)
+		return nil, errors.Internal(errors.ErrMsgFailedToGenerateAuthCode)
 	}
 
 	// Save authorization code
@@ -107,68 +188,424 @@ func (s *Service) Authorize(ctx context.Context, req AuthorizeRequest, userID ui
 		RedirectURI:         req.RedirectURI,
 		Scope:               requestedScope,
 		CodeChallenge:       req.CodeChallenge,
-		CodeChallengeMethod: req.CodeChallengeMethod,
+		CodeChallengeMethod: codeChallengeMethod,
+		Resources:           req.Resource,
+		AuthTime:            authTime,
+		SessionID:           sessionID,
+		Nonce:               req.Nonce,
+		ACR:                 acr,
+		AMR:                 auth.AuthenticationMethods(sessionACR),
+		IDTokenClaims:       claimsRequest.idTokenClaims(),
 		ExpiresAt:           time.Now().Add(10 * time.Minute),
 		CreatedAt:           time.Now(),
 		IsUsed:              false,
 	}
 
 	if err := s.oauthRepo.SaveAuthorizationCode(ctx, authCode); err != nil {
-		return "", errors.Internal(errors.ErrMsgFailedToSaveAuthCode)
+		return nil, errors.Internal(errors.ErrMsgFailedToSaveAuthCode)
 	}
 
-	return code, nil
+	state, err := s.authorizationState(ctx, authCode, req.State)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AuthorizationResult{Code: code, State: state}
+	if err := s.issueFrontChannelTokens(ctx, req.ResponseType, authCode, result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
 }
 
+// validateAuthorizeRequest checks the response type, redirect URI, PKCE parameters, scope,
+// and resource indicators of an authorization request. It returns the requested scope,
+// defaulted when empty, and the normalized code challenge method.
+func (s *Service) validateAuthorizeRequest(ctx context.Context, req AuthorizeRequest) (string, string, error) {
+	// Validate response type; codes are only issued while the grant exchanging them is enabled
+	if !isSupportedResponseType(req.ResponseType) || !isGrantTypeEnabled(GrantTypeAuthorizationCode) {
+		return "", "", errors.BadRequest(errors.ErrMsgUnsupportedResponseType).WithDetails(unsupportedValue("response_type", req.ResponseType))
+	}
+	if _, err := resolveResponseMode(req.ResponseType, req.ResponseMode); err != nil {
+		return "", "", err
+	}
+
+	// Validate client and redirect URI
+	client, err := s.ValidateRedirectURI(ctx, req.ClientID, req.RedirectURI)
+	if err != nil {
+		return "", "", err
+	}
+	if !client.AllowsResponseType(req.ResponseType) {
+		return "", "", errors.BadRequest(errors.ErrMsgUnauthorizedClient).WithDetails("the client is not registered for response_type " + req.ResponseType)
+	}
+
+	// Validate PKCE
+	codeChallengeMethod, err := s.validateCodeChallenge(client, req.CodeChallenge, req.CodeChallengeMethod)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Validate and normalize scope
+	requestedScope := req.Scope
+	if requestedScope == "" {
+		requestedScope = "profile" // Default scope
+	}
+
+	validScope, err := s.scopeService.ValidateScope(ctx, requestedScope, client.Scope)
+	if err != nil || !validScope {
+		return "", "", errors.BadRequest(errors.ErrMsgInvalidScope)
+	}
+
+	// An ID token from the authorization endpoint is only issued to OpenID Connect requests,
+	// and its nonce is the client's protection against replay (OpenID Connect Core Section 3.3.2.11)
+	if hasResponseTypeValue(req.ResponseType, responseTypeIDToken) {
+		if !containsScope(strings.Fields(requestedScope), ScopeOpenID) {
+			return "", "", invalidParameter("the openid scope is required with response_type " + req.ResponseType)
+		}
+		if req.Nonce == "" {
+			return "", "", missingParameter("nonce")
+		}
+	}
+
+	// Validate resource indicators
+	if err := validateResources(client, req.Resource); err != nil {
+		return "", "", err
+	}
+
+	return requestedScope, codeChallengeMethod, nil
+}
+
+// Token issues tokens for a token request and records the outcome in the audit trail.
+// Grant types that are not enabled are rejected as unsupported, like those the server
+// does not implement.
 func (s *Service) Token(ctx context.Context, req TokenRequest) (*TokenResponse, error) {
+	resp, err := s.issueTokens(ctx, req)
+
+	event := audit.Event{
+		Type:     audit.EventTokenIssued,
+		Actor:    audit.ClientActor(req.ClientID),
+		ClientID: req.ClientID,
+		Details:  map[string]string{"grant_type": req.GrantType},
+	}
+	if err != nil {
+		event.Outcome = audit.OutcomeFailure
+		event.Details["error"] = err.Error()
+	} else {
+		event.Details["scope"] = resp.Scope
+		event.Details["access_token_hash"] = audit.HashValue(resp.AccessToken)
+		if resp.RefreshToken != "" {
+			event.Details["refresh_token_hash"] = audit.HashValue(resp.RefreshToken)
+		}
+	}
+	s.auditService.Record(ctx, event)
+
+	return resp, err
+}
+
+// issueTokens dispatches a token request to the handler of its grant type.
+func (s *Service) issueTokens(ctx context.Context, req TokenRequest) (*TokenResponse, error) {
+	if !isGrantTypeEnabled(req.GrantType) {
+		return nil, errors.BadRequest(errors.ErrMsgUnsupportedGrantType).WithDetails(unsupportedValue("grant_type", req.GrantType))
+	}
+
 	switch req.GrantType {
-	case "authorization_code":
+	case GrantTypeAuthorizationCode:
 		return s.handleAuthorizationCodeGrant(ctx, req)
-	case "refresh_token":
+	case GrantTypeRefreshToken:
 		return s.handleRefreshTokenGrant(ctx, req)
+	case GrantTypeClientCredentials:
+		return s.handleClientCredentialsGrant(ctx, req)
+	case GrantTypeDeviceCode:
+		return s.handleDeviceCodeGrant(ctx, req)
+	case GrantTypeTokenExchange:
+		return s.handleTokenExchangeGrant(ctx, req)
 	default:
-		return nil, errors.BadRequest(errors.ErrMsgUnsupportedGrantType)
+		return nil, errors.BadRequest(errors.ErrMsgUnsupportedGrantType).WithDetails(unsupportedValue("grant_type", req.GrantType))
 	}
 }
 
+// Revoke invalidates an access or refresh token issued to the given client (RFC 7009).
+// The token type hint only determines which lookup is attempted first; if the token
+// is not found as the hinted type, the other type is tried as well.
+// Unknown, foreign, or already revoked tokens are not reported as errors, but are
+// recorded as failed revocations in the audit trail.
 func (s *Service) Revoke(ctx context.Context, req RevokeRequest, clientID string) error {
-	if req.TokenTypeHint == "access_token" || req.TokenTypeHint == "" {
-		err := s.tokenService.RevokeAccessToken(ctx, req.Token, clientID)
-		if err == nil {
-			return nil
-		}
+	revokeAccess := func() error { return s.tokenService.RevokeAccessToken(ctx, req.Token, clientID) }
+	revokeRefresh := func() error { return s.tokenService.RevokeRefreshToken(ctx, req.Token, clientID) }
+
+	attempts := []func() error{revokeRefresh, revokeAccess}
+	if req.TokenTypeHint == TokenTypeHintAccessToken {
+		attempts = []func() error{revokeAccess, revokeRefresh}
 	}
 
-	if req.TokenTypeHint == "refresh_token" || req.TokenTypeHint == "" {
-		err := s.tokenService.RevokeRefreshToken(ctx, req.Token, clientID)
-		if err == nil {
-			return nil
+	event := audit.Event{
+		Type:     audit.EventTokenRevoked,
+		Actor:    audit.ClientActor(clientID),
+		ClientID: clientID,
+		Outcome:  audit.OutcomeFailure,
+		Details:  map[string]string{"token_hash": audit.HashValue(req.Token)},
+	}
+	for _, attempt := range attempts {
+		if err := attempt(); err == nil {
+			event.Outcome = audit.OutcomeSuccess
+			break
 		}
 	}
+	s.auditService.Record(ctx, event)
 
 	// RFC 7009: Return success even if token was not found
 	return nil
 }
 
-func (s *Service) GetUserInfo(ctx context.Context, userID uint) (*UserInfoResponse, error) {
-	user, err := s.userService.GetByID(ctx, userID)
+// Introspect reports the state of an access or refresh token (RFC 7662).
+// Any authenticated client may introspect a token, including tokens issued to other clients.
+// Unknown, revoked, or expired tokens yield a response with only active set to false.
+func (s *Service) Introspect(ctx context.Context, req IntrospectRequest) (*IntrospectionResponse, error) {
+	info, kind, err := s.tokenService.FindActiveToken(ctx, req.Token, req.TokenTypeHint)
+	if err != nil {
+		return nil, err
+	}
+	if info == nil {
+		return &IntrospectionResponse{Active: false}, nil
+	}
+
+	tokenType := token.TokenTypeBearer
+	if kind == token.KindRefreshToken {
+		tokenType = TokenTypeHintRefreshToken
+	} else if info.DPoPThumbprint != "" {
+		tokenType = token.TokenTypeDPoP
+	}
+
+	resp := &IntrospectionResponse{
+		Active:    true,
+		Scope:     info.Scope,
+		ClientID:  info.ClientID,
+		TokenType: tokenType,
+		Exp:       info.ExpiresAt.Unix(),
+		Iat:       info.CreatedAt.Unix(),
+	}
+	if info.CertThumbprint != "" || info.DPoPThumbprint != "" {
+		resp.Cnf = &jwtutil.Confirmation{X5tS256: info.CertThumbprint, Jkt: info.DPoPThumbprint}
+	}
+	// Resource servers check these to demand a step-up (RFC 9470 Section 6.2)
+	if !info.AuthTime.IsZero() {
+		resp.AuthTime = info.AuthTime.Unix()
+	}
+	resp.ACR = info.ACR
+
+	// Access tokens without a requested audience are addressed to the client;
+	// refresh tokens report the resources granted to them, if any
+	audience := info.Audience
+	if len(audience) == 0 && kind != token.KindRefreshToken {
+		audience = []string{info.ClientID}
+	}
+	switch len(audience) {
+	case 0:
+		// No audience to report
+	case 1:
+		resp.Aud = audience[0]
+	default:
+		resp.Aud = audience
+	}
+
+	// Client credentials tokens have the client itself as their subject
+	if info.IsClientToken() {
+		resp.Sub = info.ClientID
+		return resp, nil
+	}
+
+	// The token's client may know the user by a pairwise subject
+	sub, err := s.subjectFor(ctx, info.ClientID, info.UserID)
+	if err != nil {
+		return nil, err
+	}
+	resp.Sub = sub
+
+	// The username is optional, so a failed lookup does not invalidate the response
+	if user, err := s.userService.GetByID(ctx, info.UserID); err == nil {
+		resp.Username = user.Username
+	}
+
+	return resp, nil
+}
+
+// GetUserInfo returns the UserInfo claims for the user identified by an access token.
+// The token must be valid, unrevoked, and granted the openid scope.
+// The sub claim is always present and matches the one in the client's ID tokens;
+// other claims depend on the granted scopes. For a client that registered signed UserInfo
+// responses the claims are also returned as the signed, and possibly encrypted, JWT to send
+// instead of them; for other clients the JWT is empty.
+func (s *Service) GetUserInfo(ctx context.Context, accessToken string) (UserInfoResponse, string, error) {
+	claims, err := s.tokenService.ValidateAccessToken(ctx, accessToken)
+	if err != nil {
+		return nil, "", errors.Unauthorized(errors.ErrMsgInvalidToken)
+	}
+
+	scopes := strings.Fields(stringClaim(*claims, jwtutil.ClaimKeyScope))
+	if !containsScope(scopes, ScopeOpenID) {
+		return nil, "", errors.Forbidden(errors.ErrMsgInsufficientScope)
+	}
+
+	// The token's sub may be pairwise, so the user is taken from the stored token
+	info, _, err := s.tokenService.FindActiveToken(ctx, accessToken, token.KindAccessToken)
+	if err != nil {
+		return nil, "", err
+	}
+	if info == nil {
+		return nil, "", errors.Unauthorized(errors.ErrMsgInvalidToken)
+	}
+	if info.IsClientToken() {
+		return nil, "", errors.Unauthorized(errors.ErrMsgInvalidUserID)
+	}
+
+	user, err := s.userService.GetByID(ctx, info.UserID)
+	if err != nil {
+		return nil, "", err
+	}
+	if !user.IsActive {
+		return nil, "", errors.Unauthorized(errors.ErrMsgAccountNotActive)
+	}
+
+	sub, err := s.subjectFor(ctx, info.ClientID, user.ID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	resp := UserInfoResponse(s.claimRegistry.Claims(scopes, user))
+	resp[jwtutil.ClaimKeySub] = sub
+
+	signed, err := s.userInfoJWT(ctx, info.ClientID, resp)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return resp, signed, nil
+}
+
+// RequestDeviceAuthorization starts the device authorization flow (RFC 8628 Section 3.1).
+// The client must be registered for the device code grant. The requested scope is validated
+// against the client's allowed scopes, and a device code is issued for polling along with a
+// short user code for the verification page.
+func (s *Service) RequestDeviceAuthorization(ctx context.Context, req DeviceAuthorizationRequest) (*DeviceAuthorizationResponse, error) {
+	if !isGrantTypeEnabled(GrantTypeDeviceCode) {
+		return nil, errors.BadRequest(errors.ErrMsgUnsupportedGrantType)
+	}
+
+	client, err := s.clientService.GetByClientID(ctx, req.ClientID)
 	if err != nil {
 		return nil, err
 	}
-	return &UserInfoResponse{
-		Sub:               strconv.FormatUint(uint64(user.ID), 10),
-		Name:              user.Username,
-		Email:             user.Email,
-		EmailVerified:     user.IsVerified,
-		PreferredUsername: user.Username,
+	if client == nil || !client.IsActive {
+		return nil, errors.Unauthorized(errors.ErrMsgInvalidClient)
+	}
+
+	// Only clients registered for this grant may start it
+	if !containsScope(client.GrantTypes, GrantTypeDeviceCode) {
+		return nil, errors.BadRequest(errors.ErrMsgUnauthorizedClient)
+	}
+
+	requestedScope := req.Scope
+	if requestedScope == "" {
+		requestedScope = client.Scope
+	}
+
+	validScope, err := s.scopeService.ValidateScope(ctx, requestedScope, client.Scope)
+	if err != nil || !validScope {
+		return nil, errors.BadRequest(errors.ErrMsgInvalidScope)
+	}
+
+	deviceCode, err := s.generateAuthorizationCode()
+	if err != nil {
+		return nil, errors.Internal(errors.ErrMsgFailedToGenerateDeviceCode)
+	}
+
+	userCode, err := generateUserCode()
+	if err != nil {
+		return nil, errors.Internal(errors.ErrMsgFailedToGenerateDeviceCode)
+	}
+
+	now := time.Now()
+	code := &DeviceCode{
+		DeviceCode:   deviceCode,
+		UserCode:     userCode,
+		ClientID:     client.ClientID,
+		Scope:        requestedScope,
+		Status:       DeviceCodeStatusPending,
+		PollInterval: devicePollInterval,
+		ExpiresAt:    now.Add(deviceCodeExpiry),
+		CreatedAt:    now,
+	}
+
+	if err := s.oauthRepo.SaveDeviceCode(ctx, code); err != nil {
+		return nil, err
+	}
+
+	verificationURI := tenant.BaseURL(ctx) + DeviceVerificationPath
+	return &DeviceAuthorizationResponse{
+		DeviceCode:              deviceCode,
+		UserCode:                userCode,
+		VerificationURI:         verificationURI,
+		VerificationURIComplete: verificationURI + "?user_code=" + url.QueryEscape(userCode),
+		ExpiresIn:               int(deviceCodeExpiry.Seconds()),
+		Interval:                devicePollInterval,
+	}, nil
+}
+
+// GetDeviceVerificationPageData returns the details of the device authorization
+// request identified by a user code, for display on the verification page.
+func (s *Service) GetDeviceVerificationPageData(ctx context.Context, userCode string) (*DeviceVerificationPageData, error) {
+	code, err := s.findPendingDeviceCode(ctx, userCode)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := s.clientService.GetByClientID(ctx, code.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, errors.NotFound(errors.ErrMsgClientNotFound)
+	}
+
+	return &DeviceVerificationPageData{
+		UserCode:   code.UserCode,
+		ClientName: client.ClientName,
+		ClientID:   client.ClientID,
+		ScopeList:  strings.Fields(code.Scope),
 	}, nil
 }
 
+// VerifyDeviceCode records the user's decision for a pending device authorization request.
+// Once approved, the next poll by the device is answered with tokens for the user.
+func (s *Service) VerifyDeviceCode(ctx context.Context, userCode string, userID uint, approve bool) error {
+	code, err := s.findPendingDeviceCode(ctx, userCode)
+	if err != nil {
+		return err
+	}
+
+	status := DeviceCodeStatusDenied
+	if approve {
+		status = DeviceCodeStatusApproved
+	}
+
+	updated, err := s.oauthRepo.UpdateDeviceCodeStatus(ctx, code.DeviceCode, DeviceCodeStatusPending, status, userID)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return errors.BadRequest(errors.ErrMsgInvalidUserCode)
+	}
+
+	return nil
+}
+
+// SaveConsent records that the user granted the scopes to the client.
+// Scopes granted earlier are kept, so the stored grant accumulates across consents.
 func (s *Service) SaveConsent(ctx context.Context, userID uint, clientID, scope string) error {
 	consent, _ := s.oauthRepo.FindUserConsent(ctx, userID, clientID)
 
 	if consent != nil {
-		consent.Scope = scope
+		consent.Scope = mergeScopes(consent.Scope, scope)
 		consent.UpdatedAt = time.Now()
 		return s.oauthRepo.UpdateUserConsent(ctx, consent)
 	}
@@ -184,28 +621,133 @@ func (s *Service) SaveConsent(ctx context.Context, userID uint, clientID, scope
 	return s.oauthRepo.SaveUserConsent(ctx, consent)
 }
 
-func (s *Service) GetConsentPageData(ctx context.Context, clientID, scope string) (*ConsentPageData, error) {
+// GrantConsent records the user's approval of the scopes shown on the consent screen.
+// A nil approved list approves every requested scope. Required scopes awaiting approval
+// must be approved, and scopes that were not requested cannot be approved.
+// Returns the scope to authorize: the requested scopes that are now granted, in request order.
+func (s *Service) GrantConsent(ctx context.Context, userID uint, clientID, requestedScope string, approved []string) (string, error) {
+	requested := strings.Fields(requestedScope)
+	if approved == nil {
+		approved = requested
+	}
+
+	for _, scope := range approved {
+		if !containsScope(requested, scope) {
+			return "", errors.BadRequest(errors.ErrMsgInvalidScope)
+		}
+	}
+
+	pending := s.pendingConsentScopes(ctx, userID, clientID, requestedScope, false)
+	scopes, err := s.scopeService.GetScopesByNames(ctx, pending)
+	if err != nil {
+		return "", err
+	}
+	for _, scope := range scopes {
+		if scope.Required && !containsScope(approved, scope.Name) {
+			return "", errors.BadRequest(errors.ErrMsgRequiredScopeNotApproved)
+		}
+	}
+
+	var granted []string
+	for _, scope := range requested {
+		if containsScope(approved, scope) || !containsScope(pending, scope) {
+			granted = append(granted, scope)
+		}
+	}
+	if len(granted) == 0 {
+		return "", errors.Forbidden(errors.ErrMsgAccessDenied)
+	}
+
+	if len(approved) > 0 {
+		if err := s.SaveConsent(ctx, userID, clientID, strings.Join(approved, " ")); err != nil {
+			return "", err
+		}
+		s.auditService.Record(ctx, audit.Event{
+			Type:     audit.EventConsentGranted,
+			Actor:    audit.UserActor(userID),
+			ClientID: clientID,
+			Details:  map[string]string{"scope": strings.Join(approved, " ")},
+		})
+	}
+
+	return strings.Join(granted, " "), nil
+}
+
+// GetConsentPageData prepares the consent screen for an authorization request.
+// Scopes the user already granted to the client are listed separately and need no
+// approval, unless forceConsent is set by prompt=consent. Scope descriptions are given in
+// the first of the user's preferred locales they are translated into. A pending offline_access
+// is flagged on its own, so the screen can call out that the client keeps access after logout.
+func (s *Service) GetConsentPageData(ctx context.Context, userID uint, clientID, scope string, forceConsent bool, locales []string) (*ConsentPageData, error) {
 	client, err := s.clientService.GetByClientID(ctx, clientID)
 	if err != nil {
 		return nil, err
 	}
+	if client == nil {
+		return nil, errors.NotFound(errors.ErrMsgClientNotFound)
+	}
+
+	pending := s.pendingConsentScopes(ctx, userID, clientID, scope, forceConsent)
+	scopes, err := s.scopeService.GetScopesByNames(ctx, pending)
+	if err != nil {
+		return nil, err
+	}
+
+	// Approving a parent scope grants its children too, so the user is shown all of them
+	effective, err := s.scopeService.ExpandScope(ctx, strings.Join(pending, " "))
+	if err != nil {
+		return nil, err
+	}
+	effectiveScopes, err := s.scopeService.GetScopesByNames(ctx, strings.Fields(effective))
+	if err != nil {
+		return nil, err
+	}
 
-	scopes := strings.Split(scope, " ")
+	granted := []string{}
+	for _, requested := range strings.Fields(scope) {
+		if !containsScope(pending, requested) {
+			granted = append(granted, requested)
+		}
+	}
 
 	return &ConsentPageData{
-		ClientName:     client.ClientName,
-		ClientID:       clientID,
-		RequestedScope: scope,
-		ScopeList:      scopes,
+		ClientName:      client.ClientName,
+		ClientID:        clientID,
+		RequestedScope:  scope,
+		ScopeList:       pending,
+		Scopes:          consentScopeList(scopes, locales),
+		EffectiveScopes: consentScopeList(effectiveScopes, locales),
+		GrantedScopes:   granted,
+		OfflineAccess:   containsScope(pending, ScopeOfflineAccess),
 	}, nil
 }
 
+// consentScopeList describes scopes as they are listed on the consent screen,
+// in the preferred locales or the default locale.
+func consentScopeList(scopes []scope.Scope, locales []string) []ConsentScope {
+	list := make([]ConsentScope, 0, len(scopes))
+	for _, sc := range scopes {
+		list = append(list, ConsentScope{
+			Name:        sc.Name,
+			Description: sc.LocalizedDescription(locales, config.AppConfig.DefaultLocale),
+			Required:    sc.Required,
+		})
+	}
+	return list
+}
+
 // Private helper methods
 
 func (s *Service) handleAuthorizationCodeGrant(ctx context.Context, req TokenRequest) (*TokenResponse, error) {
+	// The code was issued moments ago, by another request, so the exchange reads from the primary
+	ctx = routing.WithPrimary(ctx)
+
 	// Validate required parameters
-	if req.Code == "" || req.RedirectURI == "" {
-		return nil, errors.BadRequest(errors.ErrMsgInvalidRequest)
+	if req.Code == "" {
+		return nil, missingParameter("code")
+	}
+	if req.RedirectURI == "" {
+		return nil, missingParameter("redirect_uri")
 	}
 
 	// Get and validate authorization code
@@ -217,69 +759,181 @@ func (s *Service) handleAuthorizationCodeGrant(ctx context.Context, req TokenReq
 		return nil, errors.BadRequest(errors.ErrMsgInvalidGrant)
 	}
 
-	// Validate code hasn't been used
-	if authCode.IsUsed {
-		// Security: revoke all tokens associated with this code
-		s.tokenService.RevokeTokensByAuthCode(ctx, req.Code)
+	// Validate client and redirect URI first, so only the code's own client can set off the
+	// replay handling below; anyone else presenting the code is simply refused
+	if authCode.ClientID != req.ClientID {
 		return nil, errors.BadRequest(errors.ErrMsgInvalidGrant)
 	}
-
-	// Validate code hasn't expired
-	if time.Now().After(authCode.ExpiresAt) {
+	if authCode.RedirectURI != req.RedirectURI {
 		return nil, errors.BadRequest(errors.ErrMsgInvalidGrant)
 	}
 
-	// Validate client
-	if authCode.ClientID != req.ClientID {
-		return nil, errors.BadRequest(errors.ErrMsgInvalidGrant)
+	// Validate code hasn't been used
+	if authCode.IsUsed {
+		return nil, s.handleAuthorizationCodeReplay(ctx, req.Code, req.ClientID)
 	}
 
-	// Validate redirect URI
-	if authCode.RedirectURI != req.RedirectURI {
+	// Validate code hasn't expired
+	if time.Now().After(authCode.ExpiresAt) {
 		return nil, errors.BadRequest(errors.ErrMsgInvalidGrant)
 	}
 
 	// Validate PKCE if used
 	if authCode.CodeChallenge != "" {
-		if req.CodeVerifier == "" {
+		if !pkce.IsValidCodeVerifier(req.CodeVerifier) {
 			return nil, errors.BadRequest(errors.ErrMsgInvalidGrant)
 		}
 
 		if !pkce.VerifyCodeChallenge(req.CodeVerifier, authCode.CodeChallenge, authCode.CodeChallengeMethod) {
 			return nil, errors.BadRequest(errors.ErrMsgInvalidGrant)
 		}
+	} else if req.CodeVerifier != "" {
+		// A verifier without a registered challenge indicates a downgrade attempt
+		return nil, errors.BadRequest(errors.ErrMsgInvalidGrant)
+	}
+
+	// Managed_state clients return the state issued with the code; checked before the code is used up
+	if err := s.verifyManagedState(ctx, authCode, req.State); err != nil {
+		return nil, err
+	}
+
+	// Grant management parameters are checked before the code is used up too
+	grant, err := s.prepareGrant(ctx, req, authCode)
+	if err != nil {
+		return nil, err
 	}
 
-	// Mark code as used
-	if err := s.oauthRepo.MarkCodeAsUsed(ctx, req.Code); err != nil {
+	// Consume the code; losing the race means a concurrent exchange already used it
+	consumed, err := s.oauthRepo.MarkCodeAsUsed(ctx, req.Code)
+	if err != nil {
 		return nil, errors.Internal(errors.ErrMsgFailedToMarkCodeAsUsed)
 	}
+	if !consumed {
+		return nil, s.handleAuthorizationCodeReplay(ctx, req.Code, req.ClientID)
+	}
+
+	opts, err := s.accessTokenOptions(ctx, GrantTypeAuthorizationCode, authCode.ClientID, authCode.UserID, authCode.Scope, authCode.Resources, req.Resource)
+	if err != nil {
+		return nil, err
+	}
+	opts.AuthTime = authCode.AuthTime
+	opts.ACR = authCode.ACR
+
+	if grant != nil {
+		if err := s.saveGrant(ctx, grant, req.GrantManagementAction); err != nil {
+			return nil, err
+		}
+		opts.GrantID = grant.GrantID
+	}
+
+	// OpenID Connect requests only get a refresh token along with offline_access (Core Section 11)
+	grantedScopes := strings.Fields(authCode.Scope)
+	opts.WithoutRefreshToken = containsScope(grantedScopes, ScopeOpenID) && !containsScope(grantedScopes, ScopeOfflineAccess)
 
 	// Generate tokens
-	tokenResp, err := s.tokenService.CreateTokens(ctx, authCode.UserID, authCode.ClientID, authCode.Scope, req.Code)
+	tokenResp, err := s.tokenService.CreateTokens(ctx, authCode.UserID, authCode.ClientID, authCode.Scope, req.Code, opts)
 	if err != nil {
 		return nil, err
 	}
+	s.recordAuthorizationUse(ctx, authCode.UserID, authCode.ClientID)
+
+	// A replay arriving while the tokens were stored may have revoked the code's tokens
+	// before these existed; it flags the code first, so either it revoked them or this sees the flag
+	if current, err := s.oauthRepo.FindAuthorizationCode(ctx, req.Code); err != nil || current == nil || current.ReplayDetected {
+		s.tokenService.RevokeTokensByAuthCode(ctx, req.Code, authCode.ClientID)
+		return nil, errors.BadRequest(errors.ErrMsgAuthorizationCodeReused)
+	}
+
+	// Likewise a revocation of the grant may have missed tokens stored while it ran
+	if !s.isGrantActive(ctx, opts.GrantID) {
+		s.tokenService.RevokeGrantTokens(ctx, opts.GrantID)
+		return nil, errors.BadRequest(errors.ErrMsgInvalidGrantID)
+	}
 
 	// Convert token.TokenCreateResponse to TokenResponse
-	return &TokenResponse{
+	resp := &TokenResponse{
 		AccessToken:  tokenResp.AccessToken,
 		TokenType:    tokenResp.TokenType,
 		ExpiresIn:    tokenResp.ExpiresIn,
 		RefreshToken: tokenResp.RefreshToken,
 		Scope:        tokenResp.Scope,
-	}, nil
+		GrantID:      opts.GrantID,
+	}
+
+	// OpenID Connect requests also receive an ID token
+	if containsScope(grantedScopes, ScopeOpenID) {
+		idToken, err := s.createIDToken(ctx, authCode, opts.Lifetimes.IDToken, "", "")
+		if err != nil {
+			return nil, errors.Internal(errors.ErrMsgFailedToGenerateIDToken)
+		}
+		resp.IDToken = idToken
+
+		// Remember the session so the client is told when it ends
+		if authCode.SessionID != "" {
+			if err := s.logoutService.RecordSession(ctx, authCode.ClientID, authCode.UserID, authCode.SessionID); err != nil {
+				// Not critical, continue
+			}
+		}
+	}
+
+	return resp, nil
+}
+
+// handleAuthorizationCodeReplay responds to an authorization code presented after it was used.
+// The code is flagged as replayed before the tokens issued for it are revoked (RFC 6749
+// Section 4.1.2), so an exchange of the code still storing its tokens revokes them itself.
+// Returns the invalid_grant error to report to the client.
+func (s *Service) handleAuthorizationCodeReplay(ctx context.Context, code, clientID string) error {
+	if err := s.oauthRepo.MarkCodeReplayed(ctx, code); err != nil {
+		return err
+	}
+	if err := s.tokenService.RevokeTokensByAuthCode(ctx, code, clientID); err != nil {
+		return err
+	}
+	return errors.BadRequest(errors.ErrMsgAuthorizationCodeReused)
 }
 
 func (s *Service) handleRefreshTokenGrant(ctx context.Context, req TokenRequest) (*TokenResponse, error) {
 	if req.RefreshToken == "" {
-		return nil, errors.BadRequest(errors.ErrMsgInvalidRequest)
+		return nil, missingParameter("refresh_token")
+	}
+
+	// Without a requested scope the new token keeps the scope, and so the audience, of the original.
+	// A requested scope, like the resources granted to the original, narrows only the new access
+	// token; anything outside the original grant is invalid_scope. The grant is kept for the next refresh.
+	scope := req.Scope
+	var granted []string
+	var userID uint
+	var grantID string
+	if info, _, err := s.tokenService.FindActiveToken(ctx, req.RefreshToken, token.KindRefreshToken); err == nil && info != nil {
+		if scope == "" {
+			scope = info.Scope
+		}
+		for _, requested := range strings.Fields(scope) {
+			if !containsScope(strings.Fields(info.Scope), requested) {
+				return nil, errors.BadRequest(errors.ErrMsgInvalidScope).WithDetails(errors.ErrMsgRequestedScopeExceedsOriginal)
+			}
+		}
+		granted = info.Audience
+		userID = info.UserID
+		grantID = info.GrantID
+	}
+
+	// Tokens of a revoked grant cannot be refreshed
+	if !s.isGrantActive(ctx, grantID) {
+		return nil, errors.BadRequest(errors.ErrMsgInvalidGrant).WithDetails(errors.ErrMsgGrantRevoked)
+	}
+
+	opts, err := s.accessTokenOptions(ctx, GrantTypeRefreshToken, req.ClientID, userID, scope, granted, req.Resource)
+	if err != nil {
+		return nil, err
 	}
 
-	tokenResp, err := s.tokenService.RefreshTokens(ctx, req.RefreshToken, req.ClientID, req.Scope)
+	tokenResp, err := s.tokenService.RefreshTokens(ctx, req.RefreshToken, req.ClientID, req.Scope, opts)
 	if err != nil {
 		return nil, err
 	}
+	s.recordAuthorizationUse(ctx, userID, req.ClientID)
 
 	return &TokenResponse{
 		AccessToken:  tokenResp.AccessToken,
@@ -287,55 +941,329 @@ func (s *Service) handleRefreshTokenGrant(ctx context.Context, req TokenRequest)
 		ExpiresIn:    tokenResp.ExpiresIn,
 		RefreshToken: tokenResp.RefreshToken,
 		Scope:        tokenResp.Scope,
+		GrantID:      grantID,
 	}, nil
 }
 
-func (s *Service) needsConsent(ctx context.Context, userID uint, clientID, scope string) bool {
+// handleClientCredentialsGrant issues an access token to a confidential client
+// acting on its own behalf (RFC 6749 Section 4.4).
+// The granted scope is the intersection of the requested scope and the client's
+// registered scope, or the full registered scope when none is requested.
+func (s *Service) handleClientCredentialsGrant(ctx context.Context, req TokenRequest) (*TokenResponse, error) {
+	client, err := s.clientService.GetByClientID(ctx, req.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil || !client.IsActive {
+		return nil, errors.Unauthorized(errors.ErrMsgInvalidClient)
+	}
+
+	// Only confidential clients registered for this grant may use it
+	if !client.IsConfidential || !containsScope(client.GrantTypes, GrantTypeClientCredentials) {
+		return nil, errors.BadRequest(errors.ErrMsgUnauthorizedClient)
+	}
+
+	allowed := strings.Fields(client.Scope)
+	granted := allowed
+	if req.Scope != "" {
+		granted = nil
+		for _, requested := range strings.Fields(req.Scope) {
+			if containsScope(allowed, requested) && !containsScope(granted, requested) {
+				granted = append(granted, requested)
+			}
+		}
+		if len(granted) == 0 {
+			return nil, errors.BadRequest(errors.ErrMsgInvalidScope)
+		}
+	}
+
+	scope := strings.Join(granted, " ")
+	opts, err := s.accessTokenOptions(ctx, GrantTypeClientCredentials, client.ClientID, 0, scope, nil, req.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenResp, err := s.tokenService.CreateClientToken(ctx, client.ClientID, scope, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenResponse{
+		AccessToken: tokenResp.AccessToken,
+		TokenType:   tokenResp.TokenType,
+		ExpiresIn:   tokenResp.ExpiresIn,
+		Scope:       tokenResp.Scope,
+	}, nil
+}
+
+// handleDeviceCodeGrant exchanges an approved device code for tokens (RFC 8628 Section 3.4).
+// While the user has not yet decided, the device receives authorization_pending,
+// or slow_down if it polls faster than the interval; each slow_down extends the
+// interval by five seconds.
+func (s *Service) handleDeviceCodeGrant(ctx context.Context, req TokenRequest) (*TokenResponse, error) {
+	if req.DeviceCode == "" {
+		return nil, missingParameter("device_code")
+	}
+
+	code, err := s.oauthRepo.FindDeviceCode(ctx, req.DeviceCode)
+	if err != nil {
+		return nil, err
+	}
+	if code == nil || code.ClientID != req.ClientID {
+		return nil, errors.BadRequest(errors.ErrMsgInvalidGrant)
+	}
+
+	if time.Now().After(code.ExpiresAt) {
+		return nil, errors.BadRequest(errors.ErrMsgExpiredToken)
+	}
+
+	switch code.Status {
+	case DeviceCodeStatusPending:
+		// Poll tracking failures are not fatal; the device simply is not slowed down
+		tooFast, err := s.pollRepo.RecordPoll(ctx, code.DeviceCode, time.Duration(code.PollInterval)*time.Second)
+		if err == nil && tooFast {
+			s.oauthRepo.UpdateDeviceCodeInterval(ctx, code.DeviceCode, code.PollInterval+devicePollSlowDownStep)
+			return nil, errors.BadRequest(errors.ErrMsgSlowDown)
+		}
+		return nil, errors.BadRequest(errors.ErrMsgAuthorizationPending)
+	case DeviceCodeStatusDenied:
+		return nil, errors.BadRequest(errors.ErrMsgAccessDenied)
+	case DeviceCodeStatusApproved:
+		// Handled below
+	default:
+		return nil, errors.BadRequest(errors.ErrMsgInvalidGrant)
+	}
+
+	// Consume the code atomically so concurrent polls cannot both receive tokens
+	consumed, err := s.oauthRepo.UpdateDeviceCodeStatus(ctx, code.DeviceCode, DeviceCodeStatusApproved, DeviceCodeStatusConsumed, 0)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, errors.BadRequest(errors.ErrMsgInvalidGrant)
+	}
+
+	opts, err := s.accessTokenOptions(ctx, GrantTypeDeviceCode, code.ClientID, code.UserID, code.Scope, nil, req.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenResp, err := s.tokenService.CreateTokens(ctx, code.UserID, code.ClientID, code.Scope, "", opts)
+	if err != nil {
+		return nil, err
+	}
+	s.recordAuthorizationUse(ctx, code.UserID, code.ClientID)
+
+	return &TokenResponse{
+		AccessToken:  tokenResp.AccessToken,
+		TokenType:    tokenResp.TokenType,
+		ExpiresIn:    tokenResp.ExpiresIn,
+		RefreshToken: tokenResp.RefreshToken,
+		Scope:        tokenResp.Scope,
+	}, nil
+}
+
+// findPendingDeviceCode looks up an unexpired, undecided device code by user code.
+// The user code is normalized first, so it may be entered without the separator
+// or in lower case.
+func (s *Service) findPendingDeviceCode(ctx context.Context, userCode string) (*DeviceCode, error) {
+	code, err := s.oauthRepo.FindDeviceCodeByUserCode(ctx, normalizeUserCode(userCode))
+	if err != nil {
+		return nil, err
+	}
+	if code == nil || code.Status != DeviceCodeStatusPending || time.Now().After(code.ExpiresAt) {
+		return nil, errors.BadRequest(errors.ErrMsgInvalidUserCode)
+	}
+	return code, nil
+}
+
+// OfflineAccessScope returns the requested scope without offline_access unless the request
+// carries prompt=consent, so the user is asked, or the user already granted offline access
+// to the client (OpenID Connect Core Section 11).
+func (s *Service) OfflineAccessScope(ctx context.Context, userID uint, clientID, scope, prompt string) string {
+	requested := strings.Fields(scope)
+	if !containsScope(requested, ScopeOfflineAccess) || hasPrompt(prompt, PromptConsent) {
+		return scope
+	}
+	if pending := s.pendingConsentScopes(ctx, userID, clientID, ScopeOfflineAccess, false); len(pending) == 0 {
+		return scope
+	}
+
+	kept := make([]string, 0, len(requested))
+	for _, r := range requested {
+		if r != ScopeOfflineAccess {
+			kept = append(kept, r)
+		}
+	}
+	return strings.Join(kept, " ")
+}
+
+// pendingConsentScopes returns the requested scopes the user has not yet granted to the client.
+// Scopes implied by a granted scope count as granted. With forceConsent every requested scope
+// is pending, and if the stored grant cannot be read the user is asked again for everything.
+func (s *Service) pendingConsentScopes(ctx context.Context, userID uint, clientID, scope string, forceConsent bool) []string {
+	requested := strings.Fields(scope)
+	if forceConsent {
+		return requested
+	}
+
 	consent, err := s.oauthRepo.FindUserConsent(ctx, userID, clientID)
 	if err != nil || consent == nil {
-		return true
+		return requested
 	}
 
-	// Check if requested scope is within already consented scope
-	requestedScopes := strings.Split(scope, " ")
-	consentedScopes := strings.Split(consent.Scope, " ")
+	// Scopes implied by a granted parent scope were granted along with it
+	consentedScope, err := s.scopeService.ExpandScope(ctx, consent.Scope)
+	if err != nil {
+		consentedScope = consent.Scope
+	}
+	consented := strings.Fields(consentedScope)
+	pending := []string{}
+	for _, r := range requested {
+		if !containsScope(consented, r) {
+			pending = append(pending, r)
+		}
+	}
+	return pending
+}
 
-	for _, requested := range requestedScopes {
-		found := false
-		for _, consented := range consentedScopes {
-			if requested == consented {
-				found = true
-				break
-			}
+// validateCodeChallenge checks the PKCE parameters of an authorization request.
+// Public clients and clients flagged with RequirePKCE must send a code challenge.
+// The method defaults to "plain" when a challenge is sent without one (RFC 7636 Section 4.3).
+// Returns the effective challenge method, or an error if the parameters are invalid.
+func (s *Service) validateCodeChallenge(client *client.Client, codeChallenge, method string) (string, error) {
+	if codeChallenge == "" {
+		if method != "" {
+			return "", errors.BadRequest(errors.ErrMsgInvalidRequest)
 		}
-		if !found {
-			return true
+		if !client.IsConfidential || client.RequirePKCE {
+			return "", errors.BadRequest(errors.ErrMsgCodeChallengeRequired)
 		}
+		return "", nil
 	}
 
-	return false
+	if method == "" {
+		method = pkce.MethodPlain
+	}
+	if !pkce.IsValidMethod(method) {
+		return "", errors.BadRequest(errors.ErrMsgInvalidCodeChallengeMethod)
+	}
+
+	// S256 challenges are always 43 characters; plain challenges follow the verifier format
+	if !pkce.IsValidCodeVerifier(codeChallenge) {
+		return "", errors.BadRequest(errors.ErrMsgInvalidRequest)
+	}
+
+	return method, nil
 }
 
+// generateAuthorizationCode creates a random authorization or device code with the configured entropy.
 func (s *Service) generateAuthorizationCode() (string, error) {
-	b := make([]byte, 32)
-	if _, err := rand.Read(b); err != nil {
-		return "", err
+	return opaque.Random()
+}
+
+// generateUserCode creates a random user code such as "BDFH-JKLM".
+// The alphabet excludes vowels and easily confused characters (RFC 8628 Section 6.1).
+func generateUserCode() (string, error) {
+	alphabetSize := big.NewInt(int64(len(userCodeAlphabet)))
+
+	code := make([]byte, 0, userCodeLength+1)
+	for i := 0; i < userCodeLength; i++ {
+		if i == userCodeLength/2 {
+			code = append(code, '-')
+		}
+		n, err := rand.Int(rand.Reader, alphabetSize)
+		if err != nil {
+			return "", err
+		}
+		code = append(code, userCodeAlphabet[n.Int64()])
+	}
+
+	return string(code), nil
+}
+
+// normalizeUserCode converts user input into the canonical "XXXX-XXXX" user code form.
+// Case, whitespace, and separators in the input are ignored.
+func normalizeUserCode(input string) string {
+	var chars []rune
+	for _, r := range strings.ToUpper(input) {
+		if r >= 'A' && r <= 'Z' {
+			chars = append(chars, r)
+		}
+	}
+
+	if len(chars) != userCodeLength {
+		return string(chars)
 	}
-	return base64.URLEncoding.EncodeToString(b), nil
+	return string(chars[:userCodeLength/2]) + "-" + string(chars[userCodeLength/2:])
 }
 
 // Additional methods for client validation
-func (s *Service) ValidateClient(ctx context.Context, clientID, clientSecret string) (*client.Client, error) {
-	return s.clientService.ValidateClient(ctx, clientID, clientSecret)
+
+// IsAllowedOrigin reports whether a browser origin may call the OAuth endpoints
+// cross-origin, based on the redirect URIs registered by active clients.
+// Lookup failures deny the origin.
+func (s *Service) IsAllowedOrigin(ctx context.Context, origin string) bool {
+	allowed, err := s.clientService.IsRegisteredOrigin(ctx, origin)
+	return err == nil && allowed
 }
 
-func (s *Service) IsPublicClient(ctx context.Context, clientID string) (bool, error) {
-	client, err := s.clientService.GetByClientID(ctx, clientID)
+// AuthenticateClient verifies the client credentials presented at a token endpoint.
+// Signed client assertions are verified here; secret-based methods and public clients
+// are delegated to the client service.
+func (s *Service) AuthenticateClient(ctx context.Context, creds ClientCredentials) (*client.Client, error) {
+	if creds.Method == client.AuthMethodPrivateKeyJWT {
+		return s.authenticateClientAssertion(ctx, creds)
+	}
+	if creds.Method == client.AuthMethodNone && creds.ClientCertificate != nil {
+		return s.authenticateClientCertificate(ctx, creds)
+	}
+	return s.clientService.AuthenticateClient(ctx, creds.ClientID, creds.ClientSecret, creds.Method)
+}
+
+// subjectFor returns the sub value identifying the user to the client, which is
+// pairwise for clients registered with that subject type.
+func (s *Service) subjectFor(ctx context.Context, clientID string, userID uint) (string, error) {
+	c, err := s.clientService.GetByClientID(ctx, clientID)
 	if err != nil {
-		return false, err
+		return "", err
 	}
-	if client == nil {
-		return false, errors.NotFound(errors.ErrMsgClientNotFound)
+	if c == nil {
+		return strconv.FormatUint(uint64(userID), 10), nil
+	}
+	return c.Subject(userID), nil
+}
+
+// stringClaim returns the string value of a token claim, or an empty string if absent.
+func stringClaim(claims map[string]interface{}, key string) string {
+	value, _ := claims[key].(string)
+	return value
+}
+
+// mergeScopes returns the union of two space-separated scope strings, keeping the order
+// of first appearance.
+func mergeScopes(existing, added string) string {
+	merged := strings.Fields(existing)
+	for _, scope := range strings.Fields(added) {
+		if !containsScope(merged, scope) {
+			merged = append(merged, scope)
+		}
 	}
-	return !client.IsConfidential, nil
+	return strings.Join(merged, " ")
+}
+
+// hasPrompt reports whether the space-separated prompt parameter contains value.
+func hasPrompt(prompt, value string) bool {
+	return containsScope(strings.Fields(prompt), value)
+}
+
+// containsScope reports whether scope is present in the list of scopes.
+// It is also used for other space-delimited value lists such as grant types.
+func containsScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,203 @@
+package oauth
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+)
+
+// errorCode returns the code of a CustomError, or an empty string for any other error.
+func errorCode(err error) string {
+	if customErr, ok := errors.As(err); ok {
+		return customErr.Code()
+	}
+	return ""
+}
+
+func TestAuthorizationCodeReplayRevokesTokens(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+
+	const redirectURI = "https://app.example.com/callback"
+	s.addClient(t, "client-a", redirectURI)
+	s.addCode(t, "code-1", "client-a", redirectURI, "profile offline_access")
+	req := TokenRequest{Code: "code-1", RedirectURI: redirectURI, ClientID: "client-a"}
+
+	if _, err := s.handleAuthorizationCodeGrant(ctx, req); err != nil {
+		t.Fatalf("first exchange failed: %v", err)
+	}
+
+	_, err := s.handleAuthorizationCodeGrant(ctx, req)
+	if errorCode(err) != errors.ErrMsgAuthorizationCodeReused {
+		t.Fatalf("replayed exchange: got error %v, want %q", err, errors.ErrMsgAuthorizationCodeReused)
+	}
+	if access, refresh := s.tokens.activeTokens(); access != 0 || refresh != 0 {
+		t.Errorf("replay left %d access and %d refresh tokens active, want none", access, refresh)
+	}
+}
+
+func TestUsedAuthorizationCodeOfAnotherClientIsNoReplay(t *testing.T) {
+	const redirectURI = "https://app.example.com/callback"
+
+	tests := []struct {
+		name string
+		req  TokenRequest
+	}{
+		{"another client", TokenRequest{Code: "code-1", RedirectURI: redirectURI, ClientID: "client-b"}},
+		{"another redirect URI", TokenRequest{Code: "code-1", RedirectURI: "https://app.example.com/other", ClientID: "client-a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t)
+			ctx := context.Background()
+
+			s.addClient(t, "client-a", redirectURI, "https://app.example.com/other")
+			s.addClient(t, "client-b", redirectURI)
+			s.addCode(t, "code-1", "client-a", redirectURI, "profile offline_access")
+			if _, err := s.handleAuthorizationCodeGrant(ctx, TokenRequest{Code: "code-1", RedirectURI: redirectURI, ClientID: "client-a"}); err != nil {
+				t.Fatalf("first exchange failed: %v", err)
+			}
+
+			_, err := s.handleAuthorizationCodeGrant(ctx, tt.req)
+			if errorCode(err) != errors.ErrMsgInvalidGrant {
+				t.Fatalf("got error %v, want %q", err, errors.ErrMsgInvalidGrant)
+			}
+			if access, refresh := s.tokens.activeTokens(); access != 1 || refresh != 1 {
+				t.Errorf("left %d access and %d refresh tokens active, want the tokens of the code kept", access, refresh)
+			}
+			if authCode, _ := s.codes.FindAuthorizationCode(ctx, "code-1"); authCode.ReplayDetected {
+				t.Error("code flagged as replayed")
+			}
+		})
+	}
+}
+
+func TestConcurrentAuthorizationCodeExchange(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+
+	const redirectURI = "https://app.example.com/callback"
+	s.addClient(t, "client-a", redirectURI)
+	s.addCode(t, "code-1", "client-a", redirectURI, "profile offline_access")
+	req := TokenRequest{Code: "code-1", RedirectURI: redirectURI, ClientID: "client-a"}
+
+	// Both exchanges read the code before either consumes it, so both pass the IsUsed check
+	// and race on MarkCodeAsUsed
+	var reads int32
+	var bothRead sync.WaitGroup
+	bothRead.Add(2)
+	s.codes.afterFind = func() {
+		if atomic.AddInt32(&reads, 1) <= 2 {
+			bothRead.Done()
+			bothRead.Wait()
+		}
+	}
+
+	// The losing exchange is held until the winner returns, so the winner's tokens exist
+	// when the loser revokes them
+	release := make(chan struct{})
+	s.codes.afterMarkUsed = func(marked bool) {
+		if !marked {
+			<-release
+		}
+	}
+
+	type result struct {
+		resp *TokenResponse
+		err  error
+	}
+	results := make(chan result, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			resp, err := s.handleAuthorizationCodeGrant(ctx, req)
+			results <- result{resp, err}
+		}()
+	}
+
+	winner := <-results
+	close(release)
+	loser := <-results
+
+	if winner.err != nil || winner.resp == nil || winner.resp.AccessToken == "" {
+		t.Fatalf("winning exchange: got %+v, %v, want tokens", winner.resp, winner.err)
+	}
+	if errorCode(loser.err) != errors.ErrMsgAuthorizationCodeReused {
+		t.Fatalf("losing exchange: got error %v, want %q", loser.err, errors.ErrMsgAuthorizationCodeReused)
+	}
+	if access, refresh := s.tokens.activeTokens(); access != 0 || refresh != 0 {
+		t.Errorf("losing exchange left %d access and %d refresh tokens active, want none", access, refresh)
+	}
+}
+
+func TestAuthorizationCodeRefreshTokenFollowsOfflineAccess(t *testing.T) {
+	const redirectURI = "https://app.example.com/callback"
+
+	tests := []struct {
+		name        string
+		scope       string
+		wantRefresh bool
+	}{
+		{"OpenID Connect with offline_access", "openid offline_access", true},
+		{"OpenID Connect without offline_access", "openid profile", false},
+		{"plain OAuth", "profile", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t)
+			ctx := context.Background()
+			s.addClient(t, "client-a", redirectURI)
+			s.addCode(t, "code-1", "client-a", redirectURI, tt.scope)
+
+			resp, err := s.handleAuthorizationCodeGrant(ctx, TokenRequest{Code: "code-1", RedirectURI: redirectURI, ClientID: "client-a"})
+			if err != nil {
+				t.Fatalf("exchange failed: %v", err)
+			}
+			if got := resp.RefreshToken != ""; got != tt.wantRefresh {
+				t.Errorf("got refresh token %q, want one issued: %v", resp.RefreshToken, tt.wantRefresh)
+			}
+
+			wantStored := 0
+			if tt.wantRefresh {
+				wantStored = 1
+			}
+			if _, refresh := s.tokens.activeTokens(); refresh != wantStored {
+				t.Errorf("stored %d refresh tokens, want %d", refresh, wantStored)
+			}
+		})
+	}
+}
+
+func TestClientCredentialsNeverIssuesRefreshToken(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	s.addClient(t, "client-a", "https://app.example.com/callback")
+
+	resp, err := s.handleClientCredentialsGrant(ctx, TokenRequest{ClientID: "client-a", Scope: "offline_access profile"})
+	if err != nil {
+		t.Fatalf("client credentials grant failed: %v", err)
+	}
+	if resp.AccessToken == "" {
+		t.Fatal("got no access token")
+	}
+	if resp.RefreshToken != "" {
+		t.Errorf("got refresh token %q, want none", resp.RefreshToken)
+	}
+	if _, refresh := s.tokens.activeTokens(); refresh != 0 {
+		t.Errorf("stored %d refresh tokens, want none", refresh)
+	}
+}
+
+func TestDeviceAuthorizationRequiresRegisteredGrant(t *testing.T) {
+	s := newTestService(t)
+	s.addClient(t, "client-a", "https://app.example.com/callback")
+
+	_, err := s.RequestDeviceAuthorization(context.Background(), DeviceAuthorizationRequest{ClientID: "client-a", Scope: "openid"})
+	if errorCode(err) != errors.ErrMsgUnauthorizedClient {
+		t.Errorf("got error %v, want %s for a client not registered for the device code grant", err, errors.ErrMsgUnauthorizedClient)
+	}
+}
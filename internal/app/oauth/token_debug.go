@@ -0,0 +1,202 @@
+package oauth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/verigate/verigate-server/internal/app/token"
+	"github.com/verigate/verigate-server/internal/pkg/config"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+	jwtutil "github.com/verigate/verigate-server/internal/pkg/utils/jwt"
+)
+
+// Formats of the tokens the debugging endpoint reports on
+const (
+	TokenFormatJWT    = "jwt"    // A signed JWT, decoded and verified
+	TokenFormatJWE    = "jwe"    // An encrypted JWT, of which only the header can be read
+	TokenFormatOpaque = "opaque" // A reference token, shown as the introspection view
+)
+
+// TokenDebugRequest represents a token submitted for debugging.
+type TokenDebugRequest struct {
+	Token string `json:"token" binding:"required"` // The token to decode and validate
+}
+
+// TokenDebugResponse reports what a token holds and whether the server accepts it. It carries
+// only what the token itself and the server's records say about it, never key material.
+type TokenDebugResponse struct {
+	Format         string                 `json:"format"`                    // jwt, jwe, or opaque
+	Kind           string                 `json:"kind,omitempty"`            // access_token or refresh_token when the server stores the token
+	Header         map[string]interface{} `json:"header,omitempty"`          // Decoded JOSE header
+	Claims         map[string]interface{} `json:"claims,omitempty"`          // Decoded claims of a signed JWT
+	SignatureValid bool                   `json:"signature_valid"`           // Whether a JWT verifies with the published keys
+	SignatureError string                 `json:"signature_error,omitempty"` // Why a JWT does not verify
+	Expired        bool                   `json:"expired"`                   // Whether the token is past its expiry
+	Revoked        bool                   `json:"revoked"`                   // Whether the server has revoked the token
+	Active         bool                   `json:"active"`                    // Whether the server would accept the token now
+	Introspection  *IntrospectionResponse `json:"introspection,omitempty"`   // Introspection view of an opaque token
+}
+
+// DebugToken decodes a token and reports whether its signature verifies with the keys of the
+// tenant ctx is served for, whether it has expired, and whether it was revoked, so developers
+// need not paste tokens into third-party debuggers. Opaque tokens are shown as the server
+// would introspect them. Administrators, the users listed in ADMIN_USER_IDS, may inspect any
+// token; other users only tokens issued to them or to a client they own. Tokens the server did not issue reveal nothing beyond what
+// they hold themselves and are shown to anyone.
+//
+// HS256 ID tokens are keyed with the client's secret and are reported as not verifying here.
+func (s *Service) DebugToken(ctx context.Context, userID uint, tokenValue string) (*TokenDebugResponse, error) {
+	tokenValue = strings.TrimSpace(tokenValue)
+	resp := &TokenDebugResponse{Format: TokenFormatOpaque}
+
+	var claims jwt.MapClaims
+	switch strings.Count(tokenValue, ".") {
+	case 2:
+		parsed, _, err := new(jwt.Parser).ParseUnverified(tokenValue, jwt.MapClaims{})
+		if err != nil {
+			return nil, errors.BadRequest(errors.ErrMsgMalformedDebugToken)
+		}
+		claims, _ = parsed.Claims.(jwt.MapClaims)
+		resp.Format, resp.Header, resp.Claims = TokenFormatJWT, parsed.Header, claims
+
+		alg, _ := parsed.Header["alg"].(string)
+		if alg == jwtutil.SigningAlgHS256 {
+			resp.SignatureError = errors.ErrMsgDebugTokenSharedKey
+		} else if err := verifyDebugTokenSignature(ctx, tokenValue, alg); err != nil {
+			resp.SignatureError = err.Error()
+		} else {
+			resp.SignatureValid = true
+		}
+		resp.Expired = !claims.VerifyExpiresAt(time.Now().Add(-jwtutil.ClockSkew()).Unix(), false)
+	case 4:
+		header, err := decodeJOSEHeader(tokenValue)
+		if err != nil {
+			return nil, errors.BadRequest(errors.ErrMsgMalformedDebugToken)
+		}
+		resp.Format, resp.Header = TokenFormatJWE, header
+	}
+
+	info, kind, err := s.tokenService.LookupToken(ctx, tokenValue)
+	if err != nil {
+		return nil, err
+	}
+	if info != nil {
+		resp.Kind = kind
+		resp.Revoked = info.IsRevoked
+		resp.Expired = resp.Expired || time.Now().After(info.ExpiresAt)
+	}
+
+	if !isAdminUser(userID) {
+		allowed, err := s.mayDebugToken(ctx, userID, info, claims, resp.SignatureValid)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			return nil, errors.Forbidden(errors.ErrMsgTokenDebugForbidden)
+		}
+	}
+
+	if resp.Format == TokenFormatOpaque {
+		if resp.Introspection, err = s.Introspect(ctx, IntrospectRequest{Token: tokenValue}); err != nil {
+			return nil, err
+		}
+	}
+
+	// Signed tokens are accepted on their signature, the others only when the server stores them
+	accepted := info != nil
+	if resp.Format == TokenFormatJWT {
+		accepted = resp.SignatureValid
+	}
+	resp.Active = accepted && !resp.Expired && !resp.Revoked
+	return resp, nil
+}
+
+// mayDebugToken reports whether the user may inspect a token that is not theirs by default: a
+// token the server stores must have been issued to the user or to a client they own, and a
+// verified JWT it does not store must be addressed to a client they own or name the user as
+// its subject. Tokens the server did not issue hold nothing the user could not decode anyway.
+func (s *Service) mayDebugToken(ctx context.Context, userID uint, info *token.TokenInfo, claims jwt.MapClaims, verified bool) (bool, error) {
+	var clientID, subject string
+	switch {
+	case info != nil:
+		if info.UserID != 0 && info.UserID == userID {
+			return true, nil
+		}
+		clientID = info.ClientID
+	case verified:
+		clientID = stringClaim(claims, jwtutil.ClaimKeyClientID)
+		if clientID == "" {
+			if aud := debugTokenAudience(claims); len(aud) > 0 {
+				clientID = aud[0]
+			}
+		}
+		subject = stringClaim(claims, jwtutil.ClaimKeySub)
+	default:
+		return true, nil
+	}
+
+	c, err := s.clientService.GetByClientID(ctx, clientID)
+	if err != nil || c == nil {
+		return false, err
+	}
+	if c.OwnerID != 0 && c.OwnerID == userID {
+		return true, nil
+	}
+	return subject != "" && c.Subject(userID) == subject, nil
+}
+
+// isAdminUser reports whether userID is one of the configured administrators.
+func isAdminUser(userID uint) bool {
+	for _, id := range config.AppConfig.AdminUserIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyDebugTokenSignature verifies a JWT signed with RS256 or ES256 against the published
+// keys of the tenant ctx is served for, ignoring its time-based claims.
+func verifyDebugTokenSignature(ctx context.Context, tokenValue, alg string) error {
+	signer, err := jwtutil.NewSigner(alg, nil)
+	if err != nil {
+		return err
+	}
+	_, err = signer.ParseIgnoringTime(ctx, tokenValue, jwt.MapClaims{})
+	return err
+}
+
+// debugTokenAudience returns the aud claim of a token as a list, whether it is a string or an array.
+func debugTokenAudience(claims jwt.MapClaims) []string {
+	switch aud := claims[jwtutil.ClaimKeyAud].(type) {
+	case string:
+		return []string{aud}
+	case []interface{}:
+		result := make([]string, 0, len(aud))
+		for _, value := range aud {
+			if s, ok := value.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// decodeJOSEHeader decodes the protected header of a compact JWS or JWE.
+func decodeJOSEHeader(tokenValue string) (map[string]interface{}, error) {
+	encoded, _, _ := strings.Cut(tokenValue, ".")
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	var header map[string]interface{}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
@@ -0,0 +1,202 @@
+package oauth
+
+import (
+	"context"
+	"strings"
+
+	"github.com/verigate/verigate-server/internal/app/client"
+	"github.com/verigate/verigate-server/internal/app/token"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+	jwtutil "github.com/verigate/verigate-server/internal/pkg/utils/jwt"
+)
+
+// Token type identifiers (RFC 8693 Section 3)
+const (
+	TokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token" // An OAuth 2.0 access token
+	TokenTypeJWT         = "urn:ietf:params:oauth:token-type:jwt"          // A JWT, which the access tokens of this server are
+)
+
+// TokenExchange is a token exchange request whose subject and actor tokens have been validated,
+// for a TokenExchangePolicy to decide on.
+type TokenExchange struct {
+	Client   *client.Client   // Client requesting the exchange
+	Subject  *token.TokenInfo // Token representing the user the new token is for
+	Actor    *token.TokenInfo // Token representing the party acting for the user, nil for impersonation
+	Scope    []string         // Requested scope, empty when none was requested
+	Audience []string         // Requested audience and resource values
+}
+
+// TokenExchangePolicy decides whether a client may exchange a subject token for a token with the
+// requested scope and audience, and returns the scope to grant. Refusals are returned as
+// invalid_scope or invalid_target errors. The audience is checked against the client's allowed
+// resources after the policy has accepted it.
+type TokenExchangePolicy func(ctx context.Context, exchange TokenExchange) (string, error)
+
+// DownscopingPolicy is the default token exchange policy: the new token may carry no scope the
+// subject token does not, and none the client is not registered for. Without a requested scope,
+// the subject token's scope is granted as far as the client is registered for it.
+func DownscopingPolicy(ctx context.Context, exchange TokenExchange) (string, error) {
+	allowed := strings.Fields(exchange.Client.Scope)
+	var granted []string
+	for _, sc := range strings.Fields(exchange.Subject.Scope) {
+		if containsScope(allowed, sc) {
+			granted = append(granted, sc)
+		}
+	}
+
+	if len(exchange.Scope) > 0 {
+		for _, requested := range exchange.Scope {
+			if !containsScope(granted, requested) {
+				return "", errors.BadRequest(errors.ErrMsgInvalidScope).WithDetails(errors.ErrMsgExchangeScopeNotAllowed)
+			}
+		}
+		granted = exchange.Scope
+	}
+
+	if len(granted) == 0 {
+		return "", errors.BadRequest(errors.ErrMsgInvalidScope)
+	}
+	return strings.Join(granted, " "), nil
+}
+
+// SetTokenExchangePolicy replaces the policy deciding token exchanges, DownscopingPolicy by default.
+func (s *Service) SetTokenExchangePolicy(policy TokenExchangePolicy) {
+	s.exchangePolicy = policy
+}
+
+// handleTokenExchangeGrant exchanges a user's access token for one the requesting client can
+// present downstream (RFC 8693). The subject token must be an active access token issued for a
+// user; sender-constrained subject tokens are only accepted from their holder. With an actor
+// token the new token is delegated: its act claim names the actor, nesting the delegation chain
+// of the subject token (RFC 8693 Section 4.1). The actor token must have been issued to the
+// requesting client. Without one the new token impersonates the user and keeps the subject
+// token's chain. The policy decides the granted scope; requested audiences and resources must
+// be allowed for the client, or invalid_target is returned. Only access tokens are issued, and
+// never with a refresh token.
+func (s *Service) handleTokenExchangeGrant(ctx context.Context, req TokenRequest) (*TokenResponse, error) {
+	c, err := s.clientService.GetByClientID(ctx, req.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil || !c.IsActive {
+		return nil, errors.Unauthorized(errors.ErrMsgInvalidClient)
+	}
+
+	// Only confidential clients registered for this grant may use it
+	if !c.IsConfidential || !containsScope(c.GrantTypes, GrantTypeTokenExchange) {
+		return nil, errors.BadRequest(errors.ErrMsgUnauthorizedClient)
+	}
+
+	if req.SubjectToken == "" {
+		return nil, missingParameter("subject_token")
+	}
+	if req.SubjectTokenType == "" {
+		return nil, missingParameter("subject_token_type")
+	}
+	if !isExchangeableTokenType(req.SubjectTokenType) {
+		return nil, invalidParameter(errors.ErrMsgUnsupportedSubjectTokenType)
+	}
+	if req.RequestedTokenType != "" && req.RequestedTokenType != TokenTypeAccessToken {
+		return nil, invalidParameter(errors.ErrMsgUnsupportedRequestedTokenType)
+	}
+	if req.ActorToken == "" && req.ActorTokenType != "" {
+		return nil, invalidParameter(errors.ErrMsgActorTokenTypeWithoutToken)
+	}
+
+	subjectClaims, subject, err := s.exchangedToken(ctx, req.SubjectToken)
+	if err != nil {
+		return nil, invalidParameter(errors.ErrMsgInvalidSubjectToken)
+	}
+	if subject.IsClientToken() {
+		return nil, invalidParameter(errors.ErrMsgSubjectTokenNotForUser)
+	}
+
+	// The delegation chain so far is kept, and a new actor wraps it
+	var act map[string]interface{}
+	if prior, ok := subjectClaims[jwtutil.ClaimKeyAct].(map[string]interface{}); ok {
+		act = prior
+	}
+
+	var actor *token.TokenInfo
+	if req.ActorToken != "" {
+		if req.ActorTokenType == "" {
+			return nil, missingParameter("actor_token_type")
+		}
+		if !isExchangeableTokenType(req.ActorTokenType) {
+			return nil, invalidParameter(errors.ErrMsgUnsupportedActorTokenType)
+		}
+		_, actor, err = s.exchangedToken(ctx, req.ActorToken)
+		if err != nil {
+			return nil, invalidParameter(errors.ErrMsgInvalidActorToken)
+		}
+		if actor.ClientID != c.ClientID {
+			return nil, invalidParameter(errors.ErrMsgActorTokenNotIssuedToClient)
+		}
+
+		actorSubject := actor.ClientID
+		if !actor.IsClientToken() {
+			actorSubject = c.Subject(actor.UserID)
+		}
+		delegation := map[string]interface{}{jwtutil.ClaimKeySub: actorSubject}
+		if act != nil {
+			delegation[jwtutil.ClaimKeyAct] = act
+		}
+		act = delegation
+	}
+
+	audience := append(append([]string(nil), req.Audience...), req.Resource...)
+	scope, err := s.exchangePolicy(ctx, TokenExchange{
+		Client:   c,
+		Subject:  subject,
+		Actor:    actor,
+		Scope:    strings.Fields(req.Scope),
+		Audience: audience,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	opts, err := s.accessTokenOptions(ctx, GrantTypeTokenExchange, c.ClientID, subject.UserID, scope, nil, audience)
+	if err != nil {
+		return nil, err
+	}
+	opts.WithoutRefreshToken = true
+	opts.Actor = act
+
+	tokenResp, err := s.tokenService.CreateTokens(ctx, subject.UserID, c.ClientID, scope, "", opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenResponse{
+		AccessToken:     tokenResp.AccessToken,
+		IssuedTokenType: TokenTypeAccessToken,
+		TokenType:       tokenResp.TokenType,
+		ExpiresIn:       tokenResp.ExpiresIn,
+		Scope:           tokenResp.Scope,
+	}, nil
+}
+
+// exchangedToken validates a subject or actor token like a resource server would, so
+// sender-constrained tokens are only accepted with their certificate or DPoP proof, and
+// returns its claims and stored details.
+func (s *Service) exchangedToken(ctx context.Context, value string) (map[string]interface{}, *token.TokenInfo, error) {
+	claims, err := s.tokenService.ValidateAccessToken(ctx, value)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, _, err := s.tokenService.FindActiveToken(ctx, value, token.KindAccessToken)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info == nil {
+		return nil, nil, errors.Unauthorized(errors.ErrMsgInvalidToken)
+	}
+	return *claims, info, nil
+}
+
+// isExchangeableTokenType reports whether a subject or actor token of the type can be exchanged:
+// access tokens, which this server issues as JWTs.
+func isExchangeableTokenType(tokenType string) bool {
+	return tokenType == TokenTypeAccessToken || tokenType == TokenTypeJWT
+}
@@ -0,0 +1,186 @@
+package oauth
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/verigate/verigate-server/internal/app/client"
+	"github.com/verigate/verigate-server/internal/app/token"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+	jwtutil "github.com/verigate/verigate-server/internal/pkg/utils/jwt"
+)
+
+// addExchangeClient registers a confidential client allowed the token exchange grant, the
+// openid and profile scopes, and tokens for the https://api.example.com resource.
+func (s *testService) addExchangeClient(t *testing.T, clientID string) *client.Client {
+	t.Helper()
+
+	c := &client.Client{
+		ClientID:         clientID,
+		ClientName:       clientID,
+		GrantTypes:       []string{GrantTypeTokenExchange},
+		Scope:            "openid profile",
+		AllowedResources: []string{"https://api.example.com"},
+		IsConfidential:   true,
+		IsActive:         true,
+	}
+	if err := s.clients.Save(context.Background(), c); err != nil {
+		t.Fatalf("failed to save client: %v", err)
+	}
+	return c
+}
+
+// issueAccessToken issues an access token for the user to the client, carrying act when set.
+func (s *testService) issueAccessToken(t *testing.T, userID uint, clientID, scope string, act map[string]interface{}) string {
+	t.Helper()
+
+	resp, err := s.tokenService.CreateTokens(context.Background(), userID, clientID, scope, "", token.AccessTokenOptions{
+		Actor:               act,
+		WithoutRefreshToken: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to issue access token: %v", err)
+	}
+	return resp.AccessToken
+}
+
+func TestTokenExchangeScope(t *testing.T) {
+	tests := []struct {
+		name         string
+		subjectScope string
+		scope        string
+		wantScope    string
+		wantErr      string
+	}{
+		{"subject token's scope", "openid profile", "", "openid profile", ""},
+		{"narrower scope", "openid profile", "profile", "profile", ""},
+		{"scope of the client beyond the subject token's", "openid", "openid profile", "", errors.ErrMsgInvalidScope},
+		{"scope beyond the client's", "openid profile", "openid email", "", errors.ErrMsgInvalidScope},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t)
+			s.addExchangeClient(t, "client-a")
+			subjectToken := s.issueAccessToken(t, 1, "client-a", tt.subjectScope, nil)
+
+			resp, err := s.handleTokenExchangeGrant(context.Background(), TokenRequest{
+				GrantType:        GrantTypeTokenExchange,
+				ClientID:         "client-a",
+				SubjectToken:     subjectToken,
+				SubjectTokenType: TokenTypeAccessToken,
+				Scope:            tt.scope,
+			})
+			if tt.wantErr != "" {
+				if errorCode(err) != tt.wantErr {
+					t.Fatalf("got error %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("handleTokenExchangeGrant failed: %v", err)
+			}
+			if resp.Scope != tt.wantScope {
+				t.Errorf("got scope %q, want %q", resp.Scope, tt.wantScope)
+			}
+		})
+	}
+}
+
+func TestTokenExchangeAudience(t *testing.T) {
+	tests := []struct {
+		name     string
+		audience []string
+		resource []string
+		wantErr  string
+	}{
+		{"allowed audience", []string{"https://api.example.com"}, nil, ""},
+		{"allowed resource", nil, []string{"https://api.example.com"}, ""},
+		{"disallowed audience", []string{"https://other.example.com"}, nil, errors.ErrMsgInvalidTarget},
+		{"disallowed resource", nil, []string{"https://other.example.com"}, errors.ErrMsgInvalidTarget},
+		{"audience that is no resource URI", []string{"billing"}, nil, errors.ErrMsgInvalidTarget},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t)
+			s.addExchangeClient(t, "client-a")
+			subjectToken := s.issueAccessToken(t, 1, "client-a", "openid profile", nil)
+
+			_, err := s.handleTokenExchangeGrant(context.Background(), TokenRequest{
+				GrantType:        GrantTypeTokenExchange,
+				ClientID:         "client-a",
+				SubjectToken:     subjectToken,
+				SubjectTokenType: TokenTypeAccessToken,
+				Audience:         tt.audience,
+				Resource:         tt.resource,
+			})
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("handleTokenExchangeGrant failed: %v", err)
+			}
+			if tt.wantErr != "" && errorCode(err) != tt.wantErr {
+				t.Fatalf("got error %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTokenExchangeRejectsActorTokenOfAnotherClient(t *testing.T) {
+	s := newTestService(t)
+	s.addExchangeClient(t, "client-a")
+	s.addExchangeClient(t, "client-b")
+	subjectToken := s.issueAccessToken(t, 1, "client-a", "openid profile", nil)
+	actorToken := s.issueAccessToken(t, 2, "client-b", "openid", nil)
+
+	_, err := s.handleTokenExchangeGrant(context.Background(), TokenRequest{
+		GrantType:        GrantTypeTokenExchange,
+		ClientID:         "client-a",
+		SubjectToken:     subjectToken,
+		SubjectTokenType: TokenTypeAccessToken,
+		ActorToken:       actorToken,
+		ActorTokenType:   TokenTypeAccessToken,
+	})
+	customErr, ok := errors.As(err)
+	if !ok || customErr.Code() != errors.ErrMsgInvalidRequest || customErr.Details != errors.ErrMsgActorTokenNotIssuedToClient {
+		t.Fatalf("got error %v, want %s: %s", err, errors.ErrMsgInvalidRequest, errors.ErrMsgActorTokenNotIssuedToClient)
+	}
+}
+
+func TestTokenExchangeNestsActChain(t *testing.T) {
+	s := newTestService(t)
+	ctx := context.Background()
+	c := s.addExchangeClient(t, "client-a")
+
+	// The subject token was itself issued by delegation to service-1
+	prior := map[string]interface{}{jwtutil.ClaimKeySub: "service-1"}
+	subjectToken := s.issueAccessToken(t, 1, "client-a", "openid profile", prior)
+	actorToken := s.issueAccessToken(t, 2, "client-a", "openid", nil)
+
+	resp, err := s.handleTokenExchangeGrant(ctx, TokenRequest{
+		GrantType:        GrantTypeTokenExchange,
+		ClientID:         "client-a",
+		SubjectToken:     subjectToken,
+		SubjectTokenType: TokenTypeAccessToken,
+		ActorToken:       actorToken,
+		ActorTokenType:   TokenTypeAccessToken,
+	})
+	if err != nil {
+		t.Fatalf("handleTokenExchangeGrant failed: %v", err)
+	}
+	if resp.IssuedTokenType != TokenTypeAccessToken || resp.RefreshToken != "" {
+		t.Errorf("got issued token type %q and refresh token %q, want an access token alone", resp.IssuedTokenType, resp.RefreshToken)
+	}
+
+	claims, err := s.tokenService.ValidateAccessToken(ctx, resp.AccessToken)
+	if err != nil {
+		t.Fatalf("failed to validate exchanged token: %v", err)
+	}
+	want := map[string]interface{}{
+		jwtutil.ClaimKeySub: c.Subject(2),
+		jwtutil.ClaimKeyAct: prior,
+	}
+	if got := (*claims)[jwtutil.ClaimKeyAct]; !reflect.DeepEqual(got, want) {
+		t.Errorf("got act %v, want the actor wrapping the prior chain %v", got, want)
+	}
+}
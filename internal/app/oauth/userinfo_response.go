@@ -0,0 +1,57 @@
+package oauth
+
+import (
+	"context"
+	"time"
+
+	jwtutil "github.com/verigate/verigate-server/internal/pkg/utils/jwt"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// contentTypeJWT is the media type of signed and encrypted UserInfo responses
+const contentTypeJWT = "application/jwt"
+
+// userInfoJWT returns the UserInfo claims as the JWT the client registered for its UserInfo
+// responses (OpenID Connect Core Section 5.3.2): signed with its userinfo_signed_response_alg,
+// with the issuer and the client as its audience added, and then encrypted to the client's key
+// as a nested JWT when it registered userinfo_encrypted_response_alg, as ID tokens are.
+// Returns an empty string for clients receiving UserInfo responses as plain JSON.
+func (s *Service) userInfoJWT(ctx context.Context, clientID string, userInfo UserInfoResponse) (string, error) {
+	c, err := s.clientService.GetByClientID(ctx, clientID)
+	if err != nil {
+		return "", err
+	}
+	if c == nil || c.UserInfoSigningAlg == "" {
+		return "", nil
+	}
+
+	claims := jwt.MapClaims{}
+	for name, value := range userInfo {
+		claims[name] = value
+	}
+	claims[jwtutil.ClaimKeyISS] = jwtutil.Issuer(ctx)
+	claims[jwtutil.ClaimKeyAud] = c.ClientID
+	claims[jwtutil.ClaimKeyIAT] = time.Now().Unix()
+
+	signer, err := s.clientService.UserInfoSigner(c)
+	if err != nil {
+		return "", err
+	}
+	signed, err := signer.Sign(ctx, claims, "JWT")
+	if err != nil {
+		return "", err
+	}
+
+	alg, enc := c.UserInfoEncryption()
+	if alg == "" {
+		return signed, nil
+	}
+
+	key, err := s.clientService.EncryptionKey(ctx, c, alg)
+	if err != nil {
+		return "", err
+	}
+
+	return jwtutil.EncryptJWT(signed, key, alg, enc)
+}
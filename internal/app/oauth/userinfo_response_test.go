@@ -0,0 +1,197 @@
+package oauth
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/verigate/verigate-server/internal/app/client"
+	jwtutil "github.com/verigate/verigate-server/internal/pkg/utils/jwt"
+)
+
+// encryptionJWKS returns a JWK set of the public half of key, for encrypting to with RSA-OAEP-256.
+func encryptionJWKS(t *testing.T, key *rsa.PrivateKey) string {
+	t.Helper()
+	set := jwtutil.JWKSet{Keys: []jwtutil.JWK{{
+		Kty: jwtutil.JWKKeyTypeRSA,
+		Use: jwtutil.JWKUseEncryption,
+		Alg: jwtutil.JWEAlgRSAOAEP256,
+		Kid: "client-enc",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatalf("failed to encode JWK set: %v", err)
+	}
+	return string(data)
+}
+
+// decryptJWT opens a compact JWE encrypted with RSA-OAEP-256 and A256GCM, as the client would,
+// and returns the nested JWT.
+func decryptJWT(t *testing.T, token string, key *rsa.PrivateKey) string {
+	t.Helper()
+	parts := strings.Split(token, ".")
+	if len(parts) != 5 {
+		t.Fatalf("got %d JWE parts, want 5", len(parts))
+	}
+	decoded := make([][]byte, len(parts))
+	for i, part := range parts {
+		var err error
+		if decoded[i], err = base64.RawURLEncoding.DecodeString(part); err != nil {
+			t.Fatalf("JWE part %d is not base64url: %v", i, err)
+		}
+	}
+
+	var header map[string]string
+	if err := json.Unmarshal(decoded[0], &header); err != nil {
+		t.Fatalf("failed to decode JWE header: %v", err)
+	}
+	if header[jwtutil.HeaderAlgorithm] != jwtutil.JWEAlgRSAOAEP256 || header[jwtutil.HeaderEncryption] != jwtutil.JWEEncA256GCM || header[jwtutil.HeaderContentType] != jwtutil.ContentTypeJWT {
+		t.Fatalf("got JWE header %v, want RSA-OAEP-256, A256GCM and a nested JWT", header)
+	}
+
+	cek, err := rsa.DecryptOAEP(sha256.New(), nil, key, decoded[1], nil)
+	if err != nil {
+		t.Fatalf("failed to decrypt content encryption key: %v", err)
+	}
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		t.Fatalf("failed to create cipher: %v", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatalf("failed to create GCM: %v", err)
+	}
+	plaintext, err := gcm.Open(nil, decoded[2], append(decoded[3], decoded[4]...), []byte(parts[0]))
+	if err != nil {
+		t.Fatalf("failed to decrypt JWE: %v", err)
+	}
+	return string(plaintext)
+}
+
+func TestUserInfoJWT(t *testing.T) {
+	clientKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		signingAlg    string
+		encryptionAlg string
+		wantJWT       bool
+	}{
+		{"plain", "", "", false},
+		{"signed", jwtutil.SigningAlgRS256, "", true},
+		{"signed with ES256", jwtutil.SigningAlgES256, "", true},
+		{"signed then encrypted", jwtutil.SigningAlgRS256, jwtutil.JWEAlgRSAOAEP256, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t)
+			ctx := context.Background()
+
+			c := &client.Client{
+				ClientID:              "client-a",
+				ClientName:            "client-a",
+				RedirectURIs:          []string{"https://app.example.com/callback"},
+				GrantTypes:            []string{GrantTypeAuthorizationCode},
+				Scope:                 "openid profile",
+				IsConfidential:        true,
+				IsActive:              true,
+				UserInfoSigningAlg:    tt.signingAlg,
+				UserInfoEncryptionAlg: tt.encryptionAlg,
+				Jwks:                  encryptionJWKS(t, clientKey),
+			}
+			if tt.encryptionAlg != "" {
+				c.UserInfoEncryptionEnc = jwtutil.JWEEncA256GCM
+			}
+			if err := s.clients.Save(ctx, c); err != nil {
+				t.Fatalf("failed to save client: %v", err)
+			}
+
+			userInfo := UserInfoResponse{jwtutil.ClaimKeySub: "1", "name": "Jane Doe"}
+			token, err := s.userInfoJWT(ctx, c.ClientID, userInfo)
+			if err != nil {
+				t.Fatalf("userInfoJWT failed: %v", err)
+			}
+			if !tt.wantJWT {
+				if token != "" {
+					t.Errorf("got JWT %q, want the claims sent as plain JSON", token)
+				}
+				return
+			}
+
+			if tt.encryptionAlg != "" {
+				token = decryptJWT(t, token, clientKey)
+			}
+			signer, err := s.clientService.UserInfoSigner(c)
+			if err != nil {
+				t.Fatalf("UserInfoSigner failed: %v", err)
+			}
+			claims := jwt.MapClaims{}
+			parsed, err := signer.ParseIgnoringTime(ctx, token, claims)
+			if err != nil {
+				t.Fatalf("failed to verify UserInfo JWT: %v", err)
+			}
+			if parsed.Method.Alg() != tt.signingAlg {
+				t.Errorf("signed with %s, want %s", parsed.Method.Alg(), tt.signingAlg)
+			}
+
+			if got := claims[jwtutil.ClaimKeyISS]; got != jwtutil.Issuer(ctx) {
+				t.Errorf("got iss %v, want %q", got, jwtutil.Issuer(ctx))
+			}
+			if got := claims[jwtutil.ClaimKeyAud]; got != c.ClientID {
+				t.Errorf("got aud %v, want %q", got, c.ClientID)
+			}
+			if claims[jwtutil.ClaimKeySub] != "1" || claims["name"] != "Jane Doe" {
+				t.Errorf("got claims %v, want the UserInfo claims", claims)
+			}
+		})
+	}
+}
+
+func TestWriteUserInfo(t *testing.T) {
+	userInfo := UserInfoResponse{jwtutil.ClaimKeySub: "1", "name": "Jane Doe"}
+
+	t.Run("plain", func(t *testing.T) {
+		recorder := errorRecorder(func(c *gin.Context) { writeUserInfo(c, userInfo, "") })
+		if recorder.Code != http.StatusOK {
+			t.Errorf("got status %d, want %d", recorder.Code, http.StatusOK)
+		}
+		if got := recorder.Header().Get("Content-Type"); !strings.HasPrefix(got, "application/json") {
+			t.Errorf("got Content-Type %q, want application/json", got)
+		}
+		var body UserInfoResponse
+		if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil || body[jwtutil.ClaimKeySub] != "1" {
+			t.Errorf("got body %q, want the claims", recorder.Body.String())
+		}
+	})
+
+	t.Run("signed", func(t *testing.T) {
+		const signed = "header.payload.signature"
+		recorder := errorRecorder(func(c *gin.Context) { writeUserInfo(c, userInfo, signed) })
+		if recorder.Code != http.StatusOK {
+			t.Errorf("got status %d, want %d", recorder.Code, http.StatusOK)
+		}
+		if got := recorder.Header().Get("Content-Type"); got != contentTypeJWT {
+			t.Errorf("got Content-Type %q, want %q", got, contentTypeJWT)
+		}
+		if got := recorder.Body.String(); got != signed {
+			t.Errorf("got body %q, want the JWT", got)
+		}
+	})
+}
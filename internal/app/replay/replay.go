@@ -0,0 +1,33 @@
+// Package replay provides one-time tracking of JWT IDs, so that a token meant to be used
+// once, such as a client assertion, is rejected when presented again.
+package replay
+
+import (
+	"context"
+	"time"
+
+	jwtutil "github.com/verigate/verigate-server/internal/pkg/utils/jwt"
+)
+
+// Namespaces keep the jti values of different kinds of tokens apart
+const (
+	NamespaceClientAssertion = "client_assertion" // Client authentication assertions (RFC 7523), per client
+	NamespaceDPoPProof       = "dpop_proof"       // DPoP proofs (RFC 9449), per key
+)
+
+// JTIStore records the jti values of one-time tokens. A jti only needs to be kept while the
+// token it belongs to is still accepted, after which the token is rejected as expired anyway,
+// so every entry expires with its token and memory stays bounded by the tokens in flight.
+type JTIStore interface {
+	// Record stores the jti in the namespace until ttl elapses and reports whether it was
+	// already stored, meaning the token is a replay. Checking and storing is a single atomic
+	// step, so of concurrent requests presenting the same jti exactly one is accepted.
+	// A ttl that is not positive belongs to an expired token and is reported as a replay.
+	Record(ctx context.Context, namespace, jti string, ttl time.Duration) (bool, error)
+}
+
+// TTL returns how long the jti of a token expiring at expiresAt must be kept: for as long as
+// the token is accepted, including the clock skew tolerated past its expiry.
+func TTL(expiresAt time.Time) time.Duration {
+	return time.Until(expiresAt) + jwtutil.ClockSkew()
+}
@@ -0,0 +1,126 @@
+package scope
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+)
+
+// ExpandScope returns the space-separated scope with every implied scope added, transitively.
+// The requested scopes come first in their original order, followed by the scopes they
+// imply; duplicates are removed. A scope already expanded is not followed again, so a
+// cycle introduced after startup cannot loop forever.
+func (s *Service) ExpandScope(ctx context.Context, scope string) (string, error) {
+	requested := strings.Fields(scope)
+	if len(requested) == 0 {
+		return scope, nil
+	}
+
+	implies, err := s.loadHierarchy(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join(expand(requested, implies), " "), nil
+}
+
+// CheckHierarchy validates the scope hierarchy held by the scope store.
+// Every implied scope must be registered and no scope may imply itself, directly or
+// through other scopes. It is run at startup so a bad hierarchy fails fast.
+func (s *Service) CheckHierarchy(ctx context.Context) error {
+	implies, err := s.loadHierarchy(ctx)
+	if err != nil {
+		return err
+	}
+
+	for name, children := range implies {
+		for _, child := range children {
+			if _, ok := implies[child]; !ok {
+				return errors.Internal(fmt.Sprintf(errors.ErrMsgUnknownImpliedScope, name, child))
+			}
+		}
+	}
+
+	// Depth-first search, reporting the first back edge as a cycle
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(implies))
+	var path []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			for i, p := range path {
+				if p == name {
+					cycle := append(append([]string{}, path[i:]...), name)
+					return errors.Internal(fmt.Sprintf(errors.ErrMsgScopeHierarchyCycle, strings.Join(cycle, " -> ")))
+				}
+			}
+		case done:
+			return nil
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+		for _, child := range implies[name] {
+			if err := visit(child); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = done
+		return nil
+	}
+
+	for name := range implies {
+		if state[name] == unvisited {
+			if err := visit(name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// loadHierarchy loads the hierarchy from the scope store as a map from every registered
+// scope name to the scopes it directly implies.
+func (s *Service) loadHierarchy(ctx context.Context) (map[string][]string, error) {
+	scopes, err := s.repo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	implies := make(map[string][]string, len(scopes))
+	for _, sc := range scopes {
+		implies[sc.Name] = sc.Implies
+	}
+	return implies, nil
+}
+
+// expand returns names followed by every scope they imply, breadth first and without duplicates.
+func expand(names []string, implies map[string][]string) []string {
+	seen := make(map[string]bool, len(names))
+	var expanded []string
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			expanded = append(expanded, name)
+		}
+	}
+
+	for i := 0; i < len(expanded); i++ {
+		for _, child := range implies[expanded[i]] {
+			if !seen[child] {
+				seen[child] = true
+				expanded = append(expanded, child)
+			}
+		}
+	}
+	return expanded
+}
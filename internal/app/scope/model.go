@@ -4,14 +4,44 @@ package scope
 
 import (
 	"time"
+
+	"github.com/verigate/verigate-server/internal/pkg/utils/locale"
 )
 
 // Scope represents an OAuth permission scope stored in the database.
 type Scope struct {
-	ID          uint      `json:"id"`          // Primary key
-	Name        string    `json:"name"`        // Unique scope identifier (e.g., "profile", "email")
-	Description string    `json:"description"` // Human-readable description of the permission
-	IsDefault   bool      `json:"is_default"`  // Whether this scope is granted by default
-	CreatedAt   time.Time `json:"created_at"`  // Creation timestamp
-	UpdatedAt   time.Time `json:"updated_at"`  // Last update timestamp
+	ID           uint              `json:"id"`                     // Primary key
+	Name         string            `json:"name"`                   // Unique scope identifier (e.g., "profile", "email")
+	Description  string            `json:"description"`            // Human-readable description of the permission
+	Descriptions map[string]string `json:"descriptions,omitempty"` // Translations of the description keyed by language tag
+	IsDefault    bool              `json:"is_default"`             // Whether this scope is granted by default
+	Required     bool              `json:"required"`               // Whether the user must grant this scope when it is requested
+	Audience     string            `json:"audience"`               // Resource server that access tokens with this scope are addressed to
+	Implies      []string          `json:"implies"`                // Child scopes granted along with this scope
+	CreatedAt    time.Time         `json:"created_at"`             // Creation timestamp
+	UpdatedAt    time.Time         `json:"updated_at"`             // Last update timestamp
+}
+
+// LocalizedDescription returns the description of the scope in the first preferred locale it is
+// translated into, else in defaultLocale. Without a matching translation the untranslated
+// description is used, then any translation, and the scope name only when there is no text at all.
+func (s Scope) LocalizedDescription(preferred []string, defaultLocale string) string {
+	if text, ok := locale.Lookup(s.Descriptions, preferred, defaultLocale); ok {
+		return text
+	}
+	if s.Description != "" {
+		return s.Description
+	}
+
+	// Pick a translation deterministically, since map order is random
+	anyLocale := ""
+	for tag, text := range s.Descriptions {
+		if text != "" && (anyLocale == "" || tag < anyLocale) {
+			anyLocale = tag
+		}
+	}
+	if anyLocale != "" {
+		return s.Descriptions[anyLocale]
+	}
+	return s.Name
 }
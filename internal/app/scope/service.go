@@ -63,6 +63,29 @@ func (s *Service) ValidateScope(ctx context.Context, requested, allowed string)
 	return true, nil
 }
 
+// GetScopesByNames retrieves the registered scopes with the given names in the order requested.
+// Names that are not registered are skipped.
+func (s *Service) GetScopesByNames(ctx context.Context, names []string) ([]Scope, error) {
+	scopes, err := s.repo.FindByNames(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]Scope, len(scopes))
+	for _, scope := range scopes {
+		byName[scope.Name] = scope
+	}
+
+	ordered := make([]Scope, 0, len(names))
+	for _, name := range names {
+		if scope, ok := byName[name]; ok {
+			ordered = append(ordered, scope)
+		}
+	}
+
+	return ordered, nil
+}
+
 func (s *Service) GetDefaultScopes(ctx context.Context) ([]string, error) {
 	scopes, err := s.repo.FindDefaults(ctx)
 	if err != nil {
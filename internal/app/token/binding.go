@@ -0,0 +1,36 @@
+package token
+
+import (
+	"net"
+
+	"github.com/verigate/verigate-server/internal/pkg/config"
+)
+
+// bindingSubnet returns the subnet in CIDR notation that a refresh token issued to ip is bound to:
+// the address masked to the configured IPv4 or IPv6 prefix length, so the token keeps working
+// as the client moves between addresses of the same network.
+// Returns an empty string, leaving the token unbound, if ip is not an IP address.
+func bindingSubnet(ip string) string {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return ""
+	}
+
+	if v4 := addr.To4(); v4 != nil {
+		mask := net.CIDRMask(config.AppConfig.TokenBindingIPv4Prefix, 32)
+		return (&net.IPNet{IP: v4.Mask(mask), Mask: mask}).String()
+	}
+	mask := net.CIDRMask(config.AppConfig.TokenBindingIPv6Prefix, 128)
+	return (&net.IPNet{IP: addr.Mask(mask), Mask: mask}).String()
+}
+
+// withinSubnet reports whether ip lies in the subnet a refresh token is bound to.
+// A subnet that cannot be parsed contains no addresses.
+func withinSubnet(subnet, ip string) bool {
+	_, network, err := net.ParseCIDR(subnet)
+	if err != nil {
+		return false
+	}
+	addr := net.ParseIP(ip)
+	return addr != nil && network.Contains(addr)
+}
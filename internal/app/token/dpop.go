@@ -0,0 +1,32 @@
+package token
+
+import "context"
+
+// TokenTypeDPoP is the token type of access tokens bound to a DPoP key, which are sent with
+// the DPoP authorization scheme and a proof of possession of the key (RFC 9449 Section 7.1)
+const TokenTypeDPoP = "DPoP"
+
+// dpopKeyContextKey is the context key of the DPoP key thumbprint of a request
+type dpopKeyContextKey struct{}
+
+// WithDPoPKey returns a copy of ctx carrying the JWK thumbprint of the key a request's DPoP
+// proof was verified with. Tokens issued for the request are bound to that key, and tokens
+// bound to a key are only accepted in requests carrying it.
+func WithDPoPKey(ctx context.Context, thumbprint string) context.Context {
+	return context.WithValue(ctx, dpopKeyContextKey{}, thumbprint)
+}
+
+// DPoPKeyFromContext returns the DPoP key thumbprint stored in ctx by WithDPoPKey,
+// or an empty string if the request carried no verified DPoP proof.
+func DPoPKeyFromContext(ctx context.Context) string {
+	thumbprint, _ := ctx.Value(dpopKeyContextKey{}).(string)
+	return thumbprint
+}
+
+// tokenType returns the token type of an access token issued with opts.
+func (o AccessTokenOptions) tokenType() string {
+	if o.DPoPThumbprint != "" {
+		return TokenTypeDPoP
+	}
+	return TokenTypeBearer
+}
@@ -5,13 +5,25 @@ import "time"
 
 // TokenInfo represents concise information about a token for API responses.
 type TokenInfo struct {
-	ID        string    `json:"id"`         // Token identifier
-	ClientID  string    `json:"client_id"`  // OAuth client identifier
-	UserID    uint      `json:"user_id"`    // User the token was issued to
-	Scope     string    `json:"scope"`      // Space-separated list of OAuth scopes
-	ExpiresAt time.Time `json:"expires_at"` // Expiration timestamp
-	CreatedAt time.Time `json:"created_at"` // Creation timestamp
-	IsRevoked bool      `json:"is_revoked"` // Whether the token has been revoked
+	ID             string    `json:"id"`                        // Token identifier
+	ClientID       string    `json:"client_id"`                 // OAuth client identifier
+	UserID         uint      `json:"user_id"`                   // User the token was issued to, zero for client tokens
+	Scope          string    `json:"scope"`                     // Space-separated list of OAuth scopes
+	ExpiresAt      time.Time `json:"expires_at"`                // Expiration timestamp
+	CreatedAt      time.Time `json:"created_at"`                // Creation timestamp
+	IsRevoked      bool      `json:"is_revoked"`                // Whether the token has been revoked
+	Audience       []string  `json:"audience"`                  // Audience of an access token, or the resources granted to a refresh token
+	CertThumbprint string    `json:"cert_thumbprint,omitempty"` // x5t#S256 of the client certificate an access token is bound to
+	DPoPThumbprint string    `json:"dpop_jkt,omitempty"`        // JWK thumbprint of the DPoP key an access token is bound to
+	GrantID        string    `json:"grant_id,omitempty"`        // Grant a refresh token was issued under
+	AuthTime       time.Time `json:"auth_time,omitempty"`       // When the user authenticated for an access token, zero when unknown
+	ACR            string    `json:"acr,omitempty"`             // Authentication context class an access token's authentication met
+}
+
+// IsClientToken reports whether the token was issued to a client acting on its
+// own behalf (client credentials grant) rather than to a user.
+func (t TokenInfo) IsClientToken() bool {
+	return t.UserID == 0
 }
 
 // TokenListResponse wraps a paginated list of tokens for API responses.
@@ -26,8 +38,15 @@ type TokenListResponse struct {
 // Format follows the OAuth 2.0 specification.
 type TokenCreateResponse struct {
 	AccessToken  string `json:"access_token"`            // JWT access token
-	TokenType    string `json:"token_type"`              // Always "Bearer"
+	TokenType    string `json:"token_type"`              // "Bearer", or "DPoP" for tokens bound to a DPoP key
 	ExpiresIn    int    `json:"expires_in"`              // Time in seconds until the token expires
 	RefreshToken string `json:"refresh_token,omitempty"` // Refresh token for obtaining new access tokens
 	Scope        string `json:"scope,omitempty"`         // Space-separated list of granted scopes
 }
+
+// BulkRevocationResponse reports the outcome of revoking all tokens of a user or client.
+type BulkRevocationResponse struct {
+	AccessTokensRevoked  int       `json:"access_tokens_revoked"`  // Number of access tokens revoked
+	RefreshTokensRevoked int64     `json:"refresh_tokens_revoked"` // Number of refresh tokens revoked
+	RevokedBefore        time.Time `json:"revoked_before"`         // Tokens issued before this time are no longer accepted
+}
@@ -0,0 +1,166 @@
+package token
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/app/audit"
+)
+
+// Fakes implement the methods the tests reach; the embedded interface of each leaves any other
+// method nil, so a test reaching one fails loudly.
+
+// fakeRepository is an in-memory token.Repository.
+type fakeRepository struct {
+	Repository
+	mu            sync.Mutex
+	accessTokens  map[string]*AccessToken  // By token ID
+	refreshTokens map[string]*RefreshToken // By token ID
+}
+
+// newFakeRepository returns an empty fakeRepository.
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{
+		accessTokens:  make(map[string]*AccessToken),
+		refreshTokens: make(map[string]*RefreshToken),
+	}
+}
+
+// SaveAccessToken stores a copy of the access token.
+func (r *fakeRepository) SaveAccessToken(ctx context.Context, t *AccessToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := *t
+	r.accessTokens[t.TokenID] = &stored
+	return nil
+}
+
+// FindAccessToken returns a copy of the access token, or nil if it does not exist.
+func (r *fakeRepository) FindAccessToken(ctx context.Context, tokenID string) (*AccessToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, ok := r.accessTokens[tokenID]
+	if !ok {
+		return nil, nil
+	}
+	found := *stored
+	return &found, nil
+}
+
+// RevokeAccessToken marks the access token as revoked.
+func (r *fakeRepository) RevokeAccessToken(ctx context.Context, tokenID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if stored, ok := r.accessTokens[tokenID]; ok {
+		stored.IsRevoked = true
+	}
+	return nil
+}
+
+// SaveRefreshToken stores a copy of the refresh token.
+func (r *fakeRepository) SaveRefreshToken(ctx context.Context, t *RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := *t
+	r.refreshTokens[t.TokenID] = &stored
+	return nil
+}
+
+// FindRefreshTokenByHash returns a copy of the refresh token with the hash, or nil if none has it.
+func (r *fakeRepository) FindRefreshTokenByHash(ctx context.Context, tokenHash string) (*RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, stored := range r.refreshTokens {
+		if stored.TokenHash == tokenHash {
+			found := *stored
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+// RotateRefreshToken marks an active refresh token as rotated and stores its replacements.
+func (r *fakeRepository) RotateRefreshToken(ctx context.Context, oldTokenID string, accessToken *AccessToken, refreshToken *RefreshToken) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	old, ok := r.refreshTokens[oldTokenID]
+	if !ok || old.IsRevoked {
+		return false, nil
+	}
+	now := time.Now()
+	old.IsRevoked = true
+	old.RotatedAt = &now
+
+	storedAccess, storedRefresh := *accessToken, *refreshToken
+	r.accessTokens[accessToken.TokenID] = &storedAccess
+	r.refreshTokens[refreshToken.TokenID] = &storedRefresh
+	return true, nil
+}
+
+// RevokeRefreshTokenFamily revokes every refresh token of the family and their access tokens.
+func (r *fakeRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, stored := range r.refreshTokens {
+		if stored.FamilyID != familyID {
+			continue
+		}
+		stored.IsRevoked = true
+		if at, ok := r.accessTokens[stored.AccessTokenID]; ok {
+			at.IsRevoked = true
+		}
+	}
+	return nil
+}
+
+// activeRefreshTokens counts the refresh tokens of the family that are not revoked.
+func (r *fakeRepository) activeRefreshTokens(familyID string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	active := 0
+	for _, stored := range r.refreshTokens {
+		if stored.FamilyID == familyID && !stored.IsRevoked {
+			active++
+		}
+	}
+	return active
+}
+
+// fakeCache is an in-memory CacheRepository ignoring expirations.
+type fakeCache struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+// Set stores the value in its string form.
+func (c *fakeCache) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] = fmt.Sprint(value)
+	return nil
+}
+
+// Get returns the value, or an empty string if it is not cached.
+func (c *fakeCache) Get(ctx context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.values[key], nil
+}
+
+// Delete removes the value.
+func (c *fakeCache) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.values, key)
+	return nil
+}
+
+// newTestService returns a Service over fresh fakes, along with its token repository.
+// Audit events are queued but never written.
+func newTestService() (*Service, *fakeRepository) {
+	repo := newFakeRepository()
+	service := NewService(repo, &fakeCache{values: make(map[string]string)}, nil, audit.NewService(nil))
+	return service, repo
+}
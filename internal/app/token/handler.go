@@ -22,9 +22,10 @@ func NewHandler(service *Service) *Handler {
 }
 
 // RegisterRoutes registers the token management routes on the provided router group.
-// All routes are protected by web authentication.
+// All routes are protected by web authentication and, for cookie sessions, a CSRF token.
 func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
 	// All token endpoints require web authentication
+	r.Use(middleware.CSRF())
 	r.Use(middleware.WebAuth(h.service.authService))
 
 	r.GET("", h.List)          // List user's tokens
@@ -0,0 +1,121 @@
+package token
+
+import (
+	"time"
+
+	"github.com/verigate/verigate-server/internal/pkg/config"
+)
+
+// Lifetimes holds how long the tokens issued together by a grant remain valid.
+// A zero lifetime is unset and falls back to the next level of configuration.
+type Lifetimes struct {
+	AccessToken  time.Duration // Lifetime of the access token
+	RefreshToken time.Duration // Lifetime of the refresh token
+	IDToken      time.Duration // Lifetime of the ID token
+}
+
+// orDefaults returns the lifetimes with every unset lifetime taken from defaults.
+func (l Lifetimes) orDefaults(defaults Lifetimes) Lifetimes {
+	if l.AccessToken == 0 {
+		l.AccessToken = defaults.AccessToken
+	}
+	if l.RefreshToken == 0 {
+		l.RefreshToken = defaults.RefreshToken
+	}
+	if l.IDToken == 0 {
+		l.IDToken = defaults.IDToken
+	}
+	return l
+}
+
+// LifetimePolicy resolves token lifetimes from the global defaults and the per-grant-type
+// overrides in the application configuration. Refresh token lifetimes are idle lifetimes,
+// restarted by every rotation, and bounded by the absolute lifetime of the rotation chain.
+type LifetimePolicy struct {
+	defaults Lifetimes
+	grants   map[string]Lifetimes
+	absolute time.Duration // Absolute lifetime of a refresh token chain, zero when unbounded
+}
+
+// NewLifetimePolicy reads the token lifetime policy from the application configuration.
+// It panics when a configured duration is invalid, like the other services' constructors.
+func NewLifetimePolicy() LifetimePolicy {
+	policy := LifetimePolicy{
+		defaults: Lifetimes{
+			AccessToken:  mustParseDuration(config.AppConfig.JWTAccessExpiry, "access token expiry"),
+			RefreshToken: mustParseDuration(config.AppConfig.RefreshTokenIdleTTL, "refresh token idle TTL"),
+			IDToken:      mustParseDuration(config.AppConfig.JWTIDTokenExpiry, "ID token expiry"),
+		},
+		grants: make(map[string]Lifetimes),
+	}
+	if config.AppConfig.RefreshTokenAbsoluteTTL != "" {
+		policy.absolute = mustParseDuration(config.AppConfig.RefreshTokenAbsoluteTTL, "refresh token absolute TTL")
+	}
+
+	for grantType, value := range config.AppConfig.GrantAccessTokenExpiry {
+		l := policy.grants[grantType]
+		l.AccessToken = mustParseDuration(value, "access token expiry for "+grantType)
+		policy.grants[grantType] = l
+	}
+	for grantType, value := range config.AppConfig.GrantRefreshTokenExpiry {
+		l := policy.grants[grantType]
+		l.RefreshToken = mustParseDuration(value, "refresh token expiry for "+grantType)
+		policy.grants[grantType] = l
+	}
+	for grantType, value := range config.AppConfig.GrantIDTokenExpiry {
+		l := policy.grants[grantType]
+		l.IDToken = mustParseDuration(value, "ID token expiry for "+grantType)
+		policy.grants[grantType] = l
+	}
+
+	return policy
+}
+
+// Resolve returns the lifetimes of tokens issued by a grant. Each lifetime is the client's
+// override when set, else the grant type's default when configured, else the global default.
+func (p LifetimePolicy) Resolve(grantType string, clientOverrides Lifetimes) Lifetimes {
+	return clientOverrides.orDefaults(p.grants[grantType].orDefaults(p.defaults))
+}
+
+// refreshExpiry returns when a refresh token issued at issuedAt with the given idle lifetime
+// expires: at the end of the idle lifetime, or at the end of the chain's absolute lifetime,
+// counted from familyCreatedAt, if that comes first.
+func (p LifetimePolicy) refreshExpiry(issuedAt, familyCreatedAt time.Time, idle time.Duration) time.Time {
+	expiresAt := issuedAt.Add(idle)
+	if p.absolute > 0 && expiresAt.After(familyCreatedAt.Add(p.absolute)) {
+		return familyCreatedAt.Add(p.absolute)
+	}
+	return expiresAt
+}
+
+// pastAbsoluteLifetime reports whether the absolute lifetime of a refresh token chain
+// started at familyCreatedAt is over.
+func (p LifetimePolicy) pastAbsoluteLifetime(familyCreatedAt time.Time) bool {
+	return p.absolute > 0 && time.Now().After(familyCreatedAt.Add(p.absolute))
+}
+
+// longest returns the longest access or refresh token lifetime configured globally or for any grant type.
+func (p LifetimePolicy) longest() time.Duration {
+	longest := p.defaults.AccessToken
+	if p.defaults.RefreshToken > longest {
+		longest = p.defaults.RefreshToken
+	}
+	for _, l := range p.grants {
+		if l.AccessToken > longest {
+			longest = l.AccessToken
+		}
+		if l.RefreshToken > longest {
+			longest = l.RefreshToken
+		}
+	}
+	return longest
+}
+
+// mustParseDuration parses a configured duration, panicking with the setting's name if it is invalid.
+func mustParseDuration(value, name string) time.Duration {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		panic("invalid " + name + ": " + err.Error())
+	}
+	return d
+}
@@ -0,0 +1,26 @@
+package token
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/verigate/verigate-server/internal/pkg/config"
+	jwtutil "github.com/verigate/verigate-server/internal/pkg/utils/jwt"
+	"github.com/verigate/verigate-server/internal/pkg/utils/jwt/jwttest"
+)
+
+// TestMain loads the default configuration and signs tokens with in-memory keys.
+func TestMain(m *testing.M) {
+	os.Setenv("POSTGRES_PASSWORD", "test")
+	config.Load()
+
+	keys, err := jwttest.NewKeyProvider()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to generate test keys:", err)
+		os.Exit(1)
+	}
+	jwtutil.SetKeyProvider(keys)
+
+	os.Exit(m.Run())
+}
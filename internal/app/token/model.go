@@ -7,27 +7,50 @@ import (
 
 // AccessToken represents an OAuth access token stored in the database.
 type AccessToken struct {
-	ID        uint      `json:"id"`         // Primary key
-	TokenID   string    `json:"token_id"`   // Unique identifier (UUID) for the token
-	TokenHash string    `json:"-"`          // Hashed token value, not exposed in JSON
-	ClientID  string    `json:"client_id"`  // OAuth client identifier
-	UserID    uint      `json:"user_id"`    // User the token was issued to
-	Scope     string    `json:"scope"`      // Space-separated list of OAuth scopes
-	ExpiresAt time.Time `json:"expires_at"` // Expiration timestamp
-	CreatedAt time.Time `json:"created_at"` // Creation timestamp
-	IsRevoked bool      `json:"is_revoked"` // Whether the token has been revoked
+	ID             uint      `json:"id"`                        // Primary key
+	TokenID        string    `json:"token_id"`                  // Unique identifier (UUID) for the token
+	TokenHash      string    `json:"-"`                         // Hashed token value, not exposed in JSON
+	ClientID       string    `json:"client_id"`                 // OAuth client identifier
+	UserID         uint      `json:"user_id"`                   // User the token was issued to, zero for client credentials tokens
+	Scope          string    `json:"scope"`                     // Space-separated list of OAuth scopes
+	ExpiresAt      time.Time `json:"expires_at"`                // Expiration timestamp
+	CreatedAt      time.Time `json:"created_at"`                // Creation timestamp
+	IsRevoked      bool      `json:"is_revoked"`                // Whether the token has been revoked
+	Audience       []string  `json:"audience"`                  // aud claim of the token, empty when addressed to the client
+	CertThumbprint string    `json:"cert_thumbprint,omitempty"` // x5t#S256 of the client certificate the token is bound to, empty when unbound
+	DPoPThumbprint string    `json:"dpop_jkt,omitempty"`        // JWK thumbprint of the DPoP key the token is bound to, empty when unbound
+	AuthTime       time.Time `json:"auth_time,omitempty"`       // When the user authenticated, zero for tokens issued without a user login
+	ACR            string    `json:"acr,omitempty"`             // Authentication context class the authentication met, empty when unknown
 }
 
 // RefreshToken represents an OAuth refresh token stored in the database.
 type RefreshToken struct {
-	ID            uint      `json:"id"`              // Primary key
-	TokenID       string    `json:"token_id"`        // Unique identifier (UUID) for the token
-	TokenHash     string    `json:"-"`               // Hashed token value, not exposed in JSON
-	AccessTokenID string    `json:"access_token_id"` // Related access token ID
-	ClientID      string    `json:"client_id"`       // OAuth client identifier
-	UserID        uint      `json:"user_id"`         // User the token was issued to
-	Scope         string    `json:"scope"`           // Space-separated list of OAuth scopes
-	ExpiresAt     time.Time `json:"expires_at"`      // Expiration timestamp
-	CreatedAt     time.Time `json:"created_at"`      // Creation timestamp
-	IsRevoked     bool      `json:"is_revoked"`      // Whether the token has been revoked
+	ID             uint      `json:"id"`                     // Primary key
+	TokenID        string    `json:"token_id"`               // Unique identifier (UUID) for the token
+	TokenHash      string    `json:"-"`                      // Hashed token value, not exposed in JSON
+	AccessTokenID  string    `json:"access_token_id"`        // Related access token ID
+	ClientID       string    `json:"client_id"`              // OAuth client identifier
+	UserID         uint      `json:"user_id"`                // User the token was issued to
+	Scope          string    `json:"scope"`                  // Space-separated list of OAuth scopes
+	ExpiresAt      time.Time `json:"expires_at"`             // Expiration timestamp
+	CreatedAt      time.Time `json:"created_at"`             // Creation timestamp
+	IsRevoked      bool      `json:"is_revoked"`             // Whether the token has been revoked
+	Resources      []string  `json:"resources"`              // Resource indicators granted to the token family (RFC 8707)
+	BoundSubnet    string    `json:"bound_subnet,omitempty"` // Subnet the token may only be used from, empty when unbound
+	GrantID        string    `json:"grant_id,omitempty"`     // Grant the token family was issued under, empty when not managed
+	DPoPThumbprint string    `json:"dpop_jkt,omitempty"`     // JWK thumbprint of the DPoP key the token must be refreshed with, empty when unbound
+	AuthTime       time.Time `json:"auth_time,omitempty"`    // When the user authenticated, kept across rotations
+	ACR            string    `json:"acr,omitempty"`          // Authentication context class the authentication met, kept across rotations
+
+	// Rotation tracking
+	FamilyID        string     `json:"family_id"`                 // Token ID of the first refresh token in the rotation chain
+	ParentTokenID   string     `json:"parent_token_id,omitempty"` // Refresh token this one replaced, empty for the first token
+	RotatedAt       *time.Time `json:"rotated_at,omitempty"`      // When this token was exchanged for a successor
+	FamilyCreatedAt time.Time  `json:"family_created_at"`         // When the first token of the chain was issued, the start of its absolute lifetime
+}
+
+// RevokedTokens describes the tokens revoked by a bulk revocation.
+type RevokedTokens struct {
+	AccessTokens  []AccessToken // Revoked access tokens; only the token ID and expiry are set
+	RefreshTokens int64         // Number of revoked refresh tokens
 }
@@ -15,6 +15,9 @@ type Repository interface {
 	// FindAccessToken retrieves an access token by its ID
 	FindAccessToken(ctx context.Context, tokenID string) (*AccessToken, error)
 
+	// FindAccessTokenByHash retrieves an access token by its hash value
+	FindAccessTokenByHash(ctx context.Context, tokenHash string) (*AccessToken, error)
+
 	// FindAccessTokensByUserID retrieves a paginated list of access tokens for a specific user
 	FindAccessTokensByUserID(ctx context.Context, userID uint, page, limit int) ([]AccessToken, int64, error)
 
@@ -30,8 +33,12 @@ type Repository interface {
 	// RevokeAccessTokensByClientID revokes all access tokens for a specific client
 	RevokeAccessTokensByClientID(ctx context.Context, clientID string) error
 
-	// RevokeAccessTokensByAuthCode revokes all access tokens associated with an authorization code
-	RevokeAccessTokensByAuthCode(ctx context.Context, authCode string) error
+	// SaveAuthorizationCodeToken records that an access token was issued in exchange for an authorization code
+	SaveAuthorizationCodeToken(ctx context.Context, authCode, tokenID string) error
+
+	// RevokeTokensByAuthCode atomically revokes the access tokens issued for an authorization code,
+	// together with every token in the rotation families of their refresh tokens
+	RevokeTokensByAuthCode(ctx context.Context, authCode string) (*RevokedTokens, error)
 
 	// IsAccessTokenRevoked checks if an access token has been revoked
 	IsAccessTokenRevoked(ctx context.Context, tokenID string) (bool, error)
@@ -64,4 +71,29 @@ type Repository interface {
 
 	// RevokeRefreshTokensByAccessTokenID revokes all refresh tokens for a specific access token
 	RevokeRefreshTokensByAccessTokenID(ctx context.Context, accessTokenID string) error
+
+	// RotateRefreshToken atomically marks the old refresh token as rotated and stores its replacements.
+	// Returns false without storing anything if the old token was already rotated or revoked.
+	RotateRefreshToken(ctx context.Context, oldTokenID string, accessToken *AccessToken, refreshToken *RefreshToken) (bool, error)
+
+	// RevokeRefreshTokenFamily revokes every refresh token in a rotation family and their access tokens
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
+
+	// Bulk revocation methods
+
+	// RevokeAllTokensByUserID atomically revokes every active access and refresh token of a user,
+	// including all tokens in the rotation families of the user's refresh tokens
+	RevokeAllTokensByUserID(ctx context.Context, userID uint) (*RevokedTokens, error)
+
+	// RevokeAllTokensByClientID atomically revokes every active access and refresh token of a client,
+	// including all tokens in the rotation families of the client's refresh tokens
+	RevokeAllTokensByClientID(ctx context.Context, clientID string) (*RevokedTokens, error)
+
+	// RevokeAllTokensByUserAndClient atomically revokes every active access and refresh token a client
+	// holds for a user, including all tokens in the rotation families of those refresh tokens
+	RevokeAllTokensByUserAndClient(ctx context.Context, userID uint, clientID string) (*RevokedTokens, error)
+
+	// RevokeAllTokensByGrantID atomically revokes every active access and refresh token
+	// issued under a grant, across all rotations of its refresh tokens
+	RevokeAllTokensByGrantID(ctx context.Context, grantID string) (*RevokedTokens, error)
 }
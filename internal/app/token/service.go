@@ -4,19 +4,20 @@ package token
 
 import (
 	"context"
-	"crypto/rand"
-	"crypto/rsa"
-	"encoding/base64"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/google/uuid"
+	"github.com/verigate/verigate-server/internal/app/audit"
 	"github.com/verigate/verigate-server/internal/app/auth"
 	"github.com/verigate/verigate-server/internal/pkg/config"
+	"github.com/verigate/verigate-server/internal/pkg/middleware"
 	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
 	"github.com/verigate/verigate-server/internal/pkg/utils/hash"
 	jwtutil "github.com/verigate/verigate-server/internal/pkg/utils/jwt"
+	"github.com/verigate/verigate-server/internal/pkg/utils/opaque"
 )
 
 // Constants
@@ -24,9 +25,96 @@ const (
 	TokenTypeBearer = "Bearer" // Bearer token type for Authorization header
 
 	// Cache key prefixes
-	CacheKeyAccessToken = "access_token:" // Prefix for access token cache keys
+	CacheKeyAccessToken        = "access_token:"         // Prefix for access token cache keys
+	CacheKeyRevokedAccessToken = "revoked_access_token:" // Prefix for the denylist of revoked access token IDs (jti)
+
+	// Token kinds reported by token lookups
+	KindAccessToken  = "access_token"  // Token is an access token
+	KindRefreshToken = "refresh_token" // Token is a refresh token
 )
 
+// AccessTokenOptions controls the format of issued access tokens.
+type AccessTokenOptions struct {
+	// JWTProfile issues the token in the JWT profile for access tokens (RFC 9068)
+	// with a string subject, a client_id claim, and an "at+jwt" typ header.
+	JWTProfile bool
+
+	// Scope is the scope carried by the access token: the granted scope with the scopes
+	// it implies expanded. If empty, the access token carries the granted scope.
+	// Refresh tokens always keep the granted scope.
+	Scope string
+
+	// Audience lists the resource servers the token is addressed to.
+	// If empty, the token is addressed to the client.
+	Audience []string
+
+	// Resources lists the resource indicators granted by the authorization (RFC 8707).
+	// They are kept by the refresh token so later refreshes can be narrowed to any of them.
+	Resources []string
+
+	// PairwiseSector is the sector identifier of a client receiving pairwise subjects
+	// (OpenID Connect Core Section 8.1). If empty, the subject is the user ID.
+	PairwiseSector string
+
+	// Lifetimes are the lifetimes resolved for the grant and client.
+	// Unset lifetimes use the global defaults.
+	Lifetimes Lifetimes
+
+	// ClientIP is the address the token request came from.
+	// Refreshing a bound refresh token is only allowed from within its subnet.
+	ClientIP string
+
+	// BindToIP binds issued refresh tokens to the subnet of ClientIP.
+	BindToIP bool
+
+	// CertThumbprint binds the access token to the client certificate with this x5t#S256
+	// thumbprint through its cnf claim (RFC 8705 Section 3). If empty, the token is unbound.
+	CertThumbprint string
+
+	// DPoPThumbprint binds the access token to the DPoP key with this JWK thumbprint through
+	// its cnf claim (RFC 9449 Section 6). The token is then of the DPoP token type.
+	// If empty, the token is unbound.
+	DPoPThumbprint string
+
+	// Claims are custom claims added to the access token, such as those of client claim
+	// templates. They never replace a claim the server sets.
+	Claims map[string]interface{}
+
+	// Actor is the act claim of a token issued by token exchange for delegation, naming the
+	// party acting for the subject and, nested within, earlier actors (RFC 8693 Section 4.1).
+	// If nil, the token carries no act claim.
+	Actor map[string]interface{}
+
+	// BindRefreshToDPoPKey binds issued refresh tokens to the DPoP key as well, as required
+	// for public clients (RFC 9449 Section 5). Refreshing a bound refresh token is only
+	// allowed with a proof of the same key.
+	BindRefreshToDPoPKey bool
+
+	// GrantID is the grant the tokens are issued under (FAPI Grant Management).
+	// Refresh tokens keep it across rotations, so the grant's tokens can be revoked together.
+	GrantID string
+
+	// WithoutRefreshToken issues the access token alone, as for OpenID Connect requests
+	// that were not granted offline_access (OpenID Connect Core Section 11).
+	WithoutRefreshToken bool
+
+	// AuthTime and ACR describe the user authentication the tokens are issued for: when it
+	// happened and the authentication context class it met. The access token carries them as
+	// its auth_time and acr claims, so resource servers can demand a step-up (RFC 9470
+	// Section 6.1). Refresh tokens keep them, so refreshed access tokens report the original
+	// authentication rather than a newer one. If unset, the claims are left out.
+	AuthTime time.Time
+	ACR      string
+}
+
+// accessScope returns the scope an access token carries for the granted scope.
+func (o AccessTokenOptions) accessScope(granted string) string {
+	if o.Scope != "" {
+		return o.Scope
+	}
+	return granted
+}
+
 // CacheRepository defines the interface for token caching operations.
 type CacheRepository interface {
 	// Set stores a value in the cache with the specified expiration
@@ -42,134 +130,123 @@ type CacheRepository interface {
 // Service handles token-related operations including creation, validation,
 // and revocation of access and refresh tokens.
 type Service struct {
-	tokenRepo     Repository
-	cacheRepo     CacheRepository
-	authService   *auth.Service
-	privateKey    *rsa.PrivateKey
-	publicKey     *rsa.PublicKey
-	accessExpiry  time.Duration
-	refreshExpiry time.Duration
+	tokenRepo    Repository
+	cacheRepo    CacheRepository
+	authService  *auth.Service
+	auditService *audit.Service
+	lifetimes    LifetimePolicy
 }
 
 // NewService creates a new token service instance with the necessary dependencies.
-func NewService(tokenRepo Repository, cacheRepo CacheRepository, authService *auth.Service) *Service {
-	// Parse JWT keys
-	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(config.AppConfig.JWTPrivateKey))
-	if err != nil {
-		panic("failed to parse private key: " + err.Error())
-	}
-
-	publicKey, err := jwt.ParseRSAPublicKeyFromPEM([]byte(config.AppConfig.JWTPublicKey))
-	if err != nil {
-		panic("failed to parse public key: " + err.Error())
+func NewService(tokenRepo Repository, cacheRepo CacheRepository, authService *auth.Service, auditService *audit.Service) *Service {
+	return &Service{
+		tokenRepo:    tokenRepo,
+		cacheRepo:    cacheRepo,
+		authService:  authService,
+		auditService: auditService,
+		lifetimes:    NewLifetimePolicy(),
 	}
+}
 
-	// Parse expiry durations
-	accessExpiry, err := time.ParseDuration(config.AppConfig.JWTAccessExpiry)
-	if err != nil {
-		panic("invalid access token expiry: " + err.Error())
-	}
+// Lifetimes resolves the lifetimes of the tokens a grant issues to a client
+// from the client's overrides, the grant type's defaults, and the global defaults.
+func (s *Service) Lifetimes(grantType string, clientOverrides Lifetimes) Lifetimes {
+	return s.lifetimes.Resolve(grantType, clientOverrides)
+}
 
-	refreshExpiry, err := time.ParseDuration(config.AppConfig.JWTRefreshExpiry)
+// CreateTokens generates new access and refresh tokens for a user.
+// The refresh token starts a new rotation family, unless opts leave it out. Tokens issued
+// for an authorization code are linked to it, so they can be revoked if the code is replayed.
+// It stores the tokens in the database and returns them to the client.
+func (s *Service) CreateTokens(ctx context.Context, userID uint, clientID, scope, authCode string, opts AccessTokenOptions) (*TokenCreateResponse, error) {
+	accessTokenModel, refreshTokenModel, resp, err := s.newTokenPair(ctx, userID, clientID, scope, nil, opts)
 	if err != nil {
-		panic("invalid refresh token expiry: " + err.Error())
+		return nil, err
 	}
 
-	return &Service{
-		tokenRepo:     tokenRepo,
-		cacheRepo:     cacheRepo,
-		authService:   authService,
-		privateKey:    privateKey,
-		publicKey:     publicKey,
-		accessExpiry:  accessExpiry,
-		refreshExpiry: refreshExpiry,
+	// Link the tokens to the code first, so revoking the code's tokens never misses them
+	if authCode != "" {
+		if err := s.tokenRepo.SaveAuthorizationCodeToken(ctx, authCode, accessTokenModel.TokenID); err != nil {
+			return nil, err
+		}
 	}
-}
 
-// CreateTokens generates new access and refresh tokens for a user.
-// It stores the tokens in the database and returns them to the client.
-func (s *Service) CreateTokens(ctx context.Context, userID uint, clientID, scope, authCode string) (*TokenCreateResponse, error) {
-	// Generate access token
-	accessToken, accessTokenID, err := s.createAccessToken(userID, clientID, scope)
-	if err != nil {
+	if err := s.tokenRepo.SaveAccessToken(ctx, accessTokenModel); err != nil {
 		return nil, err
 	}
 
-	// Generate refresh token
-	refreshToken, refreshTokenID, err := s.createRefreshToken()
-	if err != nil {
+	if opts.WithoutRefreshToken {
+		resp.RefreshToken = ""
+	} else if err := s.tokenRepo.SaveRefreshToken(ctx, refreshTokenModel); err != nil {
 		return nil, err
 	}
 
-	// Hash tokens for storage
-	accessTokenHash, err := hash.HashPassword(accessToken)
-	if err != nil {
-		return nil, errors.Internal(errors.ErrMsgFailedToHashAccessToken)
+	// Cache the access token for quick validation
+	if err := s.cacheRepo.Set(ctx, CacheKeyAccessToken+accessTokenModel.TokenID, accessTokenModel, time.Until(accessTokenModel.ExpiresAt)); err != nil {
+		// Not critical, continue
 	}
 
-	refreshTokenHash, err := hash.HashPassword(refreshToken)
+	return resp, nil
+}
+
+// CreateClientToken generates an access token for a client acting on its own behalf.
+// It is used by the client credentials grant, so no refresh token is issued and
+// the token's subject is the client ID instead of a user.
+func (s *Service) CreateClientToken(ctx context.Context, clientID, scope string, opts AccessTokenOptions) (*TokenCreateResponse, error) {
+	lifetimes := opts.Lifetimes.orDefaults(s.lifetimes.defaults)
+	scope = opts.accessScope(scope)
+	accessToken, accessTokenID, err := s.createAccessToken(ctx, clientID, clientID, scope, opts, lifetimes.AccessToken)
 	if err != nil {
-		return nil, errors.Internal(errors.ErrMsgFailedToHashRefreshToken)
+		return nil, err
 	}
 
-	// Save tokens
+	now := time.Now()
 	accessTokenModel := &AccessToken{
-		TokenID:   accessTokenID,
-		TokenHash: accessTokenHash,
-		ClientID:  clientID,
-		UserID:    userID,
-		Scope:     scope,
-		ExpiresAt: time.Now().Add(s.accessExpiry),
-		CreatedAt: time.Now(),
-		IsRevoked: false,
+		TokenID:        accessTokenID,
+		TokenHash:      hash.HashToken(accessToken),
+		ClientID:       clientID,
+		Scope:          scope,
+		Audience:       opts.Audience,
+		ExpiresAt:      now.Add(lifetimes.AccessToken),
+		CreatedAt:      now,
+		IsRevoked:      false,
+		CertThumbprint: opts.CertThumbprint,
+		DPoPThumbprint: opts.DPoPThumbprint,
 	}
 
 	if err := s.tokenRepo.SaveAccessToken(ctx, accessTokenModel); err != nil {
 		return nil, err
 	}
 
-	refreshTokenModel := &RefreshToken{
-		TokenID:       refreshTokenID,
-		TokenHash:     refreshTokenHash,
-		AccessTokenID: accessTokenID,
-		ClientID:      clientID,
-		UserID:        userID,
-		Scope:         scope,
-		ExpiresAt:     time.Now().Add(s.refreshExpiry),
-		CreatedAt:     time.Now(),
-		IsRevoked:     false,
-	}
-
-	if err := s.tokenRepo.SaveRefreshToken(ctx, refreshTokenModel); err != nil {
-		return nil, err
-	}
-
 	// Cache the access token for quick validation
-	if err := s.cacheRepo.Set(ctx, CacheKeyAccessToken+accessTokenID, accessTokenModel, s.accessExpiry); err != nil {
+	if err := s.cacheRepo.Set(ctx, CacheKeyAccessToken+accessTokenID, accessTokenModel, lifetimes.AccessToken); err != nil {
 		// Not critical, continue
 	}
 
 	return &TokenCreateResponse{
-		AccessToken:  accessToken,
-		TokenType:    TokenTypeBearer,
-		ExpiresIn:    int(s.accessExpiry.Seconds()),
-		RefreshToken: refreshToken,
-		Scope:        scope,
+		AccessToken: accessToken,
+		TokenType:   opts.tokenType(),
+		ExpiresIn:   int(lifetimes.AccessToken.Seconds()),
+		Scope:       scope,
 	}, nil
 }
 
 // RefreshTokens exchanges a valid refresh token for a new access token and refresh token pair.
-// It validates the refresh token, checks scope restrictions, and revokes the old tokens
-// before generating new ones.
-func (s *Service) RefreshTokens(ctx context.Context, refreshToken, clientID, requestedScope string) (*TokenCreateResponse, error) {
-	// Hash the refresh token
-	tokenHash, err := hash.HashPassword(refreshToken)
-	if err != nil {
-		return nil, errors.Internal(errors.ErrMsgFailedToHashRefreshToken)
+// Each refresh token can be used only once: the presented token is rotated out and
+// its successor joins the same token family. Presenting a token that was already
+// rotated is treated as a replay and revokes the entire family. A token bound to a
+// subnet is rejected when the request comes from outside it, and a token bound to a
+// DPoP key when the request's proof is not signed with that key. A requested scope must be
+// within the scope of the refresh token and narrows only the new access token; opts.Scope is
+// then expected to be derived from it.
+func (s *Service) RefreshTokens(ctx context.Context, refreshToken, clientID, requestedScope string, opts AccessTokenOptions) (*TokenCreateResponse, error) {
+	// Reject malformed tokens before looking them up
+	if !opaque.Acceptable(refreshToken, opaque.PrefixRefreshToken) {
+		return nil, errors.Unauthorized(errors.ErrMsgInvalidToken)
 	}
 
 	// Find the refresh token
-	token, err := s.tokenRepo.FindRefreshTokenByHash(ctx, tokenHash)
+	token, err := s.tokenRepo.FindRefreshTokenByHash(ctx, hash.HashToken(refreshToken))
 	if err != nil {
 		return nil, err
 	}
@@ -177,38 +254,73 @@ func (s *Service) RefreshTokens(ctx context.Context, refreshToken, clientID, req
 		return nil, errors.Unauthorized(errors.ErrMsgInvalidToken)
 	}
 
-	// Validate token
+	// Validate token. The client is checked first so that only the token's own client can
+	// trigger reuse revocation; another client holding a leaked token cannot revoke the family
+	if token.ClientID != clientID {
+		return nil, errors.Unauthorized(errors.ErrMsgRefreshTokenNotIssuedToClient)
+	}
 	if token.IsRevoked {
+		if token.RotatedAt != nil {
+			return nil, s.handleRefreshTokenReuse(ctx, token)
+		}
 		return nil, errors.Unauthorized(errors.ErrMsgTokenRevoked)
 	}
 	if time.Now().After(token.ExpiresAt) {
 		return nil, errors.Unauthorized(errors.ErrMsgTokenExpired)
 	}
-	if token.ClientID != clientID {
-		return nil, errors.Unauthorized(errors.ErrMsgRefreshTokenNotIssuedToClient)
+	if s.lifetimes.pastAbsoluteLifetime(token.FamilyCreatedAt) {
+		return nil, errors.Unauthorized(errors.ErrMsgRefreshTokenLifetimeExceeded)
+	}
+	if token.BoundSubnet != "" && !withinSubnet(token.BoundSubnet, opts.ClientIP) {
+		return nil, errors.BadRequest(errors.ErrMsgRefreshTokenBoundElsewhere)
+	}
+	if token.DPoPThumbprint != "" && token.DPoPThumbprint != opts.DPoPThumbprint {
+		return nil, errors.BadRequest(errors.ErrMsgRefreshTokenDPoPMismatch)
+	}
+	if s.isRevokedInBulk(ctx, token.UserID, token.ClientID, token.CreatedAt) {
+		return nil, errors.Unauthorized(errors.ErrMsgTokenRevoked)
 	}
 
-	// Validate requested scope
-	scope := token.Scope
-	if requestedScope != "" {
+	// A requested scope narrows the new access token only; the successor refresh token keeps
+	// the whole original grant, so a later refresh may ask for all of it again
+	if strings.TrimSpace(requestedScope) != "" {
 		if !s.isScopeSubset(requestedScope, token.Scope) {
-			return nil, errors.BadRequest(errors.ErrMsgRequestedScopeExceedsOriginal)
+			return nil, errors.BadRequest(errors.ErrMsgInvalidScope).WithDetails(errors.ErrMsgRequestedScopeExceedsOriginal)
+		}
+		if opts.Scope == "" {
+			opts.Scope = strings.Join(strings.Fields(requestedScope), " ")
 		}
-		scope = requestedScope
 	}
 
-	// Revoke old tokens
-	if err := s.tokenRepo.RevokeRefreshToken(ctx, token.TokenID); err != nil {
+	// Issue the successor tokens within the same family
+	accessTokenModel, refreshTokenModel, resp, err := s.newTokenPair(ctx, token.UserID, token.ClientID, token.Scope, token, opts)
+	if err != nil {
 		return nil, err
 	}
+
+	// Rotate atomically; losing the race means another request already used this token
+	rotated, err := s.tokenRepo.RotateRefreshToken(ctx, token.TokenID, accessTokenModel, refreshTokenModel)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		return nil, s.handleRefreshTokenReuse(ctx, token)
+	}
+
+	// Revoke the access token issued with the old refresh token
 	if token.AccessTokenID != "" {
 		if err := s.tokenRepo.RevokeAccessToken(ctx, token.AccessTokenID); err != nil {
 			// Not critical, continue
 		}
+		s.denyAccessToken(ctx, token.AccessTokenID)
 	}
 
-	// Create new tokens
-	return s.CreateTokens(ctx, token.UserID, token.ClientID, scope, "")
+	// Cache the access token for quick validation
+	if err := s.cacheRepo.Set(ctx, CacheKeyAccessToken+accessTokenModel.TokenID, accessTokenModel, time.Until(accessTokenModel.ExpiresAt)); err != nil {
+		// Not critical, continue
+	}
+
+	return resp, nil
 }
 
 // RevokeAccessToken invalidates an access token if it belongs to the specified client.
@@ -235,23 +347,24 @@ func (s *Service) RevokeAccessToken(ctx context.Context, tokenValue, clientID st
 		return err
 	}
 
-	// Remove from cache
-	s.cacheRepo.Delete(ctx, CacheKeyAccessToken+tokenID)
+	// Remove from cache and deny the self-contained token until it expires
+	s.denyAccessToken(ctx, tokenID)
 
 	return nil
 }
 
 // RevokeRefreshToken invalidates a refresh token and its associated access token
 // if they belong to the specified client.
+// Revoking an already revoked token is a no-op for the refresh token itself,
+// but the cascade to the access token is still applied so that concurrent
+// revocation requests always leave both tokens revoked.
 func (s *Service) RevokeRefreshToken(ctx context.Context, tokenValue, clientID string) error {
-	// Hash the refresh token
-	tokenHash, err := hash.HashPassword(tokenValue)
-	if err != nil {
-		return errors.Internal(errors.ErrMsgFailedToHashRefreshToken)
+	if !opaque.Acceptable(tokenValue, opaque.PrefixRefreshToken) {
+		return errors.NotFound(errors.ErrMsgTokenNotFound)
 	}
 
 	// Find the refresh token
-	token, err := s.tokenRepo.FindRefreshTokenByHash(ctx, tokenHash)
+	token, err := s.tokenRepo.FindRefreshTokenByHash(ctx, hash.HashToken(tokenValue))
 	if err != nil || token == nil {
 		return errors.NotFound(errors.ErrMsgTokenNotFound)
 	}
@@ -261,32 +374,34 @@ func (s *Service) RevokeRefreshToken(ctx context.Context, tokenValue, clientID s
 	}
 
 	// Revoke refresh token and associated access token
-	if err := s.tokenRepo.RevokeRefreshToken(ctx, token.TokenID); err != nil {
-		return err
+	if !token.IsRevoked {
+		if err := s.tokenRepo.RevokeRefreshToken(ctx, token.TokenID); err != nil {
+			return err
+		}
 	}
 
 	if token.AccessTokenID != "" {
 		s.tokenRepo.RevokeAccessToken(ctx, token.AccessTokenID)
-		s.cacheRepo.Delete(ctx, CacheKeyAccessToken+token.AccessTokenID)
+		s.denyAccessToken(ctx, token.AccessTokenID)
 	}
 
 	return nil
 }
 
 // ValidateAccessToken verifies the signature and validity of an access token.
-// It checks if the token has been revoked and returns the claims if the token is valid.
+// It checks if the token has been revoked, that a certificate-bound token is presented
+// with the client certificate it is bound to, and that a DPoP-bound token is presented with
+// a proof of its key, and returns the claims if the token is valid.
 func (s *Service) ValidateAccessToken(ctx context.Context, tokenValue string) (*jwt.MapClaims, error) {
 	// Use the jwtutil.ValidateTokenForRevocation function to validate the token format
 	// and extract the token ID
-	tokenID, err := jwtutil.ValidateTokenForRevocation(tokenValue)
+	tokenID, err := jwtutil.ValidateTokenForRevocation(ctx, tokenValue)
 	if err != nil {
 		return nil, err
 	}
 
 	// Parse the token to get claims for additional checks and return value
-	token, err := jwt.Parse(tokenValue, func(token *jwt.Token) (interface{}, error) {
-		return s.publicKey, nil
-	})
+	token, err := jwtutil.ParseToken(ctx, tokenValue, jwt.MapClaims{})
 
 	if err != nil {
 		return nil, errors.Unauthorized(errors.ErrMsgInvalidToken)
@@ -297,24 +412,114 @@ func (s *Service) ValidateAccessToken(ctx context.Context, tokenValue string) (*
 		return nil, errors.Unauthorized(errors.ErrMsgInvalidTokenClaims)
 	}
 
+	// A certificate-bound token is only accepted with the certificate it is bound to (RFC 8705 Section 3)
+	if thumbprint := jwtutil.ConfirmedThumbprint(claims); thumbprint != "" && thumbprint != middleware.ClientCertificateThumbprint(ctx) {
+		return nil, errors.Unauthorized(errors.ErrMsgCertificateBindingMismatch)
+	}
+
+	// A DPoP-bound token is only accepted with a proof of the key it is bound to (RFC 9449 Section 7)
+	if thumbprint := jwtutil.ConfirmedKeyThumbprint(claims); thumbprint != "" && thumbprint != DPoPKeyFromContext(ctx) {
+		return nil, errors.Unauthorized(errors.ErrMsgDPoPBindingMismatch)
+	}
+
+	// Revoked tokens are denied without a database lookup
+	if s.isAccessTokenDenied(ctx, tokenID) {
+		return nil, errors.Unauthorized(errors.ErrMsgTokenRevoked)
+	}
+
 	// Check cache first
 	if cached, err := s.cacheRepo.Get(ctx, CacheKeyAccessToken+tokenID); err == nil && cached != "" {
 		// Token found in cache, check if revoked
 		// This would need proper deserialization
 	}
 
-	// Check database
-	isRevoked, err := s.tokenRepo.IsAccessTokenRevoked(ctx, tokenID)
+	// Check database; unknown tokens are considered revoked
+	stored, err := s.tokenRepo.FindAccessToken(ctx, tokenID)
 	if err != nil {
 		return nil, err
 	}
-	if isRevoked {
+	if stored == nil || stored.IsRevoked || s.isRevokedInBulk(ctx, stored.UserID, stored.ClientID, stored.CreatedAt) {
 		return nil, errors.Unauthorized(errors.ErrMsgTokenRevoked)
 	}
 
 	return &claims, nil
 }
 
+// FindActiveToken looks up an access or refresh token by its value.
+// The token hint only selects which token store is checked first; both are
+// checked if necessary. Each lookup is a single indexed query on the token hash;
+// the refresh token store is skipped for values that cannot be refresh tokens.
+// Returns the token details and its kind, or nil if the token is unknown,
+// revoked, or expired.
+func (s *Service) FindActiveToken(ctx context.Context, tokenValue, tokenHint string) (*TokenInfo, string, error) {
+	tokenHash := hash.HashToken(tokenValue)
+
+	kinds := []string{KindAccessToken, KindRefreshToken}
+	if tokenHint == KindRefreshToken {
+		kinds = []string{KindRefreshToken, KindAccessToken}
+	}
+
+	for _, kind := range kinds {
+		var info *TokenInfo
+		var err error
+		if kind == KindAccessToken {
+			info, err = s.findAccessTokenByHash(ctx, tokenHash)
+		} else if opaque.Acceptable(tokenValue, opaque.PrefixRefreshToken) {
+			info, err = s.findRefreshTokenByHash(ctx, tokenHash)
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		if info == nil {
+			continue
+		}
+
+		if info.IsRevoked || time.Now().After(info.ExpiresAt) {
+			return nil, "", nil
+		}
+		if kind == KindAccessToken && s.isAccessTokenDenied(ctx, info.ID) {
+			return nil, "", nil
+		}
+		if s.isRevokedInBulk(ctx, info.UserID, info.ClientID, info.CreatedAt) {
+			return nil, "", nil
+		}
+		return info, kind, nil
+	}
+
+	return nil, "", nil
+}
+
+// LookupToken finds a stored access or refresh token by its value whatever its state, for
+// reporting on it rather than accepting it. IsRevoked is set on tokens revoked one by one,
+// denylisted, or revoked in bulk. Returns the token details and its kind, or nil if no token
+// has the value.
+func (s *Service) LookupToken(ctx context.Context, tokenValue string) (*TokenInfo, string, error) {
+	tokenHash := hash.HashToken(tokenValue)
+
+	info, err := s.findAccessTokenByHash(ctx, tokenHash)
+	if err != nil {
+		return nil, "", err
+	}
+	kind := KindAccessToken
+	if info == nil && opaque.Acceptable(tokenValue, opaque.PrefixRefreshToken) {
+		if info, err = s.findRefreshTokenByHash(ctx, tokenHash); err != nil {
+			return nil, "", err
+		}
+		kind = KindRefreshToken
+	}
+	if info == nil {
+		return nil, "", nil
+	}
+
+	if kind == KindAccessToken && s.isAccessTokenDenied(ctx, info.ID) {
+		info.IsRevoked = true
+	}
+	if s.isRevokedInBulk(ctx, info.UserID, info.ClientID, info.CreatedAt) {
+		info.IsRevoked = true
+	}
+	return info, kind, nil
+}
+
 // ListTokens retrieves a paginated list of access tokens for a specific user.
 func (s *Service) ListTokens(ctx context.Context, userID uint, page, limit int) (*TokenListResponse, error) {
 	accessTokens, totalAccess, err := s.tokenRepo.FindAccessTokensByUserID(ctx, userID, page, limit)
@@ -358,32 +563,273 @@ func (s *Service) RevokeToken(ctx context.Context, tokenID string, userID uint)
 		return errors.Forbidden(errors.ErrMsgNotAuthorizedToRevokeToken)
 	}
 
-	return s.tokenRepo.RevokeAccessToken(ctx, tokenID)
+	if err := s.tokenRepo.RevokeAccessToken(ctx, tokenID); err != nil {
+		return err
+	}
+
+	s.denyAccessToken(ctx, tokenID)
+	s.auditService.Record(ctx, audit.Event{
+		Type:     audit.EventTokenRevoked,
+		Actor:    audit.UserActor(userID),
+		ClientID: token.ClientID,
+		Details:  map[string]string{"token_id": tokenID},
+	})
+	return nil
 }
 
-// RevokeTokensByAuthCode invalidates all access tokens associated with a specific authorization code.
-func (s *Service) RevokeTokensByAuthCode(ctx context.Context, authCode string) error {
-	return s.tokenRepo.RevokeAccessTokensByAuthCode(ctx, authCode)
+// RevokeTokensByAuthCode invalidates the tokens issued in exchange for an authorization code,
+// including every refresh token rotated from them, and denies the access tokens until they expire.
+// It is used when a client presents a code again after exchanging it (RFC 6749 Section 4.1.2).
+func (s *Service) RevokeTokensByAuthCode(ctx context.Context, authCode, clientID string) error {
+	revoked, err := s.tokenRepo.RevokeTokensByAuthCode(ctx, authCode)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range revoked.AccessTokens {
+		s.denyAccessTokenUntil(ctx, t.TokenID, t.ExpiresAt)
+	}
+	if len(revoked.AccessTokens) > 0 || revoked.RefreshTokens > 0 {
+		s.auditService.Record(ctx, audit.Event{
+			Type:     audit.EventTokenRevoked,
+			Actor:    audit.ClientActor(clientID),
+			ClientID: clientID,
+			Details:  map[string]string{"reason": "authorization_code_reuse", "code_hash": audit.HashValue(authCode)},
+		})
+	}
+	return nil
 }
 
-// createAccessToken generates a new JWT access token with the specified claims.
-func (s *Service) createAccessToken(userID uint, clientID, scope string) (string, string, error) {
-	tokenID := uuid.New().String()
+// findAccessTokenByHash retrieves an access token by hash and converts it to a TokenInfo.
+// Returns nil if no token matches.
+func (s *Service) findAccessTokenByHash(ctx context.Context, tokenHash string) (*TokenInfo, error) {
+	token, err := s.tokenRepo.FindAccessTokenByHash(ctx, tokenHash)
+	if err != nil || token == nil {
+		return nil, err
+	}
+
+	return &TokenInfo{
+		ID:             token.TokenID,
+		ClientID:       token.ClientID,
+		UserID:         token.UserID,
+		Scope:          token.Scope,
+		Audience:       token.Audience,
+		ExpiresAt:      token.ExpiresAt,
+		CreatedAt:      token.CreatedAt,
+		IsRevoked:      token.IsRevoked,
+		CertThumbprint: token.CertThumbprint,
+		DPoPThumbprint: token.DPoPThumbprint,
+		AuthTime:       token.AuthTime,
+		ACR:            token.ACR,
+	}, nil
+}
+
+// findRefreshTokenByHash retrieves a refresh token by hash and converts it to a TokenInfo.
+// Returns nil if no token matches.
+func (s *Service) findRefreshTokenByHash(ctx context.Context, tokenHash string) (*TokenInfo, error) {
+	token, err := s.tokenRepo.FindRefreshTokenByHash(ctx, tokenHash)
+	if err != nil || token == nil {
+		return nil, err
+	}
+
+	// Tokens stored before the absolute lifetime was set or shortened expire at its end
+	return &TokenInfo{
+		ID:        token.TokenID,
+		ClientID:  token.ClientID,
+		UserID:    token.UserID,
+		Scope:     token.Scope,
+		Audience:  token.Resources,
+		ExpiresAt: s.lifetimes.refreshExpiry(token.ExpiresAt, token.FamilyCreatedAt, 0),
+		CreatedAt: token.CreatedAt,
+		IsRevoked: token.IsRevoked,
+		GrantID:   token.GrantID,
+	}, nil
+}
+
+// newTokenPair builds a new access token and refresh token for a user without storing them.
+// When parent is nil the refresh token starts a new rotation family; otherwise it
+// inherits the parent's family, grant, DPoP key binding, and user authentication and records the
+// parent as its predecessor.
+// The tokens expire after the lifetimes in opts, or the global defaults where unset;
+// the refresh token no later than the end of its family's absolute lifetime.
+func (s *Service) newTokenPair(ctx context.Context, userID uint, clientID, scope string, parent *RefreshToken, opts AccessTokenOptions) (*AccessToken, *RefreshToken, *TokenCreateResponse, error) {
+	lifetimes := opts.Lifetimes.orDefaults(s.lifetimes.defaults)
+
+	// A refreshed access token reports the authentication its family was issued for
+	if parent != nil {
+		opts.AuthTime = parent.AuthTime
+		opts.ACR = parent.ACR
+	}
+
+	// Generate access token, identifying the user by the subject the client knows them by
+	var subject interface{} = userID
+	if opts.PairwiseSector != "" {
+		subject = hash.PairwiseSubject(opts.PairwiseSector, userID, config.AppConfig.PairwiseSubjectSalt)
+	}
+	accessScope := opts.accessScope(scope)
+	accessToken, accessTokenID, err := s.createAccessToken(ctx, subject, clientID, accessScope, opts, lifetimes.AccessToken)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Generate refresh token
+	refreshToken, refreshTokenID, err := s.createRefreshToken()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	now := time.Now()
+
+	accessTokenModel := &AccessToken{
+		TokenID:        accessTokenID,
+		TokenHash:      hash.HashToken(accessToken),
+		ClientID:       clientID,
+		UserID:         userID,
+		Scope:          accessScope,
+		Audience:       opts.Audience,
+		ExpiresAt:      now.Add(lifetimes.AccessToken),
+		CreatedAt:      now,
+		IsRevoked:      false,
+		CertThumbprint: opts.CertThumbprint,
+		DPoPThumbprint: opts.DPoPThumbprint,
+		AuthTime:       opts.AuthTime,
+		ACR:            opts.ACR,
+	}
+
+	refreshTokenModel := &RefreshToken{
+		TokenID:         refreshTokenID,
+		TokenHash:       hash.HashToken(refreshToken),
+		AccessTokenID:   accessTokenID,
+		ClientID:        clientID,
+		UserID:          userID,
+		Scope:           scope,
+		Resources:       opts.Resources,
+		CreatedAt:       now,
+		IsRevoked:       false,
+		FamilyID:        refreshTokenID,
+		FamilyCreatedAt: now,
+		GrantID:         opts.GrantID,
+		AuthTime:        opts.AuthTime,
+		ACR:             opts.ACR,
+	}
+	if opts.BindToIP {
+		refreshTokenModel.BoundSubnet = bindingSubnet(opts.ClientIP)
+	}
+	if opts.BindRefreshToDPoPKey {
+		refreshTokenModel.DPoPThumbprint = opts.DPoPThumbprint
+	}
+
+	if parent != nil {
+		refreshTokenModel.FamilyID = parent.FamilyID
+		refreshTokenModel.FamilyCreatedAt = parent.FamilyCreatedAt
+		refreshTokenModel.ParentTokenID = parent.TokenID
+		refreshTokenModel.GrantID = parent.GrantID
+		if parent.DPoPThumbprint != "" {
+			refreshTokenModel.DPoPThumbprint = parent.DPoPThumbprint
+		}
+	}
+	refreshTokenModel.ExpiresAt = s.lifetimes.refreshExpiry(now, refreshTokenModel.FamilyCreatedAt, lifetimes.RefreshToken)
+
+	resp := &TokenCreateResponse{
+		AccessToken:  accessToken,
+		TokenType:    opts.tokenType(),
+		ExpiresIn:    int(lifetimes.AccessToken.Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        accessScope,
+	}
+
+	return accessTokenModel, refreshTokenModel, resp, nil
+}
+
+// handleRefreshTokenReuse revokes the whole rotation family of a replayed refresh token.
+// Returns the error to report to the client, or the revocation error if it failed.
+func (s *Service) handleRefreshTokenReuse(ctx context.Context, token *RefreshToken) error {
+	if err := s.tokenRepo.RevokeRefreshTokenFamily(ctx, token.FamilyID); err != nil {
+		return err
+	}
+	s.auditService.Record(ctx, audit.Event{
+		Type:     audit.EventTokenRevoked,
+		Actor:    audit.ClientActor(token.ClientID),
+		ClientID: token.ClientID,
+		Details:  map[string]string{"reason": "refresh_token_reuse", "family_id": token.FamilyID},
+	})
+	return errors.Unauthorized(errors.ErrMsgRefreshTokenReuseDetected)
+}
+
+// createAccessToken generates a new JWT access token with the specified claims, expiring after expiry.
+// The subject is the user ID or pairwise subject for user tokens, or the client ID for client tokens.
+// The token is addressed to the requested audience, or to the client if none was requested.
+// A token bound to a client certificate or DPoP key carries its thumbprint in the cnf claim.
+// Under the RFC 9068 profile the subject is always a string and the token also
+// carries the client_id claim.
+func (s *Service) createAccessToken(ctx context.Context, subject interface{}, clientID, scope string, opts AccessTokenOptions, expiry time.Duration) (string, string, error) {
+	tokenID, err := opaque.Random()
+	if err != nil {
+		return "", "", err
+	}
 	now := time.Now()
 
 	claims := jwt.MapClaims{
 		jwtutil.ClaimKeyJTI:   tokenID,
-		jwtutil.ClaimKeySub:   userID,
+		jwtutil.ClaimKeySub:   subject,
 		jwtutil.ClaimKeyAud:   clientID,
 		jwtutil.ClaimKeyScope: scope,
 		jwtutil.ClaimKeyIAT:   now.Unix(),
-		jwtutil.ClaimKeyEXP:   now.Add(s.accessExpiry).Unix(),
-		jwtutil.ClaimKeyISS:   jwtutil.TokenIssuer,
+		jwtutil.ClaimKeyEXP:   now.Add(expiry).Unix(),
+		jwtutil.ClaimKeyISS:   jwtutil.Issuer(ctx),
 		jwtutil.ClaimKeyType:  jwtutil.TokenTypeAccess,
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-	signedToken, err := token.SignedString(s.privateKey)
+	cnf := map[string]interface{}{}
+	if opts.CertThumbprint != "" {
+		cnf[jwtutil.ConfirmationX5tS256] = opts.CertThumbprint
+	}
+	if opts.DPoPThumbprint != "" {
+		cnf[jwtutil.ConfirmationJkt] = opts.DPoPThumbprint
+	}
+	if len(cnf) > 0 {
+		claims[jwtutil.ClaimKeyCnf] = cnf
+	}
+	if opts.Actor != nil {
+		claims[jwtutil.ClaimKeyAct] = opts.Actor
+	}
+	if !opts.AuthTime.IsZero() {
+		claims[jwtutil.ClaimKeyAuthTime] = opts.AuthTime.Unix()
+	}
+	if opts.ACR != "" {
+		claims[jwtutil.ClaimKeyACR] = opts.ACR
+	}
+
+	switch len(opts.Audience) {
+	case 0:
+		// Addressed to the client as above
+	case 1:
+		claims[jwtutil.ClaimKeyAud] = opts.Audience[0]
+	default:
+		claims[jwtutil.ClaimKeyAud] = opts.Audience
+	}
+
+	// client_id is set below for the JWT profile
+	for name, value := range opts.Claims {
+		if _, set := claims[name]; !set && name != jwtutil.ClaimKeyClientID {
+			claims[name] = value
+		}
+	}
+
+	if !opts.JWTProfile {
+		signedToken, err := jwtutil.SignToken(ctx, claims)
+		if err != nil {
+			return "", "", err
+		}
+		return signedToken, tokenID, nil
+	}
+
+	if userID, ok := subject.(uint); ok {
+		claims[jwtutil.ClaimKeySub] = strconv.FormatUint(uint64(userID), 10)
+	}
+	claims[jwtutil.ClaimKeyClientID] = clientID
+
+	signedToken, err := jwtutil.SignTokenWithType(ctx, claims, jwtutil.MediaTypeAccessToken)
 	if err != nil {
 		return "", "", err
 	}
@@ -391,16 +837,45 @@ func (s *Service) createAccessToken(userID uint, clientID, scope string) (string
 	return signedToken, tokenID, nil
 }
 
-// createRefreshToken generates a new secure random refresh token.
+// denyAccessToken removes an access token from the cache and adds its ID to the
+// revocation denylist. Entries last until the stored token expires, or for the default
+// access token lifetime if it cannot be found.
+func (s *Service) denyAccessToken(ctx context.Context, tokenID string) {
+	expiresAt := time.Now().Add(s.lifetimes.defaults.AccessToken)
+	if token, err := s.tokenRepo.FindAccessToken(ctx, tokenID); err == nil && token != nil {
+		expiresAt = token.ExpiresAt
+	}
+	s.denyAccessTokenUntil(ctx, tokenID, expiresAt)
+}
+
+// denyAccessTokenUntil removes an access token from the cache and adds its ID to the
+// revocation denylist until the token expires at expiresAt.
+func (s *Service) denyAccessTokenUntil(ctx context.Context, tokenID string, expiresAt time.Time) {
+	s.cacheRepo.Delete(ctx, CacheKeyAccessToken+tokenID)
+
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return
+	}
+	if err := s.cacheRepo.Set(ctx, CacheKeyRevokedAccessToken+tokenID, true, ttl); err != nil {
+		// Not critical, the database still records the revocation
+	}
+}
+
+// isAccessTokenDenied reports whether the access token ID is on the revocation denylist.
+func (s *Service) isAccessTokenDenied(ctx context.Context, tokenID string) bool {
+	denied, err := s.cacheRepo.Get(ctx, CacheKeyRevokedAccessToken+tokenID)
+	return err == nil && denied != ""
+}
+
+// createRefreshToken generates a new secure random refresh token with the refresh token prefix.
 func (s *Service) createRefreshToken() (string, string, error) {
 	tokenID := uuid.New().String()
 
-	b := make([]byte, 32)
-	if _, err := rand.Read(b); err != nil {
+	refreshToken, err := opaque.Generate(opaque.PrefixRefreshToken)
+	if err != nil {
 		return "", "", err
 	}
-
-	refreshToken := base64.URLEncoding.EncodeToString(b)
 	return refreshToken, tokenID, nil
 }
 
@@ -426,8 +901,8 @@ func (s *Service) getTokenIDFromJWT(tokenValue string) (string, error) {
 
 // isScopeSubset checks if the requested scope is a subset of the existing scope.
 func (s *Service) isScopeSubset(requested, existing string) bool {
-	requestedScopes := strings.Split(requested, " ")
-	existingScopes := strings.Split(existing, " ")
+	requestedScopes := strings.Fields(requested)
+	existingScopes := strings.Fields(existing)
 
 	for _, req := range requestedScopes {
 		found := false
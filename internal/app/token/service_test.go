@@ -0,0 +1,78 @@
+package token
+
+import (
+	"context"
+	"testing"
+
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+	"github.com/verigate/verigate-server/internal/pkg/utils/hash"
+)
+
+// issueTokens issues a token pair for user 1 to the client and returns the response.
+func issueTokens(t *testing.T, service *Service, clientID string, opts AccessTokenOptions) *TokenCreateResponse {
+	t.Helper()
+
+	resp, err := service.CreateTokens(context.Background(), 1, clientID, "openid offline_access", "", opts)
+	if err != nil {
+		t.Fatalf("CreateTokens failed: %v", err)
+	}
+	return resp
+}
+
+// errorCode returns the code of a CustomError, or an empty string for any other error.
+func errorCode(err error) string {
+	if customErr, ok := errors.As(err); ok {
+		return customErr.Code()
+	}
+	return ""
+}
+
+// familyOf returns the family ID of the stored refresh token.
+func familyOf(t *testing.T, repo *fakeRepository, refreshToken string) string {
+	t.Helper()
+
+	stored, _ := repo.FindRefreshTokenByHash(context.Background(), hash.HashToken(refreshToken))
+	if stored == nil {
+		t.Fatal("refresh token was not stored")
+	}
+	return stored.FamilyID
+}
+
+func TestRefreshTokensReuseRevokesFamily(t *testing.T) {
+	service, repo := newTestService()
+	ctx := context.Background()
+
+	issued := issueTokens(t, service, "client-a", AccessTokenOptions{})
+	family := familyOf(t, repo, issued.RefreshToken)
+	if _, err := service.RefreshTokens(ctx, issued.RefreshToken, "client-a", "", AccessTokenOptions{}); err != nil {
+		t.Fatalf("first refresh failed: %v", err)
+	}
+
+	_, err := service.RefreshTokens(ctx, issued.RefreshToken, "client-a", "", AccessTokenOptions{})
+	if errorCode(err) != errors.ErrMsgRefreshTokenReuseDetected {
+		t.Fatalf("replayed refresh: got error %v, want %q", err, errors.ErrMsgRefreshTokenReuseDetected)
+	}
+	if active := repo.activeRefreshTokens(family); active != 0 {
+		t.Errorf("replay left %d refresh tokens of the family active, want 0", active)
+	}
+}
+
+func TestRefreshTokensReuseByOtherClientKeepsFamily(t *testing.T) {
+	service, repo := newTestService()
+	ctx := context.Background()
+
+	issued := issueTokens(t, service, "client-a", AccessTokenOptions{})
+	family := familyOf(t, repo, issued.RefreshToken)
+	if _, err := service.RefreshTokens(ctx, issued.RefreshToken, "client-a", "", AccessTokenOptions{}); err != nil {
+		t.Fatalf("first refresh failed: %v", err)
+	}
+
+	// Another client presenting the leaked, rotated token must not revoke the victim's family
+	_, err := service.RefreshTokens(ctx, issued.RefreshToken, "client-b", "", AccessTokenOptions{})
+	if errorCode(err) != errors.ErrMsgRefreshTokenNotIssuedToClient {
+		t.Fatalf("refresh by other client: got error %v, want %q", err, errors.ErrMsgRefreshTokenNotIssuedToClient)
+	}
+	if active := repo.activeRefreshTokens(family); active != 1 {
+		t.Errorf("refresh by other client left %d refresh tokens of the family active, want 1", active)
+	}
+}
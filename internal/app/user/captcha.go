@@ -0,0 +1,136 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/app/audit"
+	"github.com/verigate/verigate-server/internal/pkg/config"
+	"github.com/verigate/verigate-server/internal/pkg/utils/captcha"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+)
+
+// captchaRedeemTTL is how long a used CAPTCHA token is remembered, longer than any provider keeps one valid
+const captchaRedeemTTL = 10 * time.Minute
+
+// CaptchaPolicy controls when a login must solve a CAPTCHA and how the response is verified.
+// Logins are only challenged once failures from their IP address or for their login
+// identifier reach a threshold, so ordinary users rarely see a CAPTCHA.
+type CaptchaPolicy struct {
+	Provider       string           // CAPTCHA provider, captcha.ProviderNone disables the challenge
+	SiteKey        string           // Public key the login form renders the CAPTCHA with
+	Verifier       captcha.Verifier // Verifies CAPTCHA responses server-side
+	IPThreshold    int              // Failed logins from an IP address after which it is challenged, zero disables
+	LoginThreshold int              // Consecutive failures of a login after which it is challenged, zero disables
+	IPWindow       time.Duration    // Time without a failure after which the failures of an IP address are forgotten
+}
+
+// NewCaptchaPolicy reads the CAPTCHA policy from the application configuration.
+// It panics when the provider settings or a duration are invalid, like the other services' constructors.
+func NewCaptchaPolicy() CaptchaPolicy {
+	timeout := mustParseDuration("CAPTCHA timeout", config.AppConfig.CaptchaTimeout)
+	verifier, err := captcha.NewVerifier(config.AppConfig.CaptchaProvider, config.AppConfig.CaptchaSecret, config.AppConfig.CaptchaVerifyURL, timeout)
+	if err != nil {
+		panic("invalid CAPTCHA configuration: " + err.Error())
+	}
+
+	return CaptchaPolicy{
+		Provider:       config.AppConfig.CaptchaProvider,
+		SiteKey:        config.AppConfig.CaptchaSiteKey,
+		Verifier:       verifier,
+		IPThreshold:    config.AppConfig.CaptchaIPThreshold,
+		LoginThreshold: config.AppConfig.CaptchaLoginThreshold,
+		IPWindow:       mustParseDuration("CAPTCHA IP window", config.AppConfig.CaptchaIPWindow),
+	}
+}
+
+// Enabled reports whether logins can be challenged at all.
+func (p CaptchaPolicy) Enabled() bool {
+	return p.Provider != captcha.ProviderNone && (p.IPThreshold > 0 || p.LoginThreshold > 0)
+}
+
+// CaptchaStatus tells the login form whether its next login must include a CAPTCHA response,
+// and which provider and site key to render the CAPTCHA with. Without an email address,
+// only the failures from the IP address are considered.
+func (s *Service) CaptchaStatus(ctx context.Context, email, ipAddress string) (*CaptchaStatusResponse, error) {
+	required, err := s.captchaRequired(ctx, NormalizeLogin(email), ipAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &CaptchaStatusResponse{CaptchaRequired: required}
+	if s.captchaPolicy.Enabled() {
+		response.Provider = s.captchaPolicy.Provider
+		response.SiteKey = s.captchaPolicy.SiteKey
+	}
+	return response, nil
+}
+
+// captchaRequired reports whether a login from the IP address must solve a CAPTCHA, because the
+// failures from the address or the consecutive failures of the login reached their threshold.
+// A login identifier that is unknown to the caller may be empty.
+func (s *Service) captchaRequired(ctx context.Context, login, ipAddress string) (bool, error) {
+	if !s.captchaPolicy.Enabled() {
+		return false, nil
+	}
+
+	if s.captchaPolicy.IPThreshold > 0 && ipAddress != "" {
+		failures, err := s.captchaRepo.IPFailures(ctx, ipAddress)
+		if err != nil {
+			return false, err
+		}
+		if failures >= int64(s.captchaPolicy.IPThreshold) {
+			return true, nil
+		}
+	}
+
+	if s.captchaPolicy.LoginThreshold > 0 && login != "" {
+		failures, err := s.lockoutRepo.Failures(ctx, login)
+		if err != nil {
+			return false, err
+		}
+		if failures >= int64(s.captchaPolicy.LoginThreshold) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// checkCaptcha verifies the CAPTCHA response of a login that must solve one. The token is
+// redeemed before it is verified, so it cannot be replayed, not even by concurrent requests.
+func (s *Service) checkCaptcha(ctx context.Context, login, ipAddress, token string) error {
+	required, err := s.captchaRequired(ctx, login, ipAddress)
+	if err != nil {
+		return err
+	}
+	if !required {
+		return nil
+	}
+
+	if token == "" {
+		return errors.BadRequest(errors.ErrMsgCaptchaRequired)
+	}
+
+	redeemed, err := s.captchaRepo.Redeem(ctx, token, captchaRedeemTTL)
+	if err != nil {
+		return err
+	}
+	valid := false
+	if redeemed {
+		valid, err = s.captchaPolicy.Verifier.Verify(ctx, token, ipAddress)
+		if err != nil {
+			return errors.Internal(errors.ErrMsgFailedToVerifyCaptcha)
+		}
+	}
+	if !valid {
+		s.auditService.Record(ctx, audit.Event{
+			Type:    audit.EventLoginFailed,
+			Outcome: audit.OutcomeFailure,
+			Details: map[string]string{"login": login, "reason": "captcha"},
+		})
+		return errors.BadRequest(errors.ErrMsgInvalidCaptcha)
+	}
+
+	return nil
+}
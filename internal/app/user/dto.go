@@ -2,20 +2,28 @@
 // registration, authentication, profile management, and session handling.
 package user
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/app/auth"
+
+	"github.com/go-webauthn/webauthn/protocol"
+)
 
 // RegisterRequest represents the data needed to create a new user account.
 type RegisterRequest struct {
 	Username string `json:"username" binding:"required,min=3,max=50"` // Username (required, 3-50 chars)
 	Email    string `json:"email" binding:"required,email"`           // Email address (required, valid format)
-	Password string `json:"password" binding:"required,min=8"`        // Password (required, min 8 chars)
+	Password string `json:"password" binding:"required"`              // Password (required, checked against the password policy)
 	FullName string `json:"full_name"`                                // Optional full name
 }
 
 // LoginRequest represents the data needed for user authentication.
 type LoginRequest struct {
-	Email    string `json:"email" binding:"required,email"` // Email address (required, valid format)
-	Password string `json:"password" binding:"required"`    // Password (required)
+	Email        string `json:"email" binding:"required,email"` // Email address (required, valid format)
+	Password     string `json:"password" binding:"required"`    // Password (required)
+	CaptchaToken string `json:"captcha_token"`                  // CAPTCHA response, required while logins are challenged
 }
 
 // UpdateUserRequest represents the data for updating a user's profile.
@@ -27,8 +35,8 @@ type UpdateUserRequest struct {
 
 // ChangePasswordRequest represents the data needed for changing a password.
 type ChangePasswordRequest struct {
-	OldPassword string `json:"old_password" binding:"required"`       // Current password (required)
-	NewPassword string `json:"new_password" binding:"required,min=8"` // New password (required, min 8 chars)
+	OldPassword string `json:"old_password" binding:"required"` // Current password (required)
+	NewPassword string `json:"new_password" binding:"required"` // New password (required, checked against the password policy)
 }
 
 // UserResponse represents the user data returned in API responses.
@@ -46,13 +54,30 @@ type UserResponse struct {
 	LastLoginAt       *time.Time `json:"last_login_at,omitempty"`       // Last login time
 }
 
+// CSRFTokenResponse carries the CSRF token required by the login requests.
+type CSRFTokenResponse struct {
+	CSRFToken string `json:"csrf_token"` // Value for the X-CSRF-Token header
+}
+
+// CaptchaStatusResponse tells the login form whether the next login must solve a CAPTCHA.
+type CaptchaStatusResponse struct {
+	CaptchaRequired bool   `json:"captcha_required"`   // Whether the login must include a captcha_token
+	Provider        string `json:"provider,omitempty"` // CAPTCHA provider, when the challenge is configured
+	SiteKey         string `json:"site_key,omitempty"` // Public key to render the CAPTCHA with
+}
+
 // LoginResponse is returned after a successful login.
-// It contains user information and authentication tokens.
+// It contains user information and authentication tokens. When the account has
+// two-factor authentication enabled, only MFARequired and MFAToken are set and
+// the tokens are issued by the second-factor step instead.
 type LoginResponse struct {
-	User         UserResponse `json:"user"`          // User profile information
-	AccessToken  string       `json:"access_token"`  // JWT access token
-	RefreshToken string       `json:"refresh_token"` // Refresh token for obtaining new access tokens
-	ExpiresAt    time.Time    `json:"expires_at"`    // When the access token expires
+	User         *UserResponse `json:"user,omitempty"`          // User profile information
+	AccessToken  string        `json:"access_token,omitempty"`  // JWT access token
+	RefreshToken string        `json:"refresh_token,omitempty"` // Refresh token for obtaining new access tokens
+	ExpiresAt    *time.Time    `json:"expires_at,omitempty"`    // When the access token expires
+	MFARequired  bool          `json:"mfa_required,omitempty"`  // Whether a second factor must be presented
+	MFAToken     string        `json:"mfa_token,omitempty"`     // Challenge to present with the second factor
+	Session      *auth.Session `json:"-"`                       // Web session started by the login, sent as a cookie
 }
 
 // RefreshTokenRequest is the structure for token refresh requests.
@@ -66,3 +91,71 @@ type RefreshTokenResponse struct {
 	RefreshToken string    `json:"refresh_token"`
 	ExpiresAt    time.Time `json:"expires_at"`
 }
+
+// TwoFactorEnrollResponse is returned when two-factor enrollment starts.
+// The recovery codes are only shown once.
+type TwoFactorEnrollResponse struct {
+	Secret        string   `json:"secret"`         // Base32 TOTP secret for manual entry
+	OTPAuthURI    string   `json:"otpauth_uri"`    // otpauth URI to display as a QR code
+	RecoveryCodes []string `json:"recovery_codes"` // One-time codes for when the authenticator is unavailable
+}
+
+// TwoFactorVerifyRequest activates two-factor authentication with a code from the authenticator.
+type TwoFactorVerifyRequest struct {
+	Code string `json:"code" binding:"required"` // Current 6-digit code
+}
+
+// TwoFactorLoginRequest completes a login that requires a second factor.
+// Either a code from the authenticator or an unused recovery code must be given.
+type TwoFactorLoginRequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required"` // Challenge returned by the password step
+	Code         string `json:"code"`                         // Current 6-digit code
+	RecoveryCode string `json:"recovery_code"`                // One-time recovery code
+}
+
+// WebAuthnRegisterBeginResponse starts passkey registration.
+// The options are passed to navigator.credentials.create in the browser.
+type WebAuthnRegisterBeginResponse struct {
+	SessionID string                       `json:"session_id"` // Ceremony to name when finishing
+	Options   *protocol.CredentialCreation `json:"options"`    // Public key credential creation options
+}
+
+// WebAuthnRegisterFinishRequest completes passkey registration with the authenticator's attestation.
+type WebAuthnRegisterFinishRequest struct {
+	SessionID  string          `json:"session_id" binding:"required"` // Ceremony returned by the begin step
+	Name       string          `json:"name" binding:"max=100"`        // Optional label for the passkey
+	Credential json.RawMessage `json:"credential" binding:"required"` // PublicKeyCredential returned by the browser
+}
+
+// WebAuthnLoginBeginResponse starts a passkey login.
+// The options are passed to navigator.credentials.get in the browser.
+type WebAuthnLoginBeginResponse struct {
+	SessionID string                        `json:"session_id"` // Ceremony to name when finishing
+	Options   *protocol.CredentialAssertion `json:"options"`    // Public key credential request options
+}
+
+// WebAuthnLoginFinishRequest completes a passkey login with the authenticator's assertion.
+type WebAuthnLoginFinishRequest struct {
+	SessionID  string          `json:"session_id" binding:"required"` // Ceremony returned by the begin step
+	Credential json.RawMessage `json:"credential" binding:"required"` // PublicKeyCredential returned by the browser
+}
+
+// WebAuthnCredentialResponse describes a registered passkey.
+type WebAuthnCredentialResponse struct {
+	ID         string     `json:"id"`                     // Base64url credential ID, used to revoke the passkey
+	Name       string     `json:"name"`                   // Label chosen by the user
+	Transports []string   `json:"transports"`             // How the client can reach the authenticator
+	CreatedAt  time.Time  `json:"created_at"`             // When the passkey was registered
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"` // When the passkey last signed in
+}
+
+// SessionResponse describes one of the user's active web sessions.
+type SessionResponse struct {
+	SID       string    `json:"sid"`                  // Public session ID, used to end the session
+	ACR       string    `json:"acr"`                  // How the user authenticated
+	UserAgent string    `json:"user_agent,omitempty"` // Browser that logged in
+	IPAddress string    `json:"ip_address,omitempty"` // Address the login came from
+	CreatedAt time.Time `json:"created_at"`           // When the session started
+	ExpiresAt time.Time `json:"expires_at"`           // When the session ends regardless of activity
+	Current   bool      `json:"current"`              // Whether this is the session making the request
+}
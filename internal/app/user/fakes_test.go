@@ -0,0 +1,165 @@
+package user
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/app/audit"
+	"github.com/verigate/verigate-server/internal/app/auth"
+	"github.com/verigate/verigate-server/internal/pkg/db/memory"
+)
+
+// Fakes implement the methods the tests reach; the embedded interface of each leaves any other
+// method nil, so a test reaching one fails loudly.
+
+// fakeRepository is an in-memory user Repository.
+type fakeRepository struct {
+	Repository
+	mu    sync.Mutex
+	users map[uint]*User
+}
+
+// FindByEmail returns a copy of the user with the email, or nil if none has it.
+func (r *fakeRepository) FindByEmail(ctx context.Context, email string) (*User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, stored := range r.users {
+		if stored.Email == email {
+			found := *stored
+			return &found, nil
+		}
+	}
+	return nil, nil
+}
+
+// UpdatePassword replaces the user's password hash.
+func (r *fakeRepository) UpdatePassword(ctx context.Context, id uint, passwordHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if stored, ok := r.users[id]; ok {
+		stored.PasswordHash = passwordHash
+	}
+	return nil
+}
+
+// UpdateLastLogin records the login time.
+func (r *fakeRepository) UpdateLastLogin(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if stored, ok := r.users[id]; ok {
+		now := time.Now()
+		stored.LastLoginAt = &now
+	}
+	return nil
+}
+
+// passwordHash returns the stored password hash of the user.
+func (r *fakeRepository) passwordHash(id uint) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.users[id].PasswordHash
+}
+
+// fakeLockoutRepository never locks a login, counting every failure as the first.
+type fakeLockoutRepository struct {
+	LockoutRepository
+}
+
+// LockedFor reports that the login is not locked.
+func (fakeLockoutRepository) LockedFor(ctx context.Context, login string) (time.Duration, error) {
+	return 0, nil
+}
+
+// Failures reports no failures.
+func (fakeLockoutRepository) Failures(ctx context.Context, login string) (int64, error) {
+	return 0, nil
+}
+
+// IncrementFailures reports a single failure.
+func (fakeLockoutRepository) IncrementFailures(ctx context.Context, login string, ttl time.Duration) (int64, error) {
+	return 1, nil
+}
+
+// ResetFailures does nothing.
+func (fakeLockoutRepository) ResetFailures(ctx context.Context, login string) error {
+	return nil
+}
+
+// fakeCaptchaRepository never requires a CAPTCHA.
+type fakeCaptchaRepository struct {
+	CaptchaRepository
+}
+
+// IncrementIPFailures reports a single failure.
+func (fakeCaptchaRepository) IncrementIPFailures(ctx context.Context, ip string, ttl time.Duration) (int64, error) {
+	return 1, nil
+}
+
+// IPFailures reports no failures.
+func (fakeCaptchaRepository) IPFailures(ctx context.Context, ip string) (int64, error) {
+	return 0, nil
+}
+
+// fakeTwoFactorRepository holds no authenticators.
+type fakeTwoFactorRepository struct {
+	TwoFactorRepository
+}
+
+// FindTwoFactor reports that the user never enrolled.
+func (fakeTwoFactorRepository) FindTwoFactor(ctx context.Context, userID uint) (*TwoFactor, error) {
+	return nil, nil
+}
+
+// fakeSessionRepository is an in-memory auth.SessionRepository ignoring time to live.
+type fakeSessionRepository struct {
+	auth.SessionRepository
+	mu       sync.Mutex
+	sessions map[string]*auth.Session
+}
+
+// SaveSession stores a copy of the session.
+func (r *fakeSessionRepository) SaveSession(ctx context.Context, session *auth.Session, ttl time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := *session
+	r.sessions[session.ID] = &stored
+	return nil
+}
+
+// ListUserSessions returns the user's sessions, oldest first.
+func (r *fakeSessionRepository) ListUserSessions(ctx context.Context, userID uint) ([]*auth.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var sessions []*auth.Session
+	for _, stored := range r.sessions {
+		if stored.UserID == userID {
+			found := *stored
+			sessions = append(sessions, &found)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].CreatedAt.Before(sessions[j].CreatedAt) })
+	return sessions, nil
+}
+
+// newTestService returns a Service over fakes holding the given users, along with its user
+// repository. Audit events are queued but never written.
+func newTestService(t *testing.T, users ...*User) (*Service, *fakeRepository) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	repo := &fakeRepository{users: make(map[uint]*User)}
+	for _, u := range users {
+		stored := *u
+		repo.users[u.ID] = &stored
+	}
+
+	sessions := &fakeSessionRepository{sessions: make(map[string]*auth.Session)}
+	authService := auth.NewService(memory.NewAuthRepository(ctx), sessions)
+	service := NewService(repo, fakeLockoutRepository{}, fakeCaptchaRepository{}, fakeTwoFactorRepository{}, nil, nil, authService, nil, audit.NewService(nil))
+	return service, repo
+}
@@ -4,8 +4,12 @@ package user
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/verigate/verigate-server/internal/app/auth"
+	"github.com/verigate/verigate-server/internal/pkg/config"
 	"github.com/verigate/verigate-server/internal/pkg/middleware"
+	"github.com/verigate/verigate-server/internal/pkg/tenant"
 	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
 
 	"github.com/gin-gonic/gin"
@@ -30,11 +34,11 @@ func NewHandler(service *Service) *Handler {
 func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
 	// Public endpoints
 	r.POST("/register", h.Register)
-	r.POST("/login", h.Login)
 	r.POST("/refresh-token", h.RefreshToken) // Added
 
 	// Protected endpoints
 	protected := r.Group("")
+	protected.Use(middleware.CSRF())
 	protected.Use(middleware.WebAuth(h.service.authService)) // Changed to WebAuth
 	{
 		protected.GET("/me", h.GetMe)
@@ -45,6 +49,38 @@ func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
 	}
 }
 
+// RegisterLoginRoutes sets up the login routes on the provided router group, kept apart from
+// RegisterRoutes so that they can be rate limited on their own. The routes start a cookie
+// session and so require a CSRF token.
+func (h *Handler) RegisterLoginRoutes(r *gin.RouterGroup) {
+	r.Use(middleware.CSRF())
+
+	r.GET("/csrf-token", h.CSRFToken)
+	r.GET("/login/captcha", h.CaptchaStatus)
+	r.POST("/login", h.Login)
+	r.POST("/login/2fa", h.CompleteTwoFactorLogin)
+	r.POST("/login/webauthn/begin", h.BeginWebAuthnLogin)
+	r.POST("/login/webauthn/finish", h.FinishWebAuthnLogin)
+}
+
+// RegisterAccountRoutes sets up the account security routes on the provided router group.
+// All routes require web authentication.
+func (h *Handler) RegisterAccountRoutes(r *gin.RouterGroup) {
+	r.Use(middleware.CSRF())
+	r.Use(middleware.WebAuth(h.service.authService))
+
+	r.POST("/2fa/enroll", h.EnrollTwoFactor) // Start TOTP enrollment
+	r.POST("/2fa/verify", h.VerifyTwoFactor) // Activate TOTP with a first code
+
+	r.POST("/webauthn/register/begin", h.BeginWebAuthnRegistration)
+	r.POST("/webauthn/register/finish", h.FinishWebAuthnRegistration)
+	r.GET("/webauthn/credentials", h.ListWebAuthnCredentials)
+	r.DELETE("/webauthn/credentials/:id", h.DeleteWebAuthnCredential)
+
+	r.GET("/sessions", h.ListSessions)
+	r.DELETE("/sessions/:sid", h.EndSession)
+}
+
 // Register handles user account creation requests.
 // It validates the registration input, creates a new user account,
 // and returns the created user details on success.
@@ -76,7 +112,7 @@ func (h *Handler) Login(c *gin.Context) {
 
 	// Extract user agent and IP address
 	userAgent := c.Request.UserAgent()
-	ipAddress := c.ClientIP()
+	ipAddress := middleware.ClientIP(c)
 
 	response, err := h.service.Login(c.Request.Context(), req, userAgent, ipAddress)
 	if err != nil {
@@ -84,9 +120,194 @@ func (h *Handler) Login(c *gin.Context) {
 		return
 	}
 
+	setSessionCookie(c, response.Session)
+	c.JSON(http.StatusOK, response)
+}
+
+// CSRFToken returns the CSRF token to send in the X-CSRF-Token header of the login
+// requests, for web apps that cannot read it from the CSRF cookie.
+func (h *Handler) CSRFToken(c *gin.Context) {
+	c.JSON(http.StatusOK, CSRFTokenResponse{CSRFToken: c.GetString(middleware.ContextKeyCSRFToken)})
+}
+
+// CaptchaStatus reports whether the login form must show a CAPTCHA for the next login from
+// the client's IP address, optionally for the email address in the email query parameter.
+func (h *Handler) CaptchaStatus(c *gin.Context) {
+	response, err := h.service.CaptchaStatus(c.Request.Context(), c.Query("email"), middleware.ClientIP(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CompleteTwoFactorLogin handles the second step of a login for accounts with
+// two-factor authentication. It exchanges the MFA challenge from the password step
+// and a TOTP or recovery code for authentication tokens.
+func (h *Handler) CompleteTwoFactorLogin(c *gin.Context) {
+	var req TwoFactorLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.BadRequest(errors.ErrMsgInvalidRequestFormat))
+		return
+	}
+
+	response, err := h.service.CompleteTwoFactorLogin(c.Request.Context(), req, c.Request.UserAgent(), middleware.ClientIP(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	setSessionCookie(c, response.Session)
+	c.JSON(http.StatusOK, response)
+}
+
+// EnrollTwoFactor starts TOTP enrollment for the authenticated user and returns the
+// secret, the otpauth URI for a QR code, and the recovery codes.
+func (h *Handler) EnrollTwoFactor(c *gin.Context) {
+	userID := c.GetUint(middleware.ContextKeyUserID)
+
+	response, err := h.service.EnrollTwoFactor(c.Request.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// VerifyTwoFactor activates two-factor authentication for the authenticated user
+// once a code from the newly enrolled authenticator is confirmed.
+func (h *Handler) VerifyTwoFactor(c *gin.Context) {
+	var req TwoFactorVerifyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.BadRequest(errors.ErrMsgInvalidRequestFormat))
+		return
+	}
+
+	userID := c.GetUint(middleware.ContextKeyUserID)
+
+	if err := h.service.VerifyTwoFactor(c.Request.Context(), userID, req.Code); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"enabled": true})
+}
+
+// BeginWebAuthnLogin starts a passkey login and returns the options for navigator.credentials.get.
+func (h *Handler) BeginWebAuthnLogin(c *gin.Context) {
+	response, err := h.service.BeginWebAuthnLogin(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// FinishWebAuthnLogin verifies the assertion from the user's passkey and returns authentication tokens.
+func (h *Handler) FinishWebAuthnLogin(c *gin.Context) {
+	var req WebAuthnLoginFinishRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.BadRequest(errors.ErrMsgInvalidRequestFormat))
+		return
+	}
+
+	response, err := h.service.FinishWebAuthnLogin(c.Request.Context(), req, c.Request.UserAgent(), middleware.ClientIP(c))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	setSessionCookie(c, response.Session)
+	c.JSON(http.StatusOK, response)
+}
+
+// BeginWebAuthnRegistration starts registering a passkey for the authenticated user
+// and returns the options for navigator.credentials.create.
+func (h *Handler) BeginWebAuthnRegistration(c *gin.Context) {
+	userID := c.GetUint(middleware.ContextKeyUserID)
+
+	response, err := h.service.BeginWebAuthnRegistration(c.Request.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
 	c.JSON(http.StatusOK, response)
 }
 
+// FinishWebAuthnRegistration verifies the attestation from the new passkey and stores it.
+func (h *Handler) FinishWebAuthnRegistration(c *gin.Context) {
+	var req WebAuthnRegisterFinishRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(errors.BadRequest(errors.ErrMsgInvalidRequestFormat))
+		return
+	}
+
+	userID := c.GetUint(middleware.ContextKeyUserID)
+
+	response, err := h.service.FinishWebAuthnRegistration(c.Request.Context(), userID, req)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// ListWebAuthnCredentials returns the passkeys registered by the authenticated user.
+func (h *Handler) ListWebAuthnCredentials(c *gin.Context) {
+	userID := c.GetUint(middleware.ContextKeyUserID)
+
+	response, err := h.service.ListWebAuthnCredentials(c.Request.Context(), userID)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DeleteWebAuthnCredential revokes one of the authenticated user's passkeys by credential ID.
+func (h *Handler) DeleteWebAuthnCredential(c *gin.Context) {
+	userID := c.GetUint(middleware.ContextKeyUserID)
+
+	if err := h.service.DeleteWebAuthnCredential(c.Request.Context(), userID, c.Param("id")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListSessions returns the authenticated user's active web sessions, marking the current one.
+func (h *Handler) ListSessions(c *gin.Context) {
+	userID := c.GetUint(middleware.ContextKeyUserID)
+
+	response, err := h.service.ListSessions(c.Request.Context(), userID, c.GetString(middleware.ContextKeySessionID))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// EndSession ends one of the authenticated user's web sessions by its public session ID,
+// signing that browser out and revoking its refresh tokens.
+func (h *Handler) EndSession(c *gin.Context) {
+	userID := c.GetUint(middleware.ContextKeyUserID)
+
+	if err := h.service.EndSession(c.Request.Context(), userID, c.Param("sid")); err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
 // RefreshToken handles token refresh requests.
 // It validates the provided refresh token, checks if it's still valid,
 // and issues a new access token and refresh token pair.
@@ -100,7 +321,7 @@ func (h *Handler) RefreshToken(c *gin.Context) {
 
 	// Extract user agent and IP address
 	userAgent := c.Request.UserAgent()
-	ipAddress := c.ClientIP()
+	ipAddress := middleware.ClientIP(c)
 
 	response, err := h.service.RefreshToken(c.Request.Context(), req.RefreshToken, userAgent, ipAddress)
 	if err != nil {
@@ -177,7 +398,8 @@ func (h *Handler) DeleteMe(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
-// Logout handles user logout requests by revoking all active refresh tokens.
+// Logout handles user logout requests by revoking all active refresh tokens and
+// ending all web sessions, then clears the session cookie.
 // This effectively terminates all active sessions for the user.
 // This endpoint is protected and only accessible to authenticated users.
 func (h *Handler) Logout(c *gin.Context) {
@@ -188,5 +410,31 @@ func (h *Handler) Logout(c *gin.Context) {
 		return
 	}
 
+	clearSessionCookie(c)
 	c.Status(http.StatusNoContent)
 }
+
+// setSessionCookie hands a new web session to the browser. The cookie is HttpOnly so
+// scripts cannot read it, Secure so it never travels in the clear, and SameSite=Lax so
+// it is withheld from cross-site subrequests while top-level navigations, such as a client
+// redirecting to the authorization endpoint, still carry it. It lasts until the session's
+// absolute expiry; the idle timeout is enforced by the server.
+// A login that still awaits a second factor has no session and sets no cookie.
+// The cookie is scoped to the tenant the login was for.
+func setSessionCookie(c *gin.Context, session *auth.Session) {
+	if session == nil {
+		return
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(config.AppConfig.SessionCookieName, session.ID, int(time.Until(session.AbsoluteExpiresAt).Seconds()), tenant.Path(c.Request.Context(), "/"), "", true, true)
+
+	// The CSRF token was bound to the previous session
+	middleware.IssueCSRFToken(c, session.ID)
+}
+
+// clearSessionCookie removes the web session cookie from the browser.
+func clearSessionCookie(c *gin.Context) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(config.AppConfig.SessionCookieName, "", -1, tenant.Path(c.Request.Context(), "/"), "", true, true)
+	middleware.IssueCSRFToken(c, "")
+}
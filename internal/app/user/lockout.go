@@ -0,0 +1,138 @@
+package user
+
+import (
+	"context"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/app/audit"
+	"github.com/verigate/verigate-server/internal/pkg/config"
+	"github.com/verigate/verigate-server/internal/pkg/utils/hash"
+)
+
+// dummyPasswordHash returns the hash compared against when there is no account to check, so that
+// locked and unknown logins take as long to reject as a wrong password. It is made on first use
+// so it follows the configured hashing algorithm and parameters.
+var dummyPasswordHash = sync.OnceValue(func() string {
+	h, _ := hash.HashPassword("verigate-lockout-timing-equalizer")
+	return h
+})
+
+// LockoutPolicy controls when repeated failed logins lock an account and for how long.
+type LockoutPolicy struct {
+	Threshold   int           // Consecutive failures that lock the login, zero disables lockout
+	Duration    time.Duration // Length of the first lockout
+	Multiplier  float64       // Factor each further lockout is longer by
+	MaxDuration time.Duration // Upper bound for a single lockout
+	ResetAfter  time.Duration // Time without a failure after which failures and lockouts are forgotten
+}
+
+// NewLockoutPolicy reads the lockout policy from the application configuration.
+// It panics when a configured duration is invalid, like the other services' constructors.
+func NewLockoutPolicy() LockoutPolicy {
+	return LockoutPolicy{
+		Threshold:   config.AppConfig.LoginLockoutThreshold,
+		Duration:    mustParseDuration("login lockout duration", config.AppConfig.LoginLockoutDuration),
+		Multiplier:  config.AppConfig.LoginLockoutMultiplier,
+		MaxDuration: mustParseDuration("login lockout max duration", config.AppConfig.LoginLockoutMaxDuration),
+		ResetAfter:  mustParseDuration("login lockout reset after", config.AppConfig.LoginLockoutResetAfter),
+	}
+}
+
+// Enabled reports whether failed logins lead to lockouts.
+func (p LockoutPolicy) Enabled() bool {
+	return p.Threshold > 0
+}
+
+// LockDuration returns the length of the nth lockout, growing exponentially from
+// Duration by Multiplier and capped at MaxDuration.
+func (p LockoutPolicy) LockDuration(lockouts int64) time.Duration {
+	if lockouts < 1 {
+		lockouts = 1
+	}
+	duration := float64(p.Duration) * math.Pow(p.Multiplier, float64(lockouts-1))
+	if p.MaxDuration > 0 && duration > float64(p.MaxDuration) {
+		return p.MaxDuration
+	}
+	return time.Duration(duration)
+}
+
+// NormalizeLogin returns the form of a login identifier that lockouts are tracked under,
+// so differently cased spellings of an email share one failure count.
+func NormalizeLogin(login string) string {
+	return strings.ToLower(strings.TrimSpace(login))
+}
+
+// isLockedOut reports whether the login is currently locked.
+func (s *Service) isLockedOut(ctx context.Context, login string) (bool, error) {
+	if !s.lockoutPolicy.Enabled() {
+		return false, nil
+	}
+	remaining, err := s.lockoutRepo.LockedFor(ctx, login)
+	if err != nil {
+		return false, err
+	}
+	return remaining > 0, nil
+}
+
+// recordLoginFailure audits a failed login, counts it for the login and the IP address, and
+// locks the login once the threshold is reached. The counts also decide when logins must solve
+// a CAPTCHA. Each lockout within the reset period lasts longer than the one before.
+func (s *Service) recordLoginFailure(ctx context.Context, login, ipAddress string) error {
+	s.auditService.Record(ctx, audit.Event{
+		Type:    audit.EventLoginFailed,
+		Outcome: audit.OutcomeFailure,
+		Details: map[string]string{"login": login},
+	})
+
+	if s.captchaPolicy.Enabled() && s.captchaPolicy.IPThreshold > 0 && ipAddress != "" {
+		if _, err := s.captchaRepo.IncrementIPFailures(ctx, ipAddress, s.captchaPolicy.IPWindow); err != nil {
+			return err
+		}
+	}
+
+	countFailures := s.captchaPolicy.Enabled() && s.captchaPolicy.LoginThreshold > 0
+	if !s.lockoutPolicy.Enabled() && !countFailures {
+		return nil
+	}
+
+	failures, err := s.lockoutRepo.IncrementFailures(ctx, login, s.lockoutPolicy.ResetAfter)
+	if err != nil {
+		return err
+	}
+	if !s.lockoutPolicy.Enabled() || failures < int64(s.lockoutPolicy.Threshold) {
+		return nil
+	}
+
+	duration := s.lockoutPolicy.LockDuration(1)
+	lockouts, err := s.lockoutRepo.IncrementLockouts(ctx, login, s.lockoutPolicy.ResetAfter+s.lockoutPolicy.MaxDuration)
+	if err == nil {
+		duration = s.lockoutPolicy.LockDuration(lockouts)
+	}
+
+	if err := s.lockoutRepo.Lock(ctx, login, duration); err != nil {
+		return err
+	}
+
+	s.auditService.Record(ctx, audit.Event{
+		Type:    audit.EventAccountLocked,
+		Details: map[string]string{"login": login, "duration": duration.String()},
+	})
+	return nil
+}
+
+// ClearLockout removes the lockout and failure history of a login so the account can sign in again.
+func (s *Service) ClearLockout(ctx context.Context, login string) error {
+	return s.lockoutRepo.Clear(ctx, NormalizeLogin(login))
+}
+
+// mustParseDuration parses a configured duration, panicking with the setting's name when invalid.
+func mustParseDuration(name, value string) time.Duration {
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		panic("invalid " + name + ": " + err.Error())
+	}
+	return duration
+}
@@ -23,3 +23,31 @@ type User struct {
 	UpdatedAt               time.Time  `json:"updated_at"`                    // When the account was last updated
 	LastLoginAt             *time.Time `json:"last_login_at,omitempty"`       // When the user last logged in
 }
+
+// TwoFactor holds a user's TOTP authenticator.
+// It is stored when enrollment starts and only enforced at login once enabled.
+type TwoFactor struct {
+	UserID          uint       `json:"user_id"`              // User the authenticator belongs to
+	SecretEncrypted string     `json:"-"`                    // TOTP secret, encrypted at rest
+	Enabled         bool       `json:"enabled"`              // Whether enrollment was verified with a code
+	LastUsedStep    int64      `json:"-"`                    // Time step of the last accepted code, to reject replays
+	CreatedAt       time.Time  `json:"created_at"`           // When enrollment started
+	EnabledAt       *time.Time `json:"enabled_at,omitempty"` // When enrollment was verified
+}
+
+// WebAuthnCredential is a passkey registered by a user.
+// A user may register several, one per authenticator.
+type WebAuthnCredential struct {
+	ID              uint       `json:"-"`                      // Primary key
+	UserID          uint       `json:"user_id"`                // User the passkey belongs to
+	CredentialID    []byte     `json:"-"`                      // Credential ID chosen by the authenticator
+	PublicKey       []byte     `json:"-"`                      // COSE-encoded credential public key
+	AttestationType string     `json:"-"`                      // Attestation format used at registration
+	AAGUID          []byte     `json:"-"`                      // Authenticator model identifier
+	SignCount       uint32     `json:"-"`                      // Last signature counter reported by the authenticator
+	Flags           byte       `json:"-"`                      // Authenticator data flags recorded at registration
+	Transports      []string   `json:"transports"`             // How the client can reach the authenticator
+	Name            string     `json:"name"`                   // Label chosen by the user
+	CreatedAt       time.Time  `json:"created_at"`             // When the passkey was registered
+	LastUsedAt      *time.Time `json:"last_used_at,omitempty"` // When the passkey last signed in
+}
@@ -0,0 +1,120 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/verigate/verigate-server/internal/pkg/config"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+	"github.com/verigate/verigate-server/internal/pkg/utils/pwned"
+)
+
+// PasswordRule is one requirement a new password must meet. Check returns a message
+// describing the unmet requirement, or an empty string when the password satisfies it.
+// An error means the rule could not be evaluated.
+type PasswordRule interface {
+	Check(ctx context.Context, password string) (string, error)
+}
+
+// PasswordRuleFunc adapts a function to the PasswordRule interface.
+type PasswordRuleFunc func(ctx context.Context, password string) (string, error)
+
+// Check calls f(ctx, password).
+func (f PasswordRuleFunc) Check(ctx context.Context, password string) (string, error) {
+	return f(ctx, password)
+}
+
+// PasswordPolicy is the set of rules that new and changed passwords are validated against.
+type PasswordPolicy struct {
+	Rules []PasswordRule
+}
+
+// NewPasswordPolicy builds the password policy from the application configuration.
+// It panics when the breach check timeout is invalid, like the other services' constructors.
+func NewPasswordPolicy() PasswordPolicy {
+	rules := []PasswordRule{MinLengthRule(config.AppConfig.PasswordMinLength)}
+	if config.AppConfig.PasswordRequireUppercase {
+		rules = append(rules, CharacterClassRule(unicode.IsUpper, errors.ErrMsgPasswordNeedsUppercase))
+	}
+	if config.AppConfig.PasswordRequireLowercase {
+		rules = append(rules, CharacterClassRule(unicode.IsLower, errors.ErrMsgPasswordNeedsLowercase))
+	}
+	if config.AppConfig.PasswordRequireDigit {
+		rules = append(rules, CharacterClassRule(unicode.IsDigit, errors.ErrMsgPasswordNeedsDigit))
+	}
+	if config.AppConfig.PasswordRequireSymbol {
+		rules = append(rules, CharacterClassRule(isPasswordSymbol, errors.ErrMsgPasswordNeedsSymbol))
+	}
+	if config.AppConfig.PasswordBreachCheck {
+		timeout := mustParseDuration("password breach check timeout", config.AppConfig.PasswordBreachCheckTimeout)
+		client := pwned.NewClient(config.AppConfig.PasswordBreachCheckURL, timeout)
+		rules = append(rules, BreachedPasswordRule(client.Count))
+	}
+	return PasswordPolicy{Rules: rules}
+}
+
+// Validate checks the password against every rule. A rejected password yields a bad
+// request error whose details list each unmet requirement, not just the first.
+func (p PasswordPolicy) Validate(ctx context.Context, password string) error {
+	var violations []string
+	for _, rule := range p.Rules {
+		violation, err := rule.Check(ctx, password)
+		if err != nil {
+			return err
+		}
+		if violation != "" {
+			violations = append(violations, violation)
+		}
+	}
+
+	if len(violations) > 0 {
+		return errors.BadRequest(errors.ErrMsgPasswordPolicyViolation).WithDetails(violations)
+	}
+	return nil
+}
+
+// MinLengthRule requires at least min characters, counted as Unicode code points.
+func MinLengthRule(min int) PasswordRule {
+	return PasswordRuleFunc(func(ctx context.Context, password string) (string, error) {
+		if utf8.RuneCountInString(password) < min {
+			return fmt.Sprintf(errors.ErrMsgPasswordTooShort, min), nil
+		}
+		return "", nil
+	})
+}
+
+// CharacterClassRule requires at least one character for which inClass reports true,
+// reporting violation otherwise.
+func CharacterClassRule(inClass func(rune) bool, violation string) PasswordRule {
+	return PasswordRuleFunc(func(ctx context.Context, password string) (string, error) {
+		if strings.IndexFunc(password, inClass) < 0 {
+			return violation, nil
+		}
+		return "", nil
+	})
+}
+
+// BreachedPasswordRule rejects passwords that count reports as seen in known data breaches.
+// A failed lookup is not held against the user: the rule passes when the breach corpus
+// is unreachable, so an outage of the lookup service does not block sign-ups.
+func BreachedPasswordRule(count func(ctx context.Context, password string) (int, error)) PasswordRule {
+	return PasswordRuleFunc(func(ctx context.Context, password string) (string, error) {
+		n, err := count(ctx, password)
+		if err != nil {
+			return "", nil
+		}
+		if n > 0 {
+			return errors.ErrMsgPasswordBreached, nil
+		}
+		return "", nil
+	})
+}
+
+// isPasswordSymbol reports whether r counts as a symbol: any printable character that is
+// neither a letter, a digit, nor a space.
+func isPasswordSymbol(r rune) bool {
+	return unicode.IsPrint(r) && !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsSpace(r)
+}
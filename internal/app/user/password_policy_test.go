@@ -0,0 +1,97 @@
+package user
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"unicode"
+
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+)
+
+// stubBreachCount reports the passwords in breached as seen the given number of times,
+// standing in for the Pwned Passwords lookup.
+func stubBreachCount(breached map[string]int) func(ctx context.Context, password string) (int, error) {
+	return func(ctx context.Context, password string) (int, error) {
+		return breached[password], nil
+	}
+}
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	policy := PasswordPolicy{Rules: []PasswordRule{
+		MinLengthRule(8),
+		CharacterClassRule(unicode.IsUpper, errors.ErrMsgPasswordNeedsUppercase),
+		CharacterClassRule(unicode.IsLower, errors.ErrMsgPasswordNeedsLowercase),
+		CharacterClassRule(unicode.IsDigit, errors.ErrMsgPasswordNeedsDigit),
+		CharacterClassRule(isPasswordSymbol, errors.ErrMsgPasswordNeedsSymbol),
+		BreachedPasswordRule(stubBreachCount(map[string]int{"Passw0rd!": 42})),
+	}}
+	tooShort := fmt.Sprintf(errors.ErrMsgPasswordTooShort, 8)
+
+	tests := []struct {
+		name     string
+		password string
+		want     []string // Expected violations, none for an accepted password
+	}{
+		{"meets every rule", "Corr3ct-horse", nil},
+		{"too short", "Aa1!", []string{tooShort}},
+		{"length counts code points", "Ünïcødé1!", nil},
+		{"multibyte too short", "Ünï1!", []string{tooShort}},
+		{"no uppercase", "corr3ct-horse", []string{errors.ErrMsgPasswordNeedsUppercase}},
+		{"no lowercase", "CORR3CT-HORSE", []string{errors.ErrMsgPasswordNeedsLowercase}},
+		{"no digit", "Correct-horse", []string{errors.ErrMsgPasswordNeedsDigit}},
+		{"no symbol", "Corr3cthorse", []string{errors.ErrMsgPasswordNeedsSymbol}},
+		{"space is no symbol", "Corr3ct horse", []string{errors.ErrMsgPasswordNeedsSymbol}},
+		{"breached", "Passw0rd!", []string{errors.ErrMsgPasswordBreached}},
+		{"every violation listed", "abc", []string{
+			tooShort,
+			errors.ErrMsgPasswordNeedsUppercase,
+			errors.ErrMsgPasswordNeedsDigit,
+			errors.ErrMsgPasswordNeedsSymbol,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Validate(context.Background(), tt.password)
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("Validate(%q) = %v, want no error", tt.password, err)
+				}
+				return
+			}
+
+			customErr, ok := errors.As(err)
+			if !ok || customErr.Code() != errors.ErrMsgPasswordPolicyViolation {
+				t.Fatalf("Validate(%q) = %v, want %q", tt.password, err, errors.ErrMsgPasswordPolicyViolation)
+			}
+			if !reflect.DeepEqual(customErr.Details, tt.want) {
+				t.Errorf("Validate(%q) details = %v, want %v", tt.password, customErr.Details, tt.want)
+			}
+		})
+	}
+}
+
+func TestBreachedPasswordRulePassesWhenLookupFails(t *testing.T) {
+	rule := BreachedPasswordRule(func(ctx context.Context, password string) (int, error) {
+		return 0, stderrors.New("breach corpus unreachable")
+	})
+
+	violation, err := rule.Check(context.Background(), "Passw0rd!")
+	if violation != "" || err != nil {
+		t.Errorf("got %q, %v, want the password accepted while the lookup fails", violation, err)
+	}
+}
+
+func TestPasswordPolicyValidateReturnsRuleError(t *testing.T) {
+	ruleErr := stderrors.New("rule failed")
+	policy := PasswordPolicy{Rules: []PasswordRule{
+		PasswordRuleFunc(func(ctx context.Context, password string) (string, error) { return "", ruleErr }),
+	}}
+
+	if err := policy.Validate(context.Background(), "Corr3ct-horse"); err != ruleErr {
+		t.Errorf("got error %v, want the rule's error", err)
+	}
+}
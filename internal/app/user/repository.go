@@ -4,6 +4,7 @@ package user
 
 import (
 	"context"
+	"time"
 )
 
 // Repository defines the interface for user data access operations.
@@ -33,3 +34,93 @@ type Repository interface {
 	// Delete removes a user account from the data store
 	Delete(ctx context.Context, id uint) error
 }
+
+// LockoutRepository defines the interface for tracking failed logins and account lockouts.
+// Entries are keyed by the normalized login identifier, whether or not an account uses it.
+type LockoutRepository interface {
+	// IncrementFailures counts a failed login and returns the consecutive failures so far.
+	// The count is forgotten after ttl without another failure.
+	IncrementFailures(ctx context.Context, login string, ttl time.Duration) (int64, error)
+
+	// IncrementLockouts counts a lockout and returns the lockouts so far.
+	// The count is forgotten after ttl without another lockout.
+	IncrementLockouts(ctx context.Context, login string, ttl time.Duration) (int64, error)
+
+	// Lock locks the login for duration and restarts the failure count
+	Lock(ctx context.Context, login string, duration time.Duration) error
+
+	// Failures returns the consecutive failures counted for the login, zero when there are none
+	Failures(ctx context.Context, login string) (int64, error)
+
+	// LockedFor returns how long the login stays locked, zero when it is not locked
+	LockedFor(ctx context.Context, login string) (time.Duration, error)
+
+	// ResetFailures forgets the failure count and lockout history after a successful login
+	ResetFailures(ctx context.Context, login string) error
+
+	// Clear removes the lockout, failure count, and lockout history of the login
+	Clear(ctx context.Context, login string) error
+}
+
+// CaptchaRepository defines the interface for the state of the login CAPTCHA challenge:
+// failed logins per IP address and the CAPTCHA tokens already used.
+type CaptchaRepository interface {
+	// IncrementIPFailures counts a failed login from the IP address and returns the failures so far.
+	// The count is forgotten after ttl without another failure.
+	IncrementIPFailures(ctx context.Context, ip string, ttl time.Duration) (int64, error)
+
+	// IPFailures returns the failed logins counted for the IP address, zero when there are none
+	IPFailures(ctx context.Context, ip string) (int64, error)
+
+	// Redeem marks a CAPTCHA token as used for ttl. It returns false if the token was used before.
+	Redeem(ctx context.Context, token string, ttl time.Duration) (bool, error)
+}
+
+// TwoFactorRepository defines the interface for TOTP authenticators and recovery codes.
+type TwoFactorRepository interface {
+	// SaveTwoFactor stores a pending enrollment, replacing any authenticator the user had
+	SaveTwoFactor(ctx context.Context, twoFactor *TwoFactor) error
+
+	// FindTwoFactor retrieves the user's authenticator, nil if the user never enrolled
+	FindTwoFactor(ctx context.Context, userID uint) (*TwoFactor, error)
+
+	// EnableTwoFactor marks the user's authenticator as verified
+	EnableTwoFactor(ctx context.Context, userID uint, step int64) error
+
+	// UseStep records the time step of an accepted code and reports whether it was newer
+	// than the last one, so each code is accepted only once
+	UseStep(ctx context.Context, userID uint, step int64) (bool, error)
+
+	// ReplaceRecoveryCodes discards the user's recovery codes and stores the given hashes
+	ReplaceRecoveryCodes(ctx context.Context, userID uint, codeHashes []string) error
+
+	// UseRecoveryCode marks an unused recovery code as used and reports whether one matched
+	UseRecoveryCode(ctx context.Context, userID uint, codeHash string) (bool, error)
+}
+
+// WebAuthnRepository defines the interface for registered passkeys.
+type WebAuthnRepository interface {
+	// SaveCredential stores a newly registered passkey
+	SaveCredential(ctx context.Context, credential *WebAuthnCredential) error
+
+	// FindCredentialsByUser retrieves all passkeys registered by the user
+	FindCredentialsByUser(ctx context.Context, userID uint) ([]*WebAuthnCredential, error)
+
+	// UpdateSignCount records a successful login with the passkey and reports whether the
+	// stored counter was lower, or both are zero for authenticators without a counter,
+	// so a replayed or cloned counter is never accepted
+	UpdateSignCount(ctx context.Context, credentialID []byte, signCount uint32) (bool, error)
+
+	// DeleteCredential removes one of the user's passkeys and reports whether it existed
+	DeleteCredential(ctx context.Context, userID uint, credentialID []byte) (bool, error)
+}
+
+// WebAuthnSessionRepository defines the interface for state kept between the begin and
+// finish steps of a passkey ceremony. Each session can be taken only once.
+type WebAuthnSessionRepository interface {
+	// SaveSession stores the ceremony state under handle for ttl
+	SaveSession(ctx context.Context, handle string, data []byte, ttl time.Duration) error
+
+	// TakeSession returns and removes the ceremony state, nil if it expired or was already used
+	TakeSession(ctx context.Context, handle string) ([]byte, error)
+}
@@ -6,24 +6,67 @@ import (
 	"context"
 	"time"
 
+	"github.com/verigate/verigate-server/internal/app/audit"
 	"github.com/verigate/verigate-server/internal/app/auth"
+	"github.com/verigate/verigate-server/internal/app/logout"
+	"github.com/verigate/verigate-server/internal/pkg/config"
+	"github.com/verigate/verigate-server/internal/pkg/db/routing"
+	"github.com/verigate/verigate-server/internal/pkg/utils/encryption"
 	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
 	"github.com/verigate/verigate-server/internal/pkg/utils/hash"
+
+	"github.com/go-webauthn/webauthn/webauthn"
 )
 
 // Service handles user-related business logic including registration,
 // authentication, profile management, and account operations.
 type Service struct {
-	repo        Repository
-	authService *auth.Service
+	repo                Repository
+	lockoutRepo         LockoutRepository
+	lockoutPolicy       LockoutPolicy
+	captchaRepo         CaptchaRepository
+	captchaPolicy       CaptchaPolicy
+	passwordPolicy      PasswordPolicy
+	twoFactorRepo       TwoFactorRepository
+	totpKey             []byte // Encrypts TOTP secrets at rest, nil when two-factor authentication is not configured
+	webAuthn            *webauthn.WebAuthn
+	webAuthnRepo        WebAuthnRepository
+	webAuthnSessionRepo WebAuthnSessionRepository
+	authService         *auth.Service
+	logoutService       *logout.Service
+	auditService        *audit.Service
 }
 
 // NewService creates a new user service instance with the necessary dependencies.
-// It requires a user repository for data access and an auth service for token operations.
-func NewService(repo Repository, authService *auth.Service) *Service {
+// It requires a user repository for data access, a lockout repository for tracking failed logins,
+// a CAPTCHA repository for the state of the login CAPTCHA challenge, a two-factor repository for TOTP authenticators, WebAuthn repositories for passkeys and
+// their ceremonies, an auth service for token operations, a logout service to notify
+// clients when the user logs out, and an audit service recording logins.
+func NewService(repo Repository, lockoutRepo LockoutRepository, captchaRepo CaptchaRepository, twoFactorRepo TwoFactorRepository, webAuthnRepo WebAuthnRepository, webAuthnSessionRepo WebAuthnSessionRepository, authService *auth.Service, logoutService *logout.Service, auditService *audit.Service) *Service {
+	var totpKey []byte
+	if config.AppConfig.TOTPEncryptionKey != "" {
+		key, err := encryption.ParseKey(config.AppConfig.TOTPEncryptionKey)
+		if err != nil {
+			panic("invalid TOTP encryption key: " + err.Error())
+		}
+		totpKey = key
+	}
+
 	return &Service{
-		repo:        repo,
-		authService: authService,
+		repo:                repo,
+		lockoutRepo:         lockoutRepo,
+		lockoutPolicy:       NewLockoutPolicy(),
+		captchaRepo:         captchaRepo,
+		captchaPolicy:       NewCaptchaPolicy(),
+		passwordPolicy:      NewPasswordPolicy(),
+		twoFactorRepo:       twoFactorRepo,
+		totpKey:             totpKey,
+		webAuthn:            newWebAuthn(),
+		webAuthnRepo:        webAuthnRepo,
+		webAuthnSessionRepo: webAuthnSessionRepo,
+		authService:         authService,
+		logoutService:       logoutService,
+		auditService:        auditService,
 	}
 }
 
@@ -46,6 +89,10 @@ func (s *Service) Register(ctx context.Context, req RegisterRequest) (*UserRespo
 		return nil, errors.BadRequest(errors.ErrMsgUsernameAlreadyTaken)
 	}
 
+	if err := s.passwordPolicy.Validate(ctx, req.Password); err != nil {
+		return nil, err
+	}
+
 	// Hash password
 	hashedPassword, err := hash.HashPassword(req.Password)
 	if err != nil {
@@ -71,44 +118,176 @@ func (s *Service) Register(ctx context.Context, req RegisterRequest) (*UserRespo
 	return s.toResponse(user), nil
 }
 
+// Login authenticates a user by email and password and issues a token pair.
+// Consecutive failures for the same email lock it for a while regardless of the source IP,
+// and once failures for the email or from the IP address pile up a CAPTCHA must be solved first.
+// Locked, unknown, and wrong-password logins are rejected with the same error after a
+// password hash comparison, so the response does not reveal whether the account exists.
 func (s *Service) Login(ctx context.Context, req LoginRequest, userAgent, ipAddress string) (*LoginResponse, error) {
+	login := NormalizeLogin(req.Email)
+
+	if err := s.checkCaptcha(ctx, login, ipAddress, req.CaptchaToken); err != nil {
+		return nil, err
+	}
+
+	locked, err := s.isLockedOut(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	if locked {
+		hash.CompareHashAndPassword(dummyPasswordHash(), req.Password)
+		s.auditService.Record(ctx, audit.Event{
+			Type:    audit.EventLoginFailed,
+			Outcome: audit.OutcomeFailure,
+			Details: map[string]string{"login": login, "reason": "locked"},
+		})
+		return nil, errors.Unauthorized(errors.ErrMsgInvalidCredentials)
+	}
+
 	user, err := s.repo.FindByEmail(ctx, req.Email)
 	if err != nil {
 		return nil, err
 	}
 	if user == nil {
+		hash.CompareHashAndPassword(dummyPasswordHash(), req.Password)
+		if err := s.recordLoginFailure(ctx, login, ipAddress); err != nil {
+			return nil, err
+		}
 		return nil, errors.Unauthorized(errors.ErrMsgInvalidCredentials)
 	}
 
 	// Verify password
 	if err := hash.CompareHashAndPassword(user.PasswordHash, req.Password); err != nil {
+		if err := s.recordLoginFailure(ctx, login, ipAddress); err != nil {
+			return nil, err
+		}
 		return nil, errors.Unauthorized(errors.ErrMsgInvalidCredentials)
 	}
+	s.upgradePasswordHash(ctx, user, req.Password)
 
 	// Check if user is active
 	if !user.IsActive {
+		s.auditService.Record(ctx, audit.Event{
+			Type:    audit.EventLoginFailed,
+			Actor:   audit.UserActor(user.ID),
+			Outcome: audit.OutcomeFailure,
+			Details: map[string]string{"login": login, "reason": "inactive"},
+		})
 		return nil, errors.Unauthorized(errors.ErrMsgAccountNotActive)
 	}
 
+	// Accounts with two-factor authentication continue with the second factor
+	twoFactorEnabled, err := s.isTwoFactorEnabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if twoFactorEnabled {
+		mfaToken, err := s.authService.CreateMFAChallenge(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		return &LoginResponse{MFARequired: true, MFAToken: mfaToken}, nil
+	}
+
+	return s.completeLogin(ctx, user, login, auth.ACRPassword, userAgent, ipAddress)
+}
+
+// upgradePasswordHash re-hashes a verified password when its stored hash was made with an
+// outdated algorithm or parameters, so hashes follow the configuration without a migration.
+func (s *Service) upgradePasswordHash(ctx context.Context, user *User, password string) {
+	if !hash.NeedsRehash(user.PasswordHash) {
+		return
+	}
+
+	hashedPassword, err := hash.HashPassword(password)
+	if err != nil {
+		// Not critical, continue
+		return
+	}
+	if err := s.repo.UpdatePassword(ctx, user.ID, hashedPassword); err != nil {
+		// Not critical, continue
+		return
+	}
+	user.PasswordHash = hashedPassword
+}
+
+// completeLogin finishes a login once every factor has been verified: the failure count
+// is reset, the login recorded, and a web session started with a token pair issued in it.
+// acr records how the user authenticated.
+func (s *Service) completeLogin(ctx context.Context, user *User, login, acr, userAgent, ipAddress string) (*LoginResponse, error) {
+	// A successful login ends the run of consecutive failures
+	if err := s.lockoutRepo.ResetFailures(ctx, login); err != nil {
+		// Not critical, continue
+	}
+
 	// Update last login
 	if err := s.repo.UpdateLastLogin(ctx, user.ID); err != nil {
 		// Not critical, continue
 	}
 
-	// Generate tokens
-	tokenPair, err := s.authService.CreateTokenPair(ctx, user.ID, userAgent, ipAddress)
+	// Start the session and generate its tokens
+	session, evicted, err := s.authService.CreateSession(ctx, user.ID, acr, userAgent, ipAddress)
+	if err != nil {
+		if customErr, ok := errors.As(err); ok && customErr.Message == errors.ErrMsgTooManySessions {
+			s.auditService.Record(ctx, audit.Event{
+				Type:      audit.EventLoginFailed,
+				Actor:     audit.UserActor(user.ID),
+				IPAddress: ipAddress,
+				Outcome:   audit.OutcomeFailure,
+				Details:   map[string]string{"login": login, "reason": "session_limit"},
+			})
+		}
+		return nil, err
+	}
+	for _, ended := range evicted {
+		s.recordSessionEnded(ctx, ended, sessionEndReasonEvicted)
+	}
+	tokenPair, err := s.authService.CreateSessionTokenPair(ctx, session, userAgent, ipAddress)
 	if err != nil {
 		return nil, err
 	}
 
+	s.auditService.Record(ctx, audit.Event{
+		Type:      audit.EventLoginSucceeded,
+		Actor:     audit.UserActor(user.ID),
+		IPAddress: ipAddress,
+		Details:   map[string]string{"acr": acr, "session_id_hash": audit.HashValue(session.ID)},
+	})
+
 	return &LoginResponse{
-		User:         *s.toResponse(user),
+		User:         s.toResponse(user),
 		AccessToken:  tokenPair.AccessToken,
 		RefreshToken: tokenPair.RefreshToken,
-		ExpiresAt:    tokenPair.AccessTokenExpiresAt,
+		ExpiresAt:    &tokenPair.AccessTokenExpiresAt,
+		Session:      session,
 	}, nil
 }
 
+// AuthenticationContexts returns the ways the user can log in, as the ACR values of the sessions
+// they would start: with a password, which requires the second factor once it is enabled, and
+// with a passkey if one is registered.
+func (s *Service) AuthenticationContexts(ctx context.Context, userID uint) ([]string, error) {
+	twoFactorEnabled, err := s.isTwoFactorEnabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	contexts := []string{auth.ACRPassword}
+	if twoFactorEnabled {
+		contexts = []string{auth.ACRMFA}
+	}
+
+	credentials, err := s.webAuthnRepo.FindCredentialsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(credentials) > 0 {
+		contexts = append(contexts, auth.ACRPasskey)
+	}
+
+	return contexts, nil
+}
+
 func (s *Service) GetByID(ctx context.Context, id uint) (*UserResponse, error) {
 	user, err := s.repo.FindByID(ctx, id)
 	if err != nil {
@@ -122,7 +301,8 @@ func (s *Service) GetByID(ctx context.Context, id uint) (*UserResponse, error) {
 }
 
 func (s *Service) Update(ctx context.Context, id uint, req UpdateUserRequest) error {
-	user, err := s.repo.FindByID(ctx, id)
+	// Users about to be changed are read from the primary, so no change made within the replication lag is lost
+	user, err := s.repo.FindByID(routing.WithPrimary(ctx), id)
 	if err != nil {
 		return err
 	}
@@ -146,7 +326,7 @@ func (s *Service) Update(ctx context.Context, id uint, req UpdateUserRequest) er
 }
 
 func (s *Service) ChangePassword(ctx context.Context, id uint, req ChangePasswordRequest) error {
-	user, err := s.repo.FindByID(ctx, id)
+	user, err := s.repo.FindByID(routing.WithPrimary(ctx), id)
 	if err != nil {
 		return err
 	}
@@ -159,6 +339,10 @@ func (s *Service) ChangePassword(ctx context.Context, id uint, req ChangePasswor
 		return errors.Unauthorized(errors.ErrMsgIncorrectPassword)
 	}
 
+	if err := s.passwordPolicy.Validate(ctx, req.NewPassword); err != nil {
+		return err
+	}
+
 	// Hash new password
 	hashedPassword, err := hash.HashPassword(req.NewPassword)
 	if err != nil {
@@ -169,7 +353,7 @@ func (s *Service) ChangePassword(ctx context.Context, id uint, req ChangePasswor
 }
 
 func (s *Service) Delete(ctx context.Context, id uint) error {
-	user, err := s.repo.FindByID(ctx, id)
+	user, err := s.repo.FindByID(routing.WithPrimary(ctx), id)
 	if err != nil {
 		return err
 	}
@@ -194,9 +378,21 @@ func (s *Service) RefreshToken(ctx context.Context, refreshToken, userAgent, ipA
 	}, nil
 }
 
-// Logout revokes all the user's refresh tokens
+// Logout revokes all the user's refresh tokens, ends their web sessions, and notifies clients with back-channel logout
 func (s *Service) Logout(ctx context.Context, userID uint) error {
-	return s.authService.RevokeAllUserRefreshTokens(ctx, userID)
+	if err := s.authService.RevokeAllUserRefreshTokens(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.authService.EndAllUserSessions(ctx, userID); err != nil {
+		return err
+	}
+
+	// Tell clients with back-channel logout that the user's sessions ended
+	if err := s.logoutService.NotifyLogout(ctx, userID); err != nil {
+		// Not critical, continue
+	}
+
+	return nil
 }
 
 func (s *Service) toResponse(user *User) *UserResponse {
@@ -0,0 +1,86 @@
+package user
+
+import (
+	"context"
+	"testing"
+
+	"github.com/verigate/verigate-server/internal/pkg/config"
+	"github.com/verigate/verigate-server/internal/pkg/utils/hash"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	testEmail    = "alice@example.com"
+	testPassword = "correct horse battery staple"
+)
+
+// userWithPasswordCost returns an active user whose password is hashed with bcrypt at cost.
+func userWithPasswordCost(t *testing.T, cost int) *User {
+	t.Helper()
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(testPassword), cost)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	return &User{ID: 1, Username: "alice", Email: testEmail, PasswordHash: string(passwordHash), IsActive: true}
+}
+
+// withBcryptCost configures the bcrypt cost of new password hashes for the rest of the test.
+func withBcryptCost(t *testing.T, cost int) {
+	t.Helper()
+
+	previousAlgorithm, previousCost := config.AppConfig.PasswordHashAlgorithm, config.AppConfig.BcryptCost
+	config.AppConfig.PasswordHashAlgorithm = hash.AlgorithmBcrypt
+	config.AppConfig.BcryptCost = cost
+	t.Cleanup(func() {
+		config.AppConfig.PasswordHashAlgorithm = previousAlgorithm
+		config.AppConfig.BcryptCost = previousCost
+	})
+}
+
+func TestLoginUpgradesOutdatedPasswordHash(t *testing.T) {
+	withBcryptCost(t, bcrypt.MinCost+1)
+	service, repo := newTestService(t, userWithPasswordCost(t, bcrypt.MinCost))
+
+	if _, err := service.Login(context.Background(), LoginRequest{Email: testEmail, Password: testPassword}, "test", "192.0.2.1"); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+
+	stored := repo.passwordHash(1)
+	cost, err := bcrypt.Cost([]byte(stored))
+	if err != nil || cost != bcrypt.MinCost+1 {
+		t.Errorf("stored hash has cost %d (%v), want the configured cost %d", cost, err, bcrypt.MinCost+1)
+	}
+	if err := hash.CompareHashAndPassword(stored, testPassword); err != nil {
+		t.Errorf("upgraded hash does not verify the password: %v", err)
+	}
+}
+
+func TestLoginKeepsCurrentPasswordHash(t *testing.T) {
+	withBcryptCost(t, bcrypt.MinCost)
+	u := userWithPasswordCost(t, bcrypt.MinCost)
+	service, repo := newTestService(t, u)
+
+	if _, err := service.Login(context.Background(), LoginRequest{Email: testEmail, Password: testPassword}, "test", "192.0.2.1"); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+
+	if stored := repo.passwordHash(1); stored != u.PasswordHash {
+		t.Error("login rehashed a password hash made with the configured cost")
+	}
+}
+
+func TestLoginWithWrongPasswordKeepsOutdatedHash(t *testing.T) {
+	withBcryptCost(t, bcrypt.MinCost+1)
+	u := userWithPasswordCost(t, bcrypt.MinCost)
+	service, repo := newTestService(t, u)
+
+	if _, err := service.Login(context.Background(), LoginRequest{Email: testEmail, Password: "wrong password"}, "test", "192.0.2.1"); err == nil {
+		t.Fatal("Login with a wrong password succeeded")
+	}
+
+	if stored := repo.passwordHash(1); stored != u.PasswordHash {
+		t.Error("a failed login rehashed the password")
+	}
+}
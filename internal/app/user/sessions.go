@@ -0,0 +1,87 @@
+package user
+
+import (
+	"context"
+	stderrors "errors"
+
+	"github.com/verigate/verigate-server/internal/app/audit"
+	"github.com/verigate/verigate-server/internal/app/auth"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+)
+
+// Reasons recorded when a session is ended before it expires
+const (
+	sessionEndReasonEvicted    = "evicted"    // Ended to make room for a newer login
+	sessionEndReasonTerminated = "terminated" // Ended by the user from the account page
+	sessionEndReasonLoggedOut  = "logged_out" // Ended by an RP-initiated logout scoped to the session
+)
+
+// ListSessions returns the user's active web sessions, oldest first.
+// currentSID marks the session making the request.
+func (s *Service) ListSessions(ctx context.Context, userID uint, currentSID string) ([]*SessionResponse, error) {
+	sessions, err := s.authService.ListUserSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		responses = append(responses, toSessionResponse(session, currentSID))
+	}
+	return responses, nil
+}
+
+// EndSession ends one of the user's web sessions by its public session ID,
+// along with the refresh tokens issued in it.
+func (s *Service) EndSession(ctx context.Context, userID uint, sid string) error {
+	session, err := s.authService.EndUserSession(ctx, userID, sid)
+	if err != nil {
+		return err
+	}
+
+	s.recordSessionEnded(ctx, session, sessionEndReasonTerminated)
+	return nil
+}
+
+// LogoutSession logs the user out of one web session, identified by its public session ID:
+// the session and its refresh tokens are ended, and the clients that took part in it are told
+// over back-channel logout. The user's other sessions stay logged in. A web session that has
+// already ended is not an error, since its clients may still need to be told.
+func (s *Service) LogoutSession(ctx context.Context, userID uint, sid string) error {
+	session, err := s.authService.EndUserSession(ctx, userID, sid)
+	if err != nil && !stderrors.Is(err, errors.NotFound(errors.ErrMsgSessionNotFound)) {
+		return err
+	}
+	if session != nil {
+		s.recordSessionEnded(ctx, session, sessionEndReasonLoggedOut)
+	}
+
+	// Tell clients with back-channel logout that the session ended
+	if err := s.logoutService.NotifySessionLogout(ctx, userID, sid); err != nil {
+		// Not critical, continue
+	}
+
+	return nil
+}
+
+// recordSessionEnded records in the audit trail that a session ended early and why.
+func (s *Service) recordSessionEnded(ctx context.Context, session *auth.Session, reason string) {
+	s.auditService.Record(ctx, audit.Event{
+		Type:    audit.EventSessionEnded,
+		Actor:   audit.UserActor(session.UserID),
+		Details: map[string]string{"sid": session.SID, "reason": reason},
+	})
+}
+
+// toSessionResponse converts a web session to its account page representation.
+func toSessionResponse(session *auth.Session, currentSID string) *SessionResponse {
+	return &SessionResponse{
+		SID:       session.SID,
+		ACR:       session.ACR,
+		UserAgent: session.UserAgent,
+		IPAddress: session.IPAddress,
+		CreatedAt: session.CreatedAt,
+		ExpiresAt: session.AbsoluteExpiresAt,
+		Current:   session.SID == currentSID,
+	}
+}
@@ -0,0 +1,224 @@
+package user
+
+import (
+	"context"
+	"crypto/rand"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/app/auth"
+	"github.com/verigate/verigate-server/internal/pkg/config"
+	"github.com/verigate/verigate-server/internal/pkg/utils/encryption"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+	"github.com/verigate/verigate-server/internal/pkg/utils/hash"
+	"github.com/verigate/verigate-server/internal/pkg/utils/totp"
+)
+
+const (
+	recoveryCodeCount    = 10                                // Recovery codes generated at enrollment
+	recoveryCodeLength   = 10                                // Characters per recovery code, shown in two groups
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789" // Lowercase letters and digits without look-alikes
+)
+
+// EnrollTwoFactor starts TOTP enrollment for the user. It generates a secret, stored encrypted,
+// and a set of recovery codes, stored hashed, and returns both for display.
+// Two-factor authentication is only enforced once VerifyTwoFactor confirms a code from the authenticator;
+// enrolling again before that replaces the pending secret and recovery codes.
+func (s *Service) EnrollTwoFactor(ctx context.Context, userID uint) (*TwoFactorEnrollResponse, error) {
+	if s.totpKey == nil {
+		return nil, errors.ServiceUnavailable(errors.ErrMsgTwoFactorNotConfigured)
+	}
+
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.NotFound(errors.ErrMsgUserNotFound)
+	}
+
+	existing, err := s.twoFactorRepo.FindTwoFactor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && existing.Enabled {
+		return nil, errors.Conflict(errors.ErrMsgTwoFactorAlreadyEnabled)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, errors.Internal(errors.ErrMsgFailedToSaveTwoFactor).Wrap(err)
+	}
+	encrypted, err := encryption.Encrypt(s.totpKey, secret)
+	if err != nil {
+		return nil, errors.Internal(errors.ErrMsgFailedToSaveTwoFactor).Wrap(err)
+	}
+
+	if err := s.twoFactorRepo.SaveTwoFactor(ctx, &TwoFactor{
+		UserID:          userID,
+		SecretEncrypted: encrypted,
+		CreatedAt:       time.Now(),
+	}); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, errors.Internal(errors.ErrMsgFailedToSaveRecoveryCodes).Wrap(err)
+	}
+	if err := s.twoFactorRepo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorEnrollResponse{
+		Secret:        secret,
+		OTPAuthURI:    totp.URI(config.AppConfig.TOTPIssuer, user.Email, secret),
+		RecoveryCodes: codes,
+	}, nil
+}
+
+// VerifyTwoFactor activates two-factor authentication once the user proves the
+// authenticator was set up by entering a current code.
+func (s *Service) VerifyTwoFactor(ctx context.Context, userID uint, code string) error {
+	twoFactor, err := s.twoFactorRepo.FindTwoFactor(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if twoFactor == nil {
+		return errors.BadRequest(errors.ErrMsgTwoFactorNotEnrolled)
+	}
+	if twoFactor.Enabled {
+		return errors.Conflict(errors.ErrMsgTwoFactorAlreadyEnabled)
+	}
+
+	step, ok, err := s.validateTOTP(twoFactor, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.BadRequest(errors.ErrMsgInvalidTwoFactorCode)
+	}
+
+	return s.twoFactorRepo.EnableTwoFactor(ctx, userID, step)
+}
+
+// CompleteTwoFactorLogin finishes a login that passed the password step by verifying
+// a TOTP code or consuming a recovery code. Failed codes count towards the account lockout.
+func (s *Service) CompleteTwoFactorLogin(ctx context.Context, req TwoFactorLoginRequest, userAgent, ipAddress string) (*LoginResponse, error) {
+	if req.Code == "" && req.RecoveryCode == "" {
+		return nil, errors.BadRequest(errors.ErrMsgTwoFactorCodeRequired)
+	}
+
+	userID, err := s.authService.ValidateMFAChallenge(ctx, req.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || !user.IsActive {
+		return nil, errors.Unauthorized(errors.ErrMsgInvalidMFAChallenge)
+	}
+
+	login := NormalizeLogin(user.Email)
+	locked, err := s.isLockedOut(ctx, login)
+	if err != nil {
+		return nil, err
+	}
+	if locked {
+		return nil, errors.Unauthorized(errors.ErrMsgInvalidCredentials)
+	}
+
+	ok, err := s.verifySecondFactor(ctx, userID, req.Code, req.RecoveryCode)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if err := s.recordLoginFailure(ctx, login, ipAddress); err != nil {
+			return nil, err
+		}
+		return nil, errors.Unauthorized(errors.ErrMsgInvalidTwoFactorCode)
+	}
+
+	return s.completeLogin(ctx, user, login, auth.ACRMFA, userAgent, ipAddress)
+}
+
+// isTwoFactorEnabled reports whether the user must present a second factor at login.
+func (s *Service) isTwoFactorEnabled(ctx context.Context, userID uint) (bool, error) {
+	twoFactor, err := s.twoFactorRepo.FindTwoFactor(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return twoFactor != nil && twoFactor.Enabled, nil
+}
+
+// verifySecondFactor checks a TOTP code, or a recovery code when no TOTP code is given.
+// An accepted TOTP code cannot be used again and a recovery code is consumed.
+func (s *Service) verifySecondFactor(ctx context.Context, userID uint, code, recoveryCode string) (bool, error) {
+	twoFactor, err := s.twoFactorRepo.FindTwoFactor(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if twoFactor == nil || !twoFactor.Enabled {
+		return false, nil
+	}
+
+	if code == "" {
+		return s.twoFactorRepo.UseRecoveryCode(ctx, userID, hash.HashToken(normalizeRecoveryCode(recoveryCode)))
+	}
+
+	step, ok, err := s.validateTOTP(twoFactor, code)
+	if err != nil || !ok {
+		return false, err
+	}
+	return s.twoFactorRepo.UseStep(ctx, userID, step)
+}
+
+// validateTOTP decrypts the authenticator's secret and checks the code within the configured skew.
+// Returns the time step the code belongs to.
+func (s *Service) validateTOTP(twoFactor *TwoFactor, code string) (int64, bool, error) {
+	if s.totpKey == nil {
+		return 0, false, errors.ServiceUnavailable(errors.ErrMsgTwoFactorNotConfigured)
+	}
+
+	secret, err := encryption.Decrypt(s.totpKey, twoFactor.SecretEncrypted)
+	if err != nil {
+		return 0, false, errors.Internal(errors.ErrMsgFailedToFindTwoFactor).Wrap(err)
+	}
+
+	step, ok := totp.Validate(secret, strings.TrimSpace(code), time.Now(), config.AppConfig.TOTPSkewSteps)
+	return step, ok, nil
+}
+
+// generateRecoveryCodes returns new recovery codes formatted for display and their hashes for storage.
+// Recovery codes are random, so a fast hash suffices to store them.
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		chars := make([]byte, recoveryCodeLength)
+		for j := range chars {
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(len(recoveryCodeAlphabet))))
+			if err != nil {
+				return nil, nil, err
+			}
+			chars[j] = recoveryCodeAlphabet[n.Int64()]
+		}
+		code := string(chars)
+
+		codes = append(codes, code[:recoveryCodeLength/2]+"-"+code[recoveryCodeLength/2:])
+		hashes = append(hashes, hash.HashToken(code))
+	}
+
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode strips the separator and spacing users may type, and ignores case.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
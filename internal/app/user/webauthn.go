@@ -0,0 +1,331 @@
+package user
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/app/auth"
+	"github.com/verigate/verigate-server/internal/pkg/config"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+)
+
+const (
+	webAuthnSessionTTL = 5 * time.Minute // How long a begun ceremony can be finished
+	defaultPasskeyName = "Passkey"       // Label for passkeys registered without a name
+)
+
+// webAuthnUser adapts a user and their passkeys to the webauthn.User interface.
+type webAuthnUser struct {
+	user        *User
+	credentials []*WebAuthnCredential
+}
+
+// WebAuthnID returns the user handle stored in discoverable credentials: the decimal user ID.
+func (u *webAuthnUser) WebAuthnID() []byte {
+	return []byte(strconv.FormatUint(uint64(u.user.ID), 10))
+}
+
+// WebAuthnName returns the account name authenticators show, the user's email.
+func (u *webAuthnUser) WebAuthnName() string {
+	return u.user.Email
+}
+
+// WebAuthnDisplayName returns the user's full name, or the username when none is set.
+func (u *webAuthnUser) WebAuthnDisplayName() string {
+	if u.user.FullName != nil && *u.user.FullName != "" {
+		return *u.user.FullName
+	}
+	return u.user.Username
+}
+
+// WebAuthnCredentials returns the user's passkeys in the form the library verifies against.
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(u.credentials))
+	for _, c := range u.credentials {
+		transports := make([]protocol.AuthenticatorTransport, 0, len(c.Transports))
+		for _, t := range c.Transports {
+			transports = append(transports, protocol.AuthenticatorTransport(t))
+		}
+
+		credentials = append(credentials, webauthn.Credential{
+			ID:              c.CredentialID,
+			PublicKey:       c.PublicKey,
+			AttestationType: c.AttestationType,
+			Transport:       transports,
+			Flags:           webauthn.NewCredentialFlags(protocol.AuthenticatorFlags(c.Flags)),
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    c.AAGUID,
+				SignCount: c.SignCount,
+			},
+		})
+	}
+	return credentials
+}
+
+// newWebAuthn configures the relying party from the application config.
+// It panics if the relying party ID or origins are invalid.
+func newWebAuthn() *webauthn.WebAuthn {
+	w, err := webauthn.New(&webauthn.Config{
+		RPID:          config.AppConfig.WebAuthnRPID,
+		RPDisplayName: config.AppConfig.WebAuthnRPDisplayName,
+		RPOrigins:     config.AppConfig.WebAuthnRPOrigins,
+	})
+	if err != nil {
+		panic("invalid WebAuthn configuration: " + err.Error())
+	}
+	return w
+}
+
+// BeginWebAuthnRegistration starts registering a passkey for the user.
+// Passkeys must be discoverable and verify the user, so they can sign in without a password,
+// and authenticators that already hold one of the user's passkeys are excluded.
+func (s *Service) BeginWebAuthnRegistration(ctx context.Context, userID uint) (*WebAuthnRegisterBeginResponse, error) {
+	u, err := s.loadWebAuthnUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, errors.NotFound(errors.ErrMsgUserNotFound)
+	}
+
+	requireResidentKey := true
+	creation, session, err := s.webAuthn.BeginRegistration(u,
+		webauthn.WithExclusions(webauthn.Credentials(u.WebAuthnCredentials()).CredentialDescriptors()),
+		webauthn.WithAuthenticatorSelection(protocol.AuthenticatorSelection{
+			RequireResidentKey: &requireResidentKey,
+			ResidentKey:        protocol.ResidentKeyRequirementRequired,
+			UserVerification:   protocol.VerificationRequired,
+		}),
+	)
+	if err != nil {
+		return nil, errors.Internal(errors.ErrMsgFailedToSaveWebAuthnSession).Wrap(err)
+	}
+
+	sessionID, err := s.saveWebAuthnSession(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebAuthnRegisterBeginResponse{SessionID: sessionID, Options: creation}, nil
+}
+
+// FinishWebAuthnRegistration verifies the authenticator's attestation against the
+// ceremony begun by the same user and stores the new passkey.
+func (s *Service) FinishWebAuthnRegistration(ctx context.Context, userID uint, req WebAuthnRegisterFinishRequest) (*WebAuthnCredentialResponse, error) {
+	session, err := s.takeWebAuthnSession(ctx, req.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(req.Credential)
+	if err != nil {
+		return nil, errors.BadRequest(errors.ErrMsgInvalidWebAuthnResponse)
+	}
+
+	u, err := s.loadWebAuthnUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, errors.NotFound(errors.ErrMsgUserNotFound)
+	}
+
+	created, err := s.webAuthn.CreateCredential(u, *session, parsed)
+	if err != nil {
+		return nil, errors.BadRequest(errors.ErrMsgWebAuthnVerificationFailed)
+	}
+
+	transports := make([]string, 0, len(created.Transport))
+	for _, t := range created.Transport {
+		transports = append(transports, string(t))
+	}
+
+	name := req.Name
+	if name == "" {
+		name = defaultPasskeyName
+	}
+
+	credential := &WebAuthnCredential{
+		UserID:          userID,
+		CredentialID:    created.ID,
+		PublicKey:       created.PublicKey,
+		AttestationType: created.AttestationType,
+		AAGUID:          created.Authenticator.AAGUID,
+		SignCount:       created.Authenticator.SignCount,
+		Flags:           byte(created.Flags.ProtocolValue()),
+		Transports:      transports,
+		Name:            name,
+		CreatedAt:       time.Now(),
+	}
+	if err := s.webAuthnRepo.SaveCredential(ctx, credential); err != nil {
+		return nil, err
+	}
+
+	return toWebAuthnCredentialResponse(credential), nil
+}
+
+// BeginWebAuthnLogin starts a passkey login. No account is named up front: the browser
+// offers the passkeys it holds for this relying party and the chosen one identifies the user.
+func (s *Service) BeginWebAuthnLogin(ctx context.Context) (*WebAuthnLoginBeginResponse, error) {
+	assertion, session, err := s.webAuthn.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		return nil, errors.Internal(errors.ErrMsgFailedToSaveWebAuthnSession).Wrap(err)
+	}
+
+	sessionID, err := s.saveWebAuthnSession(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebAuthnLoginBeginResponse{SessionID: sessionID, Options: assertion}, nil
+}
+
+// FinishWebAuthnLogin verifies the authenticator's assertion and issues a token pair.
+// A passkey that verifies the user counts as both factors, so two-factor authentication is not asked for.
+// An assertion whose signature counter did not increase past the stored one is rejected,
+// since it may come from a cloned authenticator.
+func (s *Service) FinishWebAuthnLogin(ctx context.Context, req WebAuthnLoginFinishRequest, userAgent, ipAddress string) (*LoginResponse, error) {
+	session, err := s.takeWebAuthnSession(ctx, req.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(req.Credential)
+	if err != nil {
+		return nil, errors.BadRequest(errors.ErrMsgInvalidWebAuthnResponse)
+	}
+
+	var u *webAuthnUser
+	findUser := func(rawID, userHandle []byte) (webauthn.User, error) {
+		id, err := strconv.ParseUint(string(userHandle), 10, 64)
+		if err != nil {
+			return nil, errors.Unauthorized(errors.ErrMsgWebAuthnCredentialNotFound)
+		}
+		u, err = s.loadWebAuthnUser(ctx, uint(id))
+		if err != nil {
+			return nil, err
+		}
+		if u == nil {
+			return nil, errors.Unauthorized(errors.ErrMsgWebAuthnCredentialNotFound)
+		}
+		return u, nil
+	}
+
+	_, credential, err := s.webAuthn.ValidatePasskeyLogin(findUser, *session, parsed)
+	if err != nil {
+		return nil, errors.Unauthorized(errors.ErrMsgWebAuthnVerificationFailed)
+	}
+	if credential.Authenticator.CloneWarning {
+		return nil, errors.Unauthorized(errors.ErrMsgWebAuthnCloneDetected)
+	}
+
+	// Concurrent logins reporting the same counter are caught here
+	updated, err := s.webAuthnRepo.UpdateSignCount(ctx, credential.ID, credential.Authenticator.SignCount)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, errors.Unauthorized(errors.ErrMsgWebAuthnCloneDetected)
+	}
+
+	if !u.user.IsActive {
+		return nil, errors.Unauthorized(errors.ErrMsgAccountNotActive)
+	}
+
+	return s.completeLogin(ctx, u.user, NormalizeLogin(u.user.Email), auth.ACRPasskey, userAgent, ipAddress)
+}
+
+// ListWebAuthnCredentials returns the passkeys registered by the user.
+func (s *Service) ListWebAuthnCredentials(ctx context.Context, userID uint) ([]*WebAuthnCredentialResponse, error) {
+	credentials, err := s.webAuthnRepo.FindCredentialsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]*WebAuthnCredentialResponse, 0, len(credentials))
+	for _, c := range credentials {
+		responses = append(responses, toWebAuthnCredentialResponse(c))
+	}
+	return responses, nil
+}
+
+// DeleteWebAuthnCredential revokes one of the user's passkeys by its base64url credential ID.
+func (s *Service) DeleteWebAuthnCredential(ctx context.Context, userID uint, credentialID string) error {
+	id, err := base64.RawURLEncoding.DecodeString(credentialID)
+	if err != nil || len(id) == 0 {
+		return errors.BadRequest(errors.ErrMsgInvalidWebAuthnCredentialID)
+	}
+
+	deleted, err := s.webAuthnRepo.DeleteCredential(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return errors.NotFound(errors.ErrMsgWebAuthnCredentialNotFound)
+	}
+	return nil
+}
+
+// loadWebAuthnUser retrieves the user and their passkeys. Returns nil if the user does not exist.
+func (s *Service) loadWebAuthnUser(ctx context.Context, userID uint) (*webAuthnUser, error) {
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		return nil, err
+	}
+
+	credentials, err := s.webAuthnRepo.FindCredentialsByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &webAuthnUser{user: user, credentials: credentials}, nil
+}
+
+// saveWebAuthnSession stores the ceremony state and returns the handle the client finishes it with.
+func (s *Service) saveWebAuthnSession(ctx context.Context, session *webauthn.SessionData) (string, error) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return "", errors.Internal(errors.ErrMsgFailedToSaveWebAuthnSession).Wrap(err)
+	}
+
+	handle := uuid.New().String()
+	if err := s.webAuthnSessionRepo.SaveSession(ctx, handle, data, webAuthnSessionTTL); err != nil {
+		return "", errors.Internal(errors.ErrMsgFailedToSaveWebAuthnSession).Wrap(err)
+	}
+	return handle, nil
+}
+
+// takeWebAuthnSession consumes the ceremony state, so each challenge is answered at most once.
+func (s *Service) takeWebAuthnSession(ctx context.Context, handle string) (*webauthn.SessionData, error) {
+	data, err := s.webAuthnSessionRepo.TakeSession(ctx, handle)
+	if err != nil {
+		return nil, errors.Internal(errors.ErrMsgFailedToSaveWebAuthnSession).Wrap(err)
+	}
+	if data == nil {
+		return nil, errors.BadRequest(errors.ErrMsgInvalidWebAuthnSession)
+	}
+
+	var session webauthn.SessionData
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, errors.BadRequest(errors.ErrMsgInvalidWebAuthnSession)
+	}
+	return &session, nil
+}
+
+// toWebAuthnCredentialResponse converts a passkey to its API representation.
+func toWebAuthnCredentialResponse(c *WebAuthnCredential) *WebAuthnCredentialResponse {
+	return &WebAuthnCredentialResponse{
+		ID:         base64.RawURLEncoding.EncodeToString(c.CredentialID),
+		Name:       c.Name,
+		Transports: c.Transports,
+		CreatedAt:  c.CreatedAt,
+		LastUsedAt: c.LastUsedAt,
+	}
+}
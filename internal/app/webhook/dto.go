@@ -0,0 +1,34 @@
+package webhook
+
+import "time"
+
+// CreateSubscriptionRequest represents the data needed to register a webhook endpoint.
+type CreateSubscriptionRequest struct {
+	URL        string   `json:"url" binding:"required"`               // Absolute http or https URL the events are POSTed to
+	EventTypes []string `json:"event_types" binding:"required,min=1"` // Event types to deliver, such as "token.issued"
+}
+
+// SubscriptionResponse describes a webhook subscription. The secret is only included when
+// the subscription is created; it cannot be retrieved later.
+type SubscriptionResponse struct {
+	ID         uint      `json:"id"`               // Subscription identifier
+	URL        string    `json:"url"`              // Endpoint the events are POSTed to
+	EventTypes []string  `json:"event_types"`      // Event types delivered to the endpoint
+	Secret     string    `json:"secret,omitempty"` // Key to verify payload signatures with
+	CreatedAt  time.Time `json:"created_at"`       // Creation timestamp
+}
+
+// SubscriptionListResponse lists webhook subscriptions, oldest first.
+type SubscriptionListResponse struct {
+	Subscriptions []SubscriptionResponse `json:"subscriptions"`
+}
+
+// DeadLetterQuery represents the filters for listing dead-lettered deliveries.
+type DeadLetterQuery struct {
+	Limit int `form:"limit"` // Maximum number of deliveries, a default when zero
+}
+
+// DeadLetterListResponse lists deliveries that failed every attempt, most recent first.
+type DeadLetterListResponse struct {
+	Deliveries []Delivery `json:"deliveries"`
+}
@@ -0,0 +1,60 @@
+// Package webhook notifies external systems of audit events, such as token issuance and
+// revocation, account lockouts, and client registrations. Subscribers register an endpoint URL
+// and the event types they want, and receive each event as a signed JSON POST. Deliveries are
+// queued and retried with exponential backoff until they succeed, so an event may arrive more
+// than once; deliveries that keep failing end up in a dead-letter list for operators.
+package webhook
+
+import (
+	"time"
+
+	"github.com/verigate/verigate-server/internal/app/audit"
+)
+
+// Request headers of a webhook delivery
+const (
+	HeaderSignature = "X-Verigate-Signature" // "sha256=" and the hex-encoded HMAC-SHA256 of the body under the subscription secret
+	HeaderEvent     = "X-Verigate-Event"     // Type of the event delivered
+	HeaderDelivery  = "X-Verigate-Delivery"  // ID of the delivery, the same on every attempt
+)
+
+// SignaturePrefix starts the value of the signature header
+const SignaturePrefix = "sha256="
+
+// Subscription registers an endpoint to receive events of the listed types.
+type Subscription struct {
+	ID         uint      `json:"id"`          // Primary key
+	URL        string    `json:"url"`         // Endpoint the events are POSTed to
+	Secret     string    `json:"-"`           // Key of the HMAC signing the payloads, never exposed after creation
+	EventTypes []string  `json:"event_types"` // Event types delivered to the endpoint
+	CreatedAt  time.Time `json:"created_at"`  // Creation timestamp
+}
+
+// Delivery is an event queued for delivery to one subscription.
+type Delivery struct {
+	ID             string      `json:"id"`                   // Unique identifier, sent as the payload ID so receivers can discard duplicates
+	SubscriptionID uint        `json:"subscription_id"`      // Subscription the event is delivered to
+	Event          audit.Event `json:"event"`                // Event being delivered
+	Attempts       int         `json:"attempts"`             // Delivery attempts made so far
+	LastError      string      `json:"last_error,omitempty"` // Error of the most recent failed attempt
+	CreatedAt      time.Time   `json:"created_at"`           // When the event was queued
+	NextAttemptAt  time.Time   `json:"next_attempt_at"`      // When the next attempt is due, or when the last one was made for dead letters
+}
+
+// Payload is the signed JSON body POSTed to a subscriber. The timestamp is refreshed on every
+// attempt and covered by the signature, so receivers can reject payloads replayed later.
+type Payload struct {
+	ID        string    `json:"id"`        // Delivery ID, the same on every attempt
+	Type      string    `json:"type"`      // Event type
+	Timestamp int64     `json:"timestamp"` // When the attempt was sent, in seconds since the Unix epoch
+	Data      EventData `json:"data"`      // The event
+}
+
+// EventData describes the event a payload reports.
+type EventData struct {
+	Actor      string            `json:"actor,omitempty"`     // Who acted, such as "user:42" or "client:my-client"
+	ClientID   string            `json:"client_id,omitempty"` // Client involved in the event
+	Outcome    string            `json:"outcome"`             // success or failure
+	Details    map[string]string `json:"details,omitempty"`   // Event-specific context
+	OccurredAt time.Time         `json:"occurred_at"`         // When the event happened
+}
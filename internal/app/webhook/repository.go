@@ -0,0 +1,46 @@
+package webhook
+
+import (
+	"context"
+	"time"
+)
+
+// Repository defines the interface for webhook subscription storage.
+type Repository interface {
+	// SaveSubscription persists a new subscription and sets its ID
+	SaveSubscription(ctx context.Context, subscription *Subscription) error
+
+	// FindSubscription retrieves a subscription by ID, or nil if it does not exist
+	FindSubscription(ctx context.Context, id uint) (*Subscription, error)
+
+	// ListSubscriptions retrieves every subscription, oldest first
+	ListSubscriptions(ctx context.Context) ([]Subscription, error)
+
+	// FindSubscriptionsByEventType retrieves the subscriptions to an event type
+	FindSubscriptionsByEventType(ctx context.Context, eventType string) ([]Subscription, error)
+
+	// DeleteSubscription removes a subscription. Returns NotFound if it does not exist.
+	DeleteSubscription(ctx context.Context, id uint) error
+}
+
+// QueueRepository holds the deliveries waiting to be sent and those that failed for good.
+type QueueRepository interface {
+	// Enqueue stores a delivery and schedules it for its NextAttemptAt,
+	// replacing the stored delivery and its schedule if it is already queued
+	Enqueue(ctx context.Context, delivery *Delivery) error
+
+	// ClaimDue returns up to limit deliveries due by now and hides them from further claims
+	// for lease. A delivery neither completed nor rescheduled within the lease, because its
+	// worker stopped, is claimed again, so every delivery is attempted at least once.
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*Delivery, error)
+
+	// Complete removes a delivery from the queue
+	Complete(ctx context.Context, id string) error
+
+	// DeadLetter removes a delivery from the queue and adds it to the dead-letter list,
+	// which keeps the limit most recent dead letters
+	DeadLetter(ctx context.Context, delivery *Delivery, limit int) error
+
+	// ListDeadLetters retrieves up to limit dead letters, most recent first
+	ListDeadLetters(ctx context.Context, limit int) ([]Delivery, error)
+}
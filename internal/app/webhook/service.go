@@ -0,0 +1,351 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/verigate/verigate-server/internal/app/audit"
+	"github.com/verigate/verigate-server/internal/pkg/config"
+	"github.com/verigate/verigate-server/internal/pkg/metrics"
+	"github.com/verigate/verigate-server/internal/pkg/tenant"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+	"github.com/verigate/verigate-server/internal/pkg/utils/opaque"
+)
+
+const (
+	pollInterval        = time.Second // How often workers look for due deliveries
+	claimBatchSize      = 16          // Deliveries a worker claims at once
+	defaultListLimit    = 50          // Dead letters listed when no limit is requested
+	maxListLimit        = 500         // Upper bound for dead letters listed at once
+	maxErrorBodyPreview = 1024        // Bytes of a subscriber's error response kept in last_error
+)
+
+// Delivery outcomes recorded by metrics.WebhookDeliveries
+const (
+	resultDelivered    = "delivered"     // The subscriber acknowledged the event
+	resultRetried      = "retried"       // The attempt failed and another one is scheduled
+	resultDeadLettered = "dead_lettered" // The last attempt failed
+	resultDropped      = "dropped"       // The event could not be queued or its subscription was deleted
+)
+
+// Service manages webhook subscriptions and delivers events to them. It is registered as a
+// listener of the audit service, queues a delivery for every subscription to each event, and
+// sends the queued deliveries from a pool of workers.
+type Service struct {
+	repo            Repository
+	queue           QueueRepository
+	tenants         []*tenant.Tenant
+	httpClient      *http.Client
+	workers         int
+	maxAttempts     int
+	baseDelay       time.Duration
+	maxDelay        time.Duration
+	lease           time.Duration // How long a claimed delivery is hidden from other workers
+	deadLetterLimit int
+}
+
+// NewService creates a new webhook service instance delivering the events of the default
+// tenant and the given tenants. Deliveries are only sent once Start has launched the workers.
+func NewService(repo Repository, queue QueueRepository, tenants []*tenant.Tenant) *Service {
+	timeout, err := time.ParseDuration(config.AppConfig.WebhookTimeout)
+	if err != nil {
+		panic("invalid webhook timeout: " + err.Error())
+	}
+	baseDelay, err := time.ParseDuration(config.AppConfig.WebhookRetryBaseDelay)
+	if err != nil || baseDelay <= 0 {
+		panic("invalid webhook retry base delay: " + config.AppConfig.WebhookRetryBaseDelay)
+	}
+	maxDelay, err := time.ParseDuration(config.AppConfig.WebhookRetryMaxDelay)
+	if err != nil || maxDelay < baseDelay {
+		panic("invalid webhook retry max delay: " + config.AppConfig.WebhookRetryMaxDelay)
+	}
+
+	return &Service{
+		repo:            repo,
+		queue:           queue,
+		tenants:         tenants,
+		httpClient:      &http.Client{Timeout: timeout},
+		workers:         max(config.AppConfig.WebhookWorkers, 1),
+		maxAttempts:     max(config.AppConfig.WebhookMaxAttempts, 1),
+		baseDelay:       baseDelay,
+		maxDelay:        maxDelay,
+		lease:           claimBatchSize*timeout + time.Minute,
+		deadLetterLimit: max(config.AppConfig.WebhookDeadLetterLimit, 1),
+	}
+}
+
+// Start launches the delivery workers. They stop when ctx is cancelled; deliveries
+// claimed at that point are claimed again once their lease has passed.
+func (s *Service) Start(ctx context.Context) {
+	for i := 0; i < s.workers; i++ {
+		go s.work(ctx)
+	}
+}
+
+// Notify queues a delivery of the event for every subscription to its type. It implements
+// audit.Listener, so it runs on the audit worker rather than the request path. Events that
+// cannot be queued are counted and dropped.
+func (s *Service) Notify(ctx context.Context, event audit.Event) {
+	subscriptions, err := s.repo.FindSubscriptionsByEventType(ctx, event.Type)
+	if err != nil {
+		metrics.WebhookDeliveries.WithLabelValues(resultDropped).Inc()
+		return
+	}
+
+	now := time.Now()
+	for _, subscription := range subscriptions {
+		delivery := &Delivery{
+			ID:             uuid.NewString(),
+			SubscriptionID: subscription.ID,
+			Event:          event,
+			CreatedAt:      now,
+			NextAttemptAt:  now,
+		}
+		if err := s.queue.Enqueue(ctx, delivery); err != nil {
+			metrics.WebhookDeliveries.WithLabelValues(resultDropped).Inc()
+		}
+	}
+}
+
+// CreateSubscription registers an endpoint for the requested event types and returns the
+// subscription with the secret its payloads are signed with, which is not shown again.
+func (s *Service) CreateSubscription(ctx context.Context, req CreateSubscriptionRequest) (*SubscriptionResponse, error) {
+	u, err := url.Parse(req.URL)
+	if err != nil || u.Host == "" || u.Fragment != "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return nil, errors.BadRequest(errors.ErrMsgInvalidWebhookURL)
+	}
+
+	var eventTypes []string
+	for _, eventType := range req.EventTypes {
+		if !audit.IsValidEventType(eventType) {
+			return nil, errors.BadRequest(errors.ErrMsgInvalidWebhookEventType).WithDetails(eventType)
+		}
+		if !contains(eventTypes, eventType) {
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+
+	secret, err := opaque.Random()
+	if err != nil {
+		return nil, errors.Internal(errors.ErrMsgFailedToGenerateWebhookSecret)
+	}
+
+	subscription := &Subscription{
+		URL:        req.URL,
+		Secret:     secret,
+		EventTypes: eventTypes,
+		CreatedAt:  time.Now(),
+	}
+	if err := s.repo.SaveSubscription(ctx, subscription); err != nil {
+		return nil, err
+	}
+
+	resp := toResponse(subscription)
+	resp.Secret = secret
+	return &resp, nil
+}
+
+// ListSubscriptions returns every webhook subscription, oldest first, without their secrets.
+func (s *Service) ListSubscriptions(ctx context.Context) (*SubscriptionListResponse, error) {
+	subscriptions, err := s.repo.ListSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]SubscriptionResponse, 0, len(subscriptions))
+	for i := range subscriptions {
+		responses = append(responses, toResponse(&subscriptions[i]))
+	}
+	return &SubscriptionListResponse{Subscriptions: responses}, nil
+}
+
+// DeleteSubscription removes a webhook subscription. Deliveries already queued for it are
+// dropped when they come up. Returns NotFound if the subscription does not exist.
+func (s *Service) DeleteSubscription(ctx context.Context, id uint) error {
+	return s.repo.DeleteSubscription(ctx, id)
+}
+
+// ListDeadLetters returns the most recent deliveries that failed every attempt.
+func (s *Service) ListDeadLetters(ctx context.Context, query DeadLetterQuery) (*DeadLetterListResponse, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	if limit > maxListLimit {
+		limit = maxListLimit
+	}
+
+	deliveries, err := s.queue.ListDeadLetters(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	if deliveries == nil {
+		deliveries = []Delivery{}
+	}
+
+	return &DeadLetterListResponse{Deliveries: deliveries}, nil
+}
+
+// work sends due deliveries of every tenant until ctx is cancelled.
+func (s *Service) work(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.deliverDue(ctx)
+			for _, t := range s.tenants {
+				s.deliverDue(tenant.WithTenant(ctx, t))
+			}
+		}
+	}
+}
+
+// deliverDue claims the deliveries due in the tenant ctx is served for and sends them.
+func (s *Service) deliverDue(ctx context.Context) {
+	deliveries, err := s.queue.ClaimDue(ctx, time.Now(), s.lease, claimBatchSize)
+	if err != nil {
+		// Redis is unavailable; the deliveries are claimed on a later poll
+		return
+	}
+
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			return
+		}
+		s.deliver(ctx, delivery)
+	}
+}
+
+// deliver makes one attempt to send a delivery. A failed attempt is rescheduled with
+// exponential backoff, or moved to the dead-letter list once every attempt has failed.
+func (s *Service) deliver(ctx context.Context, delivery *Delivery) {
+	// Outcomes are recorded even when ctx is cancelled during the attempt
+	recordCtx := tenant.Detach(ctx)
+
+	subscription, err := s.repo.FindSubscription(ctx, delivery.SubscriptionID)
+	if err == nil && subscription == nil {
+		metrics.WebhookDeliveries.WithLabelValues(resultDropped).Inc()
+		s.queue.Complete(recordCtx, delivery.ID)
+		return
+	}
+	if err == nil {
+		err = s.send(ctx, subscription, delivery)
+	}
+	if err == nil {
+		metrics.WebhookDeliveries.WithLabelValues(resultDelivered).Inc()
+		s.queue.Complete(recordCtx, delivery.ID)
+		return
+	}
+	if ctx.Err() != nil {
+		// Shutting down, the delivery is claimed again once its lease has passed
+		return
+	}
+
+	delivery.Attempts++
+	delivery.LastError = err.Error()
+	delivery.NextAttemptAt = time.Now()
+	if delivery.Attempts >= s.maxAttempts {
+		metrics.WebhookDeliveries.WithLabelValues(resultDeadLettered).Inc()
+		s.queue.DeadLetter(recordCtx, delivery, s.deadLetterLimit)
+		return
+	}
+
+	metrics.WebhookDeliveries.WithLabelValues(resultRetried).Inc()
+	delivery.NextAttemptAt = delivery.NextAttemptAt.Add(s.retryDelay(delivery.Attempts))
+	s.queue.Enqueue(recordCtx, delivery)
+}
+
+// retryDelay returns the delay before the attempt following the given number of failed
+// ones: the base delay, doubled for every further failure, at most the maximum delay.
+func (s *Service) retryDelay(failures int) time.Duration {
+	delay := s.baseDelay
+	for i := 1; i < failures && delay < s.maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, s.maxDelay)
+}
+
+// send POSTs the signed payload of a delivery to the subscription's URL.
+// Any 2xx response counts as delivered.
+func (s *Service) send(ctx context.Context, subscription *Subscription, delivery *Delivery) error {
+	body, err := json.Marshal(Payload{
+		ID:        delivery.ID,
+		Type:      delivery.Event.Type,
+		Timestamp: time.Now().Unix(),
+		Data: EventData{
+			Actor:      delivery.Event.Actor,
+			ClientID:   delivery.Event.ClientID,
+			Outcome:    delivery.Event.Outcome,
+			Details:    delivery.Event.Details,
+			OccurredAt: delivery.Event.CreatedAt,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderSignature, Sign(subscription.Secret, body))
+	req.Header.Set(HeaderEvent, delivery.Event.Type)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		preview, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyPreview))
+		return fmt.Errorf("subscriber responded with status %d: %s", resp.StatusCode, strings.TrimSpace(string(preview)))
+	}
+
+	return nil
+}
+
+// Sign returns the signature header value of a payload: SignaturePrefix followed by the
+// hex-encoded HMAC-SHA256 of the body under the subscription secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return SignaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// toResponse converts a subscription to its response, without the secret.
+func toResponse(subscription *Subscription) SubscriptionResponse {
+	return SubscriptionResponse{
+		ID:         subscription.ID,
+		URL:        subscription.URL,
+		EventTypes: subscription.EventTypes,
+		CreatedAt:  subscription.CreatedAt,
+	}
+}
+
+// contains reports whether value is present in values.
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
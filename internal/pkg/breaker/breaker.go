@@ -0,0 +1,138 @@
+// Package breaker provides a circuit breaker that stops calls to a failing dependency,
+// so callers fail fast and apply their fallbacks instead of waiting on timeouts.
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned for calls the breaker rejects while it is open
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State is the state of a circuit breaker. The values are exported as a metric, so they are stable.
+type State int
+
+// Circuit breaker states
+const (
+	StateClosed   State = 0 // Calls pass through and failures are counted
+	StateHalfOpen State = 1 // A single trial call decides whether to close or reopen
+	StateOpen     State = 2 // Calls are rejected until the cooldown has passed
+)
+
+// String returns the name of the state.
+func (s State) String() string {
+	switch s {
+	case StateHalfOpen:
+		return "half_open"
+	case StateOpen:
+		return "open"
+	}
+	return "closed"
+}
+
+// Breaker trips open after a number of consecutive failed calls and rejects calls until its
+// cooldown has passed. It then lets one trial call through: a success closes it again and a
+// failure reopens it for another cooldown. A nil Breaker lets every call through.
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+	onChange  func(State)
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probing  bool // Whether the trial call of the half-open state is in flight
+}
+
+// New creates a closed breaker that opens after threshold consecutive failures and stays open
+// for cooldown. onChange, if not nil, is called with the new state on every transition,
+// with the breaker locked, so it must not call back into the breaker.
+func New(threshold int, cooldown time.Duration, onChange func(State)) *Breaker {
+	return &Breaker{threshold: threshold, cooldown: cooldown, onChange: onChange}
+}
+
+// Allow reports whether a call may proceed, returning ErrOpen if the breaker rejects it.
+// Every allowed call must be followed by Record with its outcome.
+func (b *Breaker) Allow() error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return ErrOpen
+		}
+		b.setState(StateHalfOpen)
+		b.probing = true
+	case StateHalfOpen:
+		if b.probing {
+			return ErrOpen
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// Record reports the outcome of a call Allow let through. A success resets the failure count
+// and closes a half-open breaker; a failure counts towards the threshold and reopens a half-open one.
+// Outcomes of calls that were in flight when the breaker opened are ignored.
+func (b *Breaker) Record(failed bool) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateClosed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.threshold {
+			b.open()
+		}
+	case StateHalfOpen:
+		b.probing = false
+		if failed {
+			b.open()
+			return
+		}
+		b.failures = 0
+		b.setState(StateClosed)
+	}
+}
+
+// State returns the current state of the breaker.
+func (b *Breaker) State() State {
+	if b == nil {
+		return StateClosed
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// open trips the breaker, starting a new cooldown.
+func (b *Breaker) open() {
+	b.openedAt = time.Now()
+	b.setState(StateOpen)
+}
+
+// setState moves the breaker to state and reports the transition.
+func (b *Breaker) setState(state State) {
+	b.state = state
+	if b.onChange != nil {
+		b.onChange(state)
+	}
+}
@@ -3,32 +3,180 @@
 package config
 
 import (
+	"net"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Config holds all configuration values for the application.
 // Most values are loaded from environment variables with sensible defaults.
 type Config struct {
 	AppPort                    string
+	TLSCertFile                string
+	TLSKeyFile                 string
+	ShutdownTimeout            string
+	ReadinessTimeout           string
+	ConcurrencyLimit           int
+	TokenConcurrencyLimit      int
+	ConcurrencyRetryAfter      string
+	MaxFormBodyBytes           int64
+	MaxJSONBodyBytes           int64
+	MaxParamValues             int
+	MaxParamLength             int
+	AppBaseURL                 string
+	LoginURL                   string
+	DefaultLocale              string
+	TokenIssuer                string
+	Tenants                    []string
+	TenantHosts                map[string]string
+	TenantKeysDir              string
+	EnabledGrantTypes          []string
+	RedirectURILoopbackAnyPort bool
+	AccessTokenFormat          string
+	ClientJWKSCacheTTL         string
+	PushedRequestTTL           string
+	DPoPProofMaxAge            string
+	DPoPNonceKey               string
+	DPoPNonceLifetime          string
+	BackchannelLogoutWorkers   int
+	BackchannelLogoutAttempts  int
+	BackchannelLogoutTimeout   string
+	EndSessionScope            string
+	WebhookWorkers             int
+	WebhookMaxAttempts         int
+	WebhookTimeout             string
+	WebhookRetryBaseDelay      string
+	WebhookRetryMaxDelay       string
+	WebhookDeadLetterLimit     int
+	CleanupInterval            string
+	CleanupRetention           string
+	CleanupBatchSize           int
+	AuditStore                 string
+	TokenStore                 string
+	ClientStore                string
+	RateLimitStore             string
+	AuditFilePath              string
+	AuditQueueSize             int
+	TokenBindingIPv4Prefix     int
+	TokenBindingIPv6Prefix     int
+	LoginLockoutThreshold      int
+	LoginLockoutDuration       string
+	LoginLockoutMaxDuration    string
+	LoginLockoutMultiplier     float64
+	LoginLockoutResetAfter     string
+	CaptchaProvider            string
+	CaptchaSiteKey             string
+	CaptchaSecret              string
+	CaptchaVerifyURL           string
+	CaptchaTimeout             string
+	CaptchaIPThreshold         int
+	CaptchaLoginThreshold      int
+	CaptchaIPWindow            string
+	PasswordMinLength          int
+	PasswordRequireUppercase   bool
+	PasswordRequireLowercase   bool
+	PasswordRequireDigit       bool
+	PasswordRequireSymbol      bool
+	PasswordBreachCheck        bool
+	PasswordBreachCheckURL     string
+	PasswordBreachCheckTimeout string
+	TOTPEncryptionKey          string
+	ClientSecretEncryptionKey  string
+	TOTPIssuer                 string
+	TOTPSkewSteps              int
+	WebAuthnRPID               string
+	WebAuthnRPDisplayName      string
+	WebAuthnRPOrigins          []string
+	PairwiseSubjectSalt        string
+	CustomClaimPrefix          string
+	PasswordHashAlgorithm      string
+	BcryptCost                 int
+	Argon2Time                 int
+	Argon2MemoryKiB            int
+	Argon2Threads              int
 	Environment                string
+	JWTKeyProvider             string
 	JWTPrivateKey              string
 	JWTPublicKey               string
+	JWTECPrivateKey            string
+	JWTPrivateKeyFile          string
+	JWTPublicKeyFile           string
+	JWTECPrivateKeyFile        string
+	VaultAddr                  string
+	VaultToken                 string
+	VaultTransitMount          string
+	VaultTransitRSAKey         string
+	VaultTransitECKey          string
+	VaultKeyCacheTTL           string
+	VaultTimeout               string
 	JWTAccessExpiry            string
 	JWTRefreshExpiry           string
+	JWTIDTokenExpiry           string
+	RefreshTokenIdleTTL        string
+	RefreshTokenAbsoluteTTL    string
+	GrantAccessTokenExpiry     map[string]string
+	GrantRefreshTokenExpiry    map[string]string
+	GrantIDTokenExpiry         map[string]string
+	AcceptUnprefixedTokens     bool
+	TokenEntropyBytes          int
+	JWTKeyRotationInterval     string
+	JWTClockSkew               string
+	SessionIdleTimeout         string
+	SessionAbsoluteTimeout     string
+	SessionCookieName          string
+	MaxSessionsPerUser         int
+	SessionLimitPolicy         string
+	CSRFSecret                 string
 	PostgresHost               string
 	PostgresPort               string
 	PostgresDB                 string
 	PostgresUser               string
 	PostgresPassword           string
+	PostgresReplicaHost        string
+	PostgresReplicaPort        string
+	PostgresReplicaMaxLag      string
+	PostgresReplicaLagInterval string
 	RedisHost                  string
 	RedisPort                  string
 	RedisPassword              string
 	RedisDB                    string
+	RedisMaxRetries            int
+	RedisMinRetryBackoff       string
+	RedisMaxRetryBackoff       string
+	RedisBreakerThreshold      int
+	RedisBreakerCooldown       string
 	RateLimitRequestsPerMinute int
+	RateLimitClientTiers       map[string]string
+	RateLimitRoutes            map[string]string
+	RateLimitKeys              map[string]string
+	ACRRequirements            map[string][]string
+	RateLimitFailClosed        bool
+	RateLimitAlgorithm         string
+	RateLimitRefillRate        float64
+	RateLimitBurstCapacity     int
+	RateLimitAllowlist         []string
+	RateLimitDenylist          []string
+	TrustedProxies             []string
+	MTLSClientCertHeader       string
+	MTLSClientCAFile           string
 	IPWhitelist                []string
+	CORSAllowedOrigins         []string
+	CORSAllowCredentials       bool
+	SecurityCSP                string
+	SecurityFrameOptions       string
+	SecurityReferrerPolicy     string
+	SecurityContentTypeOptions string
+	SecurityHSTSMaxAge         string
+	SecurityHSTSSubdomains     bool
 	IPBlacklist                []string
+	AdminUserIDs               []uint
+	AdminClientIDs             []string
+	AdminStepUpACRValues       []string
+	AdminStepUpMaxAge          string
+	ClientSecretMaxGracePeriod string
+	DebugTokenEndpoint         bool
 }
 
 // AppConfig is the global configuration instance for the application.
@@ -40,33 +188,426 @@ var AppConfig Config
 // are missing will cause the application to panic.
 func Load() {
 	AppConfig = Config{
-		AppPort:          getEnv("APP_PORT", "8080"),
-		Environment:      getEnv("ENVIRONMENT", "development"),
-		JWTPrivateKey:    mustGetEnv("JWT_PRIVATE_KEY"),
-		JWTPublicKey:     mustGetEnv("JWT_PUBLIC_KEY"),
-		JWTAccessExpiry:  getEnv("JWT_ACCESS_EXPIRY", "15m"),
-		JWTRefreshExpiry: getEnv("JWT_REFRESH_EXPIRY", "168h"),
-		PostgresHost:     getEnv("POSTGRES_HOST", "localhost"),
-		PostgresPort:     getEnv("POSTGRES_PORT", "5432"),
-		PostgresDB:       getEnv("POSTGRES_DB", "oauth_server"),
-		PostgresUser:     getEnv("POSTGRES_USER", "postgres"),
-		PostgresPassword: mustGetEnv("POSTGRES_PASSWORD"),
-		RedisHost:        getEnv("REDIS_HOST", "localhost"),
-		RedisPort:        getEnv("REDIS_PORT", "6379"),
-		RedisPassword:    getEnv("REDIS_PASSWORD", ""),
-		RedisDB:          getEnv("REDIS_DB", "0"),
+		AppPort:                    getEnv("APP_PORT", "8080"),
+		TLSCertFile:                getEnv("TLS_CERT_FILE", ""),
+		TLSKeyFile:                 getEnv("TLS_KEY_FILE", ""),
+		ShutdownTimeout:            getEnv("SHUTDOWN_TIMEOUT", "30s"),
+		ReadinessTimeout:           getEnv("READINESS_TIMEOUT", "2s"),
+		ConcurrencyRetryAfter:      getEnv("CONCURRENCY_RETRY_AFTER", "1s"),
+		AppBaseURL:                 getEnv("APP_BASE_URL", "http://localhost:8080"),
+		Environment:                getEnv("ENVIRONMENT", "development"),
+		JWTKeyProvider:             getEnv("JWT_KEY_PROVIDER", "local"),
+		JWTPrivateKey:              getEnv("JWT_PRIVATE_KEY", ""),
+		JWTPublicKey:               getEnv("JWT_PUBLIC_KEY", ""),
+		JWTECPrivateKey:            getEnv("JWT_EC_PRIVATE_KEY", ""),
+		JWTPrivateKeyFile:          getEnv("JWT_PRIVATE_KEY_FILE", ""),
+		JWTPublicKeyFile:           getEnv("JWT_PUBLIC_KEY_FILE", ""),
+		JWTECPrivateKeyFile:        getEnv("JWT_EC_PRIVATE_KEY_FILE", ""),
+		VaultAddr:                  getEnv("VAULT_ADDR", ""),
+		VaultToken:                 getEnv("VAULT_TOKEN", ""),
+		VaultTransitMount:          getEnv("VAULT_TRANSIT_MOUNT", "transit"),
+		VaultTransitRSAKey:         getEnv("VAULT_TRANSIT_RSA_KEY", ""),
+		VaultTransitECKey:          getEnv("VAULT_TRANSIT_EC_KEY", ""),
+		VaultKeyCacheTTL:           getEnv("VAULT_KEY_CACHE_TTL", "5m"),
+		VaultTimeout:               getEnv("VAULT_TIMEOUT", "5s"),
+		JWTAccessExpiry:            getEnv("JWT_ACCESS_EXPIRY", "15m"),
+		JWTRefreshExpiry:           getEnv("JWT_REFRESH_EXPIRY", "168h"),
+		JWTKeyRotationInterval:     getEnv("JWT_KEY_ROTATION_INTERVAL", "0"),
+		JWTClockSkew:               getEnv("JWT_CLOCK_SKEW", "60s"),
+		SessionIdleTimeout:         getEnv("SESSION_IDLE_TIMEOUT", "30m"),
+		SessionAbsoluteTimeout:     getEnv("SESSION_ABSOLUTE_TIMEOUT", "12h"),
+		SessionCookieName:          getEnv("SESSION_COOKIE_NAME", "verigate_session"),
+		CSRFSecret:                 getEnv("CSRF_SECRET", ""),
+		AccessTokenFormat:          getEnv("ACCESS_TOKEN_FORMAT", "legacy"),
+		ClientJWKSCacheTTL:         getEnv("CLIENT_JWKS_CACHE_TTL", "5m"),
+		PushedRequestTTL:           getEnv("PUSHED_REQUEST_TTL", "60s"),
+		DPoPProofMaxAge:            getEnv("DPOP_PROOF_MAX_AGE", "60s"),
+		DPoPNonceKey:               getEnv("DPOP_NONCE_KEY", ""),
+		DPoPNonceLifetime:          getEnv("DPOP_NONCE_LIFETIME", "5m"),
+		DefaultLocale:              getEnv("DEFAULT_LOCALE", "en"),
+		BackchannelLogoutTimeout:   getEnv("BACKCHANNEL_LOGOUT_TIMEOUT", "5s"),
+		WebhookTimeout:             getEnv("WEBHOOK_TIMEOUT", "5s"),
+		WebhookRetryBaseDelay:      getEnv("WEBHOOK_RETRY_BASE_DELAY", "10s"),
+		WebhookRetryMaxDelay:       getEnv("WEBHOOK_RETRY_MAX_DELAY", "1h"),
+		CleanupInterval:            getEnv("CLEANUP_INTERVAL", "1h"),
+		CleanupRetention:           getEnv("CLEANUP_RETENTION", "24h"),
+		AuditFilePath:              getEnv("AUDIT_FILE_PATH", "audit.log"),
+		LoginLockoutDuration:       getEnv("LOGIN_LOCKOUT_DURATION", "15m"),
+		LoginLockoutMaxDuration:    getEnv("LOGIN_LOCKOUT_MAX_DURATION", "24h"),
+		LoginLockoutResetAfter:     getEnv("LOGIN_LOCKOUT_RESET_AFTER", "24h"),
+		CaptchaProvider:            getEnv("CAPTCHA_PROVIDER", "none"),
+		CaptchaSiteKey:             getEnv("CAPTCHA_SITE_KEY", ""),
+		CaptchaSecret:              getEnv("CAPTCHA_SECRET", ""),
+		CaptchaVerifyURL:           getEnv("CAPTCHA_VERIFY_URL", ""),
+		CaptchaTimeout:             getEnv("CAPTCHA_TIMEOUT", "3s"),
+		CaptchaIPWindow:            getEnv("CAPTCHA_IP_WINDOW", "1h"),
+		PasswordBreachCheckURL:     getEnv("PASSWORD_BREACH_CHECK_URL", "https://api.pwnedpasswords.com/range/"),
+		PasswordBreachCheckTimeout: getEnv("PASSWORD_BREACH_CHECK_TIMEOUT", "3s"),
+		TOTPEncryptionKey:          getEnv("TOTP_ENCRYPTION_KEY", ""),
+		ClientSecretEncryptionKey:  getEnv("CLIENT_SECRET_ENCRYPTION_KEY", ""),
+		TOTPIssuer:                 getEnv("TOTP_ISSUER", "Verigate"),
+		WebAuthnRPID:               getEnv("WEBAUTHN_RP_ID", "localhost"),
+		WebAuthnRPDisplayName:      getEnv("WEBAUTHN_RP_DISPLAY_NAME", "Verigate"),
+		PairwiseSubjectSalt:        getEnv("PAIRWISE_SUBJECT_SALT", ""),
+		CustomClaimPrefix:          getEnv("CUSTOM_CLAIM_PREFIX", "ext_"),
+		PostgresHost:               getEnv("POSTGRES_HOST", "localhost"),
+		PostgresPort:               getEnv("POSTGRES_PORT", "5432"),
+		PostgresDB:                 getEnv("POSTGRES_DB", "oauth_server"),
+		PostgresUser:               getEnv("POSTGRES_USER", "postgres"),
+		PostgresPassword:           mustGetEnv("POSTGRES_PASSWORD"),
+		PostgresReplicaHost:        getEnv("POSTGRES_REPLICA_HOST", ""),
+		PostgresReplicaPort:        getEnv("POSTGRES_REPLICA_PORT", getEnv("POSTGRES_PORT", "5432")),
+		PostgresReplicaMaxLag:      getEnv("POSTGRES_REPLICA_MAX_LAG", "5s"),
+		PostgresReplicaLagInterval: getEnv("POSTGRES_REPLICA_LAG_CHECK_INTERVAL", "5s"),
+		RedisHost:                  getEnv("REDIS_HOST", "localhost"),
+		RedisPort:                  getEnv("REDIS_PORT", "6379"),
+		RedisPassword:              getEnv("REDIS_PASSWORD", ""),
+		RedisDB:                    getEnv("REDIS_DB", "0"),
+		RedisMinRetryBackoff:       getEnv("REDIS_MIN_RETRY_BACKOFF", "8ms"),
+		RedisMaxRetryBackoff:       getEnv("REDIS_MAX_RETRY_BACKOFF", "512ms"),
+		RedisBreakerCooldown:       getEnv("REDIS_BREAKER_COOLDOWN", "10s"),
 	}
 
+	// Parse token lifetimes; ID tokens live as long as access tokens unless configured otherwise
+	AppConfig.JWTIDTokenExpiry = getEnv("JWT_ID_TOKEN_EXPIRY", AppConfig.JWTAccessExpiry)
+	AppConfig.RefreshTokenIdleTTL = getEnv("REFRESH_TOKEN_IDLE_TTL", AppConfig.JWTRefreshExpiry)
+	AppConfig.RefreshTokenAbsoluteTTL = getEnv("REFRESH_TOKEN_ABSOLUTE_TTL", "")
+	AppConfig.GrantAccessTokenExpiry = parseGrantDurations(getEnv("GRANT_ACCESS_TOKEN_EXPIRY", ""))
+	AppConfig.GrantRefreshTokenExpiry = parseGrantDurations(getEnv("GRANT_REFRESH_TOKEN_EXPIRY", ""))
+	AppConfig.GrantIDTokenExpiry = parseGrantDurations(getEnv("GRANT_ID_TOKEN_EXPIRY", ""))
+	validateTokenLifetimes()
+	AppConfig.AcceptUnprefixedTokens = getEnvBool("ACCEPT_UNPREFIXED_TOKENS", true)
+	AppConfig.TokenEntropyBytes = parseTokenEntropy(getEnv("TOKEN_ENTROPY_BYTES", "32"))
+
+	// Parse the authentication context classes relying parties may request with acr_values
+	AppConfig.ACRRequirements = parseACRRequirements(getEnv("ACR_REQUIREMENTS", "pwd=pwd|mfa|hwk,mfa=mfa|hwk,hwk=hwk"))
+
+	// Parse Redis resilience settings; zero retries or a zero threshold disables them
+	redisRetries, err := strconv.Atoi(getEnv("REDIS_MAX_RETRIES", "3"))
+	if err != nil || redisRetries < 0 {
+		redisRetries = 3
+	}
+	AppConfig.RedisMaxRetries = redisRetries
+
+	breakerThreshold, err := strconv.Atoi(getEnv("REDIS_BREAKER_THRESHOLD", "5"))
+	if err != nil || breakerThreshold < 0 {
+		breakerThreshold = 5
+	}
+	AppConfig.RedisBreakerThreshold = breakerThreshold
+
+	// Parse the concurrency limits; zero leaves the number of requests in flight unlimited
+	concurrencyLimit, err := strconv.Atoi(getEnv("CONCURRENCY_LIMIT", "0"))
+	if err != nil || concurrencyLimit < 0 {
+		concurrencyLimit = 0
+	}
+	AppConfig.ConcurrencyLimit = concurrencyLimit
+
+	tokenConcurrencyLimit, err := strconv.Atoi(getEnv("TOKEN_CONCURRENCY_LIMIT", "0"))
+	if err != nil || tokenConcurrencyLimit < 0 {
+		tokenConcurrencyLimit = 0
+	}
+	AppConfig.TokenConcurrencyLimit = tokenConcurrencyLimit
+
+	// Parse the request limits of the OAuth endpoints; zero leaves a limit unbounded
+	maxFormBody, err := strconv.ParseInt(getEnv("MAX_FORM_BODY_BYTES", "65536"), 10, 64)
+	if err != nil || maxFormBody < 0 {
+		maxFormBody = 65536
+	}
+	AppConfig.MaxFormBodyBytes = maxFormBody
+
+	maxJSONBody, err := strconv.ParseInt(getEnv("MAX_JSON_BODY_BYTES", "65536"), 10, 64)
+	if err != nil || maxJSONBody < 0 {
+		maxJSONBody = 65536
+	}
+	AppConfig.MaxJSONBodyBytes = maxJSONBody
+
+	maxParamValues, err := strconv.Atoi(getEnv("MAX_PARAM_VALUES", "20"))
+	if err != nil || maxParamValues < 0 {
+		maxParamValues = 20
+	}
+	AppConfig.MaxParamValues = maxParamValues
+
+	maxParamLength, err := strconv.Atoi(getEnv("MAX_PARAM_LENGTH", "8192"))
+	if err != nil || maxParamLength < 0 {
+		maxParamLength = 8192
+	}
+	AppConfig.MaxParamLength = maxParamLength
+
 	// Parse rate limit
 	rateLimit, err := strconv.Atoi(getEnv("RATE_LIMIT_REQUESTS_PER_MINUTE", "60"))
 	if err != nil {
 		rateLimit = 60
 	}
 	AppConfig.RateLimitRequestsPerMinute = rateLimit
+	AppConfig.RateLimitClientTiers = parseRateLimitTiers(getEnv("RATE_LIMIT_CLIENT_TIERS", ""))
+	AppConfig.RateLimitRoutes = parseRateLimitRoutes(getEnv("RATE_LIMIT_ROUTES", "login=10/1m,token=30/1m,discovery=300/1m"))
+	AppConfig.RateLimitKeys = parseRateLimitKeys(getEnv("RATE_LIMIT_KEYS", ""))
+
+	failClosed, err := strconv.ParseBool(getEnv("RATE_LIMIT_FAIL_CLOSED", "false"))
+	if err != nil {
+		failClosed = false
+	}
+	AppConfig.RateLimitFailClosed = failClosed
+	AppConfig.RateLimitAlgorithm = getEnv("RATE_LIMIT_ALGORITHM", "sliding")
+
+	refillRate, err := strconv.ParseFloat(getEnv("RATE_LIMIT_REFILL_RATE", "0"), 64)
+	if err != nil {
+		refillRate = 0
+	}
+	AppConfig.RateLimitRefillRate = refillRate
+
+	burstCapacity, err := strconv.Atoi(getEnv("RATE_LIMIT_BURST_CAPACITY", "0"))
+	if err != nil {
+		burstCapacity = 0
+	}
+	AppConfig.RateLimitBurstCapacity = burstCapacity
+
+	AppConfig.RateLimitAllowlist = parseIPList(getEnv("RATE_LIMIT_ALLOWLIST", ""))
+	AppConfig.RateLimitDenylist = parseIPList(getEnv("RATE_LIMIT_DENYLIST", ""))
+
+	// Parse the session limit; zero allows any number of sessions, and anything but reject evicts
+	maxSessions, err := strconv.Atoi(getEnv("MAX_SESSIONS_PER_USER", "0"))
+	if err != nil || maxSessions < 0 {
+		maxSessions = 0
+	}
+	AppConfig.MaxSessionsPerUser = maxSessions
+
+	AppConfig.SessionLimitPolicy = strings.ToLower(getEnv("SESSION_LIMIT_POLICY", "evict_oldest"))
+	if AppConfig.SessionLimitPolicy != "reject" {
+		AppConfig.SessionLimitPolicy = "evict_oldest"
+	}
+
+	// Parse back-channel logout delivery settings
+	logoutWorkers, err := strconv.Atoi(getEnv("BACKCHANNEL_LOGOUT_WORKERS", "4"))
+	if err != nil || logoutWorkers < 1 {
+		logoutWorkers = 4
+	}
+	AppConfig.BackchannelLogoutWorkers = logoutWorkers
+
+	logoutAttempts, err := strconv.Atoi(getEnv("BACKCHANNEL_LOGOUT_MAX_ATTEMPTS", "5"))
+	if err != nil || logoutAttempts < 1 {
+		logoutAttempts = 5
+	}
+	AppConfig.BackchannelLogoutAttempts = logoutAttempts
+
+	// Anything but session logs the user out of every session
+	AppConfig.EndSessionScope = strings.ToLower(getEnv("END_SESSION_SCOPE", "user"))
+	if AppConfig.EndSessionScope != "session" {
+		AppConfig.EndSessionScope = "user"
+	}
+
+	// Parse webhook delivery settings
+	webhookWorkers, err := strconv.Atoi(getEnv("WEBHOOK_WORKERS", "2"))
+	if err != nil || webhookWorkers < 1 {
+		webhookWorkers = 2
+	}
+	AppConfig.WebhookWorkers = webhookWorkers
+
+	webhookAttempts, err := strconv.Atoi(getEnv("WEBHOOK_MAX_ATTEMPTS", "8"))
+	if err != nil || webhookAttempts < 1 {
+		webhookAttempts = 8
+	}
+	AppConfig.WebhookMaxAttempts = webhookAttempts
+
+	deadLetterLimit, err := strconv.Atoi(getEnv("WEBHOOK_DEAD_LETTER_LIMIT", "1000"))
+	if err != nil || deadLetterLimit < 1 {
+		deadLetterLimit = 1000
+	}
+	AppConfig.WebhookDeadLetterLimit = deadLetterLimit
+
+	// Parse the expired data purge batch size
+	cleanupBatchSize, err := strconv.Atoi(getEnv("CLEANUP_BATCH_SIZE", "1000"))
+	if err != nil || cleanupBatchSize < 1 {
+		cleanupBatchSize = 1000
+	}
+	AppConfig.CleanupBatchSize = cleanupBatchSize
+
+	// Parse the web refresh token store; anything but postgres or memory keeps tokens in Redis
+	AppConfig.TokenStore = strings.ToLower(getEnv("TOKEN_STORE", "redis"))
+	if AppConfig.TokenStore != "postgres" && AppConfig.TokenStore != "memory" {
+		AppConfig.TokenStore = "redis"
+	}
+
+	// Parse the client store; anything but memory keeps clients in PostgreSQL
+	AppConfig.ClientStore = strings.ToLower(getEnv("CLIENT_STORE", "postgres"))
+	if AppConfig.ClientStore != "memory" {
+		AppConfig.ClientStore = "postgres"
+	}
+
+	// Parse the rate limit counter store; anything but memory keeps counters in Redis
+	AppConfig.RateLimitStore = strings.ToLower(getEnv("RATE_LIMIT_STORE", "redis"))
+	if AppConfig.RateLimitStore != "memory" {
+		AppConfig.RateLimitStore = "redis"
+	}
+
+	// Parse audit log settings; anything but file stores events in PostgreSQL
+	AppConfig.AuditStore = strings.ToLower(getEnv("AUDIT_STORE", "postgres"))
+	if AppConfig.AuditStore != "file" {
+		AppConfig.AuditStore = "postgres"
+	}
+
+	auditQueueSize, err := strconv.Atoi(getEnv("AUDIT_QUEUE_SIZE", "1024"))
+	if err != nil || auditQueueSize < 1 {
+		auditQueueSize = 1024
+	}
+	AppConfig.AuditQueueSize = auditQueueSize
+
+	// Parse token binding granularity: how much of the issuing address a bound refresh token keeps
+	bindingIPv4Prefix, err := strconv.Atoi(getEnv("TOKEN_BINDING_IPV4_PREFIX", "24"))
+	if err != nil || bindingIPv4Prefix < 0 || bindingIPv4Prefix > 32 {
+		bindingIPv4Prefix = 24
+	}
+	AppConfig.TokenBindingIPv4Prefix = bindingIPv4Prefix
+
+	bindingIPv6Prefix, err := strconv.Atoi(getEnv("TOKEN_BINDING_IPV6_PREFIX", "64"))
+	if err != nil || bindingIPv6Prefix < 0 || bindingIPv6Prefix > 128 {
+		bindingIPv6Prefix = 64
+	}
+	AppConfig.TokenBindingIPv6Prefix = bindingIPv6Prefix
+
+	// Parse account lockout settings
+	lockoutThreshold, err := strconv.Atoi(getEnv("LOGIN_LOCKOUT_THRESHOLD", "5"))
+	if err != nil {
+		lockoutThreshold = 5
+	}
+	AppConfig.LoginLockoutThreshold = lockoutThreshold
+
+	lockoutMultiplier, err := strconv.ParseFloat(getEnv("LOGIN_LOCKOUT_MULTIPLIER", "2"), 64)
+	if err != nil || lockoutMultiplier < 1 {
+		lockoutMultiplier = 2
+	}
+	AppConfig.LoginLockoutMultiplier = lockoutMultiplier
+
+	// Parse the login CAPTCHA thresholds
+	captchaIPThreshold, err := strconv.Atoi(getEnv("CAPTCHA_IP_THRESHOLD", "10"))
+	if err != nil || captchaIPThreshold < 0 {
+		captchaIPThreshold = 10
+	}
+	AppConfig.CaptchaIPThreshold = captchaIPThreshold
+
+	captchaLoginThreshold, err := strconv.Atoi(getEnv("CAPTCHA_LOGIN_THRESHOLD", "3"))
+	if err != nil || captchaLoginThreshold < 0 {
+		captchaLoginThreshold = 3
+	}
+	AppConfig.CaptchaLoginThreshold = captchaLoginThreshold
+
+	// Parse the password policy; the breach check is off unless enabled
+	passwordMinLength, err := strconv.Atoi(getEnv("PASSWORD_MIN_LENGTH", "8"))
+	if err != nil || passwordMinLength < 1 {
+		passwordMinLength = 8
+	}
+	AppConfig.PasswordMinLength = passwordMinLength
+
+	AppConfig.PasswordRequireUppercase = getEnvBool("PASSWORD_REQUIRE_UPPERCASE", false)
+	AppConfig.PasswordRequireLowercase = getEnvBool("PASSWORD_REQUIRE_LOWERCASE", false)
+	AppConfig.PasswordRequireDigit = getEnvBool("PASSWORD_REQUIRE_DIGIT", false)
+	AppConfig.PasswordRequireSymbol = getEnvBool("PASSWORD_REQUIRE_SYMBOL", false)
+	AppConfig.PasswordBreachCheck = getEnvBool("PASSWORD_BREACH_CHECK", false)
+
+	// Parse two-factor authentication settings
+	totpSkew, err := strconv.Atoi(getEnv("TOTP_SKEW_STEPS", "1"))
+	if err != nil || totpSkew < 0 {
+		totpSkew = 1
+	}
+	AppConfig.TOTPSkewSteps = totpSkew
+
+	// Parse password hashing settings; anything but argon2id hashes with bcrypt
+	AppConfig.PasswordHashAlgorithm = strings.ToLower(getEnv("PASSWORD_HASH_ALGORITHM", "bcrypt"))
+	if AppConfig.PasswordHashAlgorithm != "argon2id" {
+		AppConfig.PasswordHashAlgorithm = "bcrypt"
+	}
+
+	bcryptCost, err := strconv.Atoi(getEnv("BCRYPT_COST", "10"))
+	if err != nil || bcryptCost < 4 || bcryptCost > 31 {
+		bcryptCost = 10
+	}
+	AppConfig.BcryptCost = bcryptCost
+
+	argon2Time, err := strconv.Atoi(getEnv("ARGON2_TIME", "3"))
+	if err != nil || argon2Time < 1 {
+		argon2Time = 3
+	}
+	AppConfig.Argon2Time = argon2Time
+
+	argon2Memory, err := strconv.Atoi(getEnv("ARGON2_MEMORY_KIB", "65536"))
+	if err != nil || argon2Memory < 8 {
+		argon2Memory = 65536
+	}
+	AppConfig.Argon2MemoryKiB = argon2Memory
+
+	argon2Threads, err := strconv.Atoi(getEnv("ARGON2_THREADS", "4"))
+	if err != nil || argon2Threads < 1 || argon2Threads > 255 {
+		argon2Threads = 4
+	}
+	AppConfig.Argon2Threads = argon2Threads
+
+	// Passkeys are only accepted from these origins, the base URL unless configured otherwise
+	AppConfig.WebAuthnRPOrigins = parseIPList(getEnv("WEBAUTHN_RP_ORIGINS", strings.TrimRight(AppConfig.AppBaseURL, "/")))
+
+	// Tokens are issued by the base URL unless configured otherwise
+	AppConfig.TokenIssuer = getEnv("TOKEN_ISSUER", strings.TrimRight(AppConfig.AppBaseURL, "/"))
+
+	// Tenants served besides the default one, the hosts of those with their own, and their signing keys
+	AppConfig.Tenants = parseIPList(getEnv("TENANTS", ""))
+	AppConfig.TenantHosts = parseTenantHosts(getEnv("TENANT_HOSTS", ""))
+	AppConfig.TenantKeysDir = getEnv("TENANT_KEYS_DIR", "keys/tenants")
+
+	// Grant types accepted at the token endpoint and advertised in the server metadata
+	AppConfig.EnabledGrantTypes = parseIPList(getEnv("ENABLED_GRANT_TYPES",
+		"authorization_code,refresh_token,client_credentials,urn:ietf:params:oauth:grant-type:device_code"))
+
+	// Public clients may vary the port of loopback IP redirect URIs only when enabled
+	loopbackAnyPort, err := strconv.ParseBool(getEnv("REDIRECT_URI_LOOPBACK_ANY_PORT", "false"))
+	if err != nil {
+		loopbackAnyPort = false
+	}
+	AppConfig.RedirectURILoopbackAnyPort = loopbackAnyPort
+
+	// The login page is served by the web app at the base URL unless configured otherwise
+	AppConfig.LoginURL = getEnv("APP_LOGIN_URL", strings.TrimRight(AppConfig.AppBaseURL, "/")+"/login")
 
 	// Parse IP lists
 	AppConfig.IPWhitelist = parseIPList(getEnv("IP_WHITELIST", ""))
 	AppConfig.IPBlacklist = parseIPList(getEnv("IP_BLACKLIST", ""))
+
+	// Only these proxies may set the client address through X-Forwarded-For; none by default
+	AppConfig.TrustedProxies = parseTrustedProxies(getEnv("TRUSTED_PROXIES", ""))
+
+	// Client certificates for mutual TLS, forwarded by a trusted proxy or presented to the server itself
+	AppConfig.MTLSClientCertHeader = getEnv("MTLS_CLIENT_CERT_HEADER", "X-Client-Cert")
+	AppConfig.MTLSClientCAFile = getEnv("MTLS_CLIENT_CA_FILE", "")
+
+	// Parse CORS settings
+	AppConfig.CORSAllowedOrigins = parseIPList(getEnv("CORS_ALLOWED_ORIGINS", "*"))
+
+	allowCredentials, err := strconv.ParseBool(getEnv("CORS_ALLOW_CREDENTIALS", "true"))
+	if err != nil {
+		allowCredentials = true
+	}
+	AppConfig.CORSAllowCredentials = allowCredentials
+
+	// Security headers of every response; an empty value omits its header. The default policy
+	// allows scripts only with the nonce of the response and no framing of the server's pages,
+	// and no referrer is sent, as the URLs of the server's pages can carry authorization codes
+	AppConfig.SecurityCSP = getEnvAllowEmpty("SECURITY_CSP", "default-src 'self'; script-src 'nonce-{nonce}'; object-src 'none'; base-uri 'none'; form-action 'self'; frame-ancestors 'none'")
+	AppConfig.SecurityFrameOptions = getEnvAllowEmpty("SECURITY_FRAME_OPTIONS", "DENY")
+	AppConfig.SecurityReferrerPolicy = getEnvAllowEmpty("SECURITY_REFERRER_POLICY", "no-referrer")
+	AppConfig.SecurityContentTypeOptions = getEnvAllowEmpty("SECURITY_CONTENT_TYPE_OPTIONS", "nosniff")
+	AppConfig.SecurityHSTSMaxAge = getEnv("SECURITY_HSTS_MAX_AGE", "8760h")
+	AppConfig.SecurityHSTSSubdomains = getEnvBool("SECURITY_HSTS_INCLUDE_SUBDOMAINS", true)
+
+	// Parse administrator user IDs, and the login they must step up to before revoking tokens in bulk
+	AppConfig.AdminUserIDs = parseUserIDList(getEnv("ADMIN_USER_IDS", ""))
+	AppConfig.AdminStepUpACRValues = strings.Fields(getEnv("ADMIN_STEP_UP_ACR_VALUES", ""))
+	AppConfig.AdminStepUpMaxAge = getEnv("ADMIN_STEP_UP_MAX_AGE", "15m")
+
+	// Clients whose access tokens with the client administration scope manage clients,
+	// and how long a rotated client secret may stay valid alongside its replacement
+	AppConfig.AdminClientIDs = parseIPList(getEnv("ADMIN_CLIENT_IDS", ""))
+	AppConfig.ClientSecretMaxGracePeriod = getEnv("CLIENT_SECRET_MAX_GRACE_PERIOD", "168h")
+
+	// The token debugging endpoint is only served when explicitly enabled
+	AppConfig.DebugTokenEndpoint = getEnvBool("DEBUG_TOKEN_ENDPOINT_ENABLED", false)
 }
 
 // getEnv retrieves a value from environment variables with a fallback default.
@@ -78,6 +619,26 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvAllowEmpty retrieves a value from environment variables with a fallback default.
+// Unlike getEnv, a variable that is set but empty yields the empty string; only an unset
+// variable yields the default value.
+func getEnvAllowEmpty(key, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
+// getEnvBool retrieves a boolean from environment variables with a fallback default.
+// If the environment variable is not set or does not parse as a boolean, the default value is returned.
+func getEnvBool(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(getEnv(key, strconv.FormatBool(defaultValue)))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
 // mustGetEnv retrieves a required value from environment variables.
 // If the environment variable is not set or is empty, the function panics.
 // This should be used only for configuration values that are essential
@@ -91,7 +652,8 @@ func mustGetEnv(key string) string {
 }
 
 // parseIPList converts a comma-separated string of IP addresses into a string slice.
-// This is used for parsing IP whitelist and blacklist environment variables.
+// This is used for parsing IP whitelist and blacklist environment variables,
+// and for other comma-separated lists such as CORS origins.
 // Returns an empty slice if the input string is empty.
 func parseIPList(ips string) []string {
 	if ips == "" {
@@ -99,3 +661,236 @@ func parseIPList(ips string) []string {
 	}
 	return strings.Split(ips, ",")
 }
+
+// parseTrustedProxies converts a comma-separated list of proxy addresses into a slice of
+// IPv4 or IPv6 addresses and CIDR ranges, trimmed of surrounding whitespace.
+// It panics on an entry that is neither, since a proxy silently dropped from the list would
+// make every request it forwards look like it came from the proxy itself, and the rate
+// limiter would then count all of them against the proxy's address.
+// Returns an empty slice if the input string is empty.
+func parseTrustedProxies(entries string) []string {
+	result := []string{}
+	for _, entry := range parseIPList(entries) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(entry); err != nil && net.ParseIP(entry) == nil {
+			panic("invalid trusted proxy, expected an IP address or CIDR range: " + entry)
+		}
+		result = append(result, entry)
+	}
+	return result
+}
+
+// parseUserIDList converts a comma-separated string of user IDs into a uint slice.
+// Entries that are not positive integers are ignored.
+// Returns an empty slice if the input string is empty.
+func parseUserIDList(ids string) []uint {
+	result := []uint{}
+	if ids == "" {
+		return result
+	}
+
+	for _, entry := range strings.Split(ids, ",") {
+		id, err := strconv.ParseUint(strings.TrimSpace(entry), 10, 64)
+		if err != nil || id == 0 {
+			continue
+		}
+		result = append(result, uint(id))
+	}
+
+	return result
+}
+
+// parseGrantDurations converts a comma-separated list of grant_type=duration pairs into a map
+// of per-grant-type durations. The durations are validated by validateTokenLifetimes.
+// Returns an empty map if the input string is empty.
+func parseGrantDurations(pairs string) map[string]string {
+	result := make(map[string]string)
+	if pairs == "" {
+		return result
+	}
+
+	for _, entry := range strings.Split(pairs, ",") {
+		grantType, duration, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || grantType == "" {
+			panic("invalid grant type token lifetime: " + entry)
+		}
+		result[grantType] = duration
+	}
+
+	return result
+}
+
+// validateTokenLifetimes checks that every configured token lifetime is a positive duration
+// and that refresh tokens outlive access tokens, both by default and for each grant type
+// once its overrides are applied, and that the absolute refresh token lifetime, when set,
+// is at least the idle one. It panics on the first invalid combination.
+func validateTokenLifetimes() {
+	access := mustParseLifetime("JWT_ACCESS_EXPIRY", AppConfig.JWTAccessExpiry)
+	mustParseLifetime("JWT_REFRESH_EXPIRY", AppConfig.JWTRefreshExpiry)
+	refresh := mustParseLifetime("REFRESH_TOKEN_IDLE_TTL", AppConfig.RefreshTokenIdleTTL)
+	mustParseLifetime("JWT_ID_TOKEN_EXPIRY", AppConfig.JWTIDTokenExpiry)
+	if refresh <= access {
+		panic("REFRESH_TOKEN_IDLE_TTL must be longer than JWT_ACCESS_EXPIRY")
+	}
+	if AppConfig.RefreshTokenAbsoluteTTL != "" && mustParseLifetime("REFRESH_TOKEN_ABSOLUTE_TTL", AppConfig.RefreshTokenAbsoluteTTL) < refresh {
+		panic("REFRESH_TOKEN_ABSOLUTE_TTL must not be shorter than REFRESH_TOKEN_IDLE_TTL")
+	}
+
+	grantTypes := make(map[string]bool)
+	for _, overrides := range []map[string]string{AppConfig.GrantAccessTokenExpiry, AppConfig.GrantRefreshTokenExpiry, AppConfig.GrantIDTokenExpiry} {
+		for grantType := range overrides {
+			grantTypes[grantType] = true
+		}
+	}
+
+	for grantType := range grantTypes {
+		grantAccess, grantRefresh := access, refresh
+		if value, ok := AppConfig.GrantAccessTokenExpiry[grantType]; ok {
+			grantAccess = mustParseLifetime("GRANT_ACCESS_TOKEN_EXPIRY", value)
+		}
+		if value, ok := AppConfig.GrantRefreshTokenExpiry[grantType]; ok {
+			grantRefresh = mustParseLifetime("GRANT_REFRESH_TOKEN_EXPIRY", value)
+		}
+		if value, ok := AppConfig.GrantIDTokenExpiry[grantType]; ok {
+			mustParseLifetime("GRANT_ID_TOKEN_EXPIRY", value)
+		}
+		if grantRefresh <= grantAccess {
+			panic("refresh token lifetime must be longer than access token lifetime for grant type " + grantType)
+		}
+	}
+}
+
+// Bounds of the random bytes in generated tokens: at least 256 bits, and short enough
+// that the longest token and its identifiers fit the 255-character token columns
+const (
+	minTokenEntropyBytes = 32
+	maxTokenEntropyBytes = 128
+)
+
+// parseTokenEntropy parses the number of random bytes in generated tokens, panicking if it is
+// not a number within the bounds, since a weak setting must not go unnoticed.
+func parseTokenEntropy(value string) int {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < minTokenEntropyBytes || n > maxTokenEntropyBytes {
+		panic("TOKEN_ENTROPY_BYTES must be between " + strconv.Itoa(minTokenEntropyBytes) + " and " + strconv.Itoa(maxTokenEntropyBytes) + ": " + value)
+	}
+	return n
+}
+
+// mustParseLifetime parses a token lifetime, panicking if it is not a positive duration.
+func mustParseLifetime(key, value string) time.Duration {
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		panic("invalid token lifetime in " + key + ": " + value)
+	}
+	return d
+}
+
+// parseACRRequirements converts a comma-separated list of acr_value=method|method pairs into a map
+// from each acr value relying parties may request to the ways of logging in that satisfy it.
+// It panics on a malformed entry, since a requirement silently dropped would weaken step-up.
+// Returns an empty map if the input string is empty.
+func parseACRRequirements(pairs string) map[string][]string {
+	result := make(map[string][]string)
+	if pairs == "" {
+		return result
+	}
+
+	for _, entry := range strings.Split(pairs, ",") {
+		value, methods, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || value == "" || methods == "" {
+			panic("invalid ACR requirement: " + entry)
+		}
+		result[value] = strings.Split(methods, "|")
+	}
+
+	return result
+}
+
+// parseRateLimitRoutes converts a comma-separated list of name=requests/window pairs into a map
+// of the limits of the named per-route rate limiters. The limits are parsed when the limiters are
+// created. It panics on an entry without a name or limit.
+// Returns an empty map if the input string is empty.
+func parseRateLimitRoutes(pairs string) map[string]string {
+	result := make(map[string]string)
+	if pairs == "" {
+		return result
+	}
+
+	for _, entry := range strings.Split(pairs, ",") {
+		name, limit, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || name == "" || limit == "" {
+			panic("invalid route rate limit: " + entry)
+		}
+		result[name] = limit
+	}
+
+	return result
+}
+
+// parseRateLimitKeys converts a comma-separated list of name=key pairs into a map of the
+// subjects the named rate limiters count requests for, such as token=client+ip. The keys are
+// parsed when the limiters are created. It panics on an entry without a name or key.
+// Returns an empty map if the input string is empty.
+func parseRateLimitKeys(pairs string) map[string]string {
+	result := make(map[string]string)
+	if pairs == "" {
+		return result
+	}
+
+	for _, entry := range strings.Split(pairs, ",") {
+		name, key, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || name == "" || key == "" {
+			panic("invalid rate limit key: " + entry)
+		}
+		result[name] = key
+	}
+
+	return result
+}
+
+// parseTenantHosts converts a comma-separated list of tenant=host pairs into a map of the host
+// names tenants are served at. The tenants are checked when the tenant registry is created.
+// It panics on an entry without a tenant or host.
+// Returns an empty map if the input string is empty.
+func parseTenantHosts(pairs string) map[string]string {
+	result := make(map[string]string)
+	if pairs == "" {
+		return result
+	}
+
+	for _, entry := range strings.Split(pairs, ",") {
+		id, host, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || id == "" || host == "" {
+			panic("invalid tenant host: " + entry)
+		}
+		result[id] = host
+	}
+
+	return result
+}
+
+// parseRateLimitTiers converts a comma-separated list of client_id:tier pairs
+// into a map of per-client tiers: "exempt", a number of requests per minute,
+// or a limit written as requests/window. Tiers are validated when the rate
+// limiters are set up; entries without a client ID or tier are ignored.
+// Returns an empty map if the input string is empty.
+func parseRateLimitTiers(tiers string) map[string]string {
+	result := make(map[string]string)
+	if tiers == "" {
+		return result
+	}
+
+	for _, entry := range strings.Split(tiers, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), ":", 2)
+		if len(parts) != 2 || parts[0] == "" || strings.TrimSpace(parts[1]) == "" {
+			continue
+		}
+		result[parts[0]] = strings.TrimSpace(parts[1])
+	}
+
+	return result
+}
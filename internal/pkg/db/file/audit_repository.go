@@ -0,0 +1,115 @@
+// Package file provides append-only file implementations of the application's repositories.
+package file
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/verigate/verigate-server/internal/app/audit"
+	"github.com/verigate/verigate-server/internal/pkg/pagination"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+)
+
+// maxAuditLineSize is the longest audit log line accepted when reading the file back
+const maxAuditLineSize = 1 << 20
+
+// auditRepository implements the audit.Repository interface with a JSON lines file.
+// The file is only ever appended to; each event's ID is its line number.
+type auditRepository struct {
+	mu     sync.Mutex
+	path   string
+	file   *os.File
+	lastID uint
+}
+
+// NewAuditRepository opens the audit log file at path for appending, creating it if needed,
+// and returns an audit.Repository interface. Existing events are counted so IDs continue.
+func NewAuditRepository(path string) (audit.Repository, error) {
+	lines, err := countLines(path)
+	if err != nil {
+		return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToOpenAuditLog, err.Error()))
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToOpenAuditLog, err.Error()))
+	}
+
+	return &auditRepository{path: path, file: f, lastID: lines}, nil
+}
+
+// Save appends an audit event to the file as one JSON line and sets its ID.
+// The write is synced to disk before returning.
+func (r *auditRepository) Save(ctx context.Context, event *audit.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event.ID = r.lastID + 1
+	line, err := json.Marshal(event)
+	if err != nil {
+		return errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToSaveAuditEvent, err.Error()))
+	}
+
+	if _, err := r.file.Write(append(line, '\n')); err != nil {
+		return errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToSaveAuditEvent, err.Error()))
+	}
+	if err := r.file.Sync(); err != nil {
+		return errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToSaveAuditEvent, err.Error()))
+	}
+
+	r.lastID = event.ID
+	return nil
+}
+
+// List retrieves the requested page of audit events from the file. The whole file is scanned
+// and paged in memory, so the file store suits modest volumes or external rotation.
+func (r *auditRepository) List(ctx context.Context, page *pagination.Request) (*pagination.Page[audit.Event], error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f, err := os.Open(r.path)
+	if err != nil {
+		return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToListAuditEvents, err.Error()))
+	}
+	defer f.Close()
+
+	var events []audit.Event
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxAuditLineSize)
+	for scanner.Scan() {
+		var e audit.Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToListAuditEvents, err.Error()))
+		}
+		events = append(events, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToListAuditEvents, err.Error()))
+	}
+
+	return pagination.Select(events, page, audit.EventValue), nil
+}
+
+// countLines returns the number of lines in the file at path, or zero if it does not exist.
+func countLines(path string) (uint, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var lines uint
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxAuditLineSize)
+	for scanner.Scan() {
+		lines++
+	}
+	return lines, scanner.Err()
+}
@@ -0,0 +1,199 @@
+// Package memory provides in-process implementations of the application's repositories.
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/app/auth"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+	"github.com/verigate/verigate-server/internal/pkg/utils/hash"
+)
+
+// authRepository implements the auth.TokenStore interface in process memory.
+// Like the Redis store, every token disappears once it expires. Stored tokens are copies,
+// so callers cannot change them without going through the store.
+type authRepository struct {
+	mu         sync.Mutex
+	tokens     map[string]auth.RefreshToken
+	userTokens map[uint]map[string]struct{}
+}
+
+// NewAuthRepository creates an in-memory authentication repository.
+// Expired tokens are swept in the background until ctx is cancelled.
+func NewAuthRepository(ctx context.Context) auth.TokenStore {
+	r := &authRepository{
+		tokens:     make(map[string]auth.RefreshToken),
+		userTokens: make(map[uint]map[string]struct{}),
+	}
+	sweep(ctx, sweepInterval, r.removeExpired)
+	return r
+}
+
+// SaveRefreshToken stores a new refresh token and adds it to the user's token set.
+func (r *authRepository) SaveRefreshToken(ctx context.Context, token *auth.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.save(*token)
+	return nil
+}
+
+// FindRefreshToken looks up a refresh token by ID.
+// Returns nil if the token doesn't exist or has expired.
+func (r *authRepository) FindRefreshToken(ctx context.Context, tokenID string) (*auth.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.live(tokenID, time.Now())
+	if !ok {
+		return nil, nil
+	}
+	return &token, nil
+}
+
+// FindRefreshTokenByToken looks up a refresh token by its plain text token value,
+// comparing it with the hash of every unexpired token.
+// Returns nil if the token doesn't exist.
+func (r *authRepository) FindRefreshTokenByToken(ctx context.Context, plainTextToken string) (*auth.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, token := range r.tokens {
+		if !now.Before(token.ExpiresAt) {
+			continue
+		}
+		if err := hash.CompareHashAndPassword(token.Token, plainTextToken); err == nil {
+			return &token, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// RevokeRefreshToken marks a specific refresh token as revoked.
+func (r *authRepository) RevokeRefreshToken(ctx context.Context, tokenID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.live(tokenID, time.Now())
+	if !ok {
+		return errors.NotFound(errors.ErrMsgRefreshTokenNotFound)
+	}
+
+	token.IsRevoked = true
+	r.tokens[tokenID] = token
+	return nil
+}
+
+// RotateRefreshToken revokes the old token and saves its successor while holding the lock,
+// so a concurrent rotation or revocation of the old token is never interleaved with it.
+func (r *authRepository) RotateRefreshToken(ctx context.Context, oldTokenID string, newToken *auth.RefreshToken) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old, ok := r.live(oldTokenID, time.Now())
+	if !ok || old.IsRevoked {
+		return false, nil
+	}
+
+	old.IsRevoked = true
+	r.tokens[oldTokenID] = old
+	r.save(*newToken)
+	return true, nil
+}
+
+// RevokeAllUserRefreshTokens revokes all refresh tokens for a user.
+func (r *authRepository) RevokeAllUserRefreshTokens(ctx context.Context, userID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for tokenID := range r.userTokens[userID] {
+		if token, ok := r.tokens[tokenID]; ok {
+			token.IsRevoked = true
+			r.tokens[tokenID] = token
+		}
+	}
+
+	return nil
+}
+
+// DeleteExpiredTokens removes expired tokens right away rather than at the next sweep.
+func (r *authRepository) DeleteExpiredTokens(ctx context.Context) error {
+	r.removeExpired(time.Now())
+	return nil
+}
+
+// IsRefreshTokenRevoked checks if a refresh token has been revoked.
+func (r *authRepository) IsRefreshTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	token, err := r.FindRefreshToken(ctx, tokenID)
+	if err != nil {
+		return false, err
+	}
+
+	if token == nil {
+		return true, nil // Token not found, consider revoked
+	}
+
+	return token.IsRevoked, nil
+}
+
+// DeleteSessionRefreshTokens removes the user's refresh tokens that belong to the session.
+func (r *authRepository) DeleteSessionRefreshTokens(ctx context.Context, userID uint, sessionID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for tokenID := range r.userTokens[userID] {
+		if token, ok := r.tokens[tokenID]; ok && token.SessionID == sessionID {
+			r.remove(tokenID, userID)
+		}
+	}
+
+	return nil
+}
+
+// save stores a token and indexes it under its user. The caller holds the lock.
+func (r *authRepository) save(token auth.RefreshToken) {
+	r.tokens[token.ID] = token
+
+	ids, ok := r.userTokens[token.UserID]
+	if !ok {
+		ids = make(map[string]struct{})
+		r.userTokens[token.UserID] = ids
+	}
+	ids[token.ID] = struct{}{}
+}
+
+// live returns the token with the given ID unless it is missing or expired. The caller holds the lock.
+func (r *authRepository) live(tokenID string, now time.Time) (auth.RefreshToken, bool) {
+	token, ok := r.tokens[tokenID]
+	if !ok || !now.Before(token.ExpiresAt) {
+		return auth.RefreshToken{}, false
+	}
+	return token, true
+}
+
+// remove deletes a token and its entry in the user's token set. The caller holds the lock.
+func (r *authRepository) remove(tokenID string, userID uint) {
+	delete(r.tokens, tokenID)
+	if ids, ok := r.userTokens[userID]; ok {
+		delete(ids, tokenID)
+		if len(ids) == 0 {
+			delete(r.userTokens, userID)
+		}
+	}
+}
+
+// removeExpired deletes every token that expired by now.
+func (r *authRepository) removeExpired(now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for tokenID, token := range r.tokens {
+		if !now.Before(token.ExpiresAt) {
+			r.remove(tokenID, token.UserID)
+		}
+	}
+}
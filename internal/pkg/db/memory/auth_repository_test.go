@@ -0,0 +1,16 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/verigate/verigate-server/internal/app/auth/authtest"
+)
+
+func TestAuthRepositoryConformance(t *testing.T) {
+	authtest.RunTokenStoreTests(t, func(t *testing.T) authtest.Store {
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		return authtest.Store{TokenStore: NewAuthRepository(ctx)}
+	})
+}
@@ -0,0 +1,272 @@
+// Package memory provides in-process implementations of the application's repositories.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/app/client"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+)
+
+// redirectURIOrigin matches the scheme://host[:port] prefix of a redirect URI,
+// like the pattern the PostgreSQL repository uses
+var redirectURIOrigin = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*://[^/?#]+`)
+
+// clientRepository implements the client.Repository interface in process memory.
+// Clients are copied on the way in and out, so callers never share slices with the store.
+type clientRepository struct {
+	mu      sync.RWMutex
+	nextID  uint
+	clients map[uint]*client.Client
+}
+
+// NewClientRepository creates an empty in-memory client repository.
+func NewClientRepository() client.Repository {
+	return &clientRepository{
+		nextID:  1,
+		clients: make(map[uint]*client.Client),
+	}
+}
+
+// Save stores a new OAuth client and assigns it the next ID.
+// Returns a Conflict error if the client ID is already taken.
+func (r *clientRepository) Save(ctx context.Context, c *client.Client) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.clients {
+		if existing.ClientID == c.ClientID {
+			return errors.Conflict(errors.ErrMsgClientIdAlreadyExists)
+		}
+	}
+
+	c.ID = r.nextID
+	r.nextID++
+	r.clients[c.ID] = copyClient(c)
+	return nil
+}
+
+// Update replaces the mutable fields of an existing OAuth client. The client ID, current and previous
+// secrets, sealed ID token signing secret, confidentiality, status, owner, creation time, and registration access token are kept,
+// matching the columns the PostgreSQL repository updates.
+// Returns NotFound error if the client doesn't exist.
+func (r *clientRepository) Update(ctx context.Context, c *client.Client) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.clients[c.ID]
+	if !ok {
+		return errors.NotFound(fmt.Sprintf(errors.ErrMsgClientWithIDNotFound, c.ID))
+	}
+
+	updated := copyClient(c)
+	updated.ClientID = existing.ClientID
+	updated.ClientSecret = existing.ClientSecret
+	updated.IDTokenSigningSecret = existing.IDTokenSigningSecret
+	updated.PreviousClientSecret = existing.PreviousClientSecret
+	updated.PreviousSecretExpiresAt = existing.PreviousSecretExpiresAt
+	updated.IsConfidential = existing.IsConfidential
+	updated.IsActive = existing.IsActive
+	updated.CreatedAt = existing.CreatedAt
+	updated.OwnerID = existing.OwnerID
+	updated.RegistrationAccessToken = existing.RegistrationAccessToken
+	r.clients[c.ID] = updated
+	return nil
+}
+
+// FindByID retrieves an OAuth client by its internal ID.
+// Returns nil if the client doesn't exist.
+func (r *clientRepository) FindByID(ctx context.Context, id uint) (*client.Client, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.clients[id]
+	if !ok {
+		return nil, nil
+	}
+	return copyClient(c), nil
+}
+
+// FindByClientID retrieves an OAuth client by its client ID (public identifier).
+// Returns nil if the client doesn't exist.
+func (r *clientRepository) FindByClientID(ctx context.Context, clientID string) (*client.Client, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, c := range r.clients {
+		if c.ClientID == clientID {
+			return copyClient(c), nil
+		}
+	}
+	return nil, nil
+}
+
+// FindByOwnerID retrieves a page of the OAuth clients owned by a user, newest first,
+// together with the total number of clients the user owns.
+// The page parameter is 1-indexed (first page is 1, not 0).
+func (r *clientRepository) FindByOwnerID(ctx context.Context, ownerID uint, page, limit int) ([]client.Client, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var owned []*client.Client
+	for _, c := range r.clients {
+		if c.OwnerID == ownerID {
+			owned = append(owned, c)
+		}
+	}
+	sort.Slice(owned, func(i, j int) bool {
+		return owned[i].CreatedAt.After(owned[j].CreatedAt)
+	})
+
+	total := int64(len(owned))
+	offset := (page - 1) * limit
+	if offset < 0 {
+		offset = 0
+	}
+
+	var clients []client.Client
+	for i := offset; i < len(owned) && i < offset+limit; i++ {
+		clients = append(clients, *copyClient(owned[i]))
+	}
+
+	return clients, total, nil
+}
+
+// Search retrieves a page of the OAuth clients matching the filter, newest first,
+// together with the total number of matching clients.
+// The page parameter is 1-indexed (first page is 1, not 0).
+func (r *clientRepository) Search(ctx context.Context, filter client.ClientFilter, page, limit int) ([]client.Client, int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	query := strings.ToLower(filter.Query)
+	var matched []*client.Client
+	for _, c := range r.clients {
+		if filter.OwnerID != 0 && c.OwnerID != filter.OwnerID {
+			continue
+		}
+		if query != "" && !strings.Contains(strings.ToLower(c.ClientName), query) && !strings.Contains(strings.ToLower(c.ClientID), query) {
+			continue
+		}
+		if filter.IsActive != nil && c.IsActive != *filter.IsActive {
+			continue
+		}
+		if filter.IsConfidential != nil && c.IsConfidential != *filter.IsConfidential {
+			continue
+		}
+		matched = append(matched, c)
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].CreatedAt.After(matched[j].CreatedAt)
+	})
+
+	total := int64(len(matched))
+	offset := (page - 1) * limit
+	if offset < 0 {
+		offset = 0
+	}
+
+	var clients []client.Client
+	for i := offset; i < len(matched) && i < offset+limit; i++ {
+		clients = append(clients, *copyClient(matched[i]))
+	}
+
+	return clients, total, nil
+}
+
+// UpdateSecret replaces the secret hash and sealed ID token signing secret of an OAuth client,
+// keeping the previous hash until previousExpiresAt.
+// Returns NotFound error if the client doesn't exist.
+func (r *clientRepository) UpdateSecret(ctx context.Context, id uint, secretHash, signingSecret, previousHash string, previousExpiresAt *time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.clients[id]
+	if !ok {
+		return errors.NotFound(fmt.Sprintf(errors.ErrMsgClientWithIDNotFound, id))
+	}
+	c.ClientSecret = secretHash
+	c.IDTokenSigningSecret = signingSecret
+	c.PreviousClientSecret = previousHash
+	c.PreviousSecretExpiresAt = previousExpiresAt
+	c.UpdatedAt = time.Now()
+	return nil
+}
+
+// Delete removes an OAuth client by its ID.
+// Returns NotFound error if the client doesn't exist.
+func (r *clientRepository) Delete(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.clients[id]; !ok {
+		return errors.NotFound(fmt.Sprintf(errors.ErrMsgClientWithIDNotFound, id))
+	}
+	delete(r.clients, id)
+	return nil
+}
+
+// UpdateStatus changes the active status of an OAuth client.
+// Returns NotFound error if the client doesn't exist.
+func (r *clientRepository) UpdateStatus(ctx context.Context, id uint, isActive bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.clients[id]
+	if !ok {
+		return errors.NotFound(fmt.Sprintf(errors.ErrMsgClientWithIDNotFound, id))
+	}
+	c.IsActive = isActive
+	c.UpdatedAt = time.Now()
+	return nil
+}
+
+// HasRedirectURIOrigin checks whether an active client has registered a redirect URI
+// whose origin (scheme, host, and port) equals the given origin, ignoring case.
+func (r *clientRepository) HasRedirectURIOrigin(ctx context.Context, origin string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, c := range r.clients {
+		if !c.IsActive {
+			continue
+		}
+		for _, uri := range c.RedirectURIs {
+			if o := redirectURIOrigin.FindString(uri); o != "" && strings.EqualFold(o, origin) {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// copyClient returns a copy of c that shares none of its slices.
+func copyClient(c *client.Client) *client.Client {
+	cp := *c
+	cp.RedirectURIs = copyStrings(c.RedirectURIs)
+	cp.GrantTypes = copyStrings(c.GrantTypes)
+	cp.ResponseTypes = copyStrings(c.ResponseTypes)
+	cp.Contacts = copyStrings(c.Contacts)
+	cp.AllowedResources = copyStrings(c.AllowedResources)
+	cp.PostLogoutRedirectURIs = copyStrings(c.PostLogoutRedirectURIs)
+	cp.RequestURIs = copyStrings(c.RequestURIs)
+	if c.ClaimTemplates != nil {
+		cp.ClaimTemplates = append([]client.ClaimTemplate{}, c.ClaimTemplates...)
+	}
+	return &cp
+}
+
+// copyStrings returns a copy of s, keeping nil as nil.
+func copyStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append([]string{}, s...)
+}
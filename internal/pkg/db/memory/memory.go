@@ -0,0 +1,30 @@
+// Package memory provides in-process implementations of the application's repositories.
+// They need neither Redis nor PostgreSQL, which makes them suited to tests and local
+// development. Data lives in the memory of a single process and is lost on restart, and
+// replicas do not see each other's data, so they must not back a multi-replica production
+// deployment.
+package memory
+
+import (
+	"context"
+	"time"
+)
+
+// sweepInterval is how often expired entries are removed in the background
+const sweepInterval = time.Minute
+
+// sweep calls fn with the current time every interval until ctx is cancelled.
+func sweep(ctx context.Context, interval time.Duration, fn func(now time.Time)) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				fn(now)
+			}
+		}
+	}()
+}
@@ -0,0 +1,109 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/verigate/verigate-server/internal/app/audit"
+	"github.com/verigate/verigate-server/internal/pkg/pagination"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+)
+
+// auditRepository implements the audit.Repository interface using PostgreSQL.
+type auditRepository struct {
+	db DB
+}
+
+// NewAuditRepository creates a new PostgreSQL-based audit event repository.
+// It takes a database connection and returns an audit.Repository interface.
+func NewAuditRepository(db DB) audit.Repository {
+	return &auditRepository{db: db}
+}
+
+// Save appends an audit event to the PostgreSQL database and sets its ID.
+// The table rejects updates and deletes, so recorded events cannot be altered.
+func (r *auditRepository) Save(ctx context.Context, event *audit.Event) error {
+	details := []byte("{}")
+	var err error
+	if event.Details != nil {
+		details, err = json.Marshal(event.Details)
+	}
+	if err != nil {
+		return errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToSaveAuditEvent, err.Error()))
+	}
+
+	query := `
+		INSERT INTO audit_events (event_type, actor, client_id, ip_address, outcome, details, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`
+
+	err = r.db.QueryRowContext(ctx, query,
+		event.Type,
+		event.Actor,
+		event.ClientID,
+		event.IPAddress,
+		event.Outcome,
+		details,
+		event.CreatedAt,
+	).Scan(&event.ID)
+	if err != nil {
+		return errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToSaveAuditEvent, err.Error()))
+	}
+
+	return nil
+}
+
+// List retrieves the requested page of audit events from the PostgreSQL database. The total is
+// estimated by counting the matching events up to pagination.EstimateLimit.
+func (r *auditRepository) List(ctx context.Context, page *pagination.Request) (*pagination.Page[audit.Event], error) {
+	filterWhere, filterArgs := page.FilterWhere(nil)
+	var total int64
+	if err := r.db.QueryRowContext(ctx, pagination.CountQuery("audit_events", filterWhere), filterArgs...).Scan(&total); err != nil {
+		return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToListAuditEvents, err.Error()))
+	}
+
+	where, args := page.Where(nil)
+	orderBy, args := page.OrderBy(args)
+	query := fmt.Sprintf(`
+		SELECT id, event_type, actor, client_id, ip_address, outcome, details, created_at
+		FROM audit_events
+		%s
+		%s
+	`, where, orderBy)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToListAuditEvents, err.Error()))
+	}
+	defer rows.Close()
+
+	var events []audit.Event
+	for rows.Next() {
+		var e audit.Event
+		var details []byte
+		if err := rows.Scan(
+			&e.ID,
+			&e.Type,
+			&e.Actor,
+			&e.ClientID,
+			&e.IPAddress,
+			&e.Outcome,
+			&details,
+			&e.CreatedAt,
+		); err != nil {
+			return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToListAuditEvents, err.Error()))
+		}
+		if err := json.Unmarshal(details, &e.Details); err != nil {
+			return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToListAuditEvents, err.Error()))
+		}
+		events = append(events, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToListAuditEvents, err.Error()))
+	}
+
+	return pagination.NewPage(events, page, audit.EventValue, total), nil
+}
@@ -0,0 +1,257 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/verigate/verigate-server/internal/app/auth"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+	"github.com/verigate/verigate-server/internal/pkg/utils/hash"
+)
+
+// Statements shared by the web refresh token queries
+const (
+	insertWebRefreshTokenQuery = `
+		INSERT INTO web_refresh_tokens (token_id, user_id, token_hash, expires_at, created_at, auth_time, session_id, is_revoked, user_agent, ip_address)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, NULLIF($9, ''), NULLIF($10, ''))
+	`
+
+	selectWebRefreshTokenColumns = `
+		SELECT token_id, user_id, token_hash, expires_at, created_at, auth_time,
+			COALESCE(session_id, ''), is_revoked, COALESCE(user_agent, ''), COALESCE(ip_address, '')
+		FROM web_refresh_tokens
+	`
+)
+
+// authRepository implements the auth.TokenStore interface using PostgreSQL.
+// Unlike the Redis store, revoked and expired tokens stay in the table until
+// DeleteExpiredTokens runs, so they remain available for auditing.
+type authRepository struct {
+	db DB
+}
+
+// NewAuthRepository creates a PostgreSQL-based store for web refresh tokens.
+// It takes a database connection and returns an auth.TokenStore interface.
+func NewAuthRepository(db DB) auth.TokenStore {
+	return &authRepository{db: db}
+}
+
+// SaveRefreshToken stores a new refresh token. The token is already hashed.
+func (r *authRepository) SaveRefreshToken(ctx context.Context, token *auth.RefreshToken) error {
+	if err := saveWebRefreshToken(ctx, r.db, token); err != nil {
+		return errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToSaveRefreshToken, err.Error()))
+	}
+	return nil
+}
+
+// FindRefreshToken looks up a refresh token by ID.
+// Returns nil if the token doesn't exist.
+func (r *authRepository) FindRefreshToken(ctx context.Context, tokenID string) (*auth.RefreshToken, error) {
+	row := r.db.QueryRowContext(ctx, selectWebRefreshTokenColumns+" WHERE token_id = $1", tokenID)
+
+	token, err := scanWebRefreshToken(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToFindRefreshToken, err.Error()))
+	}
+
+	return token, nil
+}
+
+// FindRefreshTokenByToken looks up an unexpired refresh token by its plain text value.
+// Like the Redis store, this compares the value against each stored hash, newest first.
+// Returns nil if the token doesn't exist.
+func (r *authRepository) FindRefreshTokenByToken(ctx context.Context, plainTextToken string) (*auth.RefreshToken, error) {
+	rows, err := r.db.QueryContext(ctx, selectWebRefreshTokenColumns+" WHERE expires_at > NOW() ORDER BY created_at DESC")
+	if err != nil {
+		return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToFindRefreshToken, err.Error()))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		token, err := scanWebRefreshToken(rows)
+		if err != nil {
+			return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToScanRefreshToken, err.Error()))
+		}
+
+		// Order matters: first param is the hash, second is the plaintext
+		if err := hash.CompareHashAndPassword(token.Token, plainTextToken); err == nil {
+			return token, nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgErrorIteratingRefreshTokens, err.Error()))
+	}
+
+	return nil, nil
+}
+
+// RevokeRefreshToken marks a refresh token as revoked, keeping the time it was first revoked.
+func (r *authRepository) RevokeRefreshToken(ctx context.Context, tokenID string) error {
+	query := `
+		UPDATE web_refresh_tokens
+		SET is_revoked = TRUE, revoked_at = COALESCE(revoked_at, NOW())
+		WHERE token_id = $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query, tokenID)
+	if err != nil {
+		return errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToRevokeRefreshToken, err.Error()))
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToGetAffectedRows, err.Error()))
+	}
+	if rowsAffected == 0 {
+		return errors.NotFound(errors.ErrMsgRefreshTokenNotFound)
+	}
+
+	return nil
+}
+
+// RotateRefreshToken revokes the old token and saves its successor in one transaction. The
+// revocation only matches a token that is still unrevoked and unexpired, and the row lock it
+// takes makes a concurrent rotation of the same token wait and then match nothing.
+func (r *authRepository) RotateRefreshToken(ctx context.Context, oldTokenID string, newToken *auth.RefreshToken) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToRotateRefreshToken, err.Error()))
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE web_refresh_tokens
+		SET is_revoked = TRUE, revoked_at = NOW()
+		WHERE token_id = $1 AND is_revoked = FALSE AND expires_at > NOW()
+	`
+	result, err := tx.ExecContext(ctx, query, oldTokenID)
+	if err != nil {
+		return false, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToRotateRefreshToken, err.Error()))
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToGetAffectedRows, err.Error()))
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+
+	if err := saveWebRefreshToken(ctx, tx, newToken); err != nil {
+		return false, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToRotateRefreshToken, err.Error()))
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToRotateRefreshToken, err.Error()))
+	}
+
+	return true, nil
+}
+
+// RevokeAllUserRefreshTokens revokes all refresh tokens for a user.
+func (r *authRepository) RevokeAllUserRefreshTokens(ctx context.Context, userID uint) error {
+	query := `
+		UPDATE web_refresh_tokens
+		SET is_revoked = TRUE, revoked_at = NOW()
+		WHERE user_id = $1 AND is_revoked = FALSE
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, userID); err != nil {
+		return errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToRevokeRefreshTokens, err.Error()))
+	}
+
+	return nil
+}
+
+// DeleteExpiredTokens removes tokens whose lifetime has ended.
+func (r *authRepository) DeleteExpiredTokens(ctx context.Context) error {
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM web_refresh_tokens WHERE expires_at < NOW()"); err != nil {
+		return errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToDeleteExpiredTokens, err.Error()))
+	}
+	return nil
+}
+
+// IsRefreshTokenRevoked checks if a refresh token has been revoked.
+// A missing token counts as revoked.
+func (r *authRepository) IsRefreshTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
+	token, err := r.FindRefreshToken(ctx, tokenID)
+	if err != nil {
+		return false, err
+	}
+	if token == nil {
+		return true, nil
+	}
+	return token.IsRevoked, nil
+}
+
+// DeleteSessionRefreshTokens removes the user's refresh tokens that belong to the session.
+func (r *authRepository) DeleteSessionRefreshTokens(ctx context.Context, userID uint, sessionID string) error {
+	query := "DELETE FROM web_refresh_tokens WHERE user_id = $1 AND session_id = $2"
+
+	if _, err := r.db.ExecContext(ctx, query, userID, sessionID); err != nil {
+		return errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToDeleteRefreshToken, err.Error()))
+	}
+	return nil
+}
+
+// webRefreshTokenExecer is satisfied by both DB and *sql.Tx.
+type webRefreshTokenExecer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// saveWebRefreshToken inserts a web refresh token through db or a transaction.
+func saveWebRefreshToken(ctx context.Context, db webRefreshTokenExecer, token *auth.RefreshToken) error {
+	var authTime sql.NullTime
+	if !token.AuthTime.IsZero() {
+		authTime = sql.NullTime{Time: token.AuthTime, Valid: true}
+	}
+
+	_, err := db.ExecContext(ctx, insertWebRefreshTokenQuery,
+		token.ID,
+		token.UserID,
+		token.Token,
+		token.ExpiresAt,
+		token.CreatedAt,
+		authTime,
+		token.SessionID,
+		token.IsRevoked,
+		token.UserAgent,
+		token.IPAddress,
+	)
+	return err
+}
+
+// webRefreshTokenScanner is satisfied by both *sql.Row and *sql.Rows.
+type webRefreshTokenScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanWebRefreshToken reads a row selected with selectWebRefreshTokenColumns.
+func scanWebRefreshToken(row webRefreshTokenScanner) (*auth.RefreshToken, error) {
+	var token auth.RefreshToken
+	var authTime sql.NullTime
+
+	err := row.Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Token,
+		&token.ExpiresAt,
+		&token.CreatedAt,
+		&authTime,
+		&token.SessionID,
+		&token.IsRevoked,
+		&token.UserAgent,
+		&token.IPAddress,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if authTime.Valid {
+		token.AuthTime = authTime.Time
+	}
+	return &token, nil
+}
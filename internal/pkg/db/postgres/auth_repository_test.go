@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/verigate/verigate-server/internal/app/auth/authtest"
+)
+
+// testDSNEnv names the environment variable holding the connection string of a disposable,
+// migrated database for the repository tests, which are skipped when it is unset.
+const testDSNEnv = "TEST_POSTGRES_DSN"
+
+// newTestDB connects to the test database, skipping the test when none is configured.
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+	db, err := connect(dsn)
+	if err != nil {
+		t.Fatalf("failed to connect to the test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestAuthRepositoryConformance(t *testing.T) {
+	db := newTestDB(t)
+
+	authtest.RunTokenStoreTests(t, func(t *testing.T) authtest.Store {
+		// Every test starts from an empty table, with the users the suite issues tokens to
+		if _, err := db.Exec("TRUNCATE web_refresh_tokens"); err != nil {
+			t.Fatalf("failed to empty web_refresh_tokens: %v", err)
+		}
+		for _, userID := range []uint{authtest.UserID, authtest.OtherUserID} {
+			_, err := db.Exec(`
+				INSERT INTO users (id, username, email, password_hash)
+				VALUES ($1, $2, $3, 'unused')
+				ON CONFLICT DO NOTHING
+			`, userID, fmt.Sprintf("authtest-%d", userID), fmt.Sprintf("authtest-%d@example.com", userID))
+			if err != nil {
+				t.Fatalf("failed to create user %d: %v", userID, err)
+			}
+		}
+		return authtest.Store{TokenStore: NewAuthRepository(db)}
+	})
+}
@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/app/cleanup"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+)
+
+// deleteExpiredQueries holds the batched delete of each purged table. The table name cannot be
+// a query parameter, so only the tables listed here are ever interpolated into a statement.
+var deleteExpiredQueries = func() map[string]string {
+	queries := make(map[string]string, len(cleanup.Tables))
+	for _, table := range cleanup.Tables {
+		queries[table] = `
+			DELETE FROM ` + table + `
+			WHERE ctid IN (
+				SELECT ctid FROM ` + table + `
+				WHERE expires_at < $1
+				LIMIT $2
+				FOR UPDATE SKIP LOCKED
+			)
+		`
+	}
+	return queries
+}()
+
+// cleanupRepository implements the cleanup.Repository interface using PostgreSQL.
+type cleanupRepository struct {
+	db DB
+}
+
+// NewCleanupRepository creates a new PostgreSQL-based repository for purging expired rows.
+// It takes a database connection and returns a cleanup.Repository interface.
+func NewCleanupRepository(db DB) cleanup.Repository {
+	return &cleanupRepository{db: db}
+}
+
+// DeleteExpired deletes up to limit rows of table that expired before cutoff. Rows are picked
+// by ctid with SKIP LOCKED, so the batch never waits on rows a transaction is updating.
+func (r *cleanupRepository) DeleteExpired(ctx context.Context, table string, cutoff time.Time, limit int) (int64, error) {
+	query, ok := deleteExpiredQueries[table]
+	if !ok {
+		return 0, errors.Internal(errors.ErrMsgFailedToPurgeExpired).WithDetails("unknown table: " + table)
+	}
+
+	result, err := r.db.ExecContext(ctx, query, cutoff, limit)
+	if err != nil {
+		return 0, errors.Internal(errors.ErrMsgFailedToPurgeExpired).Wrap(err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.Internal(errors.ErrMsgFailedToGetAffectedRows).Wrap(err)
+	}
+	return rows, nil
+}
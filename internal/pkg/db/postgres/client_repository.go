@@ -4,7 +4,10 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/lib/pq"
 	"github.com/verigate/verigate-server/internal/app/client"
@@ -13,12 +16,12 @@ import (
 
 // clientRepository implements the client.Repository interface using PostgreSQL.
 type clientRepository struct {
-	db *sql.DB
+	db DB
 }
 
 // NewClientRepository creates a new PostgreSQL-based client repository.
 // It takes a database connection and returns a client.Repository interface.
-func NewClientRepository(db *sql.DB) client.Repository {
+func NewClientRepository(db DB) client.Repository {
 	return &clientRepository{db: db}
 }
 
@@ -31,13 +34,29 @@ func (r *clientRepository) Save(ctx context.Context, client *client.Client) erro
 			client_id, client_secret, client_name, description, client_uri, logo_uri,
 			redirect_uris, grant_types, response_types, scope, tos_uri, policy_uri,
 			jwks_uri, jwks, contacts, software_id, software_version,
-			is_confidential, is_active, created_at, updated_at, owner_id
+			is_confidential, require_pkce, token_endpoint_auth_method, is_active, created_at, updated_at, owner_id,
+			registration_access_token_hash, access_token_format, allowed_resources,
+			id_token_encrypted_response_alg, id_token_encrypted_response_enc, backchannel_logout_uri,
+			frontchannel_logout_uri, post_logout_redirect_uris, subject_type, sector_identifier_uri,
+			access_token_lifetime, refresh_token_lifetime, id_token_lifetime, request_uris,
+			require_pushed_authorization_requests, bind_token_to_ip, tls_client_auth_subject_dn,
+			tls_client_certificate_bound_access_tokens, managed_state, id_token_signed_response_alg,
+			id_token_signing_secret, claim_templates, rate_limit_tier, userinfo_signed_response_alg,
+			userinfo_encrypted_response_alg, userinfo_encrypted_response_enc
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, NULLIF($23, 0),
+			NULLIF($24, ''), NULLIF($25, ''), $26, NULLIF($27, ''), NULLIF($28, ''), NULLIF($29, ''),
+			NULLIF($30, ''), $31, $32, NULLIF($33, ''), $34, $35, $36, $37, $38, $39, NULLIF($40, ''), $41, $42, $43, $44, $45, $46,
+			$47, $48, $49
 		) RETURNING id
 	`
 
-	err := r.db.QueryRowContext(ctx, query,
+	claimTemplates, err := encodeClaimTemplates(client.ClaimTemplates)
+	if err != nil {
+		return err
+	}
+
+	err = r.db.QueryRowContext(ctx, query,
 		client.ClientID,
 		client.ClientSecret,
 		client.ClientName,
@@ -56,10 +75,38 @@ func (r *clientRepository) Save(ctx context.Context, client *client.Client) erro
 		client.SoftwareID,
 		client.SoftwareVersion,
 		client.IsConfidential,
+		client.RequirePKCE,
+		client.TokenEndpointAuthMethod,
 		client.IsActive,
 		client.CreatedAt,
 		client.UpdatedAt,
 		client.OwnerID,
+		client.RegistrationAccessToken,
+		client.AccessTokenFormat,
+		pq.Array(client.AllowedResources),
+		client.IDTokenEncryptedResponseAlg,
+		client.IDTokenEncryptedResponseEnc,
+		client.BackchannelLogoutURI,
+		client.FrontchannelLogoutURI,
+		pq.Array(client.PostLogoutRedirectURIs),
+		client.SubjectType,
+		client.SectorIdentifierURI,
+		client.AccessTokenLifetime,
+		client.RefreshTokenLifetime,
+		client.IDTokenLifetime,
+		pq.Array(client.RequestURIs),
+		client.RequirePushedAuthRequests,
+		client.BindTokenToIP,
+		client.TLSClientAuthSubjectDN,
+		client.CertificateBoundTokens,
+		client.ManagedState,
+		client.IDTokenSignedResponseAlg,
+		client.IDTokenSigningSecret,
+		claimTemplates,
+		client.RateLimitTier,
+		client.UserInfoSigningAlg,
+		client.UserInfoEncryptionAlg,
+		client.UserInfoEncryptionEnc,
 	).Scan(&client.ID)
 
 	if err != nil {
@@ -67,7 +114,7 @@ func (r *clientRepository) Save(ctx context.Context, client *client.Client) erro
 		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
 			return errors.Conflict(errors.ErrMsgClientIdAlreadyExists)
 		}
-		return errors.Internal(errors.ErrMsgFailedToCreateClient + ": " + err.Error())
+		return errors.Internal(errors.ErrMsgFailedToCreateClient).Wrap(err)
 	}
 
 	return nil
@@ -83,10 +130,26 @@ func (r *clientRepository) Update(ctx context.Context, client *client.Client) er
 			redirect_uris = $6, grant_types = $7, response_types = $8, scope = $9,
 			tos_uri = $10, policy_uri = $11, jwks_uri = $12, jwks = $13,
 			contacts = $14, software_id = $15, software_version = $16,
-			updated_at = $17
+			require_pkce = $17, token_endpoint_auth_method = $18, updated_at = $19,
+			access_token_format = NULLIF($20, ''), allowed_resources = $21,
+			id_token_encrypted_response_alg = NULLIF($22, ''), id_token_encrypted_response_enc = NULLIF($23, ''),
+			backchannel_logout_uri = NULLIF($24, ''), frontchannel_logout_uri = NULLIF($25, ''),
+			post_logout_redirect_uris = $26, subject_type = $27, sector_identifier_uri = NULLIF($28, ''),
+			access_token_lifetime = $29, refresh_token_lifetime = $30, id_token_lifetime = $31,
+			request_uris = $32, require_pushed_authorization_requests = $33,
+			bind_token_to_ip = $34, tls_client_auth_subject_dn = NULLIF($35, ''),
+			tls_client_certificate_bound_access_tokens = $36, managed_state = $37,
+			id_token_signed_response_alg = $38, claim_templates = $39, rate_limit_tier = $40,
+			userinfo_signed_response_alg = $41, userinfo_encrypted_response_alg = $42,
+			userinfo_encrypted_response_enc = $43
 		WHERE id = $1
 	`
 
+	claimTemplates, err := encodeClaimTemplates(client.ClaimTemplates)
+	if err != nil {
+		return err
+	}
+
 	result, err := r.db.ExecContext(ctx, query,
 		client.ID,
 		client.ClientName,
@@ -104,16 +167,42 @@ func (r *clientRepository) Update(ctx context.Context, client *client.Client) er
 		pq.Array(client.Contacts),
 		client.SoftwareID,
 		client.SoftwareVersion,
+		client.RequirePKCE,
+		client.TokenEndpointAuthMethod,
 		client.UpdatedAt,
+		client.AccessTokenFormat,
+		pq.Array(client.AllowedResources),
+		client.IDTokenEncryptedResponseAlg,
+		client.IDTokenEncryptedResponseEnc,
+		client.BackchannelLogoutURI,
+		client.FrontchannelLogoutURI,
+		pq.Array(client.PostLogoutRedirectURIs),
+		client.SubjectType,
+		client.SectorIdentifierURI,
+		client.AccessTokenLifetime,
+		client.RefreshTokenLifetime,
+		client.IDTokenLifetime,
+		pq.Array(client.RequestURIs),
+		client.RequirePushedAuthRequests,
+		client.BindTokenToIP,
+		client.TLSClientAuthSubjectDN,
+		client.CertificateBoundTokens,
+		client.ManagedState,
+		client.IDTokenSignedResponseAlg,
+		claimTemplates,
+		client.RateLimitTier,
+		client.UserInfoSigningAlg,
+		client.UserInfoEncryptionAlg,
+		client.UserInfoEncryptionEnc,
 	)
 
 	if err != nil {
-		return errors.Internal(errors.ErrMsgFailedToUpdateClient + ": " + err.Error())
+		return errors.Internal(errors.ErrMsgFailedToUpdateClient).Wrap(err)
 	}
 
 	rows, err := result.RowsAffected()
 	if err != nil {
-		return errors.Internal(errors.ErrMsgFailedToGetAffectedRows + ": " + err.Error())
+		return errors.Internal(errors.ErrMsgFailedToGetAffectedRows).Wrap(err)
 	}
 
 	if rows == 0 {
@@ -123,19 +212,35 @@ func (r *clientRepository) Update(ctx context.Context, client *client.Client) er
 	return nil
 }
 
-// FindByID retrieves an OAuth client from the PostgreSQL database by its internal ID.
-// Returns the client if found, nil if the client doesn't exist, or an error if the query fails.
-func (r *clientRepository) FindByID(ctx context.Context, id uint) (*client.Client, error) {
-	var c client.Client
-	query := `
-		SELECT id, client_id, client_secret, client_name, description, client_uri, logo_uri,
-		       redirect_uris, grant_types, response_types, scope, tos_uri, policy_uri,
-		       jwks_uri, jwks, contacts, software_id, software_version,
-		       is_confidential, is_active, created_at, updated_at, owner_id
-		FROM clients WHERE id = $1
-	`
+// clientColumns lists the columns every client query selects, in the order scanClient reads them.
+const clientColumns = `
+		id, client_id, client_secret, client_name, description, client_uri, logo_uri,
+		redirect_uris, grant_types, response_types, scope, tos_uri, policy_uri,
+		jwks_uri, jwks, contacts, software_id, software_version,
+		is_confidential, require_pkce, token_endpoint_auth_method, is_active, created_at, updated_at,
+		COALESCE(owner_id, 0), COALESCE(registration_access_token_hash, ''),
+		COALESCE(access_token_format, ''), allowed_resources,
+		COALESCE(id_token_encrypted_response_alg, ''), COALESCE(id_token_encrypted_response_enc, ''),
+		COALESCE(backchannel_logout_uri, ''), COALESCE(frontchannel_logout_uri, ''), post_logout_redirect_uris,
+		subject_type, COALESCE(sector_identifier_uri, ''),
+		access_token_lifetime, refresh_token_lifetime, id_token_lifetime, request_uris,
+		require_pushed_authorization_requests, bind_token_to_ip, COALESCE(tls_client_auth_subject_dn, ''),
+		tls_client_certificate_bound_access_tokens, managed_state, id_token_signed_response_alg,
+		id_token_signing_secret, previous_client_secret, previous_client_secret_expires_at, claim_templates,
+		rate_limit_tier, userinfo_signed_response_alg, userinfo_encrypted_response_alg,
+		userinfo_encrypted_response_enc`
+
+// clientScanner is a single row of a client query, from QueryRowContext or QueryContext.
+type clientScanner interface {
+	Scan(dest ...interface{}) error
+}
 
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+// scanClient reads a row selected with clientColumns.
+func scanClient(row clientScanner) (*client.Client, error) {
+	var c client.Client
+	var previousSecretExpiresAt sql.NullTime
+	var claimTemplates []byte
+	err := row.Scan(
 		&c.ID,
 		&c.ClientID,
 		&c.ClientSecret,
@@ -155,68 +260,97 @@ func (r *clientRepository) FindByID(ctx context.Context, id uint) (*client.Clien
 		&c.SoftwareID,
 		&c.SoftwareVersion,
 		&c.IsConfidential,
+		&c.RequirePKCE,
+		&c.TokenEndpointAuthMethod,
 		&c.IsActive,
 		&c.CreatedAt,
 		&c.UpdatedAt,
 		&c.OwnerID,
+		&c.RegistrationAccessToken,
+		&c.AccessTokenFormat,
+		pq.Array(&c.AllowedResources),
+		&c.IDTokenEncryptedResponseAlg,
+		&c.IDTokenEncryptedResponseEnc,
+		&c.BackchannelLogoutURI,
+		&c.FrontchannelLogoutURI,
+		pq.Array(&c.PostLogoutRedirectURIs),
+		&c.SubjectType,
+		&c.SectorIdentifierURI,
+		&c.AccessTokenLifetime,
+		&c.RefreshTokenLifetime,
+		&c.IDTokenLifetime,
+		pq.Array(&c.RequestURIs),
+		&c.RequirePushedAuthRequests,
+		&c.BindTokenToIP,
+		&c.TLSClientAuthSubjectDN,
+		&c.CertificateBoundTokens,
+		&c.ManagedState,
+		&c.IDTokenSignedResponseAlg,
+		&c.IDTokenSigningSecret,
+		&c.PreviousClientSecret,
+		&previousSecretExpiresAt,
+		&claimTemplates,
+		&c.RateLimitTier,
+		&c.UserInfoSigningAlg,
+		&c.UserInfoEncryptionAlg,
+		&c.UserInfoEncryptionEnc,
 	)
+	if err != nil {
+		return nil, err
+	}
+	if previousSecretExpiresAt.Valid {
+		c.PreviousSecretExpiresAt = &previousSecretExpiresAt.Time
+	}
+	if len(claimTemplates) > 0 {
+		if err := json.Unmarshal(claimTemplates, &c.ClaimTemplates); err != nil {
+			return nil, err
+		}
+	}
+	return &c, nil
+}
+
+// encodeClaimTemplates marshals a client's claim templates for the claim_templates column.
+func encodeClaimTemplates(templates []client.ClaimTemplate) ([]byte, error) {
+	if len(templates) == 0 {
+		return []byte("[]"), nil
+	}
+	data, err := json.Marshal(templates)
+	if err != nil {
+		return nil, errors.Internal(errors.ErrMsgFailedToEncodeClaimTemplates).Wrap(err)
+	}
+	return data, nil
+}
+
+// FindByID retrieves an OAuth client from the PostgreSQL database by its internal ID.
+// Returns the client if found, nil if the client doesn't exist, or an error if the query fails.
+func (r *clientRepository) FindByID(ctx context.Context, id uint) (*client.Client, error) {
+	query := "SELECT " + clientColumns + " FROM clients WHERE id = $1"
 
+	c, err := scanClient(r.db.QueryRowContext(replicaRead(ctx), query, id))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
-		return nil, errors.Internal(errors.ErrMsgFailedToGetClientByID + ": " + err.Error())
+		return nil, errors.Internal(errors.ErrMsgFailedToGetClientByID).Wrap(err)
 	}
 
-	return &c, nil
+	return c, nil
 }
 
 // FindByClientID retrieves an OAuth client from the PostgreSQL database by its client ID (public identifier).
 // Returns the client if found, nil if the client doesn't exist, or an error if the query fails.
 func (r *clientRepository) FindByClientID(ctx context.Context, clientID string) (*client.Client, error) {
-	var c client.Client
-	query := `
-		SELECT id, client_id, client_secret, client_name, description, client_uri, logo_uri,
-		       redirect_uris, grant_types, response_types, scope, tos_uri, policy_uri,
-		       jwks_uri, jwks, contacts, software_id, software_version,
-		       is_confidential, is_active, created_at, updated_at, owner_id
-		FROM clients WHERE client_id = $1
-	`
-
-	err := r.db.QueryRowContext(ctx, query, clientID).Scan(
-		&c.ID,
-		&c.ClientID,
-		&c.ClientSecret,
-		&c.ClientName,
-		&c.Description,
-		&c.ClientURI,
-		&c.LogoURI,
-		pq.Array(&c.RedirectURIs),
-		pq.Array(&c.GrantTypes),
-		pq.Array(&c.ResponseTypes),
-		&c.Scope,
-		&c.TOSUri,
-		&c.PolicyURI,
-		&c.JwksURI,
-		&c.Jwks,
-		pq.Array(&c.Contacts),
-		&c.SoftwareID,
-		&c.SoftwareVersion,
-		&c.IsConfidential,
-		&c.IsActive,
-		&c.CreatedAt,
-		&c.UpdatedAt,
-		&c.OwnerID,
-	)
+	query := "SELECT " + clientColumns + " FROM clients WHERE client_id = $1"
 
+	c, err := scanClient(r.db.QueryRowContext(replicaRead(ctx), query, clientID))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
 	if err != nil {
-		return nil, errors.Internal(errors.ErrMsgFailedToGetClientByClientID + ": " + err.Error())
+		return nil, errors.Internal(errors.ErrMsgFailedToGetClientByClientID).Wrap(err)
 	}
 
-	return &c, nil
+	return c, nil
 }
 
 // FindByOwnerID retrieves a paginated list of OAuth clients owned by a specific user.
@@ -229,15 +363,11 @@ func (r *clientRepository) FindByOwnerID(ctx context.Context, ownerID uint, page
 	var total int64
 	countQuery := "SELECT COUNT(*) FROM clients WHERE owner_id = $1"
 	if err := r.db.QueryRowContext(ctx, countQuery, ownerID).Scan(&total); err != nil {
-		return nil, 0, errors.Internal(errors.ErrMsgFailedToCountClients + ": " + err.Error())
+		return nil, 0, errors.Internal(errors.ErrMsgFailedToCountClients).Wrap(err)
 	}
 
 	// Get clients with pagination
-	query := `
-		SELECT id, client_id, client_secret, client_name, description, client_uri, logo_uri,
-		       redirect_uris, grant_types, response_types, scope, tos_uri, policy_uri,
-		       jwks_uri, jwks, contacts, software_id, software_version,
-		       is_confidential, is_active, created_at, updated_at, owner_id
+	query := "SELECT " + clientColumns + `
 		FROM clients
 		WHERE owner_id = $1
 		ORDER BY created_at DESC
@@ -246,48 +376,122 @@ func (r *clientRepository) FindByOwnerID(ctx context.Context, ownerID uint, page
 
 	rows, err := r.db.QueryContext(ctx, query, ownerID, limit, offset)
 	if err != nil {
-		return nil, 0, errors.Internal(errors.ErrMsgFailedToRetrieveClientsByOwnerID + ": " + err.Error())
+		return nil, 0, errors.Internal(errors.ErrMsgFailedToRetrieveClientsByOwnerID).Wrap(err)
+	}
+	defer rows.Close()
+
+	clients, err := scanClients(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return clients, total, nil
+}
+
+// Search retrieves a paginated list of the OAuth clients matching the filter, newest first.
+// It returns the clients, total count of matching clients, and any error that occurred.
+// The page parameter is 1-indexed (first page is 1, not 0).
+func (r *clientRepository) Search(ctx context.Context, filter client.ClientFilter, page, limit int) ([]client.Client, int64, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.OwnerID != 0 {
+		args = append(args, filter.OwnerID)
+		conditions = append(conditions, fmt.Sprintf("owner_id = $%d", len(args)))
+	}
+	if filter.Query != "" {
+		args = append(args, "%"+escapeLikePattern(filter.Query)+"%")
+		conditions = append(conditions, fmt.Sprintf("(client_name ILIKE $%d OR client_id ILIKE $%d)", len(args), len(args)))
+	}
+	if filter.IsActive != nil {
+		args = append(args, *filter.IsActive)
+		conditions = append(conditions, fmt.Sprintf("is_active = $%d", len(args)))
+	}
+	if filter.IsConfidential != nil {
+		args = append(args, *filter.IsConfidential)
+		conditions = append(conditions, fmt.Sprintf("is_confidential = $%d", len(args)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int64
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM clients"+where, args...).Scan(&total); err != nil {
+		return nil, 0, errors.Internal(errors.ErrMsgFailedToCountClients).Wrap(err)
+	}
+
+	args = append(args, limit, (page-1)*limit)
+	query := "SELECT " + clientColumns + " FROM clients" + where +
+		fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, errors.Internal(errors.ErrMsgFailedToSearchClients).Wrap(err)
 	}
 	defer rows.Close()
 
+	clients, err := scanClients(rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return clients, total, nil
+}
+
+// scanClients reads every row of a query selecting clientColumns.
+func scanClients(rows *sql.Rows) ([]client.Client, error) {
 	var clients []client.Client
 	for rows.Next() {
-		var c client.Client
-		if err := rows.Scan(
-			&c.ID,
-			&c.ClientID,
-			&c.ClientSecret,
-			&c.ClientName,
-			&c.Description,
-			&c.ClientURI,
-			&c.LogoURI,
-			pq.Array(&c.RedirectURIs),
-			pq.Array(&c.GrantTypes),
-			pq.Array(&c.ResponseTypes),
-			&c.Scope,
-			&c.TOSUri,
-			&c.PolicyURI,
-			&c.JwksURI,
-			&c.Jwks,
-			pq.Array(&c.Contacts),
-			&c.SoftwareID,
-			&c.SoftwareVersion,
-			&c.IsConfidential,
-			&c.IsActive,
-			&c.CreatedAt,
-			&c.UpdatedAt,
-			&c.OwnerID,
-		); err != nil {
-			return nil, 0, errors.Internal(errors.ErrMsgFailedToScanClientData + ": " + err.Error())
+		c, err := scanClient(rows)
+		if err != nil {
+			return nil, errors.Internal(errors.ErrMsgFailedToScanClientData).Wrap(err)
 		}
-		clients = append(clients, c)
+		clients = append(clients, *c)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, 0, errors.Internal(errors.ErrMsgErrorIteratingClientResults + ": " + err.Error())
+		return nil, errors.Internal(errors.ErrMsgErrorIteratingClientResults).Wrap(err)
 	}
 
-	return clients, total, nil
+	return clients, nil
+}
+
+// UpdateSecret replaces the secret hash and sealed ID token signing secret of an OAuth client
+// in the PostgreSQL database, keeping the previous hash until previousExpiresAt.
+// Returns NotFound error if the client doesn't exist, or Internal error if the update fails.
+func (r *clientRepository) UpdateSecret(ctx context.Context, id uint, secretHash, signingSecret, previousHash string, previousExpiresAt *time.Time) error {
+	query := `
+		UPDATE clients
+		SET client_secret = $2, id_token_signing_secret = $3,
+		    previous_client_secret = $4, previous_client_secret_expires_at = $5,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+
+	var expiresAt sql.NullTime
+	if previousExpiresAt != nil {
+		expiresAt = sql.NullTime{Time: *previousExpiresAt, Valid: true}
+	}
+
+	result, err := r.db.ExecContext(ctx, query, id, secretHash, signingSecret, previousHash, expiresAt)
+	if err != nil {
+		return errors.Internal(errors.ErrMsgFailedToUpdateClientSecret).Wrap(err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return errors.Internal(errors.ErrMsgFailedToGetAffectedRows).Wrap(err)
+	}
+
+	if rows == 0 {
+		return errors.NotFound(fmt.Sprintf(errors.ErrMsgClientWithIDNotFound, id))
+	}
+
+	return nil
+}
+
+// escapeLikePattern escapes the LIKE wildcards in s, so it matches only itself.
+func escapeLikePattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
 }
 
 // Delete removes an OAuth client from the PostgreSQL database by its ID.
@@ -297,12 +501,12 @@ func (r *clientRepository) Delete(ctx context.Context, id uint) error {
 
 	result, err := r.db.ExecContext(ctx, query, id)
 	if err != nil {
-		return errors.Internal(errors.ErrMsgFailedToDeleteClient + ": " + err.Error())
+		return errors.Internal(errors.ErrMsgFailedToDeleteClient).Wrap(err)
 	}
 
 	rows, err := result.RowsAffected()
 	if err != nil {
-		return errors.Internal(errors.ErrMsgFailedToGetAffectedRows + ": " + err.Error())
+		return errors.Internal(errors.ErrMsgFailedToGetAffectedRows).Wrap(err)
 	}
 
 	if rows == 0 {
@@ -324,12 +528,12 @@ func (r *clientRepository) UpdateStatus(ctx context.Context, id uint, isActive b
 
 	result, err := r.db.ExecContext(ctx, query, id, isActive)
 	if err != nil {
-		return errors.Internal(errors.ErrMsgFailedToUpdateClientStatus + ": " + err.Error())
+		return errors.Internal(errors.ErrMsgFailedToUpdateClientStatus).Wrap(err)
 	}
 
 	rows, err := result.RowsAffected()
 	if err != nil {
-		return errors.Internal(errors.ErrMsgFailedToGetAffectedRows + ": " + err.Error())
+		return errors.Internal(errors.ErrMsgFailedToGetAffectedRows).Wrap(err)
 	}
 
 	if rows == 0 {
@@ -338,3 +542,24 @@ func (r *clientRepository) UpdateStatus(ctx context.Context, id uint, isActive b
 
 	return nil
 }
+
+// HasRedirectURIOrigin checks whether an active client has registered a redirect URI
+// whose origin (scheme, host, and port) equals the given origin.
+// This supports CORS checks for browser-based clients calling the OAuth endpoints.
+func (r *clientRepository) HasRedirectURIOrigin(ctx context.Context, origin string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM clients, unnest(redirect_uris) AS uri
+			WHERE is_active = true
+			  AND lower(substring(uri from '^[a-zA-Z][a-zA-Z0-9+.-]*://[^/?#]+')) = lower($1)
+		)
+	`
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, query, origin).Scan(&exists); err != nil {
+		return false, errors.Internal(errors.ErrMsgFailedToCheckClientOrigin).Wrap(err)
+	}
+
+	return exists, nil
+}
@@ -0,0 +1,239 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/app/logout"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+)
+
+// logoutRepository implements the logout.Repository interface using PostgreSQL.
+type logoutRepository struct {
+	db DB
+}
+
+// NewLogoutRepository creates a new PostgreSQL-based back-channel logout repository.
+// It takes a database connection and returns a logout.Repository interface.
+func NewLogoutRepository(db DB) logout.Repository {
+	return &logoutRepository{db: db}
+}
+
+// SaveSession records a client session in the PostgreSQL database.
+// A session recorded again, such as after a token refresh, keeps its creation time and gets the new expiry.
+func (r *logoutRepository) SaveSession(ctx context.Context, session *logout.RPSession) error {
+	query := `
+		INSERT INTO rp_sessions (client_id, user_id, session_id, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (client_id, user_id, session_id) DO UPDATE SET expires_at = EXCLUDED.expires_at
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		session.ClientID,
+		session.UserID,
+		session.SessionID,
+		session.CreatedAt,
+		session.ExpiresAt,
+	)
+	if err != nil {
+		return errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToSaveRPSession, err.Error()))
+	}
+
+	return nil
+}
+
+// FindSessionsByUser retrieves the unexpired client sessions of a user from the PostgreSQL database.
+func (r *logoutRepository) FindSessionsByUser(ctx context.Context, userID uint) ([]logout.RPSession, error) {
+	return r.findSessions(ctx, `
+		SELECT client_id, user_id, session_id, created_at, expires_at
+		FROM rp_sessions
+		WHERE user_id = $1 AND expires_at > $2
+	`, userID, time.Now())
+}
+
+// FindSessionsBySession retrieves the unexpired client sessions of a user within one web session
+// from the PostgreSQL database.
+func (r *logoutRepository) FindSessionsBySession(ctx context.Context, userID uint, sessionID string) ([]logout.RPSession, error) {
+	return r.findSessions(ctx, `
+		SELECT client_id, user_id, session_id, created_at, expires_at
+		FROM rp_sessions
+		WHERE user_id = $1 AND session_id = $2 AND expires_at > $3
+	`, userID, sessionID, time.Now())
+}
+
+// findSessions runs a query selecting client sessions and scans the results.
+func (r *logoutRepository) findSessions(ctx context.Context, query string, args ...interface{}) ([]logout.RPSession, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToFindRPSessions, err.Error()))
+	}
+	defer rows.Close()
+
+	var sessions []logout.RPSession
+	for rows.Next() {
+		var s logout.RPSession
+		if err := rows.Scan(&s.ClientID, &s.UserID, &s.SessionID, &s.CreatedAt, &s.ExpiresAt); err != nil {
+			return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToFindRPSessions, err.Error()))
+		}
+		sessions = append(sessions, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToFindRPSessions, err.Error()))
+	}
+
+	return sessions, nil
+}
+
+// FindSessionUser retrieves the user of an unexpired client session from the PostgreSQL database.
+// It returns zero if the session is not recorded or has expired.
+func (r *logoutRepository) FindSessionUser(ctx context.Context, clientID, sessionID string) (uint, error) {
+	query := `
+		SELECT user_id
+		FROM rp_sessions
+		WHERE client_id = $1 AND session_id = $2 AND expires_at > $3
+		LIMIT 1
+	`
+
+	var userID uint
+	err := r.db.QueryRowContext(ctx, query, clientID, sessionID, time.Now()).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToFindRPSessions, err.Error()))
+	}
+
+	return userID, nil
+}
+
+// DeleteSessionsByUser removes all client sessions of a user from the PostgreSQL database.
+func (r *logoutRepository) DeleteSessionsByUser(ctx context.Context, userID uint) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM rp_sessions WHERE user_id = $1", userID)
+	if err != nil {
+		return errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToDeleteRPSessions, err.Error()))
+	}
+
+	return nil
+}
+
+// DeleteSessionsBySession removes the client sessions of a user within one web session
+// from the PostgreSQL database.
+func (r *logoutRepository) DeleteSessionsBySession(ctx context.Context, userID uint, sessionID string) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM rp_sessions WHERE user_id = $1 AND session_id = $2", userID, sessionID)
+	if err != nil {
+		return errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToDeleteRPSessions, err.Error()))
+	}
+
+	return nil
+}
+
+// SaveDelivery creates a new logout delivery in the PostgreSQL database and sets its generated ID.
+func (r *logoutRepository) SaveDelivery(ctx context.Context, delivery *logout.Delivery) error {
+	query := `
+		INSERT INTO logout_deliveries (user_id, client_id, session_id, status, attempts, last_error, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		delivery.UserID,
+		delivery.ClientID,
+		delivery.SessionID,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.LastError,
+		delivery.CreatedAt,
+		delivery.UpdatedAt,
+	).Scan(&delivery.ID)
+	if err != nil {
+		return errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToSaveLogoutDelivery, err.Error()))
+	}
+
+	return nil
+}
+
+// UpdateDelivery stores the status, attempt count, and last error of a logout delivery.
+func (r *logoutRepository) UpdateDelivery(ctx context.Context, delivery *logout.Delivery) error {
+	query := `
+		UPDATE logout_deliveries
+		SET status = $1, attempts = $2, last_error = $3, updated_at = $4
+		WHERE id = $5
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.LastError,
+		delivery.UpdatedAt,
+		delivery.ID,
+	)
+	if err != nil {
+		return errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToUpdateLogoutDelivery, err.Error()))
+	}
+
+	return nil
+}
+
+// ListDeliveries retrieves the most recent logout deliveries from the PostgreSQL database, newest first.
+// The status and user filters are only applied when set.
+func (r *logoutRepository) ListDeliveries(ctx context.Context, status string, userID uint, limit int) ([]logout.Delivery, error) {
+	var conditions []string
+	var args []interface{}
+	if status != "" {
+		args = append(args, status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if userID != 0 {
+		args = append(args, userID)
+		conditions = append(conditions, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	args = append(args, limit)
+	query := fmt.Sprintf(`
+		SELECT id, user_id, client_id, session_id, status, attempts, last_error, created_at, updated_at
+		FROM logout_deliveries
+		%s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d
+	`, where, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToListLogoutDeliveries, err.Error()))
+	}
+	defer rows.Close()
+
+	var deliveries []logout.Delivery
+	for rows.Next() {
+		var d logout.Delivery
+		if err := rows.Scan(
+			&d.ID,
+			&d.UserID,
+			&d.ClientID,
+			&d.SessionID,
+			&d.Status,
+			&d.Attempts,
+			&d.LastError,
+			&d.CreatedAt,
+			&d.UpdatedAt,
+		); err != nil {
+			return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToListLogoutDeliveries, err.Error()))
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToListLogoutDeliveries, err.Error()))
+	}
+
+	return deliveries, nil
+}
@@ -7,18 +7,19 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/verigate/verigate-server/internal/app/oauth"
 	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
 )
 
 // oauthRepository implements the oauth.Repository interface using PostgreSQL.
 type oauthRepository struct {
-	db *sql.DB
+	db DB
 }
 
 // NewOAuthRepository creates a new PostgreSQL-based OAuth repository.
 // It takes a database connection and returns an oauth.Repository interface.
-func NewOAuthRepository(db *sql.DB) oauth.Repository {
+func NewOAuthRepository(db DB) oauth.Repository {
 	return &oauthRepository{db: db}
 }
 
@@ -29,8 +30,9 @@ func (r *oauthRepository) SaveAuthorizationCode(ctx context.Context, code *oauth
 	query := `
 		INSERT INTO authorization_codes (
 			code, client_id, user_id, redirect_uri, scope,
-			code_challenge, code_challenge_method, expires_at, created_at, is_used
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			code_challenge, code_challenge_method, expires_at, created_at, is_used, auth_time, resources, session_id, nonce,
+			acr, amr, id_token_claims
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, NULLIF($13, ''), NULLIF($14, ''), NULLIF($15, ''), $16, $17)
 		RETURNING id
 	`
 
@@ -45,6 +47,13 @@ func (r *oauthRepository) SaveAuthorizationCode(ctx context.Context, code *oauth
 		code.ExpiresAt,
 		code.CreatedAt,
 		code.IsUsed,
+		code.AuthTime,
+		pq.Array(code.Resources),
+		code.SessionID,
+		code.Nonce,
+		code.ACR,
+		pq.Array(code.AMR),
+		pq.Array(code.IDTokenClaims),
 	).Scan(&code.ID)
 
 	if err != nil {
@@ -62,7 +71,10 @@ func (r *oauthRepository) FindAuthorizationCode(ctx context.Context, code string
 	var ac oauth.AuthorizationCode
 	query := `
 		SELECT id, code, client_id, user_id, redirect_uri, scope,
-		       code_challenge, code_challenge_method, expires_at, created_at, is_used
+		       code_challenge, code_challenge_method, expires_at, created_at, is_used,
+		       COALESCE(auth_time, created_at), COALESCE(resources, '{}'),
+		       COALESCE(session_id, ''), COALESCE(nonce, ''), replay_detected,
+		       COALESCE(acr, ''), COALESCE(amr, '{}'), COALESCE(id_token_claims, '{}')
 		FROM authorization_codes
 		WHERE code = $1
 	`
@@ -79,6 +91,14 @@ func (r *oauthRepository) FindAuthorizationCode(ctx context.Context, code string
 		&ac.ExpiresAt,
 		&ac.CreatedAt,
 		&ac.IsUsed,
+		&ac.AuthTime,
+		pq.Array(&ac.Resources),
+		&ac.SessionID,
+		&ac.Nonce,
+		&ac.ReplayDetected,
+		&ac.ACR,
+		pq.Array(&ac.AMR),
+		pq.Array(&ac.IDTokenClaims),
 	)
 
 	if err == sql.ErrNoRows {
@@ -91,29 +111,41 @@ func (r *oauthRepository) FindAuthorizationCode(ctx context.Context, code string
 	return &ac, nil
 }
 
-// MarkCodeAsUsed updates an authorization code to mark it as used.
-// Authorization codes are one-time use only, and this method is called
-// after a code has been successfully exchanged for a token.
-// Returns an error if the update fails.
-func (r *oauthRepository) MarkCodeAsUsed(ctx context.Context, code string) error {
+// MarkCodeAsUsed consumes an authorization code by marking it as used.
+// Authorization codes are one-time use only, so the update only applies to an unused code,
+// and of several concurrent exchanges of the same code exactly one consumes it.
+// Returns false if the code was already used, or an error if the update fails.
+func (r *oauthRepository) MarkCodeAsUsed(ctx context.Context, code string) (bool, error) {
 	query := `
 		UPDATE authorization_codes
 		SET is_used = true
-		WHERE code = $1
+		WHERE code = $1 AND is_used = false
 	`
 
 	result, err := r.db.ExecContext(ctx, query, code)
 	if err != nil {
-		return errors.Internal(errors.ErrMsgFailedToMarkCodeAsUsed)
+		return false, errors.Internal(errors.ErrMsgFailedToMarkCodeAsUsed)
 	}
 
 	rows, err := result.RowsAffected()
 	if err != nil {
-		return errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToGetAffectedRows, err.Error()))
+		return false, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToGetAffectedRows, err.Error()))
 	}
 
-	if rows == 0 {
-		return errors.NotFound(errors.ErrMsgAuthorizationCodeNotFound)
+	return rows == 1, nil
+}
+
+// MarkCodeReplayed records that a used authorization code was presented again,
+// so an exchange of the code still in progress revokes the tokens it issues.
+func (r *oauthRepository) MarkCodeReplayed(ctx context.Context, code string) error {
+	query := `
+		UPDATE authorization_codes
+		SET replay_detected = true
+		WHERE code = $1
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, code); err != nil {
+		return errors.Internal(errors.ErrMsgFailedToMarkCodeReplayed)
 	}
 
 	return nil
@@ -161,11 +193,12 @@ func (r *oauthRepository) SaveUserConsent(ctx context.Context, consent *oauth.Us
 func (r *oauthRepository) FindUserConsent(ctx context.Context, userID uint, clientID string) (*oauth.UserConsent, error) {
 	var uc oauth.UserConsent
 	query := `
-		SELECT id, user_id, client_id, scope, created_at, updated_at
+		SELECT id, user_id, client_id, scope, created_at, updated_at, last_used_at
 		FROM user_consents
 		WHERE user_id = $1 AND client_id = $2
 	`
 
+	var lastUsedAt sql.NullTime
 	err := r.db.QueryRowContext(ctx, query, userID, clientID).Scan(
 		&uc.ID,
 		&uc.UserID,
@@ -173,6 +206,7 @@ func (r *oauthRepository) FindUserConsent(ctx context.Context, userID uint, clie
 		&uc.Scope,
 		&uc.CreatedAt,
 		&uc.UpdatedAt,
+		&lastUsedAt,
 	)
 
 	if err == sql.ErrNoRows {
@@ -181,10 +215,64 @@ func (r *oauthRepository) FindUserConsent(ctx context.Context, userID uint, clie
 	if err != nil {
 		return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToFindUserConsent, err.Error()))
 	}
+	if lastUsedAt.Valid {
+		uc.LastUsedAt = &lastUsedAt.Time
+	}
 
 	return &uc, nil
 }
 
+// FindUserConsentsByUserID retrieves every consent a user has granted, oldest first.
+// Returns an empty slice if the user has granted none.
+func (r *oauthRepository) FindUserConsentsByUserID(ctx context.Context, userID uint) ([]*oauth.UserConsent, error) {
+	query := `
+		SELECT id, user_id, client_id, scope, created_at, updated_at, last_used_at
+		FROM user_consents
+		WHERE user_id = $1
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToFindUserConsent, err.Error()))
+	}
+	defer rows.Close()
+
+	consents := []*oauth.UserConsent{}
+	for rows.Next() {
+		var uc oauth.UserConsent
+		var lastUsedAt sql.NullTime
+		if err := rows.Scan(&uc.ID, &uc.UserID, &uc.ClientID, &uc.Scope, &uc.CreatedAt, &uc.UpdatedAt, &lastUsedAt); err != nil {
+			return nil, errors.Internal(errors.ErrMsgFailedToScanUserConsent)
+		}
+		if lastUsedAt.Valid {
+			uc.LastUsedAt = &lastUsedAt.Time
+		}
+		consents = append(consents, &uc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Internal(errors.ErrMsgErrorIteratingUserConsents)
+	}
+
+	return consents, nil
+}
+
+// TouchUserConsent records the time tokens were last issued to a client under a user's consent.
+// A missing consent is not an error; clients that skip consent issue tokens without one.
+func (r *oauthRepository) TouchUserConsent(ctx context.Context, userID uint, clientID string, at time.Time) error {
+	query := `
+		UPDATE user_consents
+		SET last_used_at = $3
+		WHERE user_id = $1 AND client_id = $2
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, clientID, at); err != nil {
+		return errors.Internal(errors.ErrMsgFailedToTouchUserConsent)
+	}
+
+	return nil
+}
+
 // UpdateUserConsent modifies an existing user consent record.
 // This is typically called when a user grants additional permissions to a client.
 // Returns NotFound error if no consent exists, or Internal error if the update fails.
@@ -243,3 +331,224 @@ func (r *oauthRepository) DeleteUserConsent(ctx context.Context, userID uint, cl
 
 	return nil
 }
+
+// SaveGrant persists a new grant and sets its generated ID.
+func (r *oauthRepository) SaveGrant(ctx context.Context, grant *oauth.Grant) error {
+	query := `
+		INSERT INTO grants (grant_id, client_id, user_id, scope, claims, resources, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		grant.GrantID,
+		grant.ClientID,
+		grant.UserID,
+		grant.Scope,
+		pq.Array(grant.Claims),
+		pq.Array(grant.Resources),
+		grant.CreatedAt,
+		grant.UpdatedAt,
+	).Scan(&grant.ID)
+
+	if err != nil {
+		return errors.Internal(errors.ErrMsgFailedToSaveGrant)
+	}
+
+	return nil
+}
+
+// FindGrant retrieves a grant by its grant ID.
+// Returns nil if the grant does not exist, for example because it was revoked.
+func (r *oauthRepository) FindGrant(ctx context.Context, grantID string) (*oauth.Grant, error) {
+	var g oauth.Grant
+	query := `
+		SELECT id, grant_id, client_id, user_id, scope, claims, resources, created_at, updated_at
+		FROM grants
+		WHERE grant_id = $1
+	`
+
+	err := r.db.QueryRowContext(ctx, query, grantID).Scan(
+		&g.ID,
+		&g.GrantID,
+		&g.ClientID,
+		&g.UserID,
+		&g.Scope,
+		pq.Array(&g.Claims),
+		pq.Array(&g.Resources),
+		&g.CreatedAt,
+		&g.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Internal(errors.ErrMsgFailedToFindGrant)
+	}
+
+	return &g, nil
+}
+
+// UpdateGrant stores the scope, claims, and resources of an existing grant.
+// Returns NotFound if the grant was revoked in the meantime.
+func (r *oauthRepository) UpdateGrant(ctx context.Context, grant *oauth.Grant) error {
+	query := `
+		UPDATE grants
+		SET scope = $2, claims = $3, resources = $4, updated_at = $5
+		WHERE grant_id = $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		grant.GrantID,
+		grant.Scope,
+		pq.Array(grant.Claims),
+		pq.Array(grant.Resources),
+		grant.UpdatedAt,
+	)
+	if err != nil {
+		return errors.Internal(errors.ErrMsgFailedToUpdateGrant)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return errors.Internal(errors.ErrMsgFailedToGetAffectedRows)
+	}
+	if rows == 0 {
+		return errors.NotFound(errors.ErrMsgGrantNotFound)
+	}
+
+	return nil
+}
+
+// DeleteGrant removes a grant. Returns NotFound if it does not exist.
+func (r *oauthRepository) DeleteGrant(ctx context.Context, grantID string) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM grants WHERE grant_id = $1", grantID)
+	if err != nil {
+		return errors.Internal(errors.ErrMsgFailedToDeleteGrant)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return errors.Internal(errors.ErrMsgFailedToGetAffectedRows)
+	}
+	if rows == 0 {
+		return errors.NotFound(errors.ErrMsgGrantNotFound)
+	}
+
+	return nil
+}
+
+// SaveDeviceCode persists a new device authorization request in the PostgreSQL database.
+// It inserts all device code fields and returns the generated ID.
+func (r *oauthRepository) SaveDeviceCode(ctx context.Context, code *oauth.DeviceCode) error {
+	query := `
+		INSERT INTO device_codes (
+			device_code, user_code, client_id, scope, status, poll_interval, expires_at, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		code.DeviceCode,
+		code.UserCode,
+		code.ClientID,
+		code.Scope,
+		code.Status,
+		code.PollInterval,
+		code.ExpiresAt,
+		code.CreatedAt,
+	).Scan(&code.ID)
+
+	if err != nil {
+		return errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToSaveDeviceCode, err.Error()))
+	}
+
+	return nil
+}
+
+// FindDeviceCode retrieves a device authorization request by its device code.
+// Returns nil if the code doesn't exist, or an error if the query fails.
+func (r *oauthRepository) FindDeviceCode(ctx context.Context, deviceCode string) (*oauth.DeviceCode, error) {
+	return r.findDeviceCode(ctx, "device_code", deviceCode)
+}
+
+// FindDeviceCodeByUserCode retrieves a device authorization request by its user code.
+// Returns nil if the code doesn't exist, or an error if the query fails.
+func (r *oauthRepository) FindDeviceCodeByUserCode(ctx context.Context, userCode string) (*oauth.DeviceCode, error) {
+	return r.findDeviceCode(ctx, "user_code", userCode)
+}
+
+// findDeviceCode retrieves a device code by the value of a unique column.
+// The column name is supplied by callers within this file and never by user input.
+func (r *oauthRepository) findDeviceCode(ctx context.Context, column, value string) (*oauth.DeviceCode, error) {
+	var dc oauth.DeviceCode
+	query := `
+		SELECT id, device_code, user_code, client_id, scope, COALESCE(user_id, 0),
+		       status, poll_interval, expires_at, created_at
+		FROM device_codes
+		WHERE ` + column + ` = $1
+	`
+
+	err := r.db.QueryRowContext(ctx, query, value).Scan(
+		&dc.ID,
+		&dc.DeviceCode,
+		&dc.UserCode,
+		&dc.ClientID,
+		&dc.Scope,
+		&dc.UserID,
+		&dc.Status,
+		&dc.PollInterval,
+		&dc.ExpiresAt,
+		&dc.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToFindDeviceCode, err.Error()))
+	}
+
+	return &dc, nil
+}
+
+// UpdateDeviceCodeStatus transitions a device code between statuses.
+// The update only applies when the code is currently in fromStatus, which makes
+// approval and token exchange safe against concurrent requests.
+// Returns true if the status was changed.
+func (r *oauthRepository) UpdateDeviceCodeStatus(ctx context.Context, deviceCode, fromStatus, toStatus string, userID uint) (bool, error) {
+	query := `
+		UPDATE device_codes
+		SET status = $3, user_id = COALESCE(NULLIF($4, 0), user_id)
+		WHERE device_code = $1 AND status = $2
+	`
+
+	result, err := r.db.ExecContext(ctx, query, deviceCode, fromStatus, toStatus, userID)
+	if err != nil {
+		return false, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToUpdateDeviceCode, err.Error()))
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToGetAffectedRows, err.Error()))
+	}
+
+	return rows > 0, nil
+}
+
+// UpdateDeviceCodeInterval sets the minimum polling interval of a device code.
+// It is used to slow down devices that poll faster than allowed.
+func (r *oauthRepository) UpdateDeviceCodeInterval(ctx context.Context, deviceCode string, interval int) error {
+	query := `
+		UPDATE device_codes
+		SET poll_interval = $2
+		WHERE device_code = $1
+	`
+
+	if _, err := r.db.ExecContext(ctx, query, deviceCode, interval); err != nil {
+		return errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToUpdateDeviceCode, err.Error()))
+	}
+
+	return nil
+}
@@ -3,40 +3,72 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 
 	"github.com/verigate/verigate-server/internal/pkg/config"
+	"github.com/verigate/verigate-server/internal/pkg/tenant"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
+// DB is the part of a database connection pool the repositories use. It is satisfied by
+// *sql.DB, by TenantDB, which serves every tenant from its own schema, and by ReplicaDB,
+// which serves reads that tolerate replication lag from a read replica.
+type DB interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
 // NewConnection establishes a new PostgreSQL database connection using configuration settings.
 // It connects to the database, validates the connection with a ping, and runs any pending migrations.
 // Returns the database connection pool or an error if the connection or migrations fail.
 func NewConnection() (*sql.DB, error) {
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+	return open(dataSourceName())
+}
+
+// dataSourceName builds the connection string of the configured database.
+func dataSourceName() string {
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
 		config.AppConfig.PostgresHost,
 		config.AppConfig.PostgresPort,
 		config.AppConfig.PostgresUser,
 		config.AppConfig.PostgresPassword,
 		config.AppConfig.PostgresDB,
 	)
+}
 
-	db, err := sql.Open("postgres", dsn)
+// open connects to a database, validates the connection with a ping, and runs any pending migrations.
+func open(dsn string) (*sql.DB, error) {
+	db, err := connect(dsn)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := db.Ping(); err != nil {
+	if err := runMigrations(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migration failed: %w", err)
+	}
+
+	return db, nil
+}
+
+// connect connects to a database and validates the connection with a ping.
+func connect(dsn string) (*sql.DB, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
 		return nil, err
 	}
 
-	if err := runMigrations(db); err != nil {
-		return nil, fmt.Errorf("migration failed: %w", err)
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
 	}
 
 	return db, nil
@@ -66,3 +98,81 @@ func runMigrations(db *sql.DB) error {
 
 	return nil
 }
+
+// TenantDB routes every query to the database of the tenant the context is served for.
+// Each tenant's data lives in its own schema of the configured database, reached through a
+// connection pool whose search_path is that schema, so the same queries serve every tenant
+// and no query can read another tenant's rows. The default tenant uses the public schema.
+type TenantDB struct {
+	defaultDB *sql.DB
+	tenants   map[string]*sql.DB
+}
+
+// NewTenantDB creates the schema of every tenant in the database of defaultDB if it does not
+// exist, connects to it, and runs any pending migrations in it. Returns an error if a schema
+// cannot be created or migrated; connections opened so far are then closed.
+func NewTenantDB(defaultDB *sql.DB, tenants []*tenant.Tenant) (*TenantDB, error) {
+	d := &TenantDB{defaultDB: defaultDB, tenants: make(map[string]*sql.DB)}
+	for _, t := range tenants {
+		if _, err := defaultDB.Exec("CREATE SCHEMA IF NOT EXISTS " + pq.QuoteIdentifier(t.Schema())); err != nil {
+			d.closeTenants()
+			return nil, fmt.Errorf("failed to create schema of tenant %s: %w", t.ID, err)
+		}
+
+		db, err := open(dataSourceName() + " search_path=" + t.Schema())
+		if err != nil {
+			d.closeTenants()
+			return nil, fmt.Errorf("tenant %s: %w", t.ID, err)
+		}
+		d.tenants[t.ID] = db
+	}
+
+	return d, nil
+}
+
+// Close closes the connection pools of the tenants. The default pool is left to its owner.
+func (d *TenantDB) Close() error {
+	d.closeTenants()
+	return nil
+}
+
+// closeTenants closes the connection pools of the tenants opened so far.
+func (d *TenantDB) closeTenants() {
+	for _, db := range d.tenants {
+		db.Close()
+	}
+}
+
+// db returns the connection pool of the tenant ctx is served for. Every tenant served by the
+// registry has a pool, so a missing one is a wiring bug and must not fall back to another tenant's data.
+func (d *TenantDB) db(ctx context.Context) *sql.DB {
+	id := tenant.ID(ctx)
+	if id == "" {
+		return d.defaultDB
+	}
+	db, ok := d.tenants[id]
+	if !ok {
+		panic("no database connection for tenant " + id)
+	}
+	return db
+}
+
+// BeginTx starts a transaction in the database of the tenant ctx is served for.
+func (d *TenantDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return d.db(ctx).BeginTx(ctx, opts)
+}
+
+// ExecContext executes a query without returning rows in the database of the tenant ctx is served for.
+func (d *TenantDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return d.db(ctx).ExecContext(ctx, query, args...)
+}
+
+// QueryContext executes a query returning rows in the database of the tenant ctx is served for.
+func (d *TenantDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return d.db(ctx).QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext executes a query returning at most one row in the database of the tenant ctx is served for.
+func (d *TenantDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return d.db(ctx).QueryRowContext(ctx, query, args...)
+}
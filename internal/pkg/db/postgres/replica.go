@@ -0,0 +1,166 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/pkg/config"
+	"github.com/verigate/verigate-server/internal/pkg/db/routing"
+	"github.com/verigate/verigate-server/internal/pkg/tenant"
+)
+
+// replicaLagQuery measures how far a replica's replay is behind the primary. A replica that has
+// replayed everything it received is not lagging even if the primary has been idle since its
+// last transaction, and a server that is not a replica reports no lag.
+const replicaLagQuery = `
+	SELECT CASE
+		WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+		ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+	END`
+
+// replicaReadContextKey marks the context of a query that may be served by a read replica
+type replicaReadContextKey struct{}
+
+// replicaRead returns a copy of ctx whose query may be served by a read replica. Repositories
+// mark the lookups that tolerate replication lag, such as client, scope, user, and access token
+// lookups; every other query goes to the primary. A marked lookup can miss changes made on the
+// primary within the allowed lag (see ReplicaDB), unless its request requires the primary.
+func replicaRead(ctx context.Context) context.Context {
+	return context.WithValue(ctx, replicaReadContextKey{}, true)
+}
+
+// isReplicaRead reports whether ctx was marked by replicaRead.
+func isReplicaRead(ctx context.Context) bool {
+	marked, _ := ctx.Value(replicaReadContextKey{}).(bool)
+	return marked
+}
+
+// NewReplicaConnection connects to the configured read replica and validates the connection
+// with a ping. Migrations are left to the primary, whose schema the replica receives.
+func NewReplicaConnection() (*sql.DB, error) {
+	return connect(replicaDataSourceName())
+}
+
+// replicaDataSourceName builds the connection string of the configured read replica, which
+// has the credentials and database name of the primary.
+func replicaDataSourceName() string {
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		config.AppConfig.PostgresReplicaHost,
+		config.AppConfig.PostgresReplicaPort,
+		config.AppConfig.PostgresUser,
+		config.AppConfig.PostgresPassword,
+		config.AppConfig.PostgresDB,
+	)
+}
+
+// NewTenantReplicaDB connects to the schema of every tenant on the read replica of defaultDB.
+// The schemas are created and migrated on the primary, so they are only connected to here.
+// Returns an error if a schema cannot be reached; connections opened so far are then closed.
+func NewTenantReplicaDB(defaultDB *sql.DB, tenants []*tenant.Tenant) (*TenantDB, error) {
+	d := &TenantDB{defaultDB: defaultDB, tenants: make(map[string]*sql.DB)}
+	for _, t := range tenants {
+		db, err := connect(replicaDataSourceName() + " search_path=" + t.Schema())
+		if err != nil {
+			d.closeTenants()
+			return nil, fmt.Errorf("tenant %s replica: %w", t.ID, err)
+		}
+		d.tenants[t.ID] = db
+	}
+
+	return d, nil
+}
+
+// ReplicaDB routes the reads repositories mark as tolerating replication lag to a read replica,
+// and everything else to the primary. Reads go to the primary instead when the request requires
+// it (see routing.PrimaryRequired), or while the replica is further behind than the allowed lag
+// or could not be checked. Statements not marked as reads count as writes of the request, so
+// its later reads are served by the primary and see them.
+type ReplicaDB struct {
+	primary DB
+	replica DB
+	maxLag  time.Duration
+	lagging atomic.Bool // Whether the last lag check found the replica behind or unreachable
+	checked bool        // Whether a lag check has run, so that the first one is always reported
+}
+
+// NewReplicaDB creates a router between the primary and a read replica that tolerates up to
+// maxLag of replication lag. The replica is not used until Start has found it within maxLag.
+func NewReplicaDB(primary, replica DB, maxLag time.Duration) *ReplicaDB {
+	d := &ReplicaDB{primary: primary, replica: replica, maxLag: maxLag}
+	d.lagging.Store(true)
+	return d
+}
+
+// Start checks the replication lag of the replica now and every interval until ctx is done.
+// onChange is called with the result of the first check and whenever the replica starts or
+// stops being used after it, with the error of the check that stopped it, if any.
+func (d *ReplicaDB) Start(ctx context.Context, interval time.Duration, onChange func(lagging bool, lag time.Duration, err error)) {
+	d.checkLag(ctx, onChange)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				d.checkLag(ctx, onChange)
+			}
+		}
+	}()
+}
+
+// checkLag measures the replication lag of the replica and records whether it is within the
+// allowed lag. Lag is measured on the connection of the tenant ctx is served for, the default
+// one for background work, since every tenant's schema is replicated with the same database.
+func (d *ReplicaDB) checkLag(ctx context.Context, onChange func(lagging bool, lag time.Duration, err error)) {
+	var seconds float64
+	err := d.replica.QueryRowContext(ctx, replicaLagQuery).Scan(&seconds)
+	lag := time.Duration(seconds * float64(time.Second))
+
+	lagging := err != nil || lag > d.maxLag
+	changed := d.lagging.Swap(lagging) != lagging || !d.checked
+	d.checked = true
+	if changed && onChange != nil {
+		onChange(lagging, lag, err)
+	}
+}
+
+// reader returns the database a read with ctx is served by.
+func (d *ReplicaDB) reader(ctx context.Context) DB {
+	if !isReplicaRead(ctx) {
+		routing.RecordWrite(ctx)
+		return d.primary
+	}
+	if routing.PrimaryRequired(ctx) || d.lagging.Load() {
+		return d.primary
+	}
+	return d.replica
+}
+
+// BeginTx starts a transaction on the primary.
+func (d *ReplicaDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	routing.RecordWrite(ctx)
+	return d.primary.BeginTx(ctx, opts)
+}
+
+// ExecContext executes a query without returning rows on the primary.
+func (d *ReplicaDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	routing.RecordWrite(ctx)
+	return d.primary.ExecContext(ctx, query, args...)
+}
+
+// QueryContext executes a query returning rows on the replica when ctx allows it, otherwise on the primary.
+func (d *ReplicaDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return d.reader(ctx).QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext executes a query returning at most one row on the replica when ctx allows it,
+// otherwise on the primary.
+func (d *ReplicaDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return d.reader(ctx).QueryRowContext(ctx, query, args...)
+}
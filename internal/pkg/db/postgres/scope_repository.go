@@ -4,6 +4,7 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 
 	"github.com/lib/pq"
@@ -13,12 +14,12 @@ import (
 
 // scopeRepository implements the scope.Repository interface using PostgreSQL.
 type scopeRepository struct {
-	db *sql.DB
+	db DB
 }
 
 // NewScopeRepository creates a new PostgreSQL-based scope repository.
 // It takes a database connection and returns a scope.Repository interface.
-func NewScopeRepository(db *sql.DB) scope.Repository {
+func NewScopeRepository(db DB) scope.Repository {
 	return &scopeRepository{db: db}
 }
 
@@ -27,15 +28,25 @@ func NewScopeRepository(db *sql.DB) scope.Repository {
 // Returns an error if the insertion fails, such as when a duplicate scope name exists.
 func (r *scopeRepository) Save(ctx context.Context, scope *scope.Scope) error {
 	query := `
-		INSERT INTO scopes (name, description, is_default, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO scopes (name, description, descriptions, is_default, required, audience, implied_scopes, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), COALESCE($7, '{}'), $8, $9)
 		RETURNING id
 	`
 
+	// A map of strings always marshals
+	descriptions := []byte("{}")
+	if len(scope.Descriptions) > 0 {
+		descriptions, _ = json.Marshal(scope.Descriptions)
+	}
+
 	err := r.db.QueryRowContext(ctx, query,
 		scope.Name,
 		scope.Description,
+		descriptions,
 		scope.IsDefault,
+		scope.Required,
+		scope.Audience,
+		pq.Array(scope.Implies),
 		scope.CreatedAt,
 		scope.UpdatedAt,
 	).Scan(&scope.ID)
@@ -56,17 +67,22 @@ func (r *scopeRepository) Save(ctx context.Context, scope *scope.Scope) error {
 // Scope names are case-sensitive.
 func (r *scopeRepository) FindByName(ctx context.Context, name string) (*scope.Scope, error) {
 	var s scope.Scope
+	var descriptions []byte
 	query := `
-		SELECT id, name, description, is_default, created_at, updated_at
+		SELECT id, name, COALESCE(description, ''), descriptions, is_default, required, COALESCE(audience, ''), implied_scopes, created_at, updated_at
 		FROM scopes
 		WHERE name = $1
 	`
 
-	err := r.db.QueryRowContext(ctx, query, name).Scan(
+	err := r.db.QueryRowContext(replicaRead(ctx), query, name).Scan(
 		&s.ID,
 		&s.Name,
 		&s.Description,
+		&descriptions,
 		&s.IsDefault,
+		&s.Required,
+		&s.Audience,
+		pq.Array(&s.Implies),
 		&s.CreatedAt,
 		&s.UpdatedAt,
 	)
@@ -77,6 +93,9 @@ func (r *scopeRepository) FindByName(ctx context.Context, name string) (*scope.S
 	if err != nil {
 		return nil, errors.Internal(fmt.Sprintf(errors.ErrMsgFailedToFindScopeByName, name, err.Error()))
 	}
+	if err := decodeScopeDescriptions(descriptions, &s); err != nil {
+		return nil, err
+	}
 
 	return &s, nil
 }
@@ -86,12 +105,12 @@ func (r *scopeRepository) FindByName(ctx context.Context, name string) (*scope.S
 // Returns an error if the query fails.
 func (r *scopeRepository) FindByNames(ctx context.Context, names []string) ([]scope.Scope, error) {
 	query := `
-		SELECT id, name, description, is_default, created_at, updated_at
+		SELECT id, name, COALESCE(description, ''), descriptions, is_default, required, COALESCE(audience, ''), implied_scopes, created_at, updated_at
 		FROM scopes
 		WHERE name = ANY($1)
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, pq.Array(names))
+	rows, err := r.db.QueryContext(replicaRead(ctx), query, pq.Array(names))
 	if err != nil {
 		return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToFindScopesByNames, err.Error()))
 	}
@@ -100,16 +119,24 @@ func (r *scopeRepository) FindByNames(ctx context.Context, names []string) ([]sc
 	var scopes []scope.Scope
 	for rows.Next() {
 		var s scope.Scope
+		var descriptions []byte
 		if err := rows.Scan(
 			&s.ID,
 			&s.Name,
 			&s.Description,
+			&descriptions,
 			&s.IsDefault,
+			&s.Required,
+			&s.Audience,
+			pq.Array(&s.Implies),
 			&s.CreatedAt,
 			&s.UpdatedAt,
 		); err != nil {
 			return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToScanScopeData, err.Error()))
 		}
+		if err := decodeScopeDescriptions(descriptions, &s); err != nil {
+			return nil, err
+		}
 		scopes = append(scopes, s)
 	}
 
@@ -124,12 +151,12 @@ func (r *scopeRepository) FindByNames(ctx context.Context, names []string) ([]sc
 // Returns all scopes ordered by name, or an error if the query fails.
 func (r *scopeRepository) FindAll(ctx context.Context) ([]scope.Scope, error) {
 	query := `
-		SELECT id, name, description, is_default, created_at, updated_at
+		SELECT id, name, COALESCE(description, ''), descriptions, is_default, required, COALESCE(audience, ''), implied_scopes, created_at, updated_at
 		FROM scopes
 		ORDER BY name
 	`
 
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := r.db.QueryContext(replicaRead(ctx), query)
 	if err != nil {
 		return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToFindAllScopes, err.Error()))
 	}
@@ -138,16 +165,24 @@ func (r *scopeRepository) FindAll(ctx context.Context) ([]scope.Scope, error) {
 	var scopes []scope.Scope
 	for rows.Next() {
 		var s scope.Scope
+		var descriptions []byte
 		if err := rows.Scan(
 			&s.ID,
 			&s.Name,
 			&s.Description,
+			&descriptions,
 			&s.IsDefault,
+			&s.Required,
+			&s.Audience,
+			pq.Array(&s.Implies),
 			&s.CreatedAt,
 			&s.UpdatedAt,
 		); err != nil {
 			return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToScanScopeData, err.Error())) // Reusing ErrMsgFailedToScanScopeData
 		}
+		if err := decodeScopeDescriptions(descriptions, &s); err != nil {
+			return nil, err
+		}
 		scopes = append(scopes, s)
 	}
 
@@ -163,13 +198,13 @@ func (r *scopeRepository) FindAll(ctx context.Context) ([]scope.Scope, error) {
 // Returns all default scopes ordered by name, or an error if the query fails.
 func (r *scopeRepository) FindDefaults(ctx context.Context) ([]scope.Scope, error) {
 	query := `
-		SELECT id, name, description, is_default, created_at, updated_at
+		SELECT id, name, COALESCE(description, ''), descriptions, is_default, required, COALESCE(audience, ''), implied_scopes, created_at, updated_at
 		FROM scopes
 		WHERE is_default = true
 		ORDER BY name
 	`
 
-	rows, err := r.db.QueryContext(ctx, query)
+	rows, err := r.db.QueryContext(replicaRead(ctx), query)
 	if err != nil {
 		return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToFindDefaultScopes, err.Error()))
 	}
@@ -178,16 +213,24 @@ func (r *scopeRepository) FindDefaults(ctx context.Context) ([]scope.Scope, erro
 	var scopes []scope.Scope
 	for rows.Next() {
 		var s scope.Scope
+		var descriptions []byte
 		if err := rows.Scan(
 			&s.ID,
 			&s.Name,
 			&s.Description,
+			&descriptions,
 			&s.IsDefault,
+			&s.Required,
+			&s.Audience,
+			pq.Array(&s.Implies),
 			&s.CreatedAt,
 			&s.UpdatedAt,
 		); err != nil {
 			return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToScanDefaultScopeData, err.Error()))
 		}
+		if err := decodeScopeDescriptions(descriptions, &s); err != nil {
+			return nil, err
+		}
 		scopes = append(scopes, s)
 	}
 
@@ -197,3 +240,14 @@ func (r *scopeRepository) FindDefaults(ctx context.Context) ([]scope.Scope, erro
 
 	return scopes, nil
 }
+
+// decodeScopeDescriptions unmarshals the descriptions column into the scope's translations.
+func decodeScopeDescriptions(data []byte, s *scope.Scope) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, &s.Descriptions); err != nil {
+		return errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToDecodeScopeDescriptions, err.Error()))
+	}
+	return nil
+}
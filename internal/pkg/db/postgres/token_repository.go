@@ -5,20 +5,46 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"time"
 
+	"github.com/lib/pq"
 	"github.com/verigate/verigate-server/internal/app/token"
 	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
 )
 
+// Insert statements shared by the single-token saves and token rotation
+const (
+	insertAccessTokenQuery = `
+		INSERT INTO access_tokens (token_id, token_hash, client_id, user_id, scope, expires_at, created_at, is_revoked, audience,
+			cert_thumbprint, dpop_jkt, auth_time, acr)
+		VALUES ($1, $2, $3, NULLIF($4, 0), $5, $6, $7, $8, $9, NULLIF($10, ''), NULLIF($11, ''), $12, NULLIF($13, ''))
+		RETURNING id
+	`
+
+	insertRefreshTokenQuery = `
+		INSERT INTO refresh_tokens (token_id, token_hash, access_token_id, client_id, user_id, scope, expires_at, created_at, is_revoked,
+			family_id, parent_token_id, resources, bound_subnet, grant_id, family_created_at, dpop_jkt, auth_time, acr)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, ''), $12, NULLIF($13, ''), NULLIF($14, ''), $15, NULLIF($16, ''),
+			$17, NULLIF($18, ''))
+		RETURNING id
+	`
+)
+
+// nullTime converts a time to a nullable column value, NULL for the zero time.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
 // tokenRepository implements the token.Repository interface using PostgreSQL.
 // It handles persistence of OAuth access and refresh tokens.
 type tokenRepository struct {
-	db *sql.DB
+	db DB
 }
 
 // NewTokenRepository creates a new PostgreSQL implementation of the token repository.
 // It requires an active database connection to operate.
-func NewTokenRepository(db *sql.DB) token.Repository {
+func NewTokenRepository(db DB) token.Repository {
 	return &tokenRepository{db: db}
 }
 
@@ -26,13 +52,7 @@ func NewTokenRepository(db *sql.DB) token.Repository {
 // It stores all token properties and sets the auto-generated ID in the token object.
 // Returns an error if the database operation fails.
 func (r *tokenRepository) SaveAccessToken(ctx context.Context, token *token.AccessToken) error {
-	query := `
-		INSERT INTO access_tokens (token_id, token_hash, client_id, user_id, scope, expires_at, created_at, is_revoked)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		RETURNING id
-	`
-
-	err := r.db.QueryRowContext(ctx, query,
+	err := r.db.QueryRowContext(ctx, insertAccessTokenQuery,
 		token.TokenID,
 		token.TokenHash,
 		token.ClientID,
@@ -41,6 +61,11 @@ func (r *tokenRepository) SaveAccessToken(ctx context.Context, token *token.Acce
 		token.ExpiresAt,
 		token.CreatedAt,
 		token.IsRevoked,
+		pq.Array(token.Audience),
+		token.CertThumbprint,
+		token.DPoPThumbprint,
+		nullTime(token.AuthTime),
+		token.ACR,
 	).Scan(&token.ID)
 
 	if err != nil {
@@ -52,15 +77,22 @@ func (r *tokenRepository) SaveAccessToken(ctx context.Context, token *token.Acce
 
 // FindAccessToken retrieves an access token from the database by its token ID.
 // Returns the token or an error if not found or if the database operation fails.
+// As a replica read, it can return a token revoked on the primary within the replication lag
+// as unrevoked, so the token still validates and introspects as active. Callers check the
+// Redis denylist first, but that only covers access tokens revoked one by one or in bulk
+// where the write to Redis succeeded; the access tokens of a refresh token family revoked
+// for reuse are not denylisted at all.
 func (r *tokenRepository) FindAccessToken(ctx context.Context, tokenID string) (*token.AccessToken, error) {
 	var t token.AccessToken
+	var authTime sql.NullTime
 	query := `
-		SELECT id, token_id, token_hash, client_id, user_id, scope, expires_at, created_at, is_revoked
+		SELECT id, token_id, token_hash, client_id, COALESCE(user_id, 0), scope, expires_at, created_at, is_revoked,
+			COALESCE(audience, '{}'), COALESCE(cert_thumbprint, ''), COALESCE(dpop_jkt, ''), auth_time, COALESCE(acr, '')
 		FROM access_tokens
 		WHERE token_id = $1
 	`
 
-	err := r.db.QueryRowContext(ctx, query, tokenID).Scan(
+	err := r.db.QueryRowContext(replicaRead(ctx), query, tokenID).Scan(
 		&t.ID,
 		&t.TokenID,
 		&t.TokenHash,
@@ -70,6 +102,11 @@ func (r *tokenRepository) FindAccessToken(ctx context.Context, tokenID string) (
 		&t.ExpiresAt,
 		&t.CreatedAt,
 		&t.IsRevoked,
+		pq.Array(&t.Audience),
+		&t.CertThumbprint,
+		&t.DPoPThumbprint,
+		&authTime,
+		&t.ACR,
 	)
 
 	if err == sql.ErrNoRows {
@@ -79,6 +116,49 @@ func (r *tokenRepository) FindAccessToken(ctx context.Context, tokenID string) (
 		return nil, errors.Internal(errors.ErrMsgFailedToFindAccessToken)
 	}
 
+	t.AuthTime = authTime.Time
+	return &t, nil
+}
+
+// FindAccessTokenByHash retrieves an access token from the database by its token hash.
+// The lookup uses the token hash index, so it does not scan the table.
+// Returns nil if no token matches, or an error if the database operation fails.
+// Like FindAccessToken, it is a replica read and can report a just-revoked token as unrevoked.
+func (r *tokenRepository) FindAccessTokenByHash(ctx context.Context, tokenHash string) (*token.AccessToken, error) {
+	var t token.AccessToken
+	var authTime sql.NullTime
+	query := `
+		SELECT id, token_id, token_hash, client_id, COALESCE(user_id, 0), scope, expires_at, created_at, is_revoked,
+			COALESCE(audience, '{}'), COALESCE(cert_thumbprint, ''), COALESCE(dpop_jkt, ''), auth_time, COALESCE(acr, '')
+		FROM access_tokens
+		WHERE token_hash = $1
+	`
+
+	err := r.db.QueryRowContext(replicaRead(ctx), query, tokenHash).Scan(
+		&t.ID,
+		&t.TokenID,
+		&t.TokenHash,
+		&t.ClientID,
+		&t.UserID,
+		&t.Scope,
+		&t.ExpiresAt,
+		&t.CreatedAt,
+		&t.IsRevoked,
+		pq.Array(&t.Audience),
+		&t.CertThumbprint,
+		&t.DPoPThumbprint,
+		&authTime,
+		&t.ACR,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Internal(errors.ErrMsgFailedToFindAccessTokenByHash)
+	}
+
+	t.AuthTime = authTime.Time
 	return &t, nil
 }
 
@@ -94,7 +174,8 @@ func (r *tokenRepository) FindAccessTokensByUserID(ctx context.Context, userID u
 
 	// Get tokens with pagination
 	query := `
-		SELECT id, token_id, token_hash, client_id, user_id, scope, expires_at, created_at, is_revoked
+		SELECT id, token_id, token_hash, client_id, COALESCE(user_id, 0), scope, expires_at, created_at, is_revoked,
+			COALESCE(audience, '{}')
 		FROM access_tokens
 		WHERE user_id = $1
 		ORDER BY created_at DESC
@@ -120,6 +201,7 @@ func (r *tokenRepository) FindAccessTokensByUserID(ctx context.Context, userID u
 			&t.ExpiresAt,
 			&t.CreatedAt,
 			&t.IsRevoked,
+			pq.Array(&t.Audience),
 		); err != nil {
 			return nil, 0, errors.Internal(errors.ErrMsgFailedToScanAccessToken)
 		}
@@ -145,7 +227,8 @@ func (r *tokenRepository) FindAccessTokensByClientID(ctx context.Context, client
 
 	// Get tokens with pagination
 	query := `
-		SELECT id, token_id, token_hash, client_id, user_id, scope, expires_at, created_at, is_revoked
+		SELECT id, token_id, token_hash, client_id, COALESCE(user_id, 0), scope, expires_at, created_at, is_revoked,
+			COALESCE(audience, '{}')
 		FROM access_tokens
 		WHERE client_id = $1
 		ORDER BY created_at DESC
@@ -171,6 +254,7 @@ func (r *tokenRepository) FindAccessTokensByClientID(ctx context.Context, client
 			&t.ExpiresAt,
 			&t.CreatedAt,
 			&t.IsRevoked,
+			pq.Array(&t.Audience),
 		); err != nil {
 			return nil, 0, errors.Internal(errors.ErrMsgFailedToScanAccessToken)
 		}
@@ -238,25 +322,34 @@ func (r *tokenRepository) RevokeAccessTokensByClientID(ctx context.Context, clie
 	return nil
 }
 
-func (r *tokenRepository) RevokeAccessTokensByAuthCode(ctx context.Context, authCode string) error {
-	// This would typically involve a join with authorization_codes table
-	// For simplicity, we'll assume we track this relationship differently
+// SaveAuthorizationCodeToken links an access token to the authorization code it was issued for.
+func (r *tokenRepository) SaveAuthorizationCodeToken(ctx context.Context, authCode, tokenID string) error {
 	query := `
-		UPDATE access_tokens
-		SET is_revoked = true
-		WHERE token_id IN (
-			SELECT token_id FROM authorization_code_tokens WHERE auth_code = $1
-		)
+		INSERT INTO authorization_code_tokens (auth_code, token_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
 	`
 
-	_, err := r.db.ExecContext(ctx, query, authCode)
-	if err != nil {
-		return errors.Internal(errors.ErrMsgFailedToRevokeAccessTokensByAuthCode)
+	if _, err := r.db.ExecContext(ctx, query, authCode, tokenID); err != nil {
+		return errors.Internal(errors.ErrMsgFailedToSaveAuthCodeToken)
 	}
 
 	return nil
 }
 
+// RevokeTokensByAuthCode revokes the tokens issued for an authorization code and their rotation families.
+func (r *tokenRepository) RevokeTokensByAuthCode(ctx context.Context, authCode string) (*token.RevokedTokens, error) {
+	codeTokens := "SELECT token_id FROM authorization_code_tokens WHERE auth_code = $1"
+	families := "SELECT family_id FROM refresh_tokens WHERE access_token_id IN (" + codeTokens + ")"
+
+	return r.revokeTokens(ctx,
+		"token_id IN ("+codeTokens+") OR token_id IN (SELECT access_token_id FROM refresh_tokens WHERE family_id IN ("+families+"))",
+		"family_id IN ("+families+")",
+		errors.ErrMsgFailedToRevokeAccessTokensByAuthCode,
+		authCode,
+	)
+}
+
 func (r *tokenRepository) IsAccessTokenRevoked(ctx context.Context, tokenID string) (bool, error) {
 	var isRevoked bool
 	query := "SELECT is_revoked FROM access_tokens WHERE token_id = $1"
@@ -273,13 +366,7 @@ func (r *tokenRepository) IsAccessTokenRevoked(ctx context.Context, tokenID stri
 }
 
 func (r *tokenRepository) SaveRefreshToken(ctx context.Context, token *token.RefreshToken) error {
-	query := `
-		INSERT INTO refresh_tokens (token_id, token_hash, access_token_id, client_id, user_id, scope, expires_at, created_at, is_revoked)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-		RETURNING id
-	`
-
-	err := r.db.QueryRowContext(ctx, query,
+	err := r.db.QueryRowContext(ctx, insertRefreshTokenQuery,
 		token.TokenID,
 		token.TokenHash,
 		token.AccessTokenID,
@@ -289,6 +376,15 @@ func (r *tokenRepository) SaveRefreshToken(ctx context.Context, token *token.Ref
 		token.ExpiresAt,
 		token.CreatedAt,
 		token.IsRevoked,
+		token.FamilyID,
+		token.ParentTokenID,
+		pq.Array(token.Resources),
+		token.BoundSubnet,
+		token.GrantID,
+		token.FamilyCreatedAt,
+		token.DPoPThumbprint,
+		nullTime(token.AuthTime),
+		token.ACR,
 	).Scan(&token.ID)
 
 	if err != nil {
@@ -300,8 +396,11 @@ func (r *tokenRepository) SaveRefreshToken(ctx context.Context, token *token.Ref
 
 func (r *tokenRepository) FindRefreshToken(ctx context.Context, tokenID string) (*token.RefreshToken, error) {
 	var t token.RefreshToken
+	var authTime sql.NullTime
 	query := `
-		SELECT id, token_id, token_hash, access_token_id, client_id, user_id, scope, expires_at, created_at, is_revoked
+		SELECT id, token_id, token_hash, access_token_id, client_id, user_id, scope, expires_at, created_at, is_revoked,
+			family_id, COALESCE(parent_token_id, ''), rotated_at, COALESCE(resources, '{}'), COALESCE(bound_subnet, ''),
+			COALESCE(grant_id, ''), family_created_at, COALESCE(dpop_jkt, ''), auth_time, COALESCE(acr, '')
 		FROM refresh_tokens
 		WHERE token_id = $1
 	`
@@ -317,6 +416,16 @@ func (r *tokenRepository) FindRefreshToken(ctx context.Context, tokenID string)
 		&t.ExpiresAt,
 		&t.CreatedAt,
 		&t.IsRevoked,
+		&t.FamilyID,
+		&t.ParentTokenID,
+		&t.RotatedAt,
+		pq.Array(&t.Resources),
+		&t.BoundSubnet,
+		&t.GrantID,
+		&t.FamilyCreatedAt,
+		&t.DPoPThumbprint,
+		&authTime,
+		&t.ACR,
 	)
 
 	if err == sql.ErrNoRows {
@@ -326,13 +435,17 @@ func (r *tokenRepository) FindRefreshToken(ctx context.Context, tokenID string)
 		return nil, errors.Internal(errors.ErrMsgFailedToFindRefreshToken)
 	}
 
+	t.AuthTime = authTime.Time
 	return &t, nil
 }
 
 func (r *tokenRepository) FindRefreshTokenByHash(ctx context.Context, tokenHash string) (*token.RefreshToken, error) {
 	var t token.RefreshToken
+	var authTime sql.NullTime
 	query := `
-		SELECT id, token_id, token_hash, access_token_id, client_id, user_id, scope, expires_at, created_at, is_revoked
+		SELECT id, token_id, token_hash, access_token_id, client_id, user_id, scope, expires_at, created_at, is_revoked,
+			family_id, COALESCE(parent_token_id, ''), rotated_at, COALESCE(resources, '{}'), COALESCE(bound_subnet, ''),
+			COALESCE(grant_id, ''), family_created_at, COALESCE(dpop_jkt, ''), auth_time, COALESCE(acr, '')
 		FROM refresh_tokens
 		WHERE token_hash = $1
 	`
@@ -348,6 +461,16 @@ func (r *tokenRepository) FindRefreshTokenByHash(ctx context.Context, tokenHash
 		&t.ExpiresAt,
 		&t.CreatedAt,
 		&t.IsRevoked,
+		&t.FamilyID,
+		&t.ParentTokenID,
+		&t.RotatedAt,
+		pq.Array(&t.Resources),
+		&t.BoundSubnet,
+		&t.GrantID,
+		&t.FamilyCreatedAt,
+		&t.DPoPThumbprint,
+		&authTime,
+		&t.ACR,
 	)
 
 	if err == sql.ErrNoRows {
@@ -357,6 +480,7 @@ func (r *tokenRepository) FindRefreshTokenByHash(ctx context.Context, tokenHash
 		return nil, errors.Internal(errors.ErrMsgFailedToFindRefreshTokenByHash)
 	}
 
+	t.AuthTime = authTime.Time
 	return &t, nil
 }
 
@@ -372,7 +496,9 @@ func (r *tokenRepository) FindRefreshTokensByUserID(ctx context.Context, userID
 
 	// Get tokens with pagination
 	query := `
-		SELECT id, token_id, token_hash, access_token_id, client_id, user_id, scope, expires_at, created_at, is_revoked
+		SELECT id, token_id, token_hash, access_token_id, client_id, user_id, scope, expires_at, created_at, is_revoked,
+			family_id, COALESCE(parent_token_id, ''), rotated_at, COALESCE(resources, '{}'), COALESCE(bound_subnet, ''),
+			COALESCE(grant_id, ''), family_created_at, COALESCE(dpop_jkt, '')
 		FROM refresh_tokens
 		WHERE user_id = $1
 		ORDER BY created_at DESC
@@ -399,6 +525,14 @@ func (r *tokenRepository) FindRefreshTokensByUserID(ctx context.Context, userID
 			&t.ExpiresAt,
 			&t.CreatedAt,
 			&t.IsRevoked,
+			&t.FamilyID,
+			&t.ParentTokenID,
+			&t.RotatedAt,
+			pq.Array(&t.Resources),
+			&t.BoundSubnet,
+			&t.GrantID,
+			&t.FamilyCreatedAt,
+			&t.DPoPThumbprint,
 		); err != nil {
 			return nil, 0, errors.Internal(errors.ErrMsgFailedToScanRefreshToken)
 		}
@@ -424,7 +558,9 @@ func (r *tokenRepository) FindRefreshTokensByClientID(ctx context.Context, clien
 
 	// Get tokens with pagination
 	query := `
-		SELECT id, token_id, token_hash, access_token_id, client_id, user_id, scope, expires_at, created_at, is_revoked
+		SELECT id, token_id, token_hash, access_token_id, client_id, user_id, scope, expires_at, created_at, is_revoked,
+			family_id, COALESCE(parent_token_id, ''), rotated_at, COALESCE(resources, '{}'), COALESCE(bound_subnet, ''),
+			COALESCE(grant_id, ''), family_created_at, COALESCE(dpop_jkt, '')
 		FROM refresh_tokens
 		WHERE client_id = $1
 		ORDER BY created_at DESC
@@ -451,6 +587,14 @@ func (r *tokenRepository) FindRefreshTokensByClientID(ctx context.Context, clien
 			&t.ExpiresAt,
 			&t.CreatedAt,
 			&t.IsRevoked,
+			&t.FamilyID,
+			&t.ParentTokenID,
+			&t.RotatedAt,
+			pq.Array(&t.Resources),
+			&t.BoundSubnet,
+			&t.GrantID,
+			&t.FamilyCreatedAt,
+			&t.DPoPThumbprint,
 		); err != nil {
 			return nil, 0, errors.Internal(errors.ErrMsgFailedToScanRefreshToken)
 		}
@@ -532,3 +676,217 @@ func (r *tokenRepository) RevokeRefreshTokensByAccessTokenID(ctx context.Context
 
 	return nil
 }
+
+// RotateRefreshToken replaces a refresh token with a new access and refresh token pair.
+// The old token is marked as rotated only if it is still active, and the new
+// tokens are inserted in the same transaction, so concurrent rotations of the
+// same token cannot both succeed.
+// Returns false if the old token had already been rotated or revoked.
+func (r *tokenRepository) RotateRefreshToken(ctx context.Context, oldTokenID string, accessToken *token.AccessToken, refreshToken *token.RefreshToken) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, errors.Internal(errors.ErrMsgFailedToRotateRefreshToken)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE refresh_tokens
+		SET is_revoked = true, rotated_at = $2
+		WHERE token_id = $1 AND is_revoked = false
+	`, oldTokenID, time.Now())
+	if err != nil {
+		return false, errors.Internal(errors.ErrMsgFailedToRotateRefreshToken)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.Internal(errors.ErrMsgFailedToGetAffectedRows)
+	}
+	if rows == 0 {
+		return false, nil
+	}
+
+	if err := tx.QueryRowContext(ctx, insertAccessTokenQuery,
+		accessToken.TokenID,
+		accessToken.TokenHash,
+		accessToken.ClientID,
+		accessToken.UserID,
+		accessToken.Scope,
+		accessToken.ExpiresAt,
+		accessToken.CreatedAt,
+		accessToken.IsRevoked,
+		pq.Array(accessToken.Audience),
+		accessToken.CertThumbprint,
+		accessToken.DPoPThumbprint,
+		nullTime(accessToken.AuthTime),
+		accessToken.ACR,
+	).Scan(&accessToken.ID); err != nil {
+		return false, errors.Internal(errors.ErrMsgFailedToSaveAccessToken)
+	}
+
+	if err := tx.QueryRowContext(ctx, insertRefreshTokenQuery,
+		refreshToken.TokenID,
+		refreshToken.TokenHash,
+		refreshToken.AccessTokenID,
+		refreshToken.ClientID,
+		refreshToken.UserID,
+		refreshToken.Scope,
+		refreshToken.ExpiresAt,
+		refreshToken.CreatedAt,
+		refreshToken.IsRevoked,
+		refreshToken.FamilyID,
+		refreshToken.ParentTokenID,
+		pq.Array(refreshToken.Resources),
+		refreshToken.BoundSubnet,
+		refreshToken.GrantID,
+		refreshToken.FamilyCreatedAt,
+		refreshToken.DPoPThumbprint,
+		nullTime(refreshToken.AuthTime),
+		refreshToken.ACR,
+	).Scan(&refreshToken.ID); err != nil {
+		return false, errors.Internal(errors.ErrMsgFailedToSaveRefreshToken)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, errors.Internal(errors.ErrMsgFailedToRotateRefreshToken)
+	}
+
+	return true, nil
+}
+
+// RevokeRefreshTokenFamily revokes all refresh tokens descended from the same
+// original token, together with the access tokens issued alongside them.
+// Both updates run in one transaction so a family is never left partially revoked.
+func (r *tokenRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Internal(errors.ErrMsgFailedToRevokeTokenFamily)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE access_tokens
+		SET is_revoked = true
+		WHERE is_revoked = false AND token_id IN (
+			SELECT access_token_id FROM refresh_tokens WHERE family_id = $1
+		)
+	`, familyID); err != nil {
+		return errors.Internal(errors.ErrMsgFailedToRevokeTokenFamily)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE refresh_tokens
+		SET is_revoked = true
+		WHERE family_id = $1 AND is_revoked = false
+	`, familyID); err != nil {
+		return errors.Internal(errors.ErrMsgFailedToRevokeTokenFamily)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Internal(errors.ErrMsgFailedToRevokeTokenFamily)
+	}
+
+	return nil
+}
+
+// RevokeAllTokensByUserID revokes every active token of a user and of the rotation families they belong to.
+func (r *tokenRepository) RevokeAllTokensByUserID(ctx context.Context, userID uint) (*token.RevokedTokens, error) {
+	return r.revokeAllTokens(ctx, "user_id", userID)
+}
+
+// RevokeAllTokensByClientID revokes every active token of a client and of the rotation families they belong to.
+func (r *tokenRepository) RevokeAllTokensByClientID(ctx context.Context, clientID string) (*token.RevokedTokens, error) {
+	return r.revokeAllTokens(ctx, "client_id", clientID)
+}
+
+// revokeAllTokens revokes the active access and refresh tokens whose column matches subject,
+// together with every token in the rotation families of the matching refresh tokens.
+// The column is one of a fixed set of names and is never taken from user input.
+func (r *tokenRepository) revokeAllTokens(ctx context.Context, column string, subject interface{}) (*token.RevokedTokens, error) {
+	families := fmt.Sprintf("SELECT family_id FROM refresh_tokens WHERE %s = $1", column)
+
+	return r.revokeTokens(ctx,
+		fmt.Sprintf("%s = $1 OR token_id IN (SELECT access_token_id FROM refresh_tokens WHERE family_id IN (%s))", column, families),
+		fmt.Sprintf("%s = $1 OR family_id IN (%s)", column, families),
+		errors.ErrMsgFailedToRevokeAllTokens,
+		subject,
+	)
+}
+
+// RevokeAllTokensByUserAndClient revokes every active token a client holds for a user and the
+// rotation families they belong to.
+func (r *tokenRepository) RevokeAllTokensByUserAndClient(ctx context.Context, userID uint, clientID string) (*token.RevokedTokens, error) {
+	families := "SELECT family_id FROM refresh_tokens WHERE user_id = $1 AND client_id = $2"
+
+	return r.revokeTokens(ctx,
+		"(user_id = $1 AND client_id = $2) OR token_id IN (SELECT access_token_id FROM refresh_tokens WHERE family_id IN ("+families+"))",
+		"(user_id = $1 AND client_id = $2) OR family_id IN ("+families+")",
+		errors.ErrMsgFailedToRevokeAllTokens,
+		userID, clientID,
+	)
+}
+
+// RevokeAllTokensByGrantID revokes every active token issued under a grant. Refresh tokens
+// inherit the grant on rotation, so the grant's refresh tokens are its whole families.
+func (r *tokenRepository) RevokeAllTokensByGrantID(ctx context.Context, grantID string) (*token.RevokedTokens, error) {
+	return r.revokeTokens(ctx,
+		"token_id IN (SELECT access_token_id FROM refresh_tokens WHERE grant_id = $1)",
+		"grant_id = $1",
+		errors.ErrMsgFailedToRevokeAllTokens,
+		grantID,
+	)
+}
+
+// revokeTokens revokes the active access tokens matching accessCondition and the active
+// refresh tokens matching refreshCondition, both parameterized by args as $1, $2, and so on.
+// Both updates run in one transaction, so the tokens are never left partially revoked.
+// Failures are reported with errMsg.
+func (r *tokenRepository) revokeTokens(ctx context.Context, accessCondition, refreshCondition, errMsg string, args ...interface{}) (*token.RevokedTokens, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, errors.Internal(errMsg)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE access_tokens
+		SET is_revoked = true
+		WHERE is_revoked = false AND (`+accessCondition+`)
+		RETURNING token_id, expires_at
+	`, args...)
+	if err != nil {
+		return nil, errors.Internal(errMsg)
+	}
+	defer rows.Close()
+
+	revoked := &token.RevokedTokens{}
+	for rows.Next() {
+		var t token.AccessToken
+		if err := rows.Scan(&t.TokenID, &t.ExpiresAt); err != nil {
+			return nil, errors.Internal(errors.ErrMsgFailedToScanAccessToken)
+		}
+		revoked.AccessTokens = append(revoked.AccessTokens, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Internal(errors.ErrMsgErrorIteratingAccessTokens)
+	}
+	rows.Close()
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE refresh_tokens
+		SET is_revoked = true
+		WHERE is_revoked = false AND (`+refreshCondition+`)
+	`, args...)
+	if err != nil {
+		return nil, errors.Internal(errMsg)
+	}
+	if revoked.RefreshTokens, err = result.RowsAffected(); err != nil {
+		return nil, errors.Internal(errors.ErrMsgFailedToGetAffectedRows)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, errors.Internal(errMsg)
+	}
+
+	return revoked, nil
+}
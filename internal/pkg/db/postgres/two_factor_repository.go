@@ -0,0 +1,171 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/app/user"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+)
+
+// twoFactorRepository implements the user.TwoFactorRepository interface using PostgreSQL.
+type twoFactorRepository struct {
+	db DB
+}
+
+// NewTwoFactorRepository creates a new PostgreSQL-based two-factor authentication repository.
+// It takes a database connection and returns a user.TwoFactorRepository interface.
+func NewTwoFactorRepository(db DB) user.TwoFactorRepository {
+	return &twoFactorRepository{db: db}
+}
+
+// SaveTwoFactor stores a pending TOTP enrollment, replacing any authenticator the user had.
+// The replaced authenticator is disabled until the new one is verified.
+func (r *twoFactorRepository) SaveTwoFactor(ctx context.Context, twoFactor *user.TwoFactor) error {
+	query := `
+		INSERT INTO user_totp (user_id, secret_encrypted, enabled, last_used_step, created_at, enabled_at)
+		VALUES ($1, $2, FALSE, 0, $3, NULL)
+		ON CONFLICT (user_id) DO UPDATE SET
+			secret_encrypted = EXCLUDED.secret_encrypted,
+			enabled = FALSE,
+			last_used_step = 0,
+			created_at = EXCLUDED.created_at,
+			enabled_at = NULL
+	`
+
+	_, err := r.db.ExecContext(ctx, query, twoFactor.UserID, twoFactor.SecretEncrypted, twoFactor.CreatedAt)
+	if err != nil {
+		return errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToSaveTwoFactor, err.Error()))
+	}
+
+	return nil
+}
+
+// FindTwoFactor retrieves the user's TOTP authenticator from the PostgreSQL database.
+// Returns nil if the user never enrolled.
+func (r *twoFactorRepository) FindTwoFactor(ctx context.Context, userID uint) (*user.TwoFactor, error) {
+	var t user.TwoFactor
+	var enabledAt sql.NullTime
+	query := `
+		SELECT user_id, secret_encrypted, enabled, last_used_step, created_at, enabled_at
+		FROM user_totp
+		WHERE user_id = $1
+	`
+
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(
+		&t.UserID,
+		&t.SecretEncrypted,
+		&t.Enabled,
+		&t.LastUsedStep,
+		&t.CreatedAt,
+		&enabledAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToFindTwoFactor, err.Error()))
+	}
+
+	if enabledAt.Valid {
+		t.EnabledAt = &enabledAt.Time
+	}
+
+	return &t, nil
+}
+
+// EnableTwoFactor marks the user's authenticator as verified and records the step of the verifying code.
+func (r *twoFactorRepository) EnableTwoFactor(ctx context.Context, userID uint, step int64) error {
+	query := `
+		UPDATE user_totp
+		SET enabled = TRUE, enabled_at = $2, last_used_step = $3
+		WHERE user_id = $1
+	`
+
+	_, err := r.db.ExecContext(ctx, query, userID, time.Now(), step)
+	if err != nil {
+		return errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToSaveTwoFactor, err.Error()))
+	}
+
+	return nil
+}
+
+// UseStep records the time step of an accepted code if it is newer than the last one.
+// The comparison happens in the UPDATE, so concurrent logins cannot both use one code.
+func (r *twoFactorRepository) UseStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	query := `
+		UPDATE user_totp
+		SET last_used_step = $2
+		WHERE user_id = $1 AND enabled AND last_used_step < $2
+	`
+
+	result, err := r.db.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return false, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToSaveTwoFactor, err.Error()))
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToSaveTwoFactor, err.Error()))
+	}
+
+	return rows == 1, nil
+}
+
+// ReplaceRecoveryCodes discards the user's recovery codes and stores the given hashes in one transaction.
+func (r *twoFactorRepository) ReplaceRecoveryCodes(ctx context.Context, userID uint, codeHashes []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToSaveRecoveryCodes, err.Error()))
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToSaveRecoveryCodes, err.Error()))
+	}
+
+	now := time.Now()
+	for _, codeHash := range codeHashes {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO user_recovery_codes (user_id, code_hash, created_at) VALUES ($1, $2, $3)",
+			userID, codeHash, now,
+		); err != nil {
+			return errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToSaveRecoveryCodes, err.Error()))
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToSaveRecoveryCodes, err.Error()))
+	}
+
+	return nil
+}
+
+// UseRecoveryCode marks an unused recovery code matching the hash as used.
+// Returns false if no unused code matched, including one used concurrently.
+func (r *twoFactorRepository) UseRecoveryCode(ctx context.Context, userID uint, codeHash string) (bool, error) {
+	query := `
+		UPDATE user_recovery_codes
+		SET used_at = $3
+		WHERE id = (
+			SELECT id FROM user_recovery_codes
+			WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+			LIMIT 1
+		) AND used_at IS NULL
+	`
+
+	result, err := r.db.ExecContext(ctx, query, userID, codeHash, time.Now())
+	if err != nil {
+		return false, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToUseRecoveryCode, err.Error()))
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToUseRecoveryCode, err.Error()))
+	}
+
+	return rows == 1, nil
+}
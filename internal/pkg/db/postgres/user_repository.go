@@ -14,12 +14,12 @@ import (
 
 // userRepository implements the user.Repository interface using PostgreSQL.
 type userRepository struct {
-	db *sql.DB
+	db DB
 }
 
 // NewUserRepository creates a new PostgreSQL-based user repository.
 // It takes a database connection and returns a user.Repository interface.
-func NewUserRepository(db *sql.DB) user.Repository {
+func NewUserRepository(db DB) user.Repository {
 	return &userRepository{db: db}
 }
 
@@ -53,7 +53,7 @@ func (r *userRepository) Save(ctx context.Context, user *user.User) error {
 				return errors.Conflict(errors.ErrMsgEmailAlreadyRegistered)
 			}
 		}
-		return errors.Internal(errors.ErrMsgFailedToCreateUser + ": " + err.Error())
+		return errors.Internal(errors.ErrMsgFailedToCreateUser).Wrap(err)
 	}
 
 	return nil
@@ -78,12 +78,12 @@ func (r *userRepository) Update(ctx context.Context, user *user.User) error {
 	)
 
 	if err != nil {
-		return errors.Internal(errors.ErrMsgFailedToUpdateUser + ": " + err.Error())
+		return errors.Internal(errors.ErrMsgFailedToUpdateUser).Wrap(err)
 	}
 
 	rows, err := result.RowsAffected()
 	if err != nil {
-		return errors.Internal(errors.ErrMsgFailedToGetAffectedRows + ": " + err.Error())
+		return errors.Internal(errors.ErrMsgFailedToGetAffectedRows).Wrap(err)
 	}
 
 	if rows == 0 {
@@ -103,7 +103,7 @@ func (r *userRepository) FindByID(ctx context.Context, id uint) (*user.User, err
 		FROM users WHERE id = $1
 	`
 
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	err := r.db.QueryRowContext(replicaRead(ctx), query, id).Scan(
 		&u.ID,
 		&u.Username,
 		&u.Email,
@@ -122,7 +122,7 @@ func (r *userRepository) FindByID(ctx context.Context, id uint) (*user.User, err
 		return nil, nil
 	}
 	if err != nil {
-		return nil, errors.Internal(errors.ErrMsgFailedToGetUserByID + ": " + err.Error())
+		return nil, errors.Internal(errors.ErrMsgFailedToGetUserByID).Wrap(err)
 	}
 
 	return &u, nil
@@ -158,7 +158,7 @@ func (r *userRepository) FindByEmail(ctx context.Context, email string) (*user.U
 		return nil, nil
 	}
 	if err != nil {
-		return nil, errors.Internal(errors.ErrMsgFailedToGetUserByEmail + ": " + err.Error())
+		return nil, errors.Internal(errors.ErrMsgFailedToGetUserByEmail).Wrap(err)
 	}
 
 	return &u, nil
@@ -194,7 +194,7 @@ func (r *userRepository) FindByUsername(ctx context.Context, username string) (*
 		return nil, nil
 	}
 	if err != nil {
-		return nil, errors.Internal(errors.ErrMsgFailedToGetUserByUsername + ": " + err.Error())
+		return nil, errors.Internal(errors.ErrMsgFailedToGetUserByUsername).Wrap(err)
 	}
 
 	return &u, nil
@@ -212,12 +212,12 @@ func (r *userRepository) UpdatePassword(ctx context.Context, id uint, passwordHa
 
 	result, err := r.db.ExecContext(ctx, query, id, passwordHash, time.Now())
 	if err != nil {
-		return errors.Internal(errors.ErrMsgFailedToUpdatePassword + ": " + err.Error())
+		return errors.Internal(errors.ErrMsgFailedToUpdatePassword).Wrap(err)
 	}
 
 	rows, err := result.RowsAffected()
 	if err != nil {
-		return errors.Internal(errors.ErrMsgFailedToGetAffectedRows + ": " + err.Error())
+		return errors.Internal(errors.ErrMsgFailedToGetAffectedRows).Wrap(err)
 	}
 
 	if rows == 0 {
@@ -240,7 +240,7 @@ func (r *userRepository) UpdateLastLogin(ctx context.Context, id uint) error {
 
 	_, err := r.db.ExecContext(ctx, query, id, time.Now())
 	if err != nil {
-		return errors.Internal(errors.ErrMsgFailedToUpdateUser + ": " + err.Error()) // Assuming this is a general update failure
+		return errors.Internal(errors.ErrMsgFailedToUpdateUser).Wrap(err) // Assuming this is a general update failure
 	}
 
 	return nil
@@ -254,12 +254,12 @@ func (r *userRepository) Delete(ctx context.Context, id uint) error {
 
 	result, err := r.db.ExecContext(ctx, query, id)
 	if err != nil {
-		return errors.Internal(errors.ErrMsgFailedToDeleteUser + ": " + err.Error())
+		return errors.Internal(errors.ErrMsgFailedToDeleteUser).Wrap(err)
 	}
 
 	rows, err := result.RowsAffected()
 	if err != nil {
-		return errors.Internal(errors.ErrMsgFailedToGetAffectedRows + ": " + err.Error())
+		return errors.Internal(errors.ErrMsgFailedToGetAffectedRows).Wrap(err)
 	}
 
 	if rows == 0 {
@@ -0,0 +1,153 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/verigate/verigate-server/internal/app/user"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+)
+
+// webAuthnRepository implements the user.WebAuthnRepository interface using PostgreSQL.
+type webAuthnRepository struct {
+	db DB
+}
+
+// NewWebAuthnRepository creates a new PostgreSQL-based passkey repository.
+// It takes a database connection and returns a user.WebAuthnRepository interface.
+func NewWebAuthnRepository(db DB) user.WebAuthnRepository {
+	return &webAuthnRepository{db: db}
+}
+
+// SaveCredential inserts a newly registered passkey into the PostgreSQL database.
+// The generated ID is set on the credential.
+func (r *webAuthnRepository) SaveCredential(ctx context.Context, credential *user.WebAuthnCredential) error {
+	query := `
+		INSERT INTO webauthn_credentials (
+			user_id, credential_id, public_key, attestation_type, aaguid,
+			sign_count, flags, transports, name, created_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id
+	`
+
+	err := r.db.QueryRowContext(
+		ctx,
+		query,
+		credential.UserID,
+		credential.CredentialID,
+		credential.PublicKey,
+		credential.AttestationType,
+		credential.AAGUID,
+		int64(credential.SignCount),
+		int16(credential.Flags),
+		pq.Array(credential.Transports),
+		credential.Name,
+		credential.CreatedAt,
+	).Scan(&credential.ID)
+
+	if err != nil {
+		return errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToSaveWebAuthnCredential, err.Error()))
+	}
+
+	return nil
+}
+
+// FindCredentialsByUser retrieves all passkeys registered by the user, oldest first.
+func (r *webAuthnRepository) FindCredentialsByUser(ctx context.Context, userID uint) ([]*user.WebAuthnCredential, error) {
+	query := `
+		SELECT id, user_id, credential_id, public_key, attestation_type, aaguid,
+			sign_count, flags, transports, name, created_at, last_used_at
+		FROM webauthn_credentials
+		WHERE user_id = $1
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToFindWebAuthnCredential, err.Error()))
+	}
+	defer rows.Close()
+
+	var credentials []*user.WebAuthnCredential
+	for rows.Next() {
+		var c user.WebAuthnCredential
+		var signCount int64
+		var flags int16
+		var lastUsedAt sql.NullTime
+
+		if err := rows.Scan(
+			&c.ID,
+			&c.UserID,
+			&c.CredentialID,
+			&c.PublicKey,
+			&c.AttestationType,
+			&c.AAGUID,
+			&signCount,
+			&flags,
+			pq.Array(&c.Transports),
+			&c.Name,
+			&c.CreatedAt,
+			&lastUsedAt,
+		); err != nil {
+			return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToFindWebAuthnCredential, err.Error()))
+		}
+
+		c.SignCount = uint32(signCount)
+		c.Flags = byte(flags)
+		if lastUsedAt.Valid {
+			c.LastUsedAt = &lastUsedAt.Time
+		}
+
+		credentials = append(credentials, &c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToFindWebAuthnCredential, err.Error()))
+	}
+
+	return credentials, nil
+}
+
+// UpdateSignCount stores the new signature counter and the time of use.
+// The counter comparison happens in the UPDATE, so two logins racing with the
+// same counter value cannot both succeed.
+func (r *webAuthnRepository) UpdateSignCount(ctx context.Context, credentialID []byte, signCount uint32) (bool, error) {
+	query := `
+		UPDATE webauthn_credentials
+		SET sign_count = $2, last_used_at = $3
+		WHERE credential_id = $1 AND (sign_count < $2 OR (sign_count = 0 AND $2 = 0))
+	`
+
+	result, err := r.db.ExecContext(ctx, query, credentialID, int64(signCount), time.Now())
+	if err != nil {
+		return false, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToSaveWebAuthnCredential, err.Error()))
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToSaveWebAuthnCredential, err.Error()))
+	}
+
+	return rows == 1, nil
+}
+
+// DeleteCredential removes the passkey if it belongs to the user.
+func (r *webAuthnRepository) DeleteCredential(ctx context.Context, userID uint, credentialID []byte) (bool, error) {
+	query := "DELETE FROM webauthn_credentials WHERE user_id = $1 AND credential_id = $2"
+
+	result, err := r.db.ExecContext(ctx, query, userID, credentialID)
+	if err != nil {
+		return false, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToDeleteWebAuthnCredential, err.Error()))
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToDeleteWebAuthnCredential, err.Error()))
+	}
+
+	return rows == 1, nil
+}
@@ -0,0 +1,126 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/verigate/verigate-server/internal/app/webhook"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+)
+
+// webhookRepository implements the webhook.Repository interface using PostgreSQL.
+type webhookRepository struct {
+	db DB
+}
+
+// NewWebhookRepository creates a new PostgreSQL-based webhook subscription repository.
+// It takes a database connection and returns a webhook.Repository interface.
+func NewWebhookRepository(db DB) webhook.Repository {
+	return &webhookRepository{db: db}
+}
+
+// SaveSubscription persists a new webhook subscription and sets its generated ID.
+func (r *webhookRepository) SaveSubscription(ctx context.Context, subscription *webhook.Subscription) error {
+	query := `
+		INSERT INTO webhook_subscriptions (url, secret, event_types, created_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		subscription.URL,
+		subscription.Secret,
+		pq.Array(subscription.EventTypes),
+		subscription.CreatedAt,
+	).Scan(&subscription.ID)
+
+	if err != nil {
+		return errors.Internal(errors.ErrMsgFailedToSaveWebhookSubscription)
+	}
+
+	return nil
+}
+
+// FindSubscription retrieves a webhook subscription by ID.
+// Returns nil if the subscription does not exist.
+func (r *webhookRepository) FindSubscription(ctx context.Context, id uint) (*webhook.Subscription, error) {
+	var s webhook.Subscription
+	query := `
+		SELECT id, url, secret, event_types, created_at
+		FROM webhook_subscriptions
+		WHERE id = $1
+	`
+
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&s.ID, &s.URL, &s.Secret, pq.Array(&s.EventTypes), &s.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Internal(errors.ErrMsgFailedToFindWebhookSubscription)
+	}
+
+	return &s, nil
+}
+
+// ListSubscriptions retrieves every webhook subscription, oldest first.
+func (r *webhookRepository) ListSubscriptions(ctx context.Context) ([]webhook.Subscription, error) {
+	return r.listSubscriptions(ctx, `
+		SELECT id, url, secret, event_types, created_at
+		FROM webhook_subscriptions
+		ORDER BY id
+	`)
+}
+
+// FindSubscriptionsByEventType retrieves the webhook subscriptions listing an event type.
+// The containment test is served by the GIN index on event_types.
+func (r *webhookRepository) FindSubscriptionsByEventType(ctx context.Context, eventType string) ([]webhook.Subscription, error) {
+	return r.listSubscriptions(ctx, `
+		SELECT id, url, secret, event_types, created_at
+		FROM webhook_subscriptions
+		WHERE event_types @> ARRAY[$1]::TEXT[]
+		ORDER BY id
+	`, eventType)
+}
+
+// listSubscriptions runs a query selecting webhook subscriptions and scans the results.
+func (r *webhookRepository) listSubscriptions(ctx context.Context, query string, args ...interface{}) ([]webhook.Subscription, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Internal(errors.ErrMsgFailedToListWebhookSubscriptions)
+	}
+	defer rows.Close()
+
+	var subscriptions []webhook.Subscription
+	for rows.Next() {
+		var s webhook.Subscription
+		if err := rows.Scan(&s.ID, &s.URL, &s.Secret, pq.Array(&s.EventTypes), &s.CreatedAt); err != nil {
+			return nil, errors.Internal(errors.ErrMsgFailedToListWebhookSubscriptions)
+		}
+		subscriptions = append(subscriptions, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Internal(errors.ErrMsgFailedToListWebhookSubscriptions)
+	}
+
+	return subscriptions, nil
+}
+
+// DeleteSubscription removes a webhook subscription.
+// Returns NotFound if the subscription does not exist.
+func (r *webhookRepository) DeleteSubscription(ctx context.Context, id uint) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM webhook_subscriptions WHERE id = $1", id)
+	if err != nil {
+		return errors.Internal(errors.ErrMsgFailedToDeleteWebhookSubscription)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return errors.Internal(errors.ErrMsgFailedToGetAffectedRows)
+	}
+	if rows == 0 {
+		return errors.NotFound(errors.ErrMsgWebhookSubscriptionNotFound)
+	}
+
+	return nil
+}
@@ -11,8 +11,9 @@ import (
 
 	"github.com/go-redis/redis/v8"
 	"github.com/verigate/verigate-server/internal/app/auth"
+	"github.com/verigate/verigate-server/internal/pkg/tenant"
 	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/verigate/verigate-server/internal/pkg/utils/hash"
 )
 
 // Constants for Redis key prefixes to avoid collisions and organize data
@@ -21,14 +22,18 @@ const (
 	userTokensKeyPrefix   = "auth:user_tokens:"   // Prefix for user's token collection
 )
 
-// authRepository implements the auth.Repository interface using Redis for storage.
+// maxRotationAttempts bounds how often a rotation is retried when the old token changes under it
+const maxRotationAttempts = 3
+
+// authRepository implements the auth.TokenStore interface using Redis for storage.
+// Every token expires from Redis with its lifetime.
 type authRepository struct {
 	client *redis.Client
 }
 
 // NewAuthRepository creates a Redis-based authentication repository.
-// It implements the auth.Repository interface for refresh token management.
-func NewAuthRepository(client *redis.Client) auth.Repository {
+// It implements the auth.TokenStore interface for refresh token management.
+func NewAuthRepository(client *redis.Client) auth.TokenStore {
 	return &authRepository{client: client}
 }
 
@@ -47,11 +52,11 @@ func (r *authRepository) SaveRefreshToken(ctx context.Context, token *auth.Refre
 	pipe := r.client.Pipeline()
 
 	// Store token by token ID
-	tokenKey := refreshTokenKeyPrefix + token.ID
+	tokenKey := tenant.Key(ctx, refreshTokenKeyPrefix+token.ID)
 	pipe.Set(ctx, tokenKey, tokenData, time.Until(token.ExpiresAt))
 
 	// Add to user's token list
-	userTokensKey := userTokensKeyPrefix + fmt.Sprintf("%d", token.UserID)
+	userTokensKey := tenant.Key(ctx, userTokensKeyPrefix+fmt.Sprintf("%d", token.UserID))
 	pipe.SAdd(ctx, userTokensKey, token.ID)
 	pipe.ExpireAt(ctx, userTokensKey, token.ExpiresAt)
 
@@ -67,7 +72,7 @@ func (r *authRepository) SaveRefreshToken(ctx context.Context, token *auth.Refre
 // FindRefreshToken looks up a refresh token by ID.
 // Returns nil if the token doesn't exist.
 func (r *authRepository) FindRefreshToken(ctx context.Context, tokenID string) (*auth.RefreshToken, error) {
-	tokenKey := refreshTokenKeyPrefix + tokenID
+	tokenKey := tenant.Key(ctx, refreshTokenKeyPrefix+tokenID)
 	data, err := r.client.Get(ctx, tokenKey).Result()
 
 	if err == redis.Nil {
@@ -94,7 +99,7 @@ func (r *authRepository) FindRefreshTokenByToken(ctx context.Context, plainTextT
 	var err error
 
 	for {
-		keys, cursor, err = r.client.Scan(ctx, cursor, refreshTokenKeyPrefix+"*", 100).Result()
+		keys, cursor, err = r.client.Scan(ctx, cursor, tenant.Key(ctx, refreshTokenKeyPrefix+"*"), 100).Result()
 		if err != nil {
 			return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToScanRefreshToken, err.Error()))
 		}
@@ -112,8 +117,8 @@ func (r *authRepository) FindRefreshTokenByToken(ctx context.Context, plainTextT
 			}
 
 			// Verify the token using hash compare
-			// Order matters in bcrypt: first param is the hash, second is the plaintext
-			if err := bcrypt.CompareHashAndPassword([]byte(token.Token), []byte(plainTextToken)); err == nil {
+			// Order matters: first param is the hash, second is the plaintext
+			if err := hash.CompareHashAndPassword(token.Token, plainTextToken); err == nil {
 				return &token, nil
 			}
 		}
@@ -128,7 +133,7 @@ func (r *authRepository) FindRefreshTokenByToken(ctx context.Context, plainTextT
 
 // RevokeRefreshToken marks a specific refresh token as revoked.
 func (r *authRepository) RevokeRefreshToken(ctx context.Context, tokenID string) error {
-	tokenKey := refreshTokenKeyPrefix + tokenID
+	tokenKey := tenant.Key(ctx, refreshTokenKeyPrefix+tokenID)
 
 	// Get the existing token
 	data, err := r.client.Get(ctx, tokenKey).Result()
@@ -161,9 +166,71 @@ func (r *authRepository) RevokeRefreshToken(ctx context.Context, tokenID string)
 	return r.client.Set(ctx, tokenKey, updatedData, ttl).Err()
 }
 
+// RotateRefreshToken revokes the old token and saves its successor in one MULTI/EXEC transaction,
+// guarded by a WATCH on the old token so that a concurrent rotation or revocation aborts it.
+// An aborted transaction is retried, and then finds the old token revoked.
+func (r *authRepository) RotateRefreshToken(ctx context.Context, oldTokenID string, newToken *auth.RefreshToken) (bool, error) {
+	oldKey := tenant.Key(ctx, refreshTokenKeyPrefix+oldTokenID)
+	newData, err := json.Marshal(newToken)
+	if err != nil {
+		return false, errors.Internal(errors.ErrMsgFailedToMarshalRefreshToken)
+	}
+
+	rotated := false
+	rotate := func(tx *redis.Tx) error {
+		rotated = false
+
+		data, err := tx.Get(ctx, oldKey).Result()
+		if err == redis.Nil {
+			return nil // Expired or never existed
+		} else if err != nil {
+			return err
+		}
+
+		var old auth.RefreshToken
+		if err := json.Unmarshal([]byte(data), &old); err != nil {
+			return err
+		}
+		if old.IsRevoked || !time.Now().Before(old.ExpiresAt) {
+			return nil
+		}
+
+		old.IsRevoked = true
+		oldData, err := json.Marshal(old)
+		if err != nil {
+			return err
+		}
+
+		userTokensKey := tenant.Key(ctx, userTokensKeyPrefix+fmt.Sprintf("%d", newToken.UserID))
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, oldKey, oldData, time.Until(old.ExpiresAt))
+			pipe.Set(ctx, tenant.Key(ctx, refreshTokenKeyPrefix+newToken.ID), newData, time.Until(newToken.ExpiresAt))
+			pipe.SAdd(ctx, userTokensKey, newToken.ID)
+			pipe.ExpireAt(ctx, userTokensKey, newToken.ExpiresAt)
+			return nil
+		})
+		if err == nil {
+			rotated = true
+		}
+		return err
+	}
+
+	for attempt := 0; attempt < maxRotationAttempts; attempt++ {
+		err = r.client.Watch(ctx, rotate, oldKey)
+		if err != redis.TxFailedErr {
+			break
+		}
+	}
+	if err != nil {
+		return false, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToRotateRefreshToken, err.Error()))
+	}
+
+	return rotated, nil
+}
+
 // RevokeAllUserRefreshTokens revokes all refresh tokens for a user.
 func (r *authRepository) RevokeAllUserRefreshTokens(ctx context.Context, userID uint) error {
-	userTokensKey := userTokensKeyPrefix + fmt.Sprintf("%d", userID)
+	userTokensKey := tenant.Key(ctx, userTokensKeyPrefix+fmt.Sprintf("%d", userID))
 
 	// Get all token IDs for the user
 	tokenIDs, err := r.client.SMembers(ctx, userTokensKey).Result()
@@ -202,3 +269,30 @@ func (r *authRepository) IsRefreshTokenRevoked(ctx context.Context, tokenID stri
 
 	return token.IsRevoked, nil
 }
+
+// DeleteSessionRefreshTokens removes the user's refresh tokens that belong to the session,
+// together with their entries in the user's token set.
+func (r *authRepository) DeleteSessionRefreshTokens(ctx context.Context, userID uint, sessionID string) error {
+	userTokensKey := tenant.Key(ctx, userTokensKeyPrefix+fmt.Sprintf("%d", userID))
+
+	tokenIDs, err := r.client.SMembers(ctx, userTokensKey).Result()
+	if err != nil && err != redis.Nil {
+		return errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToGetRefreshTokens, err.Error()))
+	}
+
+	for _, tokenID := range tokenIDs {
+		token, err := r.FindRefreshToken(ctx, tokenID)
+		if err != nil || token == nil || token.SessionID != sessionID {
+			continue
+		}
+
+		pipe := r.client.TxPipeline()
+		pipe.Del(ctx, tenant.Key(ctx, refreshTokenKeyPrefix+tokenID))
+		pipe.SRem(ctx, userTokensKey, tokenID)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToDeleteRefreshToken, err.Error()))
+		}
+	}
+
+	return nil
+}
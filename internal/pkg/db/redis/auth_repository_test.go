@@ -0,0 +1,14 @@
+package redis
+
+import (
+	"testing"
+
+	"github.com/verigate/verigate-server/internal/app/auth/authtest"
+)
+
+func TestAuthRepositoryConformance(t *testing.T) {
+	authtest.RunTokenStoreTests(t, func(t *testing.T) authtest.Store {
+		client, server := newMiniredisClient(t)
+		return authtest.Store{TokenStore: NewAuthRepository(client), Elapse: server.FastForward}
+	})
+}
@@ -8,11 +8,13 @@ import (
 	"time"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/verigate/verigate-server/internal/pkg/tenant"
 )
 
 // cacheRepository implements a generic cache using Redis.
 // It provides methods for storing, retrieving, and deleting
 // arbitrary data with automatic JSON serialization.
+// Keys are namespaced by the tenant the context is served for.
 type cacheRepository struct {
 	client *redis.Client
 }
@@ -32,18 +34,18 @@ func (r *cacheRepository) Set(ctx context.Context, key string, value interface{}
 		return err
 	}
 
-	return r.client.Set(ctx, key, jsonData, expiration).Err()
+	return r.client.Set(ctx, tenant.Key(ctx, key), jsonData, expiration).Err()
 }
 
 // Get retrieves a value from the cache by its key.
 // Returns the serialized JSON value as a string and any error that occurred.
 // A redis.Nil error is returned if the key doesn't exist.
 func (r *cacheRepository) Get(ctx context.Context, key string) (string, error) {
-	return r.client.Get(ctx, key).Result()
+	return r.client.Get(ctx, tenant.Key(ctx, key)).Result()
 }
 
 // Delete removes a value from the cache by its key.
 // Returns an error if the deletion fails.
 func (r *cacheRepository) Delete(ctx context.Context, key string) error {
-	return r.client.Del(ctx, key).Err()
+	return r.client.Del(ctx, tenant.Key(ctx, key)).Err()
 }
@@ -0,0 +1,59 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/verigate/verigate-server/internal/app/user"
+	"github.com/verigate/verigate-server/internal/pkg/tenant"
+	"github.com/verigate/verigate-server/internal/pkg/utils/hash"
+)
+
+// Redis key prefixes for the login CAPTCHA challenge
+const (
+	captchaIPFailuresKeyPrefix = "user:captcha:ip_failures:" // Failed logins from an IP address
+	captchaRedeemedKeyPrefix   = "user:captcha:redeemed:"    // Hashes of CAPTCHA tokens already used
+)
+
+// captchaRepository implements the user.CaptchaRepository interface using Redis.
+type captchaRepository struct {
+	client *redis.Client
+}
+
+// NewCaptchaRepository creates a Redis-based repository for the state of the login CAPTCHA challenge.
+func NewCaptchaRepository(client *redis.Client) user.CaptchaRepository {
+	return &captchaRepository{client: client}
+}
+
+// IncrementIPFailures counts a failed login from an IP address and returns the failures so far.
+// INCR and EXPIRE run in one transaction so concurrent failures are all counted.
+func (r *captchaRepository) IncrementIPFailures(ctx context.Context, ip string, ttl time.Duration) (int64, error) {
+	key := tenant.Key(ctx, captchaIPFailuresKeyPrefix+ip)
+
+	var incr *redis.IntCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, key)
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+// IPFailures returns the failed logins counted for an IP address, zero when there are none.
+func (r *captchaRepository) IPFailures(ctx context.Context, ip string) (int64, error) {
+	failures, err := r.client.Get(ctx, tenant.Key(ctx, captchaIPFailuresKeyPrefix+ip)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return failures, err
+}
+
+// Redeem marks a CAPTCHA token as used with SETNX, so that of concurrent requests presenting
+// the same token only one succeeds. Only the token's hash is stored.
+func (r *captchaRepository) Redeem(ctx context.Context, token string, ttl time.Duration) (bool, error) {
+	return r.client.SetNX(ctx, tenant.Key(ctx, captchaRedeemedKeyPrefix+hash.HashToken(token)), time.Now().Unix(), ttl).Result()
+}
@@ -0,0 +1,44 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/verigate/verigate-server/internal/app/cleanup"
+	"github.com/verigate/verigate-server/internal/pkg/tenant"
+)
+
+// cleanupLockKey holds the ID of the replica purging expired rows
+const cleanupLockKey = "cleanup:lock"
+
+// releaseLockScript deletes the lock only if it still holds the owner's ID, so a purge that
+// outlived its lock never releases the lock another replica has since taken.
+//
+// KEYS[1] lock key; ARGV[1] owner ID. Returns the number of keys deleted.
+var releaseLockScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// cleanupLockRepository implements the cleanup.LockRepository interface using Redis.
+type cleanupLockRepository struct {
+	client *redis.Client
+}
+
+// NewCleanupLockRepository creates a Redis-based lock for purging expired rows.
+func NewCleanupLockRepository(client *redis.Client) cleanup.LockRepository {
+	return &cleanupLockRepository{client: client}
+}
+
+// Acquire sets the lock to owner unless it is already set.
+func (r *cleanupLockRepository) Acquire(ctx context.Context, owner string, ttl time.Duration) (bool, error) {
+	return r.client.SetNX(ctx, tenant.Key(ctx, cleanupLockKey), owner, ttl).Result()
+}
+
+// Release deletes the lock with releaseLockScript.
+func (r *cleanupLockRepository) Release(ctx context.Context, owner string) error {
+	return releaseLockScript.Run(ctx, r.client, []string{tenant.Key(ctx, cleanupLockKey)}, owner).Err()
+}
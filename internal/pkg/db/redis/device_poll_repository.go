@@ -0,0 +1,48 @@
+// Package redis provides Redis-based implementations of the application's repositories.
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/verigate/verigate-server/internal/app/oauth"
+	"github.com/verigate/verigate-server/internal/pkg/tenant"
+)
+
+// devicePollKeyPrefix is the Redis key prefix for device code poll markers
+const devicePollKeyPrefix = "oauth:device_poll:"
+
+// devicePollRepository implements the oauth.DevicePollRepository interface using Redis.
+// Each poll sets a marker key that lives for the polling interval, so a poll that
+// finds the marker still present arrived too soon.
+type devicePollRepository struct {
+	client *redis.Client
+}
+
+// NewDevicePollRepository creates a Redis-based repository for device flow poll tracking.
+func NewDevicePollRepository(client *redis.Client) oauth.DevicePollRepository {
+	return &devicePollRepository{client: client}
+}
+
+// RecordPoll records a poll for the device code and reports whether it came too fast.
+// SET NX makes the check and the update a single atomic operation, so concurrent
+// polls cannot both be accepted. A too-fast poll restarts the interval.
+func (r *devicePollRepository) RecordPoll(ctx context.Context, deviceCode string, interval time.Duration) (bool, error) {
+	key := tenant.Key(ctx, devicePollKeyPrefix+deviceCode)
+
+	accepted, err := r.client.SetNX(ctx, key, time.Now().Unix(), interval).Result()
+	if err != nil {
+		return false, err
+	}
+	if accepted {
+		return false, nil
+	}
+
+	// Polling too fast; the device must wait a full interval from this poll
+	if err := r.client.Set(ctx, key, time.Now().Unix(), interval).Err(); err != nil {
+		return true, err
+	}
+
+	return true, nil
+}
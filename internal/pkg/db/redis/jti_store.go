@@ -0,0 +1,41 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/verigate/verigate-server/internal/app/replay"
+	"github.com/verigate/verigate-server/internal/pkg/tenant"
+	"github.com/verigate/verigate-server/internal/pkg/utils/hash"
+)
+
+// jtiKeyPrefix is the Redis key prefix for recorded JWT IDs
+const jtiKeyPrefix = "jti:"
+
+// jtiStore implements the replay.JTIStore interface using Redis.
+// Each jti is stored as a key that expires with its token. The jti is hashed into the key,
+// so a client choosing long values cannot inflate the keys.
+type jtiStore struct {
+	client *redis.Client
+}
+
+// NewJTIStore creates a Redis-based store of one-time JWT IDs.
+func NewJTIStore(client *redis.Client) replay.JTIStore {
+	return &jtiStore{client: client}
+}
+
+// Record stores the jti in the namespace and reports whether it was already stored.
+// SET NX EX checks for the key and creates it with its expiry in one atomic command.
+func (s *jtiStore) Record(ctx context.Context, namespace, jti string, ttl time.Duration) (bool, error) {
+	if ttl <= 0 {
+		return true, nil
+	}
+
+	key := tenant.Key(ctx, jtiKeyPrefix+namespace+":"+hash.HashToken(jti))
+	stored, err := s.client.SetNX(ctx, key, time.Now().Unix(), ttl).Result()
+	if err != nil {
+		return false, err
+	}
+	return !stored, nil
+}
@@ -0,0 +1,64 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestJTIStoreAcceptsConcurrentSubmissionOnce(t *testing.T) {
+	const rounds = 50
+	client, _ := newMiniredisClient(t)
+	store := NewJTIStore(client)
+	ctx := context.Background()
+
+	for round := 0; round < rounds; round++ {
+		jti := fmt.Sprintf("jti-%d", round)
+
+		// Release both submissions at once, so they race to record the same jti
+		start := make(chan struct{})
+		var replays [2]bool
+		var wg sync.WaitGroup
+		for i := range replays {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				<-start
+				replayed, err := store.Record(ctx, "client_assertion", jti, time.Minute)
+				if err != nil {
+					t.Errorf("Record failed: %v", err)
+				}
+				replays[i] = replayed
+			}(i)
+		}
+		close(start)
+		wg.Wait()
+
+		if replays[0] == replays[1] {
+			t.Fatalf("round %d: got replayed %v and %v, want exactly one submission accepted", round, replays[0], replays[1])
+		}
+	}
+}
+
+func TestJTIStoreForgetsExpiredJTI(t *testing.T) {
+	client, server := newMiniredisClient(t)
+	store := NewJTIStore(client)
+	ctx := context.Background()
+
+	if replayed, err := store.Record(ctx, "client_assertion", "jti-1", time.Minute); err != nil || replayed {
+		t.Fatalf("first Record = %v, %v, want accepted", replayed, err)
+	}
+	if replayed, err := store.Record(ctx, "dpop", "jti-1", time.Minute); err != nil || replayed {
+		t.Errorf("Record in another namespace = %v, %v, want accepted", replayed, err)
+	}
+	if replayed, err := store.Record(ctx, "client_assertion", "jti-1", time.Minute); err != nil || !replayed {
+		t.Errorf("repeated Record = %v, %v, want a replay", replayed, err)
+	}
+
+	server.FastForward(time.Minute + time.Second)
+	if replayed, err := store.Record(ctx, "client_assertion", "jti-1", time.Minute); err != nil || replayed {
+		t.Errorf("Record after expiry = %v, %v, want accepted", replayed, err)
+	}
+}
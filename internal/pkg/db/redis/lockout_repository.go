@@ -0,0 +1,96 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/verigate/verigate-server/internal/app/user"
+	"github.com/verigate/verigate-server/internal/pkg/tenant"
+)
+
+// Redis key prefixes for login lockout tracking
+const (
+	lockoutFailuresKeyPrefix = "user:lockout:failures:" // Consecutive failed logins
+	lockoutCountKeyPrefix    = "user:lockout:count:"    // Lockouts so far, for the backoff
+	lockoutLockedKeyPrefix   = "user:lockout:locked:"   // Present while the login is locked
+)
+
+// lockoutRepository implements the user.LockoutRepository interface using Redis.
+// Counters expire on their own, so logins that stop failing leave nothing behind.
+type lockoutRepository struct {
+	client *redis.Client
+}
+
+// NewLockoutRepository creates a Redis-based repository for failed login and lockout tracking.
+func NewLockoutRepository(client *redis.Client) user.LockoutRepository {
+	return &lockoutRepository{client: client}
+}
+
+// IncrementFailures counts a failed login and returns the consecutive failures so far.
+// INCR and EXPIRE run in one transaction so concurrent failures are all counted.
+func (r *lockoutRepository) IncrementFailures(ctx context.Context, login string, ttl time.Duration) (int64, error) {
+	return r.increment(ctx, tenant.Key(ctx, lockoutFailuresKeyPrefix+login), ttl)
+}
+
+// IncrementLockouts counts a lockout and returns the lockouts so far.
+func (r *lockoutRepository) IncrementLockouts(ctx context.Context, login string, ttl time.Duration) (int64, error) {
+	return r.increment(ctx, tenant.Key(ctx, lockoutCountKeyPrefix+login), ttl)
+}
+
+// Lock locks the login for duration and restarts the failure count,
+// so the next lockout takes another full run of failures.
+func (r *lockoutRepository) Lock(ctx context.Context, login string, duration time.Duration) error {
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, tenant.Key(ctx, lockoutLockedKeyPrefix+login), time.Now().Unix(), duration)
+		pipe.Del(ctx, tenant.Key(ctx, lockoutFailuresKeyPrefix+login))
+		return nil
+	})
+	return err
+}
+
+// Failures returns the consecutive failures counted for the login, zero when there are none.
+func (r *lockoutRepository) Failures(ctx context.Context, login string) (int64, error) {
+	failures, err := r.client.Get(ctx, tenant.Key(ctx, lockoutFailuresKeyPrefix+login)).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return failures, err
+}
+
+// LockedFor returns the remaining lifetime of the lock key, zero when the login is not locked.
+func (r *lockoutRepository) LockedFor(ctx context.Context, login string) (time.Duration, error) {
+	ttl, err := r.client.PTTL(ctx, tenant.Key(ctx, lockoutLockedKeyPrefix+login)).Result()
+	if err != nil {
+		return 0, err
+	}
+	// Negative values mean the key does not exist or never expires
+	if ttl < 0 {
+		return 0, nil
+	}
+	return ttl, nil
+}
+
+// ResetFailures forgets the failure count and lockout history after a successful login.
+func (r *lockoutRepository) ResetFailures(ctx context.Context, login string) error {
+	return r.client.Del(ctx, tenant.Key(ctx, lockoutFailuresKeyPrefix+login), tenant.Key(ctx, lockoutCountKeyPrefix+login)).Err()
+}
+
+// Clear removes the lockout, failure count, and lockout history of the login.
+func (r *lockoutRepository) Clear(ctx context.Context, login string) error {
+	return r.client.Del(ctx, tenant.Key(ctx, lockoutLockedKeyPrefix+login), tenant.Key(ctx, lockoutFailuresKeyPrefix+login), tenant.Key(ctx, lockoutCountKeyPrefix+login)).Err()
+}
+
+// increment increments the counter under key and restarts its expiry.
+func (r *lockoutRepository) increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	var incr *redis.IntCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.Incr(ctx, key)
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
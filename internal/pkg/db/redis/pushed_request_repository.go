@@ -0,0 +1,61 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/verigate/verigate-server/internal/app/oauth"
+	"github.com/verigate/verigate-server/internal/pkg/tenant"
+)
+
+// pushedRequestKeyPrefix is the Redis key prefix for pushed authorization requests
+const pushedRequestKeyPrefix = "oauth:pushed_request:"
+
+// pushedRequestRepository implements the oauth.PushedRequestRepository interface using Redis.
+type pushedRequestRepository struct {
+	client *redis.Client
+}
+
+// NewPushedRequestRepository creates a Redis-based repository for pushed authorization requests.
+func NewPushedRequestRepository(client *redis.Client) oauth.PushedRequestRepository {
+	return &pushedRequestRepository{client: client}
+}
+
+// SavePushedRequest stores the request under handle, expiring after ttl.
+func (r *pushedRequestRepository) SavePushedRequest(ctx context.Context, handle string, req *oauth.AuthorizeRequest, ttl time.Duration) error {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(ctx, tenant.Key(ctx, pushedRequestKeyPrefix+handle), data, ttl).Err()
+}
+
+// FindPushedRequest returns the request stored under handle without consuming it.
+// Returns nil if the key does not exist.
+func (r *pushedRequestRepository) FindPushedRequest(ctx context.Context, handle string) (*oauth.AuthorizeRequest, error) {
+	return decodePushedRequest(r.client.Get(ctx, tenant.Key(ctx, pushedRequestKeyPrefix+handle)).Bytes())
+}
+
+// TakePushedRequest returns and removes the request in a single GETDEL,
+// so concurrent uses cannot both succeed. Returns nil if the key does not exist.
+func (r *pushedRequestRepository) TakePushedRequest(ctx context.Context, handle string) (*oauth.AuthorizeRequest, error) {
+	return decodePushedRequest(r.client.GetDel(ctx, tenant.Key(ctx, pushedRequestKeyPrefix+handle)).Bytes())
+}
+
+// decodePushedRequest decodes a stored pushed request, mapping a missing key to nil.
+func decodePushedRequest(data []byte, err error) (*oauth.AuthorizeRequest, error) {
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var req oauth.AuthorizeRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
@@ -4,11 +4,15 @@ package redis
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/verigate/verigate-server/internal/pkg/breaker"
 	"github.com/verigate/verigate-server/internal/pkg/config"
+	"github.com/verigate/verigate-server/internal/pkg/metrics"
 )
 
 // client is the shared Redis client instance used across the application
@@ -17,19 +21,48 @@ var client *redis.Client
 // NewConnection establishes a new Redis connection using configuration settings.
 // It initializes the Redis client, validates the connection with a ping,
 // and stores the client in a package-level variable for later access.
-// Returns the Redis client or an error if the connection fails.
+// Commands failing with transient errors are retried with jittered exponential backoff,
+// and a circuit breaker rejects commands while Redis keeps failing; see breakerHook.
+// Returns the Redis client or an error if the settings are invalid or the connection fails.
 func NewConnection() (*redis.Client, error) {
 	db, err := strconv.Atoi(config.AppConfig.RedisDB)
 	if err != nil {
 		db = 0
 	}
 
+	minBackoff, err := time.ParseDuration(config.AppConfig.RedisMinRetryBackoff)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis minimum retry backoff: %w", err)
+	}
+	maxBackoff, err := time.ParseDuration(config.AppConfig.RedisMaxRetryBackoff)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis maximum retry backoff: %w", err)
+	}
+	cooldown, err := time.ParseDuration(config.AppConfig.RedisBreakerCooldown)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis circuit breaker cooldown: %w", err)
+	}
+
+	// go-redis treats zero retries as its default of three; -1 disables them
+	maxRetries := config.AppConfig.RedisMaxRetries
+	if maxRetries == 0 {
+		maxRetries = -1
+	}
+
 	client = redis.NewClient(&redis.Options{
-		Addr:     fmt.Sprintf("%s:%s", config.AppConfig.RedisHost, config.AppConfig.RedisPort),
-		Password: config.AppConfig.RedisPassword,
-		DB:       db,
+		Addr:            fmt.Sprintf("%s:%s", config.AppConfig.RedisHost, config.AppConfig.RedisPort),
+		Password:        config.AppConfig.RedisPassword,
+		DB:              db,
+		MaxRetries:      maxRetries,
+		MinRetryBackoff: minBackoff,
+		MaxRetryBackoff: maxBackoff,
 	})
 
+	if threshold := config.AppConfig.RedisBreakerThreshold; threshold > 0 {
+		metrics.RedisCircuitBreakerState.Set(float64(breaker.StateClosed))
+		client.AddHook(&breakerHook{breaker: breaker.New(threshold, cooldown, recordBreakerState)})
+	}
+
 	ctx := context.Background()
 	if err := client.Ping(ctx).Err(); err != nil {
 		return nil, fmt.Errorf("failed to connect to redis: %w", err)
@@ -43,3 +76,62 @@ func NewConnection() (*redis.Client, error) {
 func GetClient() *redis.Client {
 	return client
 }
+
+// breakerHook guards every Redis command and pipeline with a circuit breaker. Once Redis has
+// failed the configured number of times in a row, commands fail at once with breaker.ErrOpen
+// instead of waiting on timeouts, so callers apply their fallbacks, such as the rate limiter's
+// fail-closed setting. A command counts as failed only after go-redis exhausted its retries.
+type breakerHook struct {
+	breaker *breaker.Breaker
+}
+
+// BeforeProcess rejects the command if the breaker is open.
+func (h *breakerHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return ctx, h.breaker.Allow()
+}
+
+// AfterProcess records the outcome of a command the breaker let through.
+func (h *breakerHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	if !stderrors.Is(cmd.Err(), breaker.ErrOpen) {
+		h.breaker.Record(isUnavailable(cmd.Err()))
+	}
+	return nil
+}
+
+// BeforeProcessPipeline rejects the pipeline if the breaker is open.
+func (h *breakerHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return ctx, h.breaker.Allow()
+}
+
+// AfterProcessPipeline records the outcome of a pipeline the breaker let through,
+// which failed if any of its commands did not reach Redis.
+func (h *breakerHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	failed := false
+	for _, cmd := range cmds {
+		if stderrors.Is(cmd.Err(), breaker.ErrOpen) {
+			return nil
+		}
+		failed = failed || isUnavailable(cmd.Err())
+	}
+	h.breaker.Record(failed)
+	return nil
+}
+
+// isUnavailable reports whether a command error means Redis could not serve the command.
+// Replies from the server, redis.Nil among them, show it is up, and a command the caller
+// cancelled says nothing about its health.
+func isUnavailable(err error) bool {
+	if err == nil || stderrors.Is(err, context.Canceled) {
+		return false
+	}
+	var redisErr redis.Error
+	return !stderrors.As(err, &redisErr)
+}
+
+// recordBreakerState exports the state of the Redis circuit breaker and counts its trips.
+func recordBreakerState(state breaker.State) {
+	metrics.RedisCircuitBreakerState.Set(float64(state))
+	if state == breaker.StateOpen {
+		metrics.RedisCircuitBreakerTrips.Inc()
+	}
+}
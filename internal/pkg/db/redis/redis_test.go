@@ -0,0 +1,18 @@
+package redis
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// newMiniredisClient returns a client of a fresh in-process Redis server, along with the server.
+func newMiniredisClient(tb testing.TB) (*redis.Client, *miniredis.Miniredis) {
+	tb.Helper()
+
+	server := miniredis.RunT(tb)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), PoolSize: 64})
+	tb.Cleanup(func() { client.Close() })
+	return client, server
+}
@@ -0,0 +1,220 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/verigate/verigate-server/internal/app/auth"
+	"github.com/verigate/verigate-server/internal/pkg/tenant"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+)
+
+// Redis key prefixes for web sessions
+const (
+	sessionKeyPrefix      = "auth:session:"       // Prefix for individual session storage
+	userSessionsKeyPrefix = "auth:user_sessions:" // Prefix for the set of a user's session IDs
+)
+
+// saveSessionWithinLimitScript counts the user's live sessions, dropping members whose session
+// expired, and saves the new session only if the count is below the limit, in one step.
+//
+// KEYS[1] session key; KEYS[2] user session set key; ARGV[1] session key prefix; ARGV[2] session ID;
+// ARGV[3] session data; ARGV[4] time to live in milliseconds; ARGV[5] absolute expiry in seconds;
+// ARGV[6] limit. Returns 1 if the session was saved, 0 otherwise.
+var saveSessionWithinLimitScript = redis.NewScript(`
+local live = 0
+for _, id in ipairs(redis.call('SMEMBERS', KEYS[2])) do
+	if redis.call('EXISTS', ARGV[1] .. id) == 1 then
+		live = live + 1
+	else
+		redis.call('SREM', KEYS[2], id)
+	end
+end
+if live >= tonumber(ARGV[6]) then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[3], 'PX', ARGV[4])
+redis.call('SADD', KEYS[2], ARGV[2])
+redis.call('EXPIREAT', KEYS[2], ARGV[5])
+return 1
+`)
+
+// sessionRepository implements the auth.SessionRepository interface using Redis.
+type sessionRepository struct {
+	client *redis.Client
+}
+
+// NewSessionRepository creates a Redis-based web session repository.
+// Sessions are shared by every server instance using the same Redis.
+func NewSessionRepository(client *redis.Client) auth.SessionRepository {
+	return &sessionRepository{client: client}
+}
+
+// SaveSession stores a session under its ID, expiring after ttl, and adds it to the
+// user's session set, which is kept until the session's absolute expiry.
+func (r *sessionRepository) SaveSession(ctx context.Context, session *auth.Session, ttl time.Duration) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return errors.Internal(errors.ErrMsgFailedToMarshalSession)
+	}
+
+	userSessionsKey := tenant.Key(ctx, userSessionsKeyPrefix+fmt.Sprintf("%d", session.UserID))
+
+	pipe := r.client.TxPipeline()
+	pipe.Set(ctx, tenant.Key(ctx, sessionKeyPrefix+session.ID), data, ttl)
+	pipe.SAdd(ctx, userSessionsKey, session.ID)
+	pipe.ExpireAt(ctx, userSessionsKey, session.AbsoluteExpiresAt)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToSaveSession, err.Error()))
+	}
+
+	return nil
+}
+
+// SaveSessionWithinLimit stores a session like SaveSession in a single script that first counts
+// the live sessions in the user's session set, so the count cannot change before the save.
+func (r *sessionRepository) SaveSessionWithinLimit(ctx context.Context, session *auth.Session, ttl time.Duration, limit int) (bool, error) {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return false, errors.Internal(errors.ErrMsgFailedToMarshalSession)
+	}
+
+	keys := []string{
+		tenant.Key(ctx, sessionKeyPrefix+session.ID),
+		tenant.Key(ctx, userSessionsKeyPrefix+fmt.Sprintf("%d", session.UserID)),
+	}
+	saved, err := saveSessionWithinLimitScript.Run(ctx, r.client, keys,
+		tenant.Key(ctx, sessionKeyPrefix), session.ID, data, ttl.Milliseconds(), session.AbsoluteExpiresAt.Unix(), limit).Int()
+	if err != nil {
+		return false, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToSaveSession, err.Error()))
+	}
+
+	return saved == 1, nil
+}
+
+// FindSession looks up a session by ID.
+// Returns nil if the session doesn't exist or has expired.
+func (r *sessionRepository) FindSession(ctx context.Context, id string) (*auth.Session, error) {
+	data, err := r.client.Get(ctx, tenant.Key(ctx, sessionKeyPrefix+id)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToFindSession, err.Error()))
+	}
+
+	var session auth.Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, errors.Internal(errors.ErrMsgFailedToUnmarshalSession)
+	}
+
+	return &session, nil
+}
+
+// TouchSession resets the session's time to live with a single EXPIRE.
+// EXPIRE does nothing on a missing key, so an expired session is never revived.
+func (r *sessionRepository) TouchSession(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	ok, err := r.client.Expire(ctx, tenant.Key(ctx, sessionKeyPrefix+id), ttl).Result()
+	if err != nil {
+		return false, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToSaveSession, err.Error()))
+	}
+	return ok, nil
+}
+
+// DeleteSession removes a session and its entry in the user's session set.
+func (r *sessionRepository) DeleteSession(ctx context.Context, id string) error {
+	session, err := r.FindSession(ctx, id)
+	if err != nil {
+		return err
+	}
+	if session == nil {
+		return nil
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.Del(ctx, tenant.Key(ctx, sessionKeyPrefix+id))
+	pipe.SRem(ctx, tenant.Key(ctx, userSessionsKeyPrefix+fmt.Sprintf("%d", session.UserID)), id)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToDeleteSession, err.Error()))
+	}
+
+	return nil
+}
+
+// DeleteUserSessions removes every session in the user's session set, then the set itself.
+// Members whose session already expired are deleted harmlessly.
+func (r *sessionRepository) DeleteUserSessions(ctx context.Context, userID uint) error {
+	userSessionsKey := tenant.Key(ctx, userSessionsKeyPrefix+fmt.Sprintf("%d", userID))
+
+	ids, err := r.client.SMembers(ctx, userSessionsKey).Result()
+	if err != nil {
+		return errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToDeleteSession, err.Error()))
+	}
+
+	keys := make([]string, 0, len(ids)+1)
+	for _, id := range ids {
+		keys = append(keys, tenant.Key(ctx, sessionKeyPrefix+id))
+	}
+	keys = append(keys, userSessionsKey)
+
+	if err := r.client.Del(ctx, keys...).Err(); err != nil {
+		return errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToDeleteSession, err.Error()))
+	}
+
+	return nil
+}
+
+// ListUserSessions loads every session in the user's session set with a single MGET, oldest first.
+// Members whose session already expired are removed from the set.
+func (r *sessionRepository) ListUserSessions(ctx context.Context, userID uint) ([]*auth.Session, error) {
+	userSessionsKey := tenant.Key(ctx, userSessionsKeyPrefix+fmt.Sprintf("%d", userID))
+
+	ids, err := r.client.SMembers(ctx, userSessionsKey).Result()
+	if err != nil {
+		return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToFindSession, err.Error()))
+	}
+	if len(ids) == 0 {
+		return []*auth.Session{}, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = tenant.Key(ctx, sessionKeyPrefix+id)
+	}
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToFindSession, err.Error()))
+	}
+
+	sessions := make([]*auth.Session, 0, len(values))
+	var stale []interface{}
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			stale = append(stale, ids[i])
+			continue
+		}
+		var session auth.Session
+		if err := json.Unmarshal([]byte(data), &session); err != nil {
+			return nil, errors.Internal(errors.ErrMsgFailedToUnmarshalSession)
+		}
+		sessions = append(sessions, &session)
+	}
+
+	if len(stale) > 0 {
+		// Not critical, the members are removed again on the next listing
+		r.client.SRem(ctx, userSessionsKey, stale...)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		if sessions[i].CreatedAt.Equal(sessions[j].CreatedAt) {
+			return sessions[i].SID < sessions[j].SID
+		}
+		return sessions[i].CreatedAt.Before(sessions[j].CreatedAt)
+	})
+	return sessions, nil
+}
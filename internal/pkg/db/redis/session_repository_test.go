@@ -0,0 +1,90 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/app/auth"
+)
+
+// newSession returns a session of the user created now.
+func newSession(id string, userID uint) *auth.Session {
+	now := time.Now()
+	return &auth.Session{ID: id, SID: id, UserID: userID, CreatedAt: now, AbsoluteExpiresAt: now.Add(time.Hour)}
+}
+
+func TestSaveSessionWithinLimitUnderConcurrency(t *testing.T) {
+	const (
+		limit  = 3
+		logins = 20
+	)
+	client, _ := newMiniredisClient(t)
+	repo := NewSessionRepository(client)
+	ctx := context.Background()
+
+	// Release every login at once, so they race for the remaining slots
+	start := make(chan struct{})
+	var saved int32
+	var wg sync.WaitGroup
+	for i := 0; i < logins; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			ok, err := repo.SaveSessionWithinLimit(ctx, newSession(fmt.Sprintf("session-%d", i), 1), time.Hour, limit)
+			if err != nil {
+				t.Errorf("SaveSessionWithinLimit failed: %v", err)
+			}
+			if ok {
+				atomic.AddInt32(&saved, 1)
+			}
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	if saved != limit {
+		t.Errorf("saved %d sessions, want %d", saved, limit)
+	}
+	sessions, err := repo.ListUserSessions(ctx, 1)
+	if err != nil {
+		t.Fatalf("ListUserSessions failed: %v", err)
+	}
+	if len(sessions) != limit {
+		t.Errorf("user holds %d sessions, want %d", len(sessions), limit)
+	}
+}
+
+func TestSaveSessionWithinLimitIgnoresExpiredSessions(t *testing.T) {
+	client, _ := newMiniredisClient(t)
+	repo := NewSessionRepository(client)
+	ctx := context.Background()
+
+	if err := repo.SaveSession(ctx, newSession("live", 1), time.Hour); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+	if err := repo.SaveSession(ctx, newSession("ended", 1), time.Hour); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+	// An expired session leaves its member in the user's session set
+	if err := client.Del(ctx, sessionKeyPrefix+"ended").Err(); err != nil {
+		t.Fatalf("failed to expire session: %v", err)
+	}
+
+	ok, err := repo.SaveSessionWithinLimit(ctx, newSession("new", 1), time.Hour, 2)
+	if err != nil || !ok {
+		t.Fatalf("got %v, %v, want the session saved beside one live session", ok, err)
+	}
+	ok, err = repo.SaveSessionWithinLimit(ctx, newSession("over", 1), time.Hour, 2)
+	if err != nil || ok {
+		t.Errorf("got %v, %v, want the session rejected at the limit", ok, err)
+	}
+	ok, err = repo.SaveSessionWithinLimit(ctx, newSession("other", 2), time.Hour, 2)
+	if err != nil || !ok {
+		t.Errorf("got %v, %v, want another user's session saved", ok, err)
+	}
+}
@@ -0,0 +1,41 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/verigate/verigate-server/internal/app/user"
+	"github.com/verigate/verigate-server/internal/pkg/tenant"
+)
+
+// webAuthnSessionKeyPrefix is the Redis key prefix for passkey ceremony state
+const webAuthnSessionKeyPrefix = "user:webauthn:session:"
+
+// webAuthnSessionRepository implements the user.WebAuthnSessionRepository interface using Redis.
+type webAuthnSessionRepository struct {
+	client *redis.Client
+}
+
+// NewWebAuthnSessionRepository creates a Redis-based repository for passkey ceremony state.
+func NewWebAuthnSessionRepository(client *redis.Client) user.WebAuthnSessionRepository {
+	return &webAuthnSessionRepository{client: client}
+}
+
+// SaveSession stores the ceremony state under handle, expiring after ttl.
+func (r *webAuthnSessionRepository) SaveSession(ctx context.Context, handle string, data []byte, ttl time.Duration) error {
+	return r.client.Set(ctx, tenant.Key(ctx, webAuthnSessionKeyPrefix+handle), data, ttl).Err()
+}
+
+// TakeSession returns and removes the ceremony state in a single GETDEL,
+// so a challenge cannot be answered twice. Returns nil if the key does not exist.
+func (r *webAuthnSessionRepository) TakeSession(ctx context.Context, handle string) ([]byte, error) {
+	data, err := r.client.GetDel(ctx, tenant.Key(ctx, webAuthnSessionKeyPrefix+handle)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,140 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/verigate/verigate-server/internal/app/webhook"
+	"github.com/verigate/verigate-server/internal/pkg/tenant"
+)
+
+// Redis keys for the webhook delivery queue
+const (
+	webhookQueueKey          = "webhook:queue"        // Sorted set of delivery IDs scored by when they are due, in milliseconds
+	webhookDeliveryKeyPrefix = "webhook:delivery:"    // Delivery records, JSON-encoded
+	webhookDeadLettersKey    = "webhook:dead_letters" // List of dead letters, most recent first
+)
+
+// claimDueScript takes the deliveries due by now and pushes their score out to the end of the
+// lease in one step, so concurrent workers never claim the same delivery twice.
+//
+// KEYS[1] queue key; ARGV[1] now in milliseconds; ARGV[2] end of the lease in milliseconds; ARGV[3] limit.
+// Returns the IDs of the claimed deliveries.
+var claimDueScript = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, tonumber(ARGV[3]))
+for _, id in ipairs(ids) do
+	redis.call('ZADD', KEYS[1], ARGV[2], id)
+end
+return ids
+`)
+
+// webhookQueueRepository implements the webhook.QueueRepository interface using Redis.
+type webhookQueueRepository struct {
+	client *redis.Client
+}
+
+// NewWebhookQueueRepository creates a Redis-based queue for webhook deliveries.
+func NewWebhookQueueRepository(client *redis.Client) webhook.QueueRepository {
+	return &webhookQueueRepository{client: client}
+}
+
+// Enqueue stores the delivery and schedules it for its NextAttemptAt in one transaction.
+func (r *webhookQueueRepository) Enqueue(ctx context.Context, delivery *webhook.Delivery) error {
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, tenant.Key(ctx, webhookDeliveryKeyPrefix+delivery.ID), data, 0)
+		pipe.ZAdd(ctx, tenant.Key(ctx, webhookQueueKey), &redis.Z{
+			Score:  float64(delivery.NextAttemptAt.UnixMilli()),
+			Member: delivery.ID,
+		})
+		return nil
+	})
+	return err
+}
+
+// ClaimDue leases up to limit due deliveries with claimDueScript and loads their records.
+// IDs whose record is gone are dropped from the queue.
+func (r *webhookQueueRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*webhook.Delivery, error) {
+	queueKey := tenant.Key(ctx, webhookQueueKey)
+	ids, err := claimDueScript.Run(ctx, r.client, []string{queueKey}, now.UnixMilli(), now.Add(lease).UnixMilli(), limit).StringSlice()
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = tenant.Key(ctx, webhookDeliveryKeyPrefix+id)
+	}
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]*webhook.Delivery, 0, len(ids))
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			r.client.ZRem(ctx, queueKey, ids[i])
+			continue
+		}
+		var delivery webhook.Delivery
+		if err := json.Unmarshal([]byte(data), &delivery); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, &delivery)
+	}
+	return deliveries, nil
+}
+
+// Complete removes the delivery and its schedule in one transaction.
+func (r *webhookQueueRepository) Complete(ctx context.Context, id string) error {
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, tenant.Key(ctx, webhookQueueKey), id)
+		pipe.Del(ctx, tenant.Key(ctx, webhookDeliveryKeyPrefix+id))
+		return nil
+	})
+	return err
+}
+
+// DeadLetter moves the delivery from the queue to the dead-letter list in one transaction,
+// trimming the list to its limit most recent entries.
+func (r *webhookQueueRepository) DeadLetter(ctx context.Context, delivery *webhook.Delivery, limit int) error {
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+
+	deadLettersKey := tenant.Key(ctx, webhookDeadLettersKey)
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZRem(ctx, tenant.Key(ctx, webhookQueueKey), delivery.ID)
+		pipe.Del(ctx, tenant.Key(ctx, webhookDeliveryKeyPrefix+delivery.ID))
+		pipe.LPush(ctx, deadLettersKey, data)
+		pipe.LTrim(ctx, deadLettersKey, 0, int64(limit-1))
+		return nil
+	})
+	return err
+}
+
+// ListDeadLetters retrieves up to limit dead letters, most recent first.
+func (r *webhookQueueRepository) ListDeadLetters(ctx context.Context, limit int) ([]webhook.Delivery, error) {
+	values, err := r.client.LRange(ctx, tenant.Key(ctx, webhookDeadLettersKey), 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]webhook.Delivery, 0, len(values))
+	for _, value := range values {
+		var delivery webhook.Delivery
+		if err := json.Unmarshal([]byte(value), &delivery); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,60 @@
+// Package routing tells the database layer whether the reads of a request may be served by a
+// read replica. Reads a repository marks as safe go to the replica unless the request forced
+// them to the primary with WithPrimary or has already written through the database, so that a
+// request always reads its own writes.
+package routing
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+)
+
+// primaryContextKey marks a context whose reads must be served by the primary
+type primaryContextKey struct{}
+
+// writesContextKey is the context key of the write tracker of a request
+type writesContextKey struct{}
+
+// writeTracker records whether a request has written through the database. It is shared by
+// every context derived from the request's, so a write in one call is seen by the next.
+type writeTracker struct {
+	wrote atomic.Bool
+}
+
+// WithPrimary returns a copy of ctx whose reads are all served by the primary, for flows that
+// need strong consistency, such as reading what an earlier request just wrote.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryContextKey{}, true)
+}
+
+// WithWriteTracking returns a copy of ctx that records writes made with it, after which its
+// reads are served by the primary.
+func WithWriteTracking(ctx context.Context) context.Context {
+	return context.WithValue(ctx, writesContextKey{}, &writeTracker{})
+}
+
+// RecordWrite notes that ctx was used to write through the database. It has no effect on a
+// context without write tracking.
+func RecordWrite(ctx context.Context) {
+	if tracker, ok := ctx.Value(writesContextKey{}).(*writeTracker); ok {
+		tracker.wrote.Store(true)
+	}
+}
+
+// PrimaryRequired reports whether the reads of ctx must be served by the primary: it was
+// derived from WithPrimary, or a write was recorded with it.
+func PrimaryRequired(ctx context.Context) bool {
+	if forced, _ := ctx.Value(primaryContextKey{}).(bool); forced {
+		return true
+	}
+	tracker, ok := ctx.Value(writesContextKey{}).(*writeTracker)
+	return ok && tracker.wrote.Load()
+}
+
+// Handler tracks the writes of every request, so that its reads after a write are served by the primary.
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(WithWriteTracking(r.Context())))
+	})
+}
@@ -0,0 +1,94 @@
+// Package metrics defines the Prometheus metrics exported by the application.
+// Collectors are registered with the default registry and served by Handler.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Reasons recorded by AuthenticationFailures
+const (
+	AuthFailureMalformedCredentials = "malformed_credentials" // Credentials could not be extracted from the request
+	AuthFailureInvalidClient        = "invalid_client"        // Unknown client, wrong secret, or rejected assertion
+)
+
+var (
+	// TokensIssued counts successful token endpoint responses by grant type.
+	TokensIssued = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "oauth_tokens_issued_total",
+		Help: "Tokens issued by the token endpoint, by grant type.",
+	}, []string{"grant_type"})
+
+	// AuthenticationFailures counts failed client authentications by reason.
+	AuthenticationFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "oauth_authentication_failures_total",
+		Help: "Failed client authentications, by reason.",
+	}, []string{"reason"})
+
+	// RateLimitRejected counts requests rejected with 429 by the rate limiter,
+	// by the kind of key the request was counted under (ip or user).
+	RateLimitRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ratelimit_rejected_total",
+		Help: "Requests rejected by the rate limiter, by key kind.",
+	}, []string{"kind"})
+
+	// InFlightRequests reports the requests each concurrency limiter is processing.
+	InFlightRequests = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "Requests being processed, by concurrency limiter.",
+	}, []string{"limiter"})
+
+	// LoadShed counts requests rejected with 503 because a concurrency limiter was full.
+	LoadShed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_shed_total",
+		Help: "Requests rejected by a full concurrency limiter, by limiter.",
+	}, []string{"limiter"})
+
+	// AuditWriteFailures counts audit events the audit store failed to record.
+	AuditWriteFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "audit_write_failures_total",
+		Help: "Audit events that could not be written to the audit store.",
+	})
+
+	// WebhookDeliveries counts webhook delivery attempts by result: delivered, retried,
+	// dead_lettered, or dropped when the event could not be queued or its subscription was deleted.
+	WebhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "webhook_deliveries_total",
+		Help: "Webhook delivery attempts, by result.",
+	}, []string{"result"})
+
+	// PurgedRows counts expired rows deleted by the purge job, by table.
+	PurgedRows = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "cleanup_purged_rows_total",
+		Help: "Expired rows deleted by the purge job, by table.",
+	}, []string{"table"})
+
+	// RedisCircuitBreakerState reports the state of the Redis circuit breaker:
+	// 0 closed, 1 half-open, 2 open.
+	RedisCircuitBreakerState = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "redis_circuit_breaker_state",
+		Help: "State of the Redis circuit breaker: 0 closed, 1 half-open, 2 open.",
+	})
+
+	// RedisCircuitBreakerTrips counts the times the Redis circuit breaker opened.
+	RedisCircuitBreakerTrips = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "redis_circuit_breaker_trips_total",
+		Help: "Times the Redis circuit breaker opened after repeated failures.",
+	})
+
+	// RequestDuration observes request latency by method, route template, and status code.
+	RequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "HTTP request latency in seconds, by method, route, and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+// Handler returns the HTTP handler that serves all registered metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
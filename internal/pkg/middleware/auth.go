@@ -23,8 +23,15 @@ const (
 	ErrMsgInvalidToken      = "invalid token"
 
 	// Context keys for authentication data
-	ContextKeyUserID = "user_id" // Must match jwt.ClaimKeyUserID
-	ContextKeyClaims = "claims"
+	ContextKeyUserID    = "user_id" // Must match jwt.ClaimKeyUserID
+	ContextKeyClaims    = "claims"
+	ContextKeyAuthTime  = "auth_time" // time.Time the user authenticated in the current web session
+	ContextKeySessionID = "sid"       // ID of the current web session
+	ContextKeyACR       = "acr"       // How the user authenticated in the current cookie-backed web session
+
+	// ContextKeySessionClaims holds the claims mapped from an upstream identity source for the
+	// current cookie-backed web session, when an upstream source authenticated the user
+	ContextKeySessionClaims = "session_claims"
 )
 
 // Auth is an authentication middleware for OAuth APIs.
@@ -32,10 +39,12 @@ const (
 // and is primarily used for securing the OAuth API endpoints.
 //
 // The middleware:
-// 1. Extracts the Authorization header from the request
-// 2. Validates the bearer token format
-// 3. Verifies the token signature and validity using the JWT utility
-// 4. Sets the authenticated user ID and claims in the request context
+//  1. Extracts the Authorization header from the request
+//  2. Validates the bearer token format
+//  3. Verifies the token signature and validity using the JWT utility
+//  4. Checks that a certificate-bound token comes with the client certificate it is bound to
+//     and rejects DPoP-bound tokens, which this bearer scheme cannot carry
+//  5. Sets the authenticated user ID and claims in the request context
 //
 // If authentication fails, the middleware aborts the request with an appropriate error.
 func Auth() gin.HandlerFunc {
@@ -47,9 +56,9 @@ func Auth() gin.HandlerFunc {
 		}
 
 		// Validate token and extract claims
-		claims, err := jwt.ValidateToken(tokenString)
+		claims, err := validateBearerToken(c, tokenString)
 		if err != nil {
-			c.Error(errors.Unauthorized(ErrMsgInvalidToken))
+			c.Error(err)
 			c.Abort()
 			return
 		}
@@ -62,6 +71,27 @@ func Auth() gin.HandlerFunc {
 	}
 }
 
+// validateBearerToken verifies a bearer access token as Auth does and returns its claims, or the
+// 401 Unauthorized error rejecting it.
+func validateBearerToken(c *gin.Context, tokenString string) (*jwt.Claims, error) {
+	claims, err := jwt.ValidateToken(c.Request.Context(), tokenString)
+	if err != nil {
+		return nil, errors.Unauthorized(ErrMsgInvalidToken)
+	}
+
+	// A certificate-bound token is only accepted with the certificate it is bound to (RFC 8705 Section 3)
+	if claims.Confirmation != nil && claims.Confirmation.X5tS256 != "" && claims.Confirmation.X5tS256 != ClientCertificateThumbprint(c.Request.Context()) {
+		return nil, errors.Unauthorized(errors.ErrMsgCertificateBindingMismatch)
+	}
+
+	// A DPoP-bound token cannot be presented as a bearer token (RFC 9449 Section 7.1)
+	if claims.Confirmation != nil && claims.Confirmation.Jkt != "" {
+		return nil, errors.Unauthorized(errors.ErrMsgDPoPBoundTokenNotAccepted)
+	}
+
+	return claims, nil
+}
+
 // extractBearerToken extracts the bearer token from the Authorization header.
 // It returns the token string and a boolean indicating if extraction was successful.
 // If extraction fails, it aborts the request with an appropriate error.
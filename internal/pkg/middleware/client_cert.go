@@ -0,0 +1,93 @@
+// Package middleware provides HTTP middleware functions for the application.
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+)
+
+// clientCertContextKey is the request context key under which the client certificate is stored,
+// so client authentication and token validation can use it without access to the gin context
+type clientCertContextKey struct{}
+
+// ClientCertificateMiddleware creates a middleware that stores the certificate a client presented
+// for mutual TLS in the request context for ClientCertificateFromContext. A certificate presented
+// to the server's own TLS listener is used first. Otherwise the URL-encoded PEM certificate in
+// header is used, but only when the immediate peer is one of the trusted proxies, so clients
+// cannot claim a certificate they do not hold. The certificate is not verified here; client
+// authentication decides which certificates it trusts.
+func ClientCertificateMiddleware(trustedProxies []string, header string) (gin.HandlerFunc, error) {
+	trusted, err := parseIPNets(trustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(c *gin.Context) {
+		if cert := clientCertificate(c, header, trusted); cert != nil {
+			c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), clientCertContextKey{}, cert))
+		}
+		c.Next()
+	}, nil
+}
+
+// ClientCertificateFromContext returns the client certificate ClientCertificateMiddleware stored
+// in a request's context, or nil if the client presented none.
+func ClientCertificateFromContext(ctx context.Context) *x509.Certificate {
+	cert, _ := ctx.Value(clientCertContextKey{}).(*x509.Certificate)
+	return cert
+}
+
+// ClientCertificateThumbprint returns the thumbprint of the client certificate stored in a
+// request's context, or an empty string if the client presented none.
+func ClientCertificateThumbprint(ctx context.Context) string {
+	cert := ClientCertificateFromContext(ctx)
+	if cert == nil {
+		return ""
+	}
+	return CertificateThumbprint(cert)
+}
+
+// CertificateThumbprint returns the base64url-encoded SHA-256 hash of the certificate's DER
+// encoding, the x5t#S256 value tokens bound to it are confirmed with (RFC 8705 Section 3.1).
+func CertificateThumbprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// clientCertificate returns the certificate the client presented at the TLS layer, or the one a
+// trusted proxy forwarded in header. Malformed forwarded certificates are ignored.
+func clientCertificate(c *gin.Context, header string, trusted []*net.IPNet) *x509.Certificate {
+	if c.Request.TLS != nil && len(c.Request.TLS.PeerCertificates) > 0 {
+		return c.Request.TLS.PeerCertificates[0]
+	}
+
+	if header == "" || len(trusted) == 0 || !containsIP(trusted, net.ParseIP(c.RemoteIP())) {
+		return nil
+	}
+	value := c.GetHeader(header)
+	if value == "" {
+		return nil
+	}
+
+	// Proxies escape the PEM's newlines; PathUnescape keeps the plus signs in its base64
+	decoded, err := url.PathUnescape(value)
+	if err != nil {
+		return nil
+	}
+	block, _ := pem.Decode([]byte(decoded))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil
+	}
+	return cert
+}
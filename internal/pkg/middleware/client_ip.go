@@ -0,0 +1,86 @@
+// Package middleware provides HTTP middleware functions for the application.
+package middleware
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// HeaderForwardedFor is the header through which proxies report the original client address
+	HeaderForwardedFor = "X-Forwarded-For"
+
+	// ContextKeyClientIP is the context key under which the resolved client IP is stored
+	ContextKeyClientIP = "client_ip"
+)
+
+// clientIPContextKey is the request context key under which the resolved client IP is stored,
+// so services can attribute events to the client without access to the gin context
+type clientIPContextKey struct{}
+
+// ClientIPMiddleware creates a middleware that resolves the client IP address once per request
+// and stores it in the context for ClientIP. X-Forwarded-For is only honored when the
+// immediate peer is one of the trusted proxies, so untrusted clients cannot spoof their address.
+// trustedProxies accepts IPv4 or IPv6 addresses and CIDR ranges and is parsed once here.
+// It should be registered before any middleware that uses the client IP.
+func ClientIPMiddleware(trustedProxies []string) (gin.HandlerFunc, error) {
+	trusted, err := parseIPNets(trustedProxies)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(c *gin.Context) {
+		ip := resolveClientIP(c.RemoteIP(), c.GetHeader(HeaderForwardedFor), trusted)
+		c.Set(ContextKeyClientIP, ip)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), clientIPContextKey{}, ip))
+		c.Next()
+	}, nil
+}
+
+// ClientIPFromContext returns the client IP address ClientIPMiddleware stored in a request's
+// context, or an empty string if the middleware has not run.
+func ClientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPContextKey{}).(string)
+	return ip
+}
+
+// ClientIP returns the client IP address resolved by ClientIPMiddleware.
+// If the middleware has not run, the direct remote address is returned and
+// forwarding headers are ignored.
+func ClientIP(c *gin.Context) string {
+	if ip := c.GetString(ContextKeyClientIP); ip != "" {
+		return ip
+	}
+	return c.RemoteIP()
+}
+
+// resolveClientIP determines the client address from the remote peer and the X-Forwarded-For header.
+// If the peer is not a trusted proxy its own address is returned. Otherwise the header is walked
+// from the right, skipping trusted proxies, and the first untrusted address is the client.
+// Malformed entries stop the walk so that a forged prefix cannot be used, and the last address
+// verified to be trustworthy is returned instead.
+func resolveClientIP(remoteIP, forwardedFor string, trusted []*net.IPNet) string {
+	if len(trusted) == 0 || forwardedFor == "" || !containsIP(trusted, net.ParseIP(remoteIP)) {
+		return remoteIP
+	}
+
+	clientIP := remoteIP
+	hops := strings.Split(forwardedFor, ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		ip := net.ParseIP(hop)
+		if ip == nil {
+			break
+		}
+
+		clientIP = hop
+		if !containsIP(trusted, ip) {
+			break
+		}
+	}
+
+	return clientIP
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestResolveClientIP(t *testing.T) {
+	trusted, err := parseIPNets([]string{"10.0.0.0/8", "2001:db8::1"})
+	if err != nil {
+		t.Fatalf("parseIPNets failed: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		remoteIP     string
+		forwardedFor string
+		want         string
+	}{
+		{"untrusted peer without header", "203.0.113.7", "", "203.0.113.7"},
+		{"untrusted peer spoofing header", "203.0.113.7", "198.51.100.1", "203.0.113.7"},
+		{"untrusted peer spoofing trusted address", "203.0.113.7", "10.0.0.5", "203.0.113.7"},
+		{"trusted balancer without header", "10.0.0.1", "", "10.0.0.1"},
+		{"trusted balancer", "10.0.0.1", "198.51.100.1", "198.51.100.1"},
+		{"trusted balancer with spaces", "10.0.0.1", " 198.51.100.1 ", "198.51.100.1"},
+		{"trusted chain", "10.0.0.1", "198.51.100.1, 10.0.0.2, 10.0.0.3", "198.51.100.1"},
+		{"spoofed prefix before client", "10.0.0.1", "192.0.2.66, 198.51.100.1", "198.51.100.1"},
+		{"spoofed prefix behind trusted chain", "10.0.0.1", "192.0.2.66, 198.51.100.1, 10.0.0.2", "198.51.100.1"},
+		{"malformed entry stops walk", "10.0.0.1", "198.51.100.1, garbage, 10.0.0.2", "10.0.0.2"},
+		{"malformed last entry", "10.0.0.1", "198.51.100.1, garbage", "10.0.0.1"},
+		{"only trusted hops", "10.0.0.1", "10.0.0.2, 10.0.0.3", "10.0.0.2"},
+		{"trusted IPv6 balancer", "2001:db8::1", "2001:db8::2", "2001:db8::2"},
+		{"untrusted IPv6 peer", "2001:db8::3", "198.51.100.1", "2001:db8::3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolveClientIP(tt.remoteIP, tt.forwardedFor, trusted); got != tt.want {
+				t.Errorf("resolveClientIP(%q, %q) = %q, want %q", tt.remoteIP, tt.forwardedFor, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveClientIPWithoutTrustedProxies(t *testing.T) {
+	if got := resolveClientIP("10.0.0.1", "198.51.100.1", nil); got != "10.0.0.1" {
+		t.Errorf("got %q, want the peer address when no proxy is trusted", got)
+	}
+}
+
+func TestClientIPMiddleware(t *testing.T) {
+	middleware, err := ClientIPMiddleware([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("ClientIPMiddleware failed: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware)
+	router.GET("/", func(c *gin.Context) {
+		if fromContext := ClientIPFromContext(c.Request.Context()); fromContext != ClientIP(c) {
+			t.Errorf("request context holds %q, gin context %q", fromContext, ClientIP(c))
+		}
+		c.String(http.StatusOK, ClientIP(c))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:4321"
+	req.Header.Set(HeaderForwardedFor, "192.0.2.66, 198.51.100.1")
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, req)
+
+	if got := resp.Body.String(); got != "198.51.100.1" {
+		t.Errorf("got client IP %q, want %q", got, "198.51.100.1")
+	}
+}
@@ -0,0 +1,238 @@
+// Package middleware provides HTTP middleware functions for the application.
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/verigate/verigate-server/internal/pkg/metrics"
+	"github.com/verigate/verigate-server/internal/pkg/tenant"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+	"go.uber.org/zap"
+)
+
+// RateLimitTierExempt names the top rate limit tier, whose clients are not rate limited at all
+const RateLimitTierExempt = "exempt"
+
+// contextKeyPendingClientLimits holds the rate limit decisions left to ApplyClientRateLimits
+const contextKeyPendingClientLimits = "pending_client_rate_limits"
+
+// ClientTier is the rate limit applied to an OAuth client with a dedicated tier.
+type ClientTier struct {
+	Limit  int           // Requests allowed per window
+	Window time.Duration // Window the requests are counted in
+	Exempt bool          // Whether the client is not rate limited at all, the top tier
+}
+
+// ParseClientTier parses a rate limit tier: "exempt", a limit written as requests/window such
+// as "6000/1m", or a bare number of requests per minute.
+func ParseClientTier(value string) (ClientTier, error) {
+	value = strings.TrimSpace(value)
+	if value == RateLimitTierExempt {
+		return ClientTier{Exempt: true}, nil
+	}
+	if !strings.Contains(value, "/") {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return ClientTier{}, fmt.Errorf("rate limit tier %q is neither %s nor a rate limit", value, RateLimitTierExempt)
+		}
+		return ClientTier{Limit: limit, Window: time.Minute}, nil
+	}
+
+	limit, window, err := ParseRateLimit(value)
+	if err != nil {
+		return ClientTier{}, err
+	}
+	return ClientTier{Limit: limit, Window: window}, nil
+}
+
+// pendingClientLimit is the rate limit decision of one limiter on a request of a client that
+// has yet to authenticate.
+type pendingClientLimit struct {
+	limiter  *RateLimiter
+	kind     string // Kind of subject the request was counted for
+	subject  string // Key suffix of the subject, such as ip:203.0.113.7
+	claimed  string // Client the request claimed to be from, set when rejected
+	rejected bool   // Whether the request exceeded the default limit
+}
+
+// DeferToClientAuthentication names the routes, by their full gin paths, at which the handler
+// authenticates the client and then calls ApplyClientRateLimits or ClientAuthenticationFailed.
+// Only at these routes are client tiers applied to clients that authenticate with the request,
+// and only there does a request over the default limit get past the limiter on the strength of
+// the client it claims to be from.
+func (r *RateLimiter) DeferToClientAuthentication(paths ...string) {
+	r.clientAuthPaths = make(map[string]bool, len(paths))
+	for _, path := range paths {
+		r.clientAuthPaths[path] = true
+	}
+}
+
+// awaitClientAuthentication leaves the limiter's decision on a request at a route where clients
+// authenticate to ApplyClientRateLimits. Requests at other routes are left alone.
+func (r *RateLimiter) awaitClientAuthentication(c *gin.Context, kind, subject, claimed string, rejected bool) bool {
+	if r.tierLookup == nil || !r.clientAuthPaths[c.FullPath()] {
+		return false
+	}
+
+	pending, _ := c.Get(contextKeyPendingClientLimits)
+	limits, _ := pending.([]pendingClientLimit)
+	c.Set(contextKeyPendingClientLimits, append(limits, pendingClientLimit{
+		limiter:  r,
+		kind:     kind,
+		subject:  subject,
+		claimed:  claimed,
+		rejected: rejected,
+	}))
+	return true
+}
+
+// deferRejection decides whether a request over the default limit is let through to be decided
+// once the client authenticates. That is the case at the routes where clients authenticate, when
+// the request claims to be from a client with a dedicated tier, unless a request claiming that
+// client from the same subject failed to authenticate within the window. The claim costs an
+// attacker borrowing the client ID of a service account one failed authentication per window,
+// after which the default limit applies to them again; the client itself is never locked out.
+func (r *RateLimiter) deferRejection(ctx context.Context, c *gin.Context, kind, subject string) bool {
+	if r.tierLookup == nil || !r.clientAuthPaths[c.FullPath()] {
+		return false
+	}
+
+	claimed := claimedClientID(c)
+	if claimed == "" {
+		return false
+	}
+	if _, ok := r.tierLookup(ctx, claimed); !ok {
+		return false
+	}
+
+	failures, _, err := r.store.getCounter(ctx, r.authFailureKey(ctx, claimed, subject))
+	if err != nil {
+		r.logFailure(r.keyPrefix, err, isRedisConnectionError(err))
+		return false
+	}
+	if failures > 0 {
+		return false
+	}
+
+	return r.awaitClientAuthentication(c, kind, subject, claimed, true)
+}
+
+// authFailureKey returns the key counting the failed authentications of requests that were
+// over the default limit and claimed to be from clientID.
+func (r *RateLimiter) authFailureKey(ctx context.Context, clientID, subject string) string {
+	return tenant.Key(ctx, fmt.Sprintf("%sclient_auth_failure:%s:%s", r.keyPrefix, clientID, subject))
+}
+
+// ApplyClientRateLimits applies the rate limit tiers of clientID, which the handler has just
+// authenticated, to a request the rate limiters left to be decided then. The request is counted
+// under the client's own keys with the limit of its tier, or let through without counting when
+// the client is exempt; a client without a tier keeps the default limit, which a request let
+// through only for the client it claimed has exceeded. Requests an exemption lets past the
+// default limit are logged. On rejection it records a 429 Too Many Requests (or 503 when Redis is
+// unreachable and the limiter fails closed), aborts the request, and returns false.
+func ApplyClientRateLimits(c *gin.Context, clientID string) bool {
+	pending, _ := c.Get(contextKeyPendingClientLimits)
+	limits, _ := pending.([]pendingClientLimit)
+	c.Set(contextKeyPendingClientLimits, nil)
+
+	ctx := tenant.Detach(c.Request.Context())
+	for _, p := range limits {
+		limiter := p.limiter
+		tier, ok := limiter.tierLookup(ctx, clientID)
+		switch {
+		case !ok:
+			if p.rejected {
+				rejectRateLimited(c, p.kind)
+				return false
+			}
+		case tier.Exempt:
+			if p.rejected {
+				limiter.logExempt(clientID, p.subject)
+			}
+		default:
+			key := tenant.Key(ctx, limiter.clientKeyPrefix(clientID)+p.subject)
+			result, err := limiter.take(ctx, key, tier.Limit, tier.Window)
+			if err != nil {
+				connErr := isRedisConnectionError(err)
+				limiter.logFailure(limiter.keyPrefix, err, connErr)
+				if limiter.FailClosed && connErr {
+					c.Header("Retry-After", strconv.Itoa(int(tier.Window.Seconds())))
+					c.Error(errors.ServiceUnavailable(errors.ErrMsgRateLimiterUnavailable))
+					c.Abort()
+					return false
+				}
+				continue
+			}
+
+			setRateLimitHeaders(c, limiter, result)
+			if !result.allowed {
+				rejectRateLimited(c, p.kind)
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ClientAuthenticationFailed records that the client of a request the rate limiters left to be
+// decided failed to authenticate. Requests that were over the default limit no longer get past
+// it by claiming the same client from the same subject for the rest of the window.
+func ClientAuthenticationFailed(c *gin.Context) {
+	pending, _ := c.Get(contextKeyPendingClientLimits)
+	limits, _ := pending.([]pendingClientLimit)
+	c.Set(contextKeyPendingClientLimits, nil)
+
+	ctx := tenant.Detach(c.Request.Context())
+	for _, p := range limits {
+		if !p.rejected {
+			continue
+		}
+		limiter := p.limiter
+		if _, err := limiter.store.incrementCounter(ctx, limiter.authFailureKey(ctx, p.claimed, p.subject), limiter.window); err != nil {
+			limiter.logFailure(limiter.keyPrefix, err, isRedisConnectionError(err))
+		}
+	}
+}
+
+// rejectRateLimited records a 429 Too Many Requests for a request over its limit and aborts it.
+func rejectRateLimited(c *gin.Context, kind string) {
+	metrics.RateLimitRejected.WithLabelValues(kind).Inc()
+	c.Error(errors.TooManyRequests(errors.ErrMsgRateLimitExceeded))
+	c.Abort()
+}
+
+// claimedClientID returns the OAuth client a request claims to be from before it has been
+// authenticated: the client_id form parameter, the user name of HTTP Basic credentials, or the
+// client_id query parameter. Returns an empty string if the request names no client.
+func claimedClientID(c *gin.Context) string {
+	if clientID := c.PostForm("client_id"); clientID != "" {
+		return clientID
+	}
+	if username, _, ok := c.Request.BasicAuth(); ok && username != "" {
+		// Client credentials are form-urlencoded in the Authorization header (RFC 6749 Section 2.3.1)
+		if clientID, err := url.QueryUnescape(username); err == nil {
+			return clientID
+		}
+		return username
+	}
+	return c.Query("client_id")
+}
+
+// logExempt records that an exempt client made a request over the default limit.
+func (r *RateLimiter) logExempt(clientID, subject string) {
+	if r.Logger == nil {
+		return
+	}
+
+	r.Logger.Info("rate limit exempt client over default limit",
+		zap.String("key_prefix", r.keyPrefix),
+		zap.String("client_id", clientID),
+		zap.String("subject", subject),
+	)
+}
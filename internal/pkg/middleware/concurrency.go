@@ -0,0 +1,81 @@
+// Package middleware provides HTTP middleware functions for the application.
+package middleware
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/pkg/metrics"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConcurrencyLimiter caps the number of requests processed at once. Requests arriving while
+// every slot is taken are shed instead of queued, so a burst cannot pile up goroutines and
+// latency behind work the server cannot keep up with. A nil limiter admits every request.
+type ConcurrencyLimiter struct {
+	name       string
+	slots      chan struct{}
+	retryAfter time.Duration
+}
+
+// NewConcurrencyLimiter creates a limiter admitting up to limit requests at once, reported
+// under name in the metrics. Shed requests are told to retry after retryAfter.
+// Returns nil, which admits every request, if limit is not positive.
+func NewConcurrencyLimiter(name string, limit int, retryAfter time.Duration) *ConcurrencyLimiter {
+	if limit <= 0 {
+		return nil
+	}
+	return &ConcurrencyLimiter{
+		name:       name,
+		slots:      make(chan struct{}, limit),
+		retryAfter: retryAfter,
+	}
+}
+
+// InFlight returns the number of requests the limiter is processing.
+func (l *ConcurrencyLimiter) InFlight() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.slots)
+}
+
+// ConcurrencyLimitMiddleware rejects requests with 503 Service Unavailable and a Retry-After
+// header while the limiter is full. It takes its slot before any middleware registered after
+// it, so attaching it ahead of a rate limiter also bounds the rate limiter's store lookups.
+// Nested limiters compose: a request holds a slot in each limiter along its route.
+// It must be registered after ErrorHandler, which writes the error response.
+func ConcurrencyLimitMiddleware(limiter *ConcurrencyLimiter) gin.HandlerFunc {
+	if limiter == nil {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	inFlight := metrics.InFlightRequests.WithLabelValues(limiter.name)
+	shed := metrics.LoadShed.WithLabelValues(limiter.name)
+	retryAfter := strconv.Itoa(int(math.Max(1, math.Ceil(limiter.retryAfter.Seconds()))))
+
+	return func(c *gin.Context) {
+		select {
+		case limiter.slots <- struct{}{}:
+		default:
+			shed.Inc()
+			c.Header("Retry-After", retryAfter)
+			c.Error(errors.ServiceUnavailable(errors.ErrMsgServerOverloaded))
+			c.Abort()
+			return
+		}
+
+		inFlight.Inc()
+		defer func() {
+			inFlight.Dec()
+			<-limiter.slots
+		}()
+
+		c.Next()
+	}
+}
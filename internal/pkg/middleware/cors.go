@@ -2,29 +2,112 @@
 package middleware
 
 import (
+	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
-// CORS returns a middleware that handles Cross-Origin Resource Sharing (CORS).
-// This middleware allows web applications running on different domains to
-// interact with the API securely.
-//
-// The configuration:
-// - Allows requests from any origin (*)
-// - Supports common HTTP methods (GET, POST, PUT, PATCH, DELETE)
-// - Allows Authorization and content headers
-// - Enables credentials (cookies, authorization headers)
-// - Sets preflight cache to 12 hours
-func CORS() gin.HandlerFunc {
-	return cors.New(cors.Config{
-		AllowOrigins:     []string{"*"},                                                         // Allow any origin
-		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},  // Standard HTTP methods
-		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization"}, // Common headers
-		ExposeHeaders:    []string{"Content-Length"},                                            // Expose Content-Length header
-		AllowCredentials: true,                                                                  // Allow sending cookies
-		MaxAge:           12 * time.Hour,                                                        // Cache preflight for 12 hours
-	})
+// CORSWildcardOrigin allows requests from any origin when listed in AllowedOrigins
+const CORSWildcardOrigin = "*"
+
+// OriginValidator decides dynamically whether a cross-origin request from origin is allowed.
+// It is consulted only for origins that are not in the static allowlist.
+type OriginValidator func(c *gin.Context, origin string) bool
+
+// CORSConfig configures the CORS middleware.
+type CORSConfig struct {
+	AllowedOrigins   []string        // Exact origins allowed, or "*" for any origin
+	AllowedMethods   []string        // Methods allowed in preflight responses
+	AllowedHeaders   []string        // Request headers allowed in preflight responses
+	ExposedHeaders   []string        // Response headers exposed to the browser
+	AllowCredentials bool            // Whether to allow credentials for explicitly allowed origins
+	MaxAge           time.Duration   // How long browsers may cache preflight responses
+	OriginValidator  OriginValidator // Optional dynamic check for origins not in AllowedOrigins
+}
+
+// DefaultCORSConfig returns a CORS configuration with the standard methods and
+// headers used by the API and the given origin allowlist.
+func DefaultCORSConfig(allowedOrigins []string) CORSConfig {
+	return CORSConfig{
+		AllowedOrigins:   allowedOrigins,
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
+		AllowedHeaders:   []string{"Origin", "Content-Length", "Content-Type", "Authorization", "DPoP", CSRFHeaderName, HeaderRateLimitDryRun},
+		ExposedHeaders:   []string{"Content-Length", "DPoP-Nonce", "WWW-Authenticate", CSRFHeaderName, "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"},
+		AllowCredentials: true,
+		MaxAge:           12 * time.Hour,
+	}
+}
+
+// CORSMiddleware returns a middleware that handles Cross-Origin Resource Sharing (CORS).
+// Origins are allowed by exact match against AllowedOrigins, then by OriginValidator.
+// A wildcard entry admits any other origin, but such responses carry "*" as the
+// allowed origin and never allow credentials.
+// Preflight OPTIONS requests are answered directly with 204 No Content, or
+// 403 Forbidden if the origin is not allowed.
+func CORSMiddleware(cfg CORSConfig) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(cfg.AllowedOrigins))
+	wildcard := false
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == CORSWildcardOrigin {
+			wildcard = true
+			continue
+		}
+		allowed[strings.TrimRight(origin, "/")] = true
+	}
+
+	allowedMethods := strings.Join(cfg.AllowedMethods, ", ")
+	allowedHeaders := strings.Join(cfg.AllowedHeaders, ", ")
+	exposedHeaders := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		// Responses differ by origin, so shared caches must key on it
+		c.Writer.Header().Add("Vary", "Origin")
+
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		explicit := allowed[origin] || (cfg.OriginValidator != nil && cfg.OriginValidator(c, origin))
+		if !explicit && !wildcard {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if explicit {
+			c.Header("Access-Control-Allow-Origin", origin)
+			if cfg.AllowCredentials {
+				c.Header("Access-Control-Allow-Credentials", "true")
+			}
+		} else {
+			c.Header("Access-Control-Allow-Origin", CORSWildcardOrigin)
+		}
+
+		if preflight {
+			c.Header("Access-Control-Allow-Methods", allowedMethods)
+			c.Header("Access-Control-Allow-Headers", allowedHeaders)
+			if cfg.MaxAge > 0 {
+				c.Header("Access-Control-Max-Age", maxAge)
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		if exposedHeaders != "" {
+			c.Header("Access-Control-Expose-Headers", exposedHeaders)
+		}
+
+		c.Next()
+	}
 }
@@ -0,0 +1,141 @@
+// Package middleware provides HTTP middleware functions for the application.
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"sync"
+
+	"github.com/verigate/verigate-server/internal/pkg/config"
+	"github.com/verigate/verigate-server/internal/pkg/tenant"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CSRF protection settings
+const (
+	CSRFCookieName = "verigate_csrf" // Cookie holding the CSRF token, readable by the web app
+	CSRFHeaderName = "X-CSRF-Token"  // Header echoing the CSRF token on state-changing requests
+	CSRFFormField  = "csrf_token"    // Form field accepted in place of the header for form posts
+
+	// ContextKeyCSRFToken holds the CSRF token valid for the current request's session
+	ContextKeyCSRFToken = "csrf_token"
+
+	csrfNonceBytes = 16 // Random bytes in a CSRF token, followed by its MAC
+)
+
+// csrfKey returns the key CSRF tokens are signed with. Without a configured secret a random
+// key is drawn once per process, which only suits a single instance.
+var csrfKey = sync.OnceValue(func() []byte {
+	if config.AppConfig.CSRFSecret != "" {
+		return []byte(config.AppConfig.CSRFSecret)
+	}
+	key := make([]byte, sha256.Size)
+	if _, err := rand.Read(key); err != nil {
+		panic("failed to generate CSRF key: " + err.Error())
+	}
+	return key
+})
+
+// CSRF is a middleware protecting cookie-authenticated, state-changing requests from
+// cross-site request forgery with signed double-submit cookies. A token is a random nonce
+// with a MAC binding it to the current session cookie, so a token stops being accepted once
+// the session changes and an attacker cannot plant one for the victim's session.
+//
+// The middleware:
+// 1. Skips requests with an Authorization header, which do not rely on cookies
+// 2. Rejects POST, PUT, PATCH, and DELETE requests with 403 unless the X-CSRF-Token header
+// (or the csrf_token form field) matches the CSRF cookie and the cookie is valid for the session
+// 3. Otherwise issues a new token when the cookie is missing or stale, and makes the current
+// token available in the X-CSRF-Token response header and the request context
+//
+// It is applied to the browser-facing login, consent, account, and client, token, and admin
+// management routes only; the token, introspection, and revocation endpoints authenticate
+// clients, not cookies, and are left out. It is independent of the authorization request's state parameter.
+func CSRF() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader(AuthHeaderName) != "" {
+			c.Next()
+			return
+		}
+
+		token, _ := c.Cookie(CSRFCookieName)
+		valid := validCSRFToken(token, sessionCookie(c))
+
+		if isStateChanging(c.Request.Method) {
+			submitted := c.GetHeader(CSRFHeaderName)
+			if submitted == "" {
+				submitted = c.PostForm(CSRFFormField)
+			}
+			if !valid || subtle.ConstantTimeCompare([]byte(submitted), []byte(token)) != 1 {
+				c.Error(errors.Forbidden(errors.ErrMsgInvalidCSRFToken))
+				c.Abort()
+				return
+			}
+		}
+
+		if !valid {
+			IssueCSRFToken(c, sessionCookie(c))
+		} else {
+			c.Header(CSRFHeaderName, token)
+			c.Set(ContextKeyCSRFToken, token)
+		}
+
+		c.Next()
+	}
+}
+
+// IssueCSRFToken sets a new CSRF cookie bound to the given session cookie value,
+// for handlers that start or end a session and so invalidate the current token.
+// The cookie is Secure and SameSite=Lax but not HttpOnly, since the web app reads it
+// to echo it back.
+func IssueCSRFToken(c *gin.Context, session string) {
+	nonce := make([]byte, csrfNonceBytes)
+	if _, err := rand.Read(nonce); err != nil {
+		// Without a token the next state-changing request is rejected, never let through
+		return
+	}
+	token := base64.RawURLEncoding.EncodeToString(append(nonce, csrfMAC(nonce, session)...))
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(CSRFCookieName, token, 0, tenant.Path(c.Request.Context(), "/"), "", true, false)
+	c.Header(CSRFHeaderName, token)
+	c.Set(ContextKeyCSRFToken, token)
+}
+
+// validCSRFToken reports whether a token was issued for the given session cookie value.
+func validCSRFToken(token, session string) bool {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(raw) != csrfNonceBytes+sha256.Size {
+		return false
+	}
+	return hmac.Equal(raw[csrfNonceBytes:], csrfMAC(raw[:csrfNonceBytes], session))
+}
+
+// csrfMAC computes the MAC binding a CSRF token nonce to a session cookie value.
+func csrfMAC(nonce []byte, session string) []byte {
+	mac := hmac.New(sha256.New, csrfKey())
+	mac.Write(nonce)
+	mac.Write([]byte(session))
+	return mac.Sum(nil)
+}
+
+// sessionCookie returns the web session cookie value, or an empty string before login.
+func sessionCookie(c *gin.Context) string {
+	session, _ := c.Cookie(config.AppConfig.SessionCookieName)
+	return session
+}
+
+// isStateChanging reports whether requests with the method may change server state.
+func isStateChanging(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
@@ -2,13 +2,18 @@
 package middleware
 
 import (
+	"github.com/verigate/verigate-server/internal/pkg/config"
 	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+	"github.com/verigate/verigate-server/internal/pkg/utils/locale"
 
 	"github.com/gin-gonic/gin"
 )
 
 // ErrorHandler creates a middleware that handles API errors in a consistent manner.
 // It transforms error objects attached to the request context into standardized API responses.
+// Any other error is answered with a generic 500 that does not reveal its text.
+// The "error" field is the stable message code; "error_description" is its translation into the
+// language the request's Accept-Language header prefers, or the default locale.
 // This middleware should be added early in the middleware chain to catch all errors.
 func ErrorHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -18,12 +23,15 @@ func ErrorHandler() gin.HandlerFunc {
 		// Check if there are any errors
 		if len(c.Errors) > 0 {
 			err := c.Errors.Last().Err
+			preferred := locale.Preferred("", c.GetHeader("Accept-Language"))
+			c.Writer.Header().Add("Vary", "Accept-Language")
 
-			// Handle CustomError types with proper status codes and details
-			if customErr, ok := err.(errors.CustomError); ok {
+			// Handle CustomError types, also when wrapped, with proper status codes and details
+			if customErr, ok := errors.As(err); ok {
 				response := gin.H{
-					"error":             customErr.Message, // Keep "error" for the main message
-					"error_description": customErr.Error(), // Use .Error() for a more detailed description
+					"error":             customErr.Message,                                                         // Keep "error" for the main message
+					"error_description": customErr.LocalizedDescription(preferred, config.AppConfig.DefaultLocale), // Localized for end users
+					"request_id":        GetRequestID(c),                                                           // Correlates the response with server logs
 				}
 
 				// Add error details if available and different from the main error string
@@ -38,7 +46,8 @@ func ErrorHandler() gin.HandlerFunc {
 			// Handle unknown error types with a generic 500 response
 			c.JSON(500, gin.H{
 				"error":             errors.ErrMsgInternalServerError,
-				"error_description": errors.ErrMsgUnexpectedError,
+				"error_description": errors.Localize(errors.ErrMsgUnexpectedError, preferred, config.AppConfig.DefaultLocale),
+				"request_id":        GetRequestID(c),
 			})
 		}
 	}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+)
+
+func TestErrorHandler(t *testing.T) {
+	const internals = "dial tcp 10.0.0.5:5432: connection refused"
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{"custom error", errors.NotFound(errors.ErrMsgUserNotFound), http.StatusNotFound, errors.ErrMsgUserNotFound},
+		{"custom error with cause", errors.ServiceUnavailable(errors.ErrMsgUserNotFound).Wrap(stderrors.New(internals)), http.StatusServiceUnavailable, errors.ErrMsgUserNotFound},
+		{"wrapped custom error", fmt.Errorf("loading user: %w", errors.Forbidden(errors.ErrMsgUserNotFound).Wrap(stderrors.New(internals))), http.StatusForbidden, errors.ErrMsgUserNotFound},
+		{"unknown error", stderrors.New(internals), http.StatusInternalServerError, errors.ErrMsgInternalServerError},
+		{"wrapped unknown error", fmt.Errorf("loading user: %w", stderrors.New(internals)), http.StatusInternalServerError, errors.ErrMsgInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(ErrorHandler())
+			router.GET("/", func(c *gin.Context) { c.Error(tt.err) })
+
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if resp.Code != tt.wantStatus {
+				t.Errorf("got status %d, want %d", resp.Code, tt.wantStatus)
+			}
+			var body map[string]interface{}
+			if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to decode body %q: %v", resp.Body.String(), err)
+			}
+			if body["error"] != tt.wantCode {
+				t.Errorf("got error %v, want %q", body["error"], tt.wantCode)
+			}
+			if strings.Contains(resp.Body.String(), "10.0.0.5") {
+				t.Errorf("response %q reveals the underlying error", resp.Body.String())
+			}
+		})
+	}
+}
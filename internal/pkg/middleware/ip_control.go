@@ -32,7 +32,7 @@ func NewIPControl(whitelist, blacklist []string) *IPControl {
 // If the client IP is blacklisted, access is denied regardless of whitelist.
 func IPControlMiddleware(ipControl *IPControl) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		clientIP := c.ClientIP()
+		clientIP := ClientIP(c)
 
 		// Check blacklist first
 		for _, blockedIP := range ipControl.Blacklist {
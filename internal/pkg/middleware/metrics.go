@@ -0,0 +1,34 @@
+// Package middleware provides HTTP middleware functions for the application.
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/pkg/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// routeUnmatched labels requests that did not match any registered route,
+// so that arbitrary paths cannot grow the number of label values.
+const routeUnmatched = "unmatched"
+
+// MetricsMiddleware records the latency of every request in the request duration histogram.
+// Requests are labelled with their route template rather than the raw path.
+func MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = routeUnmatched
+		}
+
+		metrics.RequestDuration.
+			WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
@@ -3,22 +3,114 @@ package middleware
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/verigate/verigate-server/internal/pkg/breaker"
+	"github.com/verigate/verigate-server/internal/pkg/metrics"
+	"github.com/verigate/verigate-server/internal/pkg/tenant"
 	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+	"github.com/verigate/verigate-server/internal/pkg/utils/jwt"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
-// RedisRateLimiter implements a sliding window rate limiting algorithm using Redis.
-// It tracks and limits the number of requests per client within a specified time window.
-type RedisRateLimiter struct {
-	client      *redis.Client
+// Rate limit subject kinds used to build Redis keys
+const (
+	RateLimitSubjectUser = "user" // Requests counted per authenticated user
+	RateLimitSubjectIP   = "ip"   // Requests counted per client IP address
+)
+
+// contextKeyRateLimitResults holds the limits the rate limiters counted a request against
+const contextKeyRateLimitResults = "rate_limit_results"
+
+// RateLimitAlgorithm selects how requests are counted within a window.
+type RateLimitAlgorithm int
+
+const (
+	// RateLimitSlidingWindow stores a timestamp per request in a sorted set and counts
+	// the requests made in the last window. It is precise but runs four Redis commands
+	// in a script and stores one sorted set member per request.
+	RateLimitSlidingWindow RateLimitAlgorithm = iota
+
+	// RateLimitFixedWindow keeps a single counter per window aligned to the clock and
+	// costs two Redis commands (INCR and EXPIRE) per request. Clients may burst up to
+	// twice the limit across a window boundary.
+	RateLimitFixedWindow
+
+	// RateLimitTokenBucket refills a bucket at a steady rate up to a burst capacity and
+	// spends one token per request, updated atomically by a Lua script. It tolerates
+	// bursts from clients that are otherwise idle.
+	RateLimitTokenBucket
+)
+
+// ParseRateLimitAlgorithm converts a configuration value ("sliding", "fixed", or "token_bucket")
+// to an algorithm. Unknown values fall back to the sliding window.
+func ParseRateLimitAlgorithm(value string) RateLimitAlgorithm {
+	switch value {
+	case "fixed":
+		return RateLimitFixedWindow
+	case "token_bucket":
+		return RateLimitTokenBucket
+	default:
+		return RateLimitSlidingWindow
+	}
+}
+
+// TierLookup resolves the rate limit tier for an OAuth client.
+// It returns the tier to apply to the client and ok=false when the client
+// has no dedicated tier and the default limit should be used.
+type TierLookup func(ctx context.Context, clientID string) (tier ClientTier, ok bool)
+
+// RateLimiter implements sliding window, fixed window, and token bucket rate limiting.
+// It tracks and limits the number of requests per client within a specified time window,
+// keeping its counters in Redis or, for a single process, in memory.
+type RateLimiter struct {
+	store       rateLimitStore
 	keyPrefix   string
 	limitPerMin int
 	window      time.Duration
+	tierLookup  TierLookup
+
+	// Full paths of the routes at which clients authenticate, set by DeferToClientAuthentication
+	clientAuthPaths map[string]bool
+
+	// Algorithm selects how requests are counted. The sliding window is used by default.
+	Algorithm RateLimitAlgorithm
+
+	// RefillRate is the number of tokens per second added to a token bucket.
+	// If zero, the bucket refills at the request limit spread over the window.
+	RefillRate float64
+
+	// BurstCapacity is the maximum number of tokens a token bucket holds.
+	// If zero, the capacity equals the request limit.
+	BurstCapacity int
+
+	// Client networks that bypass rate limiting or are always rejected, set by SetIPLists
+	allowlist []*net.IPNet
+	denylist  []*net.IPNet
+
+	// FailClosed rejects requests with 503 Service Unavailable when Redis
+	// cannot be reached instead of letting them through unthrottled.
+	FailClosed bool
+
+	// KeyFunc builds the subject requests are counted for, such as a CompositeRateLimitKey.
+	// If nil, requests are counted per authenticated user or else per client IP address, the
+	// only subjects Inspect can read.
+	KeyFunc RateLimitKeyFunc
+
+	// Logger receives a structured entry whenever Redis fails during rate limiting.
+	// Logging is skipped if it is nil.
+	Logger *zap.Logger
 }
 
 // NewRedisRateLimiter creates a new rate limiter instance.
@@ -27,76 +119,501 @@ type RedisRateLimiter struct {
 // - keyPrefix: Prefix for Redis keys to prevent collisions with other data
 // - limitPerMin: Maximum number of requests allowed per minute
 // - window: Time window for rate limiting (e.g., 1 minute)
-func NewRedisRateLimiter(client *redis.Client, keyPrefix string, limitPerMin int, window time.Duration) *RedisRateLimiter {
-	return &RedisRateLimiter{
-		client:      client,
+func NewRedisRateLimiter(client *redis.Client, keyPrefix string, limitPerMin int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		store:       &redisRateLimitStore{client: client},
 		keyPrefix:   keyPrefix,
 		limitPerMin: limitPerMin,
 		window:      window,
 	}
 }
 
+// NewTieredRedisRateLimiter creates a rate limiter that consults tierLookup for
+// a per-client limit before falling back to the default limitPerMin and window.
+// Clients with a dedicated tier are counted under their own Redis keys so that
+// clients with different limits never share a window. A tier only applies to a
+// client known to be the one it is for: one authenticated by its access token, or
+// one authenticating at a route passed to DeferToClientAuthentication.
+func NewTieredRedisRateLimiter(client *redis.Client, keyPrefix string, limitPerMin int, window time.Duration, tierLookup TierLookup) *RateLimiter {
+	limiter := NewRedisRateLimiter(client, keyPrefix, limitPerMin, window)
+	limiter.tierLookup = tierLookup
+	return limiter
+}
+
+// NewMemoryRateLimiter creates a rate limiter that keeps its counters in process memory,
+// with the same algorithms and semantics as the Redis-backed one. Expired counters are
+// swept in the background until ctx is cancelled. Each process counts on its own, so this
+// is meant for tests and local development, not for deployments with several replicas.
+func NewMemoryRateLimiter(ctx context.Context, keyPrefix string, limitPerMin int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		store:       newMemoryRateLimitStore(ctx),
+		keyPrefix:   keyPrefix,
+		limitPerMin: limitPerMin,
+		window:      window,
+	}
+}
+
+// NewTieredMemoryRateLimiter creates an in-memory rate limiter with per-client tiers,
+// like NewTieredRedisRateLimiter.
+func NewTieredMemoryRateLimiter(ctx context.Context, keyPrefix string, limitPerMin int, window time.Duration, tierLookup TierLookup) *RateLimiter {
+	limiter := NewMemoryRateLimiter(ctx, keyPrefix, limitPerMin, window)
+	limiter.tierLookup = tierLookup
+	return limiter
+}
+
+// SetIPLists configures the client networks that are never rate limited (allowlist) and the
+// networks whose requests are always rejected with 429 Too Many Requests (denylist).
+// Entries are IPv4 or IPv6 addresses or CIDR ranges and are parsed once here rather than per request.
+// The denylist takes precedence when an address is in both lists.
+// Client addresses are resolved by ClientIP, so proxy headers are only honored from trusted proxies.
+func (r *RateLimiter) SetIPLists(allowlist, denylist []string) error {
+	allowed, err := parseIPNets(allowlist)
+	if err != nil {
+		return err
+	}
+	denied, err := parseIPNets(denylist)
+	if err != nil {
+		return err
+	}
+
+	r.allowlist = allowed
+	r.denylist = denied
+	return nil
+}
+
 // RateLimitMiddleware creates a Gin middleware that enforces rate limits.
-// It uses a sliding window algorithm to count requests within a time window.
-// The rate limit can be based on either the user ID (if authenticated) or the client IP.
+// Requests are counted within a time window using the limiter's configured algorithm.
+// The rate limit is based on the user ID (if authenticated) or the client IP, unless the
+// limiter's KeyFunc counts requests for another subject.
 // When a client exceeds the rate limit, the middleware responds with a 429 Too Many Requests error.
-func RateLimitMiddleware(limiter *RedisRateLimiter) gin.HandlerFunc {
+// Clients in the denylist are rejected and clients in the allowlist are let through
+// before Redis is consulted. Every 429 is counted in the ratelimit_rejected_total metric.
+// Requests are counted separately for every tenant. Anonymous requests are keyed on ClientIP,
+// which only looks past the configured trusted proxies: behind an unlisted proxy, every client
+// shares the proxy's address and limit.
+// An authenticated request with the HeaderRateLimitDryRun header is answered with its quota
+// instead of being handled, see dryRun.
+// At the routes where clients authenticate, client tiers are applied by ApplyClientRateLimits
+// once the client has authenticated, and a request over the default limit that claims a client
+// with a tier is let through to be decided then.
+func RateLimitMiddleware(limiter *RateLimiter) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		ctx := context.Background()
+		ctx := tenant.Detach(c.Request.Context())
 
-		// Create rate limit key based on IP or user ID
-		var key string
-		if userID, exists := c.Get("user_id"); exists {
-			key = fmt.Sprintf("%suser:%v", limiter.keyPrefix, userID)
-		} else {
-			key = fmt.Sprintf("%sip:%s", limiter.keyPrefix, c.ClientIP())
+		// Only authenticated requests may ask for their quota without spending it
+		dryRun := isRateLimitDryRun(c)
+		if dryRun && !dryRunAuthenticated(c) {
+			c.Error(errors.Unauthorized(errors.ErrMsgRateLimitDryRunNeedsAuth))
+			c.Abort()
+			return
 		}
 
-		// Use Redis sliding window algorithm
-		now := time.Now().Unix()
-		windowStart := now - int64(limiter.window.Seconds())
+		// Apply the IP lists without touching Redis
+		if len(limiter.allowlist) > 0 || len(limiter.denylist) > 0 {
+			clientIP := net.ParseIP(ClientIP(c))
+			if containsIP(limiter.denylist, clientIP) {
+				metrics.RateLimitRejected.WithLabelValues(RateLimitSubjectIP).Inc()
+				c.Error(errors.TooManyRequests(errors.ErrMsgRateLimitExceeded))
+				c.Abort()
+				return
+			}
+			if containsIP(limiter.allowlist, clientIP) {
+				if dryRun {
+					endDryRun(c)
+					return
+				}
+				c.Next()
+				return
+			}
+		}
 
-		pipe := limiter.client.Pipeline()
+		// Resolve the limit that applies to this request
+		keyPrefix, tier, tiered := limiter.resolveTier(ctx, c)
+		if tier.Exempt {
+			if dryRun {
+				endDryRun(c)
+				return
+			}
+			c.Next()
+			return
+		}
 
-		// Remove old entries outside the window
-		pipe.ZRemRangeByScore(ctx, key, "0", fmt.Sprintf("%d", windowStart))
+		// Create rate limit key from the subject the limiter counts requests for
+		kind, subject := limiter.subject(c)
+		key := tenant.Key(ctx, keyPrefix+subject)
+		limit, window := tier.Limit, tier.Window
 
-		// Add current request
-		pipe.ZAdd(ctx, key, &redis.Z{
-			Score:  float64(now),
-			Member: now,
-		})
+		if dryRun {
+			limiter.dryRun(ctx, c, kind, key, limit, window)
+			return
+		}
 
-		// Count requests in window
-		pipe.ZCard(ctx, key)
+		result, err := limiter.take(ctx, key, limit, window)
+		if err != nil {
+			connErr := isRedisConnectionError(err)
+			limiter.logFailure(keyPrefix, err, connErr)
 
-		// Set expiry
-		pipe.Expire(ctx, key, limiter.window)
+			// Reject only when Redis is genuinely unreachable and fail-closed is enabled
+			if limiter.FailClosed && connErr {
+				c.Header("Retry-After", strconv.Itoa(int(window.Seconds())))
+				c.Error(errors.ServiceUnavailable(errors.ErrMsgRateLimiterUnavailable))
+				c.Abort()
+				return
+			}
 
-		results, err := pipe.Exec(ctx)
-		if err != nil {
-			// On error, allow the request
+			// Otherwise allow the request
+			if !tiered {
+				limiter.awaitClientAuthentication(c, kind, subject, "", false)
+			}
 			c.Next()
 			return
 		}
 
-		count := results[2].(*redis.IntCmd).Val()
-
-		// Set rate limit headers
-		c.Header("X-RateLimit-Limit", fmt.Sprintf("%d", limiter.limitPerMin))
-		c.Header("X-RateLimit-Remaining", fmt.Sprintf("%d", max(0, limiter.limitPerMin-int(count))))
-		c.Header("X-RateLimit-Reset", fmt.Sprintf("%d", now+int64(limiter.window.Seconds())))
+		setRateLimitHeaders(c, limiter, result)
 
-		if count > int64(limiter.limitPerMin) {
+		if !result.allowed {
+			if !tiered && limiter.deferRejection(ctx, c, kind, subject) {
+				c.Next()
+				return
+			}
+			metrics.RateLimitRejected.WithLabelValues(kind).Inc()
 			c.Error(errors.TooManyRequests(errors.ErrMsgRateLimitExceeded))
 			c.Abort()
 			return
 		}
 
+		if !tiered {
+			limiter.awaitClientAuthentication(c, kind, subject, "", false)
+		}
 		c.Next()
 	}
 }
 
+// rateLimitSubject returns the kind of subject a request is counted for and its key suffix:
+// the authenticated user or, for anonymous requests, the client IP address.
+func rateLimitSubject(c *gin.Context) (string, string) {
+	if userID, exists := c.Get(ContextKeyUserID); exists {
+		return RateLimitSubjectUser, fmt.Sprintf("%s:%v", RateLimitSubjectUser, userID)
+	}
+	return RateLimitSubjectIP, fmt.Sprintf("%s:%s", RateLimitSubjectIP, ClientIP(c))
+}
+
+// setRateLimitHeaders records the limit a limiter counted a request against and reports the
+// binding one in the response headers. A request passing several limiters, such as one keyed by
+// client and IP and one by client alone, is reported against the limit with the fewest requests
+// remaining and, among those, the one resetting last, so the headers tell the client when it
+// may really send again. A limiter counting the request anew replaces its earlier result.
+func setRateLimitHeaders(c *gin.Context, limiter *RateLimiter, result rateLimitResult) {
+	recorded, _ := c.Get(contextKeyRateLimitResults)
+	results, _ := recorded.(map[*RateLimiter]rateLimitResult)
+	if results == nil {
+		results = make(map[*RateLimiter]rateLimitResult)
+		c.Set(contextKeyRateLimitResults, results)
+	}
+	results[limiter] = result
+
+	for _, other := range results {
+		if other.remaining < result.remaining || (other.remaining == result.remaining && other.resetAt > result.resetAt) {
+			result = other
+		}
+	}
+
+	c.Header("X-RateLimit-Limit", fmt.Sprintf("%d", result.limit))
+	c.Header("X-RateLimit-Remaining", fmt.Sprintf("%d", result.remaining))
+	c.Header("X-RateLimit-Reset", fmt.Sprintf("%d", result.resetAt))
+	if result.burst > 0 {
+		c.Header("X-RateLimit-Burst", fmt.Sprintf("%d", result.burst))
+	} else {
+		c.Writer.Header().Del("X-RateLimit-Burst")
+	}
+}
+
+// rateLimitResult is the outcome of counting a request against its limit.
+type rateLimitResult struct {
+	allowed   bool  // Whether the request is within the limit
+	limit     int   // Requests allowed per window
+	remaining int   // Requests left before the limit is reached
+	resetAt   int64 // Unix time at which the full limit is available again
+	burst     int   // Bucket capacity for the token bucket, zero for window algorithms
+}
+
+// take records the current request under key using the configured algorithm
+// and reports whether it is within limit requests per window.
+func (r *RateLimiter) take(ctx context.Context, key string, limit int, window time.Duration) (rateLimitResult, error) {
+	var count, resetAt int64
+	var err error
+
+	switch r.Algorithm {
+	case RateLimitTokenBucket:
+		return r.takeToken(ctx, key, limit, window)
+	case RateLimitFixedWindow:
+		count, resetAt, err = r.countFixedWindow(ctx, key, window)
+	default:
+		count, resetAt, err = r.countSlidingWindow(ctx, key, window)
+	}
+	if err != nil {
+		return rateLimitResult{}, err
+	}
+
+	return rateLimitResult{
+		allowed:   count <= int64(limit),
+		limit:     limit,
+		remaining: max(0, limit-int(count)),
+		resetAt:   resetAt,
+	}, nil
+}
+
+// peek reports whether a request under key would be within limit requests per window, as take
+// does, without recording it: the window, counter, or bucket is only read. The request is
+// allowed while fewer than limit requests were counted, so the next real one would pass.
+func (r *RateLimiter) peek(ctx context.Context, key string, limit int, window time.Duration) (rateLimitResult, error) {
+	now := time.Now()
+	var count int64
+	var ttl time.Duration
+	var err error
+
+	resetAt := now.Unix()
+	switch r.Algorithm {
+	case RateLimitTokenBucket:
+		return r.peekToken(ctx, key, limit, window, now)
+	case RateLimitFixedWindow:
+		var windowKey string
+		windowKey, resetAt = fixedWindowKey(key, window, now)
+		count, _, err = r.store.getCounter(ctx, windowKey)
+	default:
+		count, ttl, err = r.store.countSlidingWindow(ctx, key, now.Unix()-int64(window.Seconds()))
+		if ttl > 0 {
+			resetAt = now.Add(ttl).Unix()
+		}
+	}
+	if err != nil {
+		return rateLimitResult{}, err
+	}
+
+	return rateLimitResult{
+		allowed:   count < int64(limit),
+		limit:     limit,
+		remaining: max(0, limit-int(count)),
+		resetAt:   resetAt,
+	}, nil
+}
+
+// countSlidingWindow counts the requests made within the last window using a sorted set of timestamps.
+// Each request is stored under a unique member so that requests within the same second are all counted.
+func (r *RateLimiter) countSlidingWindow(ctx context.Context, key string, window time.Duration) (int64, int64, error) {
+	now := time.Now().Unix()
+	windowStart := now - int64(window.Seconds())
+
+	count, err := r.store.recordSlidingWindow(ctx, key, now, windowStart, uuid.NewString(), window)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return count, now + int64(window.Seconds()), nil
+}
+
+// countFixedWindow counts the requests made in the current clock-aligned window with a single counter.
+// Each window has its own key, so the counter never needs to be reset explicitly.
+func (r *RateLimiter) countFixedWindow(ctx context.Context, key string, window time.Duration) (int64, int64, error) {
+	windowKey, resetAt := fixedWindowKey(key, window, time.Now())
+
+	count, err := r.store.incrementCounter(ctx, windowKey, window)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return count, resetAt, nil
+}
+
+// fixedWindowKey returns the counter key for the fixed window containing now
+// and the Unix time at which that window ends.
+func fixedWindowKey(key string, window time.Duration, now time.Time) (string, int64) {
+	seconds := int64(window.Seconds())
+	if seconds <= 0 {
+		seconds = 1
+	}
+	windowStart := now.Unix() / seconds * seconds
+	return fmt.Sprintf("%s:%d", key, windowStart), windowStart + seconds
+}
+
+// Inspect reports the live request count for a rate limit subject without consuming quota.
+// subjectKind is either "user" or "ip" and subject is the user ID or IP address,
+// matching the keys built by RateLimitMiddleware for the default tier of the tenant ctx is served for.
+// Unlike the middleware, it performs only read operations on the window, counter, or bucket.
+// Returns the number of requests in the current window (the tokens spent for a token bucket)
+// and the time the window fully resets.
+func (r *RateLimiter) Inspect(ctx context.Context, subjectKind, subject string) (int, time.Time, error) {
+	if subjectKind != RateLimitSubjectUser && subjectKind != RateLimitSubjectIP {
+		return 0, time.Time{}, errors.BadRequest(errors.ErrMsgInvalidRateLimitSubjectKind)
+	}
+
+	key := tenant.Key(ctx, fmt.Sprintf("%s%s:%s", r.keyPrefix, subjectKind, subject))
+	now := time.Now()
+
+	if r.Algorithm == RateLimitTokenBucket {
+		count, resetAt, err := r.inspectTokenBucket(ctx, key, now)
+		if err != nil {
+			return 0, time.Time{}, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToInspectRateLimit, err.Error()))
+		}
+		return count, resetAt, nil
+	}
+
+	// A missing fixed window counter means no requests were made in the window
+	var count int64
+	var ttl time.Duration
+	var err error
+	if r.Algorithm == RateLimitFixedWindow {
+		key, _ = fixedWindowKey(key, r.window, now)
+		count, ttl, err = r.store.getCounter(ctx, key)
+	} else {
+		count, ttl, err = r.store.countSlidingWindow(ctx, key, now.Unix()-int64(r.window.Seconds()))
+	}
+	if err != nil {
+		return 0, time.Time{}, errors.Internal(fmt.Sprintf("%s: %s", errors.ErrMsgFailedToInspectRateLimit, err.Error()))
+	}
+
+	// A missing key or a key without expiry has nothing left to reset
+	resetAt := now
+	if ttl > 0 {
+		resetAt = now.Add(ttl)
+	}
+
+	return int(count), resetAt, nil
+}
+
+// parseIPNets parses IP addresses and CIDR ranges into networks.
+// A bare address is treated as a single-host network (/32 for IPv4, /128 for IPv6).
+// Blank entries are skipped.
+func parseIPNets(entries []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if strings.Contains(entry, "/") {
+			_, ipNet, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %q", errors.ErrMsgInvalidIPRange, entry)
+			}
+			nets = append(nets, ipNet)
+			continue
+		}
+
+		ip := net.ParseIP(entry)
+		if ip == nil {
+			return nil, fmt.Errorf("%s: %q", errors.ErrMsgInvalidIPRange, entry)
+		}
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+			bits = 8 * net.IPv4len
+		}
+		nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	return nets, nil
+}
+
+// containsIP reports whether ip belongs to any of the networks.
+// A nil ip never matches.
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range nets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveTier determines the key prefix and tier for the request.
+// If a tier lookup is configured and the request carries a validated access token of a
+// client with a dedicated tier, the client ID is folded into the key prefix so its counters
+// are isolated, and tiered is true. Otherwise the limiter's default settings are returned.
+// The client_id a request merely names is never trusted here: the tier of a client
+// authenticating with the request is applied by ApplyClientRateLimits.
+func (r *RateLimiter) resolveTier(ctx context.Context, c *gin.Context) (keyPrefix string, tier ClientTier, tiered bool) {
+	defaultTier := ClientTier{Limit: r.limitPerMin, Window: r.window}
+	if r.tierLookup == nil {
+		return r.keyPrefix, defaultTier, false
+	}
+
+	clientID := tokenClientID(c)
+	if clientID == "" {
+		return r.keyPrefix, defaultTier, false
+	}
+
+	tier, ok := r.tierLookup(ctx, clientID)
+	if !ok {
+		return r.keyPrefix, defaultTier, false
+	}
+
+	return r.clientKeyPrefix(clientID), tier, true
+}
+
+// clientKeyPrefix returns the key prefix of the counters of a client with a dedicated tier.
+func (r *RateLimiter) clientKeyPrefix(clientID string) string {
+	return fmt.Sprintf("%sclient:%s:", r.keyPrefix, clientID)
+}
+
+// tokenClientID returns the OAuth client an authenticated token was issued to,
+// the audience of the claims the authentication middleware validated.
+// Returns an empty string if the request carries no validated token.
+func tokenClientID(c *gin.Context) string {
+	if value, exists := c.Get(ContextKeyClaims); exists {
+		if claims, ok := value.(*jwt.Claims); ok && len(claims.Audience) > 0 {
+			return claims.Audience[0]
+		}
+	}
+	return ""
+}
+
+// logFailure records a Redis failure encountered while rate limiting.
+// The key prefix is included so operators can alert on a specific limiter.
+func (r *RateLimiter) logFailure(keyPrefix string, err error, connErr bool) {
+	if r.Logger == nil {
+		return
+	}
+
+	r.Logger.Error("rate limiter redis failure",
+		zap.String("key_prefix", keyPrefix),
+		zap.Bool("connection_error", connErr),
+		zap.Bool("fail_closed", r.FailClosed),
+		zap.Error(err),
+	)
+}
+
+// isRedisConnectionError reports whether err indicates that Redis is unreachable.
+// Timeouts and Redis server replies (such as WRONGTYPE) are treated as transient
+// so that a slow or misbehaving command does not reject legitimate traffic.
+// Commands rejected by the open Redis circuit breaker count as unreachable.
+func isRedisConnectionError(err error) bool {
+	// Errors returned by the Redis server itself mean the connection works
+	var redisErr redis.Error
+	if stderrors.As(err, &redisErr) {
+		return false
+	}
+
+	if stderrors.Is(err, redis.ErrClosed) ||
+		stderrors.Is(err, breaker.ErrOpen) ||
+		stderrors.Is(err, io.EOF) ||
+		stderrors.Is(err, io.ErrUnexpectedEOF) ||
+		stderrors.Is(err, syscall.ECONNREFUSED) ||
+		stderrors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+
+	var netErr net.Error
+	if stderrors.As(err, &netErr) {
+		return !netErr.Timeout()
+	}
+
+	return false
+}
+
 func max(a, b int) int {
 	if a > b {
 		return a
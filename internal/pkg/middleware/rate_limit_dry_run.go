@@ -0,0 +1,70 @@
+// Package middleware provides HTTP middleware functions for the application.
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/verigate/verigate-server/internal/pkg/metrics"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+)
+
+// HeaderRateLimitDryRun is the request header asking the rate limiter for the quota left
+// without spending any of it, set to 1 or true
+const HeaderRateLimitDryRun = "X-RateLimit-DryRun"
+
+// isRateLimitDryRun reports whether a request asks for a rate limit dry run.
+func isRateLimitDryRun(c *gin.Context) bool {
+	dryRun, err := strconv.ParseBool(c.GetHeader(HeaderRateLimitDryRun))
+	return err == nil && dryRun
+}
+
+// dryRunAuthenticated reports whether a request may make a rate limit dry run: it was
+// authenticated earlier in the chain or carries a bearer access token that Auth would accept.
+// Limiters run before the authentication of their routes, so the token is verified here; it
+// only makes the request eligible and changes nothing about how it is counted.
+func dryRunAuthenticated(c *gin.Context) bool {
+	if _, exists := c.Get(ContextKeyUserID); exists {
+		return true
+	}
+
+	scheme, tokenString, ok := strings.Cut(c.GetHeader(AuthHeaderName), " ")
+	if !ok || scheme != AuthHeaderPrefix || tokenString == "" {
+		return false
+	}
+	_, err := validateBearerToken(c, tokenString)
+	return err == nil
+}
+
+// dryRun answers a rate limit dry run in place of the request: the limit under key is read
+// without recording the request, reported in the X-RateLimit-* headers, and the request ends
+// with 204 No Content, or 429 Too Many Requests when the next real request would exceed the
+// limit. The handler never runs, so a dry run costs no quota and does no work. A route passing
+// several limiters is answered by the first; when Redis fails the dry run gets 503.
+func (r *RateLimiter) dryRun(ctx context.Context, c *gin.Context, kind, key string, limit int, window time.Duration) {
+	result, err := r.peek(ctx, key, limit, window)
+	if err != nil {
+		r.logFailure(r.keyPrefix, err, isRedisConnectionError(err))
+		c.Error(errors.ServiceUnavailable(errors.ErrMsgRateLimiterUnavailable))
+		c.Abort()
+		return
+	}
+
+	setRateLimitHeaders(c, r, result)
+	if !result.allowed {
+		metrics.RateLimitRejected.WithLabelValues(kind).Inc()
+		c.Error(errors.TooManyRequests(errors.ErrMsgRateLimitExceeded))
+		c.Abort()
+		return
+	}
+	endDryRun(c)
+}
+
+// endDryRun ends a rate limit dry run the limit allows with 204 No Content.
+func endDryRun(c *gin.Context) {
+	c.AbortWithStatus(http.StatusNoContent)
+}
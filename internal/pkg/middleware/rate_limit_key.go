@@ -0,0 +1,101 @@
+// Package middleware provides HTTP middleware functions for the application.
+package middleware
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitSubjectClient is the subject kind of requests counted per OAuth client
+const RateLimitSubjectClient = "client"
+
+// rateLimitKeySeparator separates the parts of a composite rate limit key in its configuration
+const rateLimitKeySeparator = "+"
+
+// RateLimitKeyFunc builds the subject a rate limiter counts a request for: its kind, which
+// labels the ratelimit_rejected_total metric, and the key suffix its counters are stored under.
+// Requests with the same key suffix share a limit.
+type RateLimitKeyFunc func(c *gin.Context) (kind, subject string)
+
+// CompositeRateLimitKey returns a key builder counting requests per combination of the given
+// parts: RateLimitSubjectClient, RateLimitSubjectIP, and RateLimitSubjectUser. Keyed by client
+// and IP, the users of a public client that share its client_id are limited per address, so one
+// abusive address cannot exhaust the quota of everyone using the client; a second limiter keyed
+// by the client alone then bounds the client's total. The client is only ever an authenticated
+// one, never a client_id the request merely names, which anyone could set to spread requests
+// over fresh counters or to spend another client's quota. The user is the authenticated one;
+// a part the request lacks is left out of its key, and a request lacking all of them is counted
+// per IP. The kind of a subject is its parts joined with underscores, such as "client_ip".
+func CompositeRateLimitKey(parts ...string) RateLimitKeyFunc {
+	return func(c *gin.Context) (string, string) {
+		kinds := make([]string, 0, len(parts))
+		values := make([]string, 0, len(parts))
+		for _, part := range parts {
+			var value string
+			switch part {
+			case RateLimitSubjectClient:
+				// Client IDs are escaped so none can pose as another key
+				value = url.QueryEscape(authenticatedClientID(c))
+			case RateLimitSubjectIP:
+				value = ClientIP(c)
+			case RateLimitSubjectUser:
+				if userID, exists := c.Get(ContextKeyUserID); exists {
+					value = fmt.Sprint(userID)
+				}
+			}
+			if value == "" {
+				continue
+			}
+			kinds = append(kinds, part)
+			values = append(values, part+":"+value)
+		}
+
+		if len(values) == 0 {
+			return RateLimitSubjectIP, fmt.Sprintf("%s:%s", RateLimitSubjectIP, ClientIP(c))
+		}
+		return strings.Join(kinds, "_"), strings.Join(values, ":")
+	}
+}
+
+// authenticatedClientID returns the OAuth client the request authenticated as: the client its
+// access token was issued to, or the administration client whose token authorized it. Returns
+// an empty string if the request carries no validated token.
+func authenticatedClientID(c *gin.Context) string {
+	if clientID := tokenClientID(c); clientID != "" {
+		return clientID
+	}
+	return c.GetString(ContextKeyAdminClientID)
+}
+
+// ParseRateLimitKey parses the configuration of a composite rate limit key, its parts joined
+// with "+" such as "client+ip", into a key builder as CompositeRateLimitKey.
+func ParseRateLimitKey(value string) (RateLimitKeyFunc, error) {
+	seen := make(map[string]bool)
+	var parts []string
+	for _, part := range strings.Split(value, rateLimitKeySeparator) {
+		part = strings.TrimSpace(part)
+		switch part {
+		case RateLimitSubjectClient, RateLimitSubjectIP, RateLimitSubjectUser:
+		default:
+			return nil, fmt.Errorf("rate limit key %q has a part other than %s, %s, and %s", value, RateLimitSubjectClient, RateLimitSubjectIP, RateLimitSubjectUser)
+		}
+		if seen[part] {
+			return nil, fmt.Errorf("rate limit key %q repeats %s", value, part)
+		}
+		seen[part] = true
+		parts = append(parts, part)
+	}
+	return CompositeRateLimitKey(parts...), nil
+}
+
+// subject returns the kind and key suffix a request is counted for by the limiter's key
+// builder, by default the authenticated user or the client IP address.
+func (r *RateLimiter) subject(c *gin.Context) (string, string) {
+	if r.KeyFunc != nil {
+		return r.KeyFunc(c)
+	}
+	return rateLimitSubject(c)
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	jwtutil "github.com/verigate/verigate-server/internal/pkg/utils/jwt"
+)
+
+func TestCompositeRateLimitKeyCountsOnlyAuthenticatedClients(t *testing.T) {
+	const remoteAddr = "192.0.2.10:1234"
+
+	tests := []struct {
+		name        string
+		request     func() *http.Request
+		setup       func(c *gin.Context)
+		wantKind    string
+		wantSubject string
+	}{
+		{"client_id form parameter", func() *http.Request {
+			req := httptest.NewRequest(http.MethodPost, "/token", strings.NewReader(url.Values{"client_id": {"client-a"}}.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			return req
+		}, nil, "ip", "ip:192.0.2.10"},
+		{"client_id query parameter", func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/authorize?client_id=client-a", nil)
+		}, nil, "ip", "ip:192.0.2.10"},
+		{"HTTP Basic client credentials", func() *http.Request {
+			req := httptest.NewRequest(http.MethodPost, "/token", nil)
+			req.SetBasicAuth("client-a", "secret")
+			return req
+		}, nil, "ip", "ip:192.0.2.10"},
+		{"access token of the client", func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/userinfo?client_id=client-b", nil)
+		}, func(c *gin.Context) {
+			c.Set(ContextKeyClaims, &jwtutil.Claims{RegisteredClaims: jwt.RegisteredClaims{Audience: jwt.ClaimStrings{"client-a"}}})
+		}, "client_ip", "client:client-a:ip:192.0.2.10"},
+		{"administration client", func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/admin/clients", nil)
+		}, func(c *gin.Context) {
+			c.Set(ContextKeyAdminClientID, "admin-client")
+		}, "client_ip", "client:admin-client:ip:192.0.2.10"},
+		{"escaped client ID", func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/userinfo", nil)
+		}, func(c *gin.Context) {
+			c.Set(ContextKeyClaims, &jwtutil.Claims{RegisteredClaims: jwt.RegisteredClaims{Audience: jwt.ClaimStrings{"a:ip:b"}}})
+		}, "client_ip", "client:a%3Aip%3Ab:ip:192.0.2.10"},
+	}
+
+	keyFunc := CompositeRateLimitKey(RateLimitSubjectClient, RateLimitSubjectIP)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = tt.request()
+			c.Request.RemoteAddr = remoteAddr
+			if tt.setup != nil {
+				tt.setup(c)
+			}
+
+			kind, subject := keyFunc(c)
+			if kind != tt.wantKind || subject != tt.wantSubject {
+				t.Errorf("got %q, %q, want %q, %q", kind, subject, tt.wantKind, tt.wantSubject)
+			}
+		})
+	}
+}
+
+func TestCompositeRateLimitKeyByClientAloneFallsBackToIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/token?client_id=client-a", nil)
+	c.Request.RemoteAddr = "192.0.2.10:1234"
+
+	kind, subject := CompositeRateLimitKey(RateLimitSubjectClient)(c)
+	if kind != RateLimitSubjectIP || subject != "ip:192.0.2.10" {
+		t.Errorf("got %q, %q, want the request counted per IP", kind, subject)
+	}
+}
@@ -0,0 +1,100 @@
+// Package middleware provides HTTP middleware functions for the application.
+package middleware
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimitKeyPrefix namespaces the Redis keys of every named rate limiter
+const rateLimitKeyPrefix = "rate_limit:"
+
+// RateLimitKeyPrefix returns the key prefix for the rate limiter registered under name.
+// Names cannot contain a colon, so the prefixes of two names never overlap.
+func RateLimitKeyPrefix(name string) string {
+	return rateLimitKeyPrefix + name + ":"
+}
+
+// ParseRateLimit parses a rate limit written as requests/window, such as "10/1m".
+func ParseRateLimit(value string) (int, time.Duration, error) {
+	requests, windowValue, ok := strings.Cut(strings.TrimSpace(value), "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("rate limit %q is not of the form requests/window", value)
+	}
+
+	limit, err := strconv.Atoi(requests)
+	if err != nil || limit <= 0 {
+		return 0, 0, fmt.Errorf("rate limit %q must allow a positive number of requests", value)
+	}
+
+	window, err := time.ParseDuration(windowValue)
+	if err != nil || window < time.Second {
+		return 0, 0, fmt.Errorf("rate limit %q must have a window of at least one second", value)
+	}
+
+	return limit, window, nil
+}
+
+// RateLimiterRegistry keeps named rate limiters so that route groups can be limited separately,
+// such as a tight limit on the login and token endpoints and a loose one on discovery.
+// Each limiter counts under its own key prefix, so a client calling several limited groups
+// is tracked separately by each of them. A request passing through nested groups is counted
+// by every limiter attached along the way.
+type RateLimiterRegistry struct {
+	limiters map[string]*RateLimiter
+}
+
+// NewRateLimiterRegistry creates an empty rate limiter registry.
+func NewRateLimiterRegistry() *RateLimiterRegistry {
+	return &RateLimiterRegistry{limiters: make(map[string]*RateLimiter)}
+}
+
+// Register adds a rate limiter under name. It fails if the name is empty, contains a colon,
+// or is taken, or if the limiter's key prefix overlaps the prefix of a registered limiter,
+// since the two would then share counters.
+func (r *RateLimiterRegistry) Register(name string, limiter *RateLimiter) error {
+	if name == "" || strings.Contains(name, ":") {
+		return fmt.Errorf("invalid rate limiter name %q", name)
+	}
+	if _, exists := r.limiters[name]; exists {
+		return fmt.Errorf("rate limiter %q is already registered", name)
+	}
+
+	for other, registered := range r.limiters {
+		if strings.HasPrefix(limiter.keyPrefix, registered.keyPrefix) || strings.HasPrefix(registered.keyPrefix, limiter.keyPrefix) {
+			return fmt.Errorf("key prefix %q of rate limiter %q overlaps %q of rate limiter %q",
+				limiter.keyPrefix, name, registered.keyPrefix, other)
+		}
+	}
+
+	r.limiters[name] = limiter
+	return nil
+}
+
+// Limiter returns the rate limiter registered under name, or nil if there is none.
+func (r *RateLimiterRegistry) Limiter(name string) *RateLimiter {
+	return r.limiters[name]
+}
+
+// Attach limits the routes of a group with the rate limiter registered under name.
+// Like any gin middleware, it only applies to routes registered on the group afterwards.
+// Nothing is attached when no limiter is registered under name, so a limit that is not
+// configured leaves the group to the limiters of its parent groups.
+func (r *RateLimiterRegistry) Attach(name string, routes gin.IRoutes) {
+	if limiter, ok := r.limiters[name]; ok {
+		routes.Use(RateLimitMiddleware(limiter))
+	}
+}
+
+// DeferToClientAuthentication passes the routes at which clients authenticate to every
+// registered limiter, so that each applies its client tiers once the client has authenticated.
+// Limiters without tiers ignore them.
+func (r *RateLimiterRegistry) DeferToClientAuthentication(paths ...string) {
+	for _, limiter := range r.limiters {
+		limiter.DeferToClientAuthentication(paths...)
+	}
+}
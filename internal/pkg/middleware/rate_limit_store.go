@@ -0,0 +1,360 @@
+// Package middleware provides HTTP middleware functions for the application.
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// memoryRateLimitSweepInterval is how often the in-memory store drops expired counters
+const memoryRateLimitSweepInterval = time.Minute
+
+// rateLimitStore holds the counters of a RateLimiter. Both implementations carry out each
+// operation atomically, so concurrent requests for the same key never undercount.
+type rateLimitStore interface {
+	// recordSlidingWindow drops the requests at or before windowStart (Unix seconds), records one
+	// at now under member, and returns the requests left in the window. The window expires after ttl.
+	recordSlidingWindow(ctx context.Context, key string, now, windowStart int64, member string, ttl time.Duration) (int64, error)
+
+	// countSlidingWindow returns the requests after windowStart and the window's remaining time to live.
+	countSlidingWindow(ctx context.Context, key string, windowStart int64) (int64, time.Duration, error)
+
+	// incrementCounter increments the counter under key, expiring it after ttl, and returns the new count.
+	incrementCounter(ctx context.Context, key string, ttl time.Duration) (int64, error)
+
+	// getCounter returns the counter under key, zero if it is missing, and its remaining time to live.
+	getCounter(ctx context.Context, key string) (int64, time.Duration, error)
+
+	// spendToken refills the bucket under key at rate tokens per second up to capacity and spends
+	// one token if there is one. It returns whether a token was spent, the tokens left, and the
+	// milliseconds until the bucket is full again, after which it expires.
+	spendToken(ctx context.Context, key string, rate float64, capacity int, nowMilli int64) (bool, float64, int64, error)
+
+	// getTokenBucket returns the tokens in the bucket under key and the time of its last update
+	// in Unix milliseconds, or found=false if there is no bucket.
+	getTokenBucket(ctx context.Context, key string) (tokens float64, tsMilli int64, found bool, err error)
+}
+
+// redisRateLimitStore keeps rate limit counters in Redis, shared by every server replica.
+type redisRateLimitStore struct {
+	client *redis.Client
+}
+
+// slidingWindowScript trims, records, counts, and expires a sliding window sorted set in one step.
+// A pipeline of the same commands is not atomic, so concurrent requests could interleave and
+// each observe a count below the limit.
+//
+// KEYS[1] window key; ARGV[1] now in seconds; ARGV[2] window start in seconds;
+// ARGV[3] unique member for this request; ARGV[4] window length in seconds.
+// Returns the number of requests in the window including this one.
+var slidingWindowScript = redis.NewScript(`
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '0', ARGV[2])
+redis.call('ZADD', KEYS[1], ARGV[1], ARGV[3])
+local count = redis.call('ZCARD', KEYS[1])
+redis.call('EXPIRE', KEYS[1], ARGV[4])
+return count
+`)
+
+// tokenBucketScript refills and spends a token bucket stored as a hash with the fields
+// "tokens" and "ts" (last update in milliseconds). Running it as a single script keeps
+// concurrent requests for the same bucket from racing between the read and the write.
+//
+// KEYS[1] bucket key; ARGV[1] refill rate in tokens per second; ARGV[2] capacity; ARGV[3] now in milliseconds.
+// Returns {allowed (0 or 1), remaining tokens as a string, milliseconds until the bucket is full}.
+// The key expires once the bucket would be full again, since a missing bucket is treated as full.
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local capacity = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(bucket[1])
+local ts = tonumber(bucket[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now
+end
+
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate / 1000)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+local ttl = math.max(1, math.ceil((capacity - tokens) * 1000 / rate))
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], ttl)
+
+return {allowed, tostring(tokens), ttl}
+`)
+
+// recordSlidingWindow runs slidingWindowScript with EVALSHA, falling back to EVAL when Redis has not cached it yet.
+func (s *redisRateLimitStore) recordSlidingWindow(ctx context.Context, key string, now, windowStart int64, member string, ttl time.Duration) (int64, error) {
+	return slidingWindowScript.Run(ctx, s.client, []string{key}, now, windowStart, member, int64(ttl.Seconds())).Int64()
+}
+
+// countSlidingWindow counts the sorted set members scored after windowStart.
+func (s *redisRateLimitStore) countSlidingWindow(ctx context.Context, key string, windowStart int64) (int64, time.Duration, error) {
+	pipe := s.client.Pipeline()
+	countCmd := pipe.ZCount(ctx, key, fmt.Sprintf("(%d", windowStart), "+inf")
+	ttlCmd := pipe.PTTL(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return 0, 0, err
+	}
+	return countCmd.Val(), ttlCmd.Val(), nil
+}
+
+// incrementCounter pipelines INCR and EXPIRE.
+func (s *redisRateLimitStore) incrementCounter(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	pipe := s.client.Pipeline()
+	countCmd := pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return countCmd.Val(), nil
+}
+
+// getCounter reads the counter and its time to live in one round trip.
+func (s *redisRateLimitStore) getCounter(ctx context.Context, key string) (int64, time.Duration, error) {
+	pipe := s.client.Pipeline()
+	counterCmd := pipe.Get(ctx, key)
+	ttlCmd := pipe.PTTL(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return 0, 0, err
+	}
+	count, _ := counterCmd.Int64()
+	return count, ttlCmd.Val(), nil
+}
+
+// spendToken runs tokenBucketScript.
+func (s *redisRateLimitStore) spendToken(ctx context.Context, key string, rate float64, capacity int, nowMilli int64) (bool, float64, int64, error) {
+	values, err := tokenBucketScript.Run(ctx, s.client, []string{key}, rate, capacity, nowMilli).Slice()
+	if err != nil {
+		return false, 0, 0, err
+	}
+
+	allowed, _ := values[0].(int64)
+	tokensValue, _ := values[1].(string)
+	fullIn, _ := values[2].(int64)
+	tokens, _ := strconv.ParseFloat(tokensValue, 64)
+	return allowed == 1, tokens, fullIn, nil
+}
+
+// getTokenBucket reads the bucket hash without modifying it.
+func (s *redisRateLimitStore) getTokenBucket(ctx context.Context, key string) (float64, int64, bool, error) {
+	values, err := s.client.HMGet(ctx, key, "tokens", "ts").Result()
+	if err != nil {
+		return 0, 0, false, err
+	}
+
+	tokensValue, ok := values[0].(string)
+	tsValue, tsOK := values[1].(string)
+	if !ok || !tsOK {
+		return 0, 0, false, nil
+	}
+
+	tokens, _ := strconv.ParseFloat(tokensValue, 64)
+	ts, _ := strconv.ParseInt(tsValue, 10, 64)
+	return tokens, ts, true, nil
+}
+
+// memoryWindow is an in-memory sliding window: the Unix second of each request, in order.
+type memoryWindow struct {
+	requests  []int64
+	expiresAt time.Time
+}
+
+// memoryCounter is an in-memory fixed window counter.
+type memoryCounter struct {
+	count     int64
+	expiresAt time.Time
+}
+
+// memoryBucket is an in-memory token bucket.
+type memoryBucket struct {
+	tokens    float64
+	tsMilli   int64
+	expiresAt time.Time
+}
+
+// memoryRateLimitStore keeps rate limit counters in process memory, guarded by a mutex.
+// It mirrors the Redis store operation for operation, including key expiry, so both
+// enforce identical limits. Counters are not shared between processes.
+type memoryRateLimitStore struct {
+	mu       sync.Mutex
+	windows  map[string]*memoryWindow
+	counters map[string]*memoryCounter
+	buckets  map[string]*memoryBucket
+}
+
+// newMemoryRateLimitStore creates an empty store and sweeps expired counters from it
+// in the background until ctx is cancelled.
+func newMemoryRateLimitStore(ctx context.Context) *memoryRateLimitStore {
+	s := &memoryRateLimitStore{
+		windows:  make(map[string]*memoryWindow),
+		counters: make(map[string]*memoryCounter),
+		buckets:  make(map[string]*memoryBucket),
+	}
+
+	go func() {
+		ticker := time.NewTicker(memoryRateLimitSweepInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				s.sweep(now)
+			}
+		}
+	}()
+
+	return s
+}
+
+// sweep removes every counter that expired by now.
+func (s *memoryRateLimitStore) sweep(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, w := range s.windows {
+		if !now.Before(w.expiresAt) {
+			delete(s.windows, key)
+		}
+	}
+	for key, c := range s.counters {
+		if !now.Before(c.expiresAt) {
+			delete(s.counters, key)
+		}
+	}
+	for key, b := range s.buckets {
+		if !now.Before(b.expiresAt) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// liveWindow returns the unexpired window under key, or nil. The caller holds the lock.
+func (s *memoryRateLimitStore) liveWindow(key string, now time.Time) *memoryWindow {
+	w, ok := s.windows[key]
+	if !ok || !now.Before(w.expiresAt) {
+		return nil
+	}
+	return w
+}
+
+// recordSlidingWindow mirrors slidingWindowScript.
+func (s *memoryRateLimitStore) recordSlidingWindow(ctx context.Context, key string, now, windowStart int64, member string, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clock := time.Now()
+	w := s.liveWindow(key, clock)
+	if w == nil {
+		w = &memoryWindow{}
+		s.windows[key] = w
+	}
+
+	// Requests are appended in time order, so the expired ones form a prefix
+	kept := sort.Search(len(w.requests), func(i int) bool { return w.requests[i] > windowStart })
+	w.requests = append(w.requests[:0], w.requests[kept:]...)
+	w.requests = append(w.requests, now)
+	w.expiresAt = clock.Add(time.Duration(int64(ttl.Seconds())) * time.Second)
+
+	return int64(len(w.requests)), nil
+}
+
+// countSlidingWindow counts the requests after windowStart without modifying the window.
+func (s *memoryRateLimitStore) countSlidingWindow(ctx context.Context, key string, windowStart int64) (int64, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	w := s.liveWindow(key, now)
+	if w == nil {
+		return 0, 0, nil
+	}
+
+	first := sort.Search(len(w.requests), func(i int) bool { return w.requests[i] > windowStart })
+	return int64(len(w.requests) - first), w.expiresAt.Sub(now), nil
+}
+
+// incrementCounter increments the counter and renews its expiry, like INCR followed by EXPIRE.
+func (s *memoryRateLimitStore) incrementCounter(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	c, ok := s.counters[key]
+	if !ok || !now.Before(c.expiresAt) {
+		c = &memoryCounter{}
+		s.counters[key] = c
+	}
+	c.count++
+	c.expiresAt = now.Add(ttl)
+
+	return c.count, nil
+}
+
+// getCounter returns the counter without modifying it.
+func (s *memoryRateLimitStore) getCounter(ctx context.Context, key string) (int64, time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	c, ok := s.counters[key]
+	if !ok || !now.Before(c.expiresAt) {
+		return 0, 0, nil
+	}
+	return c.count, c.expiresAt.Sub(now), nil
+}
+
+// spendToken mirrors tokenBucketScript.
+func (s *memoryRateLimitStore) spendToken(ctx context.Context, key string, rate float64, capacity int, nowMilli int64) (bool, float64, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clock := time.Now()
+	b, ok := s.buckets[key]
+	if !ok || !clock.Before(b.expiresAt) {
+		b = &memoryBucket{tokens: float64(capacity), tsMilli: nowMilli}
+		s.buckets[key] = b
+	}
+
+	tokens := math.Min(float64(capacity), b.tokens+math.Max(0, float64(nowMilli-b.tsMilli))*rate/1000)
+
+	allowed := false
+	if tokens >= 1 {
+		tokens--
+		allowed = true
+	}
+
+	fullIn := int64(math.Max(1, math.Ceil((float64(capacity)-tokens)*1000/rate)))
+	b.tokens = tokens
+	b.tsMilli = nowMilli
+	b.expiresAt = clock.Add(time.Duration(fullIn) * time.Millisecond)
+
+	return allowed, tokens, fullIn, nil
+}
+
+// getTokenBucket returns the bucket without modifying it.
+func (s *memoryRateLimitStore) getTokenBucket(ctx context.Context, key string) (float64, int64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.buckets[key]
+	if !ok || !time.Now().Before(b.expiresAt) {
+		return 0, 0, false, nil
+	}
+	return b.tokens, b.tsMilli, true, nil
+}
@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// rateLimitStores builds a limiter of limit requests per window over each store implementation.
+var rateLimitStores = []struct {
+	name       string
+	newLimiter func(tb testing.TB, limit int, window time.Duration) *RateLimiter
+}{
+	{"memory", func(tb testing.TB, limit int, window time.Duration) *RateLimiter {
+		ctx, cancel := context.WithCancel(context.Background())
+		tb.Cleanup(cancel)
+		return NewMemoryRateLimiter(ctx, "test_rate_limit:", limit, window)
+	}},
+	{"redis", func(tb testing.TB, limit int, window time.Duration) *RateLimiter {
+		limiter, _ := newMiniredisRateLimiter(tb, limit, window)
+		return limiter
+	}},
+}
+
+func TestSlidingWindowAdmitsExactlyLimitUnderConcurrency(t *testing.T) {
+	const (
+		limit    = 100
+		requests = 200
+	)
+
+	for _, store := range rateLimitStores {
+		t.Run(store.name, func(t *testing.T) {
+			router := newRateLimitedRouter(store.newLimiter(t, limit, time.Minute))
+
+			// Release every request at once, so they race for the same window
+			start := make(chan struct{})
+			statuses := make(chan int, requests)
+			var wg sync.WaitGroup
+			for i := 0; i < requests; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					<-start
+					statuses <- serveFrom(router, "192.0.2.1:1234").Code
+				}()
+			}
+			close(start)
+			wg.Wait()
+			close(statuses)
+
+			passed, rejected := 0, 0
+			for status := range statuses {
+				switch status {
+				case http.StatusOK:
+					passed++
+				case http.StatusTooManyRequests:
+					rejected++
+				default:
+					t.Errorf("got unexpected status %d", status)
+				}
+			}
+			if passed != limit || rejected != requests-limit {
+				t.Errorf("got %d passed and %d rejected, want %d and %d", passed, rejected, limit, requests-limit)
+			}
+		})
+	}
+}
+
+// BenchmarkSlidingWindow measures recording a request in the sliding window of each store,
+// with concurrent requests for the same subject.
+func BenchmarkSlidingWindow(b *testing.B) {
+	for _, store := range rateLimitStores {
+		b.Run(store.name, func(b *testing.B) {
+			limiter := store.newLimiter(b, 1<<30, time.Minute)
+			ctx := context.Background()
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if _, err := limiter.take(ctx, "subject", 1<<30, time.Minute); err != nil {
+						b.Errorf("take failed: %v", err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+// newMiniredisRateLimiter returns a limiter of limit requests per window backed by a fresh
+// in-process Redis server, along with the server.
+func newMiniredisRateLimiter(tb testing.TB, limit int, window time.Duration) (*RateLimiter, *miniredis.Miniredis) {
+	tb.Helper()
+
+	server := miniredis.RunT(tb)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), PoolSize: 256})
+	tb.Cleanup(func() { client.Close() })
+
+	return NewRedisRateLimiter(client, "test_rate_limit:", limit, window), server
+}
+
+// newRateLimitedRouter returns a router answering GET / with 200 OK behind the limiter.
+func newRateLimitedRouter(limiter *RateLimiter) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ErrorHandler(), RateLimitMiddleware(limiter))
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+// serveFrom sends GET / to the router from the given client address.
+func serveFrom(router http.Handler, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remoteAddr
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestRateLimitHeadersMatchAcrossWindowAlgorithms(t *testing.T) {
+	const limit = 3
+	window := time.Minute
+
+	for _, algorithm := range []struct {
+		name      string
+		algorithm RateLimitAlgorithm
+	}{
+		{"sliding", RateLimitSlidingWindow},
+		{"fixed", RateLimitFixedWindow},
+	} {
+		t.Run(algorithm.name, func(t *testing.T) {
+			limiter, _ := newMiniredisRateLimiter(t, limit, window)
+			limiter.Algorithm = algorithm.algorithm
+			router := newRateLimitedRouter(limiter)
+
+			wantStatus := []int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusTooManyRequests}
+			wantRemaining := []string{"2", "1", "0", "0"}
+			for i := range wantStatus {
+				now := time.Now().Unix()
+				resp := serveFrom(router, "192.0.2.1:1234")
+
+				if resp.Code != wantStatus[i] {
+					t.Errorf("request %d: got status %d, want %d", i+1, resp.Code, wantStatus[i])
+				}
+				if got := resp.Header().Get("X-RateLimit-Limit"); got != strconv.Itoa(limit) {
+					t.Errorf("request %d: got X-RateLimit-Limit %q, want %q", i+1, got, strconv.Itoa(limit))
+				}
+				if got := resp.Header().Get("X-RateLimit-Remaining"); got != wantRemaining[i] {
+					t.Errorf("request %d: got X-RateLimit-Remaining %q, want %q", i+1, got, wantRemaining[i])
+				}
+
+				// The sliding window resets a full window from now, the fixed one at the end of the current window
+				reset, err := strconv.ParseInt(resp.Header().Get("X-RateLimit-Reset"), 10, 64)
+				if err != nil || reset <= now || reset > now+int64(window.Seconds())+1 {
+					t.Errorf("request %d: got X-RateLimit-Reset %q, want a time within the next window", i+1, resp.Header().Get("X-RateLimit-Reset"))
+				}
+				if got := resp.Header().Get("X-RateLimit-Burst"); got != "" {
+					t.Errorf("request %d: got X-RateLimit-Burst %q, want none for a window algorithm", i+1, got)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkRateLimitAlgorithms compares the cost of counting a request with the sliding and
+// fixed windows, reporting the Redis commands each runs per request.
+func BenchmarkRateLimitAlgorithms(b *testing.B) {
+	for _, algorithm := range []struct {
+		name      string
+		algorithm RateLimitAlgorithm
+	}{
+		{"sliding", RateLimitSlidingWindow},
+		{"fixed", RateLimitFixedWindow},
+	} {
+		b.Run(algorithm.name, func(b *testing.B) {
+			limiter, server := newMiniredisRateLimiter(b, b.N+1, time.Minute)
+			limiter.Algorithm = algorithm.algorithm
+			ctx := context.Background()
+
+			// Load the script first, so only the per-request commands are counted
+			if _, err := limiter.take(ctx, "warmup", 1, time.Minute); err != nil {
+				b.Fatalf("take failed: %v", err)
+			}
+
+			commands := server.CommandCount()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := limiter.take(ctx, "subject", b.N+1, time.Minute); err != nil {
+					b.Fatalf("take failed: %v", err)
+				}
+			}
+			b.StopTimer()
+
+			b.ReportMetric(float64(server.CommandCount()-commands)/float64(b.N), "commands/op")
+		})
+	}
+}
@@ -3,7 +3,6 @@ package middleware
 
 import (
 	"net/http"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -20,12 +19,14 @@ func Recovery(logger *zap.Logger) gin.HandlerFunc {
 					zap.Any("error", err),
 					zap.String("method", c.Request.Method),
 					zap.String("path", c.Request.URL.Path),
-					zap.String("ip", c.ClientIP()),
+					zap.String("ip", ClientIP(c)),
+					zap.String("request_id", GetRequestID(c)),
 				)
 
 				c.JSON(http.StatusInternalServerError, gin.H{
 					"error":             "internal_server_error",
 					"error_description": "An unexpected error occurred",
+					"request_id":        GetRequestID(c),
 				})
 				c.Abort()
 			}
@@ -34,27 +35,3 @@ func Recovery(logger *zap.Logger) gin.HandlerFunc {
 		c.Next()
 	}
 }
-
-// RequestLogger creates a middleware that logs details about each request.
-// It captures the request method, path, status code, response time, client IP,
-// user agent, and number of errors encountered during request processing.
-// This middleware provides valuable information for monitoring and debugging.
-func RequestLogger(logger *zap.Logger) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		start := time.Now()
-
-		// Process request
-		c.Next()
-
-		// Log request details
-		logger.Info("request processed",
-			zap.String("method", c.Request.Method),
-			zap.String("path", c.Request.URL.Path),
-			zap.Int("status", c.Writer.Status()),
-			zap.Duration("latency", time.Since(start)),
-			zap.String("ip", c.ClientIP()),
-			zap.String("user_agent", c.Request.UserAgent()),
-			zap.Int("errors", len(c.Errors)),
-		)
-	}
-}
@@ -0,0 +1,106 @@
+// Package middleware provides HTTP middleware functions for the application.
+package middleware
+
+import (
+	"bytes"
+	stderrors "errors"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+)
+
+// RequestLimits bounds the size of the requests to form-processing endpoints. A zero limit
+// leaves its dimension unbounded.
+type RequestLimits struct {
+	MaxFormBodyBytes int64 // Largest form or other non-JSON body accepted
+	MaxJSONBodyBytes int64 // Largest JSON body accepted, such as a client registration
+	MaxParamValues   int   // Most values one query or form parameter may have, such as repeated resource parameters
+	MaxParamLength   int   // Longest value of a query or form parameter, in bytes
+}
+
+// RequestLimitsMiddleware returns a middleware enforcing the request limits before a handler
+// reads the request. A body whose declared length exceeds its limit is rejected with 413
+// Request Entity Too Large without being read; other bodies are read through a reader that
+// stops at the limit, so an oversized body never ends up in memory whole. Form bodies are
+// parsed here, and parameters with too many values or too long a value, in the form or the
+// query, are rejected with 400 Bad Request. JSON bodies are read up to their limit and handed
+// on to the handler.
+//
+// It must run before anything that parses the form, such as the rate limiters reading the
+// client_id of a request.
+func RequestLimitsMiddleware(limits RequestLimits) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := limitRequest(c, limits); err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// limitRequest applies the limits to the request, returning the error to reject it with.
+func limitRequest(c *gin.Context, limits RequestLimits) error {
+	isJSON := c.ContentType() == gin.MIMEJSON
+	limit := limits.MaxFormBodyBytes
+	if isJSON {
+		limit = limits.MaxJSONBodyBytes
+	}
+
+	if limit > 0 && c.Request.Body != nil && c.Request.Body != http.NoBody {
+		if c.Request.ContentLength > limit {
+			return errors.RequestEntityTooLarge(errors.ErrMsgRequestBodyTooLarge)
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+	}
+
+	params := c.Request.URL.Query()
+	if isJSON {
+		if c.Request.Body != nil {
+			body, err := io.ReadAll(c.Request.Body)
+			if err != nil {
+				return bodyReadError(err)
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+	} else {
+		if err := c.Request.ParseForm(); err != nil {
+			return bodyReadError(err)
+		}
+		params = c.Request.Form
+	}
+
+	return checkParams(params, limits)
+}
+
+// checkParams rejects parameters with more values or longer values than the limits allow.
+// The name of the offending parameter is given as the error details.
+func checkParams(params url.Values, limits RequestLimits) error {
+	for name, values := range params {
+		if limits.MaxParamValues > 0 && len(values) > limits.MaxParamValues {
+			return errors.BadRequest(errors.ErrMsgTooManyParameterValues).WithDetails(name)
+		}
+		if limits.MaxParamLength <= 0 {
+			continue
+		}
+		for _, value := range values {
+			if len(value) > limits.MaxParamLength {
+				return errors.BadRequest(errors.ErrMsgParameterValueTooLong).WithDetails(name)
+			}
+		}
+	}
+	return nil
+}
+
+// bodyReadError maps an error reading a request body to the error rejecting the request:
+// 413 when the body exceeded its limit, else 400 for a body that does not parse.
+func bodyReadError(err error) error {
+	var tooLarge *http.MaxBytesError
+	if stderrors.As(err, &tooLarge) {
+		return errors.RequestEntityTooLarge(errors.ErrMsgRequestBodyTooLarge)
+	}
+	return errors.BadRequest(errors.ErrMsgInvalidRequestFormat).Wrap(err)
+}
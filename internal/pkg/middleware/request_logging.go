@@ -0,0 +1,143 @@
+// Package middleware provides HTTP middleware functions for the application.
+package middleware
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// HeaderRequestID is the HTTP header carrying the request correlation ID
+	HeaderRequestID = "X-Request-ID"
+
+	// ContextKeyRequestID is the context key under which the correlation ID is stored
+	ContextKeyRequestID = "request_id"
+
+	// maxRequestIDLength bounds client-supplied correlation IDs accepted for propagation
+	maxRequestIDLength = 128
+
+	// redactedValue replaces sensitive values in logged request bodies
+	redactedValue = "[REDACTED]"
+)
+
+// sensitiveFormFields lists form fields whose values must never be logged
+var sensitiveFormFields = map[string]bool{
+	"client_secret":    true,
+	"client_assertion": true,
+	"password":         true,
+	"old_password":     true,
+	"new_password":     true,
+	"code":             true,
+	"code_verifier":    true,
+	"device_code":      true,
+	"refresh_token":    true,
+	"access_token":     true,
+	"token":            true,
+	"subject_token":    true,
+	"actor_token":      true,
+	"id_token_hint":    true,
+}
+
+// RequestLoggingMiddleware creates a middleware that assigns each request a correlation ID
+// and logs a structured entry when the request completes.
+// A valid X-Request-ID header from the caller is propagated; otherwise a new ID is generated.
+// The ID is stored in the context for downstream handlers and echoed in the response header.
+// The log entry includes method, path, status, latency, client IP, the resolved client and
+// user IDs, the last error with its cause, and the form body with sensitive fields redacted.
+func RequestLoggingMiddleware(logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID := c.GetHeader(HeaderRequestID)
+		if !isValidRequestID(requestID) {
+			requestID = uuid.New().String()
+		}
+		c.Set(ContextKeyRequestID, requestID)
+		c.Header(HeaderRequestID, requestID)
+
+		// Process request
+		c.Next()
+
+		fields := []zap.Field{
+			zap.String("request_id", requestID),
+			zap.String("method", c.Request.Method),
+			zap.String("path", c.Request.URL.Path),
+			zap.Int("status", c.Writer.Status()),
+			zap.Duration("latency", time.Since(start)),
+			zap.String("ip", ClientIP(c)),
+			zap.String("user_agent", c.Request.UserAgent()),
+			zap.Int("errors", len(c.Errors)),
+		}
+
+		if len(c.Errors) > 0 {
+			fields = append(fields, zap.String("error", c.Errors.Last().Err.Error()))
+		}
+		if clientID := tokenClientID(c); clientID != "" {
+			fields = append(fields, zap.String("client_id", clientID))
+		} else if clientID := claimedClientID(c); clientID != "" {
+			fields = append(fields, zap.String("client_id", clientID))
+		}
+		if userID, exists := c.Get(ContextKeyUserID); exists {
+			fields = append(fields, zap.Any("user_id", userID))
+		}
+		if body := redactForm(c.Request.PostForm); body != "" {
+			fields = append(fields, zap.String("body", body))
+		}
+
+		logger.Info("request processed", fields...)
+	}
+}
+
+// GetRequestID returns the correlation ID assigned to the request,
+// or an empty string if RequestLoggingMiddleware has not run.
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(ContextKeyRequestID)
+}
+
+// isValidRequestID reports whether a caller-supplied correlation ID is safe to propagate.
+// Only short IDs of letters, digits, dashes, underscores, and dots are accepted so that
+// arbitrary input is never reflected into headers or logs.
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-' || r == '_' || r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// redactForm encodes parsed form values for logging with sensitive fields redacted.
+// Only form bodies already parsed by handlers are logged; JSON bodies are never read.
+// Returns an empty string if there are no form values.
+func redactForm(form url.Values) string {
+	if len(form) == 0 {
+		return ""
+	}
+
+	redacted := make(url.Values, len(form))
+	for key, values := range form {
+		if sensitiveFormFields[strings.ToLower(key)] {
+			redacted[key] = []string{redactedValue}
+			continue
+		}
+		redacted[key] = values
+	}
+
+	encoded, err := url.QueryUnescape(redacted.Encode())
+	if err != nil {
+		return redacted.Encode()
+	}
+	return encoded
+}
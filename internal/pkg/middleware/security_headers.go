@@ -0,0 +1,136 @@
+// Package middleware provides HTTP middleware functions for the application.
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContextKeyCSPNonce holds the nonce the Content-Security-Policy of the response allows inline scripts with
+const ContextKeyCSPNonce = "csp_nonce"
+
+// CSPNoncePlaceholder stands for the nonce of each response in a Content-Security-Policy
+const CSPNoncePlaceholder = "{nonce}"
+
+// cspNonceBytes is the number of random bytes in a CSP nonce
+const cspNonceBytes = 16
+
+// contextKeyCSP holds the Content-Security-Policy of the response, before the nonce is filled in
+const contextKeyCSP = "content_security_policy"
+
+// SecurityHeadersConfig configures the security headers middleware. An empty value omits its header.
+type SecurityHeadersConfig struct {
+	ContentSecurityPolicy string        // Policy of every response, CSPNoncePlaceholder standing for its nonce
+	FrameOptions          string        // X-Frame-Options, for browsers without frame-ancestors
+	ReferrerPolicy        string        // Referrer-Policy
+	ContentTypeOptions    string        // X-Content-Type-Options
+	HSTSMaxAge            time.Duration // How long browsers are to use only HTTPS, zero to omit Strict-Transport-Security
+	HSTSIncludeSubdomains bool          // Whether HTTPS is required of the subdomains as well
+}
+
+// SecurityHeadersMiddleware returns a middleware that sets the configured security headers on
+// every response. Each request gets a fresh CSP nonce, which pages get from CSPNonce for their
+// inline scripts. Routes with other needs replace the policy with ContentSecurityPolicy, and
+// handlers adjust single directives of it with SetCSPDirective; the nonce is filled in when
+// the response is written.
+func SecurityHeadersMiddleware(cfg SecurityHeadersConfig) gin.HandlerFunc {
+	hsts := ""
+	if cfg.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(int(cfg.HSTSMaxAge.Seconds()))
+		if cfg.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+	}
+
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		setHeader := func(name, value string) {
+			if value != "" {
+				header.Set(name, value)
+			}
+		}
+		setHeader("X-Frame-Options", cfg.FrameOptions)
+		setHeader("Referrer-Policy", cfg.ReferrerPolicy)
+		setHeader("X-Content-Type-Options", cfg.ContentTypeOptions)
+		setHeader("Strict-Transport-Security", hsts)
+
+		if cfg.ContentSecurityPolicy != "" {
+			nonce := make([]byte, cspNonceBytes)
+			if _, err := rand.Read(nonce); err != nil {
+				// Without a nonce no inline script may run, which only breaks the pages needing one
+				c.Set(contextKeyCSP, strings.ReplaceAll(cfg.ContentSecurityPolicy, "'nonce-"+CSPNoncePlaceholder+"'", "'none'"))
+			} else {
+				c.Set(ContextKeyCSPNonce, base64.StdEncoding.EncodeToString(nonce))
+				c.Set(contextKeyCSP, cfg.ContentSecurityPolicy)
+			}
+			writeContentSecurityPolicy(c)
+		}
+
+		c.Next()
+	}
+}
+
+// ContentSecurityPolicy returns a middleware replacing the Content-Security-Policy of the
+// responses of a route, CSPNoncePlaceholder standing for the nonce of each response; an empty
+// policy omits the header. It has no effect unless SecurityHeadersMiddleware sets a policy.
+func ContentSecurityPolicy(policy string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get(contextKeyCSP); ok {
+			c.Set(contextKeyCSP, policy)
+			writeContentSecurityPolicy(c)
+		}
+		c.Next()
+	}
+}
+
+// SetCSPDirective replaces one directive of the Content-Security-Policy of the response, or
+// adds it when the policy lacks it, before the handler writes the response. Values are
+// sources, such as origins or 'self'; without any the directive is set to 'none'. It has no
+// effect unless SecurityHeadersMiddleware sets a policy.
+func SetCSPDirective(c *gin.Context, name string, values ...string) {
+	policy := c.GetString(contextKeyCSP)
+	if policy == "" {
+		return
+	}
+	if len(values) == 0 {
+		values = []string{"'none'"}
+	}
+	directive := name + " " + strings.Join(values, " ")
+
+	directives := strings.Split(policy, ";")
+	replaced := false
+	for i, d := range directives {
+		if fields := strings.Fields(d); len(fields) > 0 && strings.EqualFold(fields[0], name) {
+			directives[i] = " " + directive
+			replaced = true
+		}
+	}
+	if !replaced {
+		directives = append(directives, " "+directive)
+	}
+
+	c.Set(contextKeyCSP, strings.TrimSpace(strings.Join(directives, ";")))
+	writeContentSecurityPolicy(c)
+}
+
+// CSPNonce returns the nonce inline scripts of the response must carry to run, or an empty
+// string when the response has no Content-Security-Policy with a nonce.
+func CSPNonce(c *gin.Context) string {
+	return c.GetString(ContextKeyCSPNonce)
+}
+
+// writeContentSecurityPolicy sets the Content-Security-Policy header from the current policy of
+// the response, with its nonce filled in, or removes it when a route replaced the policy with none.
+func writeContentSecurityPolicy(c *gin.Context) {
+	policy := strings.ReplaceAll(c.GetString(contextKeyCSP), CSPNoncePlaceholder, CSPNonce(c))
+	if policy == "" {
+		c.Writer.Header().Del("Content-Security-Policy")
+		return
+	}
+	c.Writer.Header().Set("Content-Security-Policy", policy)
+}
@@ -0,0 +1,49 @@
+// Package middleware provides HTTP middleware functions for the application.
+package middleware
+
+import (
+	"sync/atomic"
+
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Drainer tracks whether the server is shutting down.
+// Once draining starts, requests still in flight are allowed to finish while
+// new ones are turned away.
+type Drainer struct {
+	draining atomic.Bool
+}
+
+// NewDrainer creates a Drainer that is not yet draining.
+func NewDrainer() *Drainer {
+	return &Drainer{}
+}
+
+// Start marks the server as draining. It is safe to call more than once.
+func (d *Drainer) Start() {
+	d.draining.Store(true)
+}
+
+// Draining reports whether the server is shutting down.
+func (d *Drainer) Draining() bool {
+	return d.draining.Load()
+}
+
+// DrainMiddleware rejects requests with 503 Service Unavailable while the server is draining.
+// Requests that started before the drain are unaffected. The connection is closed after the
+// response so that clients reconnect to another instance instead of reusing it.
+// It must be registered after ErrorHandler, which writes the error response.
+func DrainMiddleware(drainer *Drainer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if drainer.Draining() {
+			c.Header("Connection", "close")
+			c.Error(errors.ServiceUnavailable(errors.ErrMsgServerShuttingDown))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,127 @@
+// Package middleware provides HTTP middleware functions for the application.
+package middleware
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/pkg/config"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+	"github.com/verigate/verigate-server/internal/pkg/utils/jwt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserAuthentication is the user authentication a protected resource demands of the access
+// tokens presented to it (RFC 9470): how the user must have authenticated and how recently.
+type UserAuthentication struct {
+	ACRValues []string      // Authentication context classes accepted, most preferred first; any is accepted when empty
+	MaxAge    time.Duration // Longest time since the user authenticated, whole seconds; unchecked when zero
+}
+
+// RequireUserAuthentication returns a middleware demanding a step-up for sensitive operations
+// when the user authentication behind the access token does not meet the requirement. It must
+// be registered after Auth or WebAuth, whose claims or web session it checks: the acr must meet
+// one of the accepted values and the authentication time be no older than the maximum age.
+// Web access tokens carry no acr, so only cookie-backed web sessions meet accepted values.
+//
+// A token that does not meet them is rejected with 401 Unauthorized and a Bearer challenge with
+// the insufficient_user_authentication error naming the acr_values and max_age to request
+// (RFC 9470 Section 3). The client then sends the user through the authorization endpoint with
+// them, which asks the user to log in again, and retries with the token issued for the new login.
+func RequireUserAuthentication(required UserAuthentication) gin.HandlerFunc {
+	challenge := required.challenge()
+
+	return func(c *gin.Context) {
+		acr, authTime, ok := userAuthentication(c)
+		if !ok {
+			c.Error(errors.Unauthorized(ErrMsgInvalidToken))
+			c.Abort()
+			return
+		}
+
+		if details := required.unmetBy(acr, authTime); details != "" {
+			c.Header("WWW-Authenticate", challenge)
+			c.Error(errors.Unauthorized(errors.ErrMsgInsufficientUserAuthentication).WithDetails(details))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// userAuthentication returns how and when the user behind the request authenticated, from the
+// claims Auth validated or else from the web session WebAuth validated. It reports false for a
+// request neither authenticated.
+func userAuthentication(c *gin.Context) (acr string, authTime time.Time, ok bool) {
+	if value, exists := c.Get(ContextKeyClaims); exists {
+		if claims, ok := value.(*jwt.Claims); ok {
+			if claims.AuthTime != nil {
+				authTime = claims.AuthTime.Time
+			}
+			return claims.ACR, authTime, true
+		}
+	}
+	if _, exists := c.Get(ContextKeyUserID); exists {
+		return c.GetString(ContextKeyACR), c.GetTime(ContextKeyAuthTime), true
+	}
+	return "", time.Time{}, false
+}
+
+// unmetBy describes how a user authentication that met acr at authTime falls short of the
+// requirement, or returns an empty string when it meets it. An authentication without an acr
+// meets no accepted value, and one without an authentication time no maximum age.
+func (u UserAuthentication) unmetBy(acr string, authTime time.Time) string {
+	if len(u.ACRValues) > 0 {
+		met := false
+		for _, value := range u.ACRValues {
+			if acrMeets(value, acr) {
+				met = true
+				break
+			}
+		}
+		if !met {
+			return "a stronger authentication is required"
+		}
+	}
+
+	if u.MaxAge > 0 && (authTime.IsZero() || time.Since(authTime) > u.MaxAge) {
+		return "a more recent authentication is required"
+	}
+
+	return ""
+}
+
+// challenge builds the WWW-Authenticate challenge asking for the user authentication
+// (RFC 9470 Section 3).
+func (u UserAuthentication) challenge() string {
+	challenge := AuthHeaderPrefix + ` error="` + errors.ErrMsgInsufficientUserAuthentication + `"` +
+		`, error_description="A different authentication level is required"`
+	if len(u.ACRValues) > 0 {
+		challenge += `, acr_values="` + strings.Join(u.ACRValues, " ") + `"`
+	}
+	if u.MaxAge > 0 {
+		challenge += `, max_age="` + strconv.Itoa(int(u.MaxAge/time.Second)) + `"`
+	}
+	return challenge
+}
+
+// acrMeets reports whether a user authentication that met acr meets the accepted acr value:
+// acr is the value itself or, by the configured ACR requirements, a way of logging in that
+// satisfies it, so a passkey login meets a demand for mfa.
+func acrMeets(value, acr string) bool {
+	if acr == "" {
+		return false
+	}
+	if acr == value {
+		return true
+	}
+	for _, method := range config.AppConfig.ACRRequirements[value] {
+		if method == acr {
+			return true
+		}
+	}
+	return false
+}
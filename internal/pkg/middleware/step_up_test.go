@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/verigate/verigate-server/internal/pkg/config"
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+	jwtutil "github.com/verigate/verigate-server/internal/pkg/utils/jwt"
+)
+
+// tokenAuthentication stands in for Auth, authenticating the request with an access token that
+// met acr at authTime.
+func tokenAuthentication(acr string, authTime time.Time) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims := &jwtutil.Claims{UserID: 1, ACR: acr}
+		if !authTime.IsZero() {
+			claims.AuthTime = jwt.NewNumericDate(authTime)
+		}
+		c.Set(ContextKeyUserID, claims.UserID)
+		c.Set(ContextKeyClaims, claims)
+	}
+}
+
+// sessionAuthentication stands in for WebAuth, authenticating the request with a cookie-backed
+// web session that met acr at authTime.
+func sessionAuthentication(acr string, authTime time.Time) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(ContextKeyUserID, uint(1))
+		c.Set(ContextKeyAuthTime, authTime)
+		c.Set(ContextKeyACR, acr)
+	}
+}
+
+func TestRequireUserAuthentication(t *testing.T) {
+	previous := config.AppConfig.ACRRequirements
+	config.AppConfig.ACRRequirements = map[string][]string{"mfa": {"mfa", "hwk"}}
+	t.Cleanup(func() { config.AppConfig.ACRRequirements = previous })
+
+	required := UserAuthentication{ACRValues: []string{"mfa"}, MaxAge: 10 * time.Minute}
+	recent := time.Now().Add(-time.Minute)
+	stale := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name         string
+		authenticate gin.HandlerFunc
+		wantStatus   int
+	}{
+		{"token meeting the requirement", tokenAuthentication("mfa", recent), http.StatusOK},
+		{"token of a login satisfying the acr", tokenAuthentication("hwk", recent), http.StatusOK},
+		{"token of a weaker login", tokenAuthentication("pwd", recent), http.StatusUnauthorized},
+		{"token without acr", tokenAuthentication("", recent), http.StatusUnauthorized},
+		{"token of a stale login", tokenAuthentication("mfa", stale), http.StatusUnauthorized},
+		{"token without auth_time", tokenAuthentication("mfa", time.Time{}), http.StatusUnauthorized},
+		{"web session meeting the requirement", sessionAuthentication("mfa", recent), http.StatusOK},
+		{"web session of a weaker login", sessionAuthentication("pwd", recent), http.StatusUnauthorized},
+		{"web session of a stale login", sessionAuthentication("mfa", stale), http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			router.Use(ErrorHandler(), tt.authenticate, RequireUserAuthentication(required))
+			router.POST("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/", nil))
+
+			if resp.Code != tt.wantStatus {
+				t.Fatalf("got status %d, want %d", resp.Code, tt.wantStatus)
+			}
+			challenge := resp.Header().Get("WWW-Authenticate")
+			if tt.wantStatus == http.StatusOK {
+				if challenge != "" {
+					t.Errorf("got challenge %q, want none", challenge)
+				}
+				return
+			}
+
+			for _, want := range []string{
+				AuthHeaderPrefix + " ",
+				`error="` + errors.ErrMsgInsufficientUserAuthentication + `"`,
+				`acr_values="mfa"`,
+				`max_age="600"`,
+			} {
+				if !strings.Contains(challenge, want) {
+					t.Errorf("got challenge %q, want it to contain %s", challenge, want)
+				}
+			}
+			var body map[string]interface{}
+			if err := json.Unmarshal(resp.Body.Bytes(), &body); err != nil {
+				t.Fatalf("failed to decode body %q: %v", resp.Body.String(), err)
+			}
+			if body["error"] != errors.ErrMsgInsufficientUserAuthentication {
+				t.Errorf("got error %v, want %q", body["error"], errors.ErrMsgInsufficientUserAuthentication)
+			}
+		})
+	}
+}
+
+func TestRequireUserAuthenticationRejectsUnauthenticatedRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ErrorHandler(), RequireUserAuthentication(UserAuthentication{MaxAge: time.Minute}))
+	router.POST("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/", nil))
+
+	if resp.Code != http.StatusUnauthorized {
+		t.Errorf("got status %d, want %d", resp.Code, http.StatusUnauthorized)
+	}
+	if challenge := resp.Header().Get("WWW-Authenticate"); strings.Contains(challenge, errors.ErrMsgInsufficientUserAuthentication) {
+		t.Errorf("got step-up challenge %q for a request that never authenticated", challenge)
+	}
+}
+
+func TestUserAuthenticationChallengeOmitsUnsetParameters(t *testing.T) {
+	challenge := UserAuthentication{MaxAge: 15 * time.Minute}.challenge()
+	if strings.Contains(challenge, "acr_values") || !strings.Contains(challenge, `max_age="900"`) {
+		t.Errorf("got challenge %q, want max_age alone", challenge)
+	}
+}
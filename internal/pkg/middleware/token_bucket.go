@@ -0,0 +1,96 @@
+// Package middleware provides HTTP middleware functions for the application.
+package middleware
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// tokenBucketParams returns the refill rate in tokens per second and the burst capacity
+// for a bucket whose sustained rate is limit requests per window.
+// RefillRate and BurstCapacity override the values derived from the limit when set.
+func (r *RateLimiter) tokenBucketParams(limit int, window time.Duration) (float64, int) {
+	rate := r.RefillRate
+	if rate <= 0 {
+		rate = float64(limit) / math.Max(window.Seconds(), 1)
+	}
+
+	capacity := r.BurstCapacity
+	if capacity <= 0 {
+		capacity = limit
+	}
+
+	return rate, capacity
+}
+
+// takeToken spends one token from the bucket under key.
+// The reported limit is the sustained number of requests per window at the refill rate,
+// and the reset time is when the bucket will be full again.
+func (r *RateLimiter) takeToken(ctx context.Context, key string, limit int, window time.Duration) (rateLimitResult, error) {
+	rate, capacity := r.tokenBucketParams(limit, window)
+	now := time.Now()
+
+	allowed, tokens, fullIn, err := r.store.spendToken(ctx, key, rate, capacity, now.UnixMilli())
+	if err != nil {
+		return rateLimitResult{}, err
+	}
+
+	return rateLimitResult{
+		allowed:   allowed,
+		limit:     int(math.Round(rate * window.Seconds())),
+		remaining: int(math.Floor(tokens)),
+		resetAt:   now.Add(time.Duration(fullIn) * time.Millisecond).Unix(),
+		burst:     capacity,
+	}, nil
+}
+
+// peekToken reports whether the bucket under key holds a token for another request, like
+// takeToken, without spending it. The bucket is only read, never modified.
+func (r *RateLimiter) peekToken(ctx context.Context, key string, limit int, window time.Duration, now time.Time) (rateLimitResult, error) {
+	rate, capacity := r.tokenBucketParams(limit, window)
+
+	tokens, err := r.bucketTokens(ctx, key, rate, capacity, now)
+	if err != nil {
+		return rateLimitResult{}, err
+	}
+
+	fullIn := time.Duration((float64(capacity) - tokens) / rate * float64(time.Second))
+	return rateLimitResult{
+		allowed:   tokens >= 1,
+		limit:     int(math.Round(rate * window.Seconds())),
+		remaining: int(math.Floor(tokens)),
+		resetAt:   now.Add(fullIn).Unix(),
+		burst:     capacity,
+	}, nil
+}
+
+// inspectTokenBucket reports how many tokens have been spent from the bucket under key,
+// accounting for the refill since its last update, and when it will be full again.
+// The bucket is only read, never modified.
+func (r *RateLimiter) inspectTokenBucket(ctx context.Context, key string, now time.Time) (int, time.Time, error) {
+	rate, capacity := r.tokenBucketParams(r.limitPerMin, r.window)
+
+	tokens, err := r.bucketTokens(ctx, key, rate, capacity, now)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	fullIn := time.Duration((float64(capacity) - tokens) / rate * float64(time.Second))
+	return int(math.Ceil(float64(capacity) - tokens)), now.Add(fullIn), nil
+}
+
+// bucketTokens returns the tokens in the bucket under key, accounting for the refill since its
+// last update. A missing bucket is full.
+func (r *RateLimiter) bucketTokens(ctx context.Context, key string, rate float64, capacity int, now time.Time) (float64, error) {
+	tokens, ts, found, err := r.store.getTokenBucket(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		return float64(capacity), nil
+	}
+
+	elapsed := math.Max(0, float64(now.UnixMilli()-ts))
+	return math.Min(float64(capacity), tokens+elapsed*rate/1000), nil
+}
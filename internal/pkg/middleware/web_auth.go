@@ -2,7 +2,10 @@
 package middleware
 
 import (
+	"strings"
+
 	"github.com/verigate/verigate-server/internal/app/auth"
+	"github.com/verigate/verigate-server/internal/pkg/config"
 	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
 
 	"github.com/gin-gonic/gin"
@@ -11,16 +14,32 @@ import (
 // WebAuth is an authentication middleware for web applications.
 // This middleware validates JWT access tokens issued to web application users
 // and operates independently from the OAuth 2.0 authentication system.
+// Browsers without an Authorization header are authenticated by their session cookie instead.
 //
 // The middleware:
-// 1. Extracts the Authorization header from the request
+// 1. Extracts the Authorization header from the request, or else the session cookie
 // 2. Validates the bearer token format
-// 3. Verifies the token signature and validity using the auth service
-// 4. Sets the authenticated user ID in the request context for downstream handlers
+// 3. Verifies the token signature and validity, or looks up and renews the session, using the auth service
+// 4. Sets the authenticated user ID, session authentication time, and session ID in the request context
 //
 // If authentication fails, the middleware aborts the request with an appropriate error.
 func WebAuth(authService *auth.Service) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if c.GetHeader(AuthHeaderName) == "" {
+			if cookie, err := c.Cookie(config.AppConfig.SessionCookieName); err == nil && cookie != "" {
+				session, err := authService.ValidateSessionID(c.Request.Context(), cookie)
+				if err != nil {
+					c.Error(errors.Unauthorized(errors.ErrMsgInvalidSession))
+					c.Abort()
+					return
+				}
+
+				setWebSession(c, session)
+				c.Next()
+				return
+			}
+		}
+
 		// Extract bearer token from Authorization header
 		tokenString, ok := extractBearerToken(c)
 		if !ok {
@@ -28,7 +47,7 @@ func WebAuth(authService *auth.Service) gin.HandlerFunc {
 		}
 
 		// Validate token and extract user ID
-		userID, err := authService.ValidateAccessToken(tokenString)
+		session, err := authService.ValidateSession(c.Request.Context(), tokenString)
 		if err != nil {
 			c.Error(errors.Unauthorized(ErrMsgInvalidToken))
 			c.Abort()
@@ -36,8 +55,47 @@ func WebAuth(authService *auth.Service) gin.HandlerFunc {
 		}
 
 		// Store user ID in context for downstream handlers
-		c.Set(ContextKeyUserID, userID)
+		c.Set(ContextKeyUserID, session.UserID)
+		c.Set(ContextKeyAuthTime, session.AuthTime)
+		c.Set(ContextKeySessionID, session.SessionID)
+
+		c.Next()
+	}
+}
+
+// OptionalWebAuth is a web authentication middleware that never rejects a request.
+// If a valid web access token or session cookie is present, the user ID, session authentication time, and
+// session ID are set in the request context exactly as WebAuth does; otherwise the request proceeds
+// unauthenticated. It is used where the handler itself decides how to respond to a
+// missing session, such as the authorization endpoint honoring prompt=none.
+func OptionalWebAuth(authService *auth.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parts := strings.SplitN(c.GetHeader(AuthHeaderName), " ", 2)
+		if len(parts) == 2 && parts[0] == AuthHeaderPrefix {
+			if session, err := authService.ValidateSession(c.Request.Context(), parts[1]); err == nil {
+				c.Set(ContextKeyUserID, session.UserID)
+				c.Set(ContextKeyAuthTime, session.AuthTime)
+				c.Set(ContextKeySessionID, session.SessionID)
+			}
+		} else if cookie, err := c.Cookie(config.AppConfig.SessionCookieName); err == nil && cookie != "" {
+			if session, err := authService.ValidateSessionID(c.Request.Context(), cookie); err == nil {
+				setWebSession(c, session)
+			}
+		}
 
 		c.Next()
 	}
 }
+
+// setWebSession stores a cookie-backed web session in the request context: the user ID,
+// authentication time, and session ID as for access tokens, plus how the user authenticated
+// and any claims mapped from an upstream identity source.
+func setWebSession(c *gin.Context, session *auth.Session) {
+	c.Set(ContextKeyUserID, session.UserID)
+	c.Set(ContextKeyAuthTime, session.AuthTime)
+	c.Set(ContextKeySessionID, session.SID)
+	c.Set(ContextKeyACR, session.ACR)
+	if session.Claims != nil {
+		c.Set(ContextKeySessionClaims, session.Claims)
+	}
+}
@@ -0,0 +1,436 @@
+// Package pagination provides the cursor-based pagination, filtering, and sorting shared by the
+// admin list endpoints. Each endpoint declares a Spec with the parameters it may be filtered by
+// and the fields it may be sorted by; Parse validates a request against it, so no other
+// parameter reaches a query and lists are only ordered by indexed columns. Pages are delimited
+// by the position of their last item, not an offset, so items added between requests neither
+// repeat nor go missing on later pages. Every list is returned in the same envelope, Page.
+package pagination
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
+)
+
+// Query parameters reserved for paging through a list
+const (
+	ParamCursor = "cursor" // next_cursor of the previous page
+	ParamLimit  = "limit"  // Items per page
+	ParamSort   = "sort"   // Field to sort by, prefixed with "-" for descending order
+)
+
+// EstimateLimit is the number of matching items at which the total estimate stops counting,
+// so counting stays cheap however many items match
+const EstimateLimit = 10000
+
+// defaultIDColumn is the unique column breaking ties between items with the same sort value
+const defaultIDColumn = "id"
+
+// Kind is the kind of the values of a filter or sort field. Values are held as the Go type
+// named with each kind.
+type Kind string
+
+// Kinds of field values
+const (
+	KindString Kind = "string" // string
+	KindInt    Kind = "int"    // int64
+	KindBool   Kind = "bool"   // bool
+	KindTime   Kind = "time"   // time.Time, written as RFC 3339
+)
+
+// Op is the comparison of a filter's column with the requested value.
+type Op string
+
+// Filter comparisons
+const (
+	OpEqual   Op = "="  // The column equals the value
+	OpAtLeast Op = ">=" // The column is at or after the value
+	OpBefore  Op = "<"  // The column is before the value
+)
+
+// Filter declares a query parameter a list may be filtered by.
+type Filter struct {
+	Param  string // Query parameter carrying the value
+	Column string // Column compared with the value, never taken from the request
+	Op     Op     // Comparison of the column with the value
+	Kind   Kind   // Kind the value must parse as
+}
+
+// Sort declares a field a list may be sorted by. Only indexed columns should be declared, so
+// no sort makes the database scan the whole table.
+type Sort struct {
+	Name   string // Value of the sort parameter selecting the field
+	Column string // Column the items are ordered by
+	Kind   Kind   // Kind of the column's values
+}
+
+// Spec declares how a list may be filtered, sorted, and paged.
+type Spec struct {
+	Filters      []Filter // Parameters the list may be filtered by
+	Sorts        []Sort   // Fields the list may be sorted by
+	DefaultSort  string   // Sort of requests without one, such as "-created_at"
+	IDColumn     string   // Unique integer column breaking ties, "id" when empty
+	DefaultLimit int      // Items per page of requests without a limit
+	MaxLimit     int      // Most items per page
+}
+
+// Condition is a filter a request applies, with its parsed value.
+type Condition struct {
+	Filter
+	Value interface{} // Requested value, of the Go type of the filter's kind
+}
+
+// Request is a validated request for a page of a list.
+type Request struct {
+	Limit      int         // Items per page
+	Sort       Sort        // Field the items are ordered by, then by their ID
+	Descending bool        // Whether the items are in descending order
+	Conditions []Condition // Filters the items must match
+	After      *Cursor     // Position of the last item of the previous page, nil for the first page
+	idColumn   string
+}
+
+// Cursor is the position of an item in a sorted list, encoded as an opaque next_cursor.
+type Cursor struct {
+	Sort       string `json:"s"` // Name of the sort the position is in
+	Descending bool   `json:"d"` // Whether the sort is descending
+	Value      string `json:"v"` // Sort value of the item, formatted by its kind
+	ID         int64  `json:"i"` // ID of the item
+}
+
+// Page is the envelope of every list: one page of items and the cursor of the next.
+type Page[T any] struct {
+	Items         []T    `json:"items"`
+	NextCursor    string `json:"next_cursor,omitempty"` // Cursor of the next page, empty on the last
+	TotalEstimate int64  `json:"total_estimate"`        // Items matching the filters, counted up to EstimateLimit
+}
+
+// ValueFunc returns the value an item holds in a column declared by a Spec, of the Go type of
+// the column's kind, and its ID as an int64 for the ID column.
+type ValueFunc[T any] func(item T, column string) interface{}
+
+// Parse validates the query parameters of a list request against the spec. Parameters other
+// than the declared filters and the paging parameters are rejected, as are values that do not
+// parse as their kind, sorts that are not declared, and cursors of another sort; the errors
+// are 400 Bad Request naming the parameter in their details.
+func Parse(query url.Values, spec Spec) (*Request, error) {
+	filters := make(map[string]Filter, len(spec.Filters))
+	for _, f := range spec.Filters {
+		filters[f.Param] = f
+	}
+
+	r := &Request{Limit: spec.DefaultLimit, idColumn: spec.IDColumn}
+	if r.idColumn == "" {
+		r.idColumn = defaultIDColumn
+	}
+
+	sortName := spec.DefaultSort
+	for param, values := range query {
+		if len(values) != 1 {
+			return nil, errors.BadRequest(errors.ErrMsgInvalidListParameter).WithDetails(param)
+		}
+		value := values[0]
+
+		switch param {
+		case ParamCursor, ParamSort:
+			if param == ParamSort && value != "" {
+				sortName = value
+			}
+			continue
+		case ParamLimit:
+			limit, err := strconv.Atoi(value)
+			if err != nil || limit <= 0 {
+				return nil, errors.BadRequest(errors.ErrMsgInvalidListLimit).WithDetails(param)
+			}
+			r.Limit = min(limit, spec.MaxLimit)
+			continue
+		}
+
+		f, ok := filters[param]
+		if !ok {
+			return nil, errors.BadRequest(errors.ErrMsgUnknownListParameter).WithDetails(param)
+		}
+		if value == "" {
+			continue
+		}
+		parsed, err := parseValue(f.Kind, value)
+		if err != nil {
+			return nil, errors.BadRequest(errors.ErrMsgInvalidListParameter).WithDetails(param)
+		}
+		r.Conditions = append(r.Conditions, Condition{Filter: f, Value: parsed})
+	}
+
+	// Conditions are applied in the declared order, so equal requests make equal queries
+	order := make(map[string]int, len(spec.Filters))
+	for i, f := range spec.Filters {
+		order[f.Param] = i
+	}
+	sort.Slice(r.Conditions, func(i, j int) bool {
+		return order[r.Conditions[i].Param] < order[r.Conditions[j].Param]
+	})
+
+	r.Descending = strings.HasPrefix(sortName, "-")
+	name := strings.TrimPrefix(sortName, "-")
+	found := false
+	for _, s := range spec.Sorts {
+		if s.Name == name {
+			r.Sort, found = s, true
+			break
+		}
+	}
+	if !found {
+		return nil, errors.BadRequest(errors.ErrMsgInvalidListSort).WithDetails(ParamSort)
+	}
+
+	if encoded := query.Get(ParamCursor); encoded != "" {
+		cursor, err := decodeCursor(encoded)
+		if err != nil || cursor.Sort != r.Sort.Name || cursor.Descending != r.Descending {
+			return nil, errors.BadRequest(errors.ErrMsgInvalidListCursor).WithDetails(ParamCursor)
+		}
+		if _, err := parseValue(r.Sort.Kind, cursor.Value); err != nil {
+			return nil, errors.BadRequest(errors.ErrMsgInvalidListCursor).WithDetails(ParamCursor)
+		}
+		r.After = cursor
+	}
+
+	return r, nil
+}
+
+// Value returns the value a request filters a parameter by, and whether it does.
+func (r *Request) Value(param string) (interface{}, bool) {
+	for _, c := range r.Conditions {
+		if c.Param == param {
+			return c.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Where returns the WHERE clause selecting the items of the requested page: the filter
+// conditions and, after the first page, the position of the previous page's last item. Its
+// PostgreSQL placeholders are numbered after args, which is returned with the values appended.
+// The clause is empty when no item is excluded.
+func (r *Request) Where(args []interface{}) (string, []interface{}) {
+	conditions, args := r.conditions(args)
+	if r.After != nil {
+		value, _ := parseValue(r.Sort.Kind, r.After.Value)
+		args = append(args, value, r.After.ID)
+		op := ">"
+		if r.Descending {
+			op = "<"
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s, %s) %s ($%d, $%d)", r.Sort.Column, r.idColumn, op, len(args)-1, len(args)))
+	}
+	return whereClause(conditions), args
+}
+
+// FilterWhere returns the WHERE clause of the filter conditions alone, for estimating how many
+// items match across all pages. Placeholders are numbered as for Where.
+func (r *Request) FilterWhere(args []interface{}) (string, []interface{}) {
+	conditions, args := r.conditions(args)
+	return whereClause(conditions), args
+}
+
+// OrderBy returns the ORDER BY and LIMIT clauses of the requested page. One item more than the
+// limit is selected, so NewPage can tell whether another page follows.
+func (r *Request) OrderBy(args []interface{}) (string, []interface{}) {
+	direction := "ASC"
+	if r.Descending {
+		direction = "DESC"
+	}
+	args = append(args, r.Limit+1)
+	return fmt.Sprintf("ORDER BY %s %s, %s %s LIMIT $%d", r.Sort.Column, direction, r.idColumn, direction, len(args)), args
+}
+
+// CountQuery returns the query estimating the total of a list: the number of rows of the
+// table matching where, counted up to EstimateLimit.
+func CountQuery(table, where string) string {
+	return fmt.Sprintf("SELECT count(*) FROM (SELECT 1 FROM %s %s LIMIT %d) AS matching", table, where, EstimateLimit)
+}
+
+// NewPage builds the page of a list from the items selected with Where and OrderBy, which may
+// hold one item more than the limit, and the estimated total. The next cursor is the position
+// of the page's last item, set only when more items follow.
+func NewPage[T any](items []T, r *Request, value ValueFunc[T], total int64) *Page[T] {
+	page := &Page[T]{Items: items, TotalEstimate: total}
+	if len(items) > r.Limit {
+		page.Items = items[:r.Limit]
+		last := page.Items[r.Limit-1]
+		id, _ := value(last, r.idColumn).(int64)
+		page.NextCursor = encodeCursor(Cursor{
+			Sort:       r.Sort.Name,
+			Descending: r.Descending,
+			Value:      formatValue(value(last, r.Sort.Column)),
+			ID:         id,
+		})
+	}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	return page
+}
+
+// Select returns the requested page of items held in memory, for stores without a query
+// language. The items are filtered, ordered, and paged as Where and OrderBy would, and the
+// total is exact.
+func Select[T any](items []T, r *Request, value ValueFunc[T]) *Page[T] {
+	var matching []T
+	for _, item := range items {
+		if matchesFilters(r, item, value) {
+			matching = append(matching, item)
+		}
+	}
+
+	sort.SliceStable(matching, func(i, j int) bool {
+		id, _ := value(matching[j], r.idColumn).(int64)
+		return comparePosition(r, matching[i], value(matching[j], r.Sort.Column), id, value) < 0
+	})
+
+	start := 0
+	if r.After != nil {
+		after, _ := parseValue(r.Sort.Kind, r.After.Value)
+		start = sort.Search(len(matching), func(i int) bool {
+			return comparePosition(r, matching[i], after, r.After.ID, value) > 0
+		})
+	}
+	end := min(start+r.Limit+1, len(matching))
+
+	return NewPage(matching[start:end], r, value, int64(len(matching)))
+}
+
+// conditions returns the SQL conditions of the request's filters, with their values appended to args.
+func (r *Request) conditions(args []interface{}) ([]string, []interface{}) {
+	conditions := make([]string, 0, len(r.Conditions)+1)
+	for _, c := range r.Conditions {
+		args = append(args, c.Value)
+		conditions = append(conditions, fmt.Sprintf("%s %s $%d", c.Column, c.Op, len(args)))
+	}
+	return conditions, args
+}
+
+// matchesFilters reports whether an item held in memory matches the request's filters.
+func matchesFilters[T any](r *Request, item T, value ValueFunc[T]) bool {
+	for _, c := range r.Conditions {
+		order, ok := compareValues(value(item, c.Column), c.Value)
+		if !ok {
+			return false
+		}
+		switch c.Op {
+		case OpEqual:
+			ok = order == 0
+		case OpAtLeast:
+			ok = order >= 0
+		case OpBefore:
+			ok = order < 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// comparePosition orders an item held in memory relative to a position in the request's sort,
+// returning a negative number, zero, or a positive number as the item comes before, at, or
+// after the position.
+func comparePosition[T any](r *Request, item T, sortValue interface{}, id int64, value ValueFunc[T]) int {
+	order, _ := compareValues(value(item, r.Sort.Column), sortValue)
+	if order == 0 {
+		itemID, _ := value(item, r.idColumn).(int64)
+		order = cmp.Compare(itemID, id)
+	}
+	if r.Descending {
+		order = -order
+	}
+	return order
+}
+
+// compareValues orders two values of the same kind, returning a negative number, zero, or a
+// positive number as a is less than, equal to, or greater than b. It reports false when the
+// values are not of the same kind.
+func compareValues(a, b interface{}) (int, bool) {
+	switch a := a.(type) {
+	case string:
+		b, ok := b.(string)
+		return strings.Compare(a, b), ok
+	case int64:
+		b, ok := b.(int64)
+		return cmp.Compare(a, b), ok
+	case bool:
+		b, ok := b.(bool)
+		if a == b {
+			return 0, ok
+		}
+		if !a {
+			return -1, ok
+		}
+		return 1, ok
+	case time.Time:
+		b, ok := b.(time.Time)
+		return a.Compare(b), ok
+	}
+	return 0, false
+}
+
+// parseValue parses a value written as a query parameter or in a cursor as the Go type of its kind.
+func parseValue(kind Kind, value string) (interface{}, error) {
+	switch kind {
+	case KindInt:
+		return strconv.ParseInt(value, 10, 64)
+	case KindBool:
+		return strconv.ParseBool(value)
+	case KindTime:
+		return time.Parse(time.RFC3339Nano, value)
+	}
+	return value, nil
+}
+
+// formatValue writes a value for a cursor, inverting parseValue. Times keep their full precision.
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case string:
+		return v
+	}
+	return fmt.Sprint(value)
+}
+
+// encodeCursor encodes a cursor as an opaque, URL-safe string.
+func encodeCursor(cursor Cursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor decodes a cursor encoded by encodeCursor.
+func decodeCursor(encoded string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+// whereClause joins conditions into a WHERE clause, empty without conditions.
+func whereClause(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(conditions, " AND ")
+}
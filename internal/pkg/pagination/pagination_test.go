@@ -0,0 +1,153 @@
+package pagination
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+// row is a list item as held by a store, ordered by creation time then ID.
+type row struct {
+	ID        int64
+	CreatedAt time.Time
+}
+
+// rowValue is the ValueFunc of rows.
+func rowValue(r row, column string) interface{} {
+	switch column {
+	case "id":
+		return r.ID
+	case "created_at":
+		return r.CreatedAt
+	}
+	return nil
+}
+
+var rowSpec = Spec{
+	Sorts:        []Sort{{Name: "created_at", Column: "created_at", Kind: KindTime}},
+	DefaultSort:  "-created_at",
+	DefaultLimit: 3,
+	MaxLimit:     10,
+}
+
+func TestSelectStableWhenRowsInsertedBetweenPages(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, sortName := range []string{"created_at", "-created_at"} {
+		t.Run(sortName, func(t *testing.T) {
+			// Rows 4 to 6 share a creation time, so the ID alone orders them
+			var rows []row
+			for id := int64(1); id <= 10; id++ {
+				createdAt := base.Add(time.Duration(id) * time.Minute)
+				if id >= 4 && id <= 6 {
+					createdAt = base.Add(4 * time.Minute)
+				}
+				rows = append(rows, row{ID: id, CreatedAt: createdAt})
+			}
+			nextID := int64(len(rows) + 1)
+
+			var seen []row
+			query := url.Values{ParamSort: {sortName}}
+			for pages := 0; ; pages++ {
+				if pages > 20 {
+					t.Fatal("paging did not end")
+				}
+				r, err := Parse(query, rowSpec)
+				if err != nil {
+					t.Fatalf("Parse failed: %v", err)
+				}
+				page := Select(rows, r, rowValue)
+				seen = append(seen, page.Items...)
+				if page.NextCursor == "" {
+					break
+				}
+				query.Set(ParamCursor, page.NextCursor)
+
+				// Between pages, a row is created, one is backdated to before every row, and one
+				// ties with rows 4 to 6
+				rows = append(rows,
+					row{ID: nextID, CreatedAt: base.Add(time.Hour + time.Duration(nextID)*time.Minute)},
+					row{ID: nextID + 1, CreatedAt: base.Add(-time.Duration(nextID) * time.Minute)},
+					row{ID: nextID + 2, CreatedAt: base.Add(4 * time.Minute)},
+				)
+				nextID += 3
+			}
+
+			// Every item follows the one before it in the sort, so none repeats and none
+			// inserted behind a page already read shifts into a later one
+			descending := sortName[0] == '-'
+			for i := 1; i < len(seen); i++ {
+				order := seen[i].CreatedAt.Compare(seen[i-1].CreatedAt)
+				if order == 0 {
+					order = int(seen[i].ID - seen[i-1].ID)
+				}
+				if descending {
+					order = -order
+				}
+				if order <= 0 {
+					t.Fatalf("item %d (ID %d) does not follow item %d (ID %d) in %s order", i, seen[i].ID, i-1, seen[i-1].ID, sortName)
+				}
+			}
+
+			found := make(map[int64]bool, len(seen))
+			for _, item := range seen {
+				found[item.ID] = true
+			}
+			for id := int64(1); id <= 10; id++ {
+				if !found[id] {
+					t.Errorf("row %d present before paging began was never returned", id)
+				}
+			}
+		})
+	}
+}
+
+func TestSelectCursorBreaksTiesByID(t *testing.T) {
+	createdAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var rows []row
+	for id := int64(1); id <= 7; id++ {
+		rows = append(rows, row{ID: id, CreatedAt: createdAt})
+	}
+
+	var ids []int64
+	query := url.Values{ParamSort: {"created_at"}, ParamLimit: {"2"}}
+	for {
+		r, err := Parse(query, rowSpec)
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		page := Select(rows, r, rowValue)
+		for _, item := range page.Items {
+			ids = append(ids, item.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Set(ParamCursor, page.NextCursor)
+	}
+
+	if len(ids) != len(rows) {
+		t.Fatalf("got IDs %v, want each of the %d rows once", ids, len(rows))
+	}
+	for i, id := range ids {
+		if id != int64(i+1) {
+			t.Fatalf("got IDs %v, want them in ID order", ids)
+		}
+	}
+}
+
+func TestParseRejectsCursorOfAnotherSort(t *testing.T) {
+	first, err := Parse(url.Values{ParamSort: {"created_at"}, ParamLimit: {"1"}}, rowSpec)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	page := Select([]row{{ID: 1}, {ID: 2}}, first, rowValue)
+	if page.NextCursor == "" {
+		t.Fatal("got no next cursor")
+	}
+
+	query := url.Values{ParamSort: {"-created_at"}, ParamCursor: {page.NextCursor}, ParamLimit: {"1"}}
+	if _, err := Parse(query, rowSpec); err == nil {
+		t.Error("cursor of an ascending sort accepted for a descending one")
+	}
+}
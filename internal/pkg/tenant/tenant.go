@@ -0,0 +1,210 @@
+// Package tenant resolves the tenant a request is served for and carries it in the request context.
+// Each tenant has its own issuer, signing keys, and storage namespace: a PostgreSQL schema and a
+// Redis key prefix. A tenant is reached at its own host name or under the /t/{tenant} path prefix.
+// Requests matching no tenant are served for the default tenant, whose issuer and base URL are the
+// configured ones and whose storage is not namespaced, so a server without tenants works as before.
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/verigate/verigate-server/internal/pkg/config"
+)
+
+// PathPrefix starts the path of every request to a tenant without a host name of its own
+const PathPrefix = "/t/"
+
+// validID restricts tenant identifiers to what is safe in paths, schema names, and Redis keys
+var validID = regexp.MustCompile(`^[a-z0-9][a-z0-9_]{0,47}$`)
+
+// contextKey is the type of the context key the tenant is stored under
+type contextKey struct{}
+
+// Tenant is an isolated authorization server sharing the process with the other tenants.
+type Tenant struct {
+	ID      string // Identifier, used in the path prefix and the storage namespaces
+	Host    string // Host name the tenant is served at, empty when it is reached through the path prefix
+	BaseURL string // Public base URL of the tenant's endpoints
+	Issuer  string // iss value of the tokens issued under the tenant
+}
+
+// Schema returns the PostgreSQL schema holding the tenant's data.
+func (t *Tenant) Schema() string {
+	return "tenant_" + t.ID
+}
+
+// KeyPrefix returns the prefix of the tenant's Redis keys.
+func (t *Tenant) KeyPrefix() string {
+	return "tenant:" + t.ID + ":"
+}
+
+// Registry holds the configured tenants and resolves requests to them.
+type Registry struct {
+	tenants map[string]*Tenant
+	hosts   map[string]*Tenant
+}
+
+// NewRegistry creates the registry of the tenants with the given identifiers. A tenant with a
+// host name is served at that host, under the scheme of the application base URL; the others are
+// served under the path prefix of the application base URL. A tenant's issuer is its base URL,
+// where its discovery documents are found (OpenID Connect Discovery Section 4).
+func NewRegistry(ids []string, hosts map[string]string) (*Registry, error) {
+	base, err := url.Parse(strings.TrimRight(config.AppConfig.AppBaseURL, "/"))
+	if err != nil || base.Scheme == "" {
+		return nil, fmt.Errorf("invalid application base URL %q", config.AppConfig.AppBaseURL)
+	}
+
+	r := &Registry{tenants: make(map[string]*Tenant), hosts: make(map[string]*Tenant)}
+	for _, id := range ids {
+		if !validID.MatchString(id) {
+			return nil, fmt.Errorf("invalid tenant identifier %q", id)
+		}
+		if r.tenants[id] != nil {
+			return nil, fmt.Errorf("duplicate tenant %q", id)
+		}
+
+		t := &Tenant{ID: id, BaseURL: base.String() + PathPrefix + id}
+		if host := strings.ToLower(hosts[id]); host != "" {
+			if r.hosts[host] != nil {
+				return nil, fmt.Errorf("host %q is assigned to more than one tenant", host)
+			}
+			t.Host = host
+			t.BaseURL = base.Scheme + "://" + host
+			r.hosts[host] = t
+		}
+		t.Issuer = t.BaseURL
+		r.tenants[id] = t
+	}
+
+	for id := range hosts {
+		if r.tenants[id] == nil {
+			return nil, fmt.Errorf("host configured for unknown tenant %q", id)
+		}
+	}
+
+	return r, nil
+}
+
+// Tenants returns the configured tenants ordered by identifier.
+func (r *Registry) Tenants() []*Tenant {
+	tenants := make([]*Tenant, 0, len(r.tenants))
+	for _, t := range r.tenants {
+		tenants = append(tenants, t)
+	}
+	sort.Slice(tenants, func(i, j int) bool { return tenants[i].ID < tenants[j].ID })
+	return tenants
+}
+
+// Handler resolves the tenant of every request before passing it to next. A request to the host
+// of a tenant is served for it. Otherwise a request under the path prefix is served for the
+// tenant it names, with the prefix removed from the path, and fails with 404 Not Found if that
+// tenant does not exist or has a host of its own. Other requests are served for the default tenant.
+func (r *Registry) Handler(next http.Handler) http.Handler {
+	if len(r.tenants) == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		t := r.hosts[hostname(req.Host)]
+		if t == nil && strings.HasPrefix(req.URL.Path, PathPrefix) {
+			id, rest, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, PathPrefix), "/")
+			t = r.tenants[id]
+			if t == nil || t.Host != "" {
+				http.NotFound(w, req)
+				return
+			}
+			req.URL.Path = "/" + rest
+			req.URL.RawPath = ""
+		}
+
+		if t != nil {
+			req = req.WithContext(WithTenant(req.Context(), t))
+		}
+		next.ServeHTTP(w, req)
+	})
+}
+
+// WithTenant returns a copy of ctx serving the tenant.
+func WithTenant(ctx context.Context, t *Tenant) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// FromContext returns the tenant ctx is served for, nil for the default tenant.
+func FromContext(ctx context.Context) *Tenant {
+	t, _ := ctx.Value(contextKey{}).(*Tenant)
+	return t
+}
+
+// Detach returns a background context serving the tenant of ctx, for work that outlives the request.
+func Detach(ctx context.Context) context.Context {
+	if t := FromContext(ctx); t != nil {
+		return WithTenant(context.Background(), t)
+	}
+	return context.Background()
+}
+
+// ID returns the identifier of the tenant ctx is served for, empty for the default tenant.
+func ID(ctx context.Context) string {
+	if t := FromContext(ctx); t != nil {
+		return t.ID
+	}
+	return ""
+}
+
+// Issuer returns the issuer identifier of the tenant ctx is served for.
+func Issuer(ctx context.Context) string {
+	if t := FromContext(ctx); t != nil {
+		return t.Issuer
+	}
+	return config.AppConfig.TokenIssuer
+}
+
+// BaseURL returns the public base URL of the tenant ctx is served for, without a trailing slash.
+func BaseURL(ctx context.Context) string {
+	if t := FromContext(ctx); t != nil {
+		return t.BaseURL
+	}
+	return strings.TrimRight(config.AppConfig.AppBaseURL, "/")
+}
+
+// LoginURL returns the login page of the tenant ctx is served for. Tenants use the login page
+// under their base URL; the default tenant uses the configured one.
+func LoginURL(ctx context.Context) string {
+	if t := FromContext(ctx); t != nil {
+		return t.BaseURL + "/login"
+	}
+	return config.AppConfig.LoginURL
+}
+
+// Path returns the path a client uses to reach path on the server of the tenant ctx is served for,
+// which starts with the path prefix for tenants without a host of their own.
+func Path(ctx context.Context, path string) string {
+	if t := FromContext(ctx); t != nil && t.Host == "" {
+		return PathPrefix + t.ID + path
+	}
+	return path
+}
+
+// Key returns the Redis key of the tenant ctx is served for, namespacing key for every tenant
+// but the default one.
+func Key(ctx context.Context, key string) string {
+	if t := FromContext(ctx); t != nil {
+		return t.KeyPrefix() + key
+	}
+	return key
+}
+
+// hostname returns the lowercased host of a Host header without its port.
+func hostname(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
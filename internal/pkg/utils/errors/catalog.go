@@ -0,0 +1,76 @@
+package errors
+
+import (
+	"embed"
+	"encoding/json"
+	"path"
+	"strings"
+
+	"github.com/verigate/verigate-server/internal/pkg/utils/locale"
+)
+
+// SourceLocale is the language the message constants are written in. Messages are their own
+// text in it, so it needs no catalog file.
+const SourceLocale = "en"
+
+// catalogFiles holds a JSON object per locale, named after its language tag (such as de.json),
+// mapping message constants to their translation. Adding a language only takes adding a file;
+// messages it leaves out fall back to the default locale.
+//
+//go:embed locales/*.json
+var catalogFiles embed.FS
+
+// catalog maps each translated message to its text by locale, including the source locale.
+var catalog = loadCatalog()
+
+// loadCatalog reads the embedded catalog files. It panics on a malformed file, since the
+// files are built into the binary and a broken one must not ship.
+func loadCatalog() map[string]map[string]string {
+	entries, err := catalogFiles.ReadDir("locales")
+	if err != nil {
+		panic("failed to read error message catalog: " + err.Error())
+	}
+
+	result := make(map[string]map[string]string)
+	for _, entry := range entries {
+		data, err := catalogFiles.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			panic("failed to read error message catalog " + entry.Name() + ": " + err.Error())
+		}
+
+		var translations map[string]string
+		if err := json.Unmarshal(data, &translations); err != nil {
+			panic("invalid error message catalog " + entry.Name() + ": " + err.Error())
+		}
+
+		tag := strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))
+		for message, text := range translations {
+			if result[message] == nil {
+				result[message] = map[string]string{SourceLocale: message}
+			}
+			result[message][tag] = text
+		}
+	}
+	return result
+}
+
+// Localize returns the text of a message in the first preferred locale the catalog translates
+// it into, else in defaultLocale, else the message itself. Messages are matched exactly, so
+// messages formatted with values are never translated.
+func Localize(message string, preferred []string, defaultLocale string) string {
+	if text, ok := locale.Lookup(catalog[message], preferred, defaultLocale); ok {
+		return text
+	}
+	return message
+}
+
+// LocalizedDescription returns a description of the error for end users in the first preferred
+// locale available: the localized message, followed by the localized details when they are text.
+// The message itself stays the stable, machine-readable code.
+func (e CustomError) LocalizedDescription(preferred []string, defaultLocale string) string {
+	description := Localize(e.Message, preferred, defaultLocale)
+	if details, ok := e.Details.(string); ok && details != "" {
+		description += ": " + Localize(details, preferred, defaultLocale)
+	}
+	return description
+}
@@ -4,6 +4,7 @@ package errors
 
 import (
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
 	"net/http"
 )
@@ -31,26 +32,66 @@ const (
 	ErrMsgFailedToHashRefreshToken = "failed to hash refresh token"
 
 	// Database-related errors
-	ErrMsgFailedToSaveAccessToken   = "failed to save access token"
-	ErrMsgFailedToSaveRefreshToken  = "failed to save refresh token"
-	ErrMsgFailedToFindAccessToken   = "failed to find access token"
-	ErrMsgFailedToCountAccessTokens = "failed to count access tokens"
-	ErrMsgFailedToGetAccessTokens   = "failed to get access tokens"
-	ErrMsgFailedToCreateUser        = "failed to create user"
-	ErrMsgFailedToUpdateUser        = "failed to update user"
-	ErrMsgFailedToGetUserByID       = "failed to get user by ID"
-	ErrMsgFailedToGetUserByEmail    = "failed to get user by email"
-	ErrMsgFailedToGetUserByUsername = "failed to get user by username"
-	ErrMsgFailedToUpdatePassword    = "failed to update password"
-	ErrMsgFailedToDeleteUser        = "failed to delete user"
-	ErrMsgFailedToGetAffectedRows   = "failed to get affected rows"
+	ErrMsgFailedToSaveAccessToken       = "failed to save access token"
+	ErrMsgFailedToSaveRefreshToken      = "failed to save refresh token"
+	ErrMsgFailedToFindAccessToken       = "failed to find access token"
+	ErrMsgFailedToFindAccessTokenByHash = "failed to find access token by hash"
+	ErrMsgFailedToCountAccessTokens     = "failed to count access tokens"
+	ErrMsgFailedToGetAccessTokens       = "failed to get access tokens"
+	ErrMsgFailedToCreateUser            = "failed to create user"
+	ErrMsgFailedToUpdateUser            = "failed to update user"
+	ErrMsgFailedToGetUserByID           = "failed to get user by ID"
+	ErrMsgFailedToGetUserByEmail        = "failed to get user by email"
+	ErrMsgFailedToGetUserByUsername     = "failed to get user by username"
+	ErrMsgFailedToUpdatePassword        = "failed to update password"
+	ErrMsgFailedToDeleteUser            = "failed to delete user"
+	ErrMsgFailedToGetAffectedRows       = "failed to get affected rows"
 
 	// OAuth-related errors
-	ErrMsgUnsupportedResponseType = "unsupported_response_type"
-	ErrMsgInvalidClient           = "invalid_client"
-	ErrMsgInvalidGrant            = "invalid_grant"
-	ErrMsgAccessDenied            = "access_denied"
-	ErrMsgUserDeniedAccess        = "user denied access"
+	ErrMsgUnsupportedResponseType   = "unsupported_response_type"
+	ErrMsgInvalidClient             = "invalid_client"
+	ErrMsgInvalidGrant              = "invalid_grant"
+	ErrMsgUnauthorizedClient        = "unauthorized_client"
+	ErrMsgAccessDenied              = "access_denied"
+	ErrMsgUserDeniedAccess          = "user denied access"
+	ErrMsgRequiredScopeNotApproved  = "required scope must be approved"
+	ErrMsgLoginRequired             = "login_required"
+	ErrMsgConsentRequired           = "consent_required"
+	ErrMsgUnmetAuthRequirements     = "unmet_authentication_requirements"
+	ErrMsgServerError               = "server_error"
+	ErrMsgTemporarilyUnavailable    = "temporarily_unavailable"
+	ErrMsgInvalidPrompt             = "prompt none cannot be combined with other values"
+	ErrMsgInvalidMaxAge             = "max_age must be a non-negative integer"
+	ErrMsgInvalidClaimsParameter    = "claims must be a JSON object of userinfo and id_token claim requests"
+	ErrMsgFailedToGenerateIDToken   = "failed to generate ID token"
+	ErrMsgInvalidManagedState       = "state was not issued with this authorization code in this session"
+	ErrMsgFailedToIssueManagedState = "failed to issue state"
+	ErrMsgInvalidDPoPProof          = "invalid_dpop_proof"
+	ErrMsgUseDPoPNonce              = "use_dpop_nonce"
+	ErrMsgFailedToIssueDPoPNonce    = "failed to issue DPoP nonce"
+
+	// Step-up authentication errors (RFC 9470)
+	ErrMsgInsufficientUserAuthentication = "insufficient_user_authentication"
+
+	// Token exchange errors (RFC 8693)
+	ErrMsgUnsupportedSubjectTokenType   = "subject_token_type must be an access token or JWT token type"
+	ErrMsgUnsupportedActorTokenType     = "actor_token_type must be an access token or JWT token type"
+	ErrMsgUnsupportedRequestedTokenType = "requested_token_type must be the access token type"
+	ErrMsgActorTokenTypeWithoutToken    = "actor_token_type must not be sent without actor_token"
+	ErrMsgInvalidSubjectToken           = "subject_token is invalid, expired, or revoked"
+	ErrMsgSubjectTokenNotForUser        = "subject_token must have been issued for a user"
+	ErrMsgInvalidActorToken             = "actor_token is invalid, expired, or revoked"
+	ErrMsgActorTokenNotIssuedToClient   = "actor_token must have been issued to the requesting client"
+	ErrMsgExchangeScopeNotAllowed       = "scope exceeds the scope of subject_token or of the client"
+
+	// Grant management errors
+	ErrMsgInvalidGrantID                = "invalid_grant_id"
+	ErrMsgInvalidGrantManagementAction  = "grant_management_action must be create, update, or replace"
+	ErrMsgGrantIDRequired               = "grant_id is required to update or replace a grant"
+	ErrMsgGrantIDNotAllowed             = "grant_id must not be sent to create a grant"
+	ErrMsgGrantManagementActionRequired = "grant_id requires a grant_management_action"
+	ErrMsgGrantNotFound                 = "grant not found"
+	ErrMsgGrantRevoked                  = "grant has been revoked"
 
 	// User-related errors
 	ErrMsgInvalidRequestFormat   = "invalid request format"
@@ -61,6 +102,45 @@ const (
 	ErrMsgUserNotFound           = "user not found"
 	ErrMsgIncorrectPassword      = "incorrect password"
 
+	// Login CAPTCHA errors
+	ErrMsgCaptchaRequired       = "captcha required"
+	ErrMsgInvalidCaptcha        = "invalid or already used captcha"
+	ErrMsgFailedToVerifyCaptcha = "failed to verify captcha"
+
+	// Password policy errors
+	ErrMsgPasswordPolicyViolation = "password does not meet the password policy"
+	ErrMsgPasswordTooShort        = "password must be at least %d characters long"
+	ErrMsgPasswordNeedsUppercase  = "password must contain an uppercase letter"
+	ErrMsgPasswordNeedsLowercase  = "password must contain a lowercase letter"
+	ErrMsgPasswordNeedsDigit      = "password must contain a digit"
+	ErrMsgPasswordNeedsSymbol     = "password must contain a symbol"
+	ErrMsgPasswordBreached        = "password has appeared in a known data breach; choose a different one"
+
+	// Two-factor authentication errors
+	ErrMsgTwoFactorNotConfigured       = "two-factor authentication is not configured on this server"
+	ErrMsgTwoFactorAlreadyEnabled      = "two-factor authentication is already enabled"
+	ErrMsgTwoFactorNotEnrolled         = "two-factor authentication enrollment not started"
+	ErrMsgInvalidTwoFactorCode         = "invalid two-factor code"
+	ErrMsgTwoFactorCodeRequired        = "a two-factor code or recovery code is required"
+	ErrMsgInvalidMFAChallenge          = "invalid or expired MFA challenge"
+	ErrMsgFailedToGenerateMFAChallenge = "failed to generate MFA challenge"
+	ErrMsgFailedToSaveTwoFactor        = "failed to save two-factor authentication"
+	ErrMsgFailedToFindTwoFactor        = "failed to find two-factor authentication"
+	ErrMsgFailedToSaveRecoveryCodes    = "failed to save recovery codes"
+	ErrMsgFailedToUseRecoveryCode      = "failed to use recovery code"
+
+	// WebAuthn errors
+	ErrMsgInvalidWebAuthnSession           = "invalid or expired passkey ceremony"
+	ErrMsgInvalidWebAuthnResponse          = "invalid passkey response"
+	ErrMsgWebAuthnVerificationFailed       = "passkey verification failed"
+	ErrMsgWebAuthnCloneDetected            = "passkey sign counter did not increase; the authenticator may be cloned"
+	ErrMsgWebAuthnCredentialNotFound       = "passkey not found"
+	ErrMsgInvalidWebAuthnCredentialID      = "invalid passkey credential ID"
+	ErrMsgFailedToSaveWebAuthnSession      = "failed to save passkey ceremony"
+	ErrMsgFailedToSaveWebAuthnCredential   = "failed to save passkey"
+	ErrMsgFailedToFindWebAuthnCredential   = "failed to find passkey"
+	ErrMsgFailedToDeleteWebAuthnCredential = "failed to delete passkey"
+
 	// Token-related errors
 	ErrMsgTokenIdRequired               = "token ID is required"
 	ErrMsgFailedToGenerateAccessToken   = "failed to generate access token"
@@ -69,35 +149,205 @@ const (
 	ErrMsgRequestedScopeExceedsOriginal = "requested scope exceeds original scope"
 	ErrMsgTokenNotBelongToClient        = "token does not belong to client"
 	ErrMsgNotAuthorizedToRevokeToken    = "not authorized to revoke this token"
+	ErrMsgRefreshTokenReuseDetected     = "refresh token reuse detected"
+	ErrMsgRefreshTokenBoundElsewhere    = "refresh token is bound to a different network"
+	ErrMsgRefreshTokenLifetimeExceeded  = "refresh token has reached its absolute lifetime"
+	ErrMsgClientCertificateRequired     = "a client certificate is required for certificate-bound access tokens"
+	ErrMsgCertificateBindingMismatch    = "access token is bound to a different client certificate"
+	ErrMsgDPoPBindingMismatch           = "access token is bound to a different DPoP key"
+	ErrMsgRefreshTokenDPoPMismatch      = "refresh token is bound to a different DPoP key"
+	ErrMsgDPoPBoundTokenNotAccepted     = "DPoP-bound access tokens are not accepted as bearer tokens"
+	ErrMsgFailedToLoadSigningKeys       = "failed to load signing keys"
+
+	// Web session errors
+	ErrMsgInvalidSession            = "invalid or expired session"
+	ErrMsgFailedToGenerateSessionID = "failed to generate session ID"
+	ErrMsgFailedToSaveSession       = "failed to save session"
+	ErrMsgFailedToFindSession       = "failed to find session"
+	ErrMsgFailedToDeleteSession     = "failed to delete session"
+	ErrMsgFailedToMarshalSession    = "failed to marshal session"
+	ErrMsgFailedToUnmarshalSession  = "failed to unmarshal session"
+	ErrMsgInvalidCSRFToken          = "missing or invalid CSRF token"
+	ErrMsgTooManySessions           = "maximum number of active sessions reached"
+	ErrMsgSessionNotFound           = "session not found"
+
+	// Federated identity errors
+	ErrMsgFederatedSubjectMissing = "upstream identity attributes produced no subject"
 
 	// Client-related errors
-	ErrMsgClientNotFound              = "client not found"
-	ErrMsgInvalidClientId             = "invalid client ID: must be a positive integer"
-	ErrMsgClientIdAlreadyExists       = "client with this client_id already exists"
-	ErrMsgInvalidClientCredentials    = "invalid client credentials"
-	ErrMsgClientNotActive             = "client is not active"
-	ErrMsgNotAuthorizedForClient      = "not authorized to update this client"
-	ErrMsgNotAuthorizedToDeleteClient = "not authorized to delete this client"
+	ErrMsgClientNotFound                 = "client not found"
+	ErrMsgInvalidClientId                = "invalid client ID: must be a positive integer"
+	ErrMsgClientIdAlreadyExists          = "client with this client_id already exists"
+	ErrMsgInvalidClientCredentials       = "invalid client credentials"
+	ErrMsgClientNotActive                = "client is not active"
+	ErrMsgNotAuthorizedForClient         = "not authorized to update this client"
+	ErrMsgNotAuthorizedToDeleteClient    = "not authorized to delete this client"
+	ErrMsgInvalidTokenEndpointAuthMethod = "token_endpoint_auth_method is not supported for this client type"
+	ErrMsgClientAuthMethodMismatch       = "client authentication method does not match the registered token_endpoint_auth_method"
+	ErrMsgClientKeysRequired             = "jwks or jwks_uri is required for private_key_jwt"
+	ErrMsgInvalidClientJwks              = "invalid client jwks"
+	ErrMsgInvalidClientAssertion         = "invalid client assertion"
+	ErrMsgClientAssertionReplayed        = "client assertion has already been used"
+	ErrMsgClientCertificatesRequired     = "jwks or jwks_uri with the client certificates is required for self_signed_tls_client_auth"
+	ErrMsgTLSClientAuthSubjectDNRequired = "tls_client_auth_subject_dn is required for tls_client_auth"
+	ErrMsgFailedToLoadClientKeys         = "failed to load client keys"
+	ErrMsgInvalidAccessTokenFormat       = "access_token_format must be legacy or jwt"
+	ErrMsgInvalidResourceURI             = "allowed_resources must be absolute URIs without a fragment"
+	ErrMsgInvalidBackchannelLogoutURI    = "backchannel_logout_uri must be an absolute http or https URI without a fragment"
+	ErrMsgInvalidFrontchannelLogoutURI   = "frontchannel_logout_uri must be an absolute http or https URI without a fragment"
+	ErrMsgInvalidPostLogoutRedirectURI   = "post_logout_redirect_uris must be absolute URIs without a fragment"
+	ErrMsgInvalidRequestURIs             = "request_uris must be https URIs"
+	ErrMsgInvalidIDTokenEncryption       = "id_token_encrypted_response_alg and id_token_encrypted_response_enc must be supported, and enc requires alg"
+	ErrMsgUnsupportedIDTokenSigningAlg   = "id_token_signed_response_alg must be RS256, ES256, or HS256"
+	ErrMsgHS256IDTokensNotEnabled        = "HS256 ID token signing is not enabled on this server"
+	ErrMsgHS256RequiresClientSecret      = "id_token_signed_response_alg HS256 requires a confidential client authenticating with a client secret"
+	ErrMsgHS256RequiresNewClient         = "id_token_signed_response_alg HS256 can only be chosen when the client is registered"
+	ErrMsgClientSecretTooWeak            = "client secret is too short to key HS256 ID token signatures"
+	ErrMsgFailedToLoadIDTokenSigningKey  = "failed to load ID token signing key"
+	ErrMsgUnsupportedUserInfoSigningAlg  = "userinfo_signed_response_alg must be RS256 or ES256"
+	ErrMsgInvalidUserInfoEncryption      = "userinfo_encrypted_response_alg and userinfo_encrypted_response_enc must be supported, and enc requires alg"
+	ErrMsgUserInfoEncryptionNeedsSigning = "userinfo_encrypted_response_alg requires userinfo_signed_response_alg"
+	ErrMsgFailedToLoadUserInfoSigningKey = "failed to load UserInfo signing key"
+	ErrMsgInvalidSubjectType             = "subject_type must be public or pairwise"
+	ErrMsgPairwiseSubjectNotConfigured   = "pairwise subject identifiers are not configured on this server"
+	ErrMsgInvalidSectorIdentifierURI     = "sector_identifier_uri must be an https URI serving a JSON array of redirect URIs"
+	ErrMsgSectorIdentifierURIRequired    = "sector_identifier_uri is required for pairwise clients with redirect URIs on several hosts"
+	ErrMsgSectorRedirectURIsMismatch     = "redirect_uris must all be listed at the sector_identifier_uri"
+	ErrMsgInvalidTokenLifetime           = "token lifetimes must be zero or a positive number of seconds"
+	ErrMsgRefreshLifetimeTooShort        = "refresh_token_lifetime must be longer than access_token_lifetime"
+	ErrMsgInvalidClaimTemplate           = "invalid claim template"
+
+	// Dynamic client registration errors (RFC 7591, RFC 7592)
+	ErrMsgInvalidClientMetadata              = "invalid_client_metadata"
+	ErrMsgRedirectURINotAbsolute             = "redirect URI must be an absolute URI without a fragment"
+	ErrMsgRedirectURINotAllowed              = "redirect URI must use https and a non-loopback host for confidential clients"
+	ErrMsgRedirectURIWildcard                = "redirect URI must not contain wildcards; redirect URIs are matched exactly"
+	ErrMsgRedirectURIsRequired               = "redirect_uris is required for the authorization_code grant"
+	ErrMsgUnsupportedClientGrantType         = "grant_types contains an unsupported grant type"
+	ErrMsgUnsupportedClientResponseType      = "response_types contains an unsupported response type"
+	ErrMsgGrantTypeNotAllowedForPublicClient = "client_credentials requires a confidential client"
+	ErrMsgRegistrationClientIDMismatch       = "client_id does not match the registered client"
+	ErrMsgClientTypeChangeNotAllowed         = "token_endpoint_auth_method cannot change the client type"
+	ErrMsgInvalidRegistrationAccessToken     = "invalid registration access token"
+	ErrMsgFailedToGenerateRegistrationToken  = "failed to generate registration access token"
 
 	// OAuth-related additional errors
 	ErrMsgAuthorizationCodeNotFound  = "authorization code not found"
+	ErrMsgAuthorizationCodeReused    = "authorization code has already been used"
 	ErrMsgInvalidRedirectUri         = "invalid_redirect_uri"
 	ErrMsgInvalidCodeChallengeMethod = "invalid_code_challenge_method"
+	ErrMsgCodeChallengeRequired      = "code_challenge required for this client"
 	ErrMsgInvalidScope               = "invalid_scope"
+	ErrMsgInvalidTarget              = "invalid_target"
+	ErrMsgInsufficientScope          = "insufficient_scope"
 	ErrMsgFailedToGenerateAuthCode   = "failed to generate authorization code"
 	ErrMsgFailedToSaveAuthCode       = "failed to save authorization code"
 	ErrMsgUnsupportedGrantType       = "unsupported_grant_type"
 	ErrMsgInvalidRequest             = "invalid_request"
+	ErrMsgInvalidRequestObject       = "invalid_request_object"
+	ErrMsgInvalidRequestURI          = "invalid_request_uri"
+	ErrMsgFailedToSavePushedRequest  = "failed to save pushed authorization request"
+	ErrMsgFailedToFindPushedRequest  = "failed to find pushed authorization request"
 	ErrMsgFailedToGetAuthCode        = "failed to get authorization code"
 	ErrMsgFailedToMarkCodeAsUsed     = "failed to mark code as used"
+	ErrMsgFailedToMarkCodeReplayed   = "failed to mark code as replayed"
 	ErrMsgFailedToDeleteExpiredCodes = "failed to delete expired codes"
 	ErrMsgInvalidBasicAuthFormat     = "invalid basic auth format"
 	ErrMsgMissingClientId            = "missing client_id"
+	ErrMsgAuthorizationPending       = "authorization_pending"
+	ErrMsgSlowDown                   = "slow_down"
+	ErrMsgExpiredToken               = "expired_token"
+	ErrMsgInvalidUserCode            = "invalid or expired user code"
+	ErrMsgFailedToGenerateDeviceCode = "failed to generate device code"
+	ErrMsgFailedToSaveDeviceCode     = "failed to save device code"
+	ErrMsgFailedToFindDeviceCode     = "failed to find device code"
+	ErrMsgFailedToUpdateDeviceCode   = "failed to update device code"
+	ErrMsgFailedToRecordAssertion    = "failed to record client assertion"
+
+	// Back-channel logout errors
+	ErrMsgFailedToSaveRPSession           = "failed to save relying party session"
+	ErrMsgFailedToFindRPSessions          = "failed to find relying party sessions"
+	ErrMsgFailedToDeleteRPSessions        = "failed to delete relying party sessions"
+	ErrMsgFailedToSaveLogoutDelivery      = "failed to save logout delivery"
+	ErrMsgFailedToUpdateLogoutDelivery    = "failed to update logout delivery"
+	ErrMsgFailedToListLogoutDeliveries    = "failed to list logout deliveries"
+	ErrMsgInvalidLogoutDeliveryStatus     = "invalid logout delivery status: must be pending, delivered, or failed"
+	ErrMsgInvalidLogoutDeliveryQuery      = "invalid logout delivery query: user_id and limit must be numbers"
+	ErrMsgInvalidIDTokenHint              = "id_token_hint is not a valid ID token issued by this server"
+	ErrMsgIDTokenHintUserMismatch         = "id_token_hint was issued to a different user than the current session"
+	ErrMsgPostLogoutRedirectURINotAllowed = "post_logout_redirect_uri is not registered for the client identified by id_token_hint"
+
+	// Audit log errors
+	ErrMsgFailedToSaveAuditEvent  = "failed to save audit event"
+	ErrMsgFailedToListAuditEvents = "failed to list audit events"
+	ErrMsgFailedToOpenAuditLog    = "failed to open audit log file"
+	ErrMsgInvalidAuditTimeRange   = "invalid audit query: until must be after since"
+
+	// Webhook errors
+	ErrMsgInvalidWebhookURL                 = "url must be an absolute http or https URL without a fragment"
+	ErrMsgInvalidWebhookEventType           = "event_types contains an unknown event type"
+	ErrMsgInvalidWebhookRequest             = "invalid webhook subscription: url and event_types are required"
+	ErrMsgInvalidWebhookSubscriptionID      = "webhook subscription ID must be a positive integer"
+	ErrMsgInvalidDeadLetterQuery            = "invalid dead letter query: limit must be a number"
+	ErrMsgWebhookSubscriptionNotFound       = "webhook subscription not found"
+	ErrMsgFailedToGenerateWebhookSecret     = "failed to generate webhook secret"
+	ErrMsgFailedToSaveWebhookSubscription   = "failed to save webhook subscription"
+	ErrMsgFailedToFindWebhookSubscription   = "failed to find webhook subscription"
+	ErrMsgFailedToListWebhookSubscriptions  = "failed to list webhook subscriptions"
+	ErrMsgFailedToDeleteWebhookSubscription = "failed to delete webhook subscription"
+	ErrMsgFailedToListWebhookDeadLetters    = "failed to list webhook dead letters"
+
+	// Expired data purge errors
+	ErrMsgPurgeInProgress          = "a purge of expired data is already in progress"
+	ErrMsgFailedToAcquirePurgeLock = "failed to acquire purge lock"
+	ErrMsgFailedToPurgeExpired     = "failed to purge expired data"
 
 	// IP control errors
 	ErrMsgAccessDeniedIp    = "access denied from your IP address"
 	ErrMsgIpNotAuthorized   = "your IP address is not authorized"
 	ErrMsgRateLimitExceeded = "rate limit exceeded"
+	ErrMsgInvalidIPRange    = "invalid IP address or CIDR range"
+
+	ErrMsgRateLimiterUnavailable      = "rate limiter unavailable"
+	ErrMsgServerShuttingDown          = "server is shutting down"
+	ErrMsgServerOverloaded            = "server is overloaded, retry later"
+	ErrMsgInvalidRateLimitSubjectKind = "invalid rate limit subject kind: must be user or ip"
+	ErrMsgFailedToInspectRateLimit    = "failed to inspect rate limit"
+	ErrMsgRateLimitSubjectRequired    = "rate limit subject is required"
+	ErrMsgRateLimitDryRunNeedsAuth    = "rate limit dry runs require a valid bearer access token"
+
+	// Admin errors
+	ErrMsgAdminAccessRequired  = "administrator access required"
+	ErrMsgLockoutEmailRequired = "email query parameter is required"
+	ErrMsgInvalidAdminUserID   = "user ID must be a positive integer"
+
+	// Client administration errors
+	ErrMsgClientAdminScopeRequired = "an access token of an administration client with the admin:clients scope is required"
+	ErrMsgInvalidClientQuery       = "invalid client query"
+	ErrMsgInvalidClientAdminBody   = "invalid client administration request"
+	ErrMsgClientHasNoSecret        = "client does not authenticate with a client secret"
+	ErrMsgInvalidSecretGracePeriod = "grace_period must be between zero and the maximum secret grace period"
+	ErrMsgFailedToGenerateSecret   = "failed to generate client secret"
+	ErrMsgInvalidRateLimitTier     = "rate_limit_tier must be exempt or a rate limit of the form requests/window"
+	ErrMsgRateLimitTierNeedsAuth   = "rate limit tiers can only be assigned to clients that authenticate"
+
+	// Request limit errors
+	ErrMsgRequestBodyTooLarge    = "request body too large"
+	ErrMsgTooManyParameterValues = "request parameter is repeated too often"
+	ErrMsgParameterValueTooLong  = "request parameter value too long"
+
+	// List pagination errors
+	ErrMsgUnknownListParameter = "unknown list parameter: lists can only be filtered by their documented parameters"
+	ErrMsgInvalidListParameter = "invalid list parameter value"
+	ErrMsgInvalidListSort      = "invalid sort: lists can only be sorted by their documented fields"
+	ErrMsgInvalidListLimit     = "invalid limit: must be a positive number"
+	ErrMsgInvalidListCursor    = "invalid cursor: pass next_cursor of the previous page with the same sort"
+
+	// Token debugging errors
+	ErrMsgInvalidTokenDebugBody = "a token to debug is required"
+	ErrMsgMalformedDebugToken   = "token is not a well-formed JWT"
+	ErrMsgDebugTokenSharedKey   = "HS256 tokens are keyed with the client secret and cannot be verified here"
+	ErrMsgTokenDebugForbidden   = "token was not issued to you or to a client you own"
 
 	// Database operation errors
 	ErrMsgFailedToSaveUserConsent              = "failed to save user consent"
@@ -111,6 +361,11 @@ const (
 	ErrMsgErrorIteratingRefreshTokens          = "error iterating refresh tokens"
 	ErrMsgFailedToRevokeRefreshToken           = "failed to revoke refresh token"
 	ErrMsgFailedToRevokeRefreshTokens          = "failed to revoke refresh tokens"
+	ErrMsgFailedToRotateRefreshToken           = "failed to rotate refresh token"
+	ErrMsgFailedToDeleteExpiredTokens          = "failed to delete expired tokens"
+	ErrMsgFailedToRevokeTokenFamily            = "failed to revoke token family"
+	ErrMsgFailedToRevokeAllTokens              = "failed to revoke all tokens"
+	ErrMsgFailedToSaveAuthCodeToken            = "failed to record token issued for authorization code"
 	ErrMsgFailedToFindAuthCode                 = "Failed to find authorization code"
 	ErrMsgFailedToUpdateUserConsent            = "Failed to update user consent"
 	ErrMsgUserConsentNotFoundForUser           = "User consent not found for user ID %d"
@@ -118,6 +373,13 @@ const (
 	ErrMsgUserConsentNotFoundForUserAndClient  = "User consent not found for user ID %d and client ID %s"
 	ErrMsgFailedToDeleteUserConsent            = "Failed to delete user consent"
 	ErrMsgFailedToFindUserConsent              = "Failed to find user consent"
+	ErrMsgFailedToScanUserConsent              = "failed to scan user consent"
+	ErrMsgErrorIteratingUserConsents           = "error iterating user consents"
+	ErrMsgFailedToTouchUserConsent             = "failed to record use of user consent"
+	ErrMsgFailedToSaveGrant                    = "failed to save grant"
+	ErrMsgFailedToFindGrant                    = "failed to find grant"
+	ErrMsgFailedToUpdateGrant                  = "failed to update grant"
+	ErrMsgFailedToDeleteGrant                  = "failed to delete grant"
 	ErrMsgFailedToFindRefreshTokenByHash       = "failed to find refresh token by hash"
 	ErrMsgFailedToCountRefreshTokens           = "failed to count refresh tokens"
 	ErrMsgFailedToGetRefreshTokens             = "failed to get refresh tokens"
@@ -130,10 +392,14 @@ const (
 	ErrMsgFailedToGetClientByClientID      = "Failed to get client by client_id"
 	ErrMsgFailedToCountClients             = "Failed to count clients"
 	ErrMsgFailedToRetrieveClientsByOwnerID = "Failed to retrieve clients by owner ID"
+	ErrMsgFailedToSearchClients            = "Failed to search clients"
+	ErrMsgFailedToUpdateClientSecret       = "Failed to update client secret"
 	ErrMsgFailedToScanClientData           = "Failed to scan client data"
 	ErrMsgErrorIteratingClientResults      = "Error iterating client results"
 	ErrMsgFailedToDeleteClient             = "Failed to delete client"
+	ErrMsgFailedToEncodeClaimTemplates     = "Failed to encode claim templates"
 	ErrMsgFailedToUpdateClientStatus       = "Failed to update client status"
+	ErrMsgFailedToCheckClientOrigin        = "Failed to check client origin"
 	ErrMsgClientWithIDNotFound             = "Client with ID %d not found"
 
 	// User Repository Errors
@@ -148,36 +414,57 @@ const (
 	ErrMsgFailedToFindDefaultScopes         = "Failed to find default scopes"
 	ErrMsgFailedToScanDefaultScopeData      = "Failed to scan default scope data"
 	ErrMsgErrorIteratingDefaultScopeResults = "Error iterating default scope results"
+	ErrMsgFailedToDecodeScopeDescriptions   = "Failed to decode scope descriptions"
+
+	// Scope hierarchy errors
+	ErrMsgScopeHierarchyCycle = "scope hierarchy contains a cycle: %s"
+	ErrMsgUnknownImpliedScope = "scope '%s' implies unregistered scope '%s'"
 
 	// Redis cache errors
 	ErrMsgFailedToMarshalRefreshToken        = "failed to marshal refresh token"
 	ErrMsgFailedToUnmarshalRefreshToken      = "failed to unmarshal refresh token"
 	ErrMsgFailedToMarshalUpdatedRefreshToken = "failed to marshal updated refresh token"
 	ErrMsgFailedToGetRefreshToken            = "failed to get refresh token"
+	ErrMsgFailedToDeleteRefreshToken         = "failed to delete refresh token"
 
 	// Generic errors
 	ErrMsgInternalServerError = "internal_server_error"
 	ErrMsgUnexpectedError     = "an unexpected error occurred"
+	ErrMsgFailedToRenderPage  = "failed to render page"
 )
 
 // CustomError represents a structured error with HTTP status code and optional details.
 // It implements the standard error interface and provides additional context for API responses.
+// The message is one of the ErrMsg constants and doubles as the error's stable machine-readable
+// code; LocalizedDescription turns it into text for end users.
 type CustomError struct {
 	Status  int         `json:"status"`            // HTTP status code
-	Message string      `json:"message"`           // Error message
+	Message string      `json:"message"`           // Error message, the stable error code
 	Details interface{} `json:"details,omitempty"` // Additional error details
+	Cause   error       `json:"-"`                 // Underlying error, logged but never sent to clients
 }
 
 // Error returns a string representation of the error, implementing the error interface.
-// If details are present, they will be included in the string representation.
+// If details or a cause are present, they will be included in the string representation,
+// which is meant for logs rather than responses.
 func (e CustomError) Error() string {
+	text := fmt.Sprintf("status: %d, message: %s", e.Status, e.Message)
 	if e.Details != nil {
 		if details, err := json.Marshal(e.Details); err == nil {
-			return fmt.Sprintf("status: %d, message: %s, details: %s", e.Status, e.Message, string(details))
+			text += ", details: " + string(details)
+		} else {
+			text += ", details: (marshalling failed)" // Indicate marshalling failure
 		}
-		return fmt.Sprintf("status: %d, message: %s, details: (marshalling failed)", e.Status, e.Message) // Indicate marshalling failure
 	}
-	return fmt.Sprintf("status: %d, message: %s", e.Status, e.Message)
+	if e.Cause != nil {
+		text += ", cause: " + e.Cause.Error()
+	}
+	return text
+}
+
+// Code returns the stable machine-readable code of the error, which is its message constant.
+func (e CustomError) Code() string {
+	return e.Message
 }
 
 // WithDetails attaches additional information to the error.
@@ -187,14 +474,37 @@ func (e CustomError) WithDetails(details interface{}) CustomError {
 	return e
 }
 
+// Wrap attaches the underlying error that caused this one. The cause is kept for logs and
+// for errors.Is and errors.As, while responses only show the status and message.
+func (e CustomError) Wrap(cause error) CustomError {
+	e.Cause = cause
+	return e
+}
+
+// Unwrap returns the underlying error, for errors.Is and errors.As.
+func (e CustomError) Unwrap() error {
+	return e.Cause
+}
+
 // Is implements error comparison for the errors.Is function.
-// Two CustomErrors are considered equal if they have the same Status.
+// A CustomError matches a target with the same Status and, unless the target's message is empty,
+// the same message, so errors.Is(err, NotFound("")) matches any 404.
 func (e CustomError) Is(target error) bool {
 	t, ok := target.(CustomError)
 	if !ok {
 		return false
 	}
-	return e.Status == t.Status
+	return e.Status == t.Status && (t.Message == "" || e.Message == t.Message)
+}
+
+// As returns the first CustomError in the chain of err, so errors wrapped with
+// fmt.Errorf("...: %w", err) keep their status and code.
+func As(err error) (CustomError, bool) {
+	var customErr CustomError
+	if !stderrors.As(err, &customErr) {
+		return CustomError{}, false
+	}
+	return customErr, true
 }
 
 // New creates a custom error with the specified HTTP status code and message.
@@ -259,6 +569,15 @@ func UnprocessableEntity(message string) CustomError {
 	}
 }
 
+// RequestEntityTooLarge creates a 413 Request Entity Too Large error with the specified message.
+// Use this when the request body exceeds the size the server accepts.
+func RequestEntityTooLarge(message string) CustomError {
+	return CustomError{
+		Status:  http.StatusRequestEntityTooLarge,
+		Message: message,
+	}
+}
+
 // TooManyRequests creates a 429 Too Many Requests error with the specified message.
 // Use this when the client has sent too many requests in a given amount of time.
 func TooManyRequests(message string) CustomError {
@@ -0,0 +1,93 @@
+package errors
+
+import (
+	"database/sql"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestWrapRoundTrip(t *testing.T) {
+	cause := sql.ErrConnDone
+	wrapped := NotFound(ErrMsgUserNotFound).Wrap(cause)
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"wrapped", wrapped},
+		{"wrapped again with fmt.Errorf", fmt.Errorf("loading user: %w", wrapped)},
+		{"wrapped twice with fmt.Errorf", fmt.Errorf("handler: %w", fmt.Errorf("loading user: %w", wrapped))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			customErr, ok := As(tt.err)
+			if !ok {
+				t.Fatalf("As(%v) found no CustomError", tt.err)
+			}
+			if customErr.Status != http.StatusNotFound || customErr.Code() != ErrMsgUserNotFound {
+				t.Errorf("got status %d and code %q, want %d and %q", customErr.Status, customErr.Code(), http.StatusNotFound, ErrMsgUserNotFound)
+			}
+			if customErr.Unwrap() != cause {
+				t.Errorf("Unwrap() = %v, want the cause", customErr.Unwrap())
+			}
+			if !stderrors.Is(tt.err, cause) {
+				t.Error("errors.Is does not find the cause")
+			}
+			if !stderrors.Is(tt.err, NotFound("")) {
+				t.Error("errors.Is does not match any 404")
+			}
+			if !stderrors.Is(tt.err, NotFound(ErrMsgUserNotFound)) {
+				t.Error("errors.Is does not match the same status and code")
+			}
+			if stderrors.Is(tt.err, NotFound(ErrMsgClientNotFound)) {
+				t.Error("errors.Is matches another code of the same status")
+			}
+			if stderrors.Is(tt.err, BadRequest(ErrMsgUserNotFound)) {
+				t.Error("errors.Is matches the same code with another status")
+			}
+		})
+	}
+}
+
+func TestWrapLeavesOriginalUnchanged(t *testing.T) {
+	original := Internal(ErrMsgFailedToRenderPage)
+	wrapped := original.Wrap(stderrors.New("template failed")).WithDetails("page")
+
+	if original.Cause != nil || original.Details != nil {
+		t.Errorf("wrapping changed the original error to %+v", original)
+	}
+	if wrapped.Code() != original.Code() || wrapped.Status != original.Status {
+		t.Errorf("wrapping changed the code or status to %q, %d", wrapped.Code(), wrapped.Status)
+	}
+}
+
+func TestAsRejectsLookalikeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"nil", nil},
+		{"plain error", stderrors.New("connection refused")},
+		{"plain error spelling a CustomError", stderrors.New("status: 404, message: " + ErrMsgUserNotFound)},
+		{"wrapped plain error", fmt.Errorf("loading user: %w", sql.ErrNoRows)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if customErr, ok := As(tt.err); ok {
+				t.Errorf("As(%v) = %+v, want no CustomError", tt.err, customErr)
+			}
+		})
+	}
+}
+
+func TestErrorIncludesCauseForLogs(t *testing.T) {
+	err := Internal(ErrMsgFailedToRenderPage).Wrap(stderrors.New("template failed"))
+	want := "status: 500, message: " + ErrMsgFailedToRenderPage + ", cause: template failed"
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+}
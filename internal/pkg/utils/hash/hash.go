@@ -1,22 +1,209 @@
 // Package hash provides password hashing and verification functions.
-// It uses bcrypt for secure password management.
+// Passwords are hashed with bcrypt or argon2id as configured; the stored hash records its
+// algorithm and parameters, so hashes made with earlier settings keep verifying.
 package hash
 
 import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/verigate/verigate-server/internal/pkg/config"
+
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// HashPassword generates a secure hash of a password using bcrypt.
-// The hash includes a random salt and uses the default cost factor.
+// Supported password hashing algorithms
+const (
+	AlgorithmBcrypt   = "bcrypt"   // bcrypt with a configurable cost
+	AlgorithmArgon2id = "argon2id" // argon2id with configurable time, memory, and parallelism
+)
+
+// argon2id output sizes
+const (
+	argon2SaltLength = 16 // Length of the random salt in bytes
+	argon2KeyLength  = 32 // Length of the derived key in bytes
+)
+
+// argon2idPrefix starts every argon2id hash in the PHC string format
+const argon2idPrefix = "$argon2id$"
+
+// ErrMismatchedHashAndPassword is returned when a password does not match its hash
+var ErrMismatchedHashAndPassword = errors.New("hash: password does not match hash")
+
+// argon2Params holds the parameters of an argon2id hash
+type argon2Params struct {
+	time    uint32
+	memory  uint32
+	threads uint8
+}
+
+// HashPassword generates a secure hash of a password with the configured algorithm and parameters.
+// The hash includes a random salt along with the algorithm and parameters used,
+// so CompareHashAndPassword can verify it after the configuration changes.
 // Returns the hash as a string and any error that occurred during hashing.
 func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if config.AppConfig.PasswordHashAlgorithm == AlgorithmArgon2id {
+		return hashArgon2id(password, currentArgon2Params())
+	}
+
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), currentBcryptCost())
 	return string(bytes), err
 }
 
 // CompareHashAndPassword verifies if a password matches a hash.
-// It compares the provided password with a previously hashed password.
+// The verifier is picked from the algorithm recorded in the hash.
 // Returns nil if the password matches, otherwise returns an error.
 func CompareHashAndPassword(hash, password string) error {
+	if strings.HasPrefix(hash, argon2idPrefix) {
+		return compareArgon2id(hash, password)
+	}
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 }
+
+// NeedsRehash reports whether a hash was made with an algorithm or parameters other than
+// the configured ones, so the password should be hashed again once it has been verified.
+func NeedsRehash(hash string) bool {
+	if strings.HasPrefix(hash, argon2idPrefix) {
+		if config.AppConfig.PasswordHashAlgorithm != AlgorithmArgon2id {
+			return true
+		}
+		params, _, _, err := decodeArgon2id(hash)
+		return err != nil || params != currentArgon2Params()
+	}
+
+	if config.AppConfig.PasswordHashAlgorithm == AlgorithmArgon2id {
+		return true
+	}
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != currentBcryptCost()
+}
+
+// currentBcryptCost returns the configured bcrypt cost, or the default cost when unset.
+func currentBcryptCost() int {
+	if config.AppConfig.BcryptCost == 0 {
+		return bcrypt.DefaultCost
+	}
+	return config.AppConfig.BcryptCost
+}
+
+// currentArgon2Params returns the configured argon2id parameters.
+func currentArgon2Params() argon2Params {
+	return argon2Params{
+		time:    uint32(config.AppConfig.Argon2Time),
+		memory:  uint32(config.AppConfig.Argon2MemoryKiB),
+		threads: uint8(config.AppConfig.Argon2Threads),
+	}
+}
+
+// hashArgon2id hashes a password with argon2id and a random salt.
+// The result uses the PHC string format: $argon2id$v=19$m=<memory>,t=<time>,p=<threads>$<salt>$<key>.
+func hashArgon2id(password string, params argon2Params) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, argon2KeyLength)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2idPrefix,
+		argon2.Version,
+		params.memory,
+		params.time,
+		params.threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// compareArgon2id verifies a password against an argon2id hash using the hash's own parameters.
+func compareArgon2id(hash, password string) error {
+	params, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return err
+	}
+
+	other := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return ErrMismatchedHashAndPassword
+	}
+	return nil
+}
+
+// decodeArgon2id splits an argon2id hash in the PHC string format into its parameters, salt, and key.
+func decodeArgon2id(hash string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return params, nil, nil, errors.New("hash: malformed argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, errors.New("hash: unsupported argon2id version")
+	}
+
+	var memory, time, threads uint64
+	for _, param := range strings.Split(parts[3], ",") {
+		name, value, _ := strings.Cut(param, "=")
+		n, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return params, nil, nil, errors.New("hash: malformed argon2id parameters")
+		}
+		switch name {
+		case "m":
+			memory = n
+		case "t":
+			time = n
+		case "p":
+			threads = n
+		}
+	}
+	if memory == 0 || time == 0 || threads == 0 || threads > 255 {
+		return params, nil, nil, errors.New("hash: malformed argon2id parameters")
+	}
+	params = argon2Params{time: uint32(time), memory: uint32(memory), threads: uint8(threads)}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, errors.New("hash: malformed argon2id salt")
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, errors.New("hash: malformed argon2id key")
+	}
+
+	return params, salt, key, nil
+}
+
+// HashToken computes a deterministic SHA-256 digest of a token.
+// Unlike HashPassword, the same input always yields the same hash, so the result
+// can be used as a lookup key. This is only safe for high-entropy random tokens,
+// never for user-chosen passwords.
+// Returns the digest as a hex-encoded string.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// PairwiseSubject computes the pairwise subject identifier of a local user ID for a sector
+// (OpenID Connect Core Section 8.1): an HMAC-SHA-256 keyed with the salt over the sector
+// identifier and the user ID. The same inputs always yield the same subject, while subjects
+// for different sectors cannot be linked without the salt.
+// Returns the digest as an unpadded base64url string.
+func PairwiseSubject(sectorIdentifier string, userID uint, salt string) string {
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(sectorIdentifier))
+	mac.Write([]byte{0})
+	mac.Write([]byte(strconv.FormatUint(uint64(userID), 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
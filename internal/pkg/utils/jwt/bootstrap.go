@@ -0,0 +1,175 @@
+package jwt
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/verigate/verigate-server/internal/pkg/config"
+)
+
+// MinRSAKeyBits is the smallest RSA signing key BootstrapKeys generates
+const MinRSAKeyBits = 2048
+
+// ErrKeysExist is returned by BootstrapKeys when the key provider already holds signing keys
+// and replacing them was not forced.
+var ErrKeysExist = stderrors.New("signing keys already exist")
+
+// BootstrapKeys generates the initial signing keys of the default tenant, an RSA key of rsaBits
+// bits for RS256 and a P-256 key for ES256, and persists them with the configured key provider:
+// as PEM files at JWT_PRIVATE_KEY_FILE, JWT_PUBLIC_KEY_FILE, and JWT_EC_PRIVATE_KEY_FILE for the
+// local provider, or as the configured transit keys, which Vault generates itself, for the vault
+// provider. Existing keys are kept and ErrKeysExist returned unless force is set; forced, local
+// key files are replaced and Vault keys rotated, so their earlier versions stay published.
+//
+// The keys are then loaded as the server would load them and the resulting JWKS is returned.
+// Key IDs are the JWK thumbprints of the keys, so the JWKS of the same keys is always the same.
+func BootstrapKeys(ctx context.Context, rsaBits int, force bool) (JWKSet, error) {
+	if rsaBits < MinRSAKeyBits {
+		return JWKSet{}, fmt.Errorf("RSA signing keys must have at least %d bits", MinRSAKeyBits)
+	}
+
+	switch config.AppConfig.JWTKeyProvider {
+	case KeyProviderLocal:
+		if err := bootstrapLocalKeys(rsaBits, force); err != nil {
+			return JWKSet{}, err
+		}
+		if err := initLocalKeys(); err != nil {
+			return JWKSet{}, err
+		}
+	case KeyProviderVault:
+		vault, err := configuredVaultKeyProvider()
+		if err != nil {
+			return JWKSet{}, err
+		}
+		if err := vault.BootstrapKeys(ctx, rsaBits, force); err != nil {
+			return JWKSet{}, err
+		}
+		SetKeyProvider(vault)
+	default:
+		return JWKSet{}, fmt.Errorf("unsupported JWT key provider %q", config.AppConfig.JWTKeyProvider)
+	}
+
+	return PublicJWKS(ctx)
+}
+
+// bootstrapLocalKeys generates the local signing keys and writes them to the configured key
+// files. Keys set inline would take precedence over the files, so they must be unset.
+func bootstrapLocalKeys(rsaBits int, force bool) error {
+	if config.AppConfig.JWTPrivateKey != "" || config.AppConfig.JWTPublicKey != "" || config.AppConfig.JWTECPrivateKey != "" {
+		return fmt.Errorf("JWT_PRIVATE_KEY, JWT_PUBLIC_KEY, and JWT_EC_PRIVATE_KEY must be unset, as they take precedence over the generated key files")
+	}
+	files := []string{config.AppConfig.JWTPrivateKeyFile, config.AppConfig.JWTPublicKeyFile, config.AppConfig.JWTECPrivateKeyFile}
+	for _, file := range files {
+		if file == "" {
+			return fmt.Errorf("JWT_PRIVATE_KEY_FILE, JWT_PUBLIC_KEY_FILE, and JWT_EC_PRIVATE_KEY_FILE must be set to persist the generated keys")
+		}
+		if _, err := os.Stat(file); err == nil && !force {
+			return fmt.Errorf("%w: %s", ErrKeysExist, file)
+		}
+	}
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, rsaBits)
+	if err != nil {
+		return err
+	}
+	ecPrivateKey, err := generateECKey()
+	if err != nil {
+		return err
+	}
+
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		return err
+	}
+	ecKeyDER, err := x509.MarshalECPrivateKey(ecPrivateKey)
+	if err != nil {
+		return err
+	}
+
+	if err := writeKeyFile(config.AppConfig.JWTPrivateKeyFile, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(privateKey), 0600); err != nil {
+		return err
+	}
+	if err := writeKeyFile(config.AppConfig.JWTPublicKeyFile, "PUBLIC KEY", publicKeyDER, 0644); err != nil {
+		return err
+	}
+	return writeKeyFile(config.AppConfig.JWTECPrivateKeyFile, "EC PRIVATE KEY", ecKeyDER, 0600)
+}
+
+// writeKeyFile writes a PEM-encoded key to path with the given permissions, replacing the file
+// only once the key is written in full.
+func writeKeyFile(path, blockType string, der []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create key directory: %w", err)
+	}
+
+	temp := path + ".tmp"
+	if err := os.WriteFile(temp, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), perm); err != nil {
+		return fmt.Errorf("failed to write key file %s: %w", path, err)
+	}
+	if err := os.Rename(temp, path); err != nil {
+		os.Remove(temp)
+		return fmt.Errorf("failed to write key file %s: %w", path, err)
+	}
+	return nil
+}
+
+// vaultRSAKeyTypes are the transit key types of the RSA key sizes Vault generates
+var vaultRSAKeyTypes = map[int]string{2048: "rsa-2048", 3072: "rsa-3072", 4096: "rsa-4096"}
+
+// BootstrapKeys creates the transit keys of the default tenant: an RSA key of rsaBits bits,
+// which must be a size Vault supports, and a P-256 key, which must be configured. Existing keys
+// are kept and ErrKeysExist returned unless force is set, when they are rotated instead, as
+// Vault never overwrites a key.
+func (p *VaultKeyProvider) BootstrapKeys(ctx context.Context, rsaBits int, force bool) error {
+	rsaType, ok := vaultRSAKeyTypes[rsaBits]
+	if !ok {
+		return fmt.Errorf("Vault generates RSA keys of 2048, 3072, or 4096 bits, not %d", rsaBits)
+	}
+	if _, ok := p.keyNames[SigningAlgES256]; !ok {
+		return fmt.Errorf("VAULT_TRANSIT_EC_KEY must be set to bootstrap the ES256 signing key")
+	}
+	keyTypes := map[string]string{SigningAlgRS256: rsaType, SigningAlgES256: "ecdsa-p256"}
+
+	existing := make(map[string]bool, len(keyTypes))
+	for _, alg := range []string{SigningAlgRS256, SigningAlgES256} {
+		name := p.keyNames[alg]
+		err := p.do(ctx, http.MethodGet, "/keys/"+name, nil, nil)
+		var statusErr *vaultStatusError
+		switch {
+		case err == nil:
+			if !force {
+				return fmt.Errorf("%w: Vault transit key %s", ErrKeysExist, name)
+			}
+			existing[alg] = true
+		case stderrors.As(err, &statusErr) && statusErr.status == http.StatusNotFound:
+		default:
+			return err
+		}
+	}
+
+	for _, alg := range []string{SigningAlgRS256, SigningAlgES256} {
+		name := p.keyNames[alg]
+		var err error
+		if existing[alg] {
+			err = p.do(ctx, http.MethodPost, "/keys/"+name+"/rotate", map[string]interface{}{}, nil)
+		} else {
+			err = p.do(ctx, http.MethodPost, "/keys/"+name, map[string]interface{}{"type": keyTypes[alg]}, nil)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	p.mu.Lock()
+	p.cache = make(map[string]*vaultKey)
+	p.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,137 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// DPoP proof constants (RFC 9449 Section 4.2)
+const (
+	// MediaTypeDPoPProof is the "typ" header of DPoP proofs
+	MediaTypeDPoPProof = "dpop+jwt"
+
+	// HeaderJWK is the JOSE header carrying the public key a DPoP proof is signed with
+	HeaderJWK = "jwk"
+)
+
+// dpopProofAlgorithms are the asymmetric JWS algorithms DPoP proofs may be signed with
+var dpopProofAlgorithms = []string{"RS256", "PS256", "ES256", "ES384", "ES512"}
+
+// DPoPProofAlgorithms returns the JWS algorithms accepted for DPoP proofs for discovery metadata.
+func DPoPProofAlgorithms() []string {
+	return append([]string(nil), dpopProofAlgorithms...)
+}
+
+// DPoPProofClaims are the claims of a DPoP proof. The jti and iat claims are required;
+// ath is required when the proof is sent with an access token, and nonce when the server
+// has demanded one.
+type DPoPProofClaims struct {
+	HTM   string `json:"htm"`             // HTTP method of the request the proof is sent with
+	HTU   string `json:"htu"`             // HTTP URI of the request, without query and fragment
+	ATH   string `json:"ath,omitempty"`   // Base64url-encoded SHA-256 hash of the access token sent with the proof
+	Nonce string `json:"nonce,omitempty"` // Nonce the server provided in a DPoP-Nonce header
+	jwt.RegisteredClaims
+}
+
+// ParseDPoPProof parses a DPoP proof and verifies its signature with the public key in its
+// jwk header. The proof must be typed dpop+jwt, signed with an asymmetric algorithm, and carry
+// a public key only. Its time-based claims are not checked, since the freshness its iat must
+// meet is up to the caller. Returns the claims and the JWK thumbprint of the proof's key.
+func ParseDPoPProof(proof string) (*DPoPProofClaims, string, error) {
+	var thumbprint string
+	claims := &DPoPProofClaims{}
+	parser := jwt.NewParser(jwt.WithValidMethods(dpopProofAlgorithms), jwt.WithoutClaimsValidation())
+
+	_, err := parser.ParseWithClaims(proof, claims, func(token *jwt.Token) (interface{}, error) {
+		if typ, _ := token.Header[HeaderType].(string); typ != MediaTypeDPoPProof {
+			return nil, fmt.Errorf("unexpected proof type: %v", token.Header[HeaderType])
+		}
+
+		members, ok := token.Header[HeaderJWK].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("missing jwk header")
+		}
+		if _, ok := members["d"]; ok {
+			return nil, fmt.Errorf("jwk header must not contain a private key")
+		}
+
+		encoded, err := json.Marshal(members)
+		if err != nil {
+			return nil, err
+		}
+		var jwk JWK
+		if err := json.Unmarshal(encoded, &jwk); err != nil {
+			return nil, fmt.Errorf("invalid jwk header: %w", err)
+		}
+
+		key, err := jwk.PublicKey()
+		if err != nil {
+			return nil, err
+		}
+		switch token.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+			if _, ok := key.(*rsa.PublicKey); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+		case *jwt.SigningMethodECDSA:
+			if _, ok := key.(*ecdsa.PublicKey); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+		}
+
+		thumbprint, err = jwk.Thumbprint()
+		if err != nil {
+			return nil, err
+		}
+		return key, nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	if claims.ID == "" || claims.IssuedAt == nil || claims.HTM == "" || claims.HTU == "" {
+		return nil, "", fmt.Errorf("proof must carry jti, iat, htm, and htu claims")
+	}
+
+	return claims, thumbprint, nil
+}
+
+// Thumbprint returns the base64url-encoded SHA-256 JWK thumbprint of the key (RFC 7638),
+// computed over its required members only, the jkt value tokens bound to it are confirmed with.
+// Returns an error for key types other than RSA and EC.
+func (k JWK) Thumbprint() (string, error) {
+	var thumbprintInput []byte
+	switch k.Kty {
+	case JWKKeyTypeRSA:
+		thumbprintInput, _ = json.Marshal(struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{E: k.E, Kty: k.Kty, N: k.N})
+	case JWKKeyTypeEC:
+		thumbprintInput, _ = json.Marshal(struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{Crv: k.Crv, Kty: k.Kty, X: k.X, Y: k.Y})
+	default:
+		return "", fmt.Errorf("unsupported key type: %s", k.Kty)
+	}
+
+	sum := sha256.Sum256(thumbprintInput)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// AccessTokenHash returns the ath value of a DPoP proof sent with the access token:
+// the base64url-encoded SHA-256 hash of its ASCII encoding (RFC 9449 Section 4.2).
+func AccessTokenHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,211 @@
+// Package jwt provides utilities for creating and validating JWT tokens
+// used throughout the application for authentication and authorization.
+package jwt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// JSON Web Encryption algorithms (RFC 7518 Sections 4.3 and 5)
+const (
+	JWEAlgRSAOAEP      = "RSA-OAEP"      // RSAES OAEP with SHA-1 key encryption
+	JWEAlgRSAOAEP256   = "RSA-OAEP-256"  // RSAES OAEP with SHA-256 key encryption
+	JWEEncA128CBCHS256 = "A128CBC-HS256" // AES-128-CBC with HMAC SHA-256 content encryption
+	JWEEncA128GCM      = "A128GCM"       // AES-128-GCM content encryption
+	JWEEncA256GCM      = "A256GCM"       // AES-256-GCM content encryption
+
+	JWKUseEncryption  = "enc" // JWK public key use for encryption
+	HeaderAlgorithm   = "alg" // JOSE header naming the key management algorithm
+	HeaderEncryption  = "enc" // JWE header naming the content encryption algorithm
+	HeaderContentType = "cty" // JOSE header naming the media type of the payload
+	ContentTypeJWT    = "JWT" // Content type of a nested JWT (RFC 7519 Section 5.2)
+)
+
+// jweKeySizes maps each supported content encryption algorithm to its key size in bytes
+var jweKeySizes = map[string]int{
+	JWEEncA128CBCHS256: 32,
+	JWEEncA128GCM:      16,
+	JWEEncA256GCM:      32,
+}
+
+// IsSupportedJWEAlgorithm reports whether alg is a supported key management algorithm.
+func IsSupportedJWEAlgorithm(alg string) bool {
+	return alg == JWEAlgRSAOAEP || alg == JWEAlgRSAOAEP256
+}
+
+// IsSupportedJWEEncryption reports whether enc is a supported content encryption algorithm.
+func IsSupportedJWEEncryption(enc string) bool {
+	_, ok := jweKeySizes[enc]
+	return ok
+}
+
+// SupportedJWEAlgorithms returns the supported key management algorithms for discovery metadata.
+func SupportedJWEAlgorithms() []string {
+	return []string{JWEAlgRSAOAEP, JWEAlgRSAOAEP256}
+}
+
+// SupportedJWEEncryptions returns the supported content encryption algorithms for discovery metadata.
+func SupportedJWEEncryptions() []string {
+	return []string{JWEEncA128CBCHS256, JWEEncA128GCM, JWEEncA256GCM}
+}
+
+// EncryptionKey selects the RSA key in set that a JWE using alg should be encrypted to.
+// Keys meant for signatures or for another algorithm are skipped; the first remaining
+// key that parses is used.
+func EncryptionKey(set JWKSet, alg string) (JWK, error) {
+	for _, jwk := range set.Keys {
+		if jwk.Kty != JWKKeyTypeRSA {
+			continue
+		}
+		if jwk.Use != "" && jwk.Use != JWKUseEncryption {
+			continue
+		}
+		if jwk.Alg != "" && jwk.Alg != alg {
+			continue
+		}
+		if _, err := jwk.PublicKey(); err == nil {
+			return jwk, nil
+		}
+	}
+	return JWK{}, fmt.Errorf("no usable %s encryption key", alg)
+}
+
+// EncryptJWT wraps a signed JWT in a JWE using the compact serialization (RFC 7516 Section 7.1),
+// producing a nested JWT whose "cty" header is "JWT". The content encryption key is random
+// and encrypted to key with alg; the token is encrypted with enc.
+func EncryptJWT(token string, key JWK, alg, enc string) (string, error) {
+	if !IsSupportedJWEAlgorithm(alg) {
+		return "", fmt.Errorf("unsupported JWE algorithm: %s", alg)
+	}
+	keySize, ok := jweKeySizes[enc]
+	if !ok {
+		return "", fmt.Errorf("unsupported JWE encryption: %s", enc)
+	}
+
+	publicKey, err := key.PublicKey()
+	if err != nil {
+		return "", err
+	}
+	rsaKey, ok := publicKey.(*rsa.PublicKey)
+	if !ok {
+		return "", fmt.Errorf("JWE algorithm %s requires an RSA key", alg)
+	}
+
+	header := map[string]string{
+		HeaderAlgorithm:   alg,
+		HeaderEncryption:  enc,
+		HeaderContentType: ContentTypeJWT,
+	}
+	if key.Kid != "" {
+		header[HeaderKeyID] = key.Kid
+	}
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	protected := base64.RawURLEncoding.EncodeToString(headerJSON)
+
+	cek := make([]byte, keySize)
+	if _, err := rand.Read(cek); err != nil {
+		return "", err
+	}
+
+	var oaepHash hash.Hash = sha1.New()
+	if alg == JWEAlgRSAOAEP256 {
+		oaepHash = sha256.New()
+	}
+	encryptedKey, err := rsa.EncryptOAEP(oaepHash, rand.Reader, rsaKey, cek, nil)
+	if err != nil {
+		return "", err
+	}
+
+	// The additional authenticated data is the encoded protected header (RFC 7516 Section 5.1)
+	iv, ciphertext, tag, err := encryptContent(enc, cek, []byte(token), []byte(protected))
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join([]string{
+		protected,
+		base64.RawURLEncoding.EncodeToString(encryptedKey),
+		base64.RawURLEncoding.EncodeToString(iv),
+		base64.RawURLEncoding.EncodeToString(ciphertext),
+		base64.RawURLEncoding.EncodeToString(tag),
+	}, "."), nil
+}
+
+// encryptContent encrypts plaintext with the content encryption key.
+// Returns the initialization vector, ciphertext, and authentication tag.
+func encryptContent(enc string, cek, plaintext, aad []byte) ([]byte, []byte, []byte, error) {
+	if enc == JWEEncA128CBCHS256 {
+		return encryptCBCHMAC(cek, plaintext, aad)
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	iv := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return nil, nil, nil, err
+	}
+
+	sealed := gcm.Seal(nil, iv, plaintext, aad)
+	split := len(sealed) - gcm.Overhead()
+	return iv, sealed[:split], sealed[split:], nil
+}
+
+// encryptCBCHMAC implements AES_128_CBC_HMAC_SHA_256 (RFC 7518 Section 5.2.3).
+// The first half of the key authenticates and the second half encrypts.
+func encryptCBCHMAC(cek, plaintext, aad []byte) ([]byte, []byte, []byte, error) {
+	macKey, encKey := cek[:16], cek[16:]
+
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, nil, nil, err
+	}
+
+	// PKCS#7 padding
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	padded := make([]byte, len(plaintext)+padding)
+	copy(padded, plaintext)
+	for i := len(plaintext); i < len(padded); i++ {
+		padded[i] = byte(padding)
+	}
+
+	ciphertext := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, padded)
+
+	aadBits := make([]byte, 8)
+	binary.BigEndian.PutUint64(aadBits, uint64(len(aad))*8)
+
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(aad)
+	mac.Write(iv)
+	mac.Write(ciphertext)
+	mac.Write(aadBits)
+
+	return iv, ciphertext, mac.Sum(nil)[:16], nil
+}
@@ -0,0 +1,246 @@
+// Package jwt provides utilities for creating and validating JWT tokens
+// used throughout the application for authentication and authorization.
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Remote JWKS retrieval limits
+const (
+	jwksFetchTimeout = 5 * time.Second // Maximum time to fetch a remote JWKS document
+	maxJWKSSize      = 1 << 20         // Maximum accepted size of a JWKS document in bytes
+)
+
+// jwksHTTPClient fetches remote JWKS documents
+var jwksHTTPClient = &http.Client{Timeout: jwksFetchTimeout}
+
+// PublicKey converts the JWK into an RSA or ECDSA public key.
+// Returns an error if the key type or curve is unsupported or a member is malformed.
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case JWKKeyTypeRSA:
+		n, err := decodeJWKInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA modulus: %w", err)
+		}
+		e, err := decodeJWKInt(k.E)
+		if err != nil || !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case JWKKeyTypeEC:
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported EC curve: %s", k.Crv)
+		}
+
+		x, err := decodeJWKInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC x coordinate: %w", err)
+		}
+		y, err := decodeJWKInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC y coordinate: %w", err)
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("EC point is not on curve %s", k.Crv)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type: %s", k.Kty)
+}
+
+// ParseJWKSet parses a JSON Web Key Set document.
+// A single JWK object is also accepted and treated as a set containing only that key.
+func ParseJWKSet(data []byte) (JWKSet, error) {
+	var set JWKSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return JWKSet{}, fmt.Errorf("invalid JWKS: %w", err)
+	}
+	if len(set.Keys) > 0 {
+		return set, nil
+	}
+
+	var key JWK
+	if err := json.Unmarshal(data, &key); err != nil || key.Kty == "" {
+		return JWKSet{}, fmt.Errorf("JWKS contains no keys")
+	}
+	return JWKSet{Keys: []JWK{key}}, nil
+}
+
+// FetchJWKS retrieves and parses the JSON Web Key Set published at uri.
+// The request is bounded by a timeout and the document by a maximum size.
+func FetchJWKS(ctx context.Context, uri string) (JWKSet, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return JWKSet{}, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := jwksHTTPClient.Do(req)
+	if err != nil {
+		return JWKSet{}, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return JWKSet{}, fmt.Errorf("failed to fetch JWKS: unexpected status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize+1))
+	if err != nil {
+		return JWKSet{}, fmt.Errorf("failed to read JWKS: %w", err)
+	}
+	if len(data) > maxJWKSSize {
+		return JWKSet{}, fmt.Errorf("JWKS document too large")
+	}
+
+	return ParseJWKSet(data)
+}
+
+// JWKSCache caches remote JWKS documents by URI for a fixed time to live,
+// so that keys published by clients are not fetched on every use.
+// Failed fetches are not cached. It is safe for concurrent use.
+type JWKSCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]jwksCacheEntry
+}
+
+// jwksCacheEntry is a fetched JWKS and the time it stops being used.
+type jwksCacheEntry struct {
+	set       JWKSet
+	expiresAt time.Time
+}
+
+// NewJWKSCache creates a cache that keeps each fetched JWKS for ttl.
+func NewJWKSCache(ttl time.Duration) *JWKSCache {
+	return &JWKSCache{
+		ttl:     ttl,
+		entries: make(map[string]jwksCacheEntry),
+	}
+}
+
+// Fetch returns the JWKS published at uri, fetching it only if no unexpired copy is cached.
+func (c *JWKSCache) Fetch(ctx context.Context, uri string) (JWKSet, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.entries[uri]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		return entry.set, nil
+	}
+
+	set, err := FetchJWKS(ctx, uri)
+	if err != nil {
+		return JWKSet{}, err
+	}
+
+	c.mu.Lock()
+	c.entries[uri] = jwksCacheEntry{set: set, expiresAt: now.Add(c.ttl)}
+	c.mu.Unlock()
+
+	return set, nil
+}
+
+// ParseWithJWKS parses and verifies a token signed by one of the keys in set.
+// The key named by the "kid" header is used when present; otherwise every signing
+// key in the set is tried. The token's algorithm must match the key type, so an
+// RSA key never verifies an HMAC or ECDSA signature. The time-based claims of a verified
+// token are checked with the tolerated clock skew.
+// Returns the parsed token or the last verification error.
+func ParseWithJWKS(tokenString string, set JWKSet, claims jwt.Claims) (*jwt.Token, error) {
+	var kid string
+	if unverified, _, err := new(jwt.Parser).ParseUnverified(tokenString, jwt.MapClaims{}); err == nil {
+		kid, _ = unverified.Header[HeaderKeyID].(string)
+	}
+
+	var candidates []crypto.PublicKey
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != JWKUseSignature {
+			continue
+		}
+		if kid != "" && jwk.Kid != "" && jwk.Kid != kid {
+			continue
+		}
+		if key, err := jwk.PublicKey(); err == nil {
+			candidates = append(candidates, key)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no usable verification key")
+	}
+
+	var lastErr error
+	for _, candidate := range candidates {
+		key := candidate
+		token, err := timelessParser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+			switch token.Method.(type) {
+			case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+				if _, ok := key.(*rsa.PublicKey); ok {
+					return key, nil
+				}
+			case *jwt.SigningMethodECDSA:
+				if _, ok := key.(*ecdsa.PublicKey); ok {
+					return key, nil
+				}
+			}
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		})
+		if err == nil {
+			if err := validateTimeClaims(claims); err != nil {
+				return nil, err
+			}
+			return token, nil
+		}
+
+		lastErr = err
+
+		// A signature mismatch or a key of the wrong type warrants trying the next key
+		var validationErr *jwt.ValidationError
+		if !stderrors.As(err, &validationErr) ||
+			validationErr.Errors&(jwt.ValidationErrorSignatureInvalid|jwt.ValidationErrorUnverifiable) == 0 {
+			break
+		}
+	}
+
+	return nil, lastErr
+}
+
+// decodeJWKInt decodes a base64url-encoded big-endian unsigned integer JWK member.
+func decodeJWKInt(value string) (*big.Int, error) {
+	if value == "" {
+		return nil, fmt.Errorf("missing value")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
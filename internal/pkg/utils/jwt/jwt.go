@@ -3,79 +3,346 @@
 package jwt
 
 import (
-	"crypto/rsa"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	stderrors "errors"
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/verigate/verigate-server/internal/pkg/config"
+	"github.com/verigate/verigate-server/internal/pkg/tenant"
 	"github.com/verigate/verigate-server/internal/pkg/utils/errors"
 )
 
 // Token type constants
 const (
-	TokenTypeAccess  = "access"       // Access tokens used for API authorization
-	TokenTypeRefresh = "refresh"      // Refresh tokens used to obtain new access tokens
-	TokenIssuer      = "oauth-server" // Issuer value for all JWT tokens
+	TokenTypeAccess  = "access"  // Access tokens used for API authorization
+	TokenTypeRefresh = "refresh" // Refresh tokens used to obtain new access tokens
+	TokenTypeMFA     = "mfa"     // Challenges proving the password step of a two-factor login
 
 	// JWT claim key constants
-	ClaimKeyJTI    = "jti"     // JWT ID claim
-	ClaimKeySub    = "sub"     // Subject claim (user ID)
-	ClaimKeyAud    = "aud"     // Audience claim (client ID)
-	ClaimKeyScope  = "scope"   // Scope claim
-	ClaimKeyIAT    = "iat"     // Issued At claim
-	ClaimKeyEXP    = "exp"     // Expiration claim
-	ClaimKeyISS    = "iss"     // Issuer claim
-	ClaimKeyType   = "type"    // Token type claim
-	ClaimKeyUserID = "user_id" // Custom user ID claim
+	ClaimKeyJTI       = "jti"       // JWT ID claim
+	ClaimKeySub       = "sub"       // Subject claim (user ID)
+	ClaimKeyAud       = "aud"       // Audience claim (client ID or resource server)
+	ClaimKeyScope     = "scope"     // Scope claim
+	ClaimKeyIAT       = "iat"       // Issued At claim
+	ClaimKeyEXP       = "exp"       // Expiration claim
+	ClaimKeyISS       = "iss"       // Issuer claim
+	ClaimKeyType      = "type"      // Token type claim
+	ClaimKeyUserID    = "user_id"   // Custom user ID claim
+	ClaimKeyAuthTime  = "auth_time" // Time the user authenticated (OpenID Connect Core Section 2)
+	ClaimKeyClientID  = "client_id" // Client the token was issued to (RFC 9068 Section 2.2)
+	ClaimKeySessionID = "sid"       // Session the token belongs to (OpenID Connect Back-Channel Logout Section 2.1)
+	ClaimKeyEvents    = "events"    // Security events the token reports (Back-Channel Logout Section 2.4)
+	ClaimKeyNonce     = "nonce"     // Value from the authorization request binding an ID token to it (OpenID Connect Core Section 2)
+	ClaimKeyACR       = "acr"       // Authentication context class the authentication met (OpenID Connect Core Section 2)
+	ClaimKeyAMR       = "amr"       // Authentication methods used (OpenID Connect Core Section 2, RFC 8176)
+	ClaimKeyCnf       = "cnf"       // Confirmation of the key the token is bound to (RFC 7800, RFC 8705 Section 3)
+	ClaimKeyAtHash    = "at_hash"   // Hash of the access token issued with an ID token (OpenID Connect Core Section 3.3.2.11)
+	ClaimKeyCHash     = "c_hash"    // Hash of the authorization code issued with an ID token (OpenID Connect Core Section 3.3.2.11)
+	ClaimKeyAct       = "act"       // Party acting for the subject of a delegated token (RFC 8693 Section 4.1)
+
+	// ConfirmationX5tS256 is the cnf member holding the thumbprint of the certificate a token is bound to
+	ConfirmationX5tS256 = "x5t#S256"
+
+	// ConfirmationJkt is the cnf member holding the JWK thumbprint of the DPoP key a token is bound to
+	ConfirmationJkt = "jkt"
+
+	// HeaderType is the JOSE header naming the token's media type
+	HeaderType = "typ"
+
+	// MediaTypeAccessToken is the "typ" header of JWT access tokens (RFC 9068 Section 2.1)
+	MediaTypeAccessToken = "at+jwt"
 )
 
+// Issuer returns the issuer identifier of the tenant ctx is served for, the iss value of every
+// token it issues and the issuer advertised in its authorization server metadata (RFC 8414 Section 2).
+func Issuer(ctx context.Context) string {
+	return tenant.Issuer(ctx)
+}
+
 // Claims represents the custom claims structure for JWT tokens.
 // It extends the standard JWT RegisteredClaims with application-specific fields.
 type Claims struct {
-	UserID               uint   `json:"user_id"`        // ID of the authenticated user
-	TokenType            string `json:"type,omitempty"` // Type of token (access or refresh)
-	jwt.RegisteredClaims        // Standard JWT claims (iss, exp, etc.)
+	UserID               uint             `json:"user_id"`             // ID of the authenticated user
+	TokenType            string           `json:"type,omitempty"`      // Type of token (access or refresh)
+	Confirmation         *Confirmation    `json:"cnf,omitempty"`       // Certificate or DPoP key the token is bound to, nil when unbound
+	AuthTime             *jwt.NumericDate `json:"auth_time,omitempty"` // When the user authenticated, nil when the token does not say
+	ACR                  string           `json:"acr,omitempty"`       // Authentication context class the user authentication met
+	jwt.RegisteredClaims                  // Standard JWT claims (iss, exp, etc.)
 }
 
-var (
-	privateKey *rsa.PrivateKey // RSA private key for token signing
-	publicKey  *rsa.PublicKey  // RSA public key for token validation
-)
+// Confirmation is the cnf claim of a token bound to a client certificate (RFC 8705 Section 3.1)
+// or to a DPoP key (RFC 9449 Section 6).
+type Confirmation struct {
+	X5tS256 string `json:"x5t#S256,omitempty"` // Base64url-encoded SHA-256 thumbprint of the certificate's DER encoding
+	Jkt     string `json:"jkt,omitempty"`      // Base64url-encoded SHA-256 JWK thumbprint of the DPoP key
+}
+
+// ConfirmedThumbprint returns the certificate thumbprint in the cnf claim of claims,
+// empty for a token that is not bound to a certificate.
+func ConfirmedThumbprint(claims jwt.MapClaims) string {
+	cnf, _ := claims[ClaimKeyCnf].(map[string]interface{})
+	thumbprint, _ := cnf[ConfirmationX5tS256].(string)
+	return thumbprint
+}
+
+// ConfirmedKeyThumbprint returns the DPoP key thumbprint in the cnf claim of claims,
+// empty for a token that is not bound to a DPoP key.
+func ConfirmedKeyThumbprint(claims jwt.MapClaims) string {
+	cnf, _ := claims[ClaimKeyCnf].(map[string]interface{})
+	thumbprint, _ := cnf[ConfirmationJkt].(string)
+	return thumbprint
+}
 
-// InitKeys initializes the JWT package by loading the RSA keys from configuration.
-// Returns an error if the keys cannot be parsed or are not provided.
+// InitKeys initializes the JWT package from configuration: the tolerated clock skew and the key
+// provider holding the signing keys. With the local provider, the configured RSA key pair becomes
+// the initial signing key of the key ring, along with the configured EC key signing ES256 tokens
+// or, without one, a generated EC key. With the Vault provider, the transit keys are read once
+// so that a misconfiguration is reported at startup.
+// Returns an error if the keys cannot be loaded, parsed, or read, are not provided, or do not
+// match, or if the clock skew is not a valid duration.
 func InitKeys() error {
+	skew, err := time.ParseDuration(config.AppConfig.JWTClockSkew)
+	if err != nil || skew < 0 {
+		return fmt.Errorf("invalid JWT clock skew %q", config.AppConfig.JWTClockSkew)
+	}
+	clockSkew = skew
+
+	switch config.AppConfig.JWTKeyProvider {
+	case KeyProviderLocal:
+		return initLocalKeys()
+	case KeyProviderVault:
+		return initVaultKeys()
+	}
+	return fmt.Errorf("unsupported JWT key provider %q", config.AppConfig.JWTKeyProvider)
+}
+
+// initLocalKeys loads the configured keys, given inline or as files, into the default tenant's key ring.
+func initLocalKeys() error {
+	privateKeyPEM, err := keyPEM("JWT_PRIVATE_KEY", config.AppConfig.JWTPrivateKey, config.AppConfig.JWTPrivateKeyFile)
+	if err != nil {
+		return err
+	}
+	publicKeyPEM, err := keyPEM("JWT_PUBLIC_KEY", config.AppConfig.JWTPublicKey, config.AppConfig.JWTPublicKeyFile)
+	if err != nil {
+		return err
+	}
+
 	// Validate that keys are provided
-	if config.AppConfig.JWTPrivateKey == "" {
+	if privateKeyPEM == "" {
 		return fmt.Errorf("JWT_PRIVATE_KEY environment variable is not set")
 	}
 
-	if config.AppConfig.JWTPublicKey == "" {
+	if publicKeyPEM == "" {
 		return fmt.Errorf("JWT_PUBLIC_KEY environment variable is not set")
 	}
 
 	// Parse the private key
-	pk, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(config.AppConfig.JWTPrivateKey))
+	pk, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(privateKeyPEM))
 	if err != nil {
 		return fmt.Errorf("failed to parse private key: %w", err)
 	}
-	privateKey = pk
 
 	// Parse the public key
-	pub, err := jwt.ParseRSAPublicKeyFromPEM([]byte(config.AppConfig.JWTPublicKey))
+	pub, err := jwt.ParseRSAPublicKeyFromPEM([]byte(publicKeyPEM))
 	if err != nil {
 		return fmt.Errorf("failed to parse public key: %w", err)
 	}
-	publicKey = pub
 
+	// The published key is derived from the private key, so both must belong together
+	if pub.N.Cmp(pk.PublicKey.N) != 0 || pub.E != pk.PublicKey.E {
+		return fmt.Errorf("JWT public key does not match private key")
+	}
+
+	ecKeyPEM, err := keyPEM("JWT_EC_PRIVATE_KEY", config.AppConfig.JWTECPrivateKey, config.AppConfig.JWTECPrivateKeyFile)
+	if err != nil {
+		return err
+	}
+	ecKey, err := loadECKey(ecKeyPEM)
+	if err != nil {
+		return err
+	}
+
+	keys.setCurrent(pk, ecKey)
 	return nil
 }
 
+// keyPEM returns the PEM-encoded key set in the environment variable name, or read from file
+// when the variable is empty. Returns an empty key when neither is set.
+func keyPEM(name, value, file string) (string, error) {
+	if value != "" || file == "" {
+		return value, nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s_FILE: %w", name, err)
+	}
+	return string(data), nil
+}
+
+// initVaultKeys makes the configured Vault transit keys the key provider and reads the keys of
+// the default tenant.
+func initVaultKeys() error {
+	vault, err := configuredVaultKeyProvider()
+	if err != nil {
+		return err
+	}
+	if _, err := vault.VerificationKeys(context.Background()); err != nil {
+		return fmt.Errorf("failed to read Vault transit keys: %w", err)
+	}
+
+	SetKeyProvider(vault)
+	return nil
+}
+
+// configuredVaultKeyProvider creates the provider of the configured Vault transit keys.
+func configuredVaultKeyProvider() (*VaultKeyProvider, error) {
+	if config.AppConfig.VaultAddr == "" || config.AppConfig.VaultToken == "" || config.AppConfig.VaultTransitRSAKey == "" {
+		return nil, fmt.Errorf("VAULT_ADDR, VAULT_TOKEN, and VAULT_TRANSIT_RSA_KEY must be set for the vault key provider")
+	}
+	cacheTTL, err := time.ParseDuration(config.AppConfig.VaultKeyCacheTTL)
+	if err != nil || cacheTTL <= 0 {
+		return nil, fmt.Errorf("invalid Vault key cache TTL %q", config.AppConfig.VaultKeyCacheTTL)
+	}
+	timeout, err := time.ParseDuration(config.AppConfig.VaultTimeout)
+	if err != nil || timeout <= 0 {
+		return nil, fmt.Errorf("invalid Vault timeout %q", config.AppConfig.VaultTimeout)
+	}
+
+	return NewVaultKeyProvider(
+		config.AppConfig.VaultAddr,
+		config.AppConfig.VaultToken,
+		config.AppConfig.VaultTransitMount,
+		config.AppConfig.VaultTransitRSAKey,
+		config.AppConfig.VaultTransitECKey,
+		cacheTTL,
+		timeout,
+	), nil
+}
+
+// loadECKey parses a PEM-encoded P-256 private key, or generates one when keyPEM is empty.
+func loadECKey(keyPEM string) (*ecdsa.PrivateKey, error) {
+	if keyPEM == "" {
+		return generateECKey()
+	}
+
+	ecKey, err := jwt.ParseECPrivateKeyFromPEM([]byte(keyPEM))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse EC private key: %w", err)
+	}
+	if ecKey.Curve != elliptic.P256() {
+		return nil, fmt.Errorf("EC private key must be on the P-256 curve")
+	}
+	return ecKey, nil
+}
+
+// InitTenantKeys loads the RSA private key of a tenant, which becomes the initial signing key of
+// the tenant's own key ring along with a generated EC key. Tenants do not share keys, so the
+// tokens of one tenant cannot be verified by another. It must be called for every tenant before
+// requests are served.
+func InitTenantKeys(tenantID string, privateKeyPEM []byte) error {
+	pk, err := jwt.ParseRSAPrivateKeyFromPEM(privateKeyPEM)
+	if err != nil {
+		return fmt.Errorf("failed to parse private key of tenant %s: %w", tenantID, err)
+	}
+	ecKey, err := generateECKey()
+	if err != nil {
+		return fmt.Errorf("failed to generate EC key of tenant %s: %w", tenantID, err)
+	}
+
+	ring := &keyRing{}
+	ring.setCurrent(pk, ecKey)
+	tenantKeys[tenantID] = ring
+	return nil
+}
+
+// SignToken signs the claims with the current signing key of the tenant ctx is served for using RS256.
+// The key ID is recorded in the "kid" header so verifiers can select the right key.
+// Returns the signed token string or an error if keys are not initialized or signing fails.
+func SignToken(ctx context.Context, claims jwt.Claims) (string, error) {
+	return SignTokenWithType(ctx, claims, "JWT")
+}
+
+// SignTokenWithType signs the claims like SignToken and sets the "typ" header to typ,
+// such as MediaTypeAccessToken for RFC 9068 access tokens.
+func SignTokenWithType(ctx context.Context, claims jwt.Claims, typ string) (string, error) {
+	return DefaultSigner().Sign(ctx, claims, typ)
+}
+
+// ParseToken parses and verifies a token against the published verification keys of the tenant
+// ctx is served for, so a token issued under one tenant never verifies under another.
+// The key named by the "kid" header is used when it is known; tokens without a
+// recognized kid are checked against every published key. The time-based claims
+// are then checked with the tolerated clock skew.
+// Returns the parsed token or the last verification error.
+func ParseToken(ctx context.Context, tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	token, err := ParseTokenIgnoringTime(ctx, tokenString, claims)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateTimeClaims(claims); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// ParseTokenIgnoringTime parses and verifies a token like ParseToken without checking
+// its time-based claims, for tokens that are still meaningful once expired.
+// Only RS256 tokens verify; Signer.ParseIgnoringTime verifies tokens of the other algorithms.
+func ParseTokenIgnoringTime(ctx context.Context, tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	return parseWithRing(ctx, tokenString, claims, jwt.SigningMethodRS256)
+}
+
+// parseWithRing parses and verifies a token signed with method by one of the published keys
+// of the tenant ctx is served for, without checking its time-based claims.
+func parseWithRing(ctx context.Context, tokenString string, claims jwt.Claims, method jwt.SigningMethod) (*jwt.Token, error) {
+	var kid string
+	if unverified, _, err := new(jwt.Parser).ParseUnverified(tokenString, jwt.MapClaims{}); err == nil {
+		kid, _ = unverified.Header[HeaderKeyID].(string)
+	}
+
+	candidates, err := verificationKeys(ctx, kid, method.Alg())
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("JWT public key not initialized")
+	}
+
+	var lastErr error
+	for _, publicKey := range candidates {
+		key := publicKey
+		token, err := timelessParser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+			if token.Method.Alg() != method.Alg() {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+			return key, nil
+		})
+		if err == nil {
+			return token, nil
+		}
+
+		lastErr = err
+
+		// Only a signature mismatch warrants trying the next key
+		var validationErr *jwt.ValidationError
+		if !stderrors.As(err, &validationErr) || validationErr.Errors&jwt.ValidationErrorSignatureInvalid == 0 {
+			break
+		}
+	}
+
+	return nil, lastErr
+}
+
 // GenerateToken creates a new JWT token for the specified user.
 // It sets standard claims including expiration time based on configuration.
 // Returns the signed token string or an error if signing fails.
-func GenerateToken(userID uint) (string, error) {
+func GenerateToken(ctx context.Context, userID uint) (string, error) {
 	expiry, err := time.ParseDuration(config.AppConfig.JWTAccessExpiry)
 	if err != nil {
 		return "", err
@@ -86,23 +353,19 @@ func GenerateToken(userID uint) (string, error) {
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiry)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Issuer:    TokenIssuer,
+			Issuer:    Issuer(ctx),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-	return token.SignedString(privateKey)
+	return SignToken(ctx, claims)
 }
 
 // GenerateCustomToken creates a JWT token with custom parameters.
 // It allows specifying the issuer, token type, and expiration duration.
+// A non-zero authTime records when the user authenticated and a non-empty sessionID
+// identifies the session; both stay the same across token refreshes within one session.
 // Returns the signed token string or an error if signing fails.
-func GenerateCustomToken(userID uint, issuer string, tokenType string, tokenID string, expiry time.Duration) (string, error) {
-	// Verify that the private key is available
-	if privateKey == nil {
-		return "", fmt.Errorf("JWT private key not initialized")
-	}
-
+func GenerateCustomToken(ctx context.Context, userID uint, issuer string, tokenType string, tokenID string, expiry time.Duration, authTime time.Time, sessionID string) (string, error) {
 	now := time.Now()
 
 	claims := jwt.MapClaims{
@@ -114,21 +377,21 @@ func GenerateCustomToken(userID uint, issuer string, tokenType string, tokenID s
 		ClaimKeyType:   tokenType,
 		ClaimKeyUserID: userID,
 	}
+	if !authTime.IsZero() {
+		claims[ClaimKeyAuthTime] = authTime.Unix()
+	}
+	if sessionID != "" {
+		claims[ClaimKeySessionID] = sessionID
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-	return token.SignedString(privateKey)
+	return SignToken(ctx, claims)
 }
 
 // ValidateToken validates a JWT token and returns the claims if valid.
 // This function verifies the token signature, expiration, and other standard validations.
 // Returns the parsed claims or an error if validation fails.
-func ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
-			return nil, jwt.ErrSignatureInvalid
-		}
-		return publicKey, nil
-	})
+func ValidateToken(ctx context.Context, tokenString string) (*Claims, error) {
+	token, err := ParseToken(ctx, tokenString, &Claims{})
 
 	if err != nil {
 		return nil, err
@@ -145,13 +408,8 @@ func ValidateToken(tokenString string) (*Claims, error) {
 // This function is distinct from ValidateToken which is used for OAuth.
 // It additionally verifies the token issuer matches the expected value.
 // Returns the parsed claims or an error if validation fails.
-func ValidateCustomToken(tokenString string, issuer string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
-			return nil, jwt.ErrSignatureInvalid
-		}
-		return publicKey, nil
-	})
+func ValidateCustomToken(ctx context.Context, tokenString string, issuer string) (*Claims, error) {
+	token, err := ParseToken(ctx, tokenString, &Claims{})
 
 	if err != nil {
 		return nil, err
@@ -172,60 +430,93 @@ func ValidateCustomToken(tokenString string, issuer string) (*Claims, error) {
 // It checks the token's signature, expiration, type, and issuer.
 // This function is a more comprehensive validation suitable for access tokens.
 // Returns the user ID from the token or a detailed error if validation fails.
-func ValidateAccessTokenWithClaims(tokenString string, expectedIssuer string) (uint, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Validate the signing method
-		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return publicKey, nil
-	})
+func ValidateAccessTokenWithClaims(ctx context.Context, tokenString string, expectedIssuer string) (uint, error) {
+	userID, _, err := ValidateAccessTokenWithAuthTime(ctx, tokenString, expectedIssuer)
+	return userID, err
+}
 
+// SessionClaims identifies the web session an access token belongs to.
+type SessionClaims struct {
+	UserID    uint      // User the session belongs to
+	AuthTime  time.Time // When the user authenticated in the session
+	SessionID string    // Session identifier, empty for tokens issued before sessions had one
+}
+
+// ValidateAccessTokenWithAuthTime validates an access token like ValidateAccessTokenWithClaims
+// and also returns when the user authenticated.
+// Tokens issued without an auth_time claim report their issue time instead.
+func ValidateAccessTokenWithAuthTime(ctx context.Context, tokenString string, expectedIssuer string) (uint, time.Time, error) {
+	session, err := ValidateSessionToken(ctx, tokenString, expectedIssuer)
 	if err != nil {
-		return 0, errors.Unauthorized(errors.ErrMsgInvalidToken + ": " + err.Error())
+		return 0, time.Time{}, err
+	}
+	return session.UserID, session.AuthTime, nil
+}
+
+// ValidateSessionToken validates an access token like ValidateAccessTokenWithClaims
+// and returns the session it belongs to.
+// Tokens issued without an auth_time claim report their issue time instead.
+func ValidateSessionToken(ctx context.Context, tokenString string, expectedIssuer string) (*SessionClaims, error) {
+	return ValidateTypedToken(ctx, tokenString, expectedIssuer, TokenTypeAccess)
+}
+
+// ValidateTypedToken validates a token issued by GenerateCustomToken with the expected
+// issuer and token type, and returns the session it belongs to.
+func ValidateTypedToken(ctx context.Context, tokenString string, expectedIssuer string, expectedType string) (*SessionClaims, error) {
+	token, err := ParseToken(ctx, tokenString, jwt.MapClaims{})
+
+	if err != nil {
+		return nil, errors.Unauthorized(errors.ErrMsgInvalidToken + ": " + err.Error())
 	}
 
 	if !token.Valid {
-		return 0, errors.Unauthorized(errors.ErrMsgInvalidToken)
+		return nil, errors.Unauthorized(errors.ErrMsgInvalidToken)
 	}
 
 	// Extract claims
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		return 0, errors.Unauthorized(errors.ErrMsgInvalidTokenClaims)
+		return nil, errors.Unauthorized(errors.ErrMsgInvalidTokenClaims)
 	}
 
 	// Check token type
 	tokenType, ok := claims[ClaimKeyType].(string)
-	if !ok || tokenType != TokenTypeAccess {
-		return 0, errors.Unauthorized(errors.ErrMsgInvalidTokenType)
+	if !ok || tokenType != expectedType {
+		return nil, errors.Unauthorized(errors.ErrMsgInvalidTokenType)
 	}
 
 	// Check issuer
 	issuer, ok := claims[ClaimKeyISS].(string)
 	if !ok || issuer != expectedIssuer {
-		return 0, errors.Unauthorized(errors.ErrMsgInvalidTokenIssuer)
+		return nil, errors.Unauthorized(errors.ErrMsgInvalidTokenIssuer)
 	}
 
 	// Extract user ID
 	userIDFloat, ok := claims[ClaimKeyUserID].(float64)
 	if !ok {
-		return 0, errors.Unauthorized(errors.ErrMsgInvalidUserID)
+		return nil, errors.Unauthorized(errors.ErrMsgInvalidUserID)
+	}
+
+	// Extract authentication time, falling back to the issue time
+	authTime, ok := claims[ClaimKeyAuthTime].(float64)
+	if !ok {
+		authTime, _ = claims[ClaimKeyIAT].(float64)
 	}
 
-	return uint(userIDFloat), nil
+	sessionID, _ := claims[ClaimKeySessionID].(string)
+
+	return &SessionClaims{
+		UserID:    uint(userIDFloat),
+		AuthTime:  time.Unix(int64(authTime), 0),
+		SessionID: sessionID,
+	}, nil
 }
 
 // ValidateTokenForRevocation validates a token's format and extracts the token ID (jti).
 // This function is used when checking if a token has been revoked.
 // Returns the token ID from the token or an error if basic validation fails.
-func ValidateTokenForRevocation(tokenString string) (string, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
-			return nil, errors.Unauthorized(errors.ErrMsgInvalidTokenFormat)
-		}
-		return publicKey, nil
-	})
+func ValidateTokenForRevocation(ctx context.Context, tokenString string) (string, error) {
+	token, err := ParseToken(ctx, tokenString, jwt.MapClaims{})
 
 	if err != nil {
 		return "", errors.Unauthorized(errors.ErrMsgInvalidToken)
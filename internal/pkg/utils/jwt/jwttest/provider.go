@@ -0,0 +1,99 @@
+// Package jwttest provides a test double of the signing key provider, for exercising code that
+// signs or verifies tokens without key files or an external key store.
+package jwttest
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"sync"
+
+	jwtutil "github.com/verigate/verigate-server/internal/pkg/utils/jwt"
+)
+
+// Key IDs of the keys of a KeyProvider
+const (
+	RSAKeyID = "test-rsa" // Key ID of the RS256 signing key
+	ECKeyID  = "test-ec"  // Key ID of the ES256 signing key
+)
+
+// KeyProvider is a jwtutil.KeyProvider holding freshly generated keys in memory, the same for
+// every tenant. It records the key ID of every signature it makes, and fails every call with
+// Err when set.
+type KeyProvider struct {
+	mu     sync.Mutex
+	keys   map[string]crypto.Signer // Private keys by key ID
+	signed []string
+	Err    error // Error returned by every call when non-nil
+}
+
+// NewKeyProvider generates an RSA key for RS256 and a P-256 key for ES256 tokens.
+func NewKeyProvider() (*KeyProvider, error) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeyProvider{keys: map[string]crypto.Signer{RSAKeyID: rsaKey, ECKeyID: ecKey}}, nil
+}
+
+// SigningKey returns the RSA key for RS256 and the EC key for ES256.
+func (p *KeyProvider) SigningKey(ctx context.Context, alg string) (jwtutil.VerificationKey, error) {
+	if p.Err != nil {
+		return jwtutil.VerificationKey{}, p.Err
+	}
+	switch alg {
+	case jwtutil.SigningAlgRS256:
+		return p.verificationKey(RSAKeyID, alg), nil
+	case jwtutil.SigningAlgES256:
+		return p.verificationKey(ECKeyID, alg), nil
+	}
+	return jwtutil.VerificationKey{}, fmt.Errorf("unsupported signing algorithm %q", alg)
+}
+
+// VerificationKeys returns the RSA key followed by the EC key.
+func (p *KeyProvider) VerificationKeys(ctx context.Context) ([]jwtutil.VerificationKey, error) {
+	if p.Err != nil {
+		return nil, p.Err
+	}
+	return []jwtutil.VerificationKey{
+		p.verificationKey(RSAKeyID, jwtutil.SigningAlgRS256),
+		p.verificationKey(ECKeyID, jwtutil.SigningAlgES256),
+	}, nil
+}
+
+// Sign signs with the key kid and records kid.
+func (p *KeyProvider) Sign(ctx context.Context, kid string, signingInput []byte) ([]byte, error) {
+	if p.Err != nil {
+		return nil, p.Err
+	}
+	key, ok := p.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	p.mu.Lock()
+	p.signed = append(p.signed, kid)
+	p.mu.Unlock()
+	return jwtutil.SignJWS(key, signingInput)
+}
+
+// Signed returns the key IDs of the signatures made so far, in order.
+func (p *KeyProvider) Signed() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.signed...)
+}
+
+// verificationKey returns the public key kid as a verification key for alg.
+func (p *KeyProvider) verificationKey(kid, alg string) jwtutil.VerificationKey {
+	return jwtutil.VerificationKey{Kid: kid, Alg: alg, Key: p.keys[kid].Public()}
+}
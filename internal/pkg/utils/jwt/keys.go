@@ -0,0 +1,376 @@
+// Package jwt provides utilities for creating and validating JWT tokens
+// used throughout the application for authentication and authorization.
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/pkg/tenant"
+)
+
+// Key management constants
+const (
+	HeaderKeyID       = "kid"   // JWT header carrying the signing key identifier
+	JWKKeyTypeRSA     = "RSA"   // JWK key type for RSA keys
+	JWKKeyTypeEC      = "EC"    // JWK key type for elliptic curve keys
+	JWKUseSignature   = "sig"   // JWK public key use for signatures
+	JWKAlgorithmRS256 = "RS256" // JWK algorithm for RSA SHA-256 signatures
+	JWKAlgorithmES256 = "ES256" // JWK algorithm for ECDSA P-256 SHA-256 signatures
+	JWKCurveP256      = "P-256" // JWK curve name of the P-256 curve
+
+	rotatedKeyBits      = 2048      // Size of RSA keys generated during rotation
+	defaultJWKSCacheAge = time.Hour // JWKS cache lifetime when rotation is disabled
+)
+
+// JWK represents a public key in JSON Web Key format (RFC 7517).
+// Keys published by this server are RSA and P-256 EC keys; client keys may use other curves.
+type JWK struct {
+	Kty string   `json:"kty"`           // Key type, "RSA" or "EC"
+	Use string   `json:"use,omitempty"` // Public key use, "sig" for signing keys
+	Alg string   `json:"alg,omitempty"` // Signing algorithm, "RS256" or "ES256" for keys published by this server
+	Kid string   `json:"kid,omitempty"` // Key identifier matching the JWT "kid" header
+	N   string   `json:"n,omitempty"`   // Base64url-encoded RSA modulus
+	E   string   `json:"e,omitempty"`   // Base64url-encoded RSA public exponent
+	Crv string   `json:"crv,omitempty"` // EC curve name
+	X   string   `json:"x,omitempty"`   // Base64url-encoded EC x coordinate
+	Y   string   `json:"y,omitempty"`   // Base64url-encoded EC y coordinate
+	X5c []string `json:"x5c,omitempty"` // Base64-encoded DER certificate chain of the key, the key's own certificate first
+}
+
+// JWKSet represents a JSON Web Key Set document.
+type JWKSet struct {
+	Keys []JWK `json:"keys"` // Published public keys, current signing key first
+}
+
+// signingKey is an RSA key pair identified by its key ID, together with the P-256 key pair
+// signing ES256 tokens during the same period. Both are rotated and retired together.
+type signingKey struct {
+	kid          string
+	privateKey   *rsa.PrivateKey
+	ecKid        string
+	ecPrivateKey *ecdsa.PrivateKey
+	retireAt     time.Time // Zero for the current key; otherwise when the public key stops being published
+}
+
+// newSigningKey pairs an RSA and an EC private key, deriving the key ID of each.
+func newSigningKey(privateKey *rsa.PrivateKey, ecPrivateKey *ecdsa.PrivateKey) *signingKey {
+	return &signingKey{
+		kid:          keyID(&privateKey.PublicKey),
+		privateKey:   privateKey,
+		ecKid:        ecKeyID(&ecPrivateKey.PublicKey),
+		ecPrivateKey: ecPrivateKey,
+	}
+}
+
+// keyRing holds the current signing key and previously used keys that are
+// still published for verification during their grace window.
+// Rotated keys are kept in memory only and do not survive a restart.
+type keyRing struct {
+	mu           sync.RWMutex
+	current      *signingKey
+	previous     []*signingKey
+	nextRotation time.Time // Zero when scheduled rotation is disabled
+}
+
+// keys is the key ring of the default tenant initialized by InitKeys
+var keys = &keyRing{}
+
+// tenantKeys holds the key rings of the tenants by tenant ID. It is filled by InitTenantKeys
+// at startup and only read once requests are served.
+var tenantKeys = map[string]*keyRing{}
+
+// ringFor returns the key ring of the tenant ctx is served for. A tenant whose keys were not
+// initialized gets an empty ring, so nothing is signed or verified with another tenant's keys.
+func ringFor(ctx context.Context) *keyRing {
+	id := tenant.ID(ctx)
+	if id == "" {
+		return keys
+	}
+	if ring := tenantKeys[id]; ring != nil {
+		return ring
+	}
+	return &keyRing{}
+}
+
+// rings returns the key rings of the default tenant and of every tenant, keyed by tenant ID.
+func rings() map[string]*keyRing {
+	all := map[string]*keyRing{"": keys}
+	for id, ring := range tenantKeys {
+		all[id] = ring
+	}
+	return all
+}
+
+// setCurrent replaces the key ring contents with a single current key.
+func (k *keyRing) setCurrent(privateKey *rsa.PrivateKey, ecPrivateKey *ecdsa.PrivateKey) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.current = newSigningKey(privateKey, ecPrivateKey)
+	k.previous = nil
+}
+
+// signer returns the current signing key, or nil if keys are not initialized.
+func (k *keyRing) signer() *signingKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.current
+}
+
+// verificationKeys returns the public keys of the tenant ctx is served for to try when verifying
+// a token signed with alg. If kid identifies a published key, only that key is returned;
+// otherwise every published key of alg is returned with the current key first.
+func verificationKeys(ctx context.Context, kid, alg string) ([]crypto.PublicKey, error) {
+	published, err := provider.VerificationKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []crypto.PublicKey
+	for _, key := range published {
+		if key.Alg != alg {
+			continue
+		}
+		if kid != "" && key.Kid == kid {
+			return []crypto.PublicKey{key.Key}, nil
+		}
+		result = append(result, key.Key)
+	}
+	return result, nil
+}
+
+// publishedLocked returns the current key followed by previous keys whose
+// grace window has not elapsed. The caller must hold the lock.
+func (k *keyRing) publishedLocked() []*signingKey {
+	var result []*signingKey
+	if k.current != nil {
+		result = append(result, k.current)
+	}
+
+	now := time.Now()
+	for _, key := range k.previous {
+		if now.Before(key.retireAt) {
+			result = append(result, key)
+		}
+	}
+	return result
+}
+
+// rotate makes privateKey and ecPrivateKey the current signing keys. The previous current keys
+// remain published for verification until the grace window elapses.
+// Returns the key ID of the new RSA key.
+func (k *keyRing) rotate(privateKey *rsa.PrivateKey, ecPrivateKey *ecdsa.PrivateKey, grace time.Duration) string {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := time.Now()
+	if k.current != nil {
+		k.current.retireAt = now.Add(grace)
+		k.previous = append(k.previous, k.current)
+	}
+
+	// Drop keys whose grace window has already elapsed
+	retained := k.previous[:0]
+	for _, key := range k.previous {
+		if now.Before(key.retireAt) {
+			retained = append(retained, key)
+		}
+	}
+	k.previous = retained
+
+	k.current = newSigningKey(privateKey, ecPrivateKey)
+	return k.current.kid
+}
+
+// RotateKey generates new RSA and EC signing keys and makes them the current keys of the tenant ctx is served for.
+// The previous key stays available for verification for the given grace period,
+// which should be at least the maximum lifetime of any token it signed.
+// Returns the key ID of the new RSA signing key, or an error when an external key provider
+// holds the keys, which are then rotated in the provider.
+func RotateKey(ctx context.Context, grace time.Duration) (string, error) {
+	if !isLocalKeyProvider() {
+		return "", fmt.Errorf("signing keys are rotated by the key provider")
+	}
+	return rotateRing(ringFor(ctx), grace)
+}
+
+// rotateRing generates new RSA and EC signing keys and makes them the current keys of ring.
+func rotateRing(ring *keyRing, grace time.Duration) (string, error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, rotatedKeyBits)
+	if err != nil {
+		return "", err
+	}
+	ecPrivateKey, err := generateECKey()
+	if err != nil {
+		return "", err
+	}
+
+	return ring.rotate(privateKey, ecPrivateKey, grace), nil
+}
+
+// generateECKey generates a P-256 key for signing ES256 tokens.
+func generateECKey() (*ecdsa.PrivateKey, error) {
+	return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+}
+
+// StartKeyRotation rotates the signing keys of the default tenant and of every tenant every
+// interval until ctx is cancelled. Retired keys remain published for the grace period so that
+// tokens signed before a rotation can still be verified. The optional onRotate callback is
+// invoked after each rotation attempt with the tenant ID, empty for the default tenant, and
+// the new key ID or the error encountered. Nothing is rotated when an external key provider
+// holds the keys.
+func StartKeyRotation(ctx context.Context, interval, grace time.Duration, onRotate func(tenantID, kid string, err error)) {
+	if interval <= 0 || !isLocalKeyProvider() {
+		return
+	}
+
+	all := rings()
+	ids := make([]string, 0, len(all))
+	for id, ring := range all {
+		ids = append(ids, id)
+		ring.mu.Lock()
+		ring.nextRotation = time.Now().Add(interval)
+		ring.mu.Unlock()
+	}
+	sort.Strings(ids)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				for _, id := range ids {
+					ring := all[id]
+					ring.mu.Lock()
+					ring.nextRotation = time.Now().Add(interval)
+					ring.mu.Unlock()
+
+					kid, err := rotateRing(ring, grace)
+					if onRotate != nil {
+						onRotate(id, kid, err)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// PublicJWKS returns the JSON Web Key Set of all currently published public keys of the tenant ctx is served for,
+// in the order of the key provider, the current keys first. Symmetric keys are never published.
+// Returns an error if the key provider cannot list its keys.
+func PublicJWKS(ctx context.Context) (JWKSet, error) {
+	published, err := provider.VerificationKeys(ctx)
+	if err != nil {
+		return JWKSet{}, err
+	}
+
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range published {
+		switch publicKey := key.Key.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, toJWK(key.Kid, publicKey))
+		case *ecdsa.PublicKey:
+			set.Keys = append(set.Keys, toECJWK(key.Kid, publicKey))
+		}
+	}
+	return set, nil
+}
+
+// JWKSCacheMaxAge returns how long clients may cache the JWKS document.
+// With scheduled rotation it is the time remaining until the next rotation,
+// so caches refresh as soon as a new key is published. Keys of an external key provider
+// may be cached for the default hour.
+func JWKSCacheMaxAge(ctx context.Context) time.Duration {
+	if !isLocalKeyProvider() {
+		return defaultJWKSCacheAge
+	}
+
+	ring := ringFor(ctx)
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+
+	if ring.nextRotation.IsZero() {
+		return defaultJWKSCacheAge
+	}
+
+	remaining := time.Until(ring.nextRotation)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// toJWK converts an RSA public key into its JWK representation.
+func toJWK(kid string, publicKey *rsa.PublicKey) JWK {
+	return JWK{
+		Kty: JWKKeyTypeRSA,
+		Use: JWKUseSignature,
+		Alg: JWKAlgorithmRS256,
+		Kid: kid,
+		N:   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+	}
+}
+
+// keyID derives a stable key identifier from an RSA public key using its
+// JWK thumbprint (RFC 7638), so the same key always has the same kid.
+func keyID(publicKey *rsa.PublicKey) string {
+	jwk := toJWK("", publicKey)
+
+	// RFC 7638 requires the required members in lexicographic order with no whitespace
+	thumbprintInput, _ := json.Marshal(struct {
+		E   string `json:"e"`
+		Kty string `json:"kty"`
+		N   string `json:"n"`
+	}{E: jwk.E, Kty: jwk.Kty, N: jwk.N})
+
+	sum := sha256.Sum256(thumbprintInput)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// toECJWK converts a P-256 public key into its JWK representation.
+func toECJWK(kid string, publicKey *ecdsa.PublicKey) JWK {
+	size := (publicKey.Curve.Params().BitSize + 7) / 8
+	return JWK{
+		Kty: JWKKeyTypeEC,
+		Use: JWKUseSignature,
+		Alg: JWKAlgorithmES256,
+		Kid: kid,
+		Crv: JWKCurveP256,
+		X:   base64.RawURLEncoding.EncodeToString(publicKey.X.FillBytes(make([]byte, size))),
+		Y:   base64.RawURLEncoding.EncodeToString(publicKey.Y.FillBytes(make([]byte, size))),
+	}
+}
+
+// ecKeyID derives a stable key identifier from an EC public key using its
+// JWK thumbprint (RFC 7638), like keyID does for RSA keys.
+func ecKeyID(publicKey *ecdsa.PublicKey) string {
+	jwk := toECJWK("", publicKey)
+
+	thumbprintInput, _ := json.Marshal(struct {
+		Crv string `json:"crv"`
+		Kty string `json:"kty"`
+		X   string `json:"x"`
+		Y   string `json:"y"`
+	}{Crv: jwk.Crv, Kty: jwk.Kty, X: jwk.X, Y: jwk.Y})
+
+	sum := sha256.Sum256(thumbprintInput)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
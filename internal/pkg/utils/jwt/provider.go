@@ -0,0 +1,137 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"fmt"
+)
+
+// Key providers the signing keys can be held by
+const (
+	KeyProviderLocal = "local" // Keys loaded from the environment or files and held in memory
+	KeyProviderVault = "vault" // Keys held by HashiCorp Vault's transit engine, which signs tokens itself
+)
+
+// VerificationKey is a public key of a KeyProvider, named by the key ID in the "kid" header of
+// the tokens its private key signs.
+type VerificationKey struct {
+	Kid string           // Key identifier, the JWK thumbprint of the key for the built-in providers
+	Alg string           // JWS algorithm the key signs with, SigningAlgRS256 or SigningAlgES256
+	Key crypto.PublicKey // *rsa.PublicKey or P-256 *ecdsa.PublicKey
+}
+
+// KeyProvider holds the signing keys of the tenant a context is served for. Every RS256 and
+// ES256 token is signed through it, so a provider backed by a KMS or HSM signs without the
+// private key ever leaving it.
+type KeyProvider interface {
+	// SigningKey returns the key currently signing tokens with alg.
+	SigningKey(ctx context.Context, alg string) (VerificationKey, error)
+
+	// VerificationKeys returns every published key, the current signing keys first. Tokens
+	// signed with any of them verify.
+	VerificationKeys(ctx context.Context) ([]VerificationKey, error)
+
+	// Sign returns the JWS signature of signingInput made with the private key kid
+	// (RFC 7515 Section 5.1), before base64url encoding.
+	Sign(ctx context.Context, kid string, signingInput []byte) ([]byte, error)
+}
+
+// provider holds the signing keys, the in-memory key rings unless SetKeyProvider replaced it
+var provider KeyProvider = ringKeyProvider{}
+
+// SetKeyProvider makes p hold the signing keys of every tenant instead of the in-memory key
+// rings. It must be called before requests are served. Keys of an external provider are
+// rotated in the provider, so RotateKey and StartKeyRotation no longer apply.
+func SetKeyProvider(p KeyProvider) {
+	provider = p
+}
+
+// isLocalKeyProvider reports whether the signing keys are held by the in-memory key rings.
+func isLocalKeyProvider() bool {
+	_, ok := provider.(ringKeyProvider)
+	return ok
+}
+
+// SignJWS signs the JWS signing input with an in-process private key: RS256 with an RSA key,
+// ES256 with a P-256 key. ES256 signatures are the concatenated R and S values (RFC 7518
+// Section 3.4). It serves KeyProvider implementations holding their keys in memory.
+func SignJWS(privateKey crypto.PrivateKey, signingInput []byte) ([]byte, error) {
+	digest := sha256.Sum256(signingInput)
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		return rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			return nil, err
+		}
+		size := (key.Curve.Params().BitSize + 7) / 8
+		signature := make([]byte, 2*size)
+		r.FillBytes(signature[:size])
+		s.FillBytes(signature[size:])
+		return signature, nil
+	}
+	return nil, fmt.Errorf("unsupported private key type %T", privateKey)
+}
+
+// ringKeyProvider is the KeyProvider of the in-memory key rings loaded by InitKeys and
+// InitTenantKeys and rotated by RotateKey.
+type ringKeyProvider struct{}
+
+// SigningKey returns the current RSA key for RS256 or EC key for ES256 of the tenant's ring.
+func (ringKeyProvider) SigningKey(ctx context.Context, alg string) (VerificationKey, error) {
+	key := ringFor(ctx).signer()
+	if key == nil {
+		return VerificationKey{}, fmt.Errorf("JWT private key not initialized")
+	}
+	switch alg {
+	case SigningAlgRS256:
+		return VerificationKey{Kid: key.kid, Alg: SigningAlgRS256, Key: &key.privateKey.PublicKey}, nil
+	case SigningAlgES256:
+		return VerificationKey{Kid: key.ecKid, Alg: SigningAlgES256, Key: &key.ecPrivateKey.PublicKey}, nil
+	}
+	return VerificationKey{}, fmt.Errorf("unsupported signing algorithm %q", alg)
+}
+
+// VerificationKeys returns the published keys of the tenant's ring, each RSA key followed by
+// the EC key of the same period.
+func (ringKeyProvider) VerificationKeys(ctx context.Context) ([]VerificationKey, error) {
+	ring := ringFor(ctx)
+	ring.mu.RLock()
+	defer ring.mu.RUnlock()
+
+	var result []VerificationKey
+	for _, key := range ring.publishedLocked() {
+		result = append(result,
+			VerificationKey{Kid: key.kid, Alg: SigningAlgRS256, Key: &key.privateKey.PublicKey},
+			VerificationKey{Kid: key.ecKid, Alg: SigningAlgES256, Key: &key.ecPrivateKey.PublicKey},
+		)
+	}
+	return result, nil
+}
+
+// Sign signs with the published private key kid of the tenant's ring, which may have been
+// rotated out since it was returned by SigningKey.
+func (ringKeyProvider) Sign(ctx context.Context, kid string, signingInput []byte) ([]byte, error) {
+	ring := ringFor(ctx)
+	ring.mu.RLock()
+	var privateKey crypto.PrivateKey
+	for _, key := range ring.publishedLocked() {
+		switch kid {
+		case key.kid:
+			privateKey = key.privateKey
+		case key.ecKid:
+			privateKey = key.ecPrivateKey
+		}
+	}
+	ring.mu.RUnlock()
+
+	if privateKey == nil {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return SignJWS(privateKey, signingInput)
+}
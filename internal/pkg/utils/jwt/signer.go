@@ -0,0 +1,133 @@
+package jwt
+
+import (
+	"context"
+	"crypto"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// JWS algorithms tokens may be signed with (RFC 7518 Section 3.1)
+const (
+	SigningAlgRS256 = JWKAlgorithmRS256 // RSASSA-PKCS1-v1_5 with SHA-256, the default
+	SigningAlgES256 = JWKAlgorithmES256 // ECDSA on the P-256 curve with SHA-256
+	SigningAlgHS256 = "HS256"           // HMAC with SHA-256 keyed with a shared secret
+
+	// MinHMACSecretLength is the shortest shared secret accepted for HS256, in bytes. A key
+	// must be at least as long as the hash output (RFC 7518 Section 3.2).
+	MinHMACSecretLength = 32
+)
+
+// IsSupportedSigningAlgorithm reports whether alg is a JWS algorithm tokens can be signed with.
+func IsSupportedSigningAlgorithm(alg string) bool {
+	return alg == SigningAlgRS256 || alg == SigningAlgES256 || alg == SigningAlgHS256
+}
+
+// SupportedSigningAlgorithms returns the JWS algorithms tokens can be signed with for discovery metadata.
+func SupportedSigningAlgorithms() []string {
+	return []string{SigningAlgRS256, SigningAlgES256, SigningAlgHS256}
+}
+
+// Signer signs tokens with one algorithm: RS256 and ES256 with the current keys of the tenant
+// a token is signed for, HS256 with a shared secret. Every token this server signs goes
+// through a Signer, so the choice of key follows from the algorithm in one place.
+type Signer struct {
+	method jwt.SigningMethod
+	secret []byte // Shared key for HS256, nil otherwise
+}
+
+// DefaultSigner returns the signer of tokens that do not select an algorithm, which uses RS256.
+func DefaultSigner() Signer {
+	return Signer{method: jwt.SigningMethodRS256}
+}
+
+// NewSigner returns the signer for alg. The secret is the shared key for HS256 and is ignored
+// otherwise. Returns an error if alg is not supported or the secret is shorter than
+// MinHMACSecretLength bytes.
+func NewSigner(alg string, secret []byte) (Signer, error) {
+	switch alg {
+	case "", SigningAlgRS256:
+		return DefaultSigner(), nil
+	case SigningAlgES256:
+		return Signer{method: jwt.SigningMethodES256}, nil
+	case SigningAlgHS256:
+		if len(secret) < MinHMACSecretLength {
+			return Signer{}, fmt.Errorf("HS256 secret must be at least %d bytes", MinHMACSecretLength)
+		}
+		return Signer{method: jwt.SigningMethodHS256, secret: secret}, nil
+	}
+	return Signer{}, fmt.Errorf("unsupported signing algorithm %q", alg)
+}
+
+// Alg returns the JWS algorithm the signer signs with.
+func (s Signer) Alg() string {
+	return s.method.Alg()
+}
+
+// HalfHash returns the base64url encoding of the left-most half of the hash of value, using the
+// hash function of the signer's algorithm, as in the at_hash and c_hash ID token claims
+// (OpenID Connect Core Section 3.3.2.11).
+func (s Signer) HalfHash(value string) string {
+	var h crypto.Hash
+	switch m := s.method.(type) {
+	case *jwt.SigningMethodRSA:
+		h = m.Hash
+	case *jwt.SigningMethodECDSA:
+		h = m.Hash
+	case *jwt.SigningMethodHMAC:
+		h = m.Hash
+	default:
+		h = crypto.SHA256
+	}
+
+	hasher := h.New()
+	hasher.Write([]byte(value))
+	sum := hasher.Sum(nil)
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+}
+
+// Sign signs the claims and sets the "typ" header to typ. Tokens signed with a key of the
+// tenant ctx is served for name it in the "kid" header and are signed by the key provider,
+// so the signer never holds the private key; HS256 tokens carry no key ID.
+func (s Signer) Sign(ctx context.Context, claims jwt.Claims, typ string) (string, error) {
+	token := jwt.NewWithClaims(s.method, claims)
+	token.Header[HeaderType] = typ
+
+	if s.secret != nil {
+		return token.SignedString(s.secret)
+	}
+
+	key, err := provider.SigningKey(ctx, s.method.Alg())
+	if err != nil {
+		return "", err
+	}
+	token.Header[HeaderKeyID] = key.Kid
+
+	signingString, err := token.SigningString()
+	if err != nil {
+		return "", err
+	}
+	signature, err := provider.Sign(ctx, key.Kid, []byte(signingString))
+	if err != nil {
+		return "", err
+	}
+	return signingString + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// ParseIgnoringTime parses and verifies a token signed by the signer, with the published keys
+// of the tenant ctx is served for or with the shared secret, without checking its time-based
+// claims. Tokens signed with any other algorithm are rejected.
+func (s Signer) ParseIgnoringTime(ctx context.Context, tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	if s.secret == nil {
+		return parseWithRing(ctx, tokenString, claims, s.method)
+	}
+
+	return timelessParser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if token.Method.Alg() != s.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return s.secret, nil
+	})
+}
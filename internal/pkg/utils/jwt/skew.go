@@ -0,0 +1,61 @@
+package jwt
+
+import (
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// defaultClockSkew is the tolerated clock skew until InitKeys reads the configured one
+const defaultClockSkew = 60 * time.Second
+
+// clockSkew is how far the clocks of token issuers and this server may drift apart
+var clockSkew = defaultClockSkew
+
+// timelessParser verifies signatures without checking claims, which validateTimeClaims checks instead
+var timelessParser = jwt.NewParser(jwt.WithoutClaimsValidation())
+
+// registeredTimeClaims is implemented by claims embedding jwt.RegisteredClaims.
+type registeredTimeClaims interface {
+	VerifyExpiresAt(cmp time.Time, req bool) bool
+	VerifyNotBefore(cmp time.Time, req bool) bool
+	VerifyIssuedAt(cmp time.Time, req bool) bool
+}
+
+// ClockSkew returns the tolerated clock skew applied to the time-based claims of received tokens.
+func ClockSkew() time.Duration {
+	return clockSkew
+}
+
+// validateTimeClaims checks the exp, nbf, and iat claims of a verified token, each optional.
+// The skew applies symmetrically: a token is accepted until exp plus the skew, from nbf
+// minus the skew, and with an iat up to the skew in the future. Claims types other than
+// map claims and registered claims are checked by their own Valid method.
+func validateTimeClaims(claims jwt.Claims) error {
+	now := time.Now()
+	late, early := now.Add(-clockSkew), now.Add(clockSkew)
+
+	var expired, notYetValid, issuedInFuture bool
+	switch c := claims.(type) {
+	case jwt.MapClaims:
+		expired = !c.VerifyExpiresAt(late.Unix(), false)
+		notYetValid = !c.VerifyNotBefore(early.Unix(), false)
+		issuedInFuture = !c.VerifyIssuedAt(early.Unix(), false)
+	case registeredTimeClaims:
+		expired = !c.VerifyExpiresAt(late, false)
+		notYetValid = !c.VerifyNotBefore(early, false)
+		issuedInFuture = !c.VerifyIssuedAt(early, false)
+	default:
+		return claims.Valid()
+	}
+
+	switch {
+	case expired:
+		return jwt.NewValidationError("token is expired", jwt.ValidationErrorExpired)
+	case notYetValid:
+		return jwt.NewValidationError("token is not valid yet", jwt.ValidationErrorNotValidYet)
+	case issuedInFuture:
+		return jwt.NewValidationError("token used before issued", jwt.ValidationErrorIssuedAt)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package jwt
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestValidateTimeClaimsLeeway(t *testing.T) {
+	previous := clockSkew
+	clockSkew = time.Minute
+	t.Cleanup(func() { clockSkew = previous })
+
+	const margin = 10 * time.Second
+	within, beyond := clockSkew-margin, clockSkew+margin
+
+	tests := []struct {
+		name      string
+		exp       time.Duration // Offset of exp from now, unset when zero
+		nbf       time.Duration // Offset of nbf from now, unset when zero
+		iat       time.Duration // Offset of iat from now, unset when zero
+		wantError uint32        // Expected validation error, zero for a valid token
+	}{
+		{"valid", time.Minute, -time.Minute, -time.Minute, 0},
+		{"expired within leeway", -within, 0, 0, 0},
+		{"expired beyond leeway", -beyond, 0, 0, jwt.ValidationErrorExpired},
+		{"not yet valid within leeway", time.Hour, within, 0, 0},
+		{"not yet valid beyond leeway", time.Hour, beyond, 0, jwt.ValidationErrorNotValidYet},
+		{"issued in future within leeway", time.Hour, 0, within, 0},
+		{"issued in future beyond leeway", time.Hour, 0, beyond, jwt.ValidationErrorIssuedAt},
+		{"without time claims", 0, 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		now := time.Now()
+
+		// Map claims hold numbers as decoded from JSON
+		mapClaims := jwt.MapClaims{}
+		registeredClaims := &jwt.RegisteredClaims{}
+		if tt.exp != 0 {
+			mapClaims["exp"] = float64(now.Add(tt.exp).Unix())
+			registeredClaims.ExpiresAt = jwt.NewNumericDate(now.Add(tt.exp))
+		}
+		if tt.nbf != 0 {
+			mapClaims["nbf"] = float64(now.Add(tt.nbf).Unix())
+			registeredClaims.NotBefore = jwt.NewNumericDate(now.Add(tt.nbf))
+		}
+		if tt.iat != 0 {
+			mapClaims["iat"] = float64(now.Add(tt.iat).Unix())
+			registeredClaims.IssuedAt = jwt.NewNumericDate(now.Add(tt.iat))
+		}
+
+		for kind, claims := range map[string]jwt.Claims{"map": mapClaims, "registered": registeredClaims} {
+			t.Run(tt.name+"/"+kind, func(t *testing.T) {
+				err := validateTimeClaims(claims)
+				if tt.wantError == 0 {
+					if err != nil {
+						t.Errorf("got error %v, want none", err)
+					}
+					return
+				}
+
+				validationErr, ok := err.(*jwt.ValidationError)
+				if !ok || validationErr.Errors&tt.wantError == 0 {
+					t.Errorf("got error %v, want validation error %d", err, tt.wantError)
+				}
+			})
+		}
+	}
+}
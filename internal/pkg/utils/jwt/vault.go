@@ -0,0 +1,307 @@
+package jwt
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/verigate/verigate-server/internal/pkg/tenant"
+)
+
+// vaultMaxResponseSize bounds the Vault responses read
+const vaultMaxResponseSize = 1 << 20
+
+// VaultKeyProvider is a KeyProvider backed by the transit secrets engine of HashiCorp Vault.
+// The private keys never leave Vault: tokens are signed by its sign endpoint, and only the
+// public keys are read. An RSA transit key signs RS256 tokens and an optional ecdsa-p256
+// transit key signs ES256 tokens. Tenants use transit keys of their own, named after the
+// default tenant's keys with "-<tenant ID>" appended.
+//
+// Every version of a transit key that Vault still keeps is published, and the latest signs.
+// Keys are rotated in Vault; the new version is picked up once the cached key list expires.
+type VaultKeyProvider struct {
+	addr     string            // Vault address, such as https://vault.example.com:8200
+	token    string            // Vault token allowed to read and sign with the transit keys
+	mount    string            // Path the transit engine is mounted at
+	keyNames map[string]string // Transit key of the default tenant by JWS algorithm
+	cacheTTL time.Duration     // How long the public keys read from Vault are used
+	client   *http.Client
+
+	mu    sync.Mutex
+	cache map[string]*vaultKey // Transit keys read from Vault by name
+}
+
+// vaultKey holds the versions of a transit key read from Vault.
+type vaultKey struct {
+	keys     []VerificationKey // Public key of every version, the latest first
+	versions map[string]int    // Version of each key by key ID
+	readAt   time.Time
+}
+
+// NewVaultKeyProvider creates a provider signing with the transit keys rsaKey and, unless empty,
+// ecKey of the transit engine mounted at mount in the Vault at addr, authenticating with token.
+// Public keys read from Vault are used for cacheTTL; requests to Vault time out after timeout.
+func NewVaultKeyProvider(addr, token, mount, rsaKey, ecKey string, cacheTTL, timeout time.Duration) *VaultKeyProvider {
+	keyNames := map[string]string{SigningAlgRS256: rsaKey}
+	if ecKey != "" {
+		keyNames[SigningAlgES256] = ecKey
+	}
+
+	return &VaultKeyProvider{
+		addr:     strings.TrimRight(addr, "/"),
+		token:    token,
+		mount:    strings.Trim(mount, "/"),
+		keyNames: keyNames,
+		cacheTTL: cacheTTL,
+		client:   &http.Client{Timeout: timeout},
+		cache:    make(map[string]*vaultKey),
+	}
+}
+
+// SigningKey returns the latest version of the transit key signing alg for the tenant ctx is served for.
+func (p *VaultKeyProvider) SigningKey(ctx context.Context, alg string) (VerificationKey, error) {
+	name, ok := p.keyName(ctx, alg)
+	if !ok {
+		return VerificationKey{}, fmt.Errorf("no Vault transit key signs %s tokens", alg)
+	}
+	key, err := p.readKey(ctx, name, alg)
+	if err != nil {
+		return VerificationKey{}, err
+	}
+	return key.keys[0], nil
+}
+
+// VerificationKeys returns every version of the tenant's transit keys, the RSA key's first.
+func (p *VaultKeyProvider) VerificationKeys(ctx context.Context) ([]VerificationKey, error) {
+	var result []VerificationKey
+	for _, alg := range []string{SigningAlgRS256, SigningAlgES256} {
+		name, ok := p.keyName(ctx, alg)
+		if !ok {
+			continue
+		}
+		key, err := p.readKey(ctx, name, alg)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, key.keys...)
+	}
+	return result, nil
+}
+
+// Sign has Vault sign signingInput with the version of the tenant's transit key that kid names.
+func (p *VaultKeyProvider) Sign(ctx context.Context, kid string, signingInput []byte) ([]byte, error) {
+	for _, alg := range []string{SigningAlgRS256, SigningAlgES256} {
+		name, ok := p.keyName(ctx, alg)
+		if !ok {
+			continue
+		}
+		key, err := p.readKey(ctx, name, alg)
+		if err != nil {
+			return nil, err
+		}
+		if version, ok := key.versions[kid]; ok {
+			return p.sign(ctx, name, alg, version, signingInput)
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// keyName returns the transit key signing alg for the tenant ctx is served for.
+func (p *VaultKeyProvider) keyName(ctx context.Context, alg string) (string, bool) {
+	name, ok := p.keyNames[alg]
+	if !ok {
+		return "", false
+	}
+	if id := tenant.ID(ctx); id != "" {
+		name += "-" + id
+	}
+	return name, true
+}
+
+// readKey returns the versions of the transit key name, read from Vault unless they were read
+// within the cache TTL. When Vault cannot be read, the versions read before are used.
+func (p *VaultKeyProvider) readKey(ctx context.Context, name, alg string) (*vaultKey, error) {
+	p.mu.Lock()
+	cached := p.cache[name]
+	p.mu.Unlock()
+	if cached != nil && time.Since(cached.readAt) < p.cacheTTL {
+		return cached, nil
+	}
+
+	key, err := p.fetchKey(ctx, name, alg)
+	if err != nil {
+		if cached != nil {
+			return cached, nil
+		}
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.cache[name] = key
+	p.mu.Unlock()
+	return key, nil
+}
+
+// fetchKey reads the public keys of every version of the transit key name from Vault and
+// checks that they are of the kind alg is signed with.
+func (p *VaultKeyProvider) fetchKey(ctx context.Context, name, alg string) (*vaultKey, error) {
+	var response struct {
+		Data struct {
+			LatestVersion int `json:"latest_version"`
+			Keys          map[string]struct {
+				PublicKey string `json:"public_key"`
+			} `json:"keys"`
+		} `json:"data"`
+	}
+	if err := p.do(ctx, http.MethodGet, "/keys/"+name, nil, &response); err != nil {
+		return nil, err
+	}
+
+	versions := make([]int, 0, len(response.Data.Keys))
+	for v := range response.Data.Keys {
+		version, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version %q of Vault transit key %s", v, name)
+		}
+		versions = append(versions, version)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+	if len(versions) == 0 || versions[0] != response.Data.LatestVersion {
+		return nil, fmt.Errorf("latest version of Vault transit key %s is not readable", name)
+	}
+
+	key := &vaultKey{versions: make(map[string]int, len(versions)), readAt: time.Now()}
+	for _, version := range versions {
+		publicKey, err := parseVaultPublicKey(response.Data.Keys[strconv.Itoa(version)].PublicKey, alg)
+		if err != nil {
+			return nil, fmt.Errorf("Vault transit key %s version %d: %w", name, version, err)
+		}
+		key.keys = append(key.keys, publicKey)
+		key.versions[publicKey.Kid] = version
+	}
+	return key, nil
+}
+
+// sign has Vault sign signingInput with the version of the transit key name. RSA keys sign
+// with PKCS #1 v1.5, and ECDSA signatures are requested in the JWS format.
+func (p *VaultKeyProvider) sign(ctx context.Context, name, alg string, version int, signingInput []byte) ([]byte, error) {
+	request := map[string]interface{}{
+		"input":       base64.StdEncoding.EncodeToString(signingInput),
+		"key_version": version,
+	}
+	if alg == SigningAlgRS256 {
+		request["signature_algorithm"] = "pkcs1v15"
+	} else {
+		request["marshaling_algorithm"] = "jws"
+	}
+
+	var response struct {
+		Data struct {
+			Signature string `json:"signature"`
+		} `json:"data"`
+	}
+	if err := p.do(ctx, http.MethodPost, "/sign/"+name+"/sha2-256", request, &response); err != nil {
+		return nil, err
+	}
+
+	// Signatures are prefixed with the key version, such as vault:v1:
+	parts := strings.SplitN(response.Data.Signature, ":", 3)
+	if len(parts) != 3 || parts[0] != "vault" {
+		return nil, fmt.Errorf("unexpected signature format from Vault transit key %s", name)
+	}
+	if alg == SigningAlgRS256 {
+		return base64.StdEncoding.DecodeString(parts[2])
+	}
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[2], "="))
+}
+
+// vaultStatusError is the error of a transit request Vault answered with an unexpected status.
+type vaultStatusError struct {
+	method string
+	path   string
+	status int
+}
+
+// Error describes the failed request.
+func (e *vaultStatusError) Error() string {
+	return fmt.Sprintf("Vault transit request %s %s failed with status %d", e.method, e.path, e.status)
+}
+
+// do sends a request to the transit engine at path, with body encoded as JSON unless nil, and
+// decodes the JSON response into result unless it is nil.
+func (p *VaultKeyProvider) do(ctx context.Context, method, path string, body, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, p.addr+"/v1/"+p.mount+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", p.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Requests without a result, such as creating a key, may be answered without a body
+	if result == nil && resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &vaultStatusError{method: method, path: path, status: resp.StatusCode}
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, vaultMaxResponseSize)).Decode(result)
+}
+
+// parseVaultPublicKey parses the PEM-encoded public key of a transit key version, which must be
+// an RSA key for RS256 or a P-256 key for ES256, and names it by its JWK thumbprint.
+func parseVaultPublicKey(publicKeyPEM, alg string) (VerificationKey, error) {
+	block, _ := pem.Decode([]byte(publicKeyPEM))
+	if block == nil {
+		return VerificationKey{}, fmt.Errorf("public key is not PEM-encoded")
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return VerificationKey{}, err
+	}
+
+	switch publicKey := parsed.(type) {
+	case *rsa.PublicKey:
+		if alg == SigningAlgRS256 {
+			return VerificationKey{Kid: keyID(publicKey), Alg: alg, Key: publicKey}, nil
+		}
+	case *ecdsa.PublicKey:
+		if alg == SigningAlgES256 && publicKey.Curve == elliptic.P256() {
+			return VerificationKey{Kid: ecKeyID(publicKey), Alg: alg, Key: publicKey}, nil
+		}
+	}
+	return VerificationKey{}, fmt.Errorf("public key cannot sign %s tokens", alg)
+}
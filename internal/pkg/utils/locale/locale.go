@@ -0,0 +1,92 @@
+// Package locale negotiates the language of user-facing text from the locales a request
+// asks for, using BCP 47 language tags such as "en", "de-CH", or "zh-Hant-TW".
+package locale
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Preferred returns the locales a request asks for, most preferred first: the space-separated
+// ui_locales parameter (OpenID Connect Core Section 3.1.2.1) followed by the Accept-Language
+// header ordered by quality (RFC 9110 Section 12.5.4). Wildcards and tags with q=0 are dropped.
+func Preferred(uiLocales, acceptLanguage string) []string {
+	preferred := strings.Fields(uiLocales)
+	return append(preferred, parseAcceptLanguage(acceptLanguage)...)
+}
+
+// parseAcceptLanguage returns the language tags of an Accept-Language header, highest quality
+// first and in header order among equal qualities.
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag     string
+		quality float64
+	}
+
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.TrimSpace(fields[0])
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range fields[1:] {
+			name, value, found := strings.Cut(strings.TrimSpace(param), "=")
+			if !found || !strings.EqualFold(name, "q") {
+				continue
+			}
+			q, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				q = 0
+			}
+			quality = q
+		}
+		if quality <= 0 {
+			continue
+		}
+
+		tags = append(tags, weighted{tag: tag, quality: quality})
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].quality > tags[j].quality })
+
+	result := make([]string, 0, len(tags))
+	for _, t := range tags {
+		result = append(result, t.tag)
+	}
+	return result
+}
+
+// Lookup returns the text for the first locale in preferred that texts has, and otherwise the
+// text for fallback. A tag without an exact match is matched by ever shorter prefixes, so
+// "de-CH" finds "de". Tags are compared case-insensitively. It reports false when neither a
+// preferred locale nor the fallback is available.
+func Lookup(texts map[string]string, preferred []string, fallback string) (string, bool) {
+	candidates := append(append([]string{}, preferred...), fallback)
+	for _, tag := range candidates {
+		for tag != "" {
+			if text, ok := find(texts, tag); ok {
+				return text, true
+			}
+			i := strings.LastIndex(tag, "-")
+			if i < 0 {
+				break
+			}
+			tag = tag[:i]
+		}
+	}
+	return "", false
+}
+
+// find returns the non-empty text stored under tag, ignoring case.
+func find(texts map[string]string, tag string) (string, bool) {
+	for key, text := range texts {
+		if text != "" && strings.EqualFold(key, tag) {
+			return text, true
+		}
+	}
+	return "", false
+}
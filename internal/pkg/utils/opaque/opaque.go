@@ -0,0 +1,100 @@
+// Package opaque generates and checks the opaque tokens this server issues.
+// A token is a recognizable type prefix, a random base64url body, and a CRC32 checksum of the
+// prefix and body, in the style of GitHub's tokens. The checksum lets the server reject a
+// mistyped or made-up token without a store lookup, and the prefix lets secret scanners
+// recognize leaked tokens.
+// Random is the single source of the random values in tokens, authorization codes, and
+// device codes, so they all get the configured entropy from crypto/rand.
+package opaque
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strings"
+
+	"github.com/verigate/verigate-server/internal/pkg/config"
+)
+
+// Token type prefixes. Every prefix starts with ServerPrefix.
+const (
+	ServerPrefix       = "vg_"    // Common start of every token prefix of this server
+	PrefixRefreshToken = "vg_rt_" // Refresh tokens, of OAuth clients and web sessions alike
+)
+
+// Token layout
+const (
+	minBodyLength  = 40 // Shortest body accepted, that of the base62 tokens issued before bodies were base64url
+	checksumLength = 6  // Base62 characters encoding the CRC32 checksum, enough for 2^32 values
+)
+
+// Character sets of token bodies and checksums. Base62 bodies of older tokens are also base64url.
+const (
+	base64URLAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+	base62Alphabet    = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+// Random returns the configured number of bytes from crypto/rand, base64url-encoded without padding.
+// It fails rather than return a shorter or partly random value if the random source errors.
+func Random() (string, error) {
+	b := make([]byte, config.AppConfig.TokenEntropyBytes)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", fmt.Errorf("failed to read random bytes: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Generate creates a random token with the given type prefix and a checksum suffix.
+func Generate(prefix string) (string, error) {
+	body, err := Random()
+	if err != nil {
+		return "", err
+	}
+
+	return prefix + body + checksum(prefix+body), nil
+}
+
+// Valid reports whether a token has the given type prefix, a body of at least the minimum
+// length in the base64url character set, and a matching checksum. Bodies of any longer length
+// are accepted, so tokens stay valid when the configured entropy changes.
+// The checksum comparison takes constant time.
+func Valid(token, prefix string) bool {
+	if len(token) < len(prefix)+minBodyLength+checksumLength || !strings.HasPrefix(token, prefix) {
+		return false
+	}
+
+	payload, sum := token[:len(token)-checksumLength], token[len(token)-checksumLength:]
+	for i := len(prefix); i < len(payload); i++ {
+		if strings.IndexByte(base64URLAlphabet, payload[i]) < 0 {
+			return false
+		}
+	}
+
+	return subtle.ConstantTimeCompare([]byte(sum), []byte(checksum(payload))) == 1
+}
+
+// Acceptable reports whether a presented token is worth looking up in a store. A token
+// carrying this server's prefix must be valid for the expected prefix. A token without it
+// was issued before tokens were prefixed and is only accepted while the configuration
+// still allows unprefixed tokens.
+func Acceptable(token, prefix string) bool {
+	if strings.HasPrefix(token, ServerPrefix) {
+		return Valid(token, prefix)
+	}
+	return config.AppConfig.AcceptUnprefixedTokens
+}
+
+// checksum encodes the CRC32 checksum of a token's prefix and body as fixed-width base62.
+func checksum(payload string) string {
+	sum := crc32.ChecksumIEEE([]byte(payload))
+
+	encoded := make([]byte, checksumLength)
+	for i := checksumLength - 1; i >= 0; i-- {
+		encoded[i] = base62Alphabet[sum%uint32(len(base62Alphabet))]
+		sum /= uint32(len(base62Alphabet))
+	}
+	return string(encoded)
+}
@@ -0,0 +1,150 @@
+package opaque
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/verigate/verigate-server/internal/pkg/config"
+)
+
+// withEntropy sets the configured token entropy for the duration of the test.
+func withEntropy(t *testing.T, bytes int) {
+	t.Helper()
+	previous := config.AppConfig.TokenEntropyBytes
+	config.AppConfig.TokenEntropyBytes = bytes
+	t.Cleanup(func() { config.AppConfig.TokenEntropyBytes = previous })
+}
+
+func TestRandom(t *testing.T) {
+	for _, entropy := range []int{32, 64, 128} {
+		withEntropy(t, entropy)
+
+		seen := make(map[string]bool)
+		for i := 0; i < 1000; i++ {
+			value, err := Random()
+			if err != nil {
+				t.Fatalf("Random failed: %v", err)
+			}
+			decoded, err := base64.RawURLEncoding.DecodeString(value)
+			if err != nil {
+				t.Fatalf("Random returned %q, not unpadded base64url: %v", value, err)
+			}
+			if len(decoded) != entropy {
+				t.Fatalf("Random returned %d bytes, want %d", len(decoded), entropy)
+			}
+			if seen[value] {
+				t.Fatalf("Random repeated %q", value)
+			}
+			seen[value] = true
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	withEntropy(t, 32)
+
+	token, err := Generate(PrefixRefreshToken)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if !strings.HasPrefix(token, PrefixRefreshToken) {
+		t.Errorf("got token %q, want prefix %q", token, PrefixRefreshToken)
+	}
+	if !Valid(token, PrefixRefreshToken) {
+		t.Errorf("generated token %q is not valid", token)
+	}
+
+	other, err := Generate(PrefixRefreshToken)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if other == token {
+		t.Error("Generate repeated a token")
+	}
+}
+
+func TestValid(t *testing.T) {
+	withEntropy(t, 32)
+
+	token, err := Generate(PrefixRefreshToken)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	body := token[len(PrefixRefreshToken) : len(token)-checksumLength]
+
+	// replaceAt returns the token with the character at i changed to another valid one
+	replaceAt := func(i int) string {
+		replacement := byte('A')
+		if token[i] == replacement {
+			replacement = 'B'
+		}
+		return token[:i] + string(replacement) + token[i+1:]
+	}
+
+	// A longer body, as issued with more entropy configured, carrying its own checksum
+	longBody := body + body
+	longToken := PrefixRefreshToken + longBody + checksum(PrefixRefreshToken+longBody)
+
+	tests := []struct {
+		name   string
+		token  string
+		prefix string
+		want   bool
+	}{
+		{"generated", token, PrefixRefreshToken, true},
+		{"longer body", longToken, PrefixRefreshToken, true},
+		{"other prefix expected", token, "vg_xx_", false},
+		{"body character changed", replaceAt(len(PrefixRefreshToken) + 5), PrefixRefreshToken, false},
+		{"checksum character changed", replaceAt(len(token) - 1), PrefixRefreshToken, false},
+		{"checksum dropped", token[:len(token)-checksumLength], PrefixRefreshToken, false},
+		{"truncated body", PrefixRefreshToken + body[:minBodyLength-1] + checksum(PrefixRefreshToken+body[:minBodyLength-1]), PrefixRefreshToken, false},
+		{"character outside base64url", PrefixRefreshToken + body[:len(body)-1] + "." + checksum(PrefixRefreshToken+body[:len(body)-1]+"."), PrefixRefreshToken, false},
+		{"without prefix", strings.TrimPrefix(token, PrefixRefreshToken), PrefixRefreshToken, false},
+		{"empty", "", PrefixRefreshToken, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Valid(tt.token, tt.prefix); got != tt.want {
+				t.Errorf("Valid(%q, %q) = %v, want %v", tt.token, tt.prefix, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAcceptable(t *testing.T) {
+	withEntropy(t, 32)
+	previous := config.AppConfig.AcceptUnprefixedTokens
+	t.Cleanup(func() { config.AppConfig.AcceptUnprefixedTokens = previous })
+
+	token, err := Generate(PrefixRefreshToken)
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	tampered := token[:len(token)-1] + "A"
+	if tampered == token {
+		tampered = token[:len(token)-1] + "B"
+	}
+	unprefixed, err := Random()
+	if err != nil {
+		t.Fatalf("Random failed: %v", err)
+	}
+
+	for _, acceptUnprefixed := range []bool{false, true} {
+		config.AppConfig.AcceptUnprefixedTokens = acceptUnprefixed
+
+		if !Acceptable(token, PrefixRefreshToken) {
+			t.Errorf("acceptUnprefixed=%v: valid token not acceptable", acceptUnprefixed)
+		}
+		if Acceptable(tampered, PrefixRefreshToken) {
+			t.Errorf("acceptUnprefixed=%v: token with a bad checksum acceptable", acceptUnprefixed)
+		}
+		if Acceptable(token, ServerPrefix+"xx_") {
+			t.Errorf("acceptUnprefixed=%v: token of another type acceptable", acceptUnprefixed)
+		}
+		if got := Acceptable(unprefixed, PrefixRefreshToken); got != acceptUnprefixed {
+			t.Errorf("acceptUnprefixed=%v: unprefixed token acceptable = %v", acceptUnprefixed, got)
+		}
+	}
+}
@@ -7,6 +7,18 @@ import (
 	"encoding/base64"
 )
 
+// Code challenge methods defined in RFC 7636 Section 4.2
+const (
+	MethodPlain = "plain" // The challenge is the verifier itself
+	MethodS256  = "S256"  // The challenge is the base64url-encoded SHA-256 of the verifier
+)
+
+// Length limits for code verifiers and challenges defined in RFC 7636 Section 4.1
+const (
+	minCodeVerifierLength = 43
+	maxCodeVerifierLength = 128
+)
+
 // VerifyCodeChallenge validates a PKCE code challenge against a code verifier.
 // It supports both "plain" and "S256" transformation methods as defined in RFC 7636.
 // Parameters:
@@ -16,11 +28,11 @@ import (
 //
 // Returns true if the verification is successful, false otherwise.
 func VerifyCodeChallenge(codeVerifier, codeChallenge, method string) bool {
-	if method == "plain" {
+	if method == MethodPlain {
 		return codeVerifier == codeChallenge
 	}
 
-	if method == "S256" {
+	if method == MethodS256 {
 		h := sha256.New()
 		h.Write([]byte(codeVerifier))
 		challenge := base64.RawURLEncoding.EncodeToString(h.Sum(nil))
@@ -29,3 +41,28 @@ func VerifyCodeChallenge(codeVerifier, codeChallenge, method string) bool {
 
 	return false
 }
+
+// IsValidMethod reports whether method is a supported code challenge method.
+func IsValidMethod(method string) bool {
+	return method == MethodPlain || method == MethodS256
+}
+
+// IsValidCodeVerifier reports whether a code verifier (or plain code challenge)
+// satisfies the RFC 7636 format: 43 to 128 characters drawn from the unreserved
+// set [A-Z] / [a-z] / [0-9] / "-" / "." / "_" / "~".
+func IsValidCodeVerifier(codeVerifier string) bool {
+	if len(codeVerifier) < minCodeVerifierLength || len(codeVerifier) > maxCodeVerifierLength {
+		return false
+	}
+
+	for _, ch := range codeVerifier {
+		switch {
+		case ch >= 'A' && ch <= 'Z', ch >= 'a' && ch <= 'z', ch >= '0' && ch <= '9':
+		case ch == '-', ch == '.', ch == '_', ch == '~':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,87 @@
+// Package pwned checks passwords against a breached-password corpus through the
+// k-anonymity range API of Have I Been Pwned (Pwned Passwords). Only the first five
+// hex characters of a password's SHA-1 hash ever leave the server; the match against
+// the returned suffixes happens locally.
+package pwned
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Range API parameters
+const (
+	DefaultRangeURL = "https://api.pwnedpasswords.com/range/" // Range endpoint, followed by the hash prefix
+	prefixLength    = 5                                       // Hex characters of the hash sent to the API
+	maxResponseSize = 1 << 20                                 // Maximum accepted size of a range response in bytes
+)
+
+// Client queries a Pwned Passwords range endpoint.
+type Client struct {
+	rangeURL   string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for the range endpoint at rangeURL, which the five-character
+// hash prefix is appended to. Each query is bounded by timeout.
+func NewClient(rangeURL string, timeout time.Duration) *Client {
+	if rangeURL == "" {
+		rangeURL = DefaultRangeURL
+	}
+	return &Client{
+		rangeURL:   rangeURL,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// Count returns how many times the password appears in the breach corpus, zero if it does not.
+// The request carries only the hash prefix and asks for padded responses, so neither the
+// password nor whether it matched can be learned from the traffic.
+func (c *Client) Count(ctx context.Context, password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	digest := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := digest[:prefixLength], digest[prefixLength:]
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.rangeURL+prefix, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Add-Padding", "true")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query breached passwords: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("failed to query breached passwords: unexpected status %d", resp.StatusCode)
+	}
+
+	// Each line is a hash suffix and its count, separated by a colon; padding lines count zero
+	scanner := bufio.NewScanner(io.LimitReader(resp.Body, maxResponseSize))
+	for scanner.Scan() {
+		lineSuffix, count, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !ok || !strings.EqualFold(lineSuffix, suffix) {
+			continue
+		}
+		n, err := strconv.Atoi(count)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse breached password count: %w", err)
+		}
+		return n, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read breached passwords: %w", err)
+	}
+
+	return 0, nil
+}
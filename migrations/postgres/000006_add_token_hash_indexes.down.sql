@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_access_tokens_token_hash;
+DROP INDEX IF EXISTS idx_refresh_tokens_token_hash;
@@ -0,0 +1,2 @@
+CREATE UNIQUE INDEX idx_refresh_tokens_token_hash ON refresh_tokens(token_hash);
+CREATE INDEX idx_access_tokens_token_hash ON access_tokens(token_hash);
@@ -0,0 +1 @@
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS require_pkce BOOLEAN NOT NULL DEFAULT FALSE;
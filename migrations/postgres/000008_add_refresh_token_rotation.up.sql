@@ -0,0 +1,9 @@
+ALTER TABLE refresh_tokens ADD COLUMN family_id VARCHAR(255);
+ALTER TABLE refresh_tokens ADD COLUMN parent_token_id VARCHAR(255);
+ALTER TABLE refresh_tokens ADD COLUMN rotated_at TIMESTAMP;
+
+-- Existing tokens each start their own rotation family
+UPDATE refresh_tokens SET family_id = token_id WHERE family_id IS NULL;
+ALTER TABLE refresh_tokens ALTER COLUMN family_id SET NOT NULL;
+
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens(family_id);
@@ -0,0 +1,2 @@
+-- Client credentials tokens are issued to a client without a resource owner
+ALTER TABLE access_tokens ALTER COLUMN user_id DROP NOT NULL;
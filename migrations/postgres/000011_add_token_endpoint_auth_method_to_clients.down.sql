@@ -0,0 +1 @@
+ALTER TABLE clients DROP COLUMN IF EXISTS token_endpoint_auth_method;
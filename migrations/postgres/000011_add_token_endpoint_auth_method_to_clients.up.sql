@@ -0,0 +1,4 @@
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS token_endpoint_auth_method VARCHAR(50) NOT NULL DEFAULT 'client_secret_basic';
+
+-- Existing public clients authenticate without a secret
+UPDATE clients SET token_endpoint_auth_method = 'none' WHERE is_confidential = FALSE;
@@ -0,0 +1 @@
+ALTER TABLE clients DROP COLUMN IF EXISTS registration_access_token_hash;
@@ -0,0 +1,2 @@
+-- Clients created through dynamic registration are managed with a registration access token
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS registration_access_token_hash VARCHAR(64);
@@ -0,0 +1 @@
+ALTER TABLE scopes DROP COLUMN IF EXISTS required;
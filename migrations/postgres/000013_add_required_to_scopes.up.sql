@@ -0,0 +1,4 @@
+ALTER TABLE scopes ADD COLUMN IF NOT EXISTS required BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- OpenID Connect requests cannot proceed without the openid scope
+UPDATE scopes SET required = TRUE WHERE name = 'openid';
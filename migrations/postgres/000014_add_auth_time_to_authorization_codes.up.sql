@@ -0,0 +1,2 @@
+-- When the user authenticated, reported as the auth_time claim of ID tokens
+ALTER TABLE authorization_codes ADD COLUMN IF NOT EXISTS auth_time TIMESTAMP;
@@ -0,0 +1,2 @@
+ALTER TABLE clients DROP COLUMN IF EXISTS access_token_format;
+ALTER TABLE scopes DROP COLUMN IF EXISTS audience;
@@ -0,0 +1,5 @@
+-- Resource server addressed by JWT access tokens carrying the scope
+ALTER TABLE scopes ADD COLUMN IF NOT EXISTS audience VARCHAR(255);
+
+-- Access token format issued to the client; NULL uses the server default
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS access_token_format VARCHAR(20);
@@ -0,0 +1,4 @@
+ALTER TABLE access_tokens DROP COLUMN IF EXISTS audience;
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS resources;
+ALTER TABLE authorization_codes DROP COLUMN IF EXISTS resources;
+ALTER TABLE clients DROP COLUMN IF EXISTS allowed_resources;
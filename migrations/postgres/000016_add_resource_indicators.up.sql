@@ -0,0 +1,9 @@
+-- Resource servers each client may request tokens for (RFC 8707)
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS allowed_resources TEXT[] NOT NULL DEFAULT '{}';
+
+-- Resource indicators granted with an authorization code and kept by its refresh token family
+ALTER TABLE authorization_codes ADD COLUMN IF NOT EXISTS resources TEXT[];
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS resources TEXT[];
+
+-- Audience of each access token, reported by introspection
+ALTER TABLE access_tokens ADD COLUMN IF NOT EXISTS audience TEXT[];
@@ -0,0 +1,2 @@
+ALTER TABLE clients DROP COLUMN IF EXISTS id_token_encrypted_response_enc;
+ALTER TABLE clients DROP COLUMN IF EXISTS id_token_encrypted_response_alg;
@@ -0,0 +1,3 @@
+-- JWE algorithms for encrypting ID tokens to the client's key (OpenID Connect Dynamic Client Registration)
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS id_token_encrypted_response_alg VARCHAR(32);
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS id_token_encrypted_response_enc VARCHAR(32);
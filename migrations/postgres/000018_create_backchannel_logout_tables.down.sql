@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS logout_deliveries;
+DROP TABLE IF EXISTS rp_sessions;
+ALTER TABLE clients DROP COLUMN IF EXISTS backchannel_logout_uri;
+ALTER TABLE authorization_codes DROP COLUMN IF EXISTS session_id;
@@ -0,0 +1,33 @@
+-- Web session that issued each authorization code, reported as the ID token sid
+ALTER TABLE authorization_codes ADD COLUMN IF NOT EXISTS session_id VARCHAR(255);
+
+-- Endpoint notified when a user's session ends (OpenID Connect Back-Channel Logout)
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS backchannel_logout_uri TEXT;
+
+-- Sessions in which clients received ID tokens, notified when the user logs out
+CREATE TABLE IF NOT EXISTS rp_sessions (
+    client_id VARCHAR(255) NOT NULL,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    session_id VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (client_id, user_id, session_id)
+);
+
+CREATE INDEX idx_rp_sessions_user_id ON rp_sessions(user_id);
+
+-- Delivery state of each logout token sent to a client
+CREATE TABLE IF NOT EXISTS logout_deliveries (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    client_id VARCHAR(255) NOT NULL,
+    session_id VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_logout_deliveries_status ON logout_deliveries(status);
+CREATE INDEX idx_logout_deliveries_user_id ON logout_deliveries(user_id);
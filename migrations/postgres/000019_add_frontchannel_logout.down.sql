@@ -0,0 +1,2 @@
+ALTER TABLE clients DROP COLUMN IF EXISTS post_logout_redirect_uris;
+ALTER TABLE clients DROP COLUMN IF EXISTS frontchannel_logout_uri;
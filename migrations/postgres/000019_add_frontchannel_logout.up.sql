@@ -0,0 +1,5 @@
+-- Page each client loads in an iframe when a user's session ends (OpenID Connect Front-Channel Logout)
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS frontchannel_logout_uri TEXT;
+
+-- Where each client may send the user after logging out (OpenID Connect RP-Initiated Logout)
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS post_logout_redirect_uris TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,2 @@
+ALTER TABLE clients DROP COLUMN IF EXISTS sector_identifier_uri;
+ALTER TABLE clients DROP COLUMN IF EXISTS subject_type;
@@ -0,0 +1,5 @@
+-- Whether each client receives the user's public ID or a pairwise subject (OpenID Connect Core Section 8)
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS subject_type VARCHAR(16) NOT NULL DEFAULT 'public';
+
+-- Document listing the redirect URIs of clients that share a pairwise sector
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS sector_identifier_uri TEXT;
@@ -0,0 +1,3 @@
+ALTER TABLE clients DROP COLUMN IF EXISTS id_token_lifetime;
+ALTER TABLE clients DROP COLUMN IF EXISTS refresh_token_lifetime;
+ALTER TABLE clients DROP COLUMN IF EXISTS access_token_lifetime;
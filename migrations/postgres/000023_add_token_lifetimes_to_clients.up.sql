@@ -0,0 +1,4 @@
+-- Per-client token lifetimes in seconds; zero uses the grant type default
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS access_token_lifetime INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS refresh_token_lifetime INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS id_token_lifetime INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+-- URIs each client may have request objects fetched from (RFC 9101 Section 5.2)
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS request_uris TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,2 @@
+-- Clients that may only start authorization with a pushed request (RFC 9126 Section 6)
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS require_pushed_authorization_requests BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,2 @@
+-- Child scopes granted along with the scope, expanded when access tokens are issued
+ALTER TABLE scopes ADD COLUMN IF NOT EXISTS implied_scopes TEXT[] NOT NULL DEFAULT '{}';
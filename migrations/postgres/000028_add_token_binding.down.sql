@@ -0,0 +1,2 @@
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS bound_subnet;
+ALTER TABLE clients DROP COLUMN IF EXISTS bind_token_to_ip;
@@ -0,0 +1,5 @@
+-- Whether the client's refresh tokens only work from the subnet they were issued to
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS bind_token_to_ip BOOLEAN NOT NULL DEFAULT false;
+
+-- Subnet a refresh token is bound to in CIDR notation; NULL when the token is unbound
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS bound_subnet VARCHAR(64);
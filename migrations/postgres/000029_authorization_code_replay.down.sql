@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS authorization_code_tokens;
+ALTER TABLE authorization_codes DROP COLUMN IF EXISTS replay_detected;
+ALTER TABLE authorization_codes DROP COLUMN IF EXISTS nonce;
@@ -0,0 +1,12 @@
+-- Nonce of the authorization request, copied into the ID token
+ALTER TABLE authorization_codes ADD COLUMN IF NOT EXISTS nonce TEXT;
+
+-- Set when a code is presented again after being exchanged
+ALTER TABLE authorization_codes ADD COLUMN IF NOT EXISTS replay_detected BOOLEAN NOT NULL DEFAULT false;
+
+-- Access tokens issued in exchange for each authorization code, revoked when the code is replayed
+CREATE TABLE IF NOT EXISTS authorization_code_tokens (
+    auth_code VARCHAR(255) NOT NULL REFERENCES authorization_codes(code) ON DELETE CASCADE,
+    token_id VARCHAR(255) NOT NULL,
+    PRIMARY KEY (auth_code, token_id)
+);
@@ -0,0 +1,18 @@
+-- Refresh tokens of web sessions, used when TOKEN_STORE selects PostgreSQL instead of Redis.
+-- Rows outlive their tokens for auditing until expired tokens are deleted.
+CREATE TABLE IF NOT EXISTS web_refresh_tokens (
+    token_id VARCHAR(255) PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    auth_time TIMESTAMP,
+    session_id VARCHAR(255),
+    is_revoked BOOLEAN NOT NULL DEFAULT FALSE,
+    revoked_at TIMESTAMP,
+    user_agent TEXT,
+    ip_address VARCHAR(45)
+);
+
+CREATE INDEX idx_web_refresh_tokens_user_id ON web_refresh_tokens(user_id);
+CREATE INDEX idx_web_refresh_tokens_expires_at ON web_refresh_tokens(expires_at);
@@ -0,0 +1,3 @@
+-- Translations of the scope description keyed by language tag, e.g. {"de": "...", "pt-BR": "..."};
+-- a locale is added by adding a key, and scopes without a translation fall back to description
+ALTER TABLE scopes ADD COLUMN IF NOT EXISTS descriptions JSONB NOT NULL DEFAULT '{}';
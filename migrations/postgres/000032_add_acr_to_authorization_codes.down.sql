@@ -0,0 +1,2 @@
+ALTER TABLE authorization_codes DROP COLUMN IF EXISTS amr;
+ALTER TABLE authorization_codes DROP COLUMN IF EXISTS acr;
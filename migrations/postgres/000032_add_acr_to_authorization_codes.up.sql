@@ -0,0 +1,4 @@
+-- Authentication context class the session met and the authentication methods used,
+-- reported as the acr and amr claims of the ID token
+ALTER TABLE authorization_codes ADD COLUMN IF NOT EXISTS acr TEXT;
+ALTER TABLE authorization_codes ADD COLUMN IF NOT EXISTS amr TEXT[];
@@ -0,0 +1,2 @@
+-- Claims the claims request parameter asked to receive in the ID token
+ALTER TABLE authorization_codes ADD COLUMN IF NOT EXISTS id_token_claims TEXT[];
@@ -0,0 +1,3 @@
+ALTER TABLE access_tokens DROP COLUMN IF EXISTS cert_thumbprint;
+ALTER TABLE clients DROP COLUMN IF EXISTS tls_client_certificate_bound_access_tokens;
+ALTER TABLE clients DROP COLUMN IF EXISTS tls_client_auth_subject_dn;
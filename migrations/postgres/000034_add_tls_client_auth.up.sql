@@ -0,0 +1,8 @@
+-- Subject DN of the certificate a tls_client_auth client authenticates with (RFC 8705 Section 2.1.2)
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS tls_client_auth_subject_dn TEXT;
+
+-- Whether the client's access tokens are bound to its certificate (RFC 8705 Section 3)
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS tls_client_certificate_bound_access_tokens BOOLEAN NOT NULL DEFAULT false;
+
+-- x5t#S256 thumbprint of the certificate an access token is bound to; NULL when the token is unbound
+ALTER TABLE access_tokens ADD COLUMN IF NOT EXISTS cert_thumbprint VARCHAR(64);
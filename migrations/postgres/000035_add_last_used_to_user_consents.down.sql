@@ -0,0 +1 @@
+ALTER TABLE user_consents DROP COLUMN IF EXISTS last_used_at;
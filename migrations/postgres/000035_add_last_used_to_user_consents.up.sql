@@ -0,0 +1,2 @@
+-- When tokens were last issued under the consent, so users can spot grants they no longer use
+ALTER TABLE user_consents ADD COLUMN IF NOT EXISTS last_used_at TIMESTAMP;
@@ -0,0 +1,2 @@
+-- Whether the server issues the client a signed state bound to the user's session
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS managed_state BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_refresh_tokens_grant_id;
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS grant_id;
+DROP TABLE IF EXISTS grants;
@@ -0,0 +1,19 @@
+-- Grants clients manage through FAPI Grant Management, outliving the individual tokens issued under them
+CREATE TABLE IF NOT EXISTS grants (
+    id SERIAL PRIMARY KEY,
+    grant_id VARCHAR(255) NOT NULL UNIQUE,
+    client_id VARCHAR(255) NOT NULL,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    scope TEXT NOT NULL,
+    claims TEXT[] NOT NULL DEFAULT '{}',
+    resources TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_grants_client_id ON grants(client_id);
+CREATE INDEX idx_grants_user_id ON grants(user_id);
+
+-- Grant each refresh token family was issued under, so revoking a grant revokes its tokens
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS grant_id VARCHAR(255);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_grant_id ON refresh_tokens(grant_id);
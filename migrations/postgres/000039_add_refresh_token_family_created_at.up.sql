@@ -0,0 +1,8 @@
+ALTER TABLE refresh_tokens ADD COLUMN family_created_at TIMESTAMP;
+
+-- Existing tokens inherit the issuance time of the first token in their rotation family
+UPDATE refresh_tokens AS r SET family_created_at = f.created_at
+FROM refresh_tokens AS f
+WHERE f.token_id = r.family_id AND r.family_created_at IS NULL;
+UPDATE refresh_tokens SET family_created_at = created_at WHERE family_created_at IS NULL;
+ALTER TABLE refresh_tokens ALTER COLUMN family_created_at SET NOT NULL;
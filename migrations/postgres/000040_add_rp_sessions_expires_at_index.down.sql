@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_rp_sessions_expires_at;
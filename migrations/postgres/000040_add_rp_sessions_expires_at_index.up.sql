@@ -0,0 +1,2 @@
+-- Lets the expired data purge find expired sessions without scanning the table
+CREATE INDEX IF NOT EXISTS idx_rp_sessions_expires_at ON rp_sessions(expires_at);
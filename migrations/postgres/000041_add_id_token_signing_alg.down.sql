@@ -0,0 +1,2 @@
+ALTER TABLE clients DROP COLUMN IF EXISTS id_token_signing_secret;
+ALTER TABLE clients DROP COLUMN IF EXISTS id_token_signed_response_alg;
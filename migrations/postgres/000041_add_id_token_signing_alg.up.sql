@@ -0,0 +1,5 @@
+-- JWS algorithm ID tokens are signed with, empty for RS256 (OpenID Connect Dynamic Client Registration)
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS id_token_signed_response_alg VARCHAR(10) NOT NULL DEFAULT '';
+
+-- Client secret sealed with the client secret encryption key, kept only for clients signing ID tokens with HS256
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS id_token_signing_secret TEXT NOT NULL DEFAULT '';
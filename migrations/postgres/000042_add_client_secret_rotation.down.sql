@@ -0,0 +1,2 @@
+ALTER TABLE clients DROP COLUMN IF EXISTS previous_client_secret_expires_at;
+ALTER TABLE clients DROP COLUMN IF EXISTS previous_client_secret;
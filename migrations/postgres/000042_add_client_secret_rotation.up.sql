@@ -0,0 +1,5 @@
+-- Hash of the secret replaced by the last rotation, accepted until the grace period ends
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS previous_client_secret VARCHAR(255) NOT NULL DEFAULT '';
+
+-- When the previous client secret stops being accepted, NULL without a grace period
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS previous_client_secret_expires_at TIMESTAMP;
@@ -0,0 +1,2 @@
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS dpop_jkt;
+ALTER TABLE access_tokens DROP COLUMN IF EXISTS dpop_jkt;
@@ -0,0 +1,5 @@
+-- JWK thumbprint of the DPoP key an access token is bound to (RFC 9449 Section 6); NULL when the token is unbound
+ALTER TABLE access_tokens ADD COLUMN IF NOT EXISTS dpop_jkt VARCHAR(64);
+
+-- JWK thumbprint of the DPoP key a public client's refresh token is bound to (RFC 9449 Section 5)
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS dpop_jkt VARCHAR(64);
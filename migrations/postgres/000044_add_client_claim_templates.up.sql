@@ -0,0 +1,2 @@
+-- Templates of the custom claims added to the client's access and ID tokens
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS claim_templates JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,2 @@
+-- Rate limit tier of the client, "exempt" or requests/window, empty for the configured tier
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS rate_limit_tier TEXT NOT NULL DEFAULT '';
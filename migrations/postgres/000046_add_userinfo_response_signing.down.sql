@@ -0,0 +1,3 @@
+ALTER TABLE clients DROP COLUMN IF EXISTS userinfo_encrypted_response_enc;
+ALTER TABLE clients DROP COLUMN IF EXISTS userinfo_encrypted_response_alg;
+ALTER TABLE clients DROP COLUMN IF EXISTS userinfo_signed_response_alg;
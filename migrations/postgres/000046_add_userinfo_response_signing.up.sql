@@ -0,0 +1,4 @@
+-- JWS and JWE algorithms of signed and encrypted UserInfo responses (OpenID Connect Dynamic Client Registration)
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS userinfo_signed_response_alg VARCHAR(10) NOT NULL DEFAULT '';
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS userinfo_encrypted_response_alg VARCHAR(32) NOT NULL DEFAULT '';
+ALTER TABLE clients ADD COLUMN IF NOT EXISTS userinfo_encrypted_response_enc VARCHAR(32) NOT NULL DEFAULT '';
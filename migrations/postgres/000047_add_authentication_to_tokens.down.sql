@@ -0,0 +1,4 @@
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS acr;
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS auth_time;
+ALTER TABLE access_tokens DROP COLUMN IF EXISTS acr;
+ALTER TABLE access_tokens DROP COLUMN IF EXISTS auth_time;
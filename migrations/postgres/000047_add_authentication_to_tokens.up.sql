@@ -0,0 +1,8 @@
+-- When the user authenticated and the authentication context class the authentication met,
+-- reported as the auth_time and acr claims of access tokens and in introspection (RFC 9470 Section 6)
+ALTER TABLE access_tokens ADD COLUMN IF NOT EXISTS auth_time TIMESTAMP;
+ALTER TABLE access_tokens ADD COLUMN IF NOT EXISTS acr TEXT;
+
+-- The authentication a refresh token family was issued for, carried into refreshed access tokens
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS auth_time TIMESTAMP;
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS acr TEXT;